@@ -24,6 +24,7 @@ import (
 	"github.com/armon/go-metrics/prometheus"
 	"github.com/hashicorp/consul/agent/rpcclient"
 	"github.com/hashicorp/consul/agent/rpcclient/configentry"
+	"github.com/hashicorp/consul/agent/rpcclient/kv"
 	"github.com/hashicorp/go-connlimit"
 	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/go-memdb"
@@ -247,6 +248,12 @@ type Agent struct {
 	// cache is the in-memory cache for data the Agent requests.
 	cache *cache.Cache
 
+	// cacheSnapshotLock serializes writes of the cache snapshot.
+	// cacheSnapshotLoaded is set once the snapshot from the previous run was
+	// loaded so that it isn't overwritten by a shutdown before then.
+	cacheSnapshotLock   sync.Mutex
+	cacheSnapshotLoaded bool
+
 	// checkReapAfter maps the check ID to a timeout after which we should
 	// reap its associated service
 	checkReapAfter map[structs.CheckID]time.Duration
@@ -354,6 +361,10 @@ type Agent struct {
 	// agent.
 	watchPlans []*watch.Plan
 
+	// watchCancel cancels the context of the watch handlers, which stops
+	// the retries of HTTP handlers, when the watches are stopped.
+	watchCancel context.CancelFunc
+
 	// tokens holds ACL tokens initially from the configuration, but can
 	// be updated at runtime, so should always be used instead of going to
 	// the configuration directly.
@@ -388,6 +399,7 @@ type Agent struct {
 	// into Agent, which will allow us to remove this field.
 	rpcClientHealth      *health.Client
 	rpcClientConfigEntry *configentry.Client
+	rpcClientKV          *kv.Client
 
 	rpcClientPeering pbpeering.PeeringServiceClient
 
@@ -497,6 +509,22 @@ func New(bd BaseDeps) (*Agent, error) {
 		},
 	}
 
+	a.rpcClientKV = &kv.Client{
+		Client: rpcclient.Client{
+			NetRPC:    &a,
+			ViewStore: bd.ViewStore,
+			MaterializerDeps: rpcclient.MaterializerDeps{
+				Conn:   conn,
+				Logger: bd.Logger.Named("rpcclient.kv"),
+			},
+			// Subscriptions to the KV topic only check the read permission of
+			// each key, so they can't enforce the list permission required on
+			// the prefix when the key list policy is enabled.
+			UseStreamingBackend: a.config.UseStreamingBackend && !a.config.ACLEnableKeyListPolicy,
+			QueryOptionDefaults: config.ApplyDefaultQueryOptions(a.config),
+		},
+	}
+
 	// We used to do this in the Start method. However it doesn't need to go
 	// there any longer. Originally it did because we passed the agent
 	// delegate to some of the cache registrations. Now we just
@@ -694,6 +722,12 @@ func (a *Agent) Start(ctx context.Context) error {
 		return fmt.Errorf("AutoConf failed to start certificate monitor: %w", err)
 	}
 
+	// Warm the cache from the last snapshot before services are loaded so
+	// that proxies and checks can be served from it straight away.
+	if err := a.startCacheSnapshots(); err != nil {
+		return err
+	}
+
 	// Load checks/services/metadata.
 	emptyCheckSnapshot := map[structs.CheckID]*structs.HealthCheck{}
 	if err := a.loadServices(c, emptyCheckSnapshot); err != nil {
@@ -1203,6 +1237,10 @@ func (a *Agent) stopAllWatches() {
 	for _, wp := range a.watchPlans {
 		wp.Stop()
 	}
+	if a.watchCancel != nil {
+		a.watchCancel()
+		a.watchCancel = nil
+	}
 }
 
 // reloadWatches stops any existing watch plans and attempts to load the given
@@ -1248,6 +1286,9 @@ func (a *Agent) reloadWatches(cfg *config.RuntimeConfig) error {
 		watchPlans = append(watchPlans, wp)
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
+	a.watchCancel = cancel
+
 	// Fire off a goroutine for each new watch plan.
 	for _, wp := range watchPlans {
 		config, err := a.config.APIConfig(true)
@@ -1264,7 +1305,7 @@ func (a *Agent) reloadWatches(cfg *config.RuntimeConfig) error {
 				wp.Handler = makeWatchHandler(a.logger, h)
 			} else {
 				httpConfig := wp.Exempt["http_handler_config"].(*watch.HttpHandlerConfig)
-				wp.Handler = makeHTTPWatchHandler(a.logger, httpConfig)
+				wp.Handler = makeHTTPWatchHandler(ctx, a.logger, httpConfig)
 			}
 			wp.Logger = a.logger.Named("watch")
 
@@ -1504,6 +1545,7 @@ func newConsulConfig(runtimeCfg *config.RuntimeConfig, logger hclog.Logger) (*co
 
 	cfg.ConfigEntryBootstrap = runtimeCfg.ConfigEntryBootstrap
 	cfg.LogStoreConfig = runtimeCfg.RaftLogStoreConfig
+	cfg.LeaderHealthConfig = runtimeCfg.RaftLeaderHealthConfig
 
 	// Duplicate our own serf config once to make sure that the duplication
 	// function does not drift.
@@ -1515,6 +1557,7 @@ func newConsulConfig(runtimeCfg *config.RuntimeConfig, logger hclog.Logger) (*co
 	cfg.RequestLimitsMode = runtimeCfg.RequestLimitsMode.String()
 	cfg.RequestLimitsReadRate = runtimeCfg.RequestLimitsReadRate
 	cfg.RequestLimitsWriteRate = runtimeCfg.RequestLimitsWriteRate
+	cfg.RequestConcurrencyLimits = runtimeCfg.RequestConcurrencyLimits
 	cfg.Locality = runtimeCfg.StructLocality()
 
 	cfg.Reporting.License.Enabled = runtimeCfg.Reporting.License.Enabled
@@ -1676,11 +1719,15 @@ func (a *Agent) ShutdownAgent() error {
 
 	// Stop the cache background work
 	if a.cache != nil {
+		if err := a.saveCacheSnapshot(); err != nil {
+			a.logger.Warn("Failed to save cache snapshot", "error", err)
+		}
 		a.cache.Close()
 	}
 
 	a.rpcClientHealth.Close()
 	a.rpcClientConfigEntry.Close()
+	a.rpcClientKV.Close()
 
 	// Shutdown SCADA provider
 	if a.scadaProvider != nil {
@@ -2914,7 +2961,16 @@ func (a *Agent) addCheck(check *structs.HealthCheck, chkType *structs.CheckType,
 				chkType.Interval = checks.MinInterval
 			}
 
-			tlsClientConfig := a.tlsConfigurator.OutgoingTLSConfigForCheck(chkType.TLSSkipVerify, chkType.TLSServerName)
+			var tlsClientConfig *tls.Config
+			if chkType.UseConnectTLS {
+				var err error
+				tlsClientConfig, err = a.connectCheckTLSConfig(service, chkType)
+				if err != nil {
+					return err
+				}
+			} else {
+				tlsClientConfig = a.tlsConfigurator.OutgoingTLSConfigForCheck(chkType.TLSSkipVerify, chkType.TLSServerName)
+			}
 
 			http := &checks.CheckHTTP{
 				CheckID:          cid,
@@ -2932,7 +2988,9 @@ func (a *Agent) addCheck(check *structs.HealthCheck, chkType *structs.CheckType,
 				StatusHandler:    statusHandler,
 			}
 
-			if proxy != nil && proxy.Proxy.Expose.Checks {
+			// Checks using the Connect leaf certificate reach the service
+			// directly over mTLS, so they don't need an exposed path.
+			if proxy != nil && proxy.Proxy.Expose.Checks && !chkType.UseConnectTLS {
 				port, err := a.listenerPortLocked(sid, cid)
 				if err != nil {
 					a.logger.Error("error exposing check",
@@ -3012,7 +3070,13 @@ func (a *Agent) addCheck(check *structs.HealthCheck, chkType *structs.CheckType,
 			}
 
 			var tlsClientConfig *tls.Config
-			if chkType.GRPCUseTLS {
+			if chkType.UseConnectTLS {
+				var err error
+				tlsClientConfig, err = a.connectCheckTLSConfig(service, chkType)
+				if err != nil {
+					return err
+				}
+			} else if chkType.GRPCUseTLS {
 				tlsClientConfig = a.tlsConfigurator.OutgoingTLSConfigForCheck(chkType.TLSSkipVerify, chkType.TLSServerName)
 			}
 
@@ -3027,7 +3091,7 @@ func (a *Agent) addCheck(check *structs.HealthCheck, chkType *structs.CheckType,
 				StatusHandler:   statusHandler,
 			}
 
-			if proxy != nil && proxy.Proxy.Expose.Checks {
+			if proxy != nil && proxy.Proxy.Expose.Checks && !chkType.UseConnectTLS {
 				port, err := a.listenerPortLocked(sid, cid)
 				if err != nil {
 					a.logger.Error("error exposing check",
@@ -4195,9 +4259,10 @@ func (a *Agent) reloadConfigInternal(newCfg *config.RuntimeConfig) error {
 
 	cc := consul.ReloadableConfig{
 		RequestLimits: &consul.RequestLimits{
-			Mode:      newCfg.RequestLimitsMode,
-			ReadRate:  newCfg.RequestLimitsReadRate,
-			WriteRate: newCfg.RequestLimitsWriteRate,
+			Mode:        newCfg.RequestLimitsMode,
+			ReadRate:    newCfg.RequestLimitsReadRate,
+			WriteRate:   newCfg.RequestLimitsWriteRate,
+			Concurrency: newCfg.RequestConcurrencyLimits,
 		},
 		RPCClientTimeout:      newCfg.RPCClientTimeout,
 		RPCRateLimit:          newCfg.RPCRateLimit,
@@ -1384,6 +1384,50 @@ func (s *HTTPHandlers) AgentNodeMaintenance(resp http.ResponseWriter, req *http.
 	return nil, nil
 }
 
+func (s *HTTPHandlers) AgentDrain(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	params := req.URL.Query()
+	opts := drainOpts{reason: params.Get("reason")}
+	if raw := params.Get("timeout"); raw != "" {
+		timeout, err := time.ParseDuration(raw)
+		if err != nil {
+			return nil, HTTPError{StatusCode: http.StatusBadRequest, Reason: fmt.Sprintf("Invalid value for timeout: %q", raw)}
+		}
+		opts.timeout = timeout
+	}
+	if raw := params.Get("threshold"); raw != "" {
+		threshold, err := strconv.Atoi(raw)
+		if err != nil || threshold < 1 {
+			return nil, HTTPError{StatusCode: http.StatusBadRequest, Reason: fmt.Sprintf("Invalid value for threshold: %q", raw)}
+		}
+		opts.threshold = threshold
+	}
+
+	// Get the provided token, if any, and vet against any ACL policies.
+	s.parseToken(req, &opts.token)
+
+	authz, err := s.agent.delegate.ResolveTokenAndDefaultMeta(opts.token, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var authzContext acl.AuthorizerContext
+	s.agent.AgentEnterpriseMeta().FillAuthzContext(&authzContext)
+	if err := authz.ToAllowAuthorizer().NodeWriteAllowed(s.agent.config.NodeName, &authzContext); err != nil {
+		return nil, err
+	}
+
+	// The sidecar proxies are deregistered once drained, so the token must
+	// also be allowed to deregister them.
+	proxies := s.agent.drainableProxies()
+	for _, svc := range proxies {
+		if err := s.agent.vetServiceUpdateWithAuthorizer(authz, svc.CompoundServiceID()); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.agent.DrainNode(req.Context(), proxies, opts)
+}
+
 func (s *HTTPHandlers) AgentMonitor(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
 	// Fetch the ACL token, if any, and enforce agent policy.
 	var token string
@@ -2223,6 +2223,122 @@ func TestAgent_HTTPCheck_EnableAgentTLSForChecks(t *testing.T) {
 	}
 }
 
+func TestAgent_HTTPCheck_UseConnectTLS(t *testing.T) {
+	if testing.Short() {
+		t.Skip("too slow for testing.Short")
+	}
+
+	t.Parallel()
+
+	a := NewTestAgent(t, "")
+	defer a.Shutdown()
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+	srv := &structs.NodeService{ID: "web", Service: "web", Port: 8080}
+	require.NoError(t, a.addServiceFromSource(srv, nil, false, "", ConfigSourceLocal))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	raw, _, err := a.cache.Get(ctx, cachetype.ConnectCARootName, &structs.DCSpecificRequest{Datacenter: "dc1"})
+	require.NoError(t, err)
+	roots := x509.NewCertPool()
+	for _, root := range raw.(*structs.IndexedCARoots).Roots {
+		require.True(t, roots.AppendCertsFromPEM([]byte(root.RootCert)))
+	}
+
+	// newServer starts an HTTPS server that presents a Connect leaf
+	// certificate for the given service and requires a client certificate
+	// signed by the Connect CA.
+	newServer := func(t *testing.T, service string) *httptest.Server {
+		raw, _, err := a.cache.Get(ctx, cachetype.ConnectCALeafName, &cachetype.ConnectCALeafRequest{
+			Datacenter: "dc1",
+			Service:    service,
+		})
+		require.NoError(t, err)
+		leaf := raw.(*structs.IssuedCert)
+		cert, err := tls.X509KeyPair([]byte(leaf.CertPEM), []byte(leaf.PrivateKeyPEM))
+		require.NoError(t, err)
+
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			uri := r.TLS.PeerCertificates[0].URIs[0]
+			if !strings.HasSuffix(uri.Path, "/svc/web") {
+				w.WriteHeader(http.StatusForbidden)
+			}
+		}))
+		server.TLS = &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			ClientAuth:   tls.RequireAndVerifyClientCert,
+			ClientCAs:    roots,
+		}
+		server.StartTLS()
+		t.Cleanup(server.Close)
+		return server
+	}
+
+	addCheck := func(t *testing.T, id types.CheckID, url string) {
+		health := &structs.HealthCheck{
+			Node:      "foo",
+			CheckID:   id,
+			Name:      "connect tls check",
+			Status:    api.HealthCritical,
+			ServiceID: "web",
+		}
+		chk := &structs.CheckType{
+			HTTP:          url,
+			UseConnectTLS: true,
+			Interval:      20 * time.Millisecond,
+		}
+		require.NoError(t, a.AddCheck(health, chk, false, "", ConfigSourceLocal))
+	}
+
+	t.Run("matching identity", func(t *testing.T) {
+		server := newServer(t, "web")
+		addCheck(t, "web-tls", server.URL)
+
+		retry.Run(t, func(r *retry.R) {
+			status := getCheck(a, "web-tls")
+			if status.Status != api.HealthPassing {
+				r.Fatalf("bad: %v %v", status.Status, status.Output)
+			}
+			if !strings.Contains(status.Output, "200 OK") {
+				r.Fatalf("bad: %v", status.Output)
+			}
+		})
+	})
+
+	t.Run("mismatched identity", func(t *testing.T) {
+		server := newServer(t, "db")
+		addCheck(t, "db-tls", server.URL)
+
+		retry.Run(t, func(r *retry.R) {
+			status := getCheck(a, "db-tls")
+			if status.Status != api.HealthCritical {
+				r.Fatalf("bad: %v", status.Status)
+			}
+			if !strings.Contains(status.Output, "peer certificate mismatch") {
+				r.Fatalf("bad: %v", status.Output)
+			}
+		})
+	})
+
+	t.Run("no service", func(t *testing.T) {
+		health := &structs.HealthCheck{
+			Node:    "foo",
+			CheckID: "node-tls",
+			Name:    "connect tls check",
+			Status:  api.HealthCritical,
+		}
+		chk := &structs.CheckType{
+			HTTP:          "https://127.0.0.1:8080",
+			UseConnectTLS: true,
+			Interval:      20 * time.Millisecond,
+		}
+		err := a.AddCheck(health, chk, false, "", ConfigSourceLocal)
+		require.ErrorContains(t, err, "only supported for checks associated with a service")
+	})
+}
+
 func TestAgent_updateTTLCheck(t *testing.T) {
 	if testing.Short() {
 		t.Skip("too slow for testing.Short")
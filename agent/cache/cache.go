@@ -100,6 +100,10 @@ const (
 	// DefaultEntryFetchMaxBurst is the number of cache entry fetches that can
 	// occur in a burst.
 	DefaultEntryFetchMaxBurst = 2
+
+	// restoredRefreshTimeout is the fetch timeout used for the first refresh
+	// of an entry restored from a snapshot.
+	restoredRefreshTimeout = 5 * time.Second
 )
 
 // Cache is a agent-local cache of Consul data. Create a Cache using the
@@ -499,7 +503,14 @@ RETRY_GET:
 
 		// If refresh is enabled, calculate age based on whether the background
 		// routine is still connected.
-		if r.TypeEntry.Opts.Refresh {
+		if entry.Restored {
+			// Entries restored from a snapshot are stale until the first
+			// refresh succeeds, which we start here if it isn't running.
+			meta.Age = time.Since(entry.FetchedAt)
+			if entry.GoroutineID == 0 {
+				c.refreshRestored(key, r)
+			}
+		} else if r.TypeEntry.Opts.Refresh {
 			meta.Age = time.Duration(0)
 			if !entry.RefreshLostContact.IsZero() {
 				meta.Age = time.Since(entry.RefreshLostContact)
@@ -748,6 +759,15 @@ func (c *Cache) runBackgroundFetcherOnce(goroutineID uint64, key string, r getOp
 				fOpts.Timeout = 10 * time.Minute
 			}
 		}
+		if entry.Restored {
+			// Don't block on the first fetch of a restored entry since the
+			// servers may well be at the same index, which would leave the
+			// entry stale until the query times out. Types that wait on
+			// something other than the index, like leaf certificates, are
+			// bounded by the short timeout instead.
+			fOpts.MinIndex = 0
+			fOpts.Timeout = restoredRefreshTimeout
+		}
 		if entry.Valid {
 			fOpts.LastResult = &FetchResult{
 				Value: entry.Value,
@@ -851,6 +871,9 @@ func (c *Cache) runBackgroundFetcherOnce(goroutineID uint64, key string, r getOp
 			if tEntry.Opts.Refresh {
 				newEntry.RefreshLostContact = time.Time{}
 			}
+			if result.Value != nil {
+				newEntry.Restored = false
+			}
 		} else {
 			// TODO (mkeeler) maybe change the name of this label to be more indicative of it just
 			// stopping the background refresh
@@ -1002,20 +1025,23 @@ func (c *Cache) Close() error {
 // time because it requires a special RPCType. Subsequent runs are fine though.
 func (c *Cache) Prepopulate(t string, res FetchResult, dc, peerName, token, k string) error {
 	key := makeEntryKey(t, dc, peerName, token, k)
-	newEntry := cacheEntry{
+	c.entriesLock.Lock()
+	c.entries[key] = c.newPrepopulatedEntry(res, time.Now())
+	c.entriesLock.Unlock()
+	return nil
+}
+
+func (c *Cache) newPrepopulatedEntry(res FetchResult, fetchedAt time.Time) cacheEntry {
+	return cacheEntry{
 		Valid:     true,
 		Value:     res.Value,
 		State:     res.State,
 		Index:     res.Index,
-		FetchedAt: time.Now(),
+		FetchedAt: fetchedAt,
 		Waiter:    make(chan struct{}),
 		FetchRateLimiter: rate.NewLimiter(
 			c.options.EntryFetchRate,
 			c.options.EntryFetchMaxBurst,
 		),
 	}
-	c.entriesLock.Lock()
-	c.entries[key] = newEntry
-	c.entriesLock.Unlock()
-	return nil
 }
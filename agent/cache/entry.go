@@ -49,4 +49,8 @@ type cacheEntry struct {
 	RefreshLostContact time.Time
 	// FetchRateLimiter limits the rate at which fetch is called for this entry.
 	FetchRateLimiter *rate.Limiter
+
+	// Restored is true if the entry was loaded from a snapshot and hasn't
+	// been successfully refreshed since. Restored entries are served as stale.
+	Restored bool
 }
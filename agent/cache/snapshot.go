@@ -0,0 +1,147 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package cache
+
+import (
+	"strings"
+	"time"
+
+	"github.com/armon/go-metrics"
+)
+
+// SnapshotEntry is a single cache entry captured by Snapshot so that it can
+// be persisted and later loaded with Restore, for example across agent
+// restarts.
+type SnapshotEntry struct {
+	// Type is the name the entry's type was registered with.
+	Type string
+
+	// Datacenter, PeerName, Token and Key identify the request the entry
+	// was fetched for. They match the arguments to Prepopulate.
+	Datacenter string
+	PeerName   string
+	Token      string
+	Key        string
+
+	Value interface{}
+	// State is not captured by Snapshot since it is opaque to the cache and
+	// usually not serializable. Callers may set it before calling Restore.
+	State     interface{}
+	Index     uint64
+	FetchedAt time.Time
+}
+
+// Snapshot returns the valid entries of the given types. The values are
+// shared with the cache and must not be modified.
+func (c *Cache) Snapshot(types ...string) []SnapshotEntry {
+	include := make(map[string]struct{}, len(types))
+	for _, t := range types {
+		include[t] = struct{}{}
+	}
+
+	c.entriesLock.RLock()
+	defer c.entriesLock.RUnlock()
+
+	var entries []SnapshotEntry
+	for key, entry := range c.entries {
+		if !entry.Valid {
+			continue
+		}
+		e, ok := parseEntryKey(key)
+		if !ok {
+			continue
+		}
+		if _, ok := include[e.Type]; !ok {
+			continue
+		}
+		e.Value = entry.Value
+		e.Index = entry.Index
+		e.FetchedAt = entry.FetchedAt
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// Restore loads entries from a snapshot into the cache like Prepopulate.
+// Restored entries are returned by Get immediately but are reported as stale,
+// with an age measured from when they were originally fetched, until the
+// first refresh from the servers succeeds. The first Get of a restored entry
+// starts that refresh.
+//
+// Entries whose type isn't registered, or that are already in the cache, for
+// example because they were prepopulated during auto-config, are skipped.
+// Restore returns the number of entries that were loaded.
+func (c *Cache) Restore(entries []SnapshotEntry) int {
+	c.typesLock.RLock()
+	defer c.typesLock.RUnlock()
+
+	c.entriesLock.Lock()
+	defer c.entriesLock.Unlock()
+
+	var restored int
+	for _, e := range entries {
+		tEntry, ok := c.types[e.Type]
+		if !ok {
+			continue
+		}
+		key := makeEntryKey(e.Type, e.Datacenter, e.PeerName, e.Token, e.Key)
+		if _, ok := c.entries[key]; ok {
+			continue
+		}
+
+		res := FetchResult{Value: e.Value, State: e.State, Index: e.Index}
+		entry := c.newPrepopulatedEntry(res, e.FetchedAt)
+		entry.Restored = true
+		// Unlike prepopulated entries, restored ones may belong to requests
+		// that are never made again, for example because the token changed,
+		// so they must expire like any other entry.
+		entry.Expiry = c.entriesExpiryHeap.Add(key, tEntry.Opts.LastGetTTL)
+		c.entries[key] = entry
+		restored++
+	}
+
+	metrics.SetGauge([]string{"consul", "cache", "entries_count"}, float32(len(c.entries)))
+	metrics.SetGauge([]string{"cache", "entries_count"}, float32(len(c.entries)))
+	return restored
+}
+
+// refreshRestored starts a background fetch for a restored entry so that it
+// is replaced with fresh data from the servers.
+func (c *Cache) refreshRestored(key string, r getOptions) {
+	c.entriesLock.Lock()
+	defer c.entriesLock.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || !entry.Restored || entry.GoroutineID != 0 {
+		return
+	}
+
+	c.lastGoroutineID++
+	entry.GoroutineID = c.lastGoroutineID
+	c.entries[key] = entry
+
+	r.Info.MinIndex = 0
+	r.Info.MustRevalidate = false
+	go c.launchBackgroundFetcher(entry.GoroutineID, key, r)
+}
+
+// parseEntryKey is the inverse of makeEntryKey. The request key is last so
+// it may contain separators.
+func parseEntryKey(key string) (SnapshotEntry, bool) {
+	parts := strings.SplitN(key, "/", 4)
+	if len(parts) != 4 {
+		return SnapshotEntry{}, false
+	}
+	e := SnapshotEntry{
+		Type:  parts[0],
+		Token: parts[2],
+		Key:   parts[3],
+	}
+	if peerName, ok := strings.CutPrefix(parts[1], "peer:"); ok {
+		e.PeerName = peerName
+	} else {
+		e.Datacenter = parts[1]
+	}
+	return e, true
+}
@@ -0,0 +1,128 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache_SnapshotRestore(t *testing.T) {
+	t.Parallel()
+
+	typ := TestType(t)
+	defer typ.AssertExpectations(t)
+	c := New(Options{})
+	defer c.Close()
+	c.RegisterType("t", typ)
+	c.RegisterType("other", TestType(t))
+
+	typ.Static(FetchResult{Value: 42, Index: 10}, nil).Times(1)
+
+	req := TestRequest(t, RequestInfo{Datacenter: "dc1", Token: "token", Key: "web/v1"})
+	_, _, err := c.Get(context.Background(), "t", req)
+	require.NoError(t, err)
+	require.NoError(t, c.Prepopulate("other", FetchResult{Value: 1, Index: 1}, "dc1", "", "", "ignored"))
+
+	entries := c.Snapshot("t")
+	require.Len(t, entries, 1)
+	require.Equal(t, "t", entries[0].Type)
+	require.Equal(t, "dc1", entries[0].Datacenter)
+	require.Equal(t, "token", entries[0].Token)
+	require.Equal(t, "web/v1", entries[0].Key)
+	require.Equal(t, 42, entries[0].Value)
+	require.Equal(t, uint64(10), entries[0].Index)
+
+	// Pretend the snapshot was taken a while ago.
+	entries[0].FetchedAt = time.Now().Add(-time.Hour)
+
+	// Restore into a fresh cache where the first refresh blocks until
+	// released so the restored value is observable.
+	typ2 := TestType(t)
+	defer typ2.AssertExpectations(t)
+	c2 := New(Options{})
+	defer c2.Close()
+	c2.RegisterType("t", typ2)
+
+	releaseCh := make(chan struct{})
+	fetchedCh := make(chan FetchOptions, 1)
+	typ2.On("Fetch", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			fetchedCh <- args.Get(0).(FetchOptions)
+			<-releaseCh
+		}).
+		Return(FetchResult{Value: 43, Index: 11}, nil).
+		Times(1)
+
+	// Entries of unknown types are skipped.
+	entries = append(entries, SnapshotEntry{Type: "unknown", Key: "foo", Value: 1, Index: 1})
+	require.Equal(t, 1, c2.Restore(entries))
+
+	result, meta, err := c2.Get(context.Background(), "t", req)
+	require.NoError(t, err)
+	require.Equal(t, 42, result)
+	require.True(t, meta.Hit)
+	require.Greater(t, meta.Age, 59*time.Minute)
+
+	// The first Get starts a non-blocking refresh of the restored entry.
+	select {
+	case opts := <-fetchedCh:
+		require.Zero(t, opts.MinIndex)
+		require.Equal(t, restoredRefreshTimeout, opts.Timeout)
+		require.NotNil(t, opts.LastResult)
+		require.Equal(t, 42, opts.LastResult.Value)
+	case <-time.After(time.Second):
+		t.Fatal("restored entry was not refreshed")
+	}
+	close(releaseCh)
+
+	// Once refreshed the entry is no longer stale.
+	result, meta, err = c2.Get(context.Background(), "t", TestRequest(t, RequestInfo{
+		Datacenter: "dc1",
+		Token:      "token",
+		Key:        "web/v1",
+		MinIndex:   10,
+		Timeout:    time.Second,
+	}))
+	require.NoError(t, err)
+	require.Equal(t, 43, result)
+	require.Equal(t, uint64(11), meta.Index)
+	require.Less(t, meta.Age, time.Minute)
+}
+
+func TestCache_RestoreSkipsExisting(t *testing.T) {
+	t.Parallel()
+
+	typ := TestType(t)
+	c := New(Options{})
+	defer c.Close()
+	c.RegisterType("t", typ)
+
+	require.NoError(t, c.Prepopulate("t", FetchResult{Value: 2, Index: 2}, "dc1", "", "", "hello"))
+
+	n := c.Restore([]SnapshotEntry{
+		{Type: "t", Datacenter: "dc1", Key: "hello", Value: 1, Index: 1},
+		{Type: "t", PeerName: "peer1", Key: "hello", Value: 3, Index: 3},
+	})
+	require.Equal(t, 1, n)
+
+	entries := c.Snapshot("t")
+	require.Len(t, entries, 2)
+	for _, e := range entries {
+		switch e.PeerName {
+		case "":
+			require.Equal(t, "dc1", e.Datacenter)
+			require.Equal(t, 2, e.Value)
+		case "peer1":
+			require.Empty(t, e.Datacenter)
+			require.Equal(t, 3, e.Value)
+		default:
+			t.Fatalf("unexpected entry %#v", e)
+		}
+	}
+}
@@ -0,0 +1,332 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package agent
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hashicorp/consul-net-rpc/go-msgpack/codec"
+	"golang.org/x/crypto/hkdf"
+
+	"github.com/hashicorp/consul/agent/cache"
+	cachetype "github.com/hashicorp/consul/agent/cache-types"
+	"github.com/hashicorp/consul/agent/connect"
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/lib/file"
+)
+
+const (
+	// cacheSnapshotFile is the file in the data directory that the cache
+	// snapshot is written to.
+	cacheSnapshotFile = "cache-snapshot"
+
+	// cacheSnapshotVersion is written before the encrypted snapshot so the
+	// format can be changed later.
+	cacheSnapshotVersion byte = 1
+)
+
+// cacheSnapshotInfo is used to derive the snapshot encryption key from the
+// configured key, so that the gossip key isn't used directly.
+var cacheSnapshotInfo = []byte("consul-agent-cache-snapshot")
+
+// cacheSnapshotType describes how to restore the values of a cache type that
+// can be persisted.
+type cacheSnapshotType struct {
+	// newValue returns a pointer to decode a persisted value into.
+	newValue func() interface{}
+
+	// state optionally rebuilds the cache type's state for a restored value.
+	state func(value interface{}) (interface{}, error)
+}
+
+// cacheSnapshotTypes are the cache types that can be persisted with
+// cache.persistence.types.
+var cacheSnapshotTypes = map[string]cacheSnapshotType{
+	cachetype.ConnectCARootName: {
+		newValue: func() interface{} { return &structs.IndexedCARoots{} },
+	},
+	cachetype.ConnectCALeafName: {
+		newValue: func() interface{} { return &structs.IssuedCert{} },
+		state:    connectCALeafSnapshotState,
+	},
+	cachetype.CompiledDiscoveryChainName: {
+		newValue: func() interface{} { return &structs.DiscoveryChainResponse{} },
+	},
+	cachetype.HealthServicesName: {
+		newValue: func() interface{} { return &structs.IndexedCheckServiceNodes{} },
+	},
+	cachetype.ResolvedServiceConfigName: {
+		newValue: func() interface{} { return &structs.ServiceConfigResponse{} },
+	},
+	cachetype.IntentionMatchName: {
+		newValue: func() interface{} { return &structs.IndexedIntentionMatches{} },
+	},
+}
+
+// connectCALeafSnapshotState rebuilds the state of a restored leaf
+// certificate the same way auto-config does when it prepopulates the cache,
+// so the cache type keeps the certificate until it needs renewing instead of
+// signing a new one for every service on restart.
+func connectCALeafSnapshotState(value interface{}) (interface{}, error) {
+	issued, ok := value.(*structs.IssuedCert)
+	if !ok {
+		return nil, fmt.Errorf("unexpected leaf certificate type %T", value)
+	}
+	cert, err := connect.ParseCert(issued.CertPEM)
+	if err != nil {
+		return nil, err
+	}
+	return cachetype.ConnectCALeafSuccess(connect.EncodeSigningKeyID(cert.AuthorityKeyId)), nil
+}
+
+// cacheSnapshot is the format of the persisted cache snapshot before it is
+// encrypted.
+type cacheSnapshot struct {
+	Entries []cacheSnapshotEntry
+}
+
+type cacheSnapshotEntry struct {
+	Type       string
+	Datacenter string
+	PeerName   string
+	Token      string
+	Key        string
+	Index      uint64
+	FetchedAt  time.Time
+
+	// Value is encoded separately so it can be decoded into the right type.
+	Value []byte
+}
+
+// startCacheSnapshots restores the cache from the snapshot written by the
+// previous run, if any, and starts writing new snapshots periodically. A
+// snapshot that can't be read is logged and ignored since the cache will be
+// filled from the servers as usual.
+func (a *Agent) startCacheSnapshots() error {
+	cfg := a.config.CachePersistence
+	if !cfg.Enabled {
+		return nil
+	}
+	for _, t := range cfg.Types {
+		if _, ok := cacheSnapshotTypes[t]; !ok {
+			return fmt.Errorf("cache.persistence: cache type %q can't be persisted", t)
+		}
+	}
+
+	n, err := a.loadCacheSnapshot()
+	switch {
+	case err != nil:
+		a.logger.Warn("Failed to load cache snapshot, the cache will be populated from the servers", "error", err)
+	case n > 0:
+		a.logger.Info("Restored cache entries from snapshot", "entries", n)
+	}
+
+	a.cacheSnapshotLock.Lock()
+	a.cacheSnapshotLoaded = true
+	a.cacheSnapshotLock.Unlock()
+
+	go a.runCacheSnapshots(cfg.Interval)
+	return nil
+}
+
+func (a *Agent) runCacheSnapshots(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-a.shutdownCh:
+			return
+		case <-ticker.C:
+			if err := a.saveCacheSnapshot(); err != nil {
+				a.logger.Warn("Failed to save cache snapshot", "error", err)
+			}
+		}
+	}
+}
+
+// loadCacheSnapshot restores the cache from the snapshot in the data
+// directory and returns the number of restored entries.
+func (a *Agent) loadCacheSnapshot() (int, error) {
+	raw, err := os.ReadFile(filepath.Join(a.config.DataDir, cacheSnapshotFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	aead, err := a.cacheSnapshotCipher()
+	if err != nil {
+		return 0, err
+	}
+	plaintext, err := openCacheSnapshot(aead, raw)
+	if err != nil {
+		return 0, err
+	}
+
+	var snap cacheSnapshot
+	if err := codec.NewDecoderBytes(plaintext, structs.MsgpackHandle).Decode(&snap); err != nil {
+		return 0, fmt.Errorf("failed to decode cache snapshot: %w", err)
+	}
+
+	include := make(map[string]struct{}, len(a.config.CachePersistence.Types))
+	for _, t := range a.config.CachePersistence.Types {
+		include[t] = struct{}{}
+	}
+
+	entries := make([]cache.SnapshotEntry, 0, len(snap.Entries))
+	for _, e := range snap.Entries {
+		// Skip types that are no longer configured to be persisted.
+		if _, ok := include[e.Type]; !ok {
+			continue
+		}
+		typ := cacheSnapshotTypes[e.Type]
+
+		value := typ.newValue()
+		if err := codec.NewDecoderBytes(e.Value, structs.MsgpackHandle).Decode(value); err != nil {
+			a.logger.Warn("Failed to decode cache snapshot entry", "type", e.Type, "error", err)
+			continue
+		}
+		var state interface{}
+		if typ.state != nil {
+			if state, err = typ.state(value); err != nil {
+				a.logger.Warn("Failed to restore cache snapshot entry", "type", e.Type, "error", err)
+				continue
+			}
+		}
+
+		entries = append(entries, cache.SnapshotEntry{
+			Type:       e.Type,
+			Datacenter: e.Datacenter,
+			PeerName:   e.PeerName,
+			Token:      e.Token,
+			Key:        e.Key,
+			Value:      value,
+			State:      state,
+			Index:      e.Index,
+			FetchedAt:  e.FetchedAt,
+		})
+	}
+
+	return a.cache.Restore(entries), nil
+}
+
+// saveCacheSnapshot writes the configured cache types to an encrypted
+// snapshot in the data directory.
+func (a *Agent) saveCacheSnapshot() error {
+	if !a.config.CachePersistence.Enabled {
+		return nil
+	}
+
+	a.cacheSnapshotLock.Lock()
+	defer a.cacheSnapshotLock.Unlock()
+
+	if !a.cacheSnapshotLoaded {
+		return nil
+	}
+
+	var snap cacheSnapshot
+	for _, e := range a.cache.Snapshot(a.config.CachePersistence.Types...) {
+		var value []byte
+		if err := codec.NewEncoderBytes(&value, structs.MsgpackHandle).Encode(e.Value); err != nil {
+			return fmt.Errorf("failed to encode %s cache entry: %w", e.Type, err)
+		}
+		snap.Entries = append(snap.Entries, cacheSnapshotEntry{
+			Type:       e.Type,
+			Datacenter: e.Datacenter,
+			PeerName:   e.PeerName,
+			Token:      e.Token,
+			Key:        e.Key,
+			Index:      e.Index,
+			FetchedAt:  e.FetchedAt,
+			Value:      value,
+		})
+	}
+
+	var plaintext []byte
+	if err := codec.NewEncoderBytes(&plaintext, structs.MsgpackHandle).Encode(&snap); err != nil {
+		return fmt.Errorf("failed to encode cache snapshot: %w", err)
+	}
+
+	aead, err := a.cacheSnapshotCipher()
+	if err != nil {
+		return err
+	}
+	sealed, err := sealCacheSnapshot(aead, plaintext)
+	if err != nil {
+		return err
+	}
+
+	// The snapshot contains ACL tokens so it must only be readable by the
+	// agent, even though it is encrypted.
+	return file.WriteAtomicWithPerms(filepath.Join(a.config.DataDir, cacheSnapshotFile), sealed, 0700, 0600)
+}
+
+// cacheSnapshotCipher returns the cipher used to encrypt the snapshot, using
+// a key derived from cache.persistence.encryption_key or the gossip key.
+func (a *Agent) cacheSnapshotCipher() (cipher.AEAD, error) {
+	encoded := a.config.CachePersistence.EncryptionKey
+	if encoded == "" {
+		encoded = a.config.EncryptKey
+	}
+	if encoded == "" {
+		return nil, fmt.Errorf("no encryption key configured for the cache snapshot")
+	}
+	secret, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cache snapshot encryption key: %w", err)
+	}
+
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, nil, cacheSnapshotInfo), key); err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealCacheSnapshot encrypts a snapshot. The result is the format version,
+// followed by the nonce and the ciphertext.
+func sealCacheSnapshot(aead cipher.AEAD, plaintext []byte) ([]byte, error) {
+	header := []byte{cacheSnapshotVersion}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	out := append(header, nonce...)
+	return aead.Seal(out, nonce, plaintext, header), nil
+}
+
+// openCacheSnapshot decrypts a snapshot written by sealCacheSnapshot.
+func openCacheSnapshot(aead cipher.AEAD, sealed []byte) ([]byte, error) {
+	if len(sealed) < 1+aead.NonceSize() {
+		return nil, fmt.Errorf("cache snapshot is truncated")
+	}
+	if sealed[0] != cacheSnapshotVersion {
+		return nil, fmt.Errorf("unsupported cache snapshot version %d", sealed[0])
+	}
+
+	header, nonce, ciphertext := sealed[:1], sealed[1:1+aead.NonceSize()], sealed[1+aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, header)
+	if err != nil {
+		// This is expected if the encryption key was changed.
+		return nil, fmt.Errorf("failed to decrypt cache snapshot: %w", err)
+	}
+	return plaintext, nil
+}
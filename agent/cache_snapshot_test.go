@@ -0,0 +1,136 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package agent
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	cachetype "github.com/hashicorp/consul/agent/cache-types"
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/testrpc"
+)
+
+func TestAgent_CacheSnapshot(t *testing.T) {
+	if testing.Short() {
+		t.Skip("too slow for testing.Short")
+	}
+
+	t.Parallel()
+
+	cfg := `
+		cache {
+			persistence {
+				enabled = true
+				types = ["connect-ca-root", "connect-ca-leaf"]
+				encryption_key = "Y4QTLp7xQZgMRqPhgdnmk4gR+y1hKxZHVd9Myaxd4cE="
+			}
+		}
+	`
+	a := StartTestAgent(t, TestAgent{HCL: cfg})
+	defer a.Shutdown()
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+	ctx := context.Background()
+	rootsReq := &structs.DCSpecificRequest{Datacenter: "dc1"}
+	leafReq := &cachetype.ConnectCALeafRequest{Datacenter: "dc1", Service: "web"}
+
+	raw, _, err := a.cache.Get(ctx, cachetype.ConnectCARootName, rootsReq)
+	require.NoError(t, err)
+	roots := raw.(*structs.IndexedCARoots)
+	raw, _, err = a.cache.Get(ctx, cachetype.ConnectCALeafName, leafReq)
+	require.NoError(t, err)
+	leaf := raw.(*structs.IssuedCert)
+
+	// The snapshot is written on shutdown.
+	futureHCL := cfg + `
+		node_id = "` + string(a.Config.NodeID) + `"
+		node_name = "` + a.Config.NodeName + `"
+	`
+	require.NoError(t, a.Shutdown())
+
+	path := filepath.Join(a.DataDir, cacheSnapshotFile)
+	fi, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.False(t, strings.Contains(string(contents), leaf.CertPEM), "snapshot is not encrypted")
+
+	a2 := StartTestAgent(t, TestAgent{HCL: futureHCL, DataDir: a.DataDir})
+	defer a2.Shutdown()
+
+	// Restored entries are served from the cache without going to the
+	// servers, and the leaf certificate isn't reissued.
+	raw, meta, err := a2.cache.Get(ctx, cachetype.ConnectCARootName, rootsReq)
+	require.NoError(t, err)
+	require.True(t, meta.Hit)
+	require.Equal(t, roots.ActiveRootID, raw.(*structs.IndexedCARoots).ActiveRootID)
+
+	raw, meta, err = a2.cache.Get(ctx, cachetype.ConnectCALeafName, leafReq)
+	require.NoError(t, err)
+	require.True(t, meta.Hit)
+	require.Equal(t, leaf.CertPEM, raw.(*structs.IssuedCert).CertPEM)
+}
+
+func TestAgent_CacheSnapshot_UnsupportedType(t *testing.T) {
+	if testing.Short() {
+		t.Skip("too slow for testing.Short")
+	}
+
+	t.Parallel()
+
+	a := TestAgent{HCL: `
+		cache {
+			persistence {
+				enabled = true
+				types = ["catalog-datacenters"]
+				encryption_key = "Y4QTLp7xQZgMRqPhgdnmk4gR+y1hKxZHVd9Myaxd4cE="
+			}
+		}
+	`}
+	err := a.Start(t)
+	require.ErrorContains(t, err, `cache.persistence: cache type "catalog-datacenters" can't be persisted`)
+}
+
+func TestCacheSnapshot_SealOpen(t *testing.T) {
+	newAEAD := func(key byte) cipher.AEAD {
+		block, err := aes.NewCipher(make([]byte, 32))
+		require.NoError(t, err)
+		if key != 0 {
+			k := make([]byte, 32)
+			k[0] = key
+			block, err = aes.NewCipher(k)
+			require.NoError(t, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		require.NoError(t, err)
+		return aead
+	}
+
+	sealed, err := sealCacheSnapshot(newAEAD(0), []byte("hello"))
+	require.NoError(t, err)
+	require.Equal(t, cacheSnapshotVersion, sealed[0])
+
+	plaintext, err := openCacheSnapshot(newAEAD(0), sealed)
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(plaintext))
+
+	_, err = openCacheSnapshot(newAEAD(1), sealed)
+	require.ErrorContains(t, err, "failed to decrypt cache snapshot")
+
+	_, err = openCacheSnapshot(newAEAD(0), sealed[:5])
+	require.EqualError(t, err, "cache snapshot is truncated")
+
+	sealed[0] = 2
+	_, err = openCacheSnapshot(newAEAD(0), sealed)
+	require.EqualError(t, err, "unsupported cache snapshot version 2")
+}
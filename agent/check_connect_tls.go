@@ -0,0 +1,180 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package agent
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/consul/acl"
+	cachetype "github.com/hashicorp/consul/agent/cache-types"
+	"github.com/hashicorp/consul/agent/connect"
+	"github.com/hashicorp/consul/agent/structs"
+)
+
+// connectCheckTLSTimeout bounds how long a check handshake will wait on the
+// agent cache for the service's leaf certificate or the Connect CA roots.
+const connectCheckTLSTimeout = 10 * time.Second
+
+// connectCheckTLSConfig returns the TLS client configuration used by HTTP and
+// gRPC checks that set UseConnectTLS. The check presents the agent's cached
+// Connect leaf certificate for the service's identity and verifies that the
+// target presents a certificate for the same service, signed by the Connect
+// CA roots. Certificates are fetched from the cache on every handshake so the
+// check always uses the current leaf after rotation.
+func (a *Agent) connectCheckTLSConfig(service *structs.NodeService, chkType *structs.CheckType) (*tls.Config, error) {
+	if service == nil {
+		return nil, fmt.Errorf("UseConnectTLS is only supported for checks associated with a service")
+	}
+	if service.Kind != structs.ServiceKindTypical {
+		return nil, fmt.Errorf("UseConnectTLS is not supported for checks of %q services", service.Kind)
+	}
+
+	sid := service.CompoundServiceID()
+	svc := &connectCheckTLS{
+		agent:   a,
+		service: service.Service,
+		token: func() string {
+			if token := a.State.ServiceToken(sid); token != "" {
+				return token
+			}
+			return a.tokens.UserToken()
+		},
+		entMeta: service.EnterpriseMeta,
+	}
+
+	return &tls.Config{
+		ServerName:           chkType.TLSServerName,
+		GetClientCertificate: svc.getClientCertificate,
+		// The standard hostname verification doesn't apply to Connect
+		// certificates, so the chain and SPIFFE identity are verified against
+		// the Connect roots in VerifyPeerCertificate instead.
+		InsecureSkipVerify: true,
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			if chkType.TLSSkipVerify {
+				return nil
+			}
+			return svc.verifyPeerCertificate(rawCerts)
+		},
+	}, nil
+}
+
+// connectCheckTLS fetches Connect certificates from the agent cache on behalf
+// of a single service's checks.
+type connectCheckTLS struct {
+	agent   *Agent
+	service string
+	token   func() string
+	entMeta acl.EnterpriseMeta
+}
+
+func (c *connectCheckTLS) getClientCertificate(info *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	ctx, cancel := context.WithTimeout(info.Context(), connectCheckTLSTimeout)
+	defer cancel()
+
+	req := &cachetype.ConnectCALeafRequest{
+		Datacenter:     c.agent.config.Datacenter,
+		Token:          c.token(),
+		Service:        c.service,
+		EnterpriseMeta: c.entMeta,
+	}
+	raw, _, err := c.agent.cache.Get(ctx, cachetype.ConnectCALeafName, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch leaf certificate for service %q: %w", c.service, err)
+	}
+	leaf, ok := raw.(*structs.IssuedCert)
+	if !ok {
+		return nil, fmt.Errorf("internal error: leaf certificate response type not correct")
+	}
+
+	cert, err := tls.X509KeyPair([]byte(leaf.CertPEM), []byte(leaf.PrivateKeyPEM))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse leaf certificate for service %q: %w", c.service, err)
+	}
+	return &cert, nil
+}
+
+func (c *connectCheckTLS) verifyPeerCertificate(rawCerts [][]byte) error {
+	if len(rawCerts) == 0 {
+		return errors.New("peer did not present a certificate")
+	}
+
+	certs := make([]*x509.Certificate, 0, len(rawCerts))
+	for _, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return fmt.Errorf("failed to parse peer certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), connectCheckTLSTimeout)
+	defer cancel()
+
+	req := &structs.DCSpecificRequest{
+		Datacenter:   c.agent.config.Datacenter,
+		QueryOptions: structs.QueryOptions{Token: c.token()},
+	}
+	raw, _, err := c.agent.cache.Get(ctx, cachetype.ConnectCARootName, req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch Connect CA roots: %w", err)
+	}
+	roots, ok := raw.(*structs.IndexedCARoots)
+	if !ok {
+		return fmt.Errorf("internal error: CA roots response type not correct")
+	}
+
+	pool := x509.NewCertPool()
+	for _, root := range roots.Roots {
+		pool.AppendCertsFromPEM([]byte(root.RootCert))
+		for _, intermediate := range root.IntermediateCerts {
+			pool.AppendCertsFromPEM([]byte(intermediate))
+		}
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err = certs[0].Verify(x509.VerifyOptions{
+		Roots:         pool,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return fmt.Errorf("peer certificate is not signed by the Connect CA: %w", err)
+	}
+
+	return c.verifyPeerIdentity(certs[0], roots.TrustDomain)
+}
+
+// verifyPeerIdentity ensures the peer's SPIFFE ID matches the service being
+// checked. The trust domain is overridden with the one in the certificate,
+// like Connect native clients do, so checks keep passing during a migration
+// between trust domains.
+func (c *connectCheckTLS) verifyPeerIdentity(cert *x509.Certificate, trustDomain string) error {
+	if len(cert.URIs) == 0 {
+		return errors.New("peer certificate has no SPIFFE ID")
+	}
+	got := cert.URIs[0]
+
+	expected := (&connect.SpiffeIDService{
+		Host:       trustDomain,
+		Partition:  c.entMeta.PartitionOrDefault(),
+		Namespace:  c.entMeta.NamespaceOrDefault(),
+		Datacenter: c.agent.config.Datacenter,
+		Service:    c.service,
+	}).URI()
+	expected.Host = got.Host
+
+	if !strings.EqualFold(got.String(), expected.String()) {
+		return fmt.Errorf("peer certificate mismatch got %s, want %s", got, expected)
+	}
+	return nil
+}
@@ -973,6 +973,7 @@ func (b *builder) build() (rt RuntimeConfig, err error) {
 				c.Cache.EntryFetchMaxBurst, cache.DefaultEntryFetchMaxBurst,
 			),
 		},
+		CachePersistence:                       b.cachePersistenceVal(c.Cache.Persistence),
 		AutoReloadConfig:                       boolVal(c.AutoReloadConfig),
 		CheckUpdateInterval:                    b.durationVal("check_update_interval", c.CheckUpdateInterval),
 		CheckOutputMaxSize:                     intValWithDefault(c.CheckOutputMaxSize, 4096),
@@ -1064,12 +1065,14 @@ func (b *builder) build() (rt RuntimeConfig, err error) {
 		RaftSnapshotInterval:              b.durationVal("raft_snapshot_interval", c.RaftSnapshotInterval),
 		RaftTrailingLogs:                  intVal(c.RaftTrailingLogs),
 		RaftLogStoreConfig:                b.raftLogStoreConfigVal(&c.RaftLogStore),
+		RaftLeaderHealthConfig:            b.raftLeaderHealthConfigVal(&c.RaftLeaderHealth),
 		ReconnectTimeoutLAN:               b.durationVal("reconnect_timeout", c.ReconnectTimeoutLAN),
 		ReconnectTimeoutWAN:               b.durationVal("reconnect_timeout_wan", c.ReconnectTimeoutWAN),
 		RejoinAfterLeave:                  boolVal(c.RejoinAfterLeave),
 		RequestLimitsMode:                 b.requestsLimitsModeVal(stringVal(c.Limits.RequestLimits.Mode)),
 		RequestLimitsReadRate:             limitVal(c.Limits.RequestLimits.ReadRate),
 		RequestLimitsWriteRate:            limitVal(c.Limits.RequestLimits.WriteRate),
+		RequestConcurrencyLimits:          b.requestConcurrencyLimitsVal(&c.Limits.ConcurrencyLimits),
 		RetryJoinIntervalLAN:              b.durationVal("retry_interval", c.RetryJoinIntervalLAN),
 		RetryJoinIntervalWAN:              b.durationVal("retry_interval_wan", c.RetryJoinIntervalWAN),
 		RetryJoinLAN:                      b.expandAllOptionalAddrs("retry_join", c.RetryJoinLAN),
@@ -1356,6 +1359,23 @@ func (b *builder) validate(rt RuntimeConfig) error {
 			return fmt.Errorf("encrypt has invalid key: %s", err)
 		}
 	}
+	if rt.CachePersistence.Enabled {
+		if rt.CachePersistence.EncryptionKey == "" && rt.EncryptKey == "" {
+			return fmt.Errorf("cache.persistence requires either cache.persistence.encryption_key or encrypt to be set")
+		}
+		if key := rt.CachePersistence.EncryptionKey; key != "" {
+			k, err := decodeBytes(key)
+			if err != nil {
+				return fmt.Errorf("cache.persistence.encryption_key is invalid: %s", err)
+			}
+			if l := len(k); l != 16 && l != 24 && l != 32 {
+				return fmt.Errorf("cache.persistence.encryption_key must be 16, 24 or 32 bytes, was: %d", l)
+			}
+		}
+		if rt.CachePersistence.Interval <= 0 {
+			return fmt.Errorf("cache.persistence.interval must be strictly positive, was: %s", rt.CachePersistence.Interval)
+		}
+	}
 
 	if rt.ConnectMeshGatewayWANFederationEnabled && !rt.ServerMode {
 		return fmt.Errorf("'connect.enable_mesh_gateway_wan_federation = true' requires 'server = true'")
@@ -1408,6 +1428,41 @@ func (b *builder) validate(rt RuntimeConfig) error {
 		}
 	}
 
+	if cl := rt.RequestConcurrencyLimits; cl.Mode != consulrate.ModeDisabled {
+		classes := map[string]consulrate.ConcurrencyClassConfig{
+			"read":     cl.Read,
+			"write":    cl.Write,
+			"blocking": cl.Blocking,
+		}
+		for _, class := range []string{"read", "write", "blocking"} {
+			c := classes[class]
+			if c.MinLimit <= 0 {
+				return fmt.Errorf("limits.concurrency_limits.%s.min_limit must be strictly positive, was: %d", class, c.MinLimit)
+			}
+			if c.InitialLimit < c.MinLimit || c.InitialLimit > c.MaxLimit {
+				return fmt.Errorf("limits.concurrency_limits.%s.initial_limit must be between min_limit and max_limit, was: %d", class, c.InitialLimit)
+			}
+			if class != "blocking" && c.LatencyTarget <= 0 {
+				return fmt.Errorf("limits.concurrency_limits.%s.latency_target must be strictly positive, was: %s", class, c.LatencyTarget)
+			}
+		}
+	}
+
+	if lh := rt.RaftLeaderHealthConfig; lh.Enabled {
+		if lh.FSMApplyThreshold < 0 || lh.StoreLogsThreshold < 0 || lh.CommitThreshold < 0 {
+			return fmt.Errorf("raft_leader_health thresholds cannot be negative")
+		}
+		if lh.FSMApplyThreshold == 0 && lh.StoreLogsThreshold == 0 && lh.CommitThreshold == 0 {
+			return fmt.Errorf("raft_leader_health requires at least one threshold to be set")
+		}
+		if lh.UnhealthyPeriod <= 0 {
+			return fmt.Errorf("raft_leader_health.unhealthy_period must be strictly positive, was: %s", lh.UnhealthyPeriod)
+		}
+		if lh.TransferCooldown < 0 {
+			return fmt.Errorf("raft_leader_health.transfer_cooldown cannot be negative, was: %s", lh.TransferCooldown)
+		}
+	}
+
 	inuse := map[string]string{}
 	if err := addrsUnique(inuse, "DNS", rt.DNSAddrs); err != nil {
 		// cannot happen since this is the first address
@@ -1618,6 +1673,7 @@ func (b *builder) checkVal(v *CheckDefinition) *structs.CheckDefinition {
 		GRPCUseTLS:                     boolVal(v.GRPCUseTLS),
 		TLSServerName:                  stringVal(v.TLSServerName),
 		TLSSkipVerify:                  boolVal(v.TLSSkipVerify),
+		UseConnectTLS:                  boolVal(v.UseConnectTLS),
 		AliasNode:                      stringVal(v.AliasNode),
 		AliasService:                   stringVal(v.AliasService),
 		Timeout:                        b.durationVal(fmt.Sprintf("check[%s].timeout", id), v.Timeout),
@@ -1823,6 +1879,30 @@ func (b *builder) requestsLimitsModeVal(v string) consulrate.Mode {
 	return out
 }
 
+func (b *builder) requestConcurrencyLimitsVal(raw *ConcurrencyLimits) consulrate.ConcurrencyConfig {
+	var cfg consulrate.ConcurrencyConfig
+
+	mode, ok := consulrate.RequestLimitsModeFromName(stringVal(raw.Mode))
+	if !ok {
+		b.err = multierror.Append(b.err, fmt.Errorf("limits.concurrency_limits.mode: invalid mode: %q", stringVal(raw.Mode)))
+	}
+	cfg.Mode = mode
+
+	cfg.Read = b.concurrencyClassLimitsVal("read", &raw.Read)
+	cfg.Write = b.concurrencyClassLimitsVal("write", &raw.Write)
+	cfg.Blocking = b.concurrencyClassLimitsVal("blocking", &raw.Blocking)
+	return cfg
+}
+
+func (b *builder) concurrencyClassLimitsVal(class string, raw *ConcurrencyClassLimits) consulrate.ConcurrencyClassConfig {
+	return consulrate.ConcurrencyClassConfig{
+		InitialLimit:  intVal(raw.InitialLimit),
+		MinLimit:      intVal(raw.MinLimit),
+		MaxLimit:      intVal(raw.MaxLimit),
+		LatencyTarget: b.durationVal("limits.concurrency_limits."+class+".latency_target", raw.LatencyTarget),
+	}
+}
+
 func (b *builder) exposeConfVal(v *ExposeConfig) structs.ExposeConfig {
 	var out structs.ExposeConfig
 	if v == nil {
@@ -2542,6 +2622,29 @@ func (b *builder) cloudConfigVal(v *CloudConfigRaw) (val hcpconfig.CloudConfig)
 	return val
 }
 
+// defaultCachePersistenceTypes are the cache types persisted when
+// cache.persistence.types isn't set. These are the types that are most
+// expensive to refetch for every agent after a rolling restart.
+var defaultCachePersistenceTypes = []string{
+	"connect-ca-root",
+	"connect-ca-leaf",
+	"compiled-discovery-chain",
+	"health-services",
+}
+
+func (b *builder) cachePersistenceVal(v CachePersistence) CachePersistenceConfig {
+	types := v.Types
+	if len(types) == 0 {
+		types = append([]string(nil), defaultCachePersistenceTypes...)
+	}
+	return CachePersistenceConfig{
+		Enabled:       boolVal(v.Enabled),
+		Types:         types,
+		Interval:      b.durationValWithDefault("cache.persistence.interval", v.Interval, time.Minute),
+		EncryptionKey: stringVal(v.EncryptionKey),
+	}
+}
+
 // decodeBytes returns the encryption key decoded.
 func decodeBytes(key string) ([]byte, error) {
 	return base64.StdEncoding.DecodeString(key)
@@ -2752,3 +2855,16 @@ func (b *builder) raftLogStoreConfigVal(raw *RaftLogStoreRaw) consul.RaftLogStor
 	}
 	return cfg
 }
+
+func (b *builder) raftLeaderHealthConfigVal(raw *RaftLeaderHealthRaw) consul.RaftLeaderHealthConfig {
+	var cfg consul.RaftLeaderHealthConfig
+	if raw != nil {
+		cfg.Enabled = boolVal(raw.Enabled)
+		cfg.FSMApplyThreshold = b.durationVal("raft_leader_health.fsm_apply_threshold", raw.FSMApplyThreshold)
+		cfg.StoreLogsThreshold = b.durationVal("raft_leader_health.store_logs_threshold", raw.StoreLogsThreshold)
+		cfg.CommitThreshold = b.durationVal("raft_leader_health.commit_threshold", raw.CommitThreshold)
+		cfg.UnhealthyPeriod = b.durationVal("raft_leader_health.unhealthy_period", raw.UnhealthyPeriod)
+		cfg.TransferCooldown = b.durationVal("raft_leader_health.transfer_cooldown", raw.TransferCooldown)
+	}
+	return cfg
+}
@@ -20,9 +20,6 @@ func validateEnterpriseConfigKeys(config *Config) []error {
 		result = append(result, enterpriseConfigKeyError{key: k})
 	}
 
-	if config.ReadReplica != nil {
-		add(`read_replica (or the deprecated non_voting_server)`)
-	}
 	if stringVal(config.SegmentName) != "" {
 		add("segment")
 	}
@@ -32,15 +29,6 @@ func validateEnterpriseConfigKeys(config *Config) []error {
 	if stringVal(config.Partition) != "" {
 		add("partition")
 	}
-	if stringVal(config.Autopilot.RedundancyZoneTag) != "" {
-		add("autopilot.redundancy_zone_tag")
-	}
-	if stringVal(config.Autopilot.UpgradeVersionTag) != "" {
-		add("autopilot.upgrade_version_tag")
-	}
-	if config.Autopilot.DisableUpgradeMigration != nil {
-		add("autopilot.disable_upgrade_migration")
-	}
 	if config.DNS.PreferNamespace != nil {
 		add("dns_config.prefer_namespace")
 		config.DNS.PreferNamespace = nil
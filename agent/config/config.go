@@ -122,6 +122,17 @@ type Cache struct {
 	EntryFetchMaxBurst *int `mapstructure:"entry_fetch_max_burst"`
 	// EntryFetchRate represents the max calls/sec for a single cache entry
 	EntryFetchRate *float64 `mapstructure:"entry_fetch_rate"`
+	// Persistence configures saving cache entries to disk so they survive
+	// agent restarts.
+	Persistence CachePersistence `mapstructure:"persistence"`
+}
+
+// CachePersistence configures the on-disk snapshot of the agent cache.
+type CachePersistence struct {
+	Enabled       *bool    `mapstructure:"enabled"`
+	Types         []string `mapstructure:"types"`
+	Interval      *string  `mapstructure:"interval"`
+	EncryptionKey *string  `mapstructure:"encryption_key"`
 }
 
 // Config defines the format of a configuration file in either JSON or
@@ -254,6 +265,8 @@ type Config struct {
 
 	RaftLogStore RaftLogStoreRaw `mapstructure:"raft_logstore" json:"raft_logstore,omitempty"`
 
+	RaftLeaderHealth RaftLeaderHealthRaw `mapstructure:"raft_leader_health" json:"raft_leader_health,omitempty"`
+
 	// UseStreamingBackend instead of blocking queries for service health and
 	// any other endpoints which support streaming.
 	UseStreamingBackend *bool `mapstructure:"use_streaming_backend" json:"-"`
@@ -429,6 +442,7 @@ type CheckDefinition struct {
 	GRPCUseTLS                     *bool               `mapstructure:"grpc_use_tls"`
 	TLSServerName                  *string             `mapstructure:"tls_server_name"`
 	TLSSkipVerify                  *bool               `mapstructure:"tls_skip_verify" alias:"tlsskipverify"`
+	UseConnectTLS                  *bool               `mapstructure:"use_connect_tls"`
 	AliasNode                      *string             `mapstructure:"alias_node"`
 	AliasService                   *string             `mapstructure:"alias_service"`
 	Timeout                        *string             `mapstructure:"timeout"`
@@ -730,17 +744,32 @@ type RequestLimits struct {
 	WriteRate *float64 `mapstructure:"write_rate"`
 }
 
+type ConcurrencyLimits struct {
+	Mode     *string                `mapstructure:"mode"`
+	Read     ConcurrencyClassLimits `mapstructure:"read"`
+	Write    ConcurrencyClassLimits `mapstructure:"write"`
+	Blocking ConcurrencyClassLimits `mapstructure:"blocking"`
+}
+
+type ConcurrencyClassLimits struct {
+	InitialLimit  *int    `mapstructure:"initial_limit"`
+	MinLimit      *int    `mapstructure:"min_limit"`
+	MaxLimit      *int    `mapstructure:"max_limit"`
+	LatencyTarget *string `mapstructure:"latency_target"`
+}
+
 type Limits struct {
-	HTTPMaxConnsPerClient *int          `mapstructure:"http_max_conns_per_client"`
-	HTTPSHandshakeTimeout *string       `mapstructure:"https_handshake_timeout"`
-	RequestLimits         RequestLimits `mapstructure:"request_limits"`
-	RPCClientTimeout      *string       `mapstructure:"rpc_client_timeout"`
-	RPCHandshakeTimeout   *string       `mapstructure:"rpc_handshake_timeout"`
-	RPCMaxBurst           *int          `mapstructure:"rpc_max_burst"`
-	RPCMaxConnsPerClient  *int          `mapstructure:"rpc_max_conns_per_client"`
-	RPCRate               *float64      `mapstructure:"rpc_rate"`
-	KVMaxValueSize        *uint64       `mapstructure:"kv_max_value_size"`
-	TxnMaxReqLen          *uint64       `mapstructure:"txn_max_req_len"`
+	HTTPMaxConnsPerClient *int              `mapstructure:"http_max_conns_per_client"`
+	HTTPSHandshakeTimeout *string           `mapstructure:"https_handshake_timeout"`
+	RequestLimits         RequestLimits     `mapstructure:"request_limits"`
+	ConcurrencyLimits     ConcurrencyLimits `mapstructure:"concurrency_limits"`
+	RPCClientTimeout      *string           `mapstructure:"rpc_client_timeout"`
+	RPCHandshakeTimeout   *string           `mapstructure:"rpc_handshake_timeout"`
+	RPCMaxBurst           *int              `mapstructure:"rpc_max_burst"`
+	RPCMaxConnsPerClient  *int              `mapstructure:"rpc_max_conns_per_client"`
+	RPCRate               *float64          `mapstructure:"rpc_rate"`
+	KVMaxValueSize        *uint64           `mapstructure:"kv_max_value_size"`
+	TxnMaxReqLen          *uint64           `mapstructure:"txn_max_req_len"`
 }
 
 type Segment struct {
@@ -960,6 +989,15 @@ type RaftWALConfigRaw struct {
 	SegmentSizeMB *int `mapstructure:"segment_size_mb" json:"segment_size_mb,omitempty"`
 }
 
+type RaftLeaderHealthRaw struct {
+	Enabled            *bool   `mapstructure:"enabled" json:"enabled,omitempty"`
+	FSMApplyThreshold  *string `mapstructure:"fsm_apply_threshold" json:"fsm_apply_threshold,omitempty"`
+	StoreLogsThreshold *string `mapstructure:"store_logs_threshold" json:"store_logs_threshold,omitempty"`
+	CommitThreshold    *string `mapstructure:"commit_threshold" json:"commit_threshold,omitempty"`
+	UnhealthyPeriod    *string `mapstructure:"unhealthy_period" json:"unhealthy_period,omitempty"`
+	TransferCooldown   *string `mapstructure:"transfer_cooldown" json:"transfer_cooldown,omitempty"`
+}
+
 type License struct {
 	Enabled *bool `mapstructure:"enabled"`
 }
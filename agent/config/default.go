@@ -105,6 +105,26 @@ func DefaultSource() Source {
 				read_rate = -1
 				write_rate = -1
 			}
+			concurrency_limits = {
+				mode = "disabled"
+				read = {
+					initial_limit = 512
+					min_limit = 32
+					max_limit = 4096
+					latency_target = "250ms"
+				}
+				write = {
+					initial_limit = 256
+					min_limit = 16
+					max_limit = 2048
+					latency_target = "1s"
+				}
+				blocking = {
+					initial_limit = 8192
+					min_limit = 1024
+					max_limit = 65536
+				}
+			}
 			rpc_handshake_timeout = "5s"
 			rpc_client_timeout = "60s"
 			rpc_rate = -1
@@ -149,6 +169,13 @@ func DefaultSource() Source {
 				segment_size_mb = 64
 			}
 		}
+		raft_leader_health {
+			fsm_apply_threshold = "250ms"
+			store_logs_threshold = "500ms"
+			commit_threshold = "1s"
+			unhealthy_period = "30s"
+			transfer_cooldown = "5m"
+		}
 		xds {
 			update_max_per_second = 250
 		}
@@ -117,7 +117,7 @@ type RuntimeConfig struct {
 
 	// AutopilotDisableUpgradeMigration will disable Autopilot's upgrade migration
 	// strategy of waiting until enough newer-versioned servers have been added to the
-	// cluster before promoting them to voters. (Enterprise-only)
+	// cluster before promoting them to voters.
 	//
 	// hcl: autopilot { disable_upgrade_migration = (true|false)
 	AutopilotDisableUpgradeMigration bool
@@ -142,7 +142,6 @@ type RuntimeConfig struct {
 
 	// AutopilotRedundancyZoneTag is the Meta tag to use for separating servers
 	// into zones for redundancy. If left blank, this feature will be disabled.
-	// (Enterprise-only)
 	//
 	// hcl: autopilot { redundancy_zone_tag = string }
 	AutopilotRedundancyZoneTag string
@@ -157,8 +156,6 @@ type RuntimeConfig struct {
 	// AutopilotUpgradeVersionTag is the node tag to use for version info when
 	// performing upgrade migrations. If left blank, the Consul version will be used.
 	//
-	// (Enterprise-only)
-	//
 	// hcl: autopilot { upgrade_version_tag = string }
 	AutopilotUpgradeVersionTag string
 
@@ -405,6 +402,13 @@ type RuntimeConfig struct {
 	// Cache represent cache configuration of agent
 	Cache cache.Options
 
+	// CachePersistence configures periodically saving selected cache types to
+	// an encrypted snapshot in the data directory, which is loaded on start so
+	// the agent can serve them as stale while it refreshes them.
+	//
+	// hcl: cache { persistence { ... } }
+	CachePersistence CachePersistenceConfig
+
 	// CheckUpdateInterval controls the interval on which the output of a health check
 	// is updated if there is no change to the state. For example, a check in a steady
 	// state may run every 5 second generating a unique output (timestamp, etc), forcing
@@ -831,7 +835,7 @@ type RuntimeConfig struct {
 	NodeMeta map[string]string
 
 	// ReadReplica is whether this server will act as a non-voting member
-	// of the cluster to help provide read scalability. (Enterprise-only)
+	// of the cluster to help provide read scalability.
 	//
 	// hcl: non_voting_server = (true|false)
 	// flag: -non-voting-server
@@ -985,6 +989,13 @@ type RuntimeConfig struct {
 
 	RaftLogStoreConfig consul.RaftLogStoreConfig
 
+	// RaftLeaderHealthConfig configures the leader to transfer leadership to
+	// the healthiest voter when its FSM apply, log store or commit times
+	// exceed the thresholds for the unhealthy period.
+	//
+	// hcl: raft_leader_health { ... }
+	RaftLeaderHealthConfig consul.RaftLeaderHealthConfig
+
 	// ReconnectTimeoutLAN specifies the amount of time to wait to reconnect with
 	// another agent before deciding it's permanently gone. This can be used to
 	// control the time it takes to reap failed nodes from the cluster.
@@ -1046,6 +1057,14 @@ type RuntimeConfig struct {
 	// hcl: limits { request_limits { write_rate = (float64|MaxFloat64) } }
 	RequestLimitsWriteRate rate.Limit
 
+	// RequestConcurrencyLimits configures the adaptive limits on the number of
+	// reads, writes and blocking queries the servers handle concurrently. The
+	// limits decrease when the requests are slower than the latency targets,
+	// and the stale reads are shed first.
+	//
+	// hcl: limits { concurrency_limits { mode = "enforcing" read { ... } write { ... } blocking { ... } } }
+	RequestConcurrencyLimits consulrate.ConcurrencyConfig
+
 	// RetryJoinIntervalLAN specifies the amount of time to wait in between join
 	// attempts on agent start. The minimum allowed value is 1 second and
 	// the default is 30s.
@@ -1515,6 +1534,30 @@ type AutoConfigAuthorizer struct {
 	AllowReuse      bool
 }
 
+type CachePersistenceConfig struct {
+	// Enabled turns on saving and restoring the cache snapshot.
+	//
+	// hcl: cache { persistence { enabled = (true|false) } }
+	Enabled bool
+
+	// Types are the names of the cache types to persist.
+	//
+	// hcl: cache { persistence { types = []string } }
+	Types []string
+
+	// Interval is how often the snapshot is written. It is also written when
+	// the agent shuts down.
+	//
+	// hcl: cache { persistence { interval = "duration" } }
+	Interval time.Duration
+
+	// EncryptionKey is the base64 encoded AES key used to encrypt the
+	// snapshot. It defaults to the gossip encryption key.
+	//
+	// hcl: cache { persistence { encryption_key = string } }
+	EncryptionKey string
+}
+
 type UIConfig struct {
 	Enabled                    bool
 	Dir                        string
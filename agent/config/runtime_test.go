@@ -653,7 +653,6 @@ func TestLoad_IntegrationWithFlags(t *testing.T) {
 			rt.ReadReplica = true
 			rt.DataDir = dataDir
 		},
-		expectedWarnings: enterpriseReadReplicaWarnings,
 	})
 	run(t, testCase{
 		desc: "-pid-file",
@@ -2407,6 +2406,37 @@ func TestLoad_IntegrationWithFlags(t *testing.T) {
 		hcl:         []string{` encrypt = "this is not a valid key" `},
 		expectedErr: "encrypt has invalid key: illegal base64 data at input byte 4",
 	})
+	run(t, testCase{
+		desc: "cache.persistence requires an encryption key",
+		args: []string{
+			`-data-dir=` + dataDir,
+		},
+		json:        []string{`{ "cache": { "persistence": { "enabled": true } } }`},
+		hcl:         []string{` cache { persistence { enabled = true } } `},
+		expectedErr: "cache.persistence requires either cache.persistence.encryption_key or encrypt to be set",
+	})
+	run(t, testCase{
+		desc: "cache.persistence.encryption_key has invalid length",
+		args: []string{
+			`-data-dir=` + dataDir,
+		},
+		json:        []string{`{ "cache": { "persistence": { "enabled": true, "encryption_key": "c2hvcnQ=" } } }`},
+		hcl:         []string{` cache { persistence { enabled = true encryption_key = "c2hvcnQ=" } } `},
+		expectedErr: "cache.persistence.encryption_key must be 16, 24 or 32 bytes, was: 5",
+	})
+	run(t, testCase{
+		desc: "cache.persistence defaults to the gossip key and default types",
+		args: []string{
+			`-data-dir=` + dataDir,
+		},
+		json: []string{`{ "encrypt": "pUqJrVyVRj5jsiYEkM/tFQYfWyJIv4s3XkvDwy7Cu5s=", "cache": { "persistence": { "enabled": true } } }`},
+		hcl:  []string{` encrypt = "pUqJrVyVRj5jsiYEkM/tFQYfWyJIv4s3XkvDwy7Cu5s=" cache { persistence { enabled = true } } `},
+		expected: func(rt *RuntimeConfig) {
+			rt.DataDir = dataDir
+			rt.EncryptKey = "pUqJrVyVRj5jsiYEkM/tFQYfWyJIv4s3XkvDwy7Cu5s="
+			rt.CachePersistence.Enabled = true
+		},
+	})
 	run(t, testCase{
 		desc: "multiple check files",
 		args: []string{
@@ -4647,6 +4677,12 @@ func TestLoad_IntegrationWithFlags(t *testing.T) {
 			rt.RequestLimitsMode = consulrate.ModeDisabled
 			rt.RequestLimitsReadRate = rate.Inf
 			rt.RequestLimitsWriteRate = rate.Inf
+			rt.RequestConcurrencyLimits = consulrate.ConcurrencyConfig{
+				Mode:     consulrate.ModeDisabled,
+				Read:     consulrate.ConcurrencyClassConfig{InitialLimit: 512, MinLimit: 32, MaxLimit: 4096, LatencyTarget: 250 * time.Millisecond},
+				Write:    consulrate.ConcurrencyClassConfig{InitialLimit: 256, MinLimit: 16, MaxLimit: 2048, LatencyTarget: time.Second},
+				Blocking: consulrate.ConcurrencyClassConfig{InitialLimit: 8192, MinLimit: 1024, MaxLimit: 65536},
+			}
 			rt.SegmentLimit = 64
 			rt.XDSUpdateRateLimit = 250
 			rt.RPCRateLimit = rate.Inf
@@ -5759,6 +5795,74 @@ func TestLoad_IntegrationWithFlags(t *testing.T) {
 			rt.TLS.GRPC.UseAutoCert = false
 		},
 	})
+	run(t, testCase{
+		desc: "concurrency_limits invalid mode",
+		args: []string{
+			`-data-dir=` + dataDir,
+		},
+		json:        []string{`{ "limits": { "concurrency_limits": { "mode": "strict" } } }`},
+		hcl:         []string{`limits { concurrency_limits { mode = "strict" } }`},
+		expectedErr: `limits.concurrency_limits.mode: invalid mode: "strict"`,
+	})
+	run(t, testCase{
+		desc: "concurrency_limits initial limit out of bounds",
+		args: []string{
+			`-data-dir=` + dataDir,
+		},
+		json:        []string{`{ "limits": { "concurrency_limits": { "mode": "enforcing", "write": { "initial_limit": 8 } } } }`},
+		hcl:         []string{`limits { concurrency_limits { mode = "enforcing" write { initial_limit = 8 } } }`},
+		expectedErr: "limits.concurrency_limits.write.initial_limit must be between min_limit and max_limit, was: 8",
+	})
+	run(t, testCase{
+		desc: "concurrency_limits overrides the defaults of a class",
+		args: []string{
+			`-data-dir=` + dataDir,
+		},
+		json: []string{`{ "limits": { "concurrency_limits": { "mode": "permissive", "read": { "latency_target": "100ms" } } } }`},
+		hcl:  []string{`limits { concurrency_limits { mode = "permissive" read { latency_target = "100ms" } } }`},
+		expected: func(rt *RuntimeConfig) {
+			rt.DataDir = dataDir
+			rt.RequestConcurrencyLimits.Mode = consulrate.ModePermissive
+			rt.RequestConcurrencyLimits.Read.LatencyTarget = 100 * time.Millisecond
+		},
+	})
+	run(t, testCase{
+		desc: "raft_leader_health requires a threshold",
+		args: []string{
+			`-data-dir=` + dataDir,
+		},
+		json:        []string{`{ "raft_leader_health": { "enabled": true, "fsm_apply_threshold": "0s", "store_logs_threshold": "0s", "commit_threshold": "0s" } }`},
+		hcl:         []string{`raft_leader_health { enabled = true fsm_apply_threshold = "0s" store_logs_threshold = "0s" commit_threshold = "0s" }`},
+		expectedErr: "raft_leader_health requires at least one threshold to be set",
+	})
+	run(t, testCase{
+		desc: "raft_leader_health unhealthy period must be positive",
+		args: []string{
+			`-data-dir=` + dataDir,
+		},
+		json:        []string{`{ "raft_leader_health": { "enabled": true, "unhealthy_period": "0s" } }`},
+		hcl:         []string{`raft_leader_health { enabled = true unhealthy_period = "0s" }`},
+		expectedErr: "raft_leader_health.unhealthy_period must be strictly positive, was: 0s",
+	})
+	run(t, testCase{
+		desc: "raft_leader_health defaults",
+		args: []string{
+			`-data-dir=` + dataDir,
+		},
+		json: []string{`{ "raft_leader_health": { "enabled": true } }`},
+		hcl:  []string{`raft_leader_health { enabled = true }`},
+		expected: func(rt *RuntimeConfig) {
+			rt.DataDir = dataDir
+			rt.RaftLeaderHealthConfig = consul.RaftLeaderHealthConfig{
+				Enabled:            true,
+				FSMApplyThreshold:  250 * time.Millisecond,
+				StoreLogsThreshold: 500 * time.Millisecond,
+				CommitThreshold:    time.Second,
+				UnhealthyPeriod:    30 * time.Second,
+				TransferCooldown:   5 * time.Minute,
+			}
+		},
+	})
 	run(t, testCase{
 		desc: "logstore defaults",
 		args: []string{
@@ -6077,6 +6181,12 @@ func TestLoad_FullConfig(t *testing.T) {
 			EntryFetchMaxBurst: 42,
 			EntryFetchRate:     0.334,
 		},
+		CachePersistence: CachePersistenceConfig{
+			Enabled:       true,
+			Types:         []string{"connect-ca-root", "health-services"},
+			Interval:      2847 * time.Second,
+			EncryptionKey: "Y4QTLp7xQZgMRqPhgdnmk4gR+y1hKxZHVd9Myaxd4cE=",
+		},
 		CheckOutputMaxSize: checks.DefaultBufSize,
 		Checks: []*structs.CheckDefinition{
 			{
@@ -6338,6 +6448,12 @@ func TestLoad_FullConfig(t *testing.T) {
 		RequestLimitsMode:       consulrate.ModePermissive,
 		RequestLimitsReadRate:   99.0,
 		RequestLimitsWriteRate:  101.0,
+		RequestConcurrencyLimits: consulrate.ConcurrencyConfig{
+			Mode:     consulrate.ModeEnforcing,
+			Read:     consulrate.ConcurrencyClassConfig{InitialLimit: 381, MinLimit: 27, MaxLimit: 2906, LatencyTarget: 317 * time.Millisecond},
+			Write:    consulrate.ConcurrencyClassConfig{InitialLimit: 194, MinLimit: 13, MaxLimit: 1577, LatencyTarget: 1209 * time.Millisecond},
+			Blocking: consulrate.ConcurrencyClassConfig{InitialLimit: 7263, MinLimit: 829, MaxLimit: 50417},
+		},
 		RejoinAfterLeave:        true,
 		RetryJoinIntervalLAN:    8067 * time.Second,
 		RetryJoinIntervalWAN:    28866 * time.Second,
@@ -6781,6 +6897,14 @@ func TestLoad_FullConfig(t *testing.T) {
 			BoltDB: consul.RaftBoltDBConfig{NoFreelistSync: true},
 			WAL:    consul.WALConfig{SegmentSize: 15 * 1024 * 1024},
 		},
+		RaftLeaderHealthConfig: consul.RaftLeaderHealthConfig{
+			Enabled:            true,
+			FSMApplyThreshold:  1483 * time.Millisecond,
+			StoreLogsThreshold: 2719 * time.Millisecond,
+			CommitThreshold:    3906 * time.Millisecond,
+			UnhealthyPeriod:    4215 * time.Second,
+			TransferCooldown:   5377 * time.Second,
+		},
 		AutoReloadConfigCoalesceInterval: 1 * time.Second,
 	}
 	entFullRuntimeConfig(expected)
@@ -94,6 +94,12 @@ bootstrap_expect = 53
 cache = {
     entry_fetch_max_burst = 42
     entry_fetch_rate = 0.334
+    persistence = {
+        enabled = true
+        types = ["connect-ca-root", "health-services"]
+        interval = "2847s"
+        encryption_key = "Y4QTLp7xQZgMRqPhgdnmk4gR+y1hKxZHVd9Myaxd4cE="
+    }
 },
 use_streaming_backend = true
 ca_file = "erA7T0PM"
@@ -313,6 +319,26 @@ limits {
         read_rate = 99.0
         write_rate = 101.0
     }
+    concurrency_limits {
+        mode = "enforcing"
+        read {
+            initial_limit = 381
+            min_limit = 27
+            max_limit = 2906
+            latency_target = "317ms"
+        }
+        write {
+            initial_limit = 194
+            min_limit = 13
+            max_limit = 1577
+            latency_target = "1209ms"
+        }
+        blocking {
+            initial_limit = 7263
+            min_limit = 829
+            max_limit = 50417
+        }
+    }
 }
 log_level = "k1zo9Spt"
 log_json = true
@@ -370,6 +396,14 @@ raft_logstore {
        segment_size_mb = 15
     }
 }
+raft_leader_health {
+    enabled = true
+    fsm_apply_threshold = "1483ms"
+    store_logs_threshold = "2719ms"
+    commit_threshold = "3906ms"
+    unhealthy_period = "4215s"
+    transfer_cooldown = "5377s"
+}
 read_replica = true
 reconnect_timeout = "23739s"
 reconnect_timeout_wan = "26694s"
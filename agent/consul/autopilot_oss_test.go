@@ -0,0 +1,90 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:build !consulent
+// +build !consulent
+
+package consul
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	msgpackrpc "github.com/hashicorp/consul-net-rpc/net-rpc-msgpackrpc"
+	"github.com/hashicorp/raft"
+	autopilot "github.com/hashicorp/raft-autopilot"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+	"github.com/hashicorp/consul/testrpc"
+)
+
+func TestAutopilot_ReadReplicaNotPromoted(t *testing.T) {
+	if testing.Short() {
+		t.Skip("too slow for testing.Short")
+	}
+
+	t.Parallel()
+	dir1, s1 := testServerWithConfig(t, func(c *Config) {
+		c.Datacenter = "dc1"
+		c.Bootstrap = true
+		c.AutopilotConfig.ServerStabilizationTime = 200 * time.Millisecond
+		c.ServerHealthInterval = 100 * time.Millisecond
+		c.AutopilotInterval = 100 * time.Millisecond
+	})
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	dir2, s2 := testServerWithConfig(t, func(c *Config) {
+		c.Datacenter = "dc1"
+		c.Bootstrap = false
+		c.ReadReplica = true
+	})
+	defer os.RemoveAll(dir2)
+	defer s2.Shutdown()
+	joinLAN(t, s2, s1)
+
+	// Wait until the read replica has been stable for well over the
+	// stabilization time, when a regular server would have been promoted.
+	retry.Run(t, func(r *retry.R) {
+		future := s1.raft.GetConfiguration()
+		if err := future.Error(); err != nil {
+			r.Fatal(err)
+		}
+		servers := future.Configuration().Servers
+		if len(servers) != 2 {
+			r.Fatalf("bad: %v", servers)
+		}
+		health := s1.autopilot.GetServerHealth(servers[1].ID)
+		if health == nil || !health.Healthy {
+			r.Fatalf("bad: %v", health)
+		}
+		if time.Since(health.StableSince) < 3*s1.config.AutopilotConfig.ServerStabilizationTime {
+			r.Fatal("stable period not elapsed")
+		}
+	})
+
+	future := s1.raft.GetConfiguration()
+	require.NoError(t, future.Error())
+	servers := future.Configuration().Servers
+	require.Len(t, servers, 2)
+	require.Equal(t, raft.Nonvoter, servers[1].Suffrage)
+
+	state := s1.autopilot.GetState()
+	require.Equal(t, nodeTypeReadReplica, state.Servers[servers[1].ID].Server.NodeType)
+	require.Equal(t, autopilot.NodeVoter, state.Servers[servers[0].ID].Server.NodeType)
+
+	arg := structs.DCSpecificRequest{Datacenter: "dc1"}
+	var reply structs.RaftConfigurationResponse
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "Operator.RaftGetConfiguration", &arg, &reply))
+	require.Len(t, reply.Servers, 2)
+	for _, srv := range reply.Servers {
+		require.Equal(t, srv.Node == s2.config.NodeName, srv.ReadReplica, srv.Node)
+		require.Equal(t, !srv.ReadReplica, srv.Voter, srv.Node)
+	}
+}
@@ -0,0 +1,494 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:build !consulent
+// +build !consulent
+
+package consul
+
+import (
+	"sort"
+	"time"
+
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/raft"
+	autopilot "github.com/hashicorp/raft-autopilot"
+
+	"github.com/hashicorp/consul/agent/structs"
+)
+
+// Node types of the servers, in addition to autopilot.NodeVoter.
+const (
+	nodeTypeReadReplica    autopilot.NodeType = "read-replica"
+	nodeTypeZoneVoter      autopilot.NodeType = "zone-voter"
+	nodeTypeZoneExtraVoter autopilot.NodeType = "zone-extra-voter"
+	nodeTypeZoneStandby    autopilot.NodeType = "zone-standby"
+)
+
+// minZoneVoters is the number of voters kept when there are fewer redundancy
+// zones, by promoting extra voters from the standbys of the zones.
+const minZoneVoters = 3
+
+// redundancyPromoter decides which servers are voters:
+//
+//   - Read replicas are never promoted, and are demoted if they are voters
+//     because they were restarted as read replicas.
+//   - When the redundancy zone tag is configured, there is one voter per
+//     zone and the other servers of the zone are standbys, which replace the
+//     voter if it fails. Servers without a zone are all voters.
+//   - When servers of a newer version join, they are only promoted once there
+//     are enough of them to replace the voters of other versions. The
+//     servers of other versions are then demoted, and leadership is
+//     transferred last.
+//
+// Demotions only happen once there are no pending promotions, and autopilot
+// applies promotions, demotions and leadership transfers in separate rounds,
+// so servers are replaced without giving up redundancy.
+type redundancyPromoter struct{}
+
+func promoterConfig(c *autopilot.Config) *structs.AutopilotConfig {
+	if conf, ok := c.Ext.(*structs.AutopilotConfig); ok && conf != nil {
+		return conf
+	}
+	return &structs.AutopilotConfig{}
+}
+
+func serverExt(srv *autopilot.ServerState) *structs.AutopilotServerExt {
+	if ext, ok := srv.Server.Ext.(*structs.AutopilotServerExt); ok && ext != nil {
+		return ext
+	}
+	return &structs.AutopilotServerExt{}
+}
+
+func (_ *redundancyPromoter) GetServerExt(c *autopilot.Config, srv *autopilot.ServerState) interface{} {
+	conf := promoterConfig(c)
+
+	ext := &structs.AutopilotServerExt{
+		ReadReplica:    serverExt(srv).ReadReplica,
+		UpgradeVersion: srv.Server.Version,
+	}
+	if conf.RedundancyZoneTag != "" {
+		ext.RedundancyZone = srv.Server.Meta[conf.RedundancyZoneTag]
+	}
+	if v := srv.Server.Meta[conf.UpgradeVersionTag]; conf.UpgradeVersionTag != "" && v != "" {
+		ext.UpgradeVersion = v
+	}
+	return ext
+}
+
+func (p *redundancyPromoter) GetStateExt(c *autopilot.Config, s *autopilot.State) interface{} {
+	return p.plan(c, s).stateExt(s)
+}
+
+func (p *redundancyPromoter) GetNodeTypes(c *autopilot.Config, s *autopilot.State) map[raft.ServerID]autopilot.NodeType {
+	return p.plan(c, s).types
+}
+
+func (_ *redundancyPromoter) FilterFailedServerRemovals(_ *autopilot.Config, _ *autopilot.State, failed *autopilot.FailedServers) *autopilot.FailedServers {
+	return failed
+}
+
+func (p *redundancyPromoter) CalculatePromotionsAndDemotions(c *autopilot.Config, s *autopilot.State) autopilot.RaftChanges {
+	plan := p.plan(c, s)
+
+	var changes autopilot.RaftChanges
+	for _, id := range plan.ids {
+		srv := s.Servers[id]
+		if plan.voters[id] && !srv.HasVotingRights() && srv.Health.IsStable(plan.now, plan.stableTime) {
+			changes.Promotions = append(changes.Promotions, id)
+		}
+	}
+
+	// Read replicas are demoted regardless, since they never count towards
+	// the voters the cluster should have.
+	pending := plan.pendingPromotions(s)
+	for _, id := range plan.ids {
+		srv := s.Servers[id]
+		if srv.State != autopilot.RaftVoter || plan.voters[id] {
+			continue
+		}
+		if serverExt(srv).ReadReplica || !pending {
+			changes.Demotions = append(changes.Demotions, id)
+		}
+	}
+
+	// A leader that shouldn't be a voter, including a read replica, hands
+	// over leadership first, and is demoted in a later round.
+	if _, ok := s.Servers[s.Leader]; ok && !plan.voters[s.Leader] && !pending {
+		for _, id := range plan.ids {
+			srv := s.Servers[id]
+			if plan.voters[id] && srv.HasVotingRights() && srv.Health.Healthy {
+				changes.Leader = id
+				break
+			}
+		}
+	}
+	return changes
+}
+
+// promotionPlan is the role of each server decided by the promoter.
+type promotionPlan struct {
+	conf       *structs.AutopilotConfig
+	now        time.Time
+	stableTime time.Duration
+
+	// ids are the IDs of all servers, sorted.
+	ids []raft.ServerID
+
+	types  map[raft.ServerID]autopilot.NodeType
+	voters map[raft.ServerID]bool
+
+	// zones are the non read replica servers by redundancy zone, only when
+	// the redundancy zone tag is configured.
+	zones map[string][]raft.ServerID
+
+	upgrade *structs.AutopilotUpgrade
+}
+
+func (p *redundancyPromoter) plan(c *autopilot.Config, s *autopilot.State) *promotionPlan {
+	plan := &promotionPlan{
+		conf:       promoterConfig(c),
+		now:        time.Now(),
+		stableTime: s.ServerStabilizationTime(c),
+		types:      make(map[raft.ServerID]autopilot.NodeType),
+		voters:     make(map[raft.ServerID]bool),
+	}
+
+	var servers []raft.ServerID
+	for id, srv := range s.Servers {
+		plan.ids = append(plan.ids, id)
+		if serverExt(srv).ReadReplica {
+			plan.types[id] = nodeTypeReadReplica
+		} else {
+			servers = append(servers, id)
+		}
+	}
+	sort.Slice(plan.ids, func(i, j int) bool { return plan.ids[i] < plan.ids[j] })
+	sort.Slice(servers, func(i, j int) bool { return servers[i] < servers[j] })
+
+	if plan.conf.RedundancyZoneTag != "" {
+		plan.zones = make(map[string][]raft.ServerID)
+		for _, id := range servers {
+			if zone := serverExt(s.Servers[id]).RedundancyZone; zone != "" {
+				plan.zones[zone] = append(plan.zones[zone], id)
+			}
+		}
+	}
+
+	eligible := plan.upgradeEligible(s, servers)
+	if plan.zones == nil {
+		for _, id := range servers {
+			plan.types[id] = autopilot.NodeVoter
+			plan.voters[id] = eligible[id]
+		}
+	} else {
+		plan.chooseZoneVoters(s, servers, eligible)
+	}
+
+	if plan.upgrade != nil && plan.upgrade.Status == "" {
+		plan.upgrade.Status = plan.upgradeStatus(s)
+	}
+	return plan
+}
+
+// usable returns true if the server is a voter, or could be promoted to one.
+func (plan *promotionPlan) usable(srv *autopilot.ServerState) bool {
+	return srv.HasVotingRights() || srv.Health.IsStable(plan.now, plan.stableTime)
+}
+
+// pendingPromotions returns true if there are healthy servers that should be
+// voters but aren't yet.
+func (plan *promotionPlan) pendingPromotions(s *autopilot.State) bool {
+	for id := range plan.voters {
+		srv := s.Servers[id]
+		if plan.voters[id] && !srv.HasVotingRights() && srv.Health.Healthy {
+			return true
+		}
+	}
+	return false
+}
+
+// upgradeEligible returns the servers that may be voters as far as upgrade
+// migrations are concerned, and fills in the upgrade state. The servers of
+// the target version are only eligible once there are enough of them to
+// replace the voters of other versions, in every redundancy zone, and then
+// they're the only eligible servers.
+func (plan *promotionPlan) upgradeEligible(s *autopilot.State, servers []raft.ServerID) map[raft.ServerID]bool {
+	eligible := make(map[raft.ServerID]bool)
+	for _, id := range servers {
+		eligible[id] = true
+	}
+
+	versions := make(map[raft.ServerID]*version.Version)
+	var target *version.Version
+	for _, id := range plan.ids {
+		v, err := version.NewVersion(serverExt(s.Servers[id]).UpgradeVersion)
+		if err != nil {
+			continue
+		}
+		versions[id] = v
+		if target == nil || v.GreaterThan(target) {
+			target = v
+		}
+	}
+	if target == nil {
+		return eligible
+	}
+
+	isTarget := func(id raft.ServerID) bool {
+		v, ok := versions[id]
+		return ok && v.Equal(target)
+	}
+
+	u := &structs.AutopilotUpgrade{TargetVersion: target.String()}
+	plan.upgrade = u
+	others := false
+	for _, id := range plan.ids {
+		srv := s.Servers[id]
+		switch {
+		case serverExt(srv).ReadReplica && isTarget(id):
+			u.TargetVersionReadReplicas = append(u.TargetVersionReadReplicas, id)
+		case serverExt(srv).ReadReplica:
+			u.OtherVersionReadReplicas = append(u.OtherVersionReadReplicas, id)
+		case srv.HasVotingRights() && isTarget(id):
+			u.TargetVersionVoters = append(u.TargetVersionVoters, id)
+		case srv.HasVotingRights():
+			u.OtherVersionVoters = append(u.OtherVersionVoters, id)
+			others = true
+		case isTarget(id):
+			u.TargetVersionNonVoters = append(u.TargetVersionNonVoters, id)
+		default:
+			u.OtherVersionNonVoters = append(u.OtherVersionNonVoters, id)
+			others = true
+		}
+	}
+
+	if len(plan.zones) > 0 {
+		u.RedundancyZones = make(map[string]structs.AutopilotZoneUpgradeVersions)
+		for zone, ids := range plan.zones {
+			var zu structs.AutopilotZoneUpgradeVersions
+			for _, id := range ids {
+				voter := s.Servers[id].HasVotingRights()
+				switch {
+				case voter && isTarget(id):
+					zu.TargetVersionVoters = append(zu.TargetVersionVoters, id)
+				case voter:
+					zu.OtherVersionVoters = append(zu.OtherVersionVoters, id)
+				case isTarget(id):
+					zu.TargetVersionNonVoters = append(zu.TargetVersionNonVoters, id)
+				default:
+					zu.OtherVersionNonVoters = append(zu.OtherVersionNonVoters, id)
+				}
+			}
+			u.RedundancyZones[zone] = zu
+		}
+	}
+
+	switch {
+	case plan.conf.DisableUpgradeMigration:
+		u.Status = structs.AutopilotUpgradeDisabled
+		return eligible
+	case !others:
+		u.Status = structs.AutopilotUpgradeIdle
+		return eligible
+	}
+
+	// Count the voters of other versions, and the servers of the target
+	// version that could replace them, overall and in each zone.
+	voters, ready := 0, 0
+	zoneVoters := make(map[string]int)
+	zoneReady := make(map[string]int)
+	for _, id := range servers {
+		srv := s.Servers[id]
+		zone := ""
+		if plan.zones != nil {
+			zone = serverExt(srv).RedundancyZone
+		}
+		if srv.HasVotingRights() && !isTarget(id) {
+			voters++
+			zoneVoters[zone]++
+		}
+		if isTarget(id) && srv.Health.Healthy && plan.usable(srv) {
+			ready++
+			zoneReady[zone]++
+		}
+	}
+	enough := ready >= voters
+	for zone, n := range zoneVoters {
+		if zone != "" && zoneReady[zone] == 0 && n > 0 {
+			enough = false
+		}
+	}
+
+	if !enough {
+		// Servers of the target version that are already voters, such as
+		// servers upgraded in place, keep their vote. The others wait.
+		u.Status = structs.AutopilotUpgradeAwaitNewVoters
+		for _, id := range servers {
+			if isTarget(id) && !s.Servers[id].HasVotingRights() {
+				eligible[id] = false
+			}
+		}
+		return eligible
+	}
+
+	for _, id := range servers {
+		eligible[id] = isTarget(id)
+	}
+	return eligible
+}
+
+// upgradeStatus returns the status of an upgrade migration in progress, once
+// the servers of the target version are eligible.
+func (plan *promotionPlan) upgradeStatus(s *autopilot.State) structs.AutopilotUpgradeStatus {
+	if plan.pendingPromotions(s) {
+		return structs.AutopilotUpgradePromoting
+	}
+	u := plan.upgrade
+	for _, id := range u.OtherVersionVoters {
+		if id != s.Leader {
+			return structs.AutopilotUpgradeDemoting
+		}
+	}
+	if len(u.OtherVersionVoters) > 0 {
+		return structs.AutopilotUpgradeLeaderTransfer
+	}
+	if len(u.TargetVersionVoters)+len(u.TargetVersionNonVoters) < len(u.OtherVersionNonVoters) {
+		return structs.AutopilotUpgradeAwaitNewServers
+	}
+	return structs.AutopilotUpgradeAwaitServerRemoval
+}
+
+// chooseZoneVoters picks the voter of each redundancy zone among the eligible
+// servers. Extra voters are picked from the standbys of other zones to make
+// up for zones without a healthy voter, such as when a whole zone fails, and
+// to keep at least minZoneVoters voters.
+func (plan *promotionPlan) chooseZoneVoters(s *autopilot.State, servers []raft.ServerID, eligible map[raft.ServerID]bool) {
+	// want is the number of healthy voters to keep, and healthy is the
+	// number of healthy voters picked so far.
+	want, healthy := len(plan.zones), 0
+	for _, id := range servers {
+		if serverExt(s.Servers[id]).RedundancyZone == "" {
+			plan.types[id] = autopilot.NodeVoter
+			plan.voters[id] = eligible[id]
+			if eligible[id] {
+				want++
+				if s.Servers[id].Health.Healthy {
+					healthy++
+				}
+			}
+		} else {
+			plan.types[id] = nodeTypeZoneStandby
+		}
+	}
+	if want < minZoneVoters {
+		want = minZoneVoters
+	}
+
+	// rank orders the servers of a zone by how suitable they are as its
+	// voter: the leader, healthy voters, servers that can be promoted, and
+	// the remaining voters, which keep their vote until they can be
+	// replaced.
+	rank := func(srv *autopilot.ServerState) int {
+		switch {
+		case srv.State == autopilot.RaftLeader:
+			return 0
+		case srv.HasVotingRights() && srv.Health.Healthy:
+			return 1
+		case srv.Health.IsStable(plan.now, plan.stableTime):
+			return 2
+		case srv.HasVotingRights():
+			return 3
+		default:
+			return 4
+		}
+	}
+
+	var names []string
+	standbys := make(map[string][]raft.ServerID)
+	for zone, ids := range plan.zones {
+		names = append(names, zone)
+
+		var candidates []raft.ServerID
+		for _, id := range ids {
+			if eligible[id] {
+				candidates = append(candidates, id)
+			}
+		}
+		sort.SliceStable(candidates, func(i, j int) bool {
+			ri, rj := rank(s.Servers[candidates[i]]), rank(s.Servers[candidates[j]])
+			if ri != rj {
+				return ri < rj
+			}
+			return autopilot.ServerLessThan(candidates[i], candidates[j], s)
+		})
+		if len(candidates) == 0 || rank(s.Servers[candidates[0]]) == 4 {
+			continue
+		}
+		plan.types[candidates[0]] = nodeTypeZoneVoter
+		plan.voters[candidates[0]] = true
+		standbys[zone] = candidates[1:]
+		if s.Servers[candidates[0]].Health.Healthy {
+			healthy++
+		}
+	}
+	sort.Strings(names)
+
+	// Pick extra voters round robin across the zones.
+	for healthy < want {
+		added := false
+		for _, zone := range names {
+			if healthy >= want {
+				break
+			}
+			for len(standbys[zone]) > 0 {
+				id := standbys[zone][0]
+				standbys[zone] = standbys[zone][1:]
+				if srv := s.Servers[id]; srv.Health.Healthy && plan.usable(srv) {
+					plan.types[id] = nodeTypeZoneExtraVoter
+					plan.voters[id] = true
+					healthy++
+					added = true
+					break
+				}
+			}
+		}
+		if !added {
+			break
+		}
+	}
+}
+
+func (plan *promotionPlan) stateExt(s *autopilot.State) *structs.AutopilotStateExt {
+	ext := &structs.AutopilotStateExt{
+		OptimisticFailureTolerance: s.FailureTolerance,
+		Upgrade:                    plan.upgrade,
+	}
+	if plan.zones == nil {
+		return ext
+	}
+
+	ext.RedundancyZones = make(map[string]structs.AutopilotZone)
+	for name, ids := range plan.zones {
+		zone := structs.AutopilotZone{Servers: ids}
+		healthy := 0
+		for _, id := range ids {
+			srv := s.Servers[id]
+			if srv.HasVotingRights() {
+				zone.Voters = append(zone.Voters, id)
+			}
+			if srv.Health.Healthy {
+				healthy++
+			}
+		}
+		if healthy > 0 {
+			zone.FailureTolerance = healthy - 1
+		}
+		// A healthy standby can replace a voter that fails.
+		if healthy > 1 && len(zone.Voters) > 0 {
+			ext.OptimisticFailureTolerance++
+		}
+		ext.RedundancyZones[name] = zone
+	}
+	return ext
+}
@@ -0,0 +1,355 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:build !consulent
+// +build !consulent
+
+package consul
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+	autopilot "github.com/hashicorp/raft-autopilot"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/consul/agent/structs"
+)
+
+// testPromoterServer returns a healthy server that has been stable for an
+// hour.
+func testPromoterServer(id string, state autopilot.RaftState, ext structs.AutopilotServerExt) *autopilot.ServerState {
+	return &autopilot.ServerState{
+		Server: autopilot.Server{
+			ID:  raft.ServerID(id),
+			Ext: &ext,
+		},
+		State:  state,
+		Health: autopilot.ServerHealth{Healthy: true, StableSince: time.Now().Add(-time.Hour)},
+	}
+}
+
+func testPromoterState(leader string, servers ...*autopilot.ServerState) *autopilot.State {
+	state := &autopilot.State{
+		Leader:  raft.ServerID(leader),
+		Servers: make(map[raft.ServerID]*autopilot.ServerState),
+	}
+	for _, srv := range servers {
+		state.Servers[srv.Server.ID] = srv
+	}
+	return state
+}
+
+func TestRedundancyPromoter_GetServerExt(t *testing.T) {
+	t.Parallel()
+
+	p := &redundancyPromoter{}
+	srv := &autopilot.ServerState{
+		Server: autopilot.Server{
+			Version: "1.16.0",
+			Meta:    map[string]string{"zone": "a", "build": "2.0.0"},
+			Ext:     &structs.AutopilotServerExt{ReadReplica: true},
+		},
+	}
+
+	require.Equal(t, &structs.AutopilotServerExt{
+		ReadReplica:    true,
+		UpgradeVersion: "1.16.0",
+	}, p.GetServerExt(&autopilot.Config{}, srv))
+
+	config := &autopilot.Config{Ext: &structs.AutopilotConfig{
+		RedundancyZoneTag: "zone",
+		UpgradeVersionTag: "build",
+	}}
+	require.Equal(t, &structs.AutopilotServerExt{
+		ReadReplica:    true,
+		RedundancyZone: "a",
+		UpgradeVersion: "2.0.0",
+	}, p.GetServerExt(config, srv))
+
+	// The Consul version is used when the node meta has no upgrade version.
+	delete(srv.Server.Meta, "build")
+	require.Equal(t, "1.16.0", p.GetServerExt(config, srv).(*structs.AutopilotServerExt).UpgradeVersion)
+}
+
+func TestRedundancyPromoter_ReadReplicas(t *testing.T) {
+	t.Parallel()
+
+	state := testPromoterState("leader",
+		testPromoterServer("leader", autopilot.RaftLeader, structs.AutopilotServerExt{}),
+		testPromoterServer("new-server", autopilot.RaftNonVoter, structs.AutopilotServerExt{}),
+		testPromoterServer("read-replica", autopilot.RaftNonVoter, structs.AutopilotServerExt{ReadReplica: true}),
+		testPromoterServer("former-voter", autopilot.RaftVoter, structs.AutopilotServerExt{ReadReplica: true}),
+		testPromoterServer("regular-server", autopilot.RaftVoter, structs.AutopilotServerExt{}),
+	)
+
+	p := &redundancyPromoter{}
+	config := &autopilot.Config{ServerStabilizationTime: time.Second}
+
+	require.Equal(t, map[raft.ServerID]autopilot.NodeType{
+		"leader":         autopilot.NodeVoter,
+		"new-server":     autopilot.NodeVoter,
+		"read-replica":   nodeTypeReadReplica,
+		"former-voter":   nodeTypeReadReplica,
+		"regular-server": autopilot.NodeVoter,
+	}, p.GetNodeTypes(config, state))
+
+	// Read replicas are demoted even while other servers are promoted.
+	changes := p.CalculatePromotionsAndDemotions(config, state)
+	require.Equal(t, []raft.ServerID{"new-server"}, changes.Promotions)
+	require.Equal(t, []raft.ServerID{"former-voter"}, changes.Demotions)
+	require.Empty(t, changes.Leader)
+
+	// A leader restarted as a read replica transfers leadership to a voter.
+	state = testPromoterState("read-replica-leader",
+		testPromoterServer("read-replica-leader", autopilot.RaftLeader, structs.AutopilotServerExt{ReadReplica: true}),
+		testPromoterServer("regular-server", autopilot.RaftVoter, structs.AutopilotServerExt{}),
+		testPromoterServer("other-server", autopilot.RaftVoter, structs.AutopilotServerExt{}),
+	)
+	changes = p.CalculatePromotionsAndDemotions(config, state)
+	require.Empty(t, changes.Promotions)
+	require.Empty(t, changes.Demotions)
+	require.Equal(t, raft.ServerID("other-server"), changes.Leader)
+
+	// Once it has stepped down, it's demoted.
+	state = testPromoterState("other-server",
+		testPromoterServer("read-replica-leader", autopilot.RaftVoter, structs.AutopilotServerExt{ReadReplica: true}),
+		testPromoterServer("regular-server", autopilot.RaftVoter, structs.AutopilotServerExt{}),
+		testPromoterServer("other-server", autopilot.RaftLeader, structs.AutopilotServerExt{}),
+	)
+	changes = p.CalculatePromotionsAndDemotions(config, state)
+	require.Empty(t, changes.Promotions)
+	require.Equal(t, []raft.ServerID{"read-replica-leader"}, changes.Demotions)
+	require.Empty(t, changes.Leader)
+}
+
+func TestRedundancyPromoter_Zones(t *testing.T) {
+	t.Parallel()
+
+	zone := func(name string) structs.AutopilotServerExt {
+		return structs.AutopilotServerExt{RedundancyZone: name}
+	}
+	unhealthy := func(srv *autopilot.ServerState) *autopilot.ServerState {
+		srv.Health = autopilot.ServerHealth{Healthy: false}
+		return srv
+	}
+	olderStable := func(srv *autopilot.ServerState) *autopilot.ServerState {
+		srv.Health.StableSince = srv.Health.StableSince.Add(-time.Hour)
+		return srv
+	}
+
+	p := &redundancyPromoter{}
+	config := &autopilot.Config{
+		ServerStabilizationTime: time.Second,
+		Ext:                     &structs.AutopilotConfig{RedundancyZoneTag: "zone"},
+	}
+
+	// The voter of zone b failed, and zone c is new.
+	state := testPromoterState("a1",
+		testPromoterServer("a1", autopilot.RaftLeader, zone("a")),
+		testPromoterServer("a2", autopilot.RaftNonVoter, zone("a")),
+		unhealthy(testPromoterServer("b1", autopilot.RaftVoter, zone("b"))),
+		testPromoterServer("b2", autopilot.RaftNonVoter, zone("b")),
+		testPromoterServer("c1", autopilot.RaftNonVoter, zone("c")),
+		olderStable(testPromoterServer("c2", autopilot.RaftNonVoter, zone("c"))),
+		testPromoterServer("no-zone", autopilot.RaftVoter, structs.AutopilotServerExt{}),
+	)
+	state.FailureTolerance = 0
+
+	require.Equal(t, map[raft.ServerID]autopilot.NodeType{
+		"a1":      nodeTypeZoneVoter,
+		"a2":      nodeTypeZoneStandby,
+		"b1":      nodeTypeZoneStandby,
+		"b2":      nodeTypeZoneVoter,
+		"c1":      nodeTypeZoneStandby,
+		"c2":      nodeTypeZoneVoter,
+		"no-zone": autopilot.NodeVoter,
+	}, p.GetNodeTypes(config, state))
+
+	changes := p.CalculatePromotionsAndDemotions(config, state)
+	require.Equal(t, []raft.ServerID{"b2", "c2"}, changes.Promotions)
+	require.Empty(t, changes.Demotions, "no demotions while there are promotions")
+
+	ext := p.GetStateExt(config, state).(*structs.AutopilotStateExt)
+	require.Equal(t, map[string]structs.AutopilotZone{
+		"a": {Servers: []raft.ServerID{"a1", "a2"}, Voters: []raft.ServerID{"a1"}, FailureTolerance: 1},
+		"b": {Servers: []raft.ServerID{"b1", "b2"}, Voters: []raft.ServerID{"b1"}, FailureTolerance: 0},
+		"c": {Servers: []raft.ServerID{"c1", "c2"}, FailureTolerance: 1},
+	}, ext.RedundancyZones)
+	require.Equal(t, 1, ext.OptimisticFailureTolerance)
+
+	// Once the new voters are promoted, the failed voter is demoted.
+	state.Servers["b2"].State = autopilot.RaftVoter
+	state.Servers["c2"].State = autopilot.RaftVoter
+	changes = p.CalculatePromotionsAndDemotions(config, state)
+	require.Empty(t, changes.Promotions)
+	require.Equal(t, []raft.ServerID{"b1"}, changes.Demotions)
+	require.Empty(t, changes.Leader)
+}
+
+func TestRedundancyPromoter_ZoneExtraVoters(t *testing.T) {
+	t.Parallel()
+
+	p := &redundancyPromoter{}
+	config := &autopilot.Config{
+		ServerStabilizationTime: time.Second,
+		Ext:                     &structs.AutopilotConfig{RedundancyZoneTag: "zone"},
+	}
+	a := structs.AutopilotServerExt{RedundancyZone: "a"}
+	b := structs.AutopilotServerExt{RedundancyZone: "b"}
+
+	// With two zones, one standby becomes an extra voter to keep three
+	// voters.
+	state := testPromoterState("a1",
+		testPromoterServer("a1", autopilot.RaftLeader, a),
+		testPromoterServer("a2", autopilot.RaftNonVoter, a),
+		testPromoterServer("b1", autopilot.RaftVoter, b),
+		testPromoterServer("b2", autopilot.RaftNonVoter, b),
+	)
+	require.Equal(t, map[raft.ServerID]autopilot.NodeType{
+		"a1": nodeTypeZoneVoter,
+		"a2": nodeTypeZoneExtraVoter,
+		"b1": nodeTypeZoneVoter,
+		"b2": nodeTypeZoneStandby,
+	}, p.GetNodeTypes(config, state))
+
+	changes := p.CalculatePromotionsAndDemotions(config, state)
+	require.Equal(t, []raft.ServerID{"a2"}, changes.Promotions)
+	require.Empty(t, changes.Demotions)
+
+	// With three zones, an extra voter replaces the voter of a zone that
+	// failed entirely, until the zone is back.
+	c := structs.AutopilotServerExt{RedundancyZone: "c"}
+	state = testPromoterState("a1",
+		testPromoterServer("a1", autopilot.RaftLeader, a),
+		testPromoterServer("a2", autopilot.RaftNonVoter, a),
+		testPromoterServer("b1", autopilot.RaftVoter, b),
+		testPromoterServer("b2", autopilot.RaftNonVoter, b),
+		testPromoterServer("c1", autopilot.RaftVoter, c),
+		testPromoterServer("c2", autopilot.RaftNonVoter, c),
+	)
+	changes = p.CalculatePromotionsAndDemotions(config, state)
+	require.Empty(t, changes.Promotions)
+	require.Empty(t, changes.Demotions)
+
+	state.Servers["c1"].Health = autopilot.ServerHealth{Healthy: false}
+	state.Servers["c2"].Health = autopilot.ServerHealth{Healthy: false}
+	require.Equal(t, map[raft.ServerID]autopilot.NodeType{
+		"a1": nodeTypeZoneVoter,
+		"a2": nodeTypeZoneExtraVoter,
+		"b1": nodeTypeZoneVoter,
+		"b2": nodeTypeZoneStandby,
+		"c1": nodeTypeZoneVoter,
+		"c2": nodeTypeZoneStandby,
+	}, p.GetNodeTypes(config, state))
+	changes = p.CalculatePromotionsAndDemotions(config, state)
+	require.Equal(t, []raft.ServerID{"a2"}, changes.Promotions)
+
+	state.Servers["a2"].State = autopilot.RaftVoter
+	state.Servers["c1"].Health = autopilot.ServerHealth{Healthy: true, StableSince: time.Now().Add(-time.Hour)}
+	changes = p.CalculatePromotionsAndDemotions(config, state)
+	require.Empty(t, changes.Promotions)
+	require.Equal(t, []raft.ServerID{"a2"}, changes.Demotions)
+}
+
+func TestRedundancyPromoter_UpgradeMigration(t *testing.T) {
+	t.Parallel()
+
+	old := structs.AutopilotServerExt{UpgradeVersion: "1.15.0"}
+	upgraded := structs.AutopilotServerExt{UpgradeVersion: "1.16.0"}
+
+	p := &redundancyPromoter{}
+	config := &autopilot.Config{
+		ServerStabilizationTime: time.Second,
+		Ext:                     &structs.AutopilotConfig{},
+	}
+	upgrade := func(state *autopilot.State) *structs.AutopilotUpgrade {
+		return p.GetStateExt(config, state).(*structs.AutopilotStateExt).Upgrade
+	}
+
+	state := testPromoterState("o1",
+		testPromoterServer("o1", autopilot.RaftLeader, old),
+		testPromoterServer("o2", autopilot.RaftVoter, old),
+		testPromoterServer("o3", autopilot.RaftVoter, old),
+	)
+	require.Equal(t, &structs.AutopilotUpgrade{
+		Status:              structs.AutopilotUpgradeIdle,
+		TargetVersion:       "1.15.0",
+		OtherVersionVoters:  nil,
+		TargetVersionVoters: []raft.ServerID{"o1", "o2", "o3"},
+	}, upgrade(state))
+
+	// Two new servers aren't enough to replace three voters.
+	state.Servers["n1"] = testPromoterServer("n1", autopilot.RaftNonVoter, upgraded)
+	state.Servers["n2"] = testPromoterServer("n2", autopilot.RaftNonVoter, upgraded)
+	changes := p.CalculatePromotionsAndDemotions(config, state)
+	require.Empty(t, changes.Promotions)
+	require.Empty(t, changes.Demotions)
+	u := upgrade(state)
+	require.Equal(t, structs.AutopilotUpgradeAwaitNewVoters, u.Status)
+	require.Equal(t, "1.16.0", u.TargetVersion)
+	require.Equal(t, []raft.ServerID{"n1", "n2"}, u.TargetVersionNonVoters)
+	require.Equal(t, []raft.ServerID{"o1", "o2", "o3"}, u.OtherVersionVoters)
+
+	// With a third one, the new servers are promoted.
+	state.Servers["n3"] = testPromoterServer("n3", autopilot.RaftNonVoter, upgraded)
+	changes = p.CalculatePromotionsAndDemotions(config, state)
+	require.Equal(t, []raft.ServerID{"n1", "n2", "n3"}, changes.Promotions)
+	require.Empty(t, changes.Demotions)
+	require.Equal(t, structs.AutopilotUpgradePromoting, upgrade(state).Status)
+
+	// Then the old voters are demoted, except for the leader.
+	for _, id := range []raft.ServerID{"n1", "n2", "n3"} {
+		state.Servers[id].State = autopilot.RaftVoter
+	}
+	changes = p.CalculatePromotionsAndDemotions(config, state)
+	require.Empty(t, changes.Promotions)
+	require.Equal(t, []raft.ServerID{"o2", "o3"}, changes.Demotions)
+	require.Equal(t, structs.AutopilotUpgradeDemoting, upgrade(state).Status)
+
+	// Then leadership is transferred to a new server.
+	state.Servers["o2"].State = autopilot.RaftNonVoter
+	state.Servers["o3"].State = autopilot.RaftNonVoter
+	changes = p.CalculatePromotionsAndDemotions(config, state)
+	require.Empty(t, changes.Promotions)
+	require.Empty(t, changes.Demotions)
+	require.Equal(t, raft.ServerID("n1"), changes.Leader)
+	require.Equal(t, structs.AutopilotUpgradeLeaderTransfer, upgrade(state).Status)
+
+	// And finally the former leader is demoted.
+	state.Leader = "n1"
+	state.Servers["n1"].State = autopilot.RaftLeader
+	state.Servers["o1"].State = autopilot.RaftVoter
+	changes = p.CalculatePromotionsAndDemotions(config, state)
+	require.Equal(t, []raft.ServerID{"o1"}, changes.Demotions)
+	require.Empty(t, changes.Leader)
+
+	state.Servers["o1"].State = autopilot.RaftNonVoter
+	changes = p.CalculatePromotionsAndDemotions(config, state)
+	require.Empty(t, changes.Promotions)
+	require.Empty(t, changes.Demotions)
+	u = upgrade(state)
+	require.Equal(t, structs.AutopilotUpgradeAwaitServerRemoval, u.Status)
+	require.Equal(t, []raft.ServerID{"n1", "n2", "n3"}, u.TargetVersionVoters)
+	require.Equal(t, []raft.ServerID{"o1", "o2", "o3"}, u.OtherVersionNonVoters)
+}
+
+func TestRedundancyPromoter_UpgradeMigrationDisabled(t *testing.T) {
+	t.Parallel()
+
+	p := &redundancyPromoter{}
+	config := &autopilot.Config{
+		ServerStabilizationTime: time.Second,
+		Ext:                     &structs.AutopilotConfig{DisableUpgradeMigration: true},
+	}
+	state := testPromoterState("o1",
+		testPromoterServer("o1", autopilot.RaftLeader, structs.AutopilotServerExt{UpgradeVersion: "1.15.0"}),
+		testPromoterServer("n1", autopilot.RaftNonVoter, structs.AutopilotServerExt{UpgradeVersion: "1.16.0"}),
+	)
+
+	changes := p.CalculatePromotionsAndDemotions(config, state)
+	require.Equal(t, []raft.ServerID{"n1"}, changes.Promotions)
+	ext := p.GetStateExt(config, state).(*structs.AutopilotStateExt)
+	require.Equal(t, structs.AutopilotUpgradeDisabled, ext.Upgrade.Status)
+}
@@ -0,0 +1,177 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"context"
+	"sync"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/go-memdb"
+
+	"github.com/hashicorp/consul/agent/consul/state"
+)
+
+// coalescedQueryFn is the part of a blocking query that can be shared by all
+// the requests with the same arguments. It must not depend on the token of the
+// request, so its result must be filtered with ACLs by each request, and the
+// caller must not modify its result in place.
+type coalescedQueryFn func(ws memdb.WatchSet, state *state.Store) (uint64, interface{}, error)
+
+// blockingQueryCoalescer lets identical blocking queries share a single watch
+// on the state store and a single execution of the query each time it fires,
+// instead of each of them re-executing the query. This matters when many
+// clients long-poll the same endpoint with the same arguments, e.g. thousands
+// of agents watching the health of a popular service.
+type blockingQueryCoalescer struct {
+	// stateFn returns the current state store.
+	stateFn func() *state.Store
+
+	// ctx is canceled when the server shuts down, which stops all the
+	// queries.
+	ctx context.Context
+
+	lock    sync.Mutex
+	queries map[string]*coalescedQuery
+}
+
+func newBlockingQueryCoalescer(ctx context.Context, stateFn func() *state.Store) *blockingQueryCoalescer {
+	return &blockingQueryCoalescer{
+		stateFn: stateFn,
+		ctx:     ctx,
+		queries: make(map[string]*coalescedQuery),
+	}
+}
+
+// coalescedQuery is a query shared by the requests with the same key. It's
+// executed in the background every time its watch fires, for as long as at
+// least one request uses it.
+type coalescedQuery struct {
+	endpoint string
+	key      string
+	fn       coalescedQueryFn
+
+	// refs is the number of requests using the query. It's protected by the
+	// lock of the coalescer.
+	refs   int
+	cancel context.CancelFunc
+
+	// ready is closed once the query has been executed for the first time.
+	ready chan struct{}
+
+	lock  sync.Mutex
+	index uint64
+	value interface{}
+	err   error
+	// changeCh is closed and replaced every time the query is executed
+	// again.
+	changeCh chan struct{}
+}
+
+// join returns the query of the given endpoint and key, and starts it if no
+// other request is using it. The returned function must be called once the
+// request no longer needs the result of the query.
+func (c *blockingQueryCoalescer) join(endpoint, key string, fn coalescedQueryFn) (*coalescedQuery, func()) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	mapKey := endpoint + "/" + key
+	q, ok := c.queries[mapKey]
+	if !ok {
+		ctx, cancel := context.WithCancel(c.ctx)
+		q = &coalescedQuery{
+			endpoint: endpoint,
+			key:      mapKey,
+			fn:       fn,
+			cancel:   cancel,
+			ready:    make(chan struct{}),
+			changeCh: make(chan struct{}),
+		}
+		c.queries[mapKey] = q
+		go c.run(ctx, q)
+	}
+	q.refs++
+
+	var once sync.Once
+	return q, func() {
+		once.Do(func() { c.leave(q) })
+	}
+}
+
+func (c *blockingQueryCoalescer) leave(q *coalescedQuery) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	q.refs--
+	if q.refs == 0 {
+		q.cancel()
+		c.remove(q)
+	}
+}
+
+// remove must be called with the lock held.
+func (c *blockingQueryCoalescer) remove(q *coalescedQuery) {
+	// The query may have already been replaced if it stopped because of an
+	// error.
+	if c.queries[q.key] == q {
+		delete(c.queries, q.key)
+	}
+}
+
+// run executes the query every time its watch fires until the given context
+// is canceled.
+func (c *blockingQueryCoalescer) run(ctx context.Context, q *coalescedQuery) {
+	labels := []metrics.Label{{Name: "endpoint", Value: q.endpoint}}
+	for {
+		// Operate on a consistent set of state, see blockingQuery.
+		state := c.stateFn()
+		ws := memdb.NewWatchSet()
+		ws.Add(state.AbandonCh())
+
+		index, value, err := q.fn(ws, state)
+		metrics.IncrCounterWithLabels([]string{"rpc", "query", "coalesced", "executions"}, 1, labels)
+		q.publish(index, value, err)
+
+		// The requests using the query return the error, so it's stopped and
+		// the next requests start a new one instead of getting the same error.
+		if err != nil {
+			c.lock.Lock()
+			c.remove(q)
+			c.lock.Unlock()
+			return
+		}
+
+		if err := ws.WatchCtx(ctx); err != nil {
+			return
+		}
+	}
+}
+
+func (q *coalescedQuery) publish(index uint64, value interface{}, err error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.index, q.value, q.err = index, value, err
+	select {
+	case <-q.ready:
+	default:
+		close(q.ready)
+	}
+	close(q.changeCh)
+	q.changeCh = make(chan struct{})
+}
+
+// result returns the latest result of the query, and adds a channel to the
+// given watch set that is closed once the query has been executed again.
+func (q *coalescedQuery) result(ws memdb.WatchSet) (uint64, interface{}, error) {
+	<-q.ready
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	metrics.IncrCounterWithLabels([]string{"rpc", "query", "coalesced", "results"}, 1,
+		[]metrics.Label{{Name: "endpoint", Value: q.endpoint}})
+	ws.Add(q.changeCh)
+	return q.index, q.value, q.err
+}
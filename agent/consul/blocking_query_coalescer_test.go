@@ -0,0 +1,90 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-memdb"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/consul/agent/consul/state"
+	"github.com/hashicorp/consul/agent/structs"
+)
+
+func TestBlockingQueryCoalescer(t *testing.T) {
+	store := state.NewStateStore(nil)
+	c := newBlockingQueryCoalescer(context.Background(), func() *state.Store { return store })
+
+	var executions uint64
+	fn := func(ws memdb.WatchSet, s *state.Store) (uint64, interface{}, error) {
+		atomic.AddUint64(&executions, 1)
+		index, entry, err := s.KVSGet(ws, "key", nil)
+		return index, entry, err
+	}
+
+	q1, release1 := c.join("KVS.Get", "key", fn)
+	q2, release2 := c.join("KVS.Get", "key", fn)
+	require.Same(t, q1, q2)
+
+	other, releaseOther := c.join("KVS.Get", "other", func(memdb.WatchSet, *state.Store) (uint64, interface{}, error) {
+		return 0, nil, nil
+	})
+	require.NotSame(t, q1, other)
+	releaseOther()
+
+	ws := memdb.NewWatchSet()
+	index, value, err := q1.result(ws)
+	require.NoError(t, err)
+	require.Zero(t, index)
+	require.Nil(t, value)
+
+	// Changing the data executes the query again, which notifies the requests
+	// using it.
+	require.NoError(t, store.KVSSet(5, &structs.DirEntry{Key: "key", Value: []byte("value")}))
+	require.False(t, ws.Watch(time.After(5*time.Second)))
+
+	index, value, err = q2.result(memdb.NewWatchSet())
+	require.NoError(t, err)
+	require.Equal(t, uint64(5), index)
+	require.Equal(t, []byte("value"), value.(*structs.DirEntry).Value)
+
+	// Unrelated changes don't.
+	ws = memdb.NewWatchSet()
+	_, _, err = q1.result(ws)
+	require.NoError(t, err)
+	require.NoError(t, store.KVSSet(6, &structs.DirEntry{Key: "unrelated"}))
+	require.True(t, ws.Watch(time.After(50*time.Millisecond)))
+	require.Equal(t, uint64(2), atomic.LoadUint64(&executions))
+
+	// The query stops once no request uses it.
+	release1()
+	release1()
+	require.Len(t, c.queries, 1)
+	release2()
+	require.Empty(t, c.queries)
+}
+
+func TestBlockingQueryCoalescer_Error(t *testing.T) {
+	store := state.NewStateStore(nil)
+	c := newBlockingQueryCoalescer(context.Background(), func() *state.Store { return store })
+
+	errQuery := errors.New("query failed")
+	q, release := c.join("KVS.Get", "key", func(memdb.WatchSet, *state.Store) (uint64, interface{}, error) {
+		return 0, nil, errQuery
+	})
+	defer release()
+
+	_, _, err := q.result(memdb.NewWatchSet())
+	require.ErrorIs(t, err, errQuery)
+
+	// The next requests start a new query instead of getting the same error.
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	require.Empty(t, c.queries)
+}
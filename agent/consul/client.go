@@ -277,9 +277,21 @@ func (c *Client) RPC(ctx context.Context, method string, args interface{}, reply
 	firstCheck := time.Now()
 	retryCount := 0
 	previousJitter := time.Duration(0)
+
+	// Use the zero value for RPCInfo if the request doesn't implement RPCInfo
+	info, _ := args.(structs.RPCInfo)
+
+	// Reads of the local datacenter that allow stale results go to read
+	// replicas if there are any. Retries go to the other servers, so a
+	// failing read replica isn't picked over and over again.
+	readRoute := info != nil && info.IsRead() && info.AllowStaleRead() && info.RequestDatacenter() == c.config.Datacenter
 TRY:
 	retryCount++
-	manager, server := c.router.FindLANRoute()
+	findRoute := c.router.FindLANRoute
+	if readRoute && retryCount == 1 {
+		findRoute = c.router.FindLANReadRoute
+	}
+	manager, server := findRoute()
 	if server == nil {
 		return structs.ErrNoServers
 	}
@@ -300,8 +312,6 @@ TRY:
 	// Move off to another server, and see if we can retry.
 	manager.NotifyFailedServer(server)
 
-	// Use the zero value for RPCInfo if the request doesn't implement RPCInfo
-	info, _ := args.(structs.RPCInfo)
 	retryableMessages := []error{
 		// If we are chunking and it doesn't seem to have completed, try again.
 		ErrChunkingResubmit,
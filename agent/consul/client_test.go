@@ -11,6 +11,7 @@ import (
 	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -26,6 +27,7 @@ import (
 	grpc "github.com/hashicorp/consul/agent/grpc-internal"
 	"github.com/hashicorp/consul/agent/grpc-internal/balancer"
 	"github.com/hashicorp/consul/agent/grpc-internal/resolver"
+	"github.com/hashicorp/consul/agent/metadata"
 	"github.com/hashicorp/consul/agent/pool"
 	"github.com/hashicorp/consul/agent/router"
 	"github.com/hashicorp/consul/agent/rpc/middleware"
@@ -344,6 +346,74 @@ func TestClient_RPC_Retry(t *testing.T) {
 	}
 }
 
+type readFailer struct {
+	calls int32
+	err   error
+}
+
+func (f *readFailer) Read(args *structs.DCSpecificRequest, reply *struct{}) error {
+	atomic.AddInt32(&f.calls, 1)
+	return f.err
+}
+
+func TestClient_RPC_RetryReadReplica(t *testing.T) {
+	if testing.Short() {
+		t.Skip("too slow for testing.Short")
+	}
+
+	t.Parallel()
+
+	dir1, s1 := testServer(t)
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+
+	// The read replica is a separate server, which fails every read.
+	dir2, s2 := testServer(t)
+	defer os.RemoveAll(dir2)
+	defer s2.Shutdown()
+
+	dir3, c1 := testClientWithConfig(t, func(c *Config) {
+		c.Datacenter = "dc1"
+		c.NodeName = uniqueNodeName(t.Name())
+		c.RPCHoldTimeout = 2 * time.Second
+	})
+	defer os.RemoveAll(dir3)
+	defer c1.Shutdown()
+
+	joinLAN(t, c1, s1)
+	retry.Run(t, func(r *retry.R) {
+		var out struct{}
+		if err := c1.RPC(context.Background(), "Status.Ping", struct{}{}, &out); err != nil {
+			r.Fatalf("err: %v", err)
+		}
+	})
+
+	server := &readFailer{}
+	replica := &readFailer{err: structs.ErrNoLeader}
+	require.NoError(t, s1.RegisterEndpoint("Fail", server))
+	require.NoError(t, s2.RegisterEndpoint("Fail", replica))
+
+	c1.router.GetLANManager().AddServer(&metadata.Server{
+		Name:        s2.config.NodeName + ".dc1",
+		ShortName:   s2.config.NodeName,
+		ID:          string(s2.config.NodeID),
+		Datacenter:  "dc1",
+		Port:        s2.config.RPCAddr.Port,
+		Addr:        s2.config.RPCAddr,
+		Status:      serf.StatusAlive,
+		ReadReplica: true,
+	})
+
+	args := &structs.DCSpecificRequest{
+		Datacenter:   "dc1",
+		QueryOptions: structs.QueryOptions{AllowStale: true},
+	}
+	var out struct{}
+	require.NoError(t, c1.RPC(context.Background(), "Fail.Read", args, &out))
+	require.Equal(t, int32(1), atomic.LoadInt32(&replica.calls))
+	require.Equal(t, int32(1), atomic.LoadInt32(&server.calls))
+}
+
 func TestClient_RPC_Pool(t *testing.T) {
 	t.Parallel()
 	dir1, s1 := testServer(t)
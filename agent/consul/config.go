@@ -116,8 +116,8 @@ type Config struct {
 	// RaftConfig is the configuration used for Raft in the local DC
 	RaftConfig *raft.Config
 
-	// (Enterprise-only) ReadReplica is used to prevent this server from being added
-	// as a voting member of the Raft cluster.
+	// ReadReplica is used to prevent this server from being added as a voting
+	// member of the Raft cluster. Autopilot never promotes read replicas.
 	ReadReplica bool
 
 	// NotifyListen is called after the RPC listener has been configured.
@@ -346,6 +346,10 @@ type Config struct {
 	// limiter limits the rate to RequestLimitsWriteRate tokens per second.
 	RequestLimitsWriteRate rate.Limit
 
+	// RequestConcurrencyLimits configures the adaptive limits on the number of
+	// reads, writes and blocking queries the server handles concurrently.
+	RequestConcurrencyLimits consulrate.ConcurrencyConfig
+
 	// RPCHandshakeTimeout limits how long we will wait for the initial magic byte
 	// on an RPC client connection. It also governs how long we will wait for a
 	// TLS handshake when TLS is configured however the timout applies separately
@@ -434,6 +438,10 @@ type Config struct {
 
 	LogStoreConfig RaftLogStoreConfig
 
+	// LeaderHealthConfig configures the leader to transfer leadership away
+	// when its own Raft performance is degraded.
+	LeaderHealthConfig RaftLeaderHealthConfig
+
 	// PeeringEnabled enables cluster peering.
 	PeeringEnabled bool
 
@@ -659,9 +667,10 @@ type RPCConfig struct {
 // RequestLimits is configuration for serverrate limiting that is a part of
 // ReloadableConfig.
 type RequestLimits struct {
-	Mode      consulrate.Mode
-	ReadRate  rate.Limit
-	WriteRate rate.Limit
+	Mode        consulrate.Mode
+	ReadRate    rate.Limit
+	WriteRate   rate.Limit
+	Concurrency consulrate.ConcurrencyConfig
 }
 
 // ReloadableConfig is the configuration that is passed to ReloadConfig when
@@ -702,6 +711,23 @@ type WALConfig struct {
 	SegmentSize int
 }
 
+// RaftLeaderHealthConfig configures the thresholds of the leader's Raft
+// performance. A threshold of zero isn't checked.
+type RaftLeaderHealthConfig struct {
+	Enabled            bool
+	FSMApplyThreshold  time.Duration
+	StoreLogsThreshold time.Duration
+	CommitThreshold    time.Duration
+
+	// UnhealthyPeriod is how long the leader must exceed a threshold before
+	// it transfers leadership.
+	UnhealthyPeriod time.Duration
+
+	// TransferCooldown is the minimum time between two transfers by the same
+	// server.
+	TransferCooldown time.Duration
+}
+
 type License struct {
 	Enabled bool
 }
@@ -41,6 +41,10 @@ var CommandsSummaries = []prometheus.SummaryDefinition{
 		Name: []string{"fsm", "tombstone"},
 		Help: "Measures the time it takes to apply the given tombstone operation to the FSM.",
 	},
+	{
+		Name: []string{"fsm", "kvs", "revision-prune"},
+		Help: "Measures the time it takes to prune the KV revision history in the FSM.",
+	},
 	{
 		Name: []string{"fsm", "coordinate", "batch-update"},
 		Help: "Measures the time it takes to apply the given batch coordinate update to the FSM.",
@@ -146,6 +150,7 @@ func init() {
 	registerCommand(structs.PeeringTrustBundleDeleteType, (*FSM).applyPeeringTrustBundleDelete)
 	registerCommand(structs.PeeringSecretsWriteType, (*FSM).applyPeeringSecretsWrite)
 	registerCommand(structs.ResourceOperationType, (*FSM).applyResourceOperation)
+	registerCommand(structs.KVRevisionPruneRequestType, (*FSM).applyKVRevisionPrune)
 }
 
 func (c *FSM) applyRegister(buf []byte, index uint64) interface{} {
@@ -278,6 +283,15 @@ func (c *FSM) applyTombstoneOperation(buf []byte, index uint64) interface{} {
 	}
 }
 
+func (c *FSM) applyKVRevisionPrune(buf []byte, index uint64) interface{} {
+	var req structs.KVRevisionPruneRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+	defer metrics.MeasureSince([]string{"fsm", "kvs", "revision-prune"}, time.Now())
+	return c.state.KVSPruneRevisions(index, req.Now)
+}
+
 // applyCoordinateBatchUpdate processes a batch of coordinate updates and applies
 // them in a single underlying transaction. This interface isn't 1:1 with the outer
 // update interface that the coordinate endpoint exposes, so we made it single
@@ -1003,6 +1003,48 @@ func TestFSM_TombstoneReap(t *testing.T) {
 	}
 }
 
+func TestFSM_KVRevisionPrune(t *testing.T) {
+	t.Parallel()
+	logger := testutil.Logger(t)
+	fsm, err := New(nil, logger)
+	require.NoError(t, err)
+
+	require.NoError(t, fsm.state.EnsureConfigEntry(1, &structs.KVPrefixConfigEntry{
+		Name:    "config",
+		Prefix:  "config/",
+		History: &structs.KVHistoryConfig{MaxAge: time.Hour},
+	}))
+	require.NoError(t, fsm.state.KVSSet(2, &structs.DirEntry{Key: "config/app", Value: []byte("v1")}))
+	require.NoError(t, fsm.state.KVSSet(3, &structs.DirEntry{Key: "config/app", Value: []byte("v2")}))
+
+	apply := func(now time.Time) {
+		t.Helper()
+		buf, err := structs.Encode(structs.KVRevisionPruneRequestType, structs.KVRevisionPruneRequest{
+			Datacenter: "dc1",
+			Now:        now,
+		})
+		require.NoError(t, err)
+		resp := fsm.Apply(makeLog(buf))
+		if err, ok := resp.(error); ok {
+			t.Fatalf("resp: %v", err)
+		}
+	}
+
+	// The first prune stamps the revision, the second removes it once it is
+	// older than MaxAge.
+	now := time.Now().UTC()
+	apply(now)
+	_, revs, err := fsm.state.KVSRevisions(nil, "config/app", nil)
+	require.NoError(t, err)
+	require.Len(t, revs, 1)
+	require.False(t, revs[0].SupersededAt.IsZero())
+
+	apply(now.Add(time.Hour))
+	_, revs, err = fsm.state.KVSRevisions(nil, "config/app", nil)
+	require.NoError(t, err)
+	require.Empty(t, revs)
+}
+
 func TestFSM_Txn(t *testing.T) {
 	t.Parallel()
 	logger := testutil.Logger(t)
@@ -299,6 +299,7 @@ func (c *FSM) Restore(old io.ReadCloser) error {
 		c.deps.Publisher.RefreshTopic(state.EventTopicServiceHealth)
 		c.deps.Publisher.RefreshTopic(state.EventTopicServiceHealthConnect)
 		c.deps.Publisher.RefreshTopic(state.EventTopicCARoots)
+		c.deps.Publisher.RefreshTopic(state.EventTopicKV)
 	}
 	c.stateLock.Unlock()
 
@@ -426,6 +427,11 @@ func (c *FSM) registerStreamSnapshotHandlers() {
 		return c.State().SamenessGroupSnapshot(req, buf)
 	}, true)
 	panicIfErr(err)
+
+	err = c.deps.Publisher.RegisterPrefixHandler(state.EventTopicKV, func(req stream.SubscribeRequest, buf stream.SnapshotAppender) (uint64, error) {
+		return c.State().KVSnapshot(req, buf)
+	})
+	panicIfErr(err)
 }
 
 func panicIfErr(err error) {
@@ -544,6 +544,19 @@ func TestFSM_SnapshotRestore_OSS(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, vip, "240.0.0.3")
 
+	// KV revisions
+	kvPrefix := &structs.KVPrefixConfigEntry{
+		Kind:    structs.KVPrefix,
+		Name:    "config",
+		Prefix:  "config/",
+		History: &structs.KVHistoryConfig{MaxRevisions: 5},
+	}
+	require.NoError(t, fsm.state.EnsureConfigEntry(35, kvPrefix))
+	require.NoError(t, fsm.state.KVSSet(36, &structs.DirEntry{Key: "config/app", Value: []byte("v1")}))
+	require.NoError(t, fsm.state.KVSSet(37, &structs.DirEntry{Key: "config/app", Value: []byte("v2")}))
+	revisionTime := time.Now().UTC().Round(0)
+	require.NoError(t, fsm.state.KVSPruneRevisions(38, revisionTime))
+
 	// Resources
 	resource, err := storageBackend.WriteCAS(context.Background(), &pbresource.Resource{
 		Id: &pbresource.ID{
@@ -843,6 +856,15 @@ func TestFSM_SnapshotRestore_OSS(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, meshConfig, meshConfigEntry)
 
+	// Verify KV revisions are restored
+	_, revs, err := fsm2.state.KVSRevisions(nil, "config/app", nil)
+	require.NoError(t, err)
+	require.Len(t, revs, 1)
+	require.Equal(t, []byte("v1"), revs[0].Value)
+	require.Equal(t, uint64(36), revs[0].ModifyIndex)
+	require.Equal(t, uint64(37), revs[0].SupersededIndex)
+	require.True(t, revisionTime.Equal(revs[0].SupersededAt))
+
 	_, restoredServiceNames, err := fsm2.state.ServiceNamesOfKind(nil, structs.ServiceKindTypical)
 	require.NoError(t, err)
 
@@ -6,6 +6,7 @@ package consul
 import (
 	"fmt"
 	"sort"
+	"strconv"
 
 	"github.com/armon/go-metrics"
 	bexpr "github.com/hashicorp/go-bexpr"
@@ -241,6 +242,40 @@ func (h *Health) ServiceNodes(args *structs.ServiceSpecificRequest, reply *struc
 		return err
 	}
 
+	// Identical blocking queries share the execution of the query, and only
+	// filter its result with ACLs and sort it separately. Consistent queries,
+	// and the ones merging the central config whose spurious wakeups are
+	// suppressed per request, are executed on their own.
+	var coalesced *coalescedQuery
+	if args.MinQueryIndex > 0 && !args.RequireConsistent && !args.MergeCentralConfig {
+		shared := *args
+		shared.ServiceTags = append([]string(nil), args.ServiceTags...)
+		shared.Token, shared.MinQueryIndex, shared.MaxQueryTime = "", 0, 0
+		shared.Source = structs.QuerySource{}
+		key, err := hashstructure_v2.Hash(shared, hashstructure_v2.FormatV2, nil)
+		if err != nil {
+			return fmt.Errorf("error hashing request for blocking query coalescing: %w", err)
+		}
+
+		var release func()
+		coalesced, release = h.srv.queryCoalescer.join("Health.ServiceNodes", strconv.FormatUint(key, 16),
+			func(ws memdb.WatchSet, state *state.Store) (uint64, interface{}, error) {
+				index, nodes, err := f(ws, state, &shared)
+				if err != nil {
+					return 0, nil, err
+				}
+				if len(shared.NodeMetaFilters) > 0 {
+					nodes = nodeMetaFilter(shared.NodeMetaFilters, nodes)
+				}
+				raw, err := filter.Execute(nodes)
+				if err != nil {
+					return 0, nil, err
+				}
+				return index, raw.(structs.CheckServiceNodes), nil
+			})
+		defer release()
+	}
+
 	var (
 		priorMergeHash uint64
 		ranMergeOnce   bool
@@ -250,6 +285,10 @@ func (h *Health) ServiceNodes(args *structs.ServiceSpecificRequest, reply *struc
 		&args.QueryOptions,
 		&reply.QueryMeta,
 		func(ws memdb.WatchSet, state *state.Store) error {
+			if coalesced != nil {
+				return h.coalescedServiceNodes(ws, coalesced, args, reply)
+			}
+
 			var thisReply structs.IndexedCheckServiceNodes
 
 			index, nodes, err := f(ws, state, args)
@@ -358,6 +397,34 @@ func (h *Health) ServiceNodes(args *structs.ServiceSpecificRequest, reply *struc
 	return err
 }
 
+// coalescedServiceNodes answers a ServiceNodes request with the result of the
+// given query shared by the identical requests.
+func (h *Health) coalescedServiceNodes(ws memdb.WatchSet, q *coalescedQuery, args *structs.ServiceSpecificRequest, reply *structs.IndexedCheckServiceNodes) error {
+	index, value, err := q.result(ws)
+	if err != nil {
+		return err
+	}
+
+	// The result is shared with the other requests, so it must be copied
+	// before being filtered and sorted in place.
+	nodes, _ := value.(structs.CheckServiceNodes)
+	thisReply := structs.IndexedCheckServiceNodes{
+		Nodes: append(structs.CheckServiceNodes(nil), nodes...),
+	}
+	thisReply.Index = index
+
+	if err := h.srv.filterACL(args.Token, &thisReply); err != nil {
+		return err
+	}
+
+	if err := h.srv.sortNodesByDistanceFrom(args.Source, thisReply.Nodes); err != nil {
+		return err
+	}
+
+	*reply = thisReply
+	return nil
+}
+
 // The serviceNodes* functions below are the various lookup methods that
 // can be used by the ServiceNodes endpoint.
 
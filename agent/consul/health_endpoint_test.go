@@ -748,6 +748,102 @@ func TestHealth_ServiceNodes_BlockingQuery_withFilter(t *testing.T) {
 	})
 }
 
+func TestHealth_ServiceNodes_BlockingQuery_Coalesced(t *testing.T) {
+	if testing.Short() {
+		t.Skip("too slow for testing.Short")
+	}
+
+	t.Parallel()
+
+	_, s1 := testServerWithConfig(t, func(c *Config) {
+		c.PrimaryDatacenter = "dc1"
+		c.ACLsEnabled = true
+		c.ACLInitialManagementToken = "root"
+		c.ACLResolverSettings.ACLDefaultPolicy = "deny"
+	})
+	codec := rpcClient(t, s1)
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1", testrpc.WithToken("root"))
+
+	register := func(t *testing.T, node, tag string) {
+		arg := structs.RegisterRequest{
+			Datacenter: "dc1",
+			Node:       node,
+			Address:    "127.0.0.1",
+			Service: &structs.NodeService{
+				ID:      "web",
+				Service: "web",
+				Tags:    []string{tag},
+			},
+			WriteRequest: structs.WriteRequest{Token: "root"},
+		}
+		var out struct{}
+		require.NoError(t, msgpackrpc.CallWithCodec(codec, "Catalog.Register", &arg, &out))
+	}
+	register(t, "node1", "foo")
+	register(t, "node2", "foo")
+
+	token := createToken(t, codec, `
+service "web" {
+	policy = "read"
+}
+node "node1" {
+	policy = "read"
+}
+`)
+
+	req := structs.ServiceSpecificRequest{
+		Datacenter:   "dc1",
+		ServiceName:  "web",
+		QueryOptions: structs.QueryOptions{Token: "root"},
+	}
+	var out structs.IndexedCheckServiceNodes
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "Health.ServiceNodes", &req, &out))
+	require.Len(t, out.Nodes, 2)
+
+	// Both queries share the same execution, even though they don't have the
+	// same token.
+	rootReq, tokenReq := req, req
+	rootReq.MinQueryIndex = out.Index
+	tokenReq.MinQueryIndex = out.Index
+	tokenReq.Token = token
+
+	var rootOut, tokenOut structs.IndexedCheckServiceNodes
+	rootErrCh := channelCallRPC(s1, "Health.ServiceNodes", &rootReq, &rootOut, nil)
+	tokenErrCh := channelCallRPC(s1, "Health.ServiceNodes", &tokenReq, &tokenOut, nil)
+
+	retry.Run(t, func(r *retry.R) {
+		s1.queryCoalescer.lock.Lock()
+		defer s1.queryCoalescer.lock.Unlock()
+
+		require.Len(r, s1.queryCoalescer.queries, 1)
+		for _, q := range s1.queryCoalescer.queries {
+			require.Equal(r, 2, q.refs)
+		}
+	})
+
+	register(t, "node1", "bar")
+
+	require.NoError(t, <-rootErrCh)
+	require.NoError(t, <-tokenErrCh)
+
+	// But their results are filtered with ACLs separately.
+	require.Len(t, rootOut.Nodes, 2)
+	require.False(t, rootOut.ResultsFilteredByACLs)
+	require.Greater(t, rootOut.Index, out.Index)
+
+	require.Len(t, tokenOut.Nodes, 1)
+	require.Equal(t, "node1", tokenOut.Nodes[0].Node.Node)
+	require.Equal(t, []string{"bar"}, tokenOut.Nodes[0].Service.Tags)
+	require.True(t, tokenOut.ResultsFilteredByACLs)
+	require.Equal(t, rootOut.Index, tokenOut.Index)
+
+	// The query stops once no request uses it.
+	s1.queryCoalescer.lock.Lock()
+	defer s1.queryCoalescer.lock.Unlock()
+	require.Empty(t, s1.queryCoalescer.queries)
+}
+
 func TestHealth_ServiceNodes_MultipleServiceTags(t *testing.T) {
 	if testing.Short() {
 		t.Skip("too slow for testing.Short")
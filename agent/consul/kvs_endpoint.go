@@ -41,6 +41,9 @@ func kvsPreApply(logger hclog.Logger, srv *Server, authz resolver.Result, op api
 	if dirEnt.Key == "" && op != api.KVDeleteTree {
 		return false, fmt.Errorf("Must provide key")
 	}
+	if err := kvsValidateTTL(dirEnt); err != nil {
+		return false, err
+	}
 
 	// Apply the ACL policy if any.
 	switch op {
@@ -75,6 +78,17 @@ func kvsPreApply(logger hclog.Logger, srv *Server, authz resolver.Result, op api
 		}
 	}
 
+	// Reject values that don't conform to the schema of their prefix or that
+	// would exceed its quota before they are submitted to Raft. The FSM checks
+	// the quota again in case the usage changes in the meantime, but not the
+	// schema, so that applying the log stays deterministic across versions.
+	switch op {
+	case api.KVSet, api.KVCAS, api.KVLock, api.KVUnlock:
+		if err := srv.fsm.State().KVSCheckWrite(dirEnt); err != nil {
+			return false, err
+		}
+	}
+
 	// If this is a lock, we must check for a lock-delay. Since lock-delay
 	// is based on wall-time, each peer would expire the lock-delay at a slightly
 	// different time. This means the enforcement of lock-delay cannot be done
@@ -96,6 +110,27 @@ func kvsPreApply(logger hclog.Logger, srv *Server, authz resolver.Result, op api
 	return true, nil
 }
 
+// kvsValidateTTL ensures the TTL of an entry is valid, and clears a zero TTL
+// so that it is stored the same as no TTL.
+func kvsValidateTTL(dirEnt *structs.DirEntry) error {
+	if dirEnt.TTL == "" {
+		return nil
+	}
+	ttl, err := time.ParseDuration(dirEnt.TTL)
+	if err != nil {
+		return fmt.Errorf("KV TTL '%s' invalid: %v", dirEnt.TTL, err)
+	}
+	if ttl == 0 {
+		dirEnt.TTL = ""
+		return nil
+	}
+	if ttl < structs.KVTTLMin || ttl > structs.KVTTLMax {
+		return fmt.Errorf("Invalid KV TTL '%s', must be between [%v=%v]",
+			dirEnt.TTL, structs.KVTTLMin, structs.KVTTLMax)
+	}
+	return nil
+}
+
 // Apply is used to apply a KVS update request to the data store.
 func (k *KVS) Apply(args *structs.KVSRequest, reply *bool) error {
 	if done, err := k.srv.ForwardRPC("KVS.Apply", args, reply); done {
@@ -128,6 +163,12 @@ func (k *KVS) Apply(args *structs.KVSRequest, reply *bool) error {
 		return fmt.Errorf("raft apply failed: %w", err)
 	}
 
+	// Update the expiry timer of the entry, unless a check-and-set or lock
+	// operation failed.
+	if applied, ok := resp.(bool); !ok || applied {
+		k.srv.resetKVSTimerAfterApply(args.Op, &args.DirEnt)
+	}
+
 	// Check if the return type is a bool.
 	if respBool, ok := resp.(bool); ok {
 		*reply = respBool
@@ -155,7 +196,14 @@ func (k *KVS) Get(args *structs.KeyRequest, reply *structs.IndexedDirEntries) er
 		&args.QueryOptions,
 		&reply.QueryMeta,
 		func(ws memdb.WatchSet, state *state.Store) error {
-			index, ent, err := state.KVSGet(ws, args.Key, &args.EnterpriseMeta)
+			var index uint64
+			var ent *structs.DirEntry
+			var err error
+			if args.AtIndex > 0 {
+				index, ent, err = state.KVSGetAtIndex(ws, args.Key, args.AtIndex, &args.EnterpriseMeta)
+			} else {
+				index, ent, err = state.KVSGet(ws, args.Key, &args.EnterpriseMeta)
+			}
 			if err != nil {
 				return err
 			}
@@ -175,6 +223,47 @@ func (k *KVS) Get(args *structs.KeyRequest, reply *structs.IndexedDirEntries) er
 		})
 }
 
+// Revisions is used to lookup the previous versions kept for a key, newest
+// first.
+func (k *KVS) Revisions(args *structs.KeyRequest, reply *structs.IndexedDirEntryRevisions) error {
+	if done, err := k.srv.ForwardRPC("KVS.Revisions", args, reply); done {
+		return err
+	}
+
+	var authzContext acl.AuthorizerContext
+	authz, err := k.srv.ResolveTokenAndDefaultMeta(args.Token, &args.EnterpriseMeta, &authzContext)
+	if err != nil {
+		return err
+	}
+
+	if err := k.srv.validateEnterpriseRequest(&args.EnterpriseMeta, false); err != nil {
+		return err
+	}
+
+	if err := authz.ToAllowAuthorizer().KeyReadAllowed(args.Key, &authzContext); err != nil {
+		return err
+	}
+
+	return k.srv.blockingQuery(
+		&args.QueryOptions,
+		&reply.QueryMeta,
+		func(ws memdb.WatchSet, state *state.Store) error {
+			index, revs, err := state.KVSRevisions(ws, args.Key, &args.EnterpriseMeta)
+			if err != nil {
+				return err
+			}
+
+			// Must provide non-zero index to prevent blocking
+			// Index 1 is impossible anyways (due to Raft internals)
+			if index == 0 {
+				index = 1
+			}
+			reply.Index = index
+			reply.Revisions = revs
+			return nil
+		})
+}
+
 // List is used to list all keys with a given prefix.
 func (k *KVS) List(args *structs.KeyRequest, reply *structs.IndexedDirEntries) error {
 	if done, err := k.srv.ForwardRPC("KVS.List", args, reply); done {
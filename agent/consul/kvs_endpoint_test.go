@@ -977,3 +977,115 @@ key "test/priv" {
 	policy = "read"
 }
 `
+
+func TestKVS_Apply_Schema(t *testing.T) {
+	if testing.Short() {
+		t.Skip("too slow for testing.Short")
+	}
+
+	t.Parallel()
+	dir1, s1 := testServer(t)
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	confArgs := structs.ConfigEntryRequest{
+		Datacenter: "dc1",
+		Entry: &structs.KVPrefixConfigEntry{
+			Name:   "config",
+			Prefix: "config/",
+			Schema: `{"type": "object", "properties": {"port": {"type": "integer"}}}`,
+		},
+	}
+	var confOut bool
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "ConfigEntry.Apply", &confArgs, &confOut))
+
+	arg := structs.KVSRequest{
+		Datacenter: "dc1",
+		Op:         api.KVSet,
+		DirEnt:     structs.DirEntry{Key: "config/app", Value: []byte(`{"port": 80}`)},
+	}
+	var out bool
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "KVS.Apply", &arg, &out))
+
+	arg.DirEnt.Value = []byte(`{"port": "80"}`)
+	err := msgpackrpc.CallWithCodec(codec, "KVS.Apply", &arg, &out)
+	require.True(t, structs.IsErrKVSchemaViolation(err), "unexpected error: %v", err)
+	require.EqualError(t, err, `Value of key "config/app" does not conform to the schema of kv-prefix config entry "config": port: Invalid type. Expected: integer, given: string`)
+
+	txnArgs := structs.TxnRequest{
+		Datacenter: "dc1",
+		Ops: structs.TxnOps{
+			&structs.TxnOp{
+				KV: &structs.TxnKVOp{
+					Verb:   api.KVSet,
+					DirEnt: structs.DirEntry{Key: "config/app", Value: []byte(`[]`)},
+				},
+			},
+		},
+	}
+	var txnOut structs.TxnResponse
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "Txn.Apply", &txnArgs, &txnOut))
+	require.Len(t, txnOut.Errors, 1)
+	require.Contains(t, txnOut.Errors[0].What, "(root): Invalid type. Expected: object, given: array")
+
+	_, entry, err := s1.fsm.State().KVSGet(nil, "config/app", nil)
+	require.NoError(t, err)
+	require.Equal(t, `{"port": 80}`, string(entry.Value))
+}
+
+func TestKVS_Apply_Quota(t *testing.T) {
+	if testing.Short() {
+		t.Skip("too slow for testing.Short")
+	}
+
+	t.Parallel()
+	dir1, s1 := testServer(t)
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	confArgs := structs.ConfigEntryRequest{
+		Datacenter: "dc1",
+		Entry: &structs.KVPrefixConfigEntry{
+			Name:   "config",
+			Prefix: "config/",
+			Quota:  &structs.KVQuotaConfig{MaxKeys: 1},
+		},
+	}
+	var confOut bool
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "ConfigEntry.Apply", &confArgs, &confOut))
+
+	arg := structs.KVSRequest{
+		Datacenter: "dc1",
+		Op:         api.KVSet,
+		DirEnt:     structs.DirEntry{Key: "config/a", Value: []byte("1")},
+	}
+	var out bool
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "KVS.Apply", &arg, &out))
+
+	arg.DirEnt.Key = "config/b"
+	err := msgpackrpc.CallWithCodec(codec, "KVS.Apply", &arg, &out)
+	require.True(t, structs.IsErrKVQuotaExceeded(err), "unexpected error: %v", err)
+	require.EqualError(t, err, `Writing key "config/b" would exceed the quota of kv-prefix config entry "config": 1 of 1 keys used`)
+
+	usageArgs := structs.OperatorUsageRequest{
+		DCSpecificRequest: structs.DCSpecificRequest{Datacenter: "dc1"},
+	}
+	var usage structs.Usage
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "Operator.Usage", &usageArgs, &usage))
+	require.Equal(t, []structs.KVPrefixUsage{{
+		Name:           "config",
+		Prefix:         "config/",
+		Keys:           1,
+		Bytes:          9,
+		MaxKeys:        1,
+		EnterpriseMeta: *structs.DefaultEnterpriseMetaInDefaultPartition(),
+	}}, usage.KVPrefixes["dc1"])
+}
@@ -0,0 +1,132 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/armon/go-metrics/prometheus"
+
+	"github.com/hashicorp/consul/acl"
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/api"
+)
+
+var KVSTTLGauges = []prometheus.GaugeDefinition{
+	{
+		Name: []string{"kvs_ttl", "active"},
+		Help: "Tracks the active number of KV entries with a TTL being tracked.",
+	},
+}
+
+var KVSTTLSummaries = []prometheus.SummaryDefinition{
+	{
+		Name: []string{"kvs_ttl", "expire"},
+		Help: "Measures the time spent deleting an expired KV entry.",
+	},
+}
+
+// initializeKVSTimers is used when a leader is newly elected to reset the
+// expiry timers of all the KV entries with a TTL. Like for sessions, this
+// means the entries get their full TTL again on failover, which is allowed
+// since the contract is only that entries are not deleted before their TTL.
+func (s *Server) initializeKVSTimers() error {
+	entries, err := s.fsm.State().KVSListTTL()
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		s.resetKVSTimer(entry)
+	}
+	return nil
+}
+
+// kvsTimerID returns the ID of the expiry timer of a KV entry.
+func kvsTimerID(key string, entMeta *acl.EnterpriseMeta) string {
+	// Partitions and namespaces can't contain a slash so the ID is unique.
+	return entMeta.PartitionOrDefault() + "/" + entMeta.NamespaceOrDefault() + "/" + key
+}
+
+// resetKVSTimer (re)starts the expiry timer of a KV entry, or stops it if the
+// entry no longer has a TTL. The timer deletes the entry only if it wasn't
+// modified since, so a timer that races with a write is harmless.
+func (s *Server) resetKVSTimer(entry *structs.DirEntry) {
+	id := kvsTimerID(entry.Key, &entry.EnterpriseMeta)
+
+	ttl, err := time.ParseDuration(entry.TTL)
+	if entry.TTL == "" || err != nil || ttl <= 0 {
+		s.kvsTimers.Stop(id)
+		return
+	}
+
+	// The timer is replaced rather than reset since its callback captures the
+	// entry's ModifyIndex.
+	key, entMeta, index := entry.Key, entry.EnterpriseMeta, entry.ModifyIndex
+	s.kvsTimers.Stop(id)
+	s.kvsTimers.ResetOrCreate(id, ttl, func() { s.expireKVS(id, key, &entMeta, index) })
+}
+
+// resetKVSTimerAfterApply updates the expiry timer of an entry after it was
+// changed by a KVS or Txn operation. This must only be called on the leader.
+func (s *Server) resetKVSTimerAfterApply(op api.KVOp, dirEnt *structs.DirEntry) {
+	switch op {
+	case api.KVSet, api.KVCAS, api.KVLock, api.KVUnlock:
+		// Look up the entry since a write of an unchanged entry keeps its
+		// ModifyIndex, which the timer needs to delete it.
+		_, entry, err := s.fsm.State().KVSGet(nil, dirEnt.Key, &dirEnt.EnterpriseMeta)
+		if err != nil {
+			s.logger.Error("Failed to look up KV entry to reset its TTL", "key", dirEnt.Key, "error", err)
+			return
+		}
+		if entry == nil {
+			s.kvsTimers.Stop(kvsTimerID(dirEnt.Key, &dirEnt.EnterpriseMeta))
+			return
+		}
+		s.resetKVSTimer(entry)
+
+	case api.KVDelete, api.KVDeleteCAS:
+		s.kvsTimers.Stop(kvsTimerID(dirEnt.Key, &dirEnt.EnterpriseMeta))
+	}
+}
+
+// expireKVS is invoked when the TTL of a KV entry is reached and deletes the
+// entry, as long as it is still at the given index.
+func (s *Server) expireKVS(id, key string, entMeta *acl.EnterpriseMeta, index uint64) {
+	defer metrics.MeasureSince([]string{"kvs_ttl", "expire"}, time.Now())
+
+	// Clear the timer
+	s.kvsTimers.Del(id)
+
+	args := structs.KVSRequest{
+		Datacenter: s.config.Datacenter,
+		Op:         api.KVDeleteCAS,
+		DirEnt: structs.DirEntry{
+			Key:            key,
+			EnterpriseMeta: *entMeta,
+			RaftIndex: structs.RaftIndex{
+				ModifyIndex: index,
+			},
+		},
+	}
+
+	// Retry with exponential backoff to delete the entry
+	for attempt := uint(0); attempt < maxInvalidateAttempts; attempt++ {
+		_, err := s.leaderRaftApply("KVS.Apply", structs.KVSRequestType, args)
+		if err == nil {
+			s.logger.Debug("KV entry TTL expired", "key", key)
+			return
+		}
+
+		s.logger.Error("Failed to delete expired KV entry", "key", key, "error", err)
+		time.Sleep((1 << attempt) * invalidateRetryBase)
+	}
+	s.logger.Error("maximum attempts reached to delete expired KV entry", "key", key)
+}
+
+// clearAllKVSTimers is used when a leader is stepping down and we no longer
+// need to track any KV expiry timers.
+func (s *Server) clearAllKVSTimers() {
+	s.kvsTimers.StopAll()
+}
@@ -0,0 +1,180 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	msgpackrpc "github.com/hashicorp/consul-net-rpc/net-rpc-msgpackrpc"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+	"github.com/hashicorp/consul/testrpc"
+)
+
+func TestInitializeKVSTimers(t *testing.T) {
+	if testing.Short() {
+		t.Skip("too slow for testing.Short")
+	}
+
+	t.Parallel()
+	dir1, s1 := testServer(t)
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	state := s1.fsm.State()
+	require.NoError(t, state.KVSSet(100, &structs.DirEntry{Key: "ttl", Value: []byte("a"), TTL: "10s"}))
+	require.NoError(t, state.KVSSet(101, &structs.DirEntry{Key: "no-ttl", Value: []byte("b")}))
+
+	require.NoError(t, s1.initializeKVSTimers())
+
+	entMeta := structs.DefaultEnterpriseMetaInDefaultPartition()
+	require.NotNil(t, s1.kvsTimers.Get(kvsTimerID("ttl", entMeta)))
+	require.Nil(t, s1.kvsTimers.Get(kvsTimerID("no-ttl", entMeta)))
+}
+
+func TestKVS_Apply_TTL(t *testing.T) {
+	if testing.Short() {
+		t.Skip("too slow for testing.Short")
+	}
+
+	t.Parallel()
+	dir1, s1 := testServer(t)
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	apply := func(op api.KVOp, ent structs.DirEntry) bool {
+		t.Helper()
+		arg := structs.KVSRequest{Datacenter: "dc1", Op: op, DirEnt: ent}
+		var out bool
+		require.NoError(t, msgpackrpc.CallWithCodec(codec, "KVS.Apply", &arg, &out))
+		return out
+	}
+	get := func(key string) *structs.DirEntry {
+		t.Helper()
+		_, ent, err := s1.fsm.State().KVSGet(nil, key, nil)
+		require.NoError(t, err)
+		return ent
+	}
+	entMeta := structs.DefaultEnterpriseMetaInDefaultPartition()
+
+	t.Run("invalid TTL", func(t *testing.T) {
+		for _, ttl := range []string{"soon", "10ms", "48h"} {
+			arg := structs.KVSRequest{
+				Datacenter: "dc1",
+				Op:         api.KVSet,
+				DirEnt:     structs.DirEntry{Key: "invalid", TTL: ttl},
+			}
+			var out bool
+			err := msgpackrpc.CallWithCodec(codec, "KVS.Apply", &arg, &out)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "KV TTL")
+		}
+	})
+
+	t.Run("expires", func(t *testing.T) {
+		apply(api.KVSet, structs.DirEntry{Key: "expires", Value: []byte("a"), TTL: "1s"})
+		require.Equal(t, "1s", get("expires").TTL)
+
+		retry.Run(t, func(r *retry.R) {
+			_, ent, err := s1.fsm.State().KVSGet(nil, "expires", nil)
+			require.NoError(r, err)
+			if ent != nil {
+				r.Fatal("entry has not expired")
+			}
+		})
+		require.Nil(t, s1.kvsTimers.Get(kvsTimerID("expires", entMeta)))
+	})
+
+	t.Run("write extends TTL", func(t *testing.T) {
+		apply(api.KVSet, structs.DirEntry{Key: "extends", Value: []byte("a"), TTL: "1s"})
+		for i := 0; i < 4; i++ {
+			time.Sleep(500 * time.Millisecond)
+			apply(api.KVSet, structs.DirEntry{Key: "extends", Value: []byte("a"), TTL: "1s"})
+		}
+		require.NotNil(t, get("extends"))
+	})
+
+	t.Run("write without TTL removes expiry", func(t *testing.T) {
+		apply(api.KVSet, structs.DirEntry{Key: "persist", Value: []byte("a"), TTL: "1s"})
+		apply(api.KVSet, structs.DirEntry{Key: "persist", Value: []byte("b")})
+		require.Nil(t, s1.kvsTimers.Get(kvsTimerID("persist", entMeta)))
+
+		time.Sleep(1500 * time.Millisecond)
+		require.NotNil(t, get("persist"))
+	})
+
+	t.Run("delete stops timer", func(t *testing.T) {
+		apply(api.KVSet, structs.DirEntry{Key: "deleted", Value: []byte("a"), TTL: "10s"})
+		require.NotNil(t, s1.kvsTimers.Get(kvsTimerID("deleted", entMeta)))
+		apply(api.KVDelete, structs.DirEntry{Key: "deleted"})
+		require.Nil(t, s1.kvsTimers.Get(kvsTimerID("deleted", entMeta)))
+	})
+
+	t.Run("failed CAS keeps timer", func(t *testing.T) {
+		apply(api.KVSet, structs.DirEntry{Key: "cas", Value: []byte("a"), TTL: "1s"})
+		require.False(t, apply(api.KVCAS, structs.DirEntry{
+			Key:       "cas",
+			Value:     []byte("b"),
+			RaftIndex: structs.RaftIndex{ModifyIndex: 1},
+		}))
+
+		retry.Run(t, func(r *retry.R) {
+			_, ent, err := s1.fsm.State().KVSGet(nil, "cas", nil)
+			require.NoError(r, err)
+			if ent != nil {
+				r.Fatal("entry has not expired")
+			}
+		})
+	})
+}
+
+func TestTxn_Apply_KVTTL(t *testing.T) {
+	if testing.Short() {
+		t.Skip("too slow for testing.Short")
+	}
+
+	t.Parallel()
+	dir1, s1 := testServer(t)
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	arg := structs.TxnRequest{
+		Datacenter: "dc1",
+		Ops: structs.TxnOps{
+			&structs.TxnOp{
+				KV: &structs.TxnKVOp{
+					Verb:   api.KVSet,
+					DirEnt: structs.DirEntry{Key: "txn", Value: []byte("a"), TTL: "1s"},
+				},
+			},
+		},
+	}
+	var out structs.TxnResponse
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "Txn.Apply", &arg, &out))
+	require.Empty(t, out.Errors)
+	require.NotNil(t, s1.kvsTimers.Get(kvsTimerID("txn", structs.DefaultEnterpriseMetaInDefaultPartition())))
+
+	retry.Run(t, func(r *retry.R) {
+		_, ent, err := s1.fsm.State().KVSGet(nil, "txn", nil)
+		require.NoError(r, err)
+		if ent != nil {
+			r.Fatal("entry has not expired")
+		}
+	})
+}
@@ -305,6 +305,13 @@ func (s *Server) establishLeadership(ctx context.Context) error {
 		return err
 	}
 
+	// The KV expiry timers are reset the same way, for the same reasons.
+	if err := s.initializeKVSTimers(); err != nil {
+		return err
+	}
+
+	s.startKVRevisionPruning(ctx)
+
 	if err := s.establishEnterpriseLeadership(ctx); err != nil {
 		return err
 	}
@@ -341,6 +348,10 @@ func (s *Server) establishLeadership(ctx context.Context) error {
 		s.startLogVerification(ctx)
 	}
 
+	if s.config.LeaderHealthConfig.Enabled {
+		s.startLeaderHealthChecks(ctx)
+	}
+
 	s.logger.Debug("successfully established leadership", "duration", time.Since(start))
 	return nil
 }
@@ -351,12 +362,16 @@ func (s *Server) revokeLeadership() {
 
 	s.stopLogVerification()
 
+	s.stopLeaderHealthChecks()
+
 	// Disable the tombstone GC, since it is only useful as a leader
 	s.tombstoneGC.SetEnabled(false)
 
 	// Clear the session timers on either shutdown or step down, since we
 	// are no longer responsible for session expirations.
 	s.clearAllSessionTimers()
+	s.clearAllKVSTimers()
+	s.stopKVRevisionPruning()
 
 	s.revokeEnterpriseLeadership()
 
@@ -0,0 +1,367 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/armon/go-metrics/prometheus"
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/raft"
+	autopilot "github.com/hashicorp/raft-autopilot"
+
+	"github.com/hashicorp/consul/agent/structs"
+)
+
+var LeaderHealthCounters = []prometheus.CounterDefinition{
+	{
+		Name: []string{"leader", "health", "transfer"},
+		Help: "Increments when the leader transfers leadership because its Raft performance was degraded.",
+	},
+}
+
+const (
+	// raftLatencyWeight is the weight of a new sample in the moving average
+	// of a Raft latency.
+	raftLatencyWeight = 0.2
+
+	// raftLatencyExpiry is how long the average of a Raft latency is kept
+	// without new samples. Past that the operation is considered idle rather
+	// than slow, so a leader doesn't stay unhealthy because of writes that
+	// were slow a long time ago.
+	raftLatencyExpiry = time.Minute
+)
+
+// latencyTracker keeps a moving average of the time an operation takes. An
+// operation that is still in progress counts for the time it has taken so far,
+// so an operation that hangs, like a write to a failing disk, shows up before
+// it completes.
+type latencyTracker struct {
+	lock     sync.Mutex
+	average  time.Duration
+	last     time.Time
+	nextID   uint64
+	inflight map[uint64]time.Time
+}
+
+// start records the start of an operation. The returned function must be
+// called when the operation is done, with whether its duration should be
+// included in the average.
+func (l *latencyTracker) start() func(record bool) {
+	start := time.Now()
+
+	l.lock.Lock()
+	if l.inflight == nil {
+		l.inflight = make(map[uint64]time.Time)
+	}
+	id := l.nextID
+	l.nextID++
+	l.inflight[id] = start
+	l.lock.Unlock()
+
+	return func(record bool) {
+		now := time.Now()
+
+		l.lock.Lock()
+		defer l.lock.Unlock()
+
+		delete(l.inflight, id)
+		if record {
+			l.observeLocked(now.Sub(start), now)
+		}
+	}
+}
+
+// observe adds the duration of a completed operation to the average.
+func (l *latencyTracker) observe(d time.Duration, now time.Time) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.observeLocked(d, now)
+}
+
+func (l *latencyTracker) observeLocked(d time.Duration, now time.Time) {
+	if l.last.IsZero() || now.Sub(l.last) > raftLatencyExpiry {
+		l.average = d
+	} else {
+		l.average += time.Duration(raftLatencyWeight * float64(d-l.average))
+	}
+	l.last = now
+}
+
+// value returns the current average, or the time taken so far by the oldest
+// operation in progress when that is longer.
+func (l *latencyTracker) value(now time.Time) time.Duration {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	var v time.Duration
+	if !l.last.IsZero() && now.Sub(l.last) <= raftLatencyExpiry {
+		v = l.average
+	}
+	for _, start := range l.inflight {
+		if d := now.Sub(start); d > v {
+			v = d
+		}
+	}
+	return v
+}
+
+// raftLatencies tracks how long this server takes to apply, store and commit
+// Raft logs. They are reported in the Raft stats of the server.
+type raftLatencies struct {
+	fsmApply  latencyTracker
+	storeLogs latencyTracker
+	commit    latencyTracker
+}
+
+func (r *raftLatencies) fillStats(stats *structs.RaftStats, now time.Time) {
+	stats.FSMApplyTime = r.fsmApply.value(now)
+	stats.StoreLogsTime = r.storeLogs.value(now)
+	stats.CommitTime = r.commit.value(now)
+}
+
+// timedLogStore wraps a raft.LogStore to track the time it takes to write
+// logs, which includes syncing them to disk.
+type timedLogStore struct {
+	raft.LogStore
+	tracker *latencyTracker
+}
+
+func (t *timedLogStore) StoreLog(log *raft.Log) error {
+	done := t.tracker.start()
+	err := t.LogStore.StoreLog(log)
+	done(err == nil)
+	return err
+}
+
+func (t *timedLogStore) StoreLogs(logs []*raft.Log) error {
+	done := t.tracker.start()
+	err := t.LogStore.StoreLogs(logs)
+	done(err == nil)
+	return err
+}
+
+// IsMonotonic passes the raft.MonotonicLogStore interface of the wrapped store
+// through.
+func (t *timedLogStore) IsMonotonic() bool {
+	if store, ok := t.LogStore.(raft.MonotonicLogStore); ok {
+		return store.IsMonotonic()
+	}
+	return false
+}
+
+// timedFSM wraps a raft.FSM to track the time it takes to apply logs.
+type timedFSM struct {
+	raft.FSM
+	tracker *latencyTracker
+}
+
+func (t *timedFSM) Apply(log *raft.Log) interface{} {
+	done := t.tracker.start()
+	defer done(true)
+	return t.FSM.Apply(log)
+}
+
+// leaderHealthMonitor decides when the leader has been slow for long enough
+// that it should hand leadership over to another voter.
+type leaderHealthMonitor struct {
+	config RaftLeaderHealthConfig
+
+	lock           sync.Mutex
+	unhealthySince time.Time
+	lastTransfer   time.Time
+}
+
+func newLeaderHealthMonitor(config RaftLeaderHealthConfig) *leaderHealthMonitor {
+	return &leaderHealthMonitor{config: config}
+}
+
+// reset forgets about the leader being unhealthy. It's called when a server
+// becomes the leader, the time of the last transfer is kept so the cooldown
+// also applies when leadership comes back quickly.
+func (m *leaderHealthMonitor) reset() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.unhealthySince = time.Time{}
+}
+
+// check returns the reasons the leader is unhealthy given its own Raft stats,
+// and whether leadership should be transferred now. That's when the leader has
+// been unhealthy for the whole unhealthy period and the cooldown after the
+// last transfer has passed.
+func (m *leaderHealthMonitor) check(stats *structs.RaftStats, now time.Time) ([]string, bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	reasons := leaderHealthViolations(m.config, stats)
+	if len(reasons) == 0 {
+		m.unhealthySince = time.Time{}
+		return nil, false
+	}
+	if m.unhealthySince.IsZero() {
+		m.unhealthySince = now
+	}
+	if now.Sub(m.unhealthySince) < m.config.UnhealthyPeriod {
+		return reasons, false
+	}
+	if !m.lastTransfer.IsZero() && now.Sub(m.lastTransfer) < m.config.TransferCooldown {
+		return reasons, false
+	}
+	return reasons, true
+}
+
+// transferred records an attempt to transfer leadership, which starts the
+// cooldown. When the attempt didn't find a target, it also restarts the
+// unhealthy period so the next attempt isn't made on every check.
+func (m *leaderHealthMonitor) transferred(now time.Time, ok bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if ok {
+		m.lastTransfer = now
+	}
+	m.unhealthySince = now
+}
+
+// leaderHealthViolations returns a description of each threshold the stats
+// exceed. Thresholds set to zero are not checked.
+func leaderHealthViolations(config RaftLeaderHealthConfig, stats *structs.RaftStats) []string {
+	var reasons []string
+	check := func(name string, value, threshold time.Duration) {
+		if threshold > 0 && value > threshold {
+			reasons = append(reasons, fmt.Sprintf("%s %s exceeds %s", name, value, threshold))
+		}
+	}
+	check("FSM apply time", stats.FSMApplyTime, config.FSMApplyThreshold)
+	check("store logs time", stats.StoreLogsTime, config.StoreLogsThreshold)
+	check("commit time", stats.CommitTime, config.CommitThreshold)
+	return reasons
+}
+
+// leaderHealthScore returns how close the stats are to the thresholds, as the
+// highest ratio of a stat to its threshold. A score of one or more means the
+// server would be unhealthy as the leader. The commit time isn't included since
+// only the leader commits logs.
+func leaderHealthScore(config RaftLeaderHealthConfig, stats *structs.RaftStats) float64 {
+	var score float64
+	ratio := func(value, threshold time.Duration) {
+		if threshold <= 0 {
+			return
+		}
+		if r := float64(value) / float64(threshold); r > score {
+			score = r
+		}
+	}
+	ratio(stats.FSMApplyTime, config.FSMApplyThreshold)
+	ratio(stats.StoreLogsTime, config.StoreLogsThreshold)
+	return score
+}
+
+// leaderHealthTarget picks the voter to transfer leadership to. It's the
+// healthy, alive voter whose Raft stats are the furthest below the thresholds.
+// It returns an empty ID when there is no suitable voter.
+func leaderHealthTarget(config RaftLeaderHealthConfig, state *autopilot.State, stats map[raft.ServerID]*structs.RaftStats) raft.ServerID {
+	if state == nil {
+		return ""
+	}
+
+	type candidate struct {
+		id    raft.ServerID
+		score float64
+	}
+	var candidates []candidate
+	for id, srv := range state.Servers {
+		if id == state.Leader || srv.State != autopilot.RaftVoter ||
+			srv.Server.NodeStatus != autopilot.NodeAlive || !srv.Health.Healthy {
+			continue
+		}
+		s, ok := stats[id]
+		if !ok {
+			continue
+		}
+		score := leaderHealthScore(config, s)
+		if score >= 1 {
+			continue
+		}
+		candidates = append(candidates, candidate{id: id, score: score})
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].score != candidates[j].score {
+			return candidates[i].score < candidates[j].score
+		}
+		return candidates[i].id < candidates[j].id
+	})
+	return candidates[0].id
+}
+
+func (s *Server) startLeaderHealthChecks(ctx context.Context) {
+	s.leaderRoutineManager.Start(ctx, leaderHealthRoutineName, s.runLeaderHealthChecks)
+}
+
+func (s *Server) stopLeaderHealthChecks() {
+	s.leaderRoutineManager.Stop(leaderHealthRoutineName)
+}
+
+// runLeaderHealthChecks watches the Raft performance of the leader, and
+// transfers leadership to the healthiest voter once the leader has exceeded
+// the configured thresholds for the unhealthy period.
+func (s *Server) runLeaderHealthChecks(ctx context.Context) error {
+	s.leaderHealth.reset()
+
+	ticker := time.NewTicker(s.config.ServerHealthInterval)
+	defer ticker.Stop()
+
+	logger := s.logger.Named("leader_health")
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			s.checkLeaderHealth(logger)
+		}
+	}
+}
+
+func (s *Server) checkLeaderHealth(logger hclog.Logger) {
+	now := time.Now()
+
+	var stats structs.RaftStats
+	s.raftLatencies.fillStats(&stats, now)
+
+	reasons, transfer := s.leaderHealth.check(&stats, now)
+	if !transfer {
+		if len(reasons) > 0 {
+			logger.Debug("leader exceeds Raft performance thresholds",
+				"reasons", strings.Join(reasons, ", "))
+		}
+		return
+	}
+
+	target := leaderHealthTarget(s.config.LeaderHealthConfig, s.autopilot.GetState(), s.statsFetcher.LastStats())
+	if target == "" {
+		logger.Warn("leader exceeds Raft performance thresholds but there is no healthy voter to transfer leadership to",
+			"reasons", strings.Join(reasons, ", "))
+		s.leaderHealth.transferred(now, false)
+		return
+	}
+
+	logger.Warn("transferring leadership because the leader exceeds Raft performance thresholds",
+		"target", target,
+		"reasons", strings.Join(reasons, ", "),
+	)
+	s.leaderHealth.transferred(now, true)
+	metrics.IncrCounter([]string{"leader", "health", "transfer"}, 1)
+	if err := s.attemptLeadershipTransfer(target); err != nil {
+		logger.Error("failed to transfer leadership", "target", target, "error", err)
+	}
+}
@@ -0,0 +1,205 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	msgpackrpc "github.com/hashicorp/consul-net-rpc/net-rpc-msgpackrpc"
+	"github.com/hashicorp/raft"
+	autopilot "github.com/hashicorp/raft-autopilot"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+	"github.com/hashicorp/consul/testrpc"
+)
+
+func TestLatencyTracker(t *testing.T) {
+	var l latencyTracker
+	now := time.Now()
+	require.Zero(t, l.value(now))
+
+	l.observe(100*time.Millisecond, now)
+	require.Equal(t, 100*time.Millisecond, l.value(now))
+
+	// New samples move the average by their weight.
+	l.observe(600*time.Millisecond, now)
+	require.Equal(t, 200*time.Millisecond, l.value(now))
+
+	// The average expires without new samples.
+	require.Zero(t, l.value(now.Add(raftLatencyExpiry+time.Second)))
+
+	// An operation in progress counts once it's been running for longer than
+	// the average.
+	done := l.start()
+	require.Equal(t, 200*time.Millisecond, l.value(time.Now()))
+	require.Equal(t, time.Hour, l.value(time.Now().Add(time.Hour)).Round(time.Hour))
+	done(false)
+	require.Equal(t, 200*time.Millisecond, l.value(time.Now()))
+}
+
+func TestLeaderHealthMonitor_Check(t *testing.T) {
+	m := newLeaderHealthMonitor(RaftLeaderHealthConfig{
+		Enabled:            true,
+		FSMApplyThreshold:  100 * time.Millisecond,
+		StoreLogsThreshold: 200 * time.Millisecond,
+		UnhealthyPeriod:    10 * time.Second,
+		TransferCooldown:   time.Minute,
+	})
+	healthy := &structs.RaftStats{FSMApplyTime: time.Millisecond, StoreLogsTime: time.Millisecond, CommitTime: time.Hour}
+	slow := &structs.RaftStats{FSMApplyTime: time.Millisecond, StoreLogsTime: time.Second}
+
+	now := time.Now()
+	reasons, transfer := m.check(healthy, now)
+	require.Empty(t, reasons)
+	require.False(t, transfer)
+
+	reasons, transfer = m.check(slow, now)
+	require.Equal(t, []string{"store logs time 1s exceeds 200ms"}, reasons)
+	require.False(t, transfer)
+
+	// Recovering restarts the unhealthy period.
+	_, transfer = m.check(healthy, now.Add(5*time.Second))
+	require.False(t, transfer)
+	_, transfer = m.check(slow, now.Add(10*time.Second))
+	require.False(t, transfer)
+	_, transfer = m.check(slow, now.Add(20*time.Second))
+	require.True(t, transfer)
+	m.transferred(now.Add(20*time.Second), true)
+
+	// Leadership came back, but the cooldown isn't over yet.
+	m.reset()
+	_, transfer = m.check(slow, now.Add(30*time.Second))
+	require.False(t, transfer)
+	_, transfer = m.check(slow, now.Add(40*time.Second))
+	require.False(t, transfer)
+	_, transfer = m.check(slow, now.Add(80*time.Second))
+	require.True(t, transfer)
+
+	// Without a target the unhealthy period starts over.
+	m.transferred(now.Add(80*time.Second), false)
+	_, transfer = m.check(slow, now.Add(85*time.Second))
+	require.False(t, transfer)
+	_, transfer = m.check(slow, now.Add(90*time.Second))
+	require.True(t, transfer)
+}
+
+func TestLeaderHealthTarget(t *testing.T) {
+	config := RaftLeaderHealthConfig{
+		FSMApplyThreshold:  100 * time.Millisecond,
+		StoreLogsThreshold: 200 * time.Millisecond,
+		CommitThreshold:    time.Second,
+	}
+	server := func(id raft.ServerID, state autopilot.RaftState, healthy bool) *autopilot.ServerState {
+		return &autopilot.ServerState{
+			Server: autopilot.Server{ID: id, NodeStatus: autopilot.NodeAlive},
+			State:  state,
+			Health: autopilot.ServerHealth{Healthy: healthy},
+		}
+	}
+	state := &autopilot.State{
+		Leader: "s1",
+		Servers: map[raft.ServerID]*autopilot.ServerState{
+			"s1": server("s1", autopilot.RaftLeader, true),
+			"s2": server("s2", autopilot.RaftVoter, true),
+			"s3": server("s3", autopilot.RaftVoter, true),
+			"s4": server("s4", autopilot.RaftVoter, false),
+			"s5": server("s5", autopilot.RaftNonVoter, true),
+			"s6": server("s6", autopilot.RaftVoter, true),
+		},
+	}
+	stats := map[raft.ServerID]*structs.RaftStats{
+		"s1": {},
+		"s2": {FSMApplyTime: 50 * time.Millisecond, StoreLogsTime: 20 * time.Millisecond},
+		"s3": {FSMApplyTime: 10 * time.Millisecond, StoreLogsTime: 40 * time.Millisecond},
+		"s4": {},
+		"s5": {},
+	}
+
+	require.Equal(t, raft.ServerID("s3"), leaderHealthTarget(config, state, stats))
+
+	// Servers that would be unhealthy as the leader aren't picked.
+	stats["s3"].StoreLogsTime = 300 * time.Millisecond
+	require.Equal(t, raft.ServerID("s2"), leaderHealthTarget(config, state, stats))
+
+	stats["s2"].FSMApplyTime = 150 * time.Millisecond
+	require.Equal(t, raft.ServerID(""), leaderHealthTarget(config, state, stats))
+	require.Equal(t, raft.ServerID(""), leaderHealthTarget(config, nil, stats))
+}
+
+func TestLeader_LeaderHealthTransfer(t *testing.T) {
+	if testing.Short() {
+		t.Skip("too slow for testing.Short")
+	}
+
+	t.Parallel()
+	conf := func(c *Config) {
+		c.Datacenter = "dc1"
+		c.ServerHealthInterval = 100 * time.Millisecond
+		c.AutopilotInterval = 100 * time.Millisecond
+		c.LeaderHealthConfig = RaftLeaderHealthConfig{
+			Enabled:            true,
+			StoreLogsThreshold: 100 * time.Millisecond,
+			UnhealthyPeriod:    500 * time.Millisecond,
+			TransferCooldown:   time.Hour,
+		}
+	}
+	dir1, s1 := testServerWithConfig(t, func(c *Config) {
+		conf(c)
+		c.Bootstrap = true
+	})
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+
+	var servers []*Server
+	servers = append(servers, s1)
+	for i := 0; i < 2; i++ {
+		dir, s := testServerWithConfig(t, func(c *Config) {
+			conf(c)
+			c.Bootstrap = false
+		})
+		defer os.RemoveAll(dir)
+		defer s.Shutdown()
+		joinLAN(t, s, s1)
+		servers = append(servers, s)
+	}
+	for _, s := range servers {
+		testrpc.WaitForLeader(t, s.RPC, "dc1")
+		retry.Run(t, func(r *retry.R) { r.Check(wantPeers(s, 3)) })
+	}
+
+	var leader *Server
+	for _, s := range servers {
+		if s.IsLeader() {
+			leader = s
+		}
+	}
+	require.NotNil(t, leader)
+
+	// Simulate a write to the log store of the leader that hangs.
+	done := leader.raftLatencies.storeLogs.start()
+	defer done(false)
+
+	retry.Run(t, func(r *retry.R) {
+		if leader.IsLeader() {
+			r.Fatal("leadership was not transferred")
+		}
+		for _, s := range servers {
+			if s != leader && s.IsLeader() {
+				return
+			}
+		}
+		r.Fatal("no new leader")
+	})
+
+	// The slow write is reported in the Raft stats.
+	codec := rpcClient(t, leader)
+	defer codec.Close()
+	var stats structs.RaftStats
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "Status.RaftStats", &EmptyReadRequest{}, &stats))
+	require.Greater(t, stats.StoreLogsTime, 100*time.Millisecond)
+}
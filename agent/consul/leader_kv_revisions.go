@@ -0,0 +1,64 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/consul/agent/structs"
+)
+
+var (
+	// kvRevisionPruneInterval is how often the leader stamps new KV revisions
+	// with the time and removes the revisions beyond their history limits. It
+	// bounds how late a revision may be removed after its MaxAge.
+	kvRevisionPruneInterval = time.Minute
+)
+
+func (s *Server) startKVRevisionPruning(ctx context.Context) {
+	s.leaderRoutineManager.Start(ctx, kvRevisionPruningRoutineName, s.kvRevisionPruning)
+}
+
+func (s *Server) stopKVRevisionPruning() {
+	s.leaderRoutineManager.Stop(kvRevisionPruningRoutineName)
+}
+
+func (s *Server) kvRevisionPruning(ctx context.Context) error {
+	ticker := time.NewTicker(kvRevisionPruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := s.pruneKVRevisions(time.Now()); err != nil {
+				s.logger.Error("error pruning KV revisions", "error", err)
+			}
+		}
+	}
+}
+
+// pruneKVRevisions applies a prune of the KV revision history, unless it
+// would not change anything. The time is set by the leader so that all the
+// servers agree on the age of the revisions.
+func (s *Server) pruneKVRevisions(now time.Time) error {
+	prune, err := s.fsm.State().KVSRevisionsToPrune(now)
+	if err != nil || !prune {
+		return err
+	}
+
+	req := structs.KVRevisionPruneRequest{
+		Datacenter: s.config.Datacenter,
+		Now:        now.UTC(),
+	}
+	_, err = s.leaderRaftApply("KVS.PruneRevisions",
+		structs.KVRevisionPruneRequestType|structs.IgnoreUnknownTypeFlag, &req)
+	if err != nil {
+		return fmt.Errorf("Failed to apply KV revision prune: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	msgpackrpc "github.com/hashicorp/consul-net-rpc/net-rpc-msgpackrpc"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/consul/acl"
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/testrpc"
+)
+
+func TestKVS_Revisions(t *testing.T) {
+	if testing.Short() {
+		t.Skip("too slow for testing.Short")
+	}
+
+	t.Parallel()
+	dir1, s1 := testServerWithConfig(t, func(c *Config) {
+		c.PrimaryDatacenter = "dc1"
+		c.ACLsEnabled = true
+		c.ACLInitialManagementToken = "root"
+		c.ACLResolverSettings.ACLDefaultPolicy = "deny"
+	})
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForTestAgent(t, s1.RPC, "dc1", testrpc.WithToken("root"))
+
+	confArgs := structs.ConfigEntryRequest{
+		Datacenter: "dc1",
+		Entry: &structs.KVPrefixConfigEntry{
+			Name:    "config",
+			Prefix:  "config/",
+			History: &structs.KVHistoryConfig{MaxRevisions: 10, MaxAge: time.Hour},
+		},
+		WriteRequest: structs.WriteRequest{Token: "root"},
+	}
+	var confOut bool
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "ConfigEntry.Apply", &confArgs, &confOut))
+	require.True(t, confOut)
+
+	var indexes []uint64
+	for _, value := range []string{"v1", "v2", "v3"} {
+		arg := structs.KVSRequest{
+			Datacenter:   "dc1",
+			Op:           api.KVSet,
+			DirEnt:       structs.DirEntry{Key: "config/app", Value: []byte(value)},
+			WriteRequest: structs.WriteRequest{Token: "root"},
+		}
+		var out bool
+		require.NoError(t, msgpackrpc.CallWithCodec(codec, "KVS.Apply", &arg, &out))
+		_, ent, err := s1.fsm.State().KVSGet(nil, "config/app", nil)
+		require.NoError(t, err)
+		indexes = append(indexes, ent.ModifyIndex)
+	}
+
+	t.Run("revisions", func(t *testing.T) {
+		args := structs.KeyRequest{
+			Datacenter:   "dc1",
+			Key:          "config/app",
+			QueryOptions: structs.QueryOptions{Token: "root"},
+		}
+		var out structs.IndexedDirEntryRevisions
+		require.NoError(t, msgpackrpc.CallWithCodec(codec, "KVS.Revisions", &args, &out))
+		require.Len(t, out.Revisions, 2)
+		require.Equal(t, "v2", string(out.Revisions[0].Value))
+		require.Equal(t, indexes[2], out.Revisions[0].SupersededIndex)
+		require.Equal(t, "v1", string(out.Revisions[1].Value))
+	})
+
+	t.Run("at index", func(t *testing.T) {
+		args := structs.KeyRequest{
+			Datacenter:   "dc1",
+			Key:          "config/app",
+			AtIndex:      indexes[1],
+			QueryOptions: structs.QueryOptions{Token: "root"},
+		}
+		var out structs.IndexedDirEntries
+		require.NoError(t, msgpackrpc.CallWithCodec(codec, "KVS.Get", &args, &out))
+		require.Len(t, out.Entries, 1)
+		require.Equal(t, "v2", string(out.Entries[0].Value))
+
+		args.AtIndex = indexes[0] - 1
+		out = structs.IndexedDirEntries{}
+		require.NoError(t, msgpackrpc.CallWithCodec(codec, "KVS.Get", &args, &out))
+		require.Empty(t, out.Entries)
+	})
+
+	t.Run("ACL deny", func(t *testing.T) {
+		args := structs.KeyRequest{
+			Datacenter: "dc1",
+			Key:        "config/app",
+		}
+		var out structs.IndexedDirEntryRevisions
+		err := msgpackrpc.CallWithCodec(codec, "KVS.Revisions", &args, &out)
+		require.True(t, acl.IsErrPermissionDenied(err), "unexpected error: %v", err)
+	})
+
+	t.Run("prune", func(t *testing.T) {
+		now := time.Now()
+		require.NoError(t, s1.pruneKVRevisions(now))
+		_, revs, err := s1.fsm.State().KVSRevisions(nil, "config/app", nil)
+		require.NoError(t, err)
+		require.Len(t, revs, 2)
+		require.False(t, revs[0].SupersededAt.IsZero())
+
+		// Nothing is applied when nothing would change.
+		idx, _, err := s1.fsm.State().KVSRevisions(nil, "config/app", nil)
+		require.NoError(t, err)
+		require.NoError(t, s1.pruneKVRevisions(now.Add(time.Minute)))
+		idx2, _, err := s1.fsm.State().KVSRevisions(nil, "config/app", nil)
+		require.NoError(t, err)
+		require.Equal(t, idx, idx2)
+
+		require.NoError(t, s1.pruneKVRevisions(now.Add(time.Hour)))
+		_, revs, err = s1.fsm.State().KVSRevisions(nil, "config/app", nil)
+		require.NoError(t, err)
+		require.Empty(t, revs)
+	})
+}
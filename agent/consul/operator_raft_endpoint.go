@@ -6,6 +6,7 @@ package consul
 import (
 	"fmt"
 	"net"
+	"sync"
 
 	"github.com/hashicorp/raft"
 	"github.com/hashicorp/serf/serf"
@@ -37,16 +38,7 @@ func (op *Operator) RaftGetConfiguration(args *structs.DCSpecificRequest, reply
 	}
 
 	// Index the Consul information about the servers.
-	serverMap := make(map[raft.ServerAddress]serf.Member)
-	for _, member := range op.srv.serfLAN.Members() {
-		valid, parts := metadata.IsConsulServer(member)
-		if !valid {
-			continue
-		}
-
-		addr := (&net.TCPAddr{IP: member.Addr, Port: parts.Port}).String()
-		serverMap[raft.ServerAddress(addr)] = member
-	}
+	serverMap := op.srv.raftServerMembers()
 
 	// Fill out the reply.
 	leader := op.srv.raft.Leader()
@@ -54,9 +46,13 @@ func (op *Operator) RaftGetConfiguration(args *structs.DCSpecificRequest, reply
 	for _, server := range future.Configuration().Servers {
 		node := "(unknown)"
 		raftProtocolVersion := "unknown"
+		readReplica := false
 		if member, ok := serverMap[server.Address]; ok {
 			node = member.Name
 			raftProtocolVersion = member.Tags["raft_vsn"]
+			if _, parts := metadata.IsConsulServer(member); parts != nil {
+				readReplica = parts.ReadReplica
+			}
 		}
 
 		entry := &structs.RaftServer{
@@ -65,6 +61,7 @@ func (op *Operator) RaftGetConfiguration(args *structs.DCSpecificRequest, reply
 			Address:         server.Address,
 			Leader:          server.Address == leader,
 			Voter:           server.Suffrage == raft.Voter,
+			ReadReplica:     readReplica,
 			ProtocolVersion: raftProtocolVersion,
 		}
 		reply.Servers = append(reply.Servers, entry)
@@ -185,3 +182,140 @@ REMOVE:
 	op.logger.Warn("Removed Raft peer with id", "peer_id", args.ID)
 	return nil
 }
+
+// raftServerMembers indexes the LAN members of the Consul servers by their
+// Raft address.
+func (s *Server) raftServerMembers() map[raft.ServerAddress]serf.Member {
+	serverMap := make(map[raft.ServerAddress]serf.Member)
+	for _, member := range s.serfLAN.Members() {
+		valid, parts := metadata.IsConsulServer(member)
+		if !valid {
+			continue
+		}
+
+		addr := (&net.TCPAddr{IP: member.Addr, Port: parts.Port}).String()
+		serverMap[raft.ServerAddress(addr)] = member
+	}
+	return serverMap
+}
+
+// RaftLogVerification is used to retrieve the state of the Raft log
+// verification of all the servers in the Raft configuration.
+func (op *Operator) RaftLogVerification(args *structs.DCSpecificRequest, reply *structs.RaftLogVerificationResponse) error {
+	if done, err := op.srv.ForwardRPC("Operator.RaftLogVerification", args, reply); done {
+		return err
+	}
+
+	// This action requires operator read access.
+	authz, err := op.srv.ResolveToken(args.Token)
+	if err != nil {
+		return err
+	}
+	if err := authz.ToAllowAuthorizer().OperatorReadAllowed(nil); err != nil {
+		return err
+	}
+
+	future := op.srv.raft.GetConfiguration()
+	if err := future.Error(); err != nil {
+		return err
+	}
+	serverMap := op.srv.raftServerMembers()
+
+	// Fetch the state from all the servers in parallel.
+	var wg sync.WaitGroup
+	for _, server := range future.Configuration().Servers {
+		status := &structs.RaftLogVerificationStatus{
+			ID:      server.ID,
+			Node:    "(unknown)",
+			Address: server.Address,
+		}
+		reply.Servers = append(reply.Servers, status)
+
+		member, ok := serverMap[server.Address]
+		if ok {
+			status.Node = member.Name
+		}
+
+		if server.ID == raft.ServerID(op.srv.config.NodeID) {
+			op.srv.fillLogVerificationStatus(status)
+			continue
+		}
+		if !ok {
+			status.Error = "server is not known to Serf"
+			continue
+		}
+
+		wg.Add(1)
+		go func(status *structs.RaftLogVerificationStatus, name string) {
+			defer wg.Done()
+			op.srv.fetchLogVerificationStatus(status, name)
+		}(status, member.Name)
+	}
+	wg.Wait()
+
+	return nil
+}
+
+// fillLogVerificationStatus sets the log verification state of this server on
+// the status, keeping the fields that identify the server.
+func (s *Server) fillLogVerificationStatus(status *structs.RaftLogVerificationStatus) {
+	local := s.logVerification.get()
+	local.ID, local.Node, local.Address = status.ID, status.Node, status.Address
+	local.Enabled = s.config.LogStoreConfig.Verification.Enabled
+	*status = local
+}
+
+// fetchLogVerificationStatus sets the log verification state of another
+// server on the status, or the error preventing to fetch it.
+func (s *Server) fetchLogVerificationStatus(status *structs.RaftLogVerificationStatus, name string) {
+	addr, err := net.ResolveTCPAddr("tcp", string(status.Address))
+	if err != nil {
+		status.Error = err.Error()
+		return
+	}
+
+	var remote structs.RaftLogVerificationStatus
+	err = s.connPool.RPC(s.config.Datacenter, name, addr, "Status.RaftLogVerification", &EmptyReadRequest{}, &remote)
+	if err != nil {
+		status.Error = err.Error()
+		return
+	}
+	remote.ID, remote.Node, remote.Address = status.ID, status.Node, status.Address
+	*status = remote
+}
+
+// RaftLogVerificationCheckpoint writes a log verification checkpoint to the
+// Raft log, which makes every server verify the logs written since the
+// previous checkpoint without waiting for the verification interval.
+func (op *Operator) RaftLogVerificationCheckpoint(args *structs.RaftLogVerificationCheckpointRequest, reply *structs.RaftLogVerificationCheckpointResponse) error {
+	if done, err := op.srv.ForwardRPC("Operator.RaftLogVerificationCheckpoint", args, reply); done {
+		return err
+	}
+
+	// This action requires operator write access.
+	authz, err := op.srv.ACLResolver.ResolveToken(args.Token)
+	if err != nil {
+		return err
+	}
+	if err := op.srv.validateEnterpriseToken(authz.Identity()); err != nil {
+		return err
+	}
+	if err := authz.ToAllowAuthorizer().OperatorWriteAllowed(nil); err != nil {
+		return err
+	}
+
+	if !op.srv.config.LogStoreConfig.Verification.Enabled {
+		return fmt.Errorf("Raft log verification is not enabled on the leader")
+	}
+
+	typ := structs.RaftLogVerifierCheckpoint | structs.IgnoreUnknownTypeFlag
+	raw, err := op.srv.raftApplyMsgpack(typ, nil)
+	if err != nil {
+		return err
+	}
+	if index, ok := raw.(uint64); ok {
+		reply.Index = index
+	}
+	op.logger.Info("Wrote Raft log verification checkpoint", "index", reply.Index)
+	return nil
+}
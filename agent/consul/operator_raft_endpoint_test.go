@@ -18,6 +18,7 @@ import (
 	"github.com/hashicorp/consul/acl"
 	"github.com/hashicorp/consul/agent/structs"
 	"github.com/hashicorp/consul/sdk/freeport"
+	"github.com/hashicorp/consul/sdk/testutil/retry"
 	"github.com/hashicorp/consul/testrpc"
 )
 
@@ -341,3 +342,85 @@ func TestOperator_RaftRemovePeerByID_ACLDeny(t *testing.T) {
 		t.Fatalf("err: %v", err)
 	}
 }
+
+func TestOperator_RaftLogVerification(t *testing.T) {
+	if testing.Short() {
+		t.Skip("too slow for testing.Short")
+	}
+
+	t.Parallel()
+	conf := func(c *Config) {
+		c.Datacenter = "dc1"
+		c.LogStoreConfig.Verification.Enabled = true
+		c.LogStoreConfig.Verification.Interval = time.Hour
+	}
+	dir1, s1 := testServerWithConfig(t, func(c *Config) {
+		conf(c)
+		c.Bootstrap = true
+	})
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	dir2, s2 := testServerWithConfig(t, func(c *Config) {
+		conf(c)
+		c.Bootstrap = false
+	})
+	defer os.RemoveAll(dir2)
+	defer s2.Shutdown()
+	joinLAN(t, s2, s1)
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+	retry.Run(t, func(r *retry.R) { r.Check(wantPeers(s1, 2)) })
+
+	var cp structs.RaftLogVerificationCheckpointResponse
+	req := structs.RaftLogVerificationCheckpointRequest{Datacenter: "dc1"}
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "Operator.RaftLogVerificationCheckpoint", &req, &cp))
+	require.NotZero(t, cp.Index)
+
+	retry.Run(t, func(r *retry.R) {
+		arg := structs.DCSpecificRequest{Datacenter: "dc1"}
+		var reply structs.RaftLogVerificationResponse
+		require.NoError(r, msgpackrpc.CallWithCodec(codec, "Operator.RaftLogVerification", &arg, &reply))
+		require.Len(r, reply.Servers, 2)
+
+		nodes := make(map[string]bool)
+		for _, srv := range reply.Servers {
+			nodes[srv.Node] = true
+			require.Empty(r, srv.Error)
+			require.True(r, srv.Enabled)
+			require.NotNil(r, srv.LastReport)
+			require.Equal(r, cp.Index, srv.LastReport.Range.End)
+			require.Equal(r, structs.RaftLogVerificationOK, srv.LastReport.Status, srv.LastReport.Error)
+			require.Nil(r, srv.LastFailure)
+		}
+		require.Equal(r, map[string]bool{s1.config.NodeName: true, s2.config.NodeName: true}, nodes)
+	})
+}
+
+func TestOperator_RaftLogVerificationCheckpoint_NotEnabled(t *testing.T) {
+	if testing.Short() {
+		t.Skip("too slow for testing.Short")
+	}
+
+	t.Parallel()
+	dir1, s1 := testServer(t)
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	var cp structs.RaftLogVerificationCheckpointResponse
+	req := structs.RaftLogVerificationCheckpointRequest{Datacenter: "dc1"}
+	err := msgpackrpc.CallWithCodec(codec, "Operator.RaftLogVerificationCheckpoint", &req, &cp)
+	require.ErrorContains(t, err, "Raft log verification is not enabled")
+
+	arg := structs.DCSpecificRequest{Datacenter: "dc1"}
+	var reply structs.RaftLogVerificationResponse
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "Operator.RaftLogVerification", &arg, &reply))
+	require.Len(t, reply.Servers, 1)
+	require.False(t, reply.Servers[0].Enabled)
+	require.Nil(t, reply.Servers[0].LastReport)
+}
@@ -5,6 +5,7 @@ package consul
 
 import (
 	"github.com/hashicorp/consul/acl"
+	rpcRate "github.com/hashicorp/consul/agent/consul/rate"
 	"github.com/hashicorp/consul/agent/consul/state"
 	"github.com/hashicorp/consul/agent/structs"
 	"github.com/hashicorp/go-memdb"
@@ -13,6 +14,8 @@ import (
 // Usage returns counts for service usage within catalog.
 func (op *Operator) Usage(args *structs.OperatorUsageRequest, reply *structs.Usage) error {
 	reply.Usage = make(map[string]structs.ServiceUsage)
+	reply.KVPrefixes = make(map[string][]structs.KVPrefixUsage)
+	reply.RPCConcurrency = make(map[string][]structs.RPCConcurrencyUsage)
 
 	if args.Global {
 		remoteDCs := op.srv.router.GetDatacenters()
@@ -23,6 +26,7 @@ func (op *Operator) Usage(args *structs.OperatorUsageRequest, reply *structs.Usa
 					QueryOptions: structs.QueryOptions{
 						Token: args.Token,
 					},
+					EnterpriseMeta: args.EnterpriseMeta,
 				},
 			}
 			var resp structs.Usage
@@ -32,6 +36,12 @@ func (op *Operator) Usage(args *structs.OperatorUsageRequest, reply *structs.Usa
 			if usage, ok := resp.Usage[dc]; ok {
 				reply.Usage[dc] = usage
 			}
+			if usage, ok := resp.KVPrefixes[dc]; ok {
+				reply.KVPrefixes[dc] = usage
+			}
+			if usage, ok := resp.RPCConcurrency[dc]; ok {
+				reply.RPCConcurrency[dc] = usage
+			}
 		}
 	}
 
@@ -59,7 +69,42 @@ func (op *Operator) Usage(args *structs.OperatorUsageRequest, reply *structs.Usa
 				return err
 			}
 
+			// Get the usage of the kv-prefix config entries.
+			kvIndex, kvPrefixUsage, err := state.KVPrefixUsage(ws, &args.EnterpriseMeta)
+			if err != nil {
+				return err
+			}
+			if kvIndex > index {
+				index = kvIndex
+			}
+
 			reply.QueryMeta.Index, reply.Usage[op.srv.config.Datacenter] = index, serviceUsage
+			if len(kvPrefixUsage) > 0 {
+				reply.KVPrefixes[op.srv.config.Datacenter] = kvPrefixUsage
+			}
+			if concurrencyUsage := op.rpcConcurrencyUsage(); len(concurrencyUsage) > 0 {
+				reply.RPCConcurrency[op.srv.config.Datacenter] = concurrencyUsage
+			}
 			return nil
 		})
 }
+
+// rpcConcurrencyUsage returns the state of the adaptive concurrency limits of
+// this server, if they are enabled.
+func (op *Operator) rpcConcurrencyUsage() []structs.RPCConcurrencyUsage {
+	var usage []structs.RPCConcurrencyUsage
+	for _, s := range op.srv.incomingRPCLimiter.ConcurrencyStats() {
+		if s.Mode == rpcRate.ModeDisabled {
+			continue
+		}
+		usage = append(usage, structs.RPCConcurrencyUsage{
+			Server:   op.srv.config.NodeName,
+			Class:    string(s.Class),
+			Mode:     s.Mode.String(),
+			Limit:    s.Limit,
+			InFlight: s.InFlight,
+			Shed:     s.Shed,
+		})
+	}
+	return usage
+}
@@ -0,0 +1,87 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	rpcRate "github.com/hashicorp/consul/agent/consul/rate"
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+	"github.com/hashicorp/consul/testrpc"
+)
+
+func TestOperator_Usage_RPCConcurrency(t *testing.T) {
+	if testing.Short() {
+		t.Skip("too slow for testing.Short")
+	}
+
+	_, config := testServerConfig(t)
+	config.RequestConcurrencyLimits = rpcRate.ConcurrencyConfig{
+		Mode:     rpcRate.ModeEnforcing,
+		Read:     rpcRate.ConcurrencyClassConfig{InitialLimit: 100, MinLimit: 100, MaxLimit: 100, LatencyTarget: time.Minute},
+		Write:    rpcRate.ConcurrencyClassConfig{InitialLimit: 100, MinLimit: 100, MaxLimit: 100, LatencyTarget: time.Minute},
+		Blocking: rpcRate.ConcurrencyClassConfig{InitialLimit: 1, MinLimit: 1, MaxLimit: 1},
+	}
+	deps := newDefaultDeps(t, config)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	limiter := ConfiguredIncomingRPCLimiter(ctx, deps.Logger, config)
+
+	s, err := NewServer(config, deps, grpc.NewServer(), limiter, deps.Logger)
+	require.NoError(t, err)
+	t.Cleanup(func() { s.Shutdown() })
+	testrpc.WaitForLeader(t, s.RPC, "dc1")
+
+	blockingGet := func() error {
+		var out structs.IndexedDirEntries
+		return s.RPC(context.Background(), "KVS.Get", &structs.KeyRequest{
+			Datacenter: "dc1",
+			Key:        "key",
+			QueryOptions: structs.QueryOptions{
+				MinQueryIndex: 1 << 20,
+				MaxQueryTime:  time.Minute,
+			},
+		}, &out)
+	}
+	usage := func(t require.TestingT) map[string]structs.RPCConcurrencyUsage {
+		var out structs.Usage
+		require.NoError(t, s.RPC(context.Background(), "Operator.Usage", &structs.OperatorUsageRequest{
+			DCSpecificRequest: structs.DCSpecificRequest{Datacenter: "dc1"},
+		}, &out))
+
+		byClass := make(map[string]structs.RPCConcurrencyUsage)
+		for _, u := range out.RPCConcurrency["dc1"] {
+			byClass[u.Class] = u
+		}
+		return byClass
+	}
+
+	// The first blocking query uses the whole budget of the blocking queries.
+	go blockingGet()
+	retry.Run(t, func(r *retry.R) {
+		require.Equal(r, 1, usage(r)["blocking"].InFlight)
+	})
+
+	// So the next one is shed, but the reads have a budget of their own.
+	require.ErrorIs(t, blockingGet(), rpcRate.ErrRetryElsewhere)
+
+	byClass := usage(t)
+	require.Equal(t, structs.RPCConcurrencyUsage{
+		Server:   config.NodeName,
+		Class:    "blocking",
+		Mode:     "enforcing",
+		Limit:    1,
+		InFlight: 1,
+		Shed:     1,
+	}, byClass["blocking"])
+	require.Equal(t, 100, byClass["read"].Limit)
+	require.Zero(t, byClass["read"].Shed)
+}
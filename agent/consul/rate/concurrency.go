@@ -0,0 +1,353 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package rate
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/armon/go-metrics"
+)
+
+// ConcurrencyClass is the budget of concurrent operations that an operation
+// counts against. Each class has a limit of its own so, for example, a flood
+// of blocking queries can't prevent writes from being handled.
+type ConcurrencyClass string
+
+const (
+	// ConcurrencyClassRead is the budget of the non-blocking reads.
+	ConcurrencyClassRead ConcurrencyClass = "read"
+
+	// ConcurrencyClassWrite is the budget of the writes.
+	ConcurrencyClassWrite ConcurrencyClass = "write"
+
+	// ConcurrencyClassBlocking is the budget of the blocking queries.
+	ConcurrencyClassBlocking ConcurrencyClass = "blocking"
+)
+
+// concurrencyClasses lists the classes in the order they are reported.
+var concurrencyClasses = []ConcurrencyClass{
+	ConcurrencyClassRead,
+	ConcurrencyClassWrite,
+	ConcurrencyClassBlocking,
+}
+
+// priority determines which operations are shed first when a class gets close
+// to its limit.
+type priority int
+
+const (
+	// priorityLow is the priority of the stale reads, which any server can
+	// handle. They are shed once the class reaches lowPriorityShare of its
+	// limit.
+	priorityLow priority = iota
+
+	// priorityNormal operations are shed once the class reaches its limit.
+	priorityNormal
+
+	// priorityHigh is the priority of the writes received by the leader, which
+	// can't be retried against another server. They may use up to
+	// highPriorityShare of the limit.
+	priorityHigh
+)
+
+const (
+	lowPriorityShare  = 0.8
+	highPriorityShare = 1.2
+
+	// concurrencyBackoffRatio is the factor the limit of a class is multiplied
+	// by when an operation is slower than the latency target.
+	concurrencyBackoffRatio = 0.9
+
+	// concurrencyMetricsInterval is how often the limit and number of in-flight
+	// operations of each class are emitted.
+	concurrencyMetricsInterval = 10 * time.Second
+)
+
+var priorityToName = map[priority]string{
+	priorityLow:    "low",
+	priorityNormal: "normal",
+	priorityHigh:   "high",
+}
+
+func (p priority) String() string {
+	return priorityToName[p]
+}
+
+// ConcurrencyClassConfig configures the limit of a class of operations.
+type ConcurrencyClassConfig struct {
+	// InitialLimit is the number of concurrent operations allowed before any
+	// of them completed.
+	InitialLimit int
+
+	// MinLimit and MaxLimit bound the adjustments of the limit.
+	MinLimit int
+	MaxLimit int
+
+	// LatencyTarget is the latency over which an operation is considered a
+	// sign of overload, which decreases the limit. It's ignored for the
+	// blocking queries, whose limit follows the latency of the reads since
+	// their own latency mostly depends on how often the data changes.
+	LatencyTarget time.Duration
+}
+
+// ConcurrencyConfig configures the adaptive limits on the number of operations
+// handled concurrently. The limit of each class is adjusted with an AIMD
+// (additive increase, multiplicative decrease) algorithm: it increases by one
+// for each operation completed under the latency target while the class is
+// busy, and decreases by concurrencyBackoffRatio when operations are over it,
+// at most once per latency target.
+type ConcurrencyConfig struct {
+	Mode Mode
+
+	Read     ConcurrencyClassConfig
+	Write    ConcurrencyClassConfig
+	Blocking ConcurrencyClassConfig
+}
+
+func (c ConcurrencyConfig) class(class ConcurrencyClass) ConcurrencyClassConfig {
+	switch class {
+	case ConcurrencyClassWrite:
+		return c.Write
+	case ConcurrencyClassBlocking:
+		return c.Blocking
+	default:
+		return c.Read
+	}
+}
+
+// ConcurrencyStats is the state of the limit of a class of operations.
+type ConcurrencyStats struct {
+	Class    ConcurrencyClass
+	Mode     Mode
+	Limit    int
+	InFlight int
+
+	// Shed is the number of operations over the limit since the server
+	// started. In permissive mode, they were still allowed to proceed.
+	Shed uint64
+}
+
+// concurrencyLimiter tracks the operations of a class in flight and adjusts
+// their limit.
+type concurrencyLimiter struct {
+	class ConcurrencyClass
+
+	lock     sync.Mutex
+	cfg      ConcurrencyClassConfig
+	limit    float64
+	inFlight int
+	shed     uint64
+
+	// lastDecrease is when the limit was last decreased.
+	lastDecrease time.Time
+}
+
+func newConcurrencyLimiter(class ConcurrencyClass, cfg ConcurrencyClassConfig) *concurrencyLimiter {
+	l := &concurrencyLimiter{class: class}
+	l.updateConfig(cfg)
+	return l
+}
+
+func (l *concurrencyLimiter) updateConfig(cfg ConcurrencyClassConfig) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if cfg != l.cfg {
+		l.cfg = cfg
+		l.limit = float64(cfg.InitialLimit)
+		l.clampLimit()
+	}
+}
+
+func (l *concurrencyLimiter) clampLimit() {
+	l.limit = math.Max(l.limit, float64(l.cfg.MinLimit))
+	if l.cfg.MaxLimit > 0 {
+		l.limit = math.Min(l.limit, float64(l.cfg.MaxLimit))
+	}
+}
+
+// acquire counts a new operation in flight. It returns whether that exceeds
+// the share of the limit the priority of the operation is allowed to use, in
+// which case the operation is only counted when the limit isn't enforced.
+func (l *concurrencyLimiter) acquire(p priority, enforced bool) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	share := 1.0
+	switch p {
+	case priorityLow:
+		share = lowPriorityShare
+	case priorityHigh:
+		share = highPriorityShare
+	}
+	exceeded := float64(l.inFlight) >= l.limit*share
+	if exceeded {
+		l.shed++
+		if enforced {
+			return true
+		}
+	}
+	l.inFlight++
+	return exceeded
+}
+
+func (l *concurrencyLimiter) release() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.inFlight--
+}
+
+// sample adjusts the limit given the latency of an operation. The limit is
+// decreased at most once per latency target, since all the operations in
+// flight when the server gets overloaded are likely to be slow.
+func (l *concurrencyLimiter) sample(latency, target time.Duration) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	switch {
+	case latency > target:
+		now := time.Now()
+		if now.Sub(l.lastDecrease) < target {
+			return
+		}
+		l.lastDecrease = now
+		l.limit *= concurrencyBackoffRatio
+	case float64(l.inFlight*2) >= l.limit:
+		// Only increase the limit when the class is busy, otherwise a
+		// long period of light load would raise it without any evidence the
+		// server can handle it.
+		l.limit++
+	default:
+		return
+	}
+	l.clampLimit()
+}
+
+func (l *concurrencyLimiter) stats() ConcurrencyStats {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return ConcurrencyStats{
+		Class:    l.class,
+		Limit:    int(l.limit),
+		InFlight: l.inFlight,
+		Shed:     l.shed,
+	}
+}
+
+// concurrencyClass returns the class and priority of the given operation.
+func (h *Handler) concurrencyClass(op Operation) (ConcurrencyClass, priority) {
+	switch {
+	case op.Type == OperationTypeWrite:
+		if h.leaderStatusProvider != nil && h.leaderStatusProvider.IsLeader() {
+			return ConcurrencyClassWrite, priorityHigh
+		}
+		return ConcurrencyClassWrite, priorityNormal
+	case op.Blocking && op.AllowStale:
+		return ConcurrencyClassBlocking, priorityLow
+	case op.Blocking:
+		return ConcurrencyClassBlocking, priorityNormal
+	case op.AllowStale:
+		return ConcurrencyClassRead, priorityLow
+	default:
+		return ConcurrencyClassRead, priorityNormal
+	}
+}
+
+// AcquireConcurrency counts the given operation against the concurrency limit
+// of its class. It returns an error if the limit is exhausted, otherwise the
+// returned function must be called once the operation has completed so its
+// latency can be used to adjust the limit.
+func (h *Handler) AcquireConcurrency(op Operation) (func(), error) {
+	cfg := h.globalCfg.Load().ConcurrencyConfig
+	if cfg.Mode == ModeDisabled || op.Type == OperationTypeExempt {
+		return func() {}, nil
+	}
+
+	class, p := h.concurrencyClass(op)
+	l := h.concurrencyLimiters[class]
+	enforced := cfg.Mode == ModeEnforcing
+	exceeded := l.acquire(p, enforced)
+	if exceeded {
+		h.logger.Debug("RPC exceeded concurrency limit",
+			"rpc", op.Name,
+			"source_addr", op.SourceAddr,
+			"class", class,
+			"priority", p,
+			"limit_enforced", enforced,
+		)
+		h.incrShed(class, p, cfg.Mode)
+
+		if enforced {
+			if p == priorityHigh {
+				return nil, ErrRetryLater
+			}
+			return nil, ErrRetryElsewhere
+		}
+	}
+
+	start := time.Now()
+	return func() {
+		l.release()
+
+		// The latency of the blocking queries depends on how often the data
+		// changes, so their limit follows the latency of the reads instead.
+		if class == ConcurrencyClassBlocking {
+			return
+		}
+		latency, target := time.Since(start), cfg.class(class).LatencyTarget
+		l.sample(latency, target)
+		if class == ConcurrencyClassRead {
+			h.concurrencyLimiters[ConcurrencyClassBlocking].sample(latency, target)
+		}
+	}, nil
+}
+
+func (h *Handler) incrShed(class ConcurrencyClass, p priority, mode Mode) {
+	metrics.IncrCounterWithLabels([]string{"rpc", "concurrency_limit", "shed"}, 1, []metrics.Label{
+		{Name: "class", Value: string(class)},
+		{Name: "priority", Value: p.String()},
+		{Name: "mode", Value: mode.String()},
+	})
+}
+
+// ConcurrencyStats returns the state of the concurrency limit of each class.
+func (h *Handler) ConcurrencyStats() []ConcurrencyStats {
+	mode := h.globalCfg.Load().ConcurrencyConfig.Mode
+	stats := make([]ConcurrencyStats, 0, len(concurrencyClasses))
+	for _, class := range concurrencyClasses {
+		s := h.concurrencyLimiters[class].stats()
+		s.Mode = mode
+		stats = append(stats, s)
+	}
+	return stats
+}
+
+// emitConcurrencyMetrics periodically emits the limit and number of
+// operations in flight of each class until the given context is canceled.
+func (h *Handler) emitConcurrencyMetrics(ctx context.Context) {
+	ticker := time.NewTicker(concurrencyMetricsInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if h.globalCfg.Load().ConcurrencyConfig.Mode == ModeDisabled {
+			continue
+		}
+		for _, s := range h.ConcurrencyStats() {
+			labels := []metrics.Label{{Name: "class", Value: string(s.Class)}}
+			metrics.SetGaugeWithLabels([]string{"rpc", "concurrency_limit", "limit"}, float32(s.Limit), labels)
+			metrics.SetGaugeWithLabels([]string{"rpc", "concurrency_limit", "in_flight"}, float32(s.InFlight), labels)
+		}
+	}
+}
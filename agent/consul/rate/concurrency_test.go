@@ -0,0 +1,246 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package rate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConcurrencyLimiter_AIMD(t *testing.T) {
+	const target = time.Hour
+	fast, slow := time.Millisecond, 2*target
+
+	l := newConcurrencyLimiter(ConcurrencyClassRead, ConcurrencyClassConfig{
+		InitialLimit: 10,
+		MinLimit:     5,
+		MaxLimit:     12,
+	})
+	require.Equal(t, 10, l.stats().Limit)
+
+	// The limit doesn't increase while the class isn't busy.
+	require.False(t, l.acquire(priorityNormal, true))
+	l.sample(fast, target)
+	require.Equal(t, 10, l.stats().Limit)
+
+	// It increases by one per operation under the latency target once half of
+	// the limit is in use, up to the max.
+	for i := 0; i < 5; i++ {
+		require.False(t, l.acquire(priorityNormal, true))
+	}
+	for i := 0; i < 5; i++ {
+		l.sample(fast, target)
+	}
+	require.Equal(t, 12, l.stats().Limit)
+
+	// It decreases by the backoff ratio once per latency target when
+	// operations are over it, down to the min.
+	l.sample(slow, target)
+	require.Equal(t, 10, l.stats().Limit)
+	for i := 0; i < 10; i++ {
+		l.lastDecrease = l.lastDecrease.Add(-target)
+		l.sample(slow, target)
+	}
+	require.Equal(t, 5, l.stats().Limit)
+
+	// Changing the config resets the limit.
+	l.updateConfig(ConcurrencyClassConfig{InitialLimit: 20, MinLimit: 5, MaxLimit: 40})
+	require.Equal(t, 20, l.stats().Limit)
+}
+
+func TestConcurrencyLimiter_SlowBurst(t *testing.T) {
+	const target = time.Hour
+
+	l := newConcurrencyLimiter(ConcurrencyClassWrite, ConcurrencyClassConfig{
+		InitialLimit: 100,
+		MinLimit:     1,
+	})
+
+	// A burst of slow operations only backs off once.
+	for i := 0; i < 50; i++ {
+		l.sample(2*target, target)
+	}
+	require.Equal(t, 90, l.stats().Limit)
+
+	// It backs off again once the latency target has elapsed.
+	l.lastDecrease = l.lastDecrease.Add(-target)
+	l.sample(2*target, target)
+	require.Equal(t, 81, l.stats().Limit)
+}
+
+func TestConcurrencyLimiter_Priorities(t *testing.T) {
+	l := newConcurrencyLimiter(ConcurrencyClassRead, ConcurrencyClassConfig{
+		InitialLimit: 10,
+		MinLimit:     10,
+		MaxLimit:     10,
+	})
+	for i := 0; i < 8; i++ {
+		require.False(t, l.acquire(priorityNormal, true))
+	}
+
+	// Low priority operations are shed first.
+	require.True(t, l.acquire(priorityLow, true))
+	require.False(t, l.acquire(priorityNormal, true))
+	require.False(t, l.acquire(priorityNormal, true))
+	require.True(t, l.acquire(priorityNormal, true))
+
+	// High priority operations may go over the limit.
+	require.False(t, l.acquire(priorityHigh, true))
+	require.False(t, l.acquire(priorityHigh, true))
+	require.True(t, l.acquire(priorityHigh, true))
+
+	// Shed operations aren't in flight.
+	stats := l.stats()
+	require.Equal(t, 12, stats.InFlight)
+	require.Equal(t, uint64(3), stats.Shed)
+
+	// Unless the limit isn't enforced.
+	require.True(t, l.acquire(priorityLow, false))
+	require.Equal(t, 13, l.stats().InFlight)
+
+	l.release()
+	require.Equal(t, 12, l.stats().InFlight)
+}
+
+func TestHandler_AcquireConcurrency(t *testing.T) {
+	one := ConcurrencyClassConfig{InitialLimit: 1, MinLimit: 1, MaxLimit: 1, LatencyTarget: time.Hour}
+
+	newHandler := func(t *testing.T, mode Mode, isLeader bool) *Handler {
+		leaderStatusProvider := NewMockLeaderStatusProvider(t)
+		leaderStatusProvider.On("IsLeader").Return(isLeader).Maybe()
+
+		h := NewHandler(HandlerConfig{
+			ConcurrencyConfig: ConcurrencyConfig{Mode: mode, Read: one, Write: one, Blocking: one},
+		}, hclog.NewNullLogger())
+		h.Register(leaderStatusProvider)
+		return h
+	}
+
+	var (
+		read     = Operation{Name: "Catalog.ListNodes", Type: OperationTypeRead}
+		stale    = Operation{Name: "Catalog.ListNodes", Type: OperationTypeRead, AllowStale: true}
+		blocking = Operation{Name: "Catalog.ListNodes", Type: OperationTypeRead, Blocking: true}
+		write    = Operation{Name: "KVS.Apply", Type: OperationTypeWrite}
+		exempt   = Operation{Name: "Status.Leader", Type: OperationTypeExempt}
+	)
+
+	t.Run("disabled", func(t *testing.T) {
+		h := newHandler(t, ModeDisabled, false)
+		for i := 0; i < 2; i++ {
+			_, err := h.AcquireConcurrency(read)
+			require.NoError(t, err)
+		}
+		require.Zero(t, h.ConcurrencyStats()[0].InFlight)
+	})
+
+	t.Run("classes have budgets of their own", func(t *testing.T) {
+		h := newHandler(t, ModeEnforcing, false)
+		for _, op := range []Operation{read, write, blocking} {
+			_, err := h.AcquireConcurrency(op)
+			require.NoError(t, err)
+		}
+
+		_, err := h.AcquireConcurrency(read)
+		require.ErrorIs(t, err, ErrRetryElsewhere)
+
+		_, err = h.AcquireConcurrency(exempt)
+		require.NoError(t, err)
+
+		require.Equal(t, []ConcurrencyStats{
+			{Class: ConcurrencyClassRead, Mode: ModeEnforcing, Limit: 1, InFlight: 1, Shed: 1},
+			{Class: ConcurrencyClassWrite, Mode: ModeEnforcing, Limit: 1, InFlight: 1},
+			{Class: ConcurrencyClassBlocking, Mode: ModeEnforcing, Limit: 1, InFlight: 1},
+		}, h.ConcurrencyStats())
+	})
+
+	t.Run("release", func(t *testing.T) {
+		h := newHandler(t, ModeEnforcing, false)
+		release, err := h.AcquireConcurrency(read)
+		require.NoError(t, err)
+		release()
+
+		release, err = h.AcquireConcurrency(read)
+		require.NoError(t, err)
+		release()
+	})
+
+	t.Run("stale reads are shed first", func(t *testing.T) {
+		h := newHandler(t, ModeEnforcing, false)
+		h.UpdateConfig(HandlerConfig{
+			ConcurrencyConfig: ConcurrencyConfig{
+				Mode:     ModeEnforcing,
+				Read:     ConcurrencyClassConfig{InitialLimit: 5, MinLimit: 5, MaxLimit: 5, LatencyTarget: time.Hour},
+				Write:    one,
+				Blocking: one,
+			},
+		})
+		for i := 0; i < 4; i++ {
+			_, err := h.AcquireConcurrency(read)
+			require.NoError(t, err)
+		}
+
+		_, err := h.AcquireConcurrency(stale)
+		require.ErrorIs(t, err, ErrRetryElsewhere)
+
+		_, err = h.AcquireConcurrency(read)
+		require.NoError(t, err)
+	})
+
+	t.Run("writes on the leader", func(t *testing.T) {
+		h := newHandler(t, ModeEnforcing, true)
+		h.UpdateConfig(HandlerConfig{
+			ConcurrencyConfig: ConcurrencyConfig{
+				Mode:     ModeEnforcing,
+				Read:     one,
+				Write:    ConcurrencyClassConfig{InitialLimit: 5, MinLimit: 5, MaxLimit: 5, LatencyTarget: time.Hour},
+				Blocking: one,
+			},
+		})
+
+		// Writes on the leader may go over the limit, and must be retried
+		// against the leader once they exhausted it.
+		for i := 0; i < 6; i++ {
+			_, err := h.AcquireConcurrency(write)
+			require.NoError(t, err)
+		}
+		_, err := h.AcquireConcurrency(write)
+		require.ErrorIs(t, err, ErrRetryLater)
+	})
+
+	t.Run("permissive", func(t *testing.T) {
+		h := newHandler(t, ModePermissive, false)
+		for i := 0; i < 2; i++ {
+			_, err := h.AcquireConcurrency(read)
+			require.NoError(t, err)
+		}
+
+		stats := h.ConcurrencyStats()[0]
+		require.Equal(t, 2, stats.InFlight)
+		require.Equal(t, uint64(1), stats.Shed)
+	})
+
+	t.Run("blocking queries follow the latency of the reads", func(t *testing.T) {
+		h := newHandler(t, ModeEnforcing, false)
+		h.UpdateConfig(HandlerConfig{
+			ConcurrencyConfig: ConcurrencyConfig{
+				Mode:     ModeEnforcing,
+				Read:     ConcurrencyClassConfig{InitialLimit: 10, MinLimit: 1, MaxLimit: 10, LatencyTarget: time.Nanosecond},
+				Write:    one,
+				Blocking: ConcurrencyClassConfig{InitialLimit: 10, MinLimit: 1, MaxLimit: 10},
+			},
+		})
+
+		release, err := h.AcquireConcurrency(read)
+		require.NoError(t, err)
+		time.Sleep(time.Millisecond)
+		release()
+
+		stats := h.ConcurrencyStats()
+		require.Equal(t, 9, stats[0].Limit)
+		require.Equal(t, 9, stats[2].Limit)
+	})
+}
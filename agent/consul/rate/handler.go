@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"net"
 	"reflect"
+	"sync"
 	"sync/atomic"
 
 	"github.com/armon/go-metrics"
@@ -145,15 +146,29 @@ type Operation struct {
 	Type OperationType
 
 	Category OperationCategory
+
+	// Token is the secret ID of the ACL token the operation was made with. It's
+	// only used by AllowToken.
+	Token string
+
+	// Blocking and AllowStale describe read operations, they're only used by
+	// AcquireConcurrency to pick the budget and priority of the operation.
+	Blocking   bool
+	AllowStale bool
 }
 
 //go:generate mockery --name RequestLimitsHandler --inpackage
 type RequestLimitsHandler interface {
 	Run(ctx context.Context)
 	Allow(op Operation) error
+	AllowToken(op Operation) error
+	AcquireConcurrency(op Operation) (func(), error)
+	ConcurrencyStats() []ConcurrencyStats
 	UpdateConfig(cfg HandlerConfig)
 	UpdateIPConfig(cfg IPLimitConfig)
+	UpdateRPCLimits(limits []RPCLimit)
 	Register(leaderStatusProvider LeaderStatusProvider)
+	RegisterTokenResolver(resolver TokenResolver)
 }
 
 // Handler enforces rate limits for incoming RPCs.
@@ -161,6 +176,16 @@ type Handler struct {
 	globalCfg            *atomic.Pointer[HandlerConfig]
 	ipCfg                *atomic.Pointer[IPLimitConfig]
 	leaderStatusProvider LeaderStatusProvider
+	tokenResolver        TokenResolver
+
+	// rpcLimits are the limits checked by AllowToken, and rpcLimitConfigs the
+	// configuration of their buckets in the limiter.
+	rpcLimits       *atomic.Pointer[[]RPCLimit]
+	rpcLimitsLock   sync.Mutex
+	rpcLimitConfigs map[string]multilimiter.LimiterConfig
+
+	// concurrencyLimiters track the operations in flight for AcquireConcurrency.
+	concurrencyLimiters map[ConcurrencyClass]*concurrencyLimiter
 
 	limiter multilimiter.RateLimiter
 
@@ -184,6 +209,8 @@ type HandlerConfig struct {
 	multilimiter.Config
 
 	GlobalLimitConfig GlobalLimitConfig
+
+	ConcurrencyConfig ConcurrencyConfig
 }
 
 //go:generate mockery --name LeaderStatusProvider --inpackage --filename mock_LeaderStatusProvider_test.go
@@ -211,8 +238,14 @@ func NewHandlerWithLimiter(
 	h := &Handler{
 		ipCfg:     new(atomic.Pointer[IPLimitConfig]),
 		globalCfg: new(atomic.Pointer[HandlerConfig]),
+		rpcLimits: new(atomic.Pointer[[]RPCLimit]),
 		limiter:   limiter,
 		logger:    logger,
+
+		concurrencyLimiters: make(map[ConcurrencyClass]*concurrencyLimiter, len(concurrencyClasses)),
+	}
+	for _, class := range concurrencyClasses {
+		h.concurrencyLimiters[class] = newConcurrencyLimiter(class, cfg.ConcurrencyConfig.class(class))
 	}
 	h.globalCfg.Store(&cfg)
 
@@ -230,6 +263,7 @@ func NewHandler(cfg HandlerConfig, logger hclog.Logger) *Handler {
 // Note: this starts a goroutine.
 func (h *Handler) Run(ctx context.Context) {
 	h.limiter.Run(ctx)
+	go h.emitConcurrencyMetrics(ctx)
 }
 
 // Allow returns an error if the given operation is not allowed to proceed
@@ -308,6 +342,9 @@ func (h *Handler) UpdateConfig(cfg HandlerConfig) {
 		h.limiter.UpdateConfig(cfg.GlobalLimitConfig.ReadConfig, globalRead)
 	}
 
+	for class, l := range h.concurrencyLimiters {
+		l.updateConfig(cfg.ConcurrencyConfig.class(class))
+	}
 }
 
 func (h *Handler) Register(leaderStatusProvider LeaderStatusProvider) {
@@ -385,8 +422,18 @@ func (h nullRequestLimitsHandler) UpdateIPConfig(cfg IPLimitConfig) {}
 
 func (nullRequestLimitsHandler) Allow(Operation) error { return nil }
 
+func (nullRequestLimitsHandler) AllowToken(Operation) error { return nil }
+
+func (nullRequestLimitsHandler) AcquireConcurrency(Operation) (func(), error) { return func() {}, nil }
+
+func (nullRequestLimitsHandler) ConcurrencyStats() []ConcurrencyStats { return nil }
+
 func (nullRequestLimitsHandler) Run(_ context.Context) {}
 
 func (nullRequestLimitsHandler) UpdateConfig(_ HandlerConfig) {}
 
+func (nullRequestLimitsHandler) UpdateRPCLimits(_ []RPCLimit) {}
+
 func (nullRequestLimitsHandler) Register(_ LeaderStatusProvider) {}
+
+func (nullRequestLimitsHandler) RegisterTokenResolver(_ TokenResolver) {}
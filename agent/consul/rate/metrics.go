@@ -14,4 +14,19 @@ var Counters = []prometheus.CounterDefinition{
 		Name: []string{"rpc", "rate_limit", "log_dropped"},
 		Help: "Increments whenever a log that is emitted because an RPC exceeded a rate limit gets dropped because the output buffer is full.",
 	},
+	{
+		Name: []string{"rpc", "concurrency_limit", "shed"},
+		Help: "Increments whenever an RPC exceeds the adaptive concurrency limit of its class. Note: in permissive mode, the RPC will have still been allowed to proceed.",
+	},
+}
+
+var Gauges = []prometheus.GaugeDefinition{
+	{
+		Name: []string{"rpc", "concurrency_limit", "limit"},
+		Help: "Measures the number of RPCs of a class (read, write or blocking) that the server currently handles concurrently.",
+	},
+	{
+		Name: []string{"rpc", "concurrency_limit", "in_flight"},
+		Help: "Measures the number of RPCs of a class (read, write or blocking) being handled by the server.",
+	},
 }
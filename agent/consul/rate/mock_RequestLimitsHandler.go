@@ -13,6 +13,32 @@ type MockRequestLimitsHandler struct {
 	mock.Mock
 }
 
+// AcquireConcurrency provides a mock function with given fields: op
+func (_m *MockRequestLimitsHandler) AcquireConcurrency(op Operation) (func(), error) {
+	ret := _m.Called(op)
+
+	var r0 func()
+	var r1 error
+	if rf, ok := ret.Get(0).(func(Operation) (func(), error)); ok {
+		return rf(op)
+	}
+	if rf, ok := ret.Get(0).(func(Operation) func()); ok {
+		r0 = rf(op)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(func())
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(Operation) error); ok {
+		r1 = rf(op)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Allow provides a mock function with given fields: op
 func (_m *MockRequestLimitsHandler) Allow(op Operation) error {
 	ret := _m.Called(op)
@@ -27,11 +53,46 @@ func (_m *MockRequestLimitsHandler) Allow(op Operation) error {
 	return r0
 }
 
+// AllowToken provides a mock function with given fields: op
+func (_m *MockRequestLimitsHandler) AllowToken(op Operation) error {
+	ret := _m.Called(op)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(Operation) error); ok {
+		r0 = rf(op)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ConcurrencyStats provides a mock function with given fields:
+func (_m *MockRequestLimitsHandler) ConcurrencyStats() []ConcurrencyStats {
+	ret := _m.Called()
+
+	var r0 []ConcurrencyStats
+	if rf, ok := ret.Get(0).(func() []ConcurrencyStats); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]ConcurrencyStats)
+		}
+	}
+
+	return r0
+}
+
 // Register provides a mock function with given fields: leaderStatusProvider
 func (_m *MockRequestLimitsHandler) Register(leaderStatusProvider LeaderStatusProvider) {
 	_m.Called(leaderStatusProvider)
 }
 
+// RegisterTokenResolver provides a mock function with given fields: resolver
+func (_m *MockRequestLimitsHandler) RegisterTokenResolver(resolver TokenResolver) {
+	_m.Called(resolver)
+}
+
 // Run provides a mock function with given fields: ctx
 func (_m *MockRequestLimitsHandler) Run(ctx context.Context) {
 	_m.Called(ctx)
@@ -47,6 +108,11 @@ func (_m *MockRequestLimitsHandler) UpdateIPConfig(cfg IPLimitConfig) {
 	_m.Called(cfg)
 }
 
+// UpdateRPCLimits provides a mock function with given fields: limits
+func (_m *MockRequestLimitsHandler) UpdateRPCLimits(limits []RPCLimit) {
+	_m.Called(limits)
+}
+
 type mockConstructorTestingTNewMockRequestLimitsHandler interface {
 	mock.TestingT
 	Cleanup(func())
@@ -0,0 +1,329 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package rate
+
+import (
+	"strings"
+
+	"github.com/armon/go-metrics"
+
+	"github.com/hashicorp/consul/agent/consul/multilimiter"
+)
+
+// LimitKey is a property of an operation that an RPCLimit keeps a separate
+// bucket for.
+type LimitKey string
+
+const (
+	// LimitKeyToken gives each ACL token a bucket of its own.
+	LimitKeyToken LimitKey = "token"
+
+	// LimitKeyIdentity gives each identity a bucket of its own, shared by all
+	// the tokens issued for the same service or node.
+	LimitKeyIdentity LimitKey = "identity"
+
+	// LimitKeyEndpoint gives each RPC endpoint a bucket of its own.
+	LimitKeyEndpoint LimitKey = "endpoint"
+)
+
+// RPCLimit is a rate limit defined by an rpc-rate-limit config entry. It
+// applies to the operations on the matching endpoints that are made with the
+// matching tokens.
+type RPCLimit struct {
+	// Name of the config entry, which identifies the limit.
+	Name string
+
+	Mode Mode
+
+	// Endpoints restricts the limit to these RPC endpoints. A name ending with
+	// "*" matches all the endpoints starting with the rest of the name. The
+	// limit applies to all the endpoints when it's empty.
+	Endpoints []string
+
+	// Tokens and Identities restrict the limit to the operations made with the
+	// tokens with these accessor IDs, or with these identities. The limit
+	// applies to all the operations when both are empty.
+	Tokens     []string
+	Identities []string
+
+	// KeyBy lists the properties of the operations that get a bucket of their
+	// own. Without keys, all the matching operations share a single bucket.
+	KeyBy []LimitKey
+
+	// MetricsIncludeTokens reports the accessor ID or identity of the tokens
+	// that exceed the limit in the limit_key label of the metrics. Otherwise
+	// the label only names the token key, so its cardinality is bounded by
+	// the number of endpoints.
+	MetricsIncludeTokens bool
+
+	ReadWriteConfig
+}
+
+// TokenIdentity describes the ACL token an operation was made with.
+type TokenIdentity struct {
+	// AccessorID of the token.
+	AccessorID string
+
+	// Identity is "service:<name>" or "node:<name>" for the tokens with a
+	// service or node identity, and "token:<accessor ID>" for the others.
+	Identity string
+}
+
+//go:generate mockery --name TokenResolver --inpackage --filename mock_TokenResolver_test.go
+type TokenResolver interface {
+	// ResolveTokenIdentity returns the identity of the ACL token with the given
+	// secret ID, which is empty for operations made without a token.
+	ResolveTokenIdentity(secretID string) (TokenIdentity, error)
+}
+
+func (l *RPCLimit) config(t OperationType) multilimiter.LimiterConfig {
+	if t == OperationTypeWrite {
+		return l.WriteConfig
+	}
+	return l.ReadConfig
+}
+
+func (l *RPCLimit) matchesEndpoint(name string) bool {
+	if len(l.Endpoints) == 0 {
+		return true
+	}
+	for _, e := range l.Endpoints {
+		if prefix, ok := strings.CutSuffix(e, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if e == name {
+			return true
+		}
+	}
+	return false
+}
+
+// needsIdentity returns whether the identity of the token is required to
+// check the limit, which requires resolving the token.
+func (l *RPCLimit) needsIdentity() bool {
+	if len(l.Tokens) > 0 || len(l.Identities) > 0 {
+		return true
+	}
+	for _, k := range l.KeyBy {
+		if k == LimitKeyToken || k == LimitKeyIdentity {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *RPCLimit) matchesIdentity(id TokenIdentity) bool {
+	if len(l.Tokens) == 0 && len(l.Identities) == 0 {
+		return true
+	}
+	for _, t := range l.Tokens {
+		if t == id.AccessorID {
+			return true
+		}
+	}
+	for _, i := range l.Identities {
+		if i == id.Identity {
+			return true
+		}
+	}
+	return false
+}
+
+// key returns the description of the bucket the operation falls in, such as
+// "token=<accessor ID>,endpoint=KVS.Apply". It's empty when the operations
+// share a single bucket.
+func (l *RPCLimit) key(op Operation, id TokenIdentity) string {
+	parts := make([]string, 0, len(l.KeyBy))
+	for _, k := range l.KeyBy {
+		switch k {
+		case LimitKeyToken:
+			parts = append(parts, "token="+id.AccessorID)
+		case LimitKeyIdentity:
+			parts = append(parts, "identity="+id.Identity)
+		case LimitKeyEndpoint:
+			parts = append(parts, "endpoint="+op.Name)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// metricKey returns the key reported in the limit_key label of the metrics
+// for the bucket the operation falls in. Unless MetricsIncludeTokens is set,
+// the accessor ID and identity of the token are left out, as in
+// "token,endpoint=KVS.Apply". It's "all" when the operations share a single
+// bucket.
+func (l *RPCLimit) metricKey(op Operation, id TokenIdentity) string {
+	if len(l.KeyBy) == 0 {
+		return "all"
+	}
+	if l.MetricsIncludeTokens {
+		return l.key(op, id)
+	}
+	parts := make([]string, 0, len(l.KeyBy))
+	for _, k := range l.KeyBy {
+		if k == LimitKeyEndpoint {
+			parts = append(parts, "endpoint="+op.Name)
+		} else {
+			parts = append(parts, string(k))
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func operationTypeName(t OperationType) string {
+	if t == OperationTypeWrite {
+		return "write"
+	}
+	return "read"
+}
+
+// rpcLimitPrefix returns the prefix of the keys of the buckets of the limit
+// with the given name for the operations of the given type.
+func rpcLimitPrefix(name string, t OperationType) []byte {
+	return multilimiter.Key([]byte("rpc_limit"), []byte(name), []byte(operationTypeName(t)), nil)
+}
+
+// rpcLimitBucket identifies the bucket of an RPCLimit an operation falls in.
+type rpcLimitBucket []byte
+
+// Key satisfies the multilimiter.LimitedEntity interface.
+func (b rpcLimitBucket) Key() multilimiter.KeyType {
+	return multilimiter.KeyType(b)
+}
+
+// AllowToken returns an error if the given operation is not allowed to proceed
+// because it exhausted one of the limits set by UpdateRPCLimits. Those limits
+// can depend on the ACL token of the operation, so they are checked separately
+// from the global limits once the token is known: after the request body is
+// decoded for net/rpc, and from the request metadata for gRPC.
+func (h *Handler) AllowToken(op Operation) error {
+	if op.Type == OperationTypeExempt {
+		return nil
+	}
+	limits := h.rpcLimits.Load()
+	if limits == nil {
+		return nil
+	}
+
+	var (
+		id       TokenIdentity
+		resolved bool
+	)
+	for _, l := range *limits {
+		if l.Mode == ModeDisabled || !l.matchesEndpoint(op.Name) || isInfRate(l.config(op.Type)) {
+			continue
+		}
+		if l.needsIdentity() {
+			if !resolved {
+				id = h.resolveTokenIdentity(op.Token)
+				resolved = true
+			}
+			if !l.matchesIdentity(id) {
+				continue
+			}
+		}
+
+		key := l.key(op, id)
+		prefix := rpcLimitPrefix(l.Name, op.Type)
+		if h.limiter.Allow(rpcLimitBucket(append(prefix, key...))) {
+			continue
+		}
+
+		if key == "" {
+			key = "all"
+		}
+		desc := "rpc-rate-limit/" + l.Name + "/" + operationTypeName(op.Type)
+		enforced := l.Mode == ModeEnforcing
+		h.logger.Debug("RPC exceeded allowed rate limit",
+			"rpc", op.Name,
+			"source_addr", op.SourceAddr,
+			"limit_type", desc,
+			"limit_key", key,
+			"limit_enforced", enforced,
+		)
+
+		// The tokens aren't reported in the metrics by default, to keep
+		// their cardinality bounded, but they are always logged.
+		metrics.IncrCounterWithLabels([]string{"rpc", "rate_limit", "exceeded"}, 1, []metrics.Label{
+			{
+				Name:  "limit_type",
+				Value: desc,
+			},
+			{
+				Name:  "limit_key",
+				Value: l.metricKey(op, id),
+			},
+			{
+				Name:  "op",
+				Value: op.Name,
+			},
+			{
+				Name:  "mode",
+				Value: l.Mode.String(),
+			},
+		})
+
+		if enforced {
+			if h.leaderStatusProvider != nil && h.leaderStatusProvider.IsLeader() && op.Type == OperationTypeWrite {
+				return ErrRetryLater
+			}
+			return ErrRetryElsewhere
+		}
+	}
+	return nil
+}
+
+// resolveTokenIdentity returns the identity of the token with the given secret
+// ID. The operations whose token can't be resolved share the zero identity, so
+// they are still limited together.
+func (h *Handler) resolveTokenIdentity(secretID string) TokenIdentity {
+	if h.tokenResolver == nil {
+		return TokenIdentity{}
+	}
+	id, err := h.tokenResolver.ResolveTokenIdentity(secretID)
+	if err != nil {
+		return TokenIdentity{}
+	}
+	return id
+}
+
+// UpdateRPCLimits replaces the limits checked by AllowToken.
+func (h *Handler) UpdateRPCLimits(limits []RPCLimit) {
+	h.rpcLimitsLock.Lock()
+	defer h.rpcLimitsLock.Unlock()
+
+	configs := make(map[string]multilimiter.LimiterConfig)
+	for _, l := range limits {
+		for _, t := range []OperationType{OperationTypeRead, OperationTypeWrite} {
+			if cfg := l.config(t); !isInfRate(cfg) {
+				configs[string(rpcLimitPrefix(l.Name, t))] = cfg
+			}
+		}
+	}
+
+	for prefix := range h.rpcLimitConfigs {
+		if _, ok := configs[prefix]; !ok {
+			h.limiter.DeleteConfig([]byte(prefix))
+		}
+	}
+	for prefix, cfg := range configs {
+		if existing, ok := h.rpcLimitConfigs[prefix]; !ok || existing != cfg {
+			h.limiter.UpdateConfig(cfg, []byte(prefix))
+		}
+	}
+	h.rpcLimitConfigs = configs
+
+	if len(limits) == 0 {
+		h.rpcLimits.Store(nil)
+	} else {
+		h.rpcLimits.Store(&limits)
+	}
+}
+
+// RegisterTokenResolver sets the resolver used to find the identity of the
+// tokens for the limits set by UpdateRPCLimits.
+func (h *Handler) RegisterTokenResolver(resolver TokenResolver) {
+	h.tokenResolver = resolver
+}
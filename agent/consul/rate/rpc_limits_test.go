@@ -0,0 +1,240 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package rate
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+
+	"github.com/hashicorp/consul/agent/consul/multilimiter"
+	"github.com/hashicorp/consul/agent/metrics"
+)
+
+func TestHandler_AllowToken(t *testing.T) {
+	var (
+		sourceAddr = net.TCPAddrFromAddrPort(netip.MustParseAddrPort("1.2.3.4:5678"))
+		ci         = TokenIdentity{AccessorID: "ci-accessor", Identity: "service:ci"}
+		limited    = multilimiter.LimiterConfig{Rate: 10, Burst: 100}
+		unlimited  = multilimiter.LimiterConfig{Rate: rate.Inf}
+	)
+
+	bucket := func(name string, t OperationType, key string) rpcLimitBucket {
+		return rpcLimitBucket(append(rpcLimitPrefix(name, t), key...))
+	}
+
+	type limitCheck struct {
+		bucket rpcLimitBucket
+		allow  bool
+	}
+	testCases := map[string]struct {
+		limits       []RPCLimit
+		op           Operation
+		identity     *TokenIdentity
+		checks       []limitCheck
+		isLeader     bool
+		expectErr    error
+		expectLog    bool
+		expectMetric string
+	}{
+		"no limits": {
+			op: Operation{Name: "KVS.Apply", Type: OperationTypeWrite, Token: "ci-secret"},
+		},
+		"operation exempt from limiting": {
+			limits: []RPCLimit{{Name: "all", Mode: ModeEnforcing, ReadWriteConfig: ReadWriteConfig{ReadConfig: limited, WriteConfig: limited}}},
+			op:     Operation{Name: "Status.Leader", Type: OperationTypeExempt},
+		},
+		"limit disabled": {
+			limits: []RPCLimit{{Name: "all", Mode: ModeDisabled, ReadWriteConfig: ReadWriteConfig{ReadConfig: limited, WriteConfig: limited}}},
+			op:     Operation{Name: "KVS.Apply", Type: OperationTypeWrite},
+		},
+		"no limit for the operation type": {
+			limits: []RPCLimit{{Name: "writes", Mode: ModeEnforcing, ReadWriteConfig: ReadWriteConfig{ReadConfig: unlimited, WriteConfig: limited}}},
+			op:     Operation{Name: "KVS.Get", Type: OperationTypeRead},
+		},
+		"endpoint does not match": {
+			limits: []RPCLimit{{Name: "catalog", Mode: ModeEnforcing, Endpoints: []string{"Catalog.*"}, ReadWriteConfig: ReadWriteConfig{ReadConfig: limited, WriteConfig: limited}}},
+			op:     Operation{Name: "KVS.Apply", Type: OperationTypeWrite},
+		},
+		"token does not match": {
+			limits:   []RPCLimit{{Name: "ci", Mode: ModeEnforcing, Tokens: []string{"other-accessor"}, ReadWriteConfig: ReadWriteConfig{ReadConfig: limited, WriteConfig: limited}}},
+			op:       Operation{Name: "KVS.Apply", Type: OperationTypeWrite, Token: "ci-secret"},
+			identity: &ci,
+		},
+		"shared bucket within allowance": {
+			limits: []RPCLimit{{Name: "catalog", Mode: ModeEnforcing, Endpoints: []string{"Catalog.*"}, ReadWriteConfig: ReadWriteConfig{ReadConfig: limited, WriteConfig: limited}}},
+			op:     Operation{Name: "Catalog.Register", Type: OperationTypeWrite},
+			checks: []limitCheck{{bucket: bucket("catalog", OperationTypeWrite, ""), allow: true}},
+		},
+		"shared bucket exceeded (enforcing, leader)": {
+			limits:       []RPCLimit{{Name: "catalog", Mode: ModeEnforcing, Endpoints: []string{"Catalog.Register"}, ReadWriteConfig: ReadWriteConfig{ReadConfig: limited, WriteConfig: limited}}},
+			op:           Operation{Name: "Catalog.Register", Type: OperationTypeWrite},
+			checks:       []limitCheck{{bucket: bucket("catalog", OperationTypeWrite, ""), allow: false}},
+			isLeader:     true,
+			expectErr:    ErrRetryLater,
+			expectLog:    true,
+			expectMetric: "rpc.rate_limit.exceeded;limit_type=rpc-rate-limit/catalog/write;limit_key=all;op=Catalog.Register;mode=enforcing",
+		},
+		"token bucket exceeded (enforcing, follower)": {
+			limits:       []RPCLimit{{Name: "per-token", Mode: ModeEnforcing, KeyBy: []LimitKey{LimitKeyToken}, MetricsIncludeTokens: true, ReadWriteConfig: ReadWriteConfig{ReadConfig: limited, WriteConfig: limited}}},
+			op:           Operation{Name: "KVS.Apply", Type: OperationTypeWrite, Token: "ci-secret"},
+			identity:     &ci,
+			checks:       []limitCheck{{bucket: bucket("per-token", OperationTypeWrite, "token=ci-accessor"), allow: false}},
+			expectErr:    ErrRetryElsewhere,
+			expectLog:    true,
+			expectMetric: "rpc.rate_limit.exceeded;limit_type=rpc-rate-limit/per-token/write;limit_key=token=ci-accessor;op=KVS.Apply;mode=enforcing",
+		},
+		"token bucket exceeded (tokens not in metrics)": {
+			limits:       []RPCLimit{{Name: "per-token", Mode: ModeEnforcing, KeyBy: []LimitKey{LimitKeyToken}, ReadWriteConfig: ReadWriteConfig{ReadConfig: limited, WriteConfig: limited}}},
+			op:           Operation{Name: "KVS.Apply", Type: OperationTypeWrite, Token: "ci-secret"},
+			identity:     &ci,
+			checks:       []limitCheck{{bucket: bucket("per-token", OperationTypeWrite, "token=ci-accessor"), allow: false}},
+			expectErr:    ErrRetryElsewhere,
+			expectLog:    true,
+			expectMetric: "rpc.rate_limit.exceeded;limit_type=rpc-rate-limit/per-token/write;limit_key=token;op=KVS.Apply;mode=enforcing",
+		},
+		"identity and endpoint bucket exceeded (permissive)": {
+			limits:       []RPCLimit{{Name: "ci", Mode: ModePermissive, Identities: []string{"service:ci"}, KeyBy: []LimitKey{LimitKeyIdentity, LimitKeyEndpoint}, ReadWriteConfig: ReadWriteConfig{ReadConfig: limited, WriteConfig: limited}}},
+			op:           Operation{Name: "Health.ServiceNodes", Type: OperationTypeRead, Token: "ci-secret"},
+			identity:     &ci,
+			checks:       []limitCheck{{bucket: bucket("ci", OperationTypeRead, "identity=service:ci,endpoint=Health.ServiceNodes"), allow: false}},
+			expectLog:    true,
+			expectMetric: "rpc.rate_limit.exceeded;limit_type=rpc-rate-limit/ci/read;limit_key=identity,endpoint=Health.ServiceNodes;op=Health.ServiceNodes;mode=permissive",
+		},
+		"token resolved once for several limits": {
+			limits: []RPCLimit{
+				{Name: "a", Mode: ModeEnforcing, Tokens: []string{"ci-accessor"}, ReadWriteConfig: ReadWriteConfig{ReadConfig: limited, WriteConfig: limited}},
+				{Name: "b", Mode: ModeEnforcing, KeyBy: []LimitKey{LimitKeyToken}, ReadWriteConfig: ReadWriteConfig{ReadConfig: limited, WriteConfig: limited}},
+			},
+			op:       Operation{Name: "KVS.Get", Type: OperationTypeRead, Token: "ci-secret"},
+			identity: &ci,
+			checks: []limitCheck{
+				{bucket: bucket("a", OperationTypeRead, ""), allow: true},
+				{bucket: bucket("b", OperationTypeRead, "token=ci-accessor"), allow: true},
+			},
+		},
+	}
+	for desc, tc := range testCases {
+		t.Run(desc, func(t *testing.T) {
+			sink := metrics.TestSetupMetrics(t, "")
+			limiter := newMockLimiter(t)
+			limiter.On("UpdateConfig", mock.Anything, mock.Anything).Return()
+			for _, c := range tc.checks {
+				limiter.On("Allow", c.bucket).Return(c.allow).Once()
+			}
+
+			leaderStatusProvider := NewMockLeaderStatusProvider(t)
+			leaderStatusProvider.On("IsLeader").Return(tc.isLeader).Maybe()
+
+			tokenResolver := NewMockTokenResolver(t)
+			if tc.identity != nil {
+				tokenResolver.On("ResolveTokenIdentity", tc.op.Token).Return(*tc.identity, nil).Once()
+			}
+
+			var output bytes.Buffer
+			logger := hclog.NewInterceptLogger(&hclog.LoggerOptions{
+				Level:  hclog.Trace,
+				Output: &output,
+			})
+
+			handler := NewHandlerWithLimiter(HandlerConfig{}, limiter, logger)
+			handler.Register(leaderStatusProvider)
+			handler.RegisterTokenResolver(tokenResolver)
+			handler.UpdateRPCLimits(tc.limits)
+
+			tc.op.SourceAddr = sourceAddr
+			require.Equal(t, tc.expectErr, handler.AllowToken(tc.op))
+
+			if tc.expectLog {
+				require.Contains(t, output.String(), "RPC exceeded allowed rate limit")
+			} else {
+				require.Zero(t, output.Len(), "expected no logs to be emitted")
+			}
+
+			if tc.expectMetric != "" {
+				metrics.AssertCounter(t, sink, tc.expectMetric, 1)
+			}
+		})
+	}
+}
+
+func TestHandler_AllowToken_UnresolvedToken(t *testing.T) {
+	limiter := newMockLimiter(t)
+	limiter.On("UpdateConfig", mock.Anything, mock.Anything).Return()
+
+	// Tokens that can't be resolved share a bucket.
+	limiter.On("Allow", rpcLimitBucket(append(rpcLimitPrefix("per-token", OperationTypeRead), "token="...))).Return(true).Twice()
+
+	tokenResolver := NewMockTokenResolver(t)
+	tokenResolver.On("ResolveTokenIdentity", mock.Anything).Return(TokenIdentity{}, errors.New("ACL not found")).Twice()
+
+	handler := NewHandlerWithLimiter(HandlerConfig{}, limiter, hclog.NewNullLogger())
+	handler.RegisterTokenResolver(tokenResolver)
+	handler.UpdateRPCLimits([]RPCLimit{{
+		Name:  "per-token",
+		Mode:  ModeEnforcing,
+		KeyBy: []LimitKey{LimitKeyToken},
+		ReadWriteConfig: ReadWriteConfig{
+			ReadConfig:  multilimiter.LimiterConfig{Rate: 1, Burst: 1},
+			WriteConfig: multilimiter.LimiterConfig{Rate: rate.Inf},
+		},
+	}})
+
+	require.NoError(t, handler.AllowToken(Operation{Name: "KVS.Get", Token: "bogus-1"}))
+	require.NoError(t, handler.AllowToken(Operation{Name: "KVS.Get", Token: "bogus-2"}))
+}
+
+func TestHandler_UpdateRPCLimits(t *testing.T) {
+	limiter := newMockLimiter(t)
+	limiter.On("UpdateConfig", mock.Anything, mock.Anything).Return()
+	limiter.On("DeleteConfig", mock.Anything).Return()
+
+	handler := NewHandlerWithLimiter(HandlerConfig{}, limiter, hclog.NewNullLogger())
+	limiter.Calls = nil
+
+	readCfg := multilimiter.LimiterConfig{Rate: 10, Burst: 100}
+	writeCfg := multilimiter.LimiterConfig{Rate: 1, Burst: 10}
+	limits := []RPCLimit{
+		{Name: "a", ReadWriteConfig: ReadWriteConfig{ReadConfig: readCfg, WriteConfig: writeCfg}},
+		{Name: "b", ReadWriteConfig: ReadWriteConfig{ReadConfig: readCfg, WriteConfig: multilimiter.LimiterConfig{Rate: rate.Inf}}},
+	}
+	handler.UpdateRPCLimits(limits)
+	limiter.AssertNumberOfCalls(t, "UpdateConfig", 3)
+	limiter.AssertCalled(t, "UpdateConfig", readCfg, rpcLimitPrefix("a", OperationTypeRead))
+	limiter.AssertCalled(t, "UpdateConfig", writeCfg, rpcLimitPrefix("a", OperationTypeWrite))
+	limiter.AssertCalled(t, "UpdateConfig", readCfg, rpcLimitPrefix("b", OperationTypeRead))
+
+	// Only the configuration of the buckets that changed is updated.
+	limiter.Calls = nil
+	limits[0].WriteConfig = readCfg
+	limits = limits[:1]
+	handler.UpdateRPCLimits(limits)
+	limiter.AssertNumberOfCalls(t, "UpdateConfig", 1)
+	limiter.AssertCalled(t, "UpdateConfig", readCfg, rpcLimitPrefix("a", OperationTypeWrite))
+	limiter.AssertNumberOfCalls(t, "DeleteConfig", 1)
+	limiter.AssertCalled(t, "DeleteConfig", rpcLimitPrefix("b", OperationTypeRead))
+
+	limiter.Calls = nil
+	handler.UpdateRPCLimits(nil)
+	limiter.AssertNumberOfCalls(t, "DeleteConfig", 2)
+	require.Nil(t, handler.rpcLimits.Load())
+}
+
+func TestRPCLimit_MatchesEndpoint(t *testing.T) {
+	l := RPCLimit{Endpoints: []string{"KVS.Apply", "Catalog.*", "/hashicorp.consul.dataplane.DataplaneService/*"}}
+	require.True(t, l.matchesEndpoint("KVS.Apply"))
+	require.False(t, l.matchesEndpoint("KVS.Get"))
+	require.True(t, l.matchesEndpoint("Catalog.Register"))
+	require.True(t, l.matchesEndpoint("/hashicorp.consul.dataplane.DataplaneService/GetEnvoyBootstrapParams"))
+	require.False(t, l.matchesEndpoint("/hashicorp.consul.acl.ACLService/Login"))
+
+	require.True(t, (&RPCLimit{}).matchesEndpoint("KVS.Get"))
+}
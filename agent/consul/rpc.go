@@ -66,6 +66,14 @@ var RPCCounters = []prometheus.CounterDefinition{
 		Name: []string{"rpc", "query"},
 		Help: "Increments when a server receives a read request, indicating the rate of new read queries.",
 	},
+	{
+		Name: []string{"rpc", "query", "coalesced", "executions"},
+		Help: "Increments when a server executes a blocking query shared by identical requests.",
+	},
+	{
+		Name: []string{"rpc", "query", "coalesced", "results"},
+		Help: "Increments when a blocking query is answered with the result of a query shared by identical requests.",
+	},
 }
 
 var RPCGauges = []prometheus.GaugeDefinition{
@@ -419,7 +427,11 @@ func (s *Server) handleMultiplexV2(conn net.Conn) {
 // handleConsulConn is used to service a single Consul RPC connection
 func (s *Server) handleConsulConn(conn net.Conn) {
 	defer conn.Close()
-	rpcCodec := msgpackrpc.NewCodecFromHandle(true, true, conn, structs.MsgpackHandle)
+	rpcCodec := middleware.NewNetRPCRateLimitingCodec(
+		msgpackrpc.NewCodecFromHandle(true, true, conn, structs.MsgpackHandle),
+		s.incomingRPCLimiter,
+		middleware.NewPanicHandler(s.logger),
+	)
 	for {
 		select {
 		case <-s.shutdownCh:
@@ -452,7 +464,11 @@ func (s *Server) handleConsulConn(conn net.Conn) {
 // handleInsecureConsulConn is used to service a single Consul INSECURERPC connection
 func (s *Server) handleInsecureConn(conn net.Conn) {
 	defer conn.Close()
-	rpcCodec := msgpackrpc.NewCodecFromHandle(true, true, conn, structs.MsgpackHandle)
+	rpcCodec := middleware.NewNetRPCRateLimitingCodec(
+		msgpackrpc.NewCodecFromHandle(true, true, conn, structs.MsgpackHandle),
+		s.incomingRPCLimiter,
+		middleware.NewPanicHandler(s.logger),
+	)
 	for {
 		select {
 		case <-s.shutdownCh:
@@ -959,6 +975,8 @@ func (s *Server) raftApplyEncoded(t structs.MessageType, buf []byte) (any, error
 		s.rpcLogger().Warn("Attempting to apply large raft entry", "size_in_bytes", n)
 	}
 
+	done := s.raftLatencies.commit.start()
+
 	var chunked bool
 	var future raft.ApplyFuture
 	switch {
@@ -970,8 +988,10 @@ func (s *Server) raftApplyEncoded(t structs.MessageType, buf []byte) (any, error
 	}
 
 	if err := future.Error(); err != nil {
+		done(false)
 		return nil, err
 	}
+	done(true)
 
 	resp := future.Response()
 
@@ -0,0 +1,114 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"context"
+	"math"
+	"reflect"
+
+	"github.com/hashicorp/go-memdb"
+	"golang.org/x/time/rate"
+
+	"github.com/hashicorp/consul/acl"
+	"github.com/hashicorp/consul/agent/consul/multilimiter"
+	rpcRate "github.com/hashicorp/consul/agent/consul/rate"
+	"github.com/hashicorp/consul/agent/structs"
+)
+
+// rpcRateLimitMonitor keeps the limits of the incoming RPC rate limiter in
+// sync with the rpc-rate-limit config entries.
+func (s *Server) rpcRateLimitMonitor(ctx context.Context) {
+	var current []rpcRate.RPCLimit
+	for {
+		ws := memdb.NewWatchSet()
+		state := s.fsm.State()
+		ws.Add(state.AbandonCh())
+		_, entries, err := state.ConfigEntriesByKind(ws, structs.RPCRateLimit, acl.WildcardEnterpriseMeta())
+		if err != nil {
+			s.logger.Error("Failed to watch rpc-rate-limit config entries", "error", err)
+			return
+		}
+
+		limits := rpcLimitsFromConfigEntries(entries)
+		if !reflect.DeepEqual(limits, current) {
+			s.incomingRPCLimiter.UpdateRPCLimits(limits)
+			current = limits
+		}
+
+		if err := ws.WatchCtx(ctx); err == context.Canceled {
+			s.logger.Info("shutting down RPC rate limit monitor")
+			return
+		}
+	}
+}
+
+func rpcLimitsFromConfigEntries(entries []structs.ConfigEntry) []rpcRate.RPCLimit {
+	var limits []rpcRate.RPCLimit
+	for _, entry := range entries {
+		e, ok := entry.(*structs.RPCRateLimitConfigEntry)
+		if !ok {
+			continue
+		}
+		l := rpcRate.RPCLimit{
+			Name:       e.Name,
+			Mode:       rpcRate.RequestLimitsModeFromNameWithDefault(e.Mode),
+			Endpoints:  e.Endpoints,
+			Tokens:     e.Tokens,
+			Identities: e.Identities,
+
+			MetricsIncludeTokens: e.MetricsIncludeTokens,
+			ReadWriteConfig: rpcRate.ReadWriteConfig{
+				ReadConfig:  rpcLimiterConfig(e.ReadRate),
+				WriteConfig: rpcLimiterConfig(e.WriteRate),
+			},
+		}
+		for _, k := range e.KeyBy {
+			l.KeyBy = append(l.KeyBy, rpcRate.LimitKey(k))
+		}
+		limits = append(limits, l)
+	}
+	return limits
+}
+
+// rpcLimiterConfig returns the limiter config for the rate of an rpc-rate-limit
+// config entry, where zero means no limit.
+func rpcLimiterConfig(r float64) multilimiter.LimiterConfig {
+	if r == 0 {
+		return multilimiter.LimiterConfig{Rate: rate.Inf}
+	}
+	return multilimiter.LimiterConfig{
+		Rate:  rate.Limit(r),
+		Burst: int(math.Ceil(r)) * requestLimitsBurstMultiplier,
+	}
+}
+
+// ResolveTokenIdentity implements rpcRate.TokenResolver so the rpc-rate-limit
+// config entries can limit the requests by token and identity.
+func (s *Server) ResolveTokenIdentity(secretID string) (rpcRate.TokenIdentity, error) {
+	result, err := s.ACLResolver.ResolveToken(secretID)
+	if err != nil {
+		return rpcRate.TokenIdentity{}, err
+	}
+
+	accessorID := result.AccessorID()
+	if accessorID == "" {
+		// ACLs are disabled, all the requests are made with the anonymous
+		// token.
+		accessorID = acl.AnonymousTokenID
+	}
+	id := rpcRate.TokenIdentity{
+		AccessorID: accessorID,
+		Identity:   "token:" + accessorID,
+	}
+	if result.ACLIdentity == nil {
+		return id, nil
+	}
+	if services := result.ACLIdentity.ServiceIdentityList(); len(services) > 0 {
+		id.Identity = "service:" + services[0].ServiceName
+	} else if nodes := result.ACLIdentity.NodeIdentityList(); len(nodes) > 0 {
+		id.Identity = "node:" + nodes[0].NodeName
+	}
+	return id, nil
+}
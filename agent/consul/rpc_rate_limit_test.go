@@ -0,0 +1,131 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+
+	"github.com/hashicorp/consul/agent/consul/multilimiter"
+	rpcRate "github.com/hashicorp/consul/agent/consul/rate"
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+	"github.com/hashicorp/consul/testrpc"
+)
+
+func TestRPCLimitsFromConfigEntries(t *testing.T) {
+	entries := []structs.ConfigEntry{
+		&structs.RPCRateLimitConfigEntry{
+			Name:       "ci",
+			Mode:       structs.RPCRateLimitModeEnforcing,
+			Endpoints:  []string{"KVS.*"},
+			Identities: []string{"service:ci"},
+			KeyBy:      []string{"token", "endpoint"},
+			WriteRate:  2.5,
+
+			MetricsIncludeTokens: true,
+		},
+		&structs.RPCRateLimitConfigEntry{
+			Name:     "reads",
+			Mode:     structs.RPCRateLimitModePermissive,
+			ReadRate: 100,
+		},
+	}
+
+	require.Equal(t, []rpcRate.RPCLimit{
+		{
+			Name:       "ci",
+			Mode:       rpcRate.ModeEnforcing,
+			Endpoints:  []string{"KVS.*"},
+			Identities: []string{"service:ci"},
+			KeyBy:      []rpcRate.LimitKey{rpcRate.LimitKeyToken, rpcRate.LimitKeyEndpoint},
+
+			MetricsIncludeTokens: true,
+			ReadWriteConfig: rpcRate.ReadWriteConfig{
+				ReadConfig:  multilimiter.LimiterConfig{Rate: rate.Inf},
+				WriteConfig: multilimiter.LimiterConfig{Rate: 2.5, Burst: 3 * requestLimitsBurstMultiplier},
+			},
+		},
+		{
+			Name: "reads",
+			Mode: rpcRate.ModePermissive,
+			ReadWriteConfig: rpcRate.ReadWriteConfig{
+				ReadConfig:  multilimiter.LimiterConfig{Rate: 100, Burst: 100 * requestLimitsBurstMultiplier},
+				WriteConfig: multilimiter.LimiterConfig{Rate: rate.Inf},
+			},
+		},
+	}, rpcLimitsFromConfigEntries(entries))
+
+	require.Nil(t, rpcLimitsFromConfigEntries(nil))
+}
+
+func TestServer_RPCRateLimit(t *testing.T) {
+	if testing.Short() {
+		t.Skip("too slow for testing.Short")
+	}
+
+	_, config := testServerConfig(t)
+	deps := newDefaultDeps(t, config)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	limiter := ConfiguredIncomingRPCLimiter(ctx, deps.Logger, config)
+
+	s, err := NewServer(config, deps, grpc.NewServer(), limiter, deps.Logger)
+	require.NoError(t, err)
+	t.Cleanup(func() { s.Shutdown() })
+	testrpc.WaitForLeader(t, s.RPC, "dc1")
+
+	var out bool
+	require.NoError(t, s.RPC(context.Background(), "ConfigEntry.Apply", &structs.ConfigEntryRequest{
+		Datacenter: "dc1",
+		Entry: &structs.RPCRateLimitConfigEntry{
+			Name:      "kv-writes",
+			Endpoints: []string{"KVS.Apply"},
+			KeyBy:     []string{"token"},
+			WriteRate: 1,
+		},
+	}, &out))
+
+	kvApply := func(i int) error {
+		var out bool
+		return s.RPC(context.Background(), "KVS.Apply", &structs.KVSRequest{
+			Datacenter: "dc1",
+			Op:         "set",
+			DirEnt: structs.DirEntry{
+				Key:   fmt.Sprintf("key%d", i),
+				Value: []byte("value"),
+			},
+		}, &out)
+	}
+
+	// The limit applies once the server has loaded the config entry, and the
+	// burst of the bucket has been used.
+	retry.Run(t, func(r *retry.R) {
+		var err error
+		for i := 0; i < 100 && err == nil; i++ {
+			err = kvApply(i)
+		}
+		require.ErrorIs(r, err, rpcRate.ErrRetryLater)
+	})
+
+	// The other endpoints are not limited.
+	var nodes structs.IndexedNodes
+	require.NoError(t, s.RPC(context.Background(), "Catalog.ListNodes", &structs.DCSpecificRequest{Datacenter: "dc1"}, &nodes))
+
+	// Deleting the config entry removes the limit.
+	require.NoError(t, s.RPC(context.Background(), "ConfigEntry.Delete", &structs.ConfigEntryRequest{
+		Datacenter: "dc1",
+		Op:         structs.ConfigEntryDelete,
+		Entry:      &structs.RPCRateLimitConfigEntry{Name: "kv-writes"},
+	}, &structs.ConfigEntryDeleteResponse{}))
+	retry.Run(t, func(r *retry.R) {
+		require.NoError(r, kvApply(0))
+	})
+}
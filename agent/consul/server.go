@@ -138,6 +138,8 @@ const (
 	federationStateAntiEntropyRoutineName = "federation state anti-entropy"
 	federationStatePruningRoutineName     = "federation state pruning"
 	intentionMigrationRoutineName         = "intention config entry migration"
+	kvRevisionPruningRoutineName          = "KV revision pruning"
+	leaderHealthRoutineName               = "leader health checks"
 	secondaryCARootWatchRoutineName       = "secondary CA roots watch"
 	intermediateCertRenewWatchRoutineName = "intermediate cert renew watch"
 	backgroundCAInitializationRoutineName = "CA initialization"
@@ -306,6 +308,9 @@ type Server struct {
 	// incomingRPCLimiter rate-limits incoming net/rpc and gRPC calls.
 	incomingRPCLimiter rpcRate.RequestLimitsHandler
 
+	// queryCoalescer lets identical blocking queries share their execution.
+	queryCoalescer *blockingQueryCoalescer
+
 	// insecureRPCServer is a RPC server that is configure with
 	// IncomingInsecureRPCConfig to allow clients to call AutoEncrypt.Sign
 	// to request client certificates. At this point a client doesn't have
@@ -355,10 +360,26 @@ type Server struct {
 	// destroy the session via standard session destroy processing
 	sessionTimers *SessionTimers
 
+	// kvsTimers track the expiration time of each KV entry that has a TTL.
+	// On expiration the entry is deleted, unless it was modified since.
+	kvsTimers *SessionTimers
+
 	// statsFetcher is used by autopilot to check the status of the other
 	// Consul router.
 	statsFetcher *StatsFetcher
 
+	// raftLatencies tracks the time this server takes to apply, store and
+	// commit Raft logs.
+	raftLatencies raftLatencies
+
+	// leaderHealth decides when the leader should transfer leadership away
+	// because its Raft performance is degraded.
+	leaderHealth *leaderHealthMonitor
+
+	// logVerification holds the recent results of the Raft log verification
+	// of this server.
+	logVerification logVerificationStatus
+
 	// overviewManager is used to periodically update the cluster overview
 	// and emit node/service/check health metrics.
 	overviewManager *OverviewManager
@@ -478,6 +499,7 @@ func NewServer(config *Config, flat Deps, externalGRPCServer *grpc.Server, incom
 		externalGRPCServer:      externalGRPCServer,
 		reassertLeaderCh:        make(chan chan error),
 		sessionTimers:           NewSessionTimers(),
+		kvsTimers:               NewSessionTimers(),
 		tombstoneGC:             gc,
 		serverLookup:            NewServerLookup(),
 		shutdownCh:              shutdownCh,
@@ -488,6 +510,11 @@ func NewServer(config *Config, flat Deps, externalGRPCServer *grpc.Server, incom
 		routineManager:          routine.NewManager(logger.Named(logging.ConsulServer)),
 	}
 	incomingRPCLimiter.Register(s)
+	incomingRPCLimiter.RegisterTokenResolver(s)
+
+	s.queryCoalescer = newBlockingQueryCoalescer(&lib.StopChannelContext{StopCh: shutdownCh}, func() *state.Store {
+		return s.fsm.State()
+	})
 
 	s.raftStorageBackend, err = raftstorage.NewBackend(&raftHandle{s}, logger.Named("raft-storage-backend"))
 	if err != nil {
@@ -592,6 +619,7 @@ func NewServer(config *Config, flat Deps, externalGRPCServer *grpc.Server, incom
 
 	// Initialize the stats fetcher that autopilot will use.
 	s.statsFetcher = NewStatsFetcher(logger, s.connPool, s.config.Datacenter)
+	s.leaderHealth = newLeaderHealthMonitor(s.config.LeaderHealthConfig)
 
 	partitionInfo := serverPartitionInfo(s)
 	s.aclConfig = newACLConfig(partitionInfo, logger)
@@ -637,6 +665,8 @@ func NewServer(config *Config, flat Deps, externalGRPCServer *grpc.Server, incom
 		go s.gatewayLocator.Run(&lib.StopChannelContext{StopCh: s.shutdownCh})
 	}
 
+	go s.rpcRateLimitMonitor(&lib.StopChannelContext{StopCh: s.shutdownCh})
+
 	// Serf and dynamic bind ports
 	//
 	// The LAN serf cluster announces the port of the WAN serf cluster
@@ -826,6 +856,9 @@ func newGRPCHandlerFromConfig(deps Deps, config *Config, s *Server) connHandler
 		ConnectEnabled: config.ConnectEnabled,
 		PeeringEnabled: config.PeeringEnabled,
 		Locality:       config.Locality,
+
+		DefaultQueryTime: config.DefaultQueryTime,
+		MaxQueryTime:     config.MaxQueryTime,
 	})
 	s.peeringServer = p
 	o := operator.NewServer(operator.Config{
@@ -995,7 +1028,7 @@ func (s *Server) setupRaft() error {
 		// See if log verification is enabled
 		if s.config.LogStoreConfig.Verification.Enabled {
 			mc := walmetrics.NewGoMetricsCollector([]string{"raft", "logstore", "verifier"}, nil, nil)
-			reportFn := makeLogVerifyReportFn(s.logger.Named("raft.logstore.verifier"))
+			reportFn := makeLogVerifyReportFn(s.logger.Named("raft.logstore.verifier"), &s.logVerification)
 			verifier := verifier.NewLogStore(log, isLogVerifyCheckpoint, reportFn, mc)
 			s.raftStore = verifier
 			log = verifier
@@ -1109,7 +1142,9 @@ func (s *Server) setupRaft() error {
 
 	// Setup the Raft store.
 	var err error
-	s.raft, err = raft.NewRaft(s.config.RaftConfig, s.fsm.ChunkingFSM(), log, stable, snap, trans)
+	raftFSM := &timedFSM{FSM: s.fsm.ChunkingFSM(), tracker: &s.raftLatencies.fsmApply}
+	log = &timedLogStore{LogStore: log, tracker: &s.raftLatencies.storeLogs}
+	s.raft, err = raft.NewRaft(s.config.RaftConfig, raftFSM, log, stable, snap, trans)
 	return err
 }
 
@@ -1664,7 +1699,8 @@ func (s *Server) RPC(ctx context.Context, method string, args interface{}, reply
 		metrics.IncrCounter([]string{"client", "rpc", "exceeded"}, 1)
 		return structs.ErrRPCRateExceeded
 	}
-	if err := s.rpcServer.ServeRequest(codec); err != nil {
+	rateLimitingCodec := middleware.NewNetRPCRateLimitingCodec(codec, s.incomingRPCLimiter, middleware.NewPanicHandler(s.logger))
+	if err := s.rpcServer.ServeRequest(rateLimitingCodec); err != nil {
 		return err
 	}
 	return codec.err
@@ -1979,9 +2015,10 @@ func fileExists(name string) (bool, error) {
 func ConfiguredIncomingRPCLimiter(ctx context.Context, serverLogger hclog.InterceptLogger, consulCfg *Config) *rpcRate.Handler {
 	mlCfg := &multilimiter.Config{ReconcileCheckLimit: 30 * time.Second, ReconcileCheckInterval: time.Second}
 	limitsConfig := &RequestLimits{
-		Mode:      rpcRate.RequestLimitsModeFromNameWithDefault(consulCfg.RequestLimitsMode),
-		ReadRate:  consulCfg.RequestLimitsReadRate,
-		WriteRate: consulCfg.RequestLimitsWriteRate,
+		Mode:        rpcRate.RequestLimitsModeFromNameWithDefault(consulCfg.RequestLimitsMode),
+		ReadRate:    consulCfg.RequestLimitsReadRate,
+		WriteRate:   consulCfg.RequestLimitsWriteRate,
+		Concurrency: consulCfg.RequestConcurrencyLimits,
 	}
 
 	sink := logdrop.NewLogDropSink(ctx, 100, serverLogger.Named("rpc-rate-limit"), func(l logdrop.Log) {
@@ -2010,6 +2047,7 @@ func convertConsulConfigToRateLimitHandlerConfig(limitsConfig RequestLimits, mul
 				},
 			},
 		},
+		ConcurrencyConfig: limitsConfig.Concurrency,
 	}
 	if multilimiterConfig != nil {
 		hc.Config = *multilimiterConfig
@@ -6,6 +6,8 @@ package consul
 import (
 	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/raft"
@@ -32,8 +34,68 @@ func isLogVerifyCheckpoint(l *raft.Log) (bool, error) {
 	return typ == structs.RaftLogVerifierCheckpoint, nil
 }
 
-func makeLogVerifyReportFn(logger hclog.Logger) verifier.ReportFn {
+// logVerificationStatus keeps the recent log verification reports of this
+// server, so they can be queried through the operator endpoints.
+type logVerificationStatus struct {
+	lock   sync.Mutex
+	status structs.RaftLogVerificationStatus
+}
+
+// record updates the status with a new verification report.
+func (l *logVerificationStatus) record(r verifier.VerificationReport, now time.Time) {
+	report := &structs.RaftLogVerificationReport{
+		Time:             now,
+		Range:            structs.RaftLogRange{Start: r.Range.Start, End: r.Range.End},
+		Status:           structs.RaftLogVerificationOK,
+		ExpectedChecksum: fmt.Sprintf("%08x", r.ExpectedSum),
+		Elapsed:          r.Elapsed,
+	}
+	if r.SkippedRange != nil {
+		report.SkippedRange = &structs.RaftLogRange{Start: r.SkippedRange.Start, End: r.SkippedRange.End}
+	}
+	if r.WrittenSum > 0 {
+		report.WrittenChecksum = fmt.Sprintf("%08x", r.WrittenSum)
+	}
+	if r.ReadSum > 0 {
+		report.ReadChecksum = fmt.Sprintf("%08x", r.ReadSum)
+	}
+
+	var csErr verifier.ErrChecksumMismatch
+	switch {
+	case r.Err == nil:
+	case r.Err == verifier.ErrRangeMismatch:
+		report.Status = structs.RaftLogVerificationRangeMismatch
+		report.Error = "the server doesn't have all the logs in the range"
+	case errors.As(r.Err, &csErr):
+		report.Status = structs.RaftLogVerificationFailed
+		report.Error = r.Err.Error()
+	default:
+		report.Status = structs.RaftLogVerificationError
+		report.Error = r.Err.Error()
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.status.RangesVerified++
+	l.status.LastReport = report
+	if report.Status == structs.RaftLogVerificationFailed {
+		l.status.RangesFailed++
+		l.status.LastFailure = report
+	}
+}
+
+// get returns a copy of the status.
+func (l *logVerificationStatus) get() structs.RaftLogVerificationStatus {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.status
+}
+
+func makeLogVerifyReportFn(logger hclog.Logger, status *logVerificationStatus) verifier.ReportFn {
 	return func(r verifier.VerificationReport) {
+		status.record(r, time.Now())
+
 		if r.SkippedRange != nil {
 			logger.Warn("verification skipped range, consider decreasing validation interval if this is frequent",
 				"rangeStart", int64(r.SkippedRange.Start),
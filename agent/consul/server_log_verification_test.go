@@ -0,0 +1,70 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft-wal/verifier"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/consul/agent/structs"
+)
+
+func TestLogVerificationStatus_Record(t *testing.T) {
+	var l logVerificationStatus
+	now := time.Now()
+
+	l.record(verifier.VerificationReport{
+		Range:       verifier.LogRange{Start: 10, End: 20},
+		ExpectedSum: 0xabcd,
+		ReadSum:     0xabcd,
+		Elapsed:     time.Millisecond,
+	}, now)
+	status := l.get()
+	require.Equal(t, uint64(1), status.RangesVerified)
+	require.Zero(t, status.RangesFailed)
+	require.Nil(t, status.LastFailure)
+	require.Equal(t, &structs.RaftLogVerificationReport{
+		Time:             now,
+		Range:            structs.RaftLogRange{Start: 10, End: 20},
+		Status:           structs.RaftLogVerificationOK,
+		ExpectedChecksum: "0000abcd",
+		ReadChecksum:     "0000abcd",
+		Elapsed:          time.Millisecond,
+	}, status.LastReport)
+
+	l.record(verifier.VerificationReport{
+		Range:        verifier.LogRange{Start: 30, End: 40},
+		SkippedRange: &verifier.LogRange{Start: 20, End: 30},
+		ExpectedSum:  0xabcd,
+		WrittenSum:   0x1234,
+		Err:          verifier.ErrChecksumMismatch("in-flight corruption"),
+	}, now)
+	status = l.get()
+	require.Equal(t, uint64(2), status.RangesVerified)
+	require.Equal(t, uint64(1), status.RangesFailed)
+	require.Equal(t, structs.RaftLogVerificationFailed, status.LastReport.Status)
+	require.Equal(t, &structs.RaftLogRange{Start: 20, End: 30}, status.LastReport.SkippedRange)
+	require.Equal(t, "00001234", status.LastReport.WrittenChecksum)
+	require.Equal(t, "in-flight corruption", status.LastReport.Error)
+	require.Same(t, status.LastReport, status.LastFailure)
+
+	// The last failure is kept when later ranges can't be verified.
+	l.record(verifier.VerificationReport{
+		Range: verifier.LogRange{Start: 40, End: 50},
+		Err:   verifier.ErrRangeMismatch,
+	}, now)
+	l.record(verifier.VerificationReport{
+		Range: verifier.LogRange{Start: 50, End: 60},
+		Err:   errors.New("unable to read"),
+	}, now)
+	status = l.get()
+	require.Equal(t, uint64(4), status.RangesVerified)
+	require.Equal(t, uint64(1), status.RangesFailed)
+	require.Equal(t, structs.RaftLogVerificationError, status.LastReport.Status)
+	require.Equal(t, structs.RaftLogRange{Start: 30, End: 40}, status.LastFailure.Range)
+}
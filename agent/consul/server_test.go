@@ -1847,6 +1847,11 @@ func TestServer_ReloadConfig(t *testing.T) {
 			Mode:      rpcRate.ModeEnforcing,
 			ReadRate:  1000,
 			WriteRate: 1100,
+			Concurrency: rpcRate.ConcurrencyConfig{
+				Mode:  rpcRate.ModePermissive,
+				Read:  rpcRate.ConcurrencyClassConfig{InitialLimit: 100, MinLimit: 10, MaxLimit: 1000, LatencyTarget: time.Second},
+				Write: rpcRate.ConcurrencyClassConfig{InitialLimit: 50, MinLimit: 5, MaxLimit: 500, LatencyTarget: time.Second},
+			},
 		},
 		RPCClientTimeout:     2 * time.Minute,
 		RPCRateLimit:         1000,
@@ -1864,6 +1869,7 @@ func TestServer_ReloadConfig(t *testing.T) {
 
 	mockHandler := rpcRate.NewMockRequestLimitsHandler(t)
 	mockHandler.On("UpdateConfig", mock.Anything).Return(func(cfg rpcRate.HandlerConfig) {})
+	mockHandler.On("AllowToken", mock.Anything).Return(nil).Maybe()
 
 	s.incomingRPCLimiter = mockHandler
 	require.NoError(t, s.ReloadConfig(rc))
@@ -1897,6 +1903,7 @@ func TestServer_ReloadConfig(t *testing.T) {
 				},
 			},
 		},
+		ConcurrencyConfig: rc.RequestLimits.Concurrency,
 	})
 
 	// Check RPC client timeout got updated
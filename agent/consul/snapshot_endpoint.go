@@ -80,8 +80,17 @@ func (s *Server) dispatchSnapshotRequest(args *structs.SnapshotRequest, in io.Re
 		// pessimistic if we get more data while the snapshot is being taken.
 		s.setQueryMeta(&reply.QueryMeta, args.Token)
 
+		kp, err := snapshot.ParseKeyProvider(args.EncryptionKey, args.EncryptionPassphrase)
+		if err != nil {
+			return nil, err
+		}
+		opts := snapshot.Options{
+			Compression: snapshot.Compression(args.Compression),
+			KeyProvider: kp,
+		}
+
 		// Take the snapshot and capture the index.
-		snap, err := snapshot.New(s.logger, s.raft)
+		snap, err := snapshot.NewWithOptions(s.logger, s.raft, opts)
 		reply.Index = snap.Index()
 		return snap, err
 
@@ -90,8 +99,13 @@ func (s *Server) dispatchSnapshotRequest(args *structs.SnapshotRequest, in io.Re
 			return nil, fmt.Errorf("stale not allowed for restore")
 		}
 
+		kp, err := snapshot.ParseKeyProvider(args.EncryptionKey, args.EncryptionPassphrase)
+		if err != nil {
+			return nil, err
+		}
+
 		// Restore the snapshot.
-		if err := snapshot.Restore(s.logger, in, s.raft); err != nil {
+		if err := snapshot.Restore(s.logger, in, s.raft, kp); err != nil {
 			return nil, err
 		}
 
@@ -576,6 +576,11 @@ func validateProposedConfigEntryInGraph(
 	case structs.HTTPRoute:
 	case structs.TCPRoute:
 	case structs.RateLimitIPConfig:
+	case structs.RPCRateLimit:
+	case structs.KVPrefix:
+		if err := checkKVPrefixClash(tx, kindName, newEntry); err != nil {
+			return err
+		}
 	default:
 		return fmt.Errorf("unhandled kind %q during validation of %q", kindName.Kind, kindName.Name)
 	}
@@ -749,6 +754,10 @@ func validateProposedConfigEntryInServiceGraph(
 		// Exported services and mesh config do not influence discovery chains.
 		return nil
 
+	case structs.KVPrefix, structs.RPCRateLimit:
+		// KV prefixes and RPC rate limits are unrelated to services.
+		return nil
+
 	case structs.SamenessGroup:
 		// Any service resolver could reference a sameness group.
 		_, resolverEntries, err := configEntriesByKindTxn(tx, nil, structs.ServiceResolver, wildcardEntMeta)
@@ -18,6 +18,9 @@ func PBToStreamSubscribeRequest(req *pbsubscribe.SubscribeRequest, entMeta acl.E
 	var subject stream.Subject
 
 	if req.GetWildcardSubject() {
+		if req.Topic == EventTopicKV {
+			return nil, fmt.Errorf("topic %s does not support WildcardSubject, use an empty NamedSubject.Key instead", EventTopicKV)
+		}
 		subject = stream.SubjectWildcard
 	} else {
 		named := req.GetNamedSubject()
@@ -32,7 +35,9 @@ func PBToStreamSubscribeRequest(req *pbsubscribe.SubscribeRequest, entMeta acl.E
 			}
 		}
 
-		if named.Key == "" {
+		// The key of the KV topic is a prefix, which may be empty to subscribe
+		// to all the keys.
+		if named.Key == "" && req.Topic != EventTopicKV {
 			return nil, errors.New("either WildcardSubject or NamedSubject.Key is required")
 		}
 
@@ -51,6 +56,11 @@ func PBToStreamSubscribeRequest(req *pbsubscribe.SubscribeRequest, entMeta acl.E
 				Name:           named.Key,
 				EnterpriseMeta: &entMeta,
 			}
+		case EventTopicKV:
+			subject = EventSubjectKV{
+				Key:            named.Key,
+				EnterpriseMeta: entMeta,
+			}
 		case EventTopicServiceList:
 			// Events on this topic are published to SubjectNone, but rather than
 			// exposing this in (and further complicating) the streaming API we rely
@@ -152,6 +152,54 @@ func TestPBToStreamSubscribeRequest(t *testing.T) {
 			expectedSubscribeRequest: nil,
 			err:                      fmt.Errorf("topic %s can only be consumed using WildcardSubject", EventTopicServiceList),
 		},
+		"KV prefix": {
+			req: &pbsubscribe.SubscribeRequest{
+				Topic: EventTopicKV,
+				Subject: &pbsubscribe.SubscribeRequest_NamedSubject{
+					NamedSubject: &pbsubscribe.NamedSubject{
+						Key:       "config/",
+						Namespace: "consul",
+						Partition: "partition",
+					},
+				},
+				Token: aclToken,
+				Index: 2,
+			},
+			entMeta: acl.EnterpriseMeta{},
+			expectedSubscribeRequest: &stream.SubscribeRequest{
+				Topic: EventTopicKV,
+				Subject: EventSubjectKV{
+					Key:            "config/",
+					EnterpriseMeta: acl.EnterpriseMeta{},
+				},
+				Token: aclToken,
+				Index: 2,
+			},
+			err: nil,
+		},
+		"KV empty prefix": {
+			req: &pbsubscribe.SubscribeRequest{
+				Topic: EventTopicKV,
+				Subject: &pbsubscribe.SubscribeRequest_NamedSubject{
+					NamedSubject: &pbsubscribe.NamedSubject{},
+				},
+			},
+			entMeta: acl.EnterpriseMeta{},
+			expectedSubscribeRequest: &stream.SubscribeRequest{
+				Topic:   EventTopicKV,
+				Subject: EventSubjectKV{EnterpriseMeta: acl.EnterpriseMeta{}},
+			},
+			err: nil,
+		},
+		"KV with wildcard returns error": {
+			req: &pbsubscribe.SubscribeRequest{
+				Topic:   EventTopicKV,
+				Subject: &pbsubscribe.SubscribeRequest_WildcardSubject{WildcardSubject: true},
+			},
+			entMeta:                  acl.EnterpriseMeta{},
+			expectedSubscribeRequest: nil,
+			err:                      fmt.Errorf("topic %s does not support WildcardSubject, use an empty NamedSubject.Key instead", EventTopicKV),
+		},
 		"Unrecognized topic returns error": {
 			req: &pbsubscribe.SubscribeRequest{
 				Topic: 99999,
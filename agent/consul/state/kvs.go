@@ -113,6 +113,30 @@ func (s *Store) ReapTombstones(idx uint64, index uint64) error {
 	return tx.Commit()
 }
 
+// KVSCheckWrite returns an error if writing the entry would be rejected
+// because its value doesn't conform to the schema of its prefix or because it
+// would exceed the quota of its prefix. It is used to reject writes before
+// they are submitted to Raft, which is the only place the schema is enforced.
+func (s *Store) KVSCheckWrite(entry *structs.DirEntry) error {
+	tx := s.db.Txn(false)
+	defer tx.Abort()
+
+	existingNode, err := tx.First(tableKVs, indexID, entry)
+	if err != nil {
+		return fmt.Errorf("failed kvs lookup: %s", err)
+	}
+	existing, _ := existingNode.(*structs.DirEntry)
+
+	conf, err := kvPrefixConfigTxn(tx, nil, entry.Key, &entry.EnterpriseMeta)
+	if err != nil {
+		return err
+	}
+	if err := kvsValidateValue(existing, entry, conf); err != nil {
+		return err
+	}
+	return kvsCheckQuotaTxn(tx, existing, entry, conf)
+}
+
 // KVSSet is used to store a key/value pair.
 func (s *Store) KVSSet(idx uint64, entry *structs.DirEntry) error {
 	entry.EnterpriseMeta.Normalize()
@@ -166,6 +190,26 @@ func kvsSetTxn(tx WriteTxn, idx uint64, entry *structs.DirEntry, updateSession b
 	}
 	entry.ModifyIndex = idx
 
+	conf, err := kvPrefixConfigTxn(tx, nil, entry.Key, &entry.EnterpriseMeta)
+	if err != nil {
+		return err
+	}
+
+	// Values are only validated against the schema of their prefix by
+	// KVSCheckWrite, before they are submitted to Raft, so that servers with
+	// different versions of the validator can't apply the same log entry
+	// differently.
+	if err := kvsCheckQuotaTxn(tx, existing, entry, conf); err != nil {
+		return err
+	}
+
+	// Keep the previous version if the key has revision history enabled.
+	if existing != nil {
+		if err := kvsRecordRevisionWithConfigTxn(tx, idx, existing, false, conf); err != nil {
+			return err
+		}
+	}
+
 	// Store the kv pair in the state store and update the index.
 	if err := insertKVTxn(tx, entry, false, false); err != nil {
 		return fmt.Errorf("failed inserting kvs entry: %s", err)
@@ -259,6 +303,26 @@ func (s *Store) kvsListTxn(tx ReadTxn,
 	return idx, entries, nil
 }
 
+// KVSListTTL returns the entries with a TTL across all partitions and
+// namespaces. It is used by the leader to set up the expiry timers.
+func (s *Store) KVSListTTL() (structs.DirEntries, error) {
+	tx := s.db.Txn(false)
+	defer tx.Abort()
+
+	iter, err := tx.Get(tableKVs, indexID+"_prefix")
+	if err != nil {
+		return nil, fmt.Errorf("failed kvs lookup: %s", err)
+	}
+
+	var entries structs.DirEntries
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		if e := raw.(*structs.DirEntry); e.TTL != "" {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
 // KVSDelete is used to perform a shallow delete on a single key in the
 // the state store.
 func (s *Store) KVSDelete(idx uint64, key string, entMeta *acl.EnterpriseMeta) error {
@@ -295,6 +359,11 @@ func (s *Store) kvsDeleteTxn(tx WriteTxn, idx uint64, key string, entMeta *acl.E
 		return fmt.Errorf("failed adding to graveyard: %s", err)
 	}
 
+	// Keep the deleted version if the key has revision history enabled.
+	if err := kvsRecordRevisionTxn(tx, idx, entry.(*structs.DirEntry), true); err != nil {
+		return err
+	}
+
 	return kvsDeleteWithEntry(tx, entry.(*structs.DirEntry), idx)
 }
 
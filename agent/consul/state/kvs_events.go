@@ -0,0 +1,114 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package state
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/consul/acl"
+	"github.com/hashicorp/consul/agent/consul/stream"
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/proto/private/pbsubscribe"
+)
+
+// EventSubjectKV is a stream.Subject used to route and receive events for KV
+// entries. Events are published with the key of the entry, and subscriptions
+// receive the events of every key that starts with their key, since the KV
+// topic is registered with stream.EventPublisher.RegisterPrefixHandler.
+type EventSubjectKV struct {
+	Key            string
+	EnterpriseMeta acl.EnterpriseMeta
+}
+
+func (s EventSubjectKV) String() string {
+	return fmt.Sprintf(
+		"%s/%s/%s",
+		s.EnterpriseMeta.PartitionOrDefault(),
+		s.EnterpriseMeta.NamespaceOrDefault(),
+		s.Key,
+	)
+}
+
+type EventPayloadKV struct {
+	Op    pbsubscribe.KVUpdate_UpdateOp
+	Value *structs.DirEntry
+}
+
+func (e EventPayloadKV) Subject() stream.Subject {
+	return EventSubjectKV{
+		Key:            e.Value.Key,
+		EnterpriseMeta: e.Value.EnterpriseMeta,
+	}
+}
+
+func (e EventPayloadKV) HasReadPermission(authz acl.Authorizer) bool {
+	var authzContext acl.AuthorizerContext
+	e.Value.FillAuthzContext(&authzContext)
+	return authz.KeyRead(e.Value.Key, &authzContext) == acl.Allow
+}
+
+func (e EventPayloadKV) ToSubscriptionEvent(idx uint64) *pbsubscribe.Event {
+	return &pbsubscribe.Event{
+		Index: idx,
+		Payload: &pbsubscribe.Event_KV{
+			KV: &pbsubscribe.KVUpdate{
+				Op:    e.Op,
+				Entry: pbsubscribe.NewKVEntryFromStructs(e.Value),
+			},
+		},
+	}
+}
+
+// KVEventsFromChanges returns events that will be emitted when KV entries
+// change in the state store.
+func KVEventsFromChanges(_ ReadTxn, changes Changes) ([]stream.Event, error) {
+	var events []stream.Event
+	for _, c := range changes.Changes {
+		if c.Table != tableKVs {
+			continue
+		}
+
+		op := pbsubscribe.KVUpdate_Upsert
+		if c.Deleted() {
+			op = pbsubscribe.KVUpdate_Delete
+		}
+		events = append(events, kvEvent(changes.Index, op, changeObject(c).(*structs.DirEntry)))
+	}
+	return events, nil
+}
+
+// KVSnapshot is a stream.SnapshotFunc that returns a snapshot of the KV
+// entries under the prefix of the subject.
+func (s *Store) KVSnapshot(req stream.SubscribeRequest, buf stream.SnapshotAppender) (uint64, error) {
+	subject, ok := req.Subject.(EventSubjectKV)
+	if !ok {
+		return 0, fmt.Errorf("expected SubscribeRequest.Subject to be a: state.EventSubjectKV, was a: %T", req.Subject)
+	}
+
+	idx, entries, err := s.KVSList(nil, subject.Key, &subject.EnterpriseMeta)
+	if err != nil {
+		return 0, err
+	}
+
+	if l := len(entries); l != 0 {
+		events := make([]stream.Event, l)
+		for i, entry := range entries {
+			events[i] = kvEvent(idx, pbsubscribe.KVUpdate_Upsert, entry)
+		}
+		buf.Append(events)
+	}
+
+	return idx, nil
+}
+
+func kvEvent(idx uint64, op pbsubscribe.KVUpdate_UpdateOp, entry *structs.DirEntry) stream.Event {
+	return stream.Event{
+		Topic: EventTopicKV,
+		Index: idx,
+		Payload: EventPayloadKV{
+			Op:    op,
+			Value: entry,
+		},
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package state
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/consul/acl"
+	"github.com/hashicorp/consul/agent/consul/stream"
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/proto/private/pbsubscribe"
+)
+
+func TestKVEventsFromChanges(t *testing.T) {
+	const changeIndex uint64 = 123
+
+	store := testStateStore(t)
+	testSetKey(t, store, 1, "config/a", "1", nil)
+	testSetKey(t, store, 2, "config/b", "1", nil)
+
+	tx := store.db.WriteTxn(changeIndex)
+	t.Cleanup(tx.Abort)
+
+	require.NoError(t, kvsSetTxn(tx, changeIndex, &structs.DirEntry{Key: "config/a", Value: []byte("2")}, false))
+	existing, err := tx.First(tableKVs, indexID, Query{Value: "config/b"})
+	require.NoError(t, err)
+	require.NoError(t, kvsDeleteWithEntry(tx, existing.(*structs.DirEntry), changeIndex))
+
+	events, err := KVEventsFromChanges(tx, Changes{Index: changeIndex, Changes: tx.Changes()})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+
+	for _, event := range events {
+		require.Equal(t, EventTopicKV, event.Topic)
+		require.Equal(t, changeIndex, event.Index)
+	}
+
+	upsert := events[0].Payload.(EventPayloadKV)
+	require.Equal(t, pbsubscribe.KVUpdate_Upsert, upsert.Op)
+	require.Equal(t, "config/a", upsert.Value.Key)
+	require.Equal(t, []byte("2"), upsert.Value.Value)
+	require.Equal(t, EventSubjectKV{Key: "config/a", EnterpriseMeta: upsert.Value.EnterpriseMeta}, upsert.Subject())
+
+	del := events[1].Payload.(EventPayloadKV)
+	require.Equal(t, pbsubscribe.KVUpdate_Delete, del.Op)
+	require.Equal(t, "config/b", del.Value.Key)
+}
+
+func TestKVSnapshot(t *testing.T) {
+	store := testStateStore(t)
+	testSetKey(t, store, 1, "config/a", "1", nil)
+	testSetKey(t, store, 2, "config/b", "1", nil)
+	testSetKey(t, store, 3, "other", "1", nil)
+
+	testCases := map[string]struct {
+		prefix string
+		index  uint64
+		keys   []string
+	}{
+		"prefix": {
+			prefix: "config/",
+			index:  2,
+			keys:   []string{"config/a", "config/b"},
+		},
+		"key": {
+			prefix: "other",
+			index:  3,
+			keys:   []string{"other"},
+		},
+		"all keys": {
+			prefix: "",
+			index:  3,
+			keys:   []string{"config/a", "config/b", "other"},
+		},
+		"no keys": {
+			prefix: "missing/",
+			index:  3,
+		},
+	}
+	for desc, tc := range testCases {
+		t.Run(desc, func(t *testing.T) {
+			buf := &snapshotAppender{}
+
+			subject := EventSubjectKV{Key: tc.prefix}
+			idx, err := store.KVSnapshot(stream.SubscribeRequest{Subject: subject}, buf)
+			require.NoError(t, err)
+			require.Equal(t, tc.index, idx)
+
+			var keys []string
+			for _, events := range buf.events {
+				for _, event := range events {
+					payload := event.Payload.(EventPayloadKV)
+					require.Equal(t, pbsubscribe.KVUpdate_Upsert, payload.Op)
+					keys = append(keys, payload.Value.Key)
+				}
+			}
+			require.Equal(t, tc.keys, keys)
+		})
+	}
+}
+
+func TestEventPayloadKV_HasReadPermission(t *testing.T) {
+	authz, err := acl.NewPolicyAuthorizerWithDefaults(acl.DenyAll(), []*acl.Policy{
+		{
+			PolicyRules: acl.PolicyRules{
+				KeyPrefixes: []*acl.KeyRule{{Prefix: "config/", Policy: acl.PolicyRead}},
+			},
+		},
+	}, nil)
+	require.NoError(t, err)
+
+	allowed := EventPayloadKV{Value: &structs.DirEntry{Key: "config/a"}}
+	require.True(t, allowed.HasReadPermission(authz))
+
+	denied := EventPayloadKV{Value: &structs.DirEntry{Key: "secret/a"}}
+	require.False(t, denied.HasReadPermission(authz))
+}
+
+func TestEventPayloadKV_ToSubscriptionEvent(t *testing.T) {
+	entry := &structs.DirEntry{
+		Key:       "config/a",
+		Value:     []byte("1"),
+		Flags:     42,
+		Session:   "session",
+		LockIndex: 2,
+		RaftIndex: structs.RaftIndex{CreateIndex: 3, ModifyIndex: 4},
+	}
+	event := EventPayloadKV{Op: pbsubscribe.KVUpdate_Upsert, Value: entry}.ToSubscriptionEvent(4)
+	require.Equal(t, uint64(4), event.Index)
+	require.Equal(t, pbsubscribe.KVUpdate_Upsert, event.GetKV().Op)
+
+	actual := event.GetKV().Entry.ToStructs()
+	actual.EnterpriseMeta = entry.EnterpriseMeta
+	require.Equal(t, entry, actual)
+}
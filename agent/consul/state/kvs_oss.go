@@ -8,6 +8,7 @@ package state
 
 import (
 	"bytes"
+	"encoding/binary"
 	"fmt"
 
 	"github.com/hashicorp/go-memdb"
@@ -43,6 +44,41 @@ func prefixIndexForIDValue(arg interface{}) ([]byte, error) {
 	return nil, fmt.Errorf("unexpected type %T for singleValueID prefix index", arg)
 }
 
+func kvRevisionsIndexer() indexerSingleWithPrefix[KVRevisionQuery, *structs.DirEntryRevision, any] {
+	return indexerSingleWithPrefix[KVRevisionQuery, *structs.DirEntryRevision, any]{
+		readIndex:   indexFromKVRevisionQuery,
+		writeIndex:  indexFromKVRevision,
+		prefixIndex: prefixIndexForKVRevision,
+	}
+}
+
+func indexFromKVRevisionQuery(q KVRevisionQuery) ([]byte, error) {
+	var b indexBuilder
+	b.String(q.Key)
+	b.Raw(binary.BigEndian.AppendUint64(nil, q.Index))
+	return b.Bytes(), nil
+}
+
+func indexFromKVRevision(r *structs.DirEntryRevision) ([]byte, error) {
+	return indexFromKVRevisionQuery(KVRevisionQuery{Key: r.Key, Index: r.ModifyIndex})
+}
+
+// prefixIndexForKVRevision matches the revisions of the keys under a prefix
+// when given a string, or of a single key when given a Query.
+func prefixIndexForKVRevision(arg interface{}) ([]byte, error) {
+	switch v := arg.(type) {
+	case string:
+		return []byte(v), nil
+	case acl.EnterpriseMeta:
+		return nil, nil
+	case Query:
+		var b indexBuilder
+		b.String(v.Value)
+		return b.Bytes(), nil
+	}
+	return nil, fmt.Errorf("unexpected type %T for kv revision prefix index", arg)
+}
+
 func insertKVTxn(tx WriteTxn, entry *structs.DirEntry, updateMax bool, _ bool) error {
 	if err := tx.Insert(tableKVs, entry); err != nil {
 		return err
@@ -84,6 +120,10 @@ func kvsListEntriesTxn(tx ReadTxn, ws memdb.WatchSet, prefix string, entMeta acl
 // kvsDeleteTreeTxn is the inner method that does a recursive delete inside an
 // existing transaction.
 func (s *Store) kvsDeleteTreeTxn(tx WriteTxn, idx uint64, prefix string, entMeta *acl.EnterpriseMeta) error {
+	if err := kvsRecordTreeRevisionsTxn(tx, idx, prefix, entMeta); err != nil {
+		return err
+	}
+
 	// For prefix deletes, only insert one tombstone and delete the entire subtree
 	deleted, err := tx.DeletePrefix(tableKVs, indexID+"_prefix", prefix)
 	if err != nil {
@@ -120,3 +160,15 @@ func kvsDeleteWithEntry(tx WriteTxn, entry *structs.DirEntry, idx uint64) error
 
 	return nil
 }
+
+func kvPrefixUsageIndexer() indexerSingleWithPrefix[Query, *kvPrefixUsageEntry, any] {
+	return indexerSingleWithPrefix[Query, *kvPrefixUsageEntry, any]{
+		readIndex:   indexFromQuery,
+		writeIndex:  indexFromKVPrefixUsage,
+		prefixIndex: prefixIndexFromQuery,
+	}
+}
+
+func indexFromKVPrefixUsage(u *kvPrefixUsageEntry) ([]byte, error) {
+	return indexFromQuery(Query{Value: u.Name})
+}
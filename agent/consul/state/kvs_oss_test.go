@@ -68,3 +68,62 @@ func testIndexerTableTombstones() map[string]indexerTestCase {
 		},
 	}
 }
+
+func testIndexerTableKVRevisions() map[string]indexerTestCase {
+	return map[string]indexerTestCase{
+		indexID: {
+			read: indexValue{
+				source:   KVRevisionQuery{Key: "TheKey", Index: 258},
+				expected: []byte("TheKey\x00\x00\x00\x00\x00\x00\x00\x01\x02"),
+			},
+			write: indexValue{
+				source: &structs.DirEntryRevision{
+					DirEntry: structs.DirEntry{
+						Key:       "TheKey",
+						RaftIndex: structs.RaftIndex{ModifyIndex: 258},
+					},
+				},
+				expected: []byte("TheKey\x00\x00\x00\x00\x00\x00\x00\x01\x02"),
+			},
+			prefix: []indexValue{
+				{
+					source:   "indexString",
+					expected: []byte("indexString"),
+				},
+				{
+					source:   acl.EnterpriseMeta{},
+					expected: nil,
+				},
+				{
+					source:   Query{Value: "TheKey"},
+					expected: []byte("TheKey\x00"),
+				},
+			},
+		},
+	}
+}
+
+func testIndexerTableKVPrefixUsage() map[string]indexerTestCase {
+	return map[string]indexerTestCase{
+		indexID: {
+			read: indexValue{
+				source:   Query{Value: "Config"},
+				expected: []byte("config\x00"),
+			},
+			write: indexValue{
+				source:   &kvPrefixUsageEntry{Name: "Config"},
+				expected: []byte("config\x00"),
+			},
+			prefix: []indexValue{
+				{
+					source:   acl.EnterpriseMeta{},
+					expected: nil,
+				},
+				{
+					source:   structs.DefaultEnterpriseMetaInDefaultPartition(),
+					expected: nil,
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,272 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package state
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/go-memdb"
+
+	"github.com/hashicorp/consul/acl"
+	"github.com/hashicorp/consul/agent/structs"
+)
+
+const tableKVPrefixUsage = "kv-prefix-usage"
+
+// kvPrefixUsageTableSchema returns a new table schema used to track the number
+// of keys and bytes that each kv-prefix config entry applies to. The table is
+// derived from the KV entries and the config entries when transactions are
+// committed, so it is not part of snapshots.
+func kvPrefixUsageTableSchema() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: tableKVPrefixUsage,
+		Indexes: map[string]*memdb.IndexSchema{
+			indexID: {
+				Name:         indexID,
+				AllowMissing: false,
+				Unique:       true,
+				Indexer:      kvPrefixUsageIndexer(),
+			},
+		},
+	}
+}
+
+// kvPrefixUsageEntry is the usage of the keys a kv-prefix config entry applies
+// to, as of the Raft index it was last updated at.
+type kvPrefixUsageEntry struct {
+	Name string
+	acl.EnterpriseMeta
+	Keys  int
+	Bytes int
+	Index uint64
+}
+
+// kvEntrySize is the size of a KV entry counted towards a quota.
+func kvEntrySize(entry *structs.DirEntry) int {
+	return len(entry.Key) + len(entry.Value)
+}
+
+// updateKVPrefixUsage updates the usage of the kv-prefix config entries with
+// the changes of a transaction. When kv-prefix config entries changed, which
+// may change the entry that applies to any key, the usage of the entries of
+// the partitions is recomputed from all their keys.
+func updateKVPrefixUsage(tx WriteTxn, idx uint64, kvChanges memdb.Changes, recompute map[string]struct{}) error {
+	for partition := range recompute {
+		if err := recomputeKVPrefixUsageTxn(tx, idx, partition); err != nil {
+			return err
+		}
+	}
+
+	type delta struct {
+		conf         *structs.KVPrefixConfigEntry
+		keys, nbytes int
+	}
+	deltas := make(map[string]*delta)
+	configs := make(map[string][]*structs.KVPrefixConfigEntry)
+	add := func(entry *structs.DirEntry, sign int) error {
+		partition := entry.PartitionOrDefault()
+		if _, ok := recompute[partition]; ok {
+			return nil
+		}
+		entries, ok := configs[partition]
+		if !ok {
+			var err error
+			entries, err = kvPrefixConfigsTxn(tx, nil, &entry.EnterpriseMeta)
+			if err != nil {
+				return err
+			}
+			configs[partition] = entries
+		}
+		conf := matchKVPrefixConfig(entries, entry.Key)
+		if conf == nil {
+			return nil
+		}
+		id := partition + "/" + conf.Name
+		d, ok := deltas[id]
+		if !ok {
+			d = &delta{conf: conf}
+			deltas[id] = d
+		}
+		d.keys += sign
+		d.nbytes += sign * kvEntrySize(entry)
+		return nil
+	}
+
+	for _, change := range kvChanges {
+		if change.Before != nil {
+			if err := add(change.Before.(*structs.DirEntry), -1); err != nil {
+				return err
+			}
+		}
+		if change.After != nil {
+			if err := add(change.After.(*structs.DirEntry), 1); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, d := range deltas {
+		if d.keys == 0 && d.nbytes == 0 {
+			continue
+		}
+		usage, err := kvPrefixUsageTxn(tx, nil, d.conf)
+		if err != nil {
+			return err
+		}
+		updated := &kvPrefixUsageEntry{
+			Name:           d.conf.Name,
+			EnterpriseMeta: d.conf.EnterpriseMeta,
+			Keys:           usage.Keys + d.keys,
+			Bytes:          usage.Bytes + d.nbytes,
+			Index:          idx,
+		}
+		if err := tx.Insert(tableKVPrefixUsage, updated); err != nil {
+			return fmt.Errorf("failed updating kv prefix usage: %s", err)
+		}
+	}
+	return nil
+}
+
+// recomputeKVPrefixUsageTxn replaces the usage of the kv-prefix config entries
+// of the partition with the usage computed from all the keys of the partition.
+func recomputeKVPrefixUsageTxn(tx WriteTxn, idx uint64, partition string) error {
+	entMeta := structs.DefaultEnterpriseMetaInPartition(partition)
+	if _, err := tx.DeleteAll(tableKVPrefixUsage, indexID+"_prefix", entMeta); err != nil {
+		return fmt.Errorf("failed deleting kv prefix usage: %s", err)
+	}
+
+	entries, err := kvPrefixConfigsTxn(tx, nil, entMeta)
+	if err != nil || len(entries) == 0 {
+		return err
+	}
+	usages := make(map[string]*kvPrefixUsageEntry, len(entries))
+	for _, conf := range entries {
+		usages[conf.Name] = &kvPrefixUsageEntry{
+			Name:           conf.Name,
+			EnterpriseMeta: conf.EnterpriseMeta,
+			Index:          idx,
+		}
+	}
+
+	iter, err := tx.Get(tableKVs, indexID+"_prefix", *structs.WildcardEnterpriseMetaInPartition(partition))
+	if err != nil {
+		return fmt.Errorf("failed kvs lookup: %s", err)
+	}
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		entry := raw.(*structs.DirEntry)
+		if conf := matchKVPrefixConfig(entries, entry.Key); conf != nil {
+			usages[conf.Name].Keys++
+			usages[conf.Name].Bytes += kvEntrySize(entry)
+		}
+	}
+
+	for _, usage := range usages {
+		if err := tx.Insert(tableKVPrefixUsage, usage); err != nil {
+			return fmt.Errorf("failed updating kv prefix usage: %s", err)
+		}
+	}
+	return nil
+}
+
+// kvPrefixUsageTxn returns the usage of a kv-prefix config entry as of the
+// last committed transaction.
+func kvPrefixUsageTxn(tx ReadTxn, ws memdb.WatchSet, conf *structs.KVPrefixConfigEntry) (structs.KVPrefixUsage, error) {
+	usage := structs.KVPrefixUsage{
+		Name:           conf.Name,
+		Prefix:         conf.Prefix,
+		EnterpriseMeta: conf.EnterpriseMeta,
+	}
+	if conf.Quota != nil {
+		usage.MaxKeys = conf.Quota.MaxKeys
+		usage.MaxBytes = conf.Quota.MaxBytes
+	}
+
+	watchCh, raw, err := tx.FirstWatch(tableKVPrefixUsage, indexID,
+		Query{Value: conf.Name, EnterpriseMeta: conf.EnterpriseMeta})
+	if err != nil {
+		return usage, fmt.Errorf("failed kv prefix usage lookup: %s", err)
+	}
+	ws.Add(watchCh)
+	if entry, ok := raw.(*kvPrefixUsageEntry); ok {
+		usage.Keys = entry.Keys
+		usage.Bytes = entry.Bytes
+	}
+	return usage, nil
+}
+
+// kvsCheckQuotaTxn returns an error if replacing the existing entry with the
+// new one would exceed the quota of the kv-prefix config entry that applies to
+// the key. The usage includes the changes made earlier in the same
+// transaction, which are not reflected in the usage table until it is
+// committed.
+func kvsCheckQuotaTxn(tx ReadTxn, existing, entry *structs.DirEntry, conf *structs.KVPrefixConfigEntry) error {
+	if conf == nil || conf.Quota == nil {
+		return nil
+	}
+
+	keysDelta, bytesDelta := 1, kvEntrySize(entry)
+	if existing != nil {
+		keysDelta, bytesDelta = 0, bytesDelta-kvEntrySize(existing)
+	}
+	if keysDelta <= 0 && bytesDelta <= 0 {
+		return nil
+	}
+
+	usage, err := kvPrefixUsageTxn(tx, nil, conf)
+	if err != nil {
+		return err
+	}
+
+	if t, ok := tx.(*txn); ok {
+		entries, err := kvPrefixConfigsTxn(tx, nil, &conf.EnterpriseMeta)
+		if err != nil {
+			return err
+		}
+		for _, change := range t.Txn.Changes() {
+			if change.Table != tableKVs {
+				continue
+			}
+			if before, ok := change.Before.(*structs.DirEntry); ok && kvPrefixConfigApplies(entries, before.Key, conf) {
+				usage.Keys--
+				usage.Bytes -= kvEntrySize(before)
+			}
+			if after, ok := change.After.(*structs.DirEntry); ok && kvPrefixConfigApplies(entries, after.Key, conf) {
+				usage.Keys++
+				usage.Bytes += kvEntrySize(after)
+			}
+		}
+	}
+
+	return conf.CheckQuota(entry.Key, usage, keysDelta, bytesDelta)
+}
+
+// kvPrefixConfigApplies returns whether conf is the config entry that applies
+// to the key.
+func kvPrefixConfigApplies(entries []*structs.KVPrefixConfigEntry, key string, conf *structs.KVPrefixConfigEntry) bool {
+	match := matchKVPrefixConfig(entries, key)
+	return match != nil && match.Name == conf.Name
+}
+
+// KVPrefixUsage returns the usage of the kv-prefix config entries of the
+// partition.
+func (s *Store) KVPrefixUsage(ws memdb.WatchSet, entMeta *acl.EnterpriseMeta) (uint64, []structs.KVPrefixUsage, error) {
+	tx := s.db.ReadTxn()
+	defer tx.Abort()
+
+	entries, err := kvPrefixConfigsTxn(tx, ws, entMeta)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	idx := maxIndexTxn(tx, tableConfigEntries, tableKVs)
+	usages := make([]structs.KVPrefixUsage, 0, len(entries))
+	for _, conf := range entries {
+		usage, err := kvPrefixUsageTxn(tx, ws, conf)
+		if err != nil {
+			return 0, nil, err
+		}
+		usages = append(usages, usage)
+	}
+	return idx, usages, nil
+}
@@ -0,0 +1,184 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package state
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/api"
+)
+
+func requireKVPrefixUsage(t *testing.T, s *Store, expected map[string][2]int) {
+	t.Helper()
+
+	_, usages, err := s.KVPrefixUsage(nil, nil)
+	require.NoError(t, err)
+	actual := make(map[string][2]int)
+	for _, usage := range usages {
+		actual[usage.Name] = [2]int{usage.Keys, usage.Bytes}
+	}
+	require.Equal(t, expected, actual)
+}
+
+func TestStateStore_KVSSet_Quota(t *testing.T) {
+	s := testStateStore(t)
+	require.NoError(t, s.EnsureConfigEntry(1, &structs.KVPrefixConfigEntry{
+		Name:   "config",
+		Prefix: "config/",
+		Quota:  &structs.KVQuotaConfig{MaxKeys: 2, MaxBytes: 30},
+	}))
+
+	// "config/a" + "12345" is 13 bytes.
+	testSetKey(t, s, 2, "config/a", "12345", nil)
+	testSetKey(t, s, 3, "config/b", "1", nil)
+	testSetKey(t, s, 4, "other", "not counted", nil)
+	requireKVPrefixUsage(t, s, map[string][2]int{"config": {2, 22}})
+
+	// A new key exceeds MaxKeys.
+	err := s.KVSSet(5, &structs.DirEntry{Key: "config/c", Value: []byte("1")})
+	require.ErrorContains(t, err, `Writing key "config/c" would exceed the quota of kv-prefix config entry "config": 2 of 2 keys used`)
+	require.True(t, structs.IsErrKVQuotaExceeded(err))
+	require.Error(t, s.KVSCheckWrite(&structs.DirEntry{Key: "config/c", Value: []byte("1")}))
+
+	// A larger value exceeds MaxBytes.
+	err = s.KVSSet(5, &structs.DirEntry{Key: "config/b", Value: []byte("123456789-123")})
+	require.ErrorContains(t, err, "22 of 30 bytes used, the write needs 12 more")
+	require.True(t, structs.IsErrKVQuotaExceeded(err))
+
+	// Shrinking a value and deleting keys are always allowed.
+	testSetKey(t, s, 6, "config/a", "", nil)
+	requireKVPrefixUsage(t, s, map[string][2]int{"config": {2, 17}})
+	require.NoError(t, s.KVSDelete(7, "config/b", nil))
+	requireKVPrefixUsage(t, s, map[string][2]int{"config": {1, 8}})
+
+	testSetKey(t, s, 8, "config/c", "1", nil)
+	requireKVPrefixUsage(t, s, map[string][2]int{"config": {2, 17}})
+
+	// Deleting a tree updates the usage as well.
+	require.NoError(t, s.KVSDeleteTree(9, "config/", nil))
+	requireKVPrefixUsage(t, s, map[string][2]int{"config": {0, 0}})
+}
+
+func TestStateStore_Txn_KVS_Quota(t *testing.T) {
+	s := testStateStore(t)
+	require.NoError(t, s.EnsureConfigEntry(1, &structs.KVPrefixConfigEntry{
+		Name:   "config",
+		Prefix: "config/",
+		Quota:  &structs.KVQuotaConfig{MaxKeys: 2},
+	}))
+	testSetKey(t, s, 2, "config/a", "1", nil)
+
+	set := func(key string) *structs.TxnOp {
+		return &structs.TxnOp{
+			KV: &structs.TxnKVOp{
+				Verb:   api.KVSet,
+				DirEnt: structs.DirEntry{Key: key, Value: []byte("1")},
+			},
+		}
+	}
+
+	// The keys written earlier in the transaction count towards the quota.
+	results, errors := s.TxnRW(3, structs.TxnOps{set("config/b"), set("config/c")})
+	require.Nil(t, results)
+	require.Len(t, errors, 1)
+	require.Equal(t, 1, errors[0].OpIndex)
+	require.Contains(t, errors[0].What, "2 of 2 keys used")
+	requireKVPrefixUsage(t, s, map[string][2]int{"config": {1, 9}})
+
+	// And so do the keys deleted earlier in the transaction.
+	del := &structs.TxnOp{
+		KV: &structs.TxnKVOp{
+			Verb:   api.KVDelete,
+			DirEnt: structs.DirEntry{Key: "config/a"},
+		},
+	}
+	results, errors = s.TxnRW(4, structs.TxnOps{del, set("config/b"), set("config/c")})
+	require.Empty(t, errors)
+	require.Len(t, results, 2)
+	requireKVPrefixUsage(t, s, map[string][2]int{"config": {2, 18}})
+}
+
+func TestStateStore_KVPrefixUsage_ConfigChanges(t *testing.T) {
+	s := testStateStore(t)
+	testSetKey(t, s, 1, "config/a", "1", nil)
+	testSetKey(t, s, 2, "config/web/a", "1", nil)
+	testSetKey(t, s, 3, "other", "1", nil)
+
+	// The usage of a new entry includes the existing keys.
+	require.NoError(t, s.EnsureConfigEntry(4, &structs.KVPrefixConfigEntry{
+		Name:   "config",
+		Prefix: "config/",
+	}))
+	requireKVPrefixUsage(t, s, map[string][2]int{"config": {2, 22}})
+
+	// Only the entry with the longest prefix counts a key.
+	require.NoError(t, s.EnsureConfigEntry(5, &structs.KVPrefixConfigEntry{
+		Name:   "web",
+		Prefix: "config/web/",
+	}))
+	requireKVPrefixUsage(t, s, map[string][2]int{"config": {1, 9}, "web": {1, 13}})
+
+	testSetKey(t, s, 6, "config/web/b", "1", nil)
+	requireKVPrefixUsage(t, s, map[string][2]int{"config": {1, 9}, "web": {2, 26}})
+
+	// Changing the prefix of an entry recomputes the usage.
+	require.NoError(t, s.EnsureConfigEntry(7, &structs.KVPrefixConfigEntry{
+		Name:   "config",
+		Prefix: "",
+	}))
+	requireKVPrefixUsage(t, s, map[string][2]int{"config": {2, 15}, "web": {2, 26}})
+
+	require.NoError(t, s.DeleteConfigEntry(8, structs.KVPrefix, "web", nil))
+	requireKVPrefixUsage(t, s, map[string][2]int{"config": {4, 41}})
+
+	// A quota set below the current usage only prevents the usage from
+	// growing.
+	require.NoError(t, s.EnsureConfigEntry(9, &structs.KVPrefixConfigEntry{
+		Name:  "config",
+		Quota: &structs.KVQuotaConfig{MaxKeys: 1},
+	}))
+	err := s.KVSSet(10, &structs.DirEntry{Key: "new", Value: []byte("1")})
+	require.ErrorContains(t, err, "4 of 1 keys used")
+	testSetKey(t, s, 10, "other", "2", nil)
+	require.NoError(t, s.KVSDelete(11, "other", nil))
+	requireKVPrefixUsage(t, s, map[string][2]int{"config": {3, 35}})
+}
+
+func TestStateStore_KVPrefixUsage_Restore(t *testing.T) {
+	s := testStateStore(t)
+	testSetKey(t, s, 1, "config/a", "1", nil)
+	testSetKey(t, s, 2, "config/b", "12", nil)
+	conf := &structs.KVPrefixConfigEntry{
+		Kind:   structs.KVPrefix,
+		Name:   "config",
+		Prefix: "config/",
+		Quota:  &structs.KVQuotaConfig{MaxKeys: 2},
+	}
+	require.NoError(t, s.EnsureConfigEntry(3, conf))
+
+	snap := s.Snapshot()
+	defer snap.Close()
+	iter, err := snap.KVs()
+	require.NoError(t, err)
+	var entries structs.DirEntries
+	for entry := iter.Next(); entry != nil; entry = iter.Next() {
+		entries = append(entries, entry.(*structs.DirEntry))
+	}
+
+	// The usage isn't part of the snapshot, it is computed when restoring it.
+	restored := testStateStore(t)
+	restore := restored.Restore()
+	for _, entry := range entries {
+		require.NoError(t, restore.KVS(entry))
+	}
+	require.NoError(t, restore.ConfigEntry(conf))
+	require.NoError(t, restore.Commit())
+
+	requireKVPrefixUsage(t, restored, map[string][2]int{"config": {2, 19}})
+	err = restored.KVSSet(4, &structs.DirEntry{Key: "config/c"})
+	require.ErrorContains(t, err, "2 of 2 keys used")
+}
@@ -0,0 +1,404 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package state
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hashicorp/go-memdb"
+
+	"github.com/hashicorp/consul/acl"
+	"github.com/hashicorp/consul/agent/configentry"
+	"github.com/hashicorp/consul/agent/structs"
+)
+
+const tableKVRevisions = "kv-revisions"
+
+// kvRevisionsTableSchema returns a new table schema used for storing the
+// previous versions of the KV entries whose prefix has revision history
+// enabled. Revisions are indexed by key and ModifyIndex.
+func kvRevisionsTableSchema() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: tableKVRevisions,
+		Indexes: map[string]*memdb.IndexSchema{
+			indexID: {
+				Name:         indexID,
+				AllowMissing: false,
+				Unique:       true,
+				Indexer:      kvRevisionsIndexer(),
+			},
+		},
+	}
+}
+
+// KVRevisionQuery is used to look up the revision of a key by the
+// ModifyIndex of the revision.
+type KVRevisionQuery struct {
+	Key   string
+	Index uint64
+	acl.EnterpriseMeta
+}
+
+// NamespaceOrDefault exists because structs.EnterpriseMeta uses a pointer
+// receiver for this method. Remove once that is fixed.
+func (q KVRevisionQuery) NamespaceOrDefault() string {
+	return q.EnterpriseMeta.NamespaceOrDefault()
+}
+
+// PartitionOrDefault exists because structs.EnterpriseMeta uses a pointer
+// receiver for this method. Remove once that is fixed.
+func (q KVRevisionQuery) PartitionOrDefault() string {
+	return q.EnterpriseMeta.PartitionOrDefault()
+}
+
+// KVRevisions is used to pull all the KV revisions for use during snapshots.
+func (s *Snapshot) KVRevisions() (memdb.ResultIterator, error) {
+	return s.tx.Get(tableKVRevisions, indexID+"_prefix")
+}
+
+// KVRevision is used when restoring from a snapshot.
+func (s *Restore) KVRevision(rev *structs.DirEntryRevision) error {
+	if err := s.tx.Insert(tableKVRevisions, rev); err != nil {
+		return fmt.Errorf("failed inserting kv revision: %s", err)
+	}
+	if err := indexUpdateMaxTxn(s.tx, rev.SupersededIndex, tableKVRevisions); err != nil {
+		return fmt.Errorf("failed updating index: %s", err)
+	}
+	return nil
+}
+
+// kvPrefixConfigTxn returns the kv-prefix config entry with the longest
+// prefix matching the key, or nil if there is none.
+func kvPrefixConfigTxn(tx ReadTxn, ws memdb.WatchSet, key string, entMeta *acl.EnterpriseMeta) (*structs.KVPrefixConfigEntry, error) {
+	entries, err := kvPrefixConfigsTxn(tx, ws, entMeta)
+	if err != nil {
+		return nil, err
+	}
+	return matchKVPrefixConfig(entries, key), nil
+}
+
+// kvPrefixConfigsTxn returns the kv-prefix config entries of the partition.
+func kvPrefixConfigsTxn(tx ReadTxn, ws memdb.WatchSet, entMeta *acl.EnterpriseMeta) ([]*structs.KVPrefixConfigEntry, error) {
+	if entMeta == nil {
+		entMeta = structs.DefaultEnterpriseMetaInDefaultPartition()
+	}
+	_, raw, err := configEntriesByKindTxn(tx, ws, structs.KVPrefix,
+		structs.DefaultEnterpriseMetaInPartition(entMeta.PartitionOrDefault()))
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*structs.KVPrefixConfigEntry, 0, len(raw))
+	for _, entry := range raw {
+		conf, ok := entry.(*structs.KVPrefixConfigEntry)
+		if !ok {
+			return nil, fmt.Errorf("type %T is not a kv-prefix config entry", entry)
+		}
+		entries = append(entries, conf)
+	}
+	return entries, nil
+}
+
+// matchKVPrefixConfig returns the entry with the longest prefix matching the
+// key.
+func matchKVPrefixConfig(entries []*structs.KVPrefixConfigEntry, key string) *structs.KVPrefixConfigEntry {
+	var match *structs.KVPrefixConfigEntry
+	for _, entry := range entries {
+		if entry.Matches(key) && (match == nil || len(entry.Prefix) > len(match.Prefix)) {
+			match = entry
+		}
+	}
+	return match
+}
+
+// checkKVPrefixClash ensures that no two kv-prefix config entries have the same
+// prefix, so that there is always a single entry that applies to a key.
+func checkKVPrefixClash(tx ReadTxn, kindName configentry.KindName, newEntry structs.ConfigEntry) error {
+	// This is the case for deleting a config entry
+	if newEntry == nil {
+		return nil
+	}
+	proposed, ok := newEntry.(*structs.KVPrefixConfigEntry)
+	if !ok {
+		return fmt.Errorf("type %T is not a kv-prefix config entry", newEntry)
+	}
+
+	entries, err := kvPrefixConfigsTxn(tx, nil, &kindName.EnterpriseMeta)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.Name != proposed.Name && entry.Prefix == proposed.Prefix {
+			return fmt.Errorf("cannot create a %q config entry with name %q, "+
+				"the config entry %q already applies to the prefix %q",
+				structs.KVPrefix, proposed.Name, entry.Name, proposed.Prefix)
+		}
+	}
+	return nil
+}
+
+// kvsRecordRevisionTxn keeps the entry as a revision superseded at idx, if
+// revision history is enabled for its key, and removes the revisions of the key
+// beyond the maximum count.
+func kvsRecordRevisionTxn(tx WriteTxn, idx uint64, entry *structs.DirEntry, deleted bool) error {
+	conf, err := kvPrefixConfigTxn(tx, nil, entry.Key, &entry.EnterpriseMeta)
+	if err != nil {
+		return err
+	}
+	return kvsRecordRevisionWithConfigTxn(tx, idx, entry, deleted, conf)
+}
+
+func kvsRecordRevisionWithConfigTxn(tx WriteTxn, idx uint64, entry *structs.DirEntry, deleted bool, conf *structs.KVPrefixConfigEntry) error {
+	// A version written and superseded by the same transaction was never
+	// visible so it is not a revision.
+	if !conf.HistoryEnabled() || entry.ModifyIndex == idx {
+		return nil
+	}
+
+	rev := &structs.DirEntryRevision{
+		DirEntry:        *entry,
+		SupersededIndex: idx,
+		Deleted:         deleted,
+	}
+	if err := tx.Insert(tableKVRevisions, rev); err != nil {
+		return fmt.Errorf("failed inserting kv revision: %s", err)
+	}
+	if err := tx.Insert(tableIndex, &IndexEntry{tableKVRevisions, idx}); err != nil {
+		return fmt.Errorf("failed updating index: %s", err)
+	}
+
+	if max := conf.History.MaxRevisions; max > 0 {
+		revs, err := kvsRevisionsForKeyTxn(tx, nil, entry.Key, entry.EnterpriseMeta)
+		if err != nil {
+			return err
+		}
+		// Revisions are sorted oldest first.
+		for len(revs) > max {
+			if err := tx.Delete(tableKVRevisions, revs[0]); err != nil {
+				return fmt.Errorf("failed deleting kv revision: %s", err)
+			}
+			revs = revs[1:]
+		}
+	}
+	return nil
+}
+
+// kvsRecordTreeRevisionsTxn keeps the entries under the prefix that are about
+// to be deleted as revisions, for the keys that have revision history enabled.
+func kvsRecordTreeRevisionsTxn(tx WriteTxn, idx uint64, prefix string, entMeta *acl.EnterpriseMeta) error {
+	confs, err := kvPrefixConfigsTxn(tx, nil, entMeta)
+	if err != nil {
+		return err
+	}
+	var enabled bool
+	for _, conf := range confs {
+		enabled = enabled || conf.HistoryEnabled()
+	}
+	if !enabled {
+		return nil
+	}
+
+	if entMeta == nil {
+		entMeta = structs.DefaultEnterpriseMetaInDefaultPartition()
+	}
+	_, entries, err := kvsListEntriesTxn(tx, nil, prefix, *entMeta)
+	if err != nil {
+		return fmt.Errorf("failed kvs lookup: %s", err)
+	}
+	for _, entry := range entries {
+		conf := matchKVPrefixConfig(confs, entry.Key)
+		if err := kvsRecordRevisionWithConfigTxn(tx, idx, entry, true, conf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// kvsRevisionsForKeyTxn returns the revisions of a key, oldest first.
+func kvsRevisionsForKeyTxn(tx ReadTxn, ws memdb.WatchSet, key string, entMeta acl.EnterpriseMeta) (structs.DirEntryRevisions, error) {
+	iter, err := tx.Get(tableKVRevisions, indexID+"_prefix", Query{Value: key, EnterpriseMeta: entMeta})
+	if err != nil {
+		return nil, fmt.Errorf("failed kv revision lookup: %s", err)
+	}
+	ws.Add(iter.WatchCh())
+
+	var revs structs.DirEntryRevisions
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		revs = append(revs, raw.(*structs.DirEntryRevision))
+	}
+	return revs, nil
+}
+
+// KVSRevisions returns the revisions kept for a key, newest first.
+func (s *Store) KVSRevisions(ws memdb.WatchSet, key string, entMeta *acl.EnterpriseMeta) (uint64, structs.DirEntryRevisions, error) {
+	tx := s.db.Txn(false)
+	defer tx.Abort()
+
+	if entMeta == nil {
+		entMeta = structs.DefaultEnterpriseMetaInDefaultPartition()
+	}
+
+	idx := maxIndexTxn(tx, tableKVRevisions)
+	revs, err := kvsRevisionsForKeyTxn(tx, ws, key, *entMeta)
+	if err != nil {
+		return 0, nil, err
+	}
+	sort.SliceStable(revs, func(i, j int) bool {
+		return revs[i].ModifyIndex > revs[j].ModifyIndex
+	})
+	return idx, revs, nil
+}
+
+// KVSGetAtIndex returns the version of a key that was current at the given
+// index, or nil if the key didn't exist at that index or its revision is not
+// kept.
+func (s *Store) KVSGetAtIndex(ws memdb.WatchSet, key string, atIndex uint64, entMeta *acl.EnterpriseMeta) (uint64, *structs.DirEntry, error) {
+	tx := s.db.Txn(false)
+	defer tx.Abort()
+
+	if entMeta == nil {
+		entMeta = structs.DefaultEnterpriseMetaInDefaultPartition()
+	}
+
+	idx, entry, err := kvsGetTxn(tx, ws, key, *entMeta)
+	if err != nil {
+		return 0, nil, err
+	}
+	if revIdx := maxIndexTxn(tx, tableKVRevisions); revIdx > idx {
+		idx = revIdx
+	}
+	if entry != nil && entry.ModifyIndex <= atIndex {
+		return idx, entry, nil
+	}
+
+	revs, err := kvsRevisionsForKeyTxn(tx, ws, key, *entMeta)
+	if err != nil {
+		return 0, nil, err
+	}
+	for _, rev := range revs {
+		if rev.ModifyIndex <= atIndex && atIndex < rev.SupersededIndex {
+			return idx, &rev.DirEntry, nil
+		}
+	}
+	return idx, nil, nil
+}
+
+// KVSRevisionsToPrune returns whether a KVSPruneRevisions at the given time
+// would change any revision. It is used by the leader to skip applying no-op
+// prunes.
+func (s *Store) KVSRevisionsToPrune(now time.Time) (bool, error) {
+	tx := s.db.Txn(false)
+	defer tx.Abort()
+
+	stamp, remove, err := kvsRevisionsToPruneTxn(tx, now)
+	if err != nil {
+		return false, err
+	}
+	return len(stamp) > 0 || len(remove) > 0, nil
+}
+
+// KVSPruneRevisions sets the SupersededAt time of the revisions that don't
+// have one yet to now, and removes the revisions that exceed the limits of
+// their history, or whose key no longer has revision history enabled.
+func (s *Store) KVSPruneRevisions(idx uint64, now time.Time) error {
+	tx := s.db.WriteTxn(idx)
+	defer tx.Abort()
+
+	stamp, remove, err := kvsRevisionsToPruneTxn(tx, now)
+	if err != nil {
+		return err
+	}
+	if len(stamp) == 0 && len(remove) == 0 {
+		return nil
+	}
+
+	for _, rev := range stamp {
+		updated := *rev
+		updated.SupersededAt = now
+		if err := tx.Insert(tableKVRevisions, &updated); err != nil {
+			return fmt.Errorf("failed updating kv revision: %s", err)
+		}
+	}
+	for _, rev := range remove {
+		if err := tx.Delete(tableKVRevisions, rev); err != nil {
+			return fmt.Errorf("failed deleting kv revision: %s", err)
+		}
+	}
+	if err := tx.Insert(tableIndex, &IndexEntry{tableKVRevisions, idx}); err != nil {
+		return fmt.Errorf("failed updating index: %s", err)
+	}
+	return tx.Commit()
+}
+
+// kvsRevisionsToPruneTxn returns the revisions that need to be stamped with
+// the current time, and those that need to be removed.
+func kvsRevisionsToPruneTxn(tx ReadTxn, now time.Time) (stamp, remove structs.DirEntryRevisions, err error) {
+	iter, err := tx.Get(tableKVRevisions, indexID+"_prefix")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed kv revision lookup: %s", err)
+	}
+
+	// Revisions are sorted by key, oldest first, so the revisions of a key are
+	// collected to enforce the maximum count.
+	confs := make(map[string][]*structs.KVPrefixConfigEntry)
+	var keyRevs structs.DirEntryRevisions
+	flush := func() error {
+		if len(keyRevs) == 0 {
+			return nil
+		}
+		entMeta := keyRevs[0].EnterpriseMeta
+		partition := entMeta.PartitionOrDefault()
+		if _, ok := confs[partition]; !ok {
+			entries, err := kvPrefixConfigsTxn(tx, nil, &entMeta)
+			if err != nil {
+				return err
+			}
+			confs[partition] = entries
+		}
+
+		conf := matchKVPrefixConfig(confs[partition], keyRevs[0].Key)
+		if !conf.HistoryEnabled() {
+			remove = append(remove, keyRevs...)
+			keyRevs = nil
+			return nil
+		}
+
+		excess := 0
+		if max := conf.History.MaxRevisions; max > 0 && len(keyRevs) > max {
+			excess = len(keyRevs) - max
+		}
+		for i, rev := range keyRevs {
+			switch {
+			case i < excess:
+				remove = append(remove, rev)
+			case rev.SupersededAt.IsZero():
+				stamp = append(stamp, rev)
+			case conf.History.MaxAge > 0 && now.Sub(rev.SupersededAt) >= conf.History.MaxAge:
+				remove = append(remove, rev)
+			}
+		}
+		keyRevs = nil
+		return nil
+	}
+
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		rev := raw.(*structs.DirEntryRevision)
+		if len(keyRevs) > 0 && !sameKVRevisionKey(keyRevs[0], rev) {
+			if err := flush(); err != nil {
+				return nil, nil, err
+			}
+		}
+		keyRevs = append(keyRevs, rev)
+	}
+	if err := flush(); err != nil {
+		return nil, nil, err
+	}
+	return stamp, remove, nil
+}
+
+func sameKVRevisionKey(a, b *structs.DirEntryRevision) bool {
+	return a.Key == b.Key && a.EnterpriseMeta.IsSame(&b.EnterpriseMeta)
+}
@@ -0,0 +1,212 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package state
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/consul/agent/structs"
+)
+
+func testKVPrefixConfig(t *testing.T, s *Store, idx uint64, name, prefix string, history *structs.KVHistoryConfig) {
+	t.Helper()
+	require.NoError(t, s.EnsureConfigEntry(idx, &structs.KVPrefixConfigEntry{
+		Name:    name,
+		Prefix:  prefix,
+		History: history,
+	}))
+}
+
+func testKVRevisionValues(t *testing.T, s *Store, key string) []string {
+	t.Helper()
+	_, revs, err := s.KVSRevisions(nil, key, nil)
+	require.NoError(t, err)
+	values := make([]string, 0, len(revs))
+	for _, rev := range revs {
+		values = append(values, string(rev.Value))
+	}
+	return values
+}
+
+func TestStateStore_KVSRevisions(t *testing.T) {
+	s := testStateStore(t)
+	testKVPrefixConfig(t, s, 1, "config", "config/", &structs.KVHistoryConfig{MaxRevisions: 3})
+
+	// Keys outside of the prefix have no history.
+	testSetKey(t, s, 2, "other", "a", nil)
+	testSetKey(t, s, 3, "other", "b", nil)
+	require.Empty(t, testKVRevisionValues(t, s, "other"))
+
+	testSetKey(t, s, 4, "config/app", "v1", nil)
+	testSetKey(t, s, 5, "config/app", "v2", nil)
+	testSetKey(t, s, 6, "config/app", "v3", nil)
+
+	idx, revs, err := s.KVSRevisions(nil, "config/app", nil)
+	require.NoError(t, err)
+	require.Equal(t, uint64(6), idx)
+	require.Len(t, revs, 2)
+	require.Equal(t, "v2", string(revs[0].Value))
+	require.Equal(t, uint64(5), revs[0].ModifyIndex)
+	require.Equal(t, uint64(6), revs[0].SupersededIndex)
+	require.False(t, revs[0].Deleted)
+	require.Equal(t, "v1", string(revs[1].Value))
+
+	// An unchanged write is not a revision.
+	testSetKey(t, s, 7, "config/app", "v3", nil)
+	require.Equal(t, []string{"v2", "v1"}, testKVRevisionValues(t, s, "config/app"))
+
+	// Revisions beyond MaxRevisions are removed, oldest first.
+	testSetKey(t, s, 8, "config/app", "v4", nil)
+	testSetKey(t, s, 9, "config/app", "v5", nil)
+	require.Equal(t, []string{"v4", "v3", "v2"}, testKVRevisionValues(t, s, "config/app"))
+
+	// Revisions of a key don't include those of keys it is a prefix of.
+	testSetKey(t, s, 10, "config/app2", "x", nil)
+	testSetKey(t, s, 11, "config/app2", "y", nil)
+	require.Equal(t, []string{"v4", "v3", "v2"}, testKVRevisionValues(t, s, "config/app"))
+	require.Equal(t, []string{"x"}, testKVRevisionValues(t, s, "config/app2"))
+
+	// Deletes are kept as revisions.
+	require.NoError(t, s.KVSDelete(12, "config/app", nil))
+	_, revs, err = s.KVSRevisions(nil, "config/app", nil)
+	require.NoError(t, err)
+	require.Len(t, revs, 3)
+	require.Equal(t, "v5", string(revs[0].Value))
+	require.True(t, revs[0].Deleted)
+	require.Equal(t, uint64(12), revs[0].SupersededIndex)
+
+	// So are tree deletes.
+	require.NoError(t, s.KVSDeleteTree(13, "config/", nil))
+	_, revs, err = s.KVSRevisions(nil, "config/app2", nil)
+	require.NoError(t, err)
+	require.Len(t, revs, 2)
+	require.Equal(t, "y", string(revs[0].Value))
+	require.True(t, revs[0].Deleted)
+}
+
+func TestStateStore_KVSRevisions_LongestPrefix(t *testing.T) {
+	s := testStateStore(t)
+	testKVPrefixConfig(t, s, 1, "config", "config/", &structs.KVHistoryConfig{MaxRevisions: 10})
+	testKVPrefixConfig(t, s, 2, "scratch", "config/scratch/", nil)
+
+	testSetKey(t, s, 3, "config/app", "a", nil)
+	testSetKey(t, s, 4, "config/app", "b", nil)
+	testSetKey(t, s, 5, "config/scratch/app", "a", nil)
+	testSetKey(t, s, 6, "config/scratch/app", "b", nil)
+
+	require.Equal(t, []string{"a"}, testKVRevisionValues(t, s, "config/app"))
+	require.Empty(t, testKVRevisionValues(t, s, "config/scratch/app"))
+
+	// Two config entries can't have the same prefix.
+	err := s.EnsureConfigEntry(7, &structs.KVPrefixConfigEntry{Name: "dup", Prefix: "config/"})
+	require.ErrorContains(t, err, `the config entry "config" already applies to the prefix "config/"`)
+}
+
+func TestStateStore_KVSGetAtIndex(t *testing.T) {
+	s := testStateStore(t)
+	testKVPrefixConfig(t, s, 1, "config", "config/", &structs.KVHistoryConfig{MaxRevisions: 10})
+
+	testSetKey(t, s, 2, "config/app", "v1", nil)
+	testSetKey(t, s, 4, "config/app", "v2", nil)
+	require.NoError(t, s.KVSDelete(6, "config/app", nil))
+	testSetKey(t, s, 8, "config/app", "v3", nil)
+
+	for at, expected := range map[uint64]string{
+		1:   "",
+		2:   "v1",
+		3:   "v1",
+		4:   "v2",
+		5:   "v2",
+		6:   "",
+		7:   "",
+		8:   "v3",
+		100: "v3",
+	} {
+		_, entry, err := s.KVSGetAtIndex(nil, "config/app", at, nil)
+		require.NoError(t, err)
+		if expected == "" {
+			require.Nil(t, entry, "index %d", at)
+			continue
+		}
+		require.NotNil(t, entry, "index %d", at)
+		require.Equal(t, expected, string(entry.Value), "index %d", at)
+	}
+}
+
+func TestStateStore_KVSPruneRevisions(t *testing.T) {
+	s := testStateStore(t)
+	testKVPrefixConfig(t, s, 1, "config", "config/", &structs.KVHistoryConfig{MaxAge: time.Hour})
+	testKVPrefixConfig(t, s, 2, "other", "other/", &structs.KVHistoryConfig{MaxRevisions: 10})
+
+	testSetKey(t, s, 3, "config/app", "v1", nil)
+	testSetKey(t, s, 4, "config/app", "v2", nil)
+	testSetKey(t, s, 5, "other/app", "v1", nil)
+	testSetKey(t, s, 6, "other/app", "v2", nil)
+
+	// The first prune stamps the new revisions.
+	now := time.Now().UTC()
+	prune, err := s.KVSRevisionsToPrune(now)
+	require.NoError(t, err)
+	require.True(t, prune)
+	require.NoError(t, s.KVSPruneRevisions(7, now))
+
+	_, revs, err := s.KVSRevisions(nil, "config/app", nil)
+	require.NoError(t, err)
+	require.Len(t, revs, 1)
+	require.True(t, now.Equal(revs[0].SupersededAt))
+
+	prune, err = s.KVSRevisionsToPrune(now.Add(time.Minute))
+	require.NoError(t, err)
+	require.False(t, prune)
+
+	// Revisions older than MaxAge are removed.
+	testSetKey(t, s, 8, "config/app", "v3", nil)
+	require.NoError(t, s.KVSPruneRevisions(9, now.Add(30*time.Minute)))
+	require.NoError(t, s.KVSPruneRevisions(10, now.Add(time.Hour)))
+	require.Equal(t, []string{"v2"}, testKVRevisionValues(t, s, "config/app"))
+	require.Equal(t, []string{"v1"}, testKVRevisionValues(t, s, "other/app"))
+
+	// Revisions of keys whose history is disabled are removed.
+	require.NoError(t, s.DeleteConfigEntry(11, structs.KVPrefix, "other", nil))
+	require.NoError(t, s.KVSPruneRevisions(12, now.Add(time.Hour)))
+	require.Empty(t, testKVRevisionValues(t, s, "other/app"))
+	require.Equal(t, []string{"v2"}, testKVRevisionValues(t, s, "config/app"))
+}
+
+func TestStateStore_KVRevisions_Snapshot_Restore(t *testing.T) {
+	s := testStateStore(t)
+	testKVPrefixConfig(t, s, 1, "config", "config/", &structs.KVHistoryConfig{MaxRevisions: 10})
+	testSetKey(t, s, 2, "config/app", "v1", nil)
+	testSetKey(t, s, 3, "config/app", "v2", nil)
+	testSetKey(t, s, 4, "config/db", "v1", nil)
+	require.NoError(t, s.KVSDelete(5, "config/db", nil))
+
+	snap := s.Snapshot()
+	defer snap.Close()
+
+	iter, err := snap.KVRevisions()
+	require.NoError(t, err)
+	var dump structs.DirEntryRevisions
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		dump = append(dump, raw.(*structs.DirEntryRevision))
+	}
+	require.Len(t, dump, 2)
+
+	s2 := testStateStore(t)
+	restore := s2.Restore()
+	for _, rev := range dump {
+		require.NoError(t, restore.KVRevision(rev))
+	}
+	require.NoError(t, restore.Commit())
+
+	idx, revs, err := s2.KVSRevisions(nil, "config/db", nil)
+	require.NoError(t, err)
+	require.Equal(t, uint64(5), idx)
+	require.Len(t, revs, 1)
+	require.True(t, revs[0].Deleted)
+	require.Equal(t, []string{"v1"}, testKVRevisionValues(t, s2, "config/app"))
+}
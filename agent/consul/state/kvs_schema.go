@@ -0,0 +1,21 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package state
+
+import (
+	"bytes"
+
+	"github.com/hashicorp/consul/agent/structs"
+)
+
+// kvsValidateValue validates the value of the entry against the schema of the
+// kv-prefix config entry that applies to its key. Writes that leave the value
+// unchanged, such as lock operations, are not validated so that a schema added
+// later doesn't prevent them.
+func kvsValidateValue(existing, entry *structs.DirEntry, conf *structs.KVPrefixConfigEntry) error {
+	if existing != nil && bytes.Equal(existing.Value, entry.Value) {
+		return nil
+	}
+	return conf.ValidateValue(entry.Key, entry.Value)
+}
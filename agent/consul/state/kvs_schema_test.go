@@ -0,0 +1,84 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package state
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/api"
+)
+
+const testKVSchema = `{
+	"type": "object",
+	"required": ["port"],
+	"additionalProperties": false,
+	"properties": {"port": {"type": "integer"}}
+}`
+
+func TestStateStore_KVSCheckWrite_Schema(t *testing.T) {
+	s := testStateStore(t)
+
+	// Values written before the schema are kept.
+	testSetKey(t, s, 1, "config/app", "not json", nil)
+
+	require.NoError(t, s.EnsureConfigEntry(2, &structs.KVPrefixConfigEntry{
+		Name:   "config",
+		Prefix: "config/",
+		Schema: testKVSchema,
+	}))
+
+	require.NoError(t, s.KVSCheckWrite(&structs.DirEntry{Key: "config/db", Value: []byte(`{"port": 5432}`)}))
+	require.NoError(t, s.KVSCheckWrite(&structs.DirEntry{Key: "other", Value: []byte("anything")}))
+
+	// Folder keys aren't validated.
+	require.NoError(t, s.KVSCheckWrite(&structs.DirEntry{Key: "config/web/"}))
+
+	for value, expected := range map[string]string{
+		`{"port": "80"}`:         `port: Invalid type. Expected: integer, given: string`,
+		`{"prot": 80}`:           `(root): port is required`,
+		`{"port": 80, "x": 1}`:   `(root): Additional property x is not allowed`,
+		`{"port": 80`:            `value is not valid JSON`,
+		``:                       `value is not valid JSON`,
+		`{"port": 80} trailing`:  `value is not valid JSON`,
+		`{"port": 80, "x": [1]}`: `Value of key "config/db" does not conform to the schema of kv-prefix config entry "config"`,
+	} {
+		err := s.KVSCheckWrite(&structs.DirEntry{Key: "config/db", Value: []byte(value)})
+		require.ErrorContains(t, err, expected, value)
+		require.True(t, structs.IsErrKVSchemaViolation(err))
+	}
+
+	// Writes that don't change the value aren't validated, so that a session
+	// can still lock and unlock a key written before the schema.
+	require.NoError(t, s.KVSCheckWrite(&structs.DirEntry{Key: "config/app", Value: []byte("not json")}))
+	require.ErrorContains(t, s.KVSCheckWrite(&structs.DirEntry{Key: "config/app", Value: []byte("still not json")}),
+		"value is not valid JSON")
+}
+
+func TestStateStore_KVSSet_Schema(t *testing.T) {
+	s := testStateStore(t)
+	require.NoError(t, s.EnsureConfigEntry(1, &structs.KVPrefixConfigEntry{
+		Name:   "config",
+		Prefix: "config/",
+		Schema: testKVSchema,
+	}))
+
+	// The schema is only enforced before writes are submitted to Raft, so that
+	// applying them doesn't depend on the version of the validator.
+	testSetKey(t, s, 2, "config/db", "not json", nil)
+
+	ops := structs.TxnOps{
+		&structs.TxnOp{
+			KV: &structs.TxnKVOp{
+				Verb:   api.KVSet,
+				DirEnt: structs.DirEntry{Key: "config/a", Value: []byte(`{"port": true}`)},
+			},
+		},
+	}
+	results, errors := s.TxnRW(3, ops)
+	require.Empty(t, errors)
+	require.Len(t, results, 1)
+}
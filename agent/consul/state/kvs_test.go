@@ -488,6 +488,32 @@ func TestStateStore_KVSList(t *testing.T) {
 	}
 }
 
+func TestStateStore_KVSListTTL(t *testing.T) {
+	s := testStateStore(t)
+
+	entries, err := s.KVSListTTL()
+	require.NoError(t, err)
+	require.Empty(t, entries)
+
+	testSetKey(t, s, 1, "foo", "foo", nil)
+	require.NoError(t, s.KVSSet(2, &structs.DirEntry{Key: "bar", Value: []byte("bar"), TTL: "10s"}))
+	require.NoError(t, s.KVSSet(3, &structs.DirEntry{Key: "baz/qux", Value: []byte("qux"), TTL: "1m"}))
+
+	entries, err = s.KVSListTTL()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, "bar", entries[0].Key)
+	require.Equal(t, "10s", entries[0].TTL)
+	require.Equal(t, "baz/qux", entries[1].Key)
+
+	// Writing the key without a TTL removes it from the list.
+	testSetKey(t, s, 4, "bar", "bar", nil)
+	entries, err = s.KVSListTTL()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, "baz/qux", entries[0].Key)
+}
+
 func TestStateStore_KVSDelete(t *testing.T) {
 	s := testStateStore(t)
 
@@ -31,6 +31,8 @@ func newDBSchema() *memdb.DBSchema {
 		intentionsTableSchema,
 		kindServiceNameTableSchema,
 		kvsTableSchema,
+		kvRevisionsTableSchema,
+		kvPrefixUsageTableSchema,
 		meshTopologyTableSchema,
 		nodesTableSchema,
 		peeringTableSchema,
@@ -57,8 +57,10 @@ func TestNewDBSchema_Indexers(t *testing.T) {
 		tableServiceVirtualIPs: testIndexerTableServiceVirtualIPs,
 		tableKindServiceNames:  testIndexerTableKindServiceNames,
 		// KV
-		tableKVs:        testIndexerTableKVs,
-		tableTombstones: testIndexerTableTombstones,
+		tableKVs:           testIndexerTableKVs,
+		tableKVRevisions:   testIndexerTableKVRevisions,
+		tableKVPrefixUsage: testIndexerTableKVPrefixUsage,
+		tableTombstones:    testIndexerTableTombstones,
 		// config
 		tableConfigEntries: testIndexerTableConfigEntries,
 		// peerings
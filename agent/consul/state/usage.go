@@ -91,6 +91,8 @@ const (
 func updateUsage(tx WriteTxn, changes Changes) error {
 	usageDeltas := make(map[string]int)
 	serviceNameChanges := make(map[structs.ServiceName]int)
+	var kvChanges memdb.Changes
+	kvPrefixPartitions := make(map[string]struct{})
 	for _, change := range changes.Changes {
 		var delta int
 		if change.Created() {
@@ -139,10 +141,14 @@ func updateUsage(tx WriteTxn, changes Changes) error {
 		case "kvs":
 			usageDeltas[change.Table] += delta
 			addEnterpriseKVUsage(usageDeltas, change)
+			kvChanges = append(kvChanges, change)
 		case tableConfigEntries:
 			entry := changeObject(change).(structs.ConfigEntry)
 			usageDeltas[configEntryUsageTableName(entry.GetKind())] += delta
 			addEnterpriseConfigEntryUsage(usageDeltas, change)
+			if entry.GetKind() == structs.KVPrefix {
+				kvPrefixPartitions[entry.GetEnterpriseMeta().PartitionOrDefault()] = struct{}{}
+			}
 		}
 	}
 
@@ -160,6 +166,12 @@ func updateUsage(tx WriteTxn, changes Changes) error {
 		idx = maxIndexTxn(tx, tableNodes, tableServices, "kvs")
 	}
 
+	if len(kvChanges) > 0 || len(kvPrefixPartitions) > 0 {
+		if err := updateKVPrefixUsage(tx, idx, kvChanges, kvPrefixPartitions); err != nil {
+			return err
+		}
+	}
+
 	return writeUsageDeltas(tx, idx, usageDeltas)
 }
 
@@ -29,6 +29,11 @@ type StatsFetcher struct {
 	datacenter   string
 	inflight     map[raft.ServerID]struct{}
 	inflightLock sync.Mutex
+
+	// lastStats holds the stats of the servers that replied to the last
+	// fetch, for the leader health checks.
+	lastStats     map[raft.ServerID]*structs.RaftStats
+	lastStatsLock sync.Mutex
 }
 
 // NewStatsFetcher returns a stats fetcher.
@@ -45,7 +50,7 @@ func NewStatsFetcher(logger hclog.Logger, pool *pool.ConnPool, datacenter string
 // cancel this when the context is canceled because we only want one in-flight
 // RPC to each server, so we let it finish and then clean up the in-flight
 // tracking.
-func (f *StatsFetcher) fetch(server *autopilot.Server, replyCh chan *autopilot.ServerStats) {
+func (f *StatsFetcher) fetch(server *autopilot.Server, replyCh chan *structs.RaftStats) {
 	var args EmptyReadRequest
 	var reply structs.RaftStats
 
@@ -74,14 +79,14 @@ func (f *StatsFetcher) fetch(server *autopilot.Server, replyCh chan *autopilot.S
 		return
 	}
 
-	replyCh <- reply.ToAutopilotServerStats()
+	replyCh <- &reply
 }
 
 // Fetch will attempt to query all the servers in parallel.
 func (f *StatsFetcher) Fetch(ctx context.Context, servers map[raft.ServerID]*autopilot.Server) map[raft.ServerID]*autopilot.ServerStats {
 	type workItem struct {
 		server  *autopilot.Server
-		replyCh chan *autopilot.ServerStats
+		replyCh chan *structs.RaftStats
 	}
 
 	// Skip any servers that have inflight requests.
@@ -96,7 +101,7 @@ func (f *StatsFetcher) Fetch(ctx context.Context, servers map[raft.ServerID]*aut
 		} else {
 			workItem := &workItem{
 				server:  server,
-				replyCh: make(chan *autopilot.ServerStats, 1),
+				replyCh: make(chan *structs.RaftStats, 1),
 			}
 			work = append(work, workItem)
 			f.inflight[server.ID] = struct{}{}
@@ -107,7 +112,7 @@ func (f *StatsFetcher) Fetch(ctx context.Context, servers map[raft.ServerID]*aut
 
 	// Now wait for the results to come in, or for the context to be
 	// canceled.
-	replies := make(map[raft.ServerID]*autopilot.ServerStats)
+	replies := make(map[raft.ServerID]*structs.RaftStats)
 	for _, workItem := range work {
 		// Drain the reply first if there is one.
 		select {
@@ -132,5 +137,22 @@ func (f *StatsFetcher) Fetch(ctx context.Context, servers map[raft.ServerID]*aut
 			f.inflightLock.Unlock()
 		}
 	}
-	return replies
+
+	f.lastStatsLock.Lock()
+	f.lastStats = replies
+	f.lastStatsLock.Unlock()
+
+	stats := make(map[raft.ServerID]*autopilot.ServerStats, len(replies))
+	for id, reply := range replies {
+		stats[id] = reply.ToAutopilotServerStats()
+	}
+	return stats
+}
+
+// LastStats returns the Raft stats of the servers that replied to the last
+// fetch. The returned map must not be modified.
+func (f *StatsFetcher) LastStats() map[raft.ServerID]*structs.RaftStats {
+	f.lastStatsLock.Lock()
+	defer f.lastStatsLock.Unlock()
+	return f.lastStats
 }
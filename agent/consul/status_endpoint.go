@@ -6,6 +6,7 @@ package consul
 import (
 	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/hashicorp/consul/agent/structs"
 )
@@ -80,6 +81,16 @@ func (s *Status) RaftStats(args EmptyReadRequest, reply *structs.RaftStats) erro
 	if err != nil {
 		return fmt.Errorf("error parsing server's last_log_term value: %w", err)
 	}
+	s.server.raftLatencies.fillStats(reply, time.Now())
+
+	return nil
+}
+
+// RaftLogVerification returns the state of the Raft log verification of this
+// server. It's used by the leader to gather the state of all the servers.
+func (s *Status) RaftLogVerification(args EmptyReadRequest, reply *structs.RaftLogVerificationStatus) error {
+	*reply = s.server.logVerification.get()
+	reply.Enabled = s.server.config.LogStoreConfig.Verification.Enabled
 
 	return nil
 }
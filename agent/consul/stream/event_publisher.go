@@ -8,6 +8,8 @@ import (
 	"fmt"
 	"sync"
 	"time"
+
+	"github.com/armon/go-radix"
 )
 
 // EventPublisher receives change events from Publish, and sends the events to
@@ -46,6 +48,12 @@ type EventPublisher struct {
 	// wildcards contains map keys used to access the buffer for a topic's wildcard
 	// subject — it is used to track which topics support wildcard subscriptions.
 	wildcards map[Topic]topicSubject
+
+	// prefixSubjects contains the topics that support prefix subscriptions,
+	// mapped to a tree of the subjects that have a topic buffer, so that the
+	// subjects that are a prefix of an event's subject can be found without
+	// visiting the others. It is guarded by lock.
+	prefixSubjects map[string]*radix.Tree
 }
 
 // topicSubject is used as a map key when accessing topic buffers and cached
@@ -110,6 +118,7 @@ func NewEventPublisher(snapCacheTTL time.Duration) *EventPublisher {
 		},
 		snapshotHandlers: make(map[Topic]SnapshotFunc),
 		wildcards:        make(map[Topic]topicSubject),
+		prefixSubjects:   make(map[string]*radix.Tree),
 	}
 
 	return e
@@ -142,6 +151,20 @@ func (e *EventPublisher) RegisterHandler(topic Topic, handler SnapshotFunc, supp
 	return nil
 }
 
+// RegisterPrefixHandler registers a snapshot handler for a topic whose
+// subscriptions receive the events of every subject that starts with the
+// subject of the subscription, such as the keys under a prefix. The handler
+// must return a snapshot of all the subjects that start with the subject of
+// the request. Like RegisterHandler, it must be called before the event
+// publisher is Run.
+func (e *EventPublisher) RegisterPrefixHandler(topic Topic, handler SnapshotFunc) error {
+	if err := e.RegisterHandler(topic, handler, false); err != nil {
+		return err
+	}
+	e.prefixSubjects[topic.String()] = radix.New()
+	return nil
+}
+
 func (e *EventPublisher) RefreshTopic(topic Topic) error {
 	if _, found := e.snapshotHandlers[topic]; !found {
 		return fmt.Errorf("topic %s is not registered", topic)
@@ -207,6 +230,17 @@ func (e *EventPublisher) publishEvent(events []Event) {
 		// buffer too.
 		e.lock.Lock()
 		wildcard, ok := e.wildcards[event.Topic]
+		// If the topic supports prefix subscribers, copy the events to the buffers
+		// of the subjects that are a prefix of the event's subject too.
+		if subjects, ok := e.prefixSubjects[groupKey.Topic]; ok {
+			subjects.WalkPath(groupKey.Subject, func(subject string, _ interface{}) bool {
+				if subject != groupKey.Subject {
+					prefixKey := topicSubject{Topic: groupKey.Topic, Subject: subject}
+					groupedEvents[prefixKey] = append(groupedEvents[prefixKey], event)
+				}
+				return false
+			})
+		}
 		e.lock.Unlock()
 		if ok {
 			groupedEvents[wildcard] = append(groupedEvents[wildcard], event)
@@ -237,6 +271,10 @@ func (e *EventPublisher) bufferForSubscription(key topicSubject) *topicBuffer {
 			buf: newEventBuffer(),
 		}
 		e.topicBuffers[key] = buf
+
+		if subjects, ok := e.prefixSubjects[key.Topic]; ok {
+			subjects.Insert(key.Subject, nil)
+		}
 	}
 
 	return buf
@@ -297,6 +335,9 @@ func (e *EventPublisher) Subscribe(req *SubscribeRequest) (*Subscription, error)
 
 		if topicBuf.refs == 0 {
 			delete(e.topicBuffers, req.topicSubject())
+			if subjects, ok := e.prefixSubjects[req.Topic.String()]; ok {
+				subjects.Delete(req.Subject.String())
+			}
 
 			// Evict cached snapshot too because the topic buffer will have been spliced
 			// onto it. If we don't do this, any new subscribers started before the cache
@@ -700,3 +700,73 @@ func TestEventPublisher_SnapshotIndex0(t *testing.T) {
 	// Even though the snapshot handler returned 0, the subscriber shouldn't see it.
 	require.Equal(t, uint64(1), event.Index)
 }
+
+func TestEventPublisher_Subscribe_Prefix(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	publisher := NewEventPublisher(0)
+	go publisher.Run(ctx)
+
+	handler := func(SubscribeRequest, SnapshotAppender) (uint64, error) { return 1, nil }
+	require.NoError(t, publisher.RegisterPrefixHandler(testTopic, handler))
+
+	subscribe := func(subject string) <-chan eventOrErr {
+		sub, err := publisher.Subscribe(&SubscribeRequest{
+			Topic:   testTopic,
+			Subject: StringSubject(subject),
+		})
+		require.NoError(t, err)
+		t.Cleanup(sub.Unsubscribe)
+
+		eventCh := runSubscription(ctx, sub)
+		require.True(t, getNextEvent(t, eventCh).IsEndOfSnapshot(), "expected end of snapshot")
+		return eventCh
+	}
+	all := subscribe("")
+	prefix := subscribe("a/")
+	exact := subscribe("a/b")
+
+	ab := Event{
+		Topic:   testTopic,
+		Payload: simplePayload{key: "a/b", value: "1"},
+		Index:   2,
+	}
+	publisher.Publish([]Event{ab})
+	require.Equal(t, ab, getNextEvent(t, all))
+	require.Equal(t, ab, getNextEvent(t, prefix))
+	require.Equal(t, ab, getNextEvent(t, exact))
+
+	ac := Event{
+		Topic:   testTopic,
+		Payload: simplePayload{key: "a/c", value: "1"},
+		Index:   3,
+	}
+	publisher.Publish([]Event{ac})
+	require.Equal(t, ac, getNextEvent(t, all))
+	require.Equal(t, ac, getNextEvent(t, prefix))
+	assertNoResult(t, exact)
+
+	other := Event{
+		Topic:   testTopic,
+		Payload: simplePayload{key: "other", value: "1"},
+		Index:   4,
+	}
+	publisher.Publish([]Event{other})
+	require.Equal(t, other, getNextEvent(t, all))
+	assertNoResult(t, prefix)
+
+	// The subjects are matched as strings, not as paths.
+	partial := subscribe("oth")
+	publisher.Publish([]Event{other})
+	require.Equal(t, other, getNextEvent(t, partial))
+
+	// The subjects without subscribers are forgotten.
+	sub, err := publisher.Subscribe(&SubscribeRequest{Topic: testTopic, Subject: StringSubject("b/")})
+	require.NoError(t, err)
+	sub.Unsubscribe()
+	publisher.lock.Lock()
+	defer publisher.lock.Unlock()
+	_, found := publisher.prefixSubjects[testTopic.String()].Get("b/")
+	require.False(t, found)
+}
@@ -173,6 +173,16 @@ func (t *Txn) Apply(args *structs.TxnRequest, reply *structs.TxnResponse) error
 	} else {
 		return fmt.Errorf("unexpected return type %T", resp)
 	}
+
+	// Update the expiry timers of the entries that were written, unless the
+	// transaction was rolled back.
+	if len(reply.Errors) == 0 {
+		for _, op := range args.Ops {
+			if op.KV != nil {
+				t.srv.resetKVSTimerAfterApply(op.KV.Verb, &op.KV.DirEnt)
+			}
+		}
+	}
 	return nil
 }
 
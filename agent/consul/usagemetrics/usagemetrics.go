@@ -15,6 +15,7 @@ import (
 	"github.com/hashicorp/serf/serf"
 
 	"github.com/hashicorp/consul/agent/consul/state"
+	"github.com/hashicorp/consul/agent/structs"
 	"github.com/hashicorp/consul/logging"
 )
 
@@ -95,8 +96,24 @@ var Gauges = []prometheus.GaugeDefinition{
 		Name: []string{"state", "billable_service_instances"},
 		Help: "Total number of billable service instances in the local datacenter.",
 	},
+	{
+		Name: []string{"state", "kv_prefix_keys"},
+		Help: "Measures the current number of keys that a kv-prefix config entry applies to, labeled by name. It is only emitted by Consul servers.",
+	},
+	{
+		Name: []string{"state", "kv_prefix_bytes"},
+		Help: "Measures the current size in bytes of the keys and values that a kv-prefix config entry applies to, labeled by name. It is only emitted by Consul servers.",
+	},
+	{
+		Name: []string{"state", "kv_prefix_quota_utilization"},
+		Help: "Measures the fraction of the quota of a kv-prefix config entry that is used, labeled by name. It is only emitted by Consul servers for config entries with a quota.",
+	},
 }
 
+// kvQuotaWarningThreshold is the fraction of the quota of a kv-prefix config
+// entry above which a warning is logged.
+const kvQuotaWarningThreshold = 0.9
+
 type getMembersFunc func() []serf.Member
 
 // Config holds the settings for various parameters for the
@@ -156,6 +173,11 @@ type UsageMetricsReporter struct {
 	stateProvider  StateProvider
 	tickerInterval time.Duration
 	getMembersFunc getMembersFunc
+
+	// kvQuotaWarned tracks the kv-prefix config entries whose usage is above
+	// kvQuotaWarningThreshold, so that the warning is only logged when the
+	// usage crosses it.
+	kvQuotaWarned map[string]bool
 }
 
 func NewUsageMetricsReporter(cfg *Config) (*UsageMetricsReporter, error) {
@@ -182,6 +204,7 @@ func NewUsageMetricsReporter(cfg *Config) (*UsageMetricsReporter, error) {
 		metricLabels:   cfg.metricLabels,
 		tickerInterval: cfg.tickerInterval,
 		getMembersFunc: cfg.getMembersFunc,
+		kvQuotaWarned:  make(map[string]bool),
 	}
 
 	return u, nil
@@ -244,6 +267,49 @@ func (u *UsageMetricsReporter) runOnce() {
 	}
 
 	u.emitConfigEntryUsage(configUsage)
+
+	_, kvPrefixUsage, err := state.KVPrefixUsage(nil, structs.WildcardEnterpriseMetaInDefaultPartition())
+	if err != nil {
+		u.logger.Warn("failed to retrieve kv prefix usage from state store", "error", err)
+	}
+
+	u.emitKVPrefixUsage(kvPrefixUsage)
+}
+
+func (u *UsageMetricsReporter) emitKVPrefixUsage(usages []structs.KVPrefixUsage) {
+	for _, usage := range usages {
+		labels := append([]metrics.Label{{Name: "name", Value: usage.Name}}, u.metricLabels...)
+		metrics.SetGaugeWithLabels([]string{"state", "kv_prefix_keys"}, float32(usage.Keys), labels)
+		metrics.SetGaugeWithLabels([]string{"state", "kv_prefix_bytes"}, float32(usage.Bytes), labels)
+
+		if usage.MaxKeys == 0 && usage.MaxBytes == 0 {
+			continue
+		}
+		var utilization float64
+		if usage.MaxKeys > 0 {
+			utilization = float64(usage.Keys) / float64(usage.MaxKeys)
+		}
+		if usage.MaxBytes > 0 {
+			if b := float64(usage.Bytes) / float64(usage.MaxBytes); b > utilization {
+				utilization = b
+			}
+		}
+		metrics.SetGaugeWithLabels([]string{"state", "kv_prefix_quota_utilization"}, float32(utilization), labels)
+
+		id := usage.EnterpriseMeta.PartitionOrDefault() + "/" + usage.Name
+		above := utilization >= kvQuotaWarningThreshold
+		if above && !u.kvQuotaWarned[id] {
+			u.logger.Warn("kv-prefix config entry is close to its quota",
+				"name", usage.Name,
+				"prefix", usage.Prefix,
+				"keys", usage.Keys,
+				"max_keys", usage.MaxKeys,
+				"bytes", usage.Bytes,
+				"max_bytes", usage.MaxBytes,
+			)
+		}
+		u.kvQuotaWarned[id] = above
+	}
 }
 
 func (u *UsageMetricsReporter) memberUsage() []serf.Member {
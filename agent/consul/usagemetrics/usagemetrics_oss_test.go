@@ -376,6 +376,38 @@ var baseCases = map[string]testCase{
 					{Name: "kind", Value: "sameness-group"},
 				},
 			},
+			"consul.usage.test.consul.state.config_entries;datacenter=dc1;kind=kv-prefix": { // Legacy
+				Name:  "consul.usage.test.consul.state.config_entries",
+				Value: 0,
+				Labels: []metrics.Label{
+					{Name: "datacenter", Value: "dc1"},
+					{Name: "kind", Value: "kv-prefix"},
+				},
+			},
+			"consul.usage.test.state.config_entries;datacenter=dc1;kind=kv-prefix": {
+				Name:  "consul.usage.test.state.config_entries",
+				Value: 0,
+				Labels: []metrics.Label{
+					{Name: "datacenter", Value: "dc1"},
+					{Name: "kind", Value: "kv-prefix"},
+				},
+			},
+			"consul.usage.test.consul.state.config_entries;datacenter=dc1;kind=rpc-rate-limit": { // Legacy
+				Name:  "consul.usage.test.consul.state.config_entries",
+				Value: 0,
+				Labels: []metrics.Label{
+					{Name: "datacenter", Value: "dc1"},
+					{Name: "kind", Value: "rpc-rate-limit"},
+				},
+			},
+			"consul.usage.test.state.config_entries;datacenter=dc1;kind=rpc-rate-limit": {
+				Name:  "consul.usage.test.state.config_entries",
+				Value: 0,
+				Labels: []metrics.Label{
+					{Name: "datacenter", Value: "dc1"},
+					{Name: "kind", Value: "rpc-rate-limit"},
+				},
+			},
 			"consul.usage.test.consul.state.config_entries;datacenter=dc1;kind=api-gateway": { // Legacy
 				Name:  "consul.usage.test.consul.state.config_entries",
 				Value: 0,
@@ -835,6 +867,38 @@ var baseCases = map[string]testCase{
 					{Name: "kind", Value: "sameness-group"},
 				},
 			},
+			"consul.usage.test.consul.state.config_entries;datacenter=dc1;kind=kv-prefix": { // Legacy
+				Name:  "consul.usage.test.consul.state.config_entries",
+				Value: 0,
+				Labels: []metrics.Label{
+					{Name: "datacenter", Value: "dc1"},
+					{Name: "kind", Value: "kv-prefix"},
+				},
+			},
+			"consul.usage.test.state.config_entries;datacenter=dc1;kind=kv-prefix": {
+				Name:  "consul.usage.test.state.config_entries",
+				Value: 0,
+				Labels: []metrics.Label{
+					{Name: "datacenter", Value: "dc1"},
+					{Name: "kind", Value: "kv-prefix"},
+				},
+			},
+			"consul.usage.test.consul.state.config_entries;datacenter=dc1;kind=rpc-rate-limit": { // Legacy
+				Name:  "consul.usage.test.consul.state.config_entries",
+				Value: 0,
+				Labels: []metrics.Label{
+					{Name: "datacenter", Value: "dc1"},
+					{Name: "kind", Value: "rpc-rate-limit"},
+				},
+			},
+			"consul.usage.test.state.config_entries;datacenter=dc1;kind=rpc-rate-limit": {
+				Name:  "consul.usage.test.state.config_entries",
+				Value: 0,
+				Labels: []metrics.Label{
+					{Name: "datacenter", Value: "dc1"},
+					{Name: "kind", Value: "rpc-rate-limit"},
+				},
+			},
 			"consul.usage.test.consul.state.config_entries;datacenter=dc1;kind=api-gateway": { // Legacy
 				Name:  "consul.usage.test.consul.state.config_entries",
 				Value: 0,
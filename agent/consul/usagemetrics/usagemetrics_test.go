@@ -5,12 +5,17 @@ package usagemetrics
 
 import (
 	"testing"
+	"time"
 
 	"github.com/armon/go-metrics"
+	"github.com/hashicorp/serf/serf"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 
 	"github.com/hashicorp/consul/agent/consul/state"
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/sdk/testutil"
 )
 
 type mockStateProvider struct {
@@ -39,3 +44,67 @@ func assertEqualGaugeMaps(t *testing.T, expectedMap, foundMap map[string]metrics
 		assert.Equal(t, expected, foundMap[key], "gauge key mismatch on %q", key)
 	}
 }
+
+func TestUsageReporter_emitKVPrefixUsage(t *testing.T) {
+	sink := metrics.NewInmemSink(1*time.Minute, 1*time.Minute)
+	cfg := metrics.DefaultConfig("consul.usage.test")
+	cfg.EnableHostname = false
+	metrics.NewGlobal(cfg, sink)
+
+	s := state.NewStateStore(nil)
+	require.NoError(t, s.EnsureConfigEntry(1, &structs.KVPrefixConfigEntry{
+		Name:   "config",
+		Prefix: "config/",
+		Quota:  &structs.KVQuotaConfig{MaxKeys: 10, MaxBytes: 100},
+	}))
+	require.NoError(t, s.EnsureConfigEntry(2, &structs.KVPrefixConfigEntry{
+		Name:   "other",
+		Prefix: "other/",
+	}))
+	require.NoError(t, s.KVSSet(3, &structs.DirEntry{Key: "config/a", Value: []byte("12")}))
+
+	mockStateProvider := &mockStateProvider{}
+	mockStateProvider.On("State").Return(s)
+	reporter, err := NewUsageMetricsReporter(
+		new(Config).
+			WithStateProvider(mockStateProvider).
+			WithLogger(testutil.Logger(t)).
+			WithDatacenter("dc1").
+			WithGetMembersFunc(func() []serf.Member { return nil }),
+	)
+	require.NoError(t, err)
+
+	reporter.runOnce()
+
+	labels := func(name string) []metrics.Label {
+		return []metrics.Label{{Name: "name", Value: name}, {Name: "datacenter", Value: "dc1"}}
+	}
+	gauges := sink.Data()[0].Gauges
+	require.Equal(t, metrics.GaugeValue{
+		Name:   "consul.usage.test.state.kv_prefix_keys",
+		Value:  1,
+		Labels: labels("config"),
+	}, gauges["consul.usage.test.state.kv_prefix_keys;name=config;datacenter=dc1"])
+	require.Equal(t, metrics.GaugeValue{
+		Name:   "consul.usage.test.state.kv_prefix_bytes",
+		Value:  10,
+		Labels: labels("config"),
+	}, gauges["consul.usage.test.state.kv_prefix_bytes;name=config;datacenter=dc1"])
+	require.Equal(t, metrics.GaugeValue{
+		Name:   "consul.usage.test.state.kv_prefix_quota_utilization",
+		Value:  0.1,
+		Labels: labels("config"),
+	}, gauges["consul.usage.test.state.kv_prefix_quota_utilization;name=config;datacenter=dc1"])
+	require.Contains(t, gauges, "consul.usage.test.state.kv_prefix_keys;name=other;datacenter=dc1")
+	require.NotContains(t, gauges, "consul.usage.test.state.kv_prefix_quota_utilization;name=other;datacenter=dc1")
+	require.False(t, reporter.kvQuotaWarned["default/config"])
+
+	// The warning is logged once the usage crosses the threshold.
+	require.NoError(t, s.KVSSet(4, &structs.DirEntry{Key: "config/b", Value: make([]byte, 80)}))
+	reporter.runOnce()
+	require.True(t, reporter.kvQuotaWarned["default/config"])
+
+	require.NoError(t, s.KVSDelete(5, "config/b", nil))
+	reporter.runOnce()
+	require.False(t, reporter.kvQuotaWarned["default/config"])
+}
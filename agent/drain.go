@@ -0,0 +1,283 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package agent
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/api"
+)
+
+const (
+	// defaultDrainTimeout is how long a drain waits for the proxies' active
+	// connections to drop when no timeout is given.
+	defaultDrainTimeout = 5 * time.Minute
+
+	// drainPollInterval is how often the proxies' stats are polled.
+	drainPollInterval = time.Second
+
+	// envoyStatsTimeout bounds a single request to an Envoy stats listener.
+	envoyStatsTimeout = 5 * time.Second
+
+	// defaultDrainThreshold is the number of active connections below which
+	// a proxy is drained when no threshold is given, i.e. none.
+	defaultDrainThreshold = 1
+
+	// envoyDefaultAdminAddr is the default address of the Envoy admin
+	// listener, see the -admin-bind flag of consul connect envoy. It's used
+	// to read the stats of proxies that don't set envoy_stats_bind_addr.
+	envoyDefaultAdminAddr = "127.0.0.1:19000"
+
+	defaultNodeDrainReason = "The node is being drained, " +
+		"but no reason was provided. This is a default message."
+)
+
+// envoyListenerActiveRe matches the active downstream connections stats of
+// Envoy listeners, which are named after the listener's address.
+var envoyListenerActiveRe = regexp.MustCompile(`^listener\..*_(\d+)\.downstream_cx_active$`)
+
+// drainOpts configures DrainNode.
+type drainOpts struct {
+	reason    string
+	token     string
+	timeout   time.Duration
+	threshold int
+}
+
+// drainProxyConfig holds the keys of a proxy's opaque config that are needed
+// to read its active connections.
+type drainProxyConfig struct {
+	// StatsBindAddr is the address of the listener that exposes the Envoy
+	// admin /stats endpoint, see command/connect/envoy/bootstrap_config.go.
+	StatsBindAddr string `mapstructure:"envoy_stats_bind_addr"`
+
+	// BindPort overrides the port of the public listener.
+	BindPort int `mapstructure:"bind_port"`
+}
+
+// drainProxy tracks the drain of a single sidecar proxy.
+type drainProxy struct {
+	sid       structs.ServiceID
+	statsAddr string
+	port      int
+	status    api.AgentDrainProxy
+}
+
+// drainableProxies returns the local sidecar proxies that DrainNode will
+// drain and deregister.
+func (a *Agent) drainableProxies() []*structs.NodeService {
+	var proxies []*structs.NodeService
+	for _, svc := range a.State.AllServices() {
+		if svc.Kind == structs.ServiceKindConnectProxy {
+			proxies = append(proxies, svc)
+		}
+	}
+	return proxies
+}
+
+// DrainNode places the node in maintenance mode so that no new traffic is
+// routed to it, waits for the active inbound connections of the given sidecar
+// proxies to drop below the threshold, or for the timeout, and then
+// deregisters the proxies. The outcome is recorded in the output of the node
+// maintenance check so that it is visible in the catalog.
+//
+// The stats of a proxy are read from the listener set with
+// envoy_stats_bind_addr, or from the default Envoy admin listener otherwise.
+// A proxy whose stats can't be read is not considered drained, so the drain
+// waits for the timeout. If ctx is cancelled the node is left in maintenance
+// mode but the proxies are not deregistered.
+func (a *Agent) DrainNode(ctx context.Context, services []*structs.NodeService, opts drainOpts) (*api.AgentDrainResult, error) {
+	if opts.reason == "" {
+		opts.reason = defaultNodeDrainReason
+	}
+	if opts.timeout <= 0 {
+		opts.timeout = defaultDrainTimeout
+	}
+	if opts.threshold <= 0 {
+		opts.threshold = defaultDrainThreshold
+	}
+
+	a.EnableNodeMaintenance(opts.reason, opts.token)
+	a.State.UpdateCheck(structs.NodeMaintCheckID, api.HealthCritical,
+		fmt.Sprintf("Draining %d sidecar proxies", len(services)))
+	a.syncDrainChanges()
+	a.logger.Info("Draining node", "proxies", len(services), "timeout", opts.timeout)
+
+	proxies := make([]*drainProxy, 0, len(services))
+	for _, svc := range services {
+		p := &drainProxy{
+			sid:    svc.CompoundServiceID(),
+			port:   svc.Port,
+			status: api.AgentDrainProxy{ServiceID: svc.ID},
+		}
+		var cfg drainProxyConfig
+		if svc.Proxy.Config != nil {
+			if err := mapstructure.WeakDecode(svc.Proxy.Config, &cfg); err != nil {
+				p.status.Error = fmt.Sprintf("failed to parse proxy config: %s", err)
+			}
+		}
+		if cfg.BindPort != 0 {
+			p.port = cfg.BindPort
+		}
+		p.statsAddr = envoyDefaultAdminAddr
+		if cfg.StatsBindAddr != "" {
+			p.statsAddr = envoyStatsDialAddr(cfg.StatsBindAddr)
+		}
+		proxies = append(proxies, p)
+	}
+
+	timeout := time.NewTimer(opts.timeout)
+	defer timeout.Stop()
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+
+WAIT:
+	for !pollDrainProxies(ctx, proxies, opts.threshold) {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-a.shutdownCh:
+			return nil, fmt.Errorf("agent is shutting down")
+		case <-timeout.C:
+			a.logger.Warn("Timed out waiting for proxies to drain")
+			break WAIT
+		case <-ticker.C:
+		}
+	}
+
+	result := &api.AgentDrainResult{Drained: true}
+	var active int
+	for _, p := range proxies {
+		if err := a.RemoveService(p.sid); err != nil {
+			p.status.Error = fmt.Sprintf("failed to deregister: %s", err)
+		} else {
+			p.status.Deregistered = true
+		}
+		if !p.status.Drained {
+			result.Drained = false
+		}
+		active += p.status.ActiveConnections
+		result.Proxies = append(result.Proxies, p.status)
+	}
+
+	output := fmt.Sprintf("Node drained, %d sidecar proxies deregistered", len(proxies))
+	if !result.Drained {
+		output = fmt.Sprintf("Node drain timed out with %d active connections, %d sidecar proxies deregistered",
+			active, len(proxies))
+	}
+	a.State.UpdateCheck(structs.NodeMaintCheckID, api.HealthCritical, output)
+	a.syncDrainChanges()
+	a.logger.Info("Node drain finished", "drained", result.Drained, "active_connections", active)
+
+	return result, nil
+}
+
+// pollDrainProxies updates the active connections of the proxies that are not
+// drained yet and returns whether all of them are drained.
+func pollDrainProxies(ctx context.Context, proxies []*drainProxy, threshold int) bool {
+	drained := true
+	for _, p := range proxies {
+		if p.status.Drained {
+			continue
+		}
+		n, err := envoyActiveConnections(ctx, p.statsAddr, p.port)
+		if err != nil {
+			p.status.Error = err.Error()
+			drained = false
+			continue
+		}
+		p.status.Error = ""
+		p.status.ActiveConnections = n
+		if n < threshold {
+			p.status.Drained = true
+			continue
+		}
+		drained = false
+	}
+	return drained
+}
+
+func (a *Agent) syncDrainChanges() {
+	if err := a.State.SyncChanges(); err != nil {
+		a.logger.Error("failed to sync changes", "error", err)
+	}
+}
+
+// envoyStatsDialAddr returns the address to reach a stats listener bound to
+// addr, replacing a wildcard host with the loopback address.
+func envoyStatsDialAddr(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "127.0.0.1"
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// envoyActiveConnections returns the number of active downstream connections
+// of the proxy's public listener, read from the Envoy stats at addr. Envoy
+// names listener stats after the listener's address, so the public listener
+// is identified by its port. It's an error if there are no stats for the
+// public listener, e.g. because addr belongs to another proxy.
+func envoyActiveConnections(ctx context.Context, addr string, port int) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, envoyStatsTimeout)
+	defer cancel()
+
+	pattern := fmt.Sprintf(`^listener\..*_%d\.downstream_cx_active$`, port)
+	u := fmt.Sprintf("http://%s/stats?filter=%s", addr, url.QueryEscape(pattern))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read Envoy stats: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("failed to read Envoy stats: unexpected response code %d", resp.StatusCode)
+	}
+
+	// Filter again in case the stats listener ignores the filter.
+	var total int
+	var found bool
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		name, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		m := envoyListenerActiveRe.FindStringSubmatch(strings.TrimSpace(name))
+		if m == nil || m[1] != strconv.Itoa(port) {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return 0, fmt.Errorf("invalid value for Envoy stat %q: %w", name, err)
+		}
+		total += n
+		found = true
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read Envoy stats: %w", err)
+	}
+	if !found {
+		return 0, fmt.Errorf("no Envoy stats found for the public listener on port %d", port)
+	}
+	return total, nil
+}
@@ -0,0 +1,227 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package agent
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/testrpc"
+)
+
+// testEnvoyStats returns a server that serves Envoy stats for a proxy whose
+// public listener is on port 21000 with the active connections returned by
+// active.
+func testEnvoyStats(t *testing.T, active func() int) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/stats", r.URL.Path)
+		// The stats listener itself must not be counted.
+		fmt.Fprintf(w, "listener.127.0.0.1_9102.downstream_cx_active: 1\n")
+		fmt.Fprintf(w, "listener.0.0.0.0_21000.downstream_cx_active: %d\n", active())
+		fmt.Fprintf(w, "listener.0.0.0.0_21000.worker_0.downstream_cx_active: %d\n", active())
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func testDrainProxy(t *testing.T, a *TestAgent, id string, config map[string]interface{}) *structs.NodeService {
+	svc := &structs.NodeService{
+		Kind:    structs.ServiceKindConnectProxy,
+		ID:      id,
+		Service: "web-sidecar-proxy",
+		Port:    21000,
+		Proxy: structs.ConnectProxyConfig{
+			DestinationServiceName: "web",
+			DestinationServiceID:   "web",
+			Config:                 config,
+		},
+	}
+	require.NoError(t, a.addServiceFromSource(svc, nil, false, "", ConfigSourceLocal))
+	return svc
+}
+
+func TestAgent_DrainNode(t *testing.T) {
+	if testing.Short() {
+		t.Skip("too slow for testing.Short")
+	}
+
+	t.Parallel()
+	a := NewTestAgent(t, "")
+	defer a.Shutdown()
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+	var active int32 = 3
+	stats := testEnvoyStats(t, func() int {
+		// Connections close one at a time.
+		n := atomic.AddInt32(&active, -1)
+		if n < 0 {
+			n = 0
+		}
+		return int(n)
+	})
+
+	testDrainProxy(t, a, "web-sidecar-proxy", map[string]interface{}{
+		"envoy_stats_bind_addr": strings.TrimPrefix(stats.URL, "http://"),
+	})
+	idle := testEnvoyStats(t, func() int { return 0 })
+	testDrainProxy(t, a, "web-sidecar-proxy-2", map[string]interface{}{
+		"envoy_stats_bind_addr": strings.TrimPrefix(idle.URL, "http://"),
+	})
+
+	result, err := a.DrainNode(context.Background(), a.drainableProxies(), drainOpts{
+		reason:  "upgrade",
+		timeout: 10 * time.Second,
+	})
+	require.NoError(t, err)
+	require.True(t, result.Drained)
+	require.ElementsMatch(t, []api.AgentDrainProxy{
+		{ServiceID: "web-sidecar-proxy", Drained: true, Deregistered: true},
+		{ServiceID: "web-sidecar-proxy-2", Drained: true, Deregistered: true},
+	}, result.Proxies)
+
+	require.Nil(t, a.State.Service(structs.NewServiceID("web-sidecar-proxy", nil)))
+	require.Nil(t, a.State.Service(structs.NewServiceID("web-sidecar-proxy-2", nil)))
+
+	check := a.State.Check(structs.NodeMaintCheckID)
+	require.NotNil(t, check)
+	require.Equal(t, api.HealthCritical, check.Status)
+	require.Equal(t, "upgrade", check.Notes)
+	require.Equal(t, "Node drained, 2 sidecar proxies deregistered", check.Output)
+}
+
+func TestAgent_DrainNode_Timeout(t *testing.T) {
+	if testing.Short() {
+		t.Skip("too slow for testing.Short")
+	}
+
+	t.Parallel()
+	a := NewTestAgent(t, "")
+	defer a.Shutdown()
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+	stats := testEnvoyStats(t, func() int { return 5 })
+	testDrainProxy(t, a, "web-sidecar-proxy", map[string]interface{}{
+		"envoy_stats_bind_addr": strings.TrimPrefix(stats.URL, "http://"),
+	})
+
+	// The active connections must drop below the threshold.
+	result, err := a.DrainNode(context.Background(), a.drainableProxies(), drainOpts{
+		timeout:   1500 * time.Millisecond,
+		threshold: 5,
+	})
+	require.NoError(t, err)
+	require.False(t, result.Drained)
+	require.Equal(t, []api.AgentDrainProxy{
+		{ServiceID: "web-sidecar-proxy", ActiveConnections: 5, Deregistered: true},
+	}, result.Proxies)
+	require.Nil(t, a.State.Service(structs.NewServiceID("web-sidecar-proxy", nil)))
+
+	check := a.State.Check(structs.NodeMaintCheckID)
+	require.NotNil(t, check)
+	require.Equal(t, defaultNodeDrainReason, check.Notes)
+	require.Equal(t, "Node drain timed out with 5 active connections, 1 sidecar proxies deregistered", check.Output)
+}
+
+func TestAgent_DrainNode_Unverifiable(t *testing.T) {
+	if testing.Short() {
+		t.Skip("too slow for testing.Short")
+	}
+
+	t.Parallel()
+	a := NewTestAgent(t, "")
+	defer a.Shutdown()
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+	// The stats of another proxy don't tell whether this one is drained.
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "listener.0.0.0.0_21001.downstream_cx_active: 0\n")
+	}))
+	defer other.Close()
+	testDrainProxy(t, a, "web-sidecar-proxy", map[string]interface{}{
+		"envoy_stats_bind_addr": strings.TrimPrefix(other.URL, "http://"),
+	})
+
+	result, err := a.DrainNode(context.Background(), a.drainableProxies(), drainOpts{
+		timeout: 1500 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	require.False(t, result.Drained)
+	require.Equal(t, []api.AgentDrainProxy{
+		{
+			ServiceID:    "web-sidecar-proxy",
+			Deregistered: true,
+			Error:        "no Envoy stats found for the public listener on port 21000",
+		},
+	}, result.Proxies)
+}
+
+func TestAgent_DrainNode_HTTP(t *testing.T) {
+	if testing.Short() {
+		t.Skip("too slow for testing.Short")
+	}
+
+	t.Parallel()
+	a := NewTestAgent(t, "")
+	defer a.Shutdown()
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+	stats := testEnvoyStats(t, func() int { return 0 })
+	testDrainProxy(t, a, "web-sidecar-proxy", map[string]interface{}{
+		"envoy_stats_bind_addr": strings.TrimPrefix(stats.URL, "http://"),
+	})
+
+	t.Run("invalid timeout", func(t *testing.T) {
+		req, _ := http.NewRequest("PUT", "/v1/agent/drain?timeout=soon", nil)
+		resp := httptest.NewRecorder()
+		a.srv.h.ServeHTTP(resp, req)
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+	})
+
+	t.Run("invalid threshold", func(t *testing.T) {
+		req, _ := http.NewRequest("PUT", "/v1/agent/drain?threshold=0", nil)
+		resp := httptest.NewRecorder()
+		a.srv.h.ServeHTTP(resp, req)
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+	})
+
+	t.Run("drain", func(t *testing.T) {
+		req, _ := http.NewRequest("PUT", "/v1/agent/drain?reason=broken&timeout=5s", nil)
+		resp := httptest.NewRecorder()
+		a.srv.h.ServeHTTP(resp, req)
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Contains(t, resp.Body.String(), `"Drained":true`)
+
+		check := a.State.Check(structs.NodeMaintCheckID)
+		require.NotNil(t, check)
+		require.Equal(t, "broken", check.Notes)
+		require.Nil(t, a.State.Service(structs.NewServiceID("web-sidecar-proxy", nil)))
+	})
+}
+
+func TestAgent_DrainNode_ACLDeny(t *testing.T) {
+	if testing.Short() {
+		t.Skip("too slow for testing.Short")
+	}
+
+	t.Parallel()
+	a := NewTestAgent(t, TestACLConfig())
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	req, _ := http.NewRequest("PUT", "/v1/agent/drain", nil)
+	resp := httptest.NewRecorder()
+	a.srv.h.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusForbidden, resp.Code)
+	require.Nil(t, a.State.Check(structs.NodeMaintCheckID))
+}
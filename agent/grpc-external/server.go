@@ -36,6 +36,7 @@ func NewServer(logger agentmiddleware.Logger, metricsObj *metrics.Metrics, tls *
 	unaryInterceptors := []grpc.UnaryServerInterceptor{
 		// Add middlware interceptors to recover in case of panics.
 		recovery.UnaryServerInterceptor(recoveryOpts...),
+		agentmiddleware.ServerConcurrencyLimiterInterceptor(limiter),
 	}
 	streamInterceptors := []grpc.StreamServerInterceptor{
 		// Add middlware interceptors to recover in case of panics.
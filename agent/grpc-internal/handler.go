@@ -45,6 +45,7 @@ func NewHandler(logger Logger, addr net.Addr, register func(server *grpc.Server)
 		middleware.WithUnaryServerChain(
 			// Add middlware interceptors to recover in case of panics.
 			recovery.UnaryServerInterceptor(recoveryOpts...),
+			agentmiddleware.ServerConcurrencyLimiterInterceptor(rateLimiter),
 		),
 		middleware.WithStreamServerChain(
 			// Add middlware interceptors to recover in case of panics.
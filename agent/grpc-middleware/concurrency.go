@@ -0,0 +1,68 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package middleware
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+
+	"github.com/hashicorp/consul/agent/consul/rate"
+)
+
+// ServerConcurrencyLimiterInterceptor implements a unary interceptor counting
+// the RPCs against the adaptive concurrency limits of the server. Unlike
+// ServerRateLimiterMiddleware it needs to know when the RPC completes, so it
+// can't be a tap handle. Streaming RPCs are long-lived, their latency says
+// nothing about the load of the server so they aren't counted.
+//
+// Like for net/rpc, the priority of a read is derived from the query options
+// of the request message. The requests that don't carry them, which is most
+// of them, are non-blocking reads that must be consistent, so they have the
+// normal priority.
+func ServerConcurrencyLimiterInterceptor(limiter rate.RequestLimitsHandler) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		operationSpec, ok := rpcRateLimitSpecs[info.FullMethod]
+		if !ok {
+			// ServerRateLimiterMiddleware already warned about it.
+			return handler(ctx, req)
+		}
+
+		op := rate.Operation{
+			Name:     info.FullMethod,
+			Type:     operationSpec.Type,
+			Category: operationSpec.Category,
+		}
+		if p, ok := peer.FromContext(ctx); ok {
+			op.SourceAddr = p.Addr
+		}
+		if q, ok := req.(blockingQuery); ok {
+			op.Blocking = q.GetMinQueryIndex() > 0
+		}
+		if q, ok := req.(staleRead); ok {
+			op.AllowStale = q.AllowStaleRead()
+		}
+
+		release, err := limiter.AcquireConcurrency(op)
+		if err != nil {
+			return nil, rateLimitStatus(err)
+		}
+		defer release()
+
+		return handler(ctx, req)
+	}
+}
+
+// blockingQuery and staleRead are implemented by the request messages that
+// carry query options, see the readQuery interface of the net/rpc
+// interceptors. They are checked separately since most gRPC requests only
+// implement staleRead.
+type blockingQuery interface {
+	GetMinQueryIndex() uint64
+}
+
+type staleRead interface {
+	AllowStaleRead() bool
+}
@@ -0,0 +1,91 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package middleware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/hashicorp/consul/agent/consul/rate"
+	"github.com/hashicorp/consul/proto/private/pbpeering"
+)
+
+func TestServerConcurrencyLimiterInterceptor(t *testing.T) {
+	limiter := rate.NewMockRequestLimitsHandler(t)
+	interceptor := ServerConcurrencyLimiterInterceptor(limiter)
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/hashicorp.consul.acl.ACLService/Login"}
+
+	t.Run("released once the handler returns", func(t *testing.T) {
+		var released, handled bool
+		limiter.On("AcquireConcurrency", mock.MatchedBy(func(op rate.Operation) bool {
+			return op.Name == info.FullMethod && op.Type == rate.OperationTypeWrite
+		})).
+			Return(func() { released = true }, nil).
+			Once()
+
+		_, err := interceptor(context.Background(), nil, info, func(context.Context, interface{}) (interface{}, error) {
+			require.False(t, released)
+			handled = true
+			return nil, nil
+		})
+		require.NoError(t, err)
+		require.True(t, handled)
+		require.True(t, released)
+	})
+
+	t.Run("priority from the query options", func(t *testing.T) {
+		readInfo := &grpc.UnaryServerInfo{FullMethod: "/hashicorp.consul.internal.peering.PeeringService/PeeringRead"}
+		for _, req := range []interface{}{
+			nil,
+			&pbpeering.PeeringReadRequest{},
+			testQueryOptions{minQueryIndex: 5, allowStale: true},
+		} {
+			q, isQuery := req.(testQueryOptions)
+			limiter.On("AcquireConcurrency", mock.MatchedBy(func(op rate.Operation) bool {
+				return op.Type == rate.OperationTypeRead && op.Blocking == isQuery && op.AllowStale == q.allowStale
+			})).
+				Return(func() {}, nil).
+				Once()
+
+			_, err := interceptor(context.Background(), req, readInfo, func(context.Context, interface{}) (interface{}, error) {
+				return nil, nil
+			})
+			require.NoError(t, err)
+		}
+	})
+
+	t.Run("limit exceeded", func(t *testing.T) {
+		limiter.On("AcquireConcurrency", mock.Anything).
+			Return(nil, rate.ErrRetryElsewhere).
+			Once()
+
+		_, err := interceptor(context.Background(), nil, info, func(context.Context, interface{}) (interface{}, error) {
+			t.Fatal("handler should not be called")
+			return nil, nil
+		})
+		require.Equal(t, codes.ResourceExhausted.String(), status.Code(err).String())
+	})
+
+	t.Run("unknown method", func(t *testing.T) {
+		_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/foo.Bar/Baz"}, func(context.Context, interface{}) (interface{}, error) {
+			return nil, nil
+		})
+		require.NoError(t, err)
+	})
+}
+
+type testQueryOptions struct {
+	minQueryIndex uint64
+	allowStale    bool
+}
+
+func (q testQueryOptions) GetMinQueryIndex() uint64 { return q.minQueryIndex }
+func (q testQueryOptions) AllowStaleRead() bool     { return q.allowStale }
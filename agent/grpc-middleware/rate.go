@@ -8,6 +8,7 @@ import (
 	"errors"
 
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
 	"google.golang.org/grpc/tap"
@@ -47,22 +48,38 @@ func ServerRateLimiterMiddleware(limiter rate.RequestLimitsHandler, panicHandler
 			return ctx, nil
 		}
 
-		err := limiter.Allow(rate.Operation{
+		op := rate.Operation{
 			Name:       info.FullMethodName,
 			SourceAddr: peer.Addr,
 			Type:       operationSpec.Type,
 			Category:   operationSpec.Category,
-		})
-
-		switch {
-		case err == nil:
-			return ctx, nil
-		case errors.Is(err, rate.ErrRetryElsewhere):
-			return ctx, status.Error(codes.ResourceExhausted, err.Error())
-		case errors.Is(err, rate.ErrRetryLater):
-			return ctx, status.Error(codes.Unavailable, err.Error())
-		default:
-			return ctx, status.Error(codes.Internal, err.Error())
 		}
+		err := limiter.Allow(op)
+		if err == nil {
+			// Unlike net/rpc, the token is sent in the metadata so the limits that
+			// depend on it can be checked right away.
+			if md, ok := metadata.FromIncomingContext(ctx); ok {
+				if vals := md.Get("x-consul-token"); len(vals) > 0 {
+					op.Token = vals[0]
+				}
+			}
+			err = limiter.AllowToken(op)
+		}
+
+		return ctx, rateLimitStatus(err)
+	}
+}
+
+// rateLimitStatus converts an error of the rate limit handler to a gRPC status.
+func rateLimitStatus(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, rate.ErrRetryElsewhere):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, rate.ErrRetryLater):
+		return status.Error(codes.Unavailable, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
 	}
 }
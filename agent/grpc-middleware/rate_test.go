@@ -14,6 +14,7 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 
 	pbacl "github.com/hashicorp/consul/proto-public/pbacl"
@@ -98,11 +99,33 @@ func TestServerRateLimiterMiddleware_Integration(t *testing.T) {
 		limiter.On("Allow", mock.Anything).
 			Return(nil).
 			Once()
+		limiter.On("AllowToken", mock.Anything).
+			Return(nil).
+			Once()
 
 		_, err = client.Login(ctx, &pbacl.LoginRequest{})
 		require.NoError(t, err)
 	})
 
+	t.Run("AllowToken gets the token from the metadata", func(t *testing.T) {
+		limiter.On("Allow", mock.Anything).
+			Return(nil).
+			Once()
+		limiter.On("AllowToken", mock.Anything).
+			Run(func(args mock.Arguments) {
+				op := args.Get(0).(rate.Operation)
+				require.Equal(t, "/hashicorp.consul.acl.ACLService/Login", op.Name)
+				require.Equal(t, "secret", op.Token)
+			}).
+			Return(rate.ErrRetryElsewhere).
+			Once()
+
+		ctx := metadata.AppendToOutgoingContext(ctx, "x-consul-token", "secret")
+		_, err = client.Login(ctx, &pbacl.LoginRequest{})
+		require.Error(t, err)
+		require.Equal(t, codes.ResourceExhausted.String(), status.Code(err).String())
+	})
+
 	t.Run("Allow panics", func(t *testing.T) {
 		limiter.On("Allow", mock.Anything).
 			Panic("uh oh").
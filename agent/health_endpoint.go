@@ -245,28 +245,33 @@ func (s *HTTPHandlers) healthServiceNodes(resp http.ResponseWriter, req *http.Re
 	// Translate addresses after filtering so we don't waste effort.
 	s.agent.TranslateAddresses(args.Datacenter, out.Nodes, TranslateAddressAcceptAny)
 
-	// Use empty list instead of nil
-	if out.Nodes == nil {
-		out.Nodes = make(structs.CheckServiceNodes, 0)
+	return emptyListsForNil(out.Nodes), nil
+}
+
+// emptyListsForNil replaces the nil lists of the given nodes with empty ones,
+// so they are encoded as empty lists rather than null.
+func emptyListsForNil(nodes structs.CheckServiceNodes) structs.CheckServiceNodes {
+	if nodes == nil {
+		nodes = make(structs.CheckServiceNodes, 0)
 	}
-	for i := range out.Nodes {
-		if out.Nodes[i].Checks == nil {
-			out.Nodes[i].Checks = make(structs.HealthChecks, 0)
+	for i := range nodes {
+		if nodes[i].Checks == nil {
+			nodes[i].Checks = make(structs.HealthChecks, 0)
 		}
-		for j, c := range out.Nodes[i].Checks {
+		for j, c := range nodes[i].Checks {
 			if c.ServiceTags == nil {
 				clone := *c
 				clone.ServiceTags = make([]string, 0)
-				out.Nodes[i].Checks[j] = &clone
+				nodes[i].Checks[j] = &clone
 			}
 		}
-		if out.Nodes[i].Service != nil && out.Nodes[i].Service.Tags == nil {
-			clone := *out.Nodes[i].Service
+		if nodes[i].Service != nil && nodes[i].Service.Tags == nil {
+			clone := *nodes[i].Service
 			clone.Tags = make([]string, 0)
-			out.Nodes[i].Service = &clone
+			nodes[i].Service = &clone
 		}
 	}
-	return out.Nodes, nil
+	return nodes
 }
 
 func getBoolQueryParam(params url.Values, key string) (bool, error) {
@@ -195,7 +195,7 @@ func (s *HTTPHandlers) handler(enableDebug bool) http.Handler {
 
 		var gzipHandler http.Handler
 		minSize := gziphandler.DefaultMinSize
-		if pattern == "/v1/agent/monitor" || pattern == "/v1/agent/metrics/stream" {
+		if pattern == "/v1/agent/monitor" || pattern == "/v1/agent/metrics/stream" || pattern == "/v1/stream" {
 			minSize = 0
 		}
 		gzipWrapper, err := gziphandler.GzipHandlerWithOpts(gziphandler.MinSize(minSize))
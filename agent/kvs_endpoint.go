@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/consul/agent/structs"
 	"github.com/hashicorp/consul/api"
@@ -38,6 +39,9 @@ func (s *HTTPHandlers) KVSEndpoint(resp http.ResponseWriter, req *http.Request)
 		if keyList {
 			return s.KVSGetKeys(resp, req, &args)
 		}
+		if _, ok := params["revisions"]; ok {
+			return s.KVSGetRevisions(resp, req, &args)
+		}
 		return s.KVSGet(resp, req, &args)
 	case "PUT":
 		return s.KVSPut(resp, req, &args)
@@ -59,6 +63,18 @@ func (s *HTTPHandlers) KVSGet(resp http.ResponseWriter, req *http.Request, args
 		return nil, HTTPError{StatusCode: http.StatusBadRequest, Reason: "Missing key name"}
 	}
 
+	// Check for a point-in-time read
+	if v := params.Get("at-index"); v != "" {
+		if method != "KVS.Get" {
+			return nil, HTTPError{StatusCode: http.StatusBadRequest, Reason: "at-index cannot be used with recurse"}
+		}
+		atIndex, err := strconv.ParseUint(v, 10, 64)
+		if err != nil || atIndex == 0 {
+			return nil, HTTPError{StatusCode: http.StatusBadRequest, Reason: fmt.Sprintf("Invalid at-index %q", v)}
+		}
+		args.AtIndex = atIndex
+	}
+
 	// Do not allow wildcard NS on GET reqs
 	if method == "KVS.Get" {
 		if err := s.parseEntMetaNoWildcard(req, &args.EnterpriseMeta); err != nil {
@@ -70,9 +86,14 @@ func (s *HTTPHandlers) KVSGet(resp http.ResponseWriter, req *http.Request, args
 		}
 	}
 
-	// Make the RPC
+	// Make the RPC, recursive blocking queries may be served by streaming
 	var out structs.IndexedDirEntries
-	if err := s.agent.RPC(req.Context(), method, args, &out); err != nil {
+	if method == "KVS.List" {
+		var err error
+		if out, _, err = s.agent.rpcClientKV.List(req.Context(), args); err != nil {
+			return nil, err
+		}
+	} else if err := s.agent.RPC(req.Context(), method, args, &out); err != nil {
 		return nil, err
 	}
 	setMeta(resp, &out.QueryMeta)
@@ -104,6 +125,28 @@ func (s *HTTPHandlers) KVSGet(resp http.ResponseWriter, req *http.Request, args
 	return out.Entries, nil
 }
 
+// KVSGetRevisions handles a GET request for the revisions of a key
+func (s *HTTPHandlers) KVSGetRevisions(resp http.ResponseWriter, req *http.Request, args *structs.KeyRequest) (interface{}, error) {
+	if args.Key == "" {
+		return nil, HTTPError{StatusCode: http.StatusBadRequest, Reason: "Missing key name"}
+	}
+	if err := s.parseEntMetaNoWildcard(req, &args.EnterpriseMeta); err != nil {
+		return nil, err
+	}
+
+	// Make the RPC
+	var out structs.IndexedDirEntryRevisions
+	if err := s.agent.RPC(req.Context(), "KVS.Revisions", args, &out); err != nil {
+		return nil, err
+	}
+	setMeta(resp, &out.QueryMeta)
+
+	if out.Revisions == nil {
+		out.Revisions = make(structs.DirEntryRevisions, 0)
+	}
+	return out.Revisions, nil
+}
+
 // KVSGetKeys handles a GET request for keys
 func (s *HTTPHandlers) KVSGetKeys(resp http.ResponseWriter, req *http.Request, args *structs.KeyRequest) (interface{}, error) {
 	if err := s.parseEntMeta(req, &args.EnterpriseMeta); err != nil {
@@ -206,6 +249,21 @@ func (s *HTTPHandlers) KVSPut(resp http.ResponseWriter, req *http.Request, args
 		applyReq.Op = api.KVUnlock
 	}
 
+	// Check for a TTL
+	if _, ok := params["ttl"]; ok {
+		ttl, err := time.ParseDuration(params.Get("ttl"))
+		if err != nil {
+			return nil, HTTPError{StatusCode: http.StatusBadRequest, Reason: fmt.Sprintf("Invalid ttl: %v", err)}
+		}
+		if ttl != 0 && (ttl < structs.KVTTLMin || ttl > structs.KVTTLMax) {
+			return nil, HTTPError{
+				StatusCode: http.StatusBadRequest,
+				Reason:     fmt.Sprintf("Invalid ttl %q, must be between [%v=%v]", params.Get("ttl"), structs.KVTTLMin, structs.KVTTLMax),
+			}
+		}
+		applyReq.DirEnt.TTL = params.Get("ttl")
+	}
+
 	// Check the content-length
 	if req.ContentLength > int64(s.agent.config.KVMaxValueSize) {
 		return nil, HTTPError{
@@ -225,6 +283,12 @@ func (s *HTTPHandlers) KVSPut(resp http.ResponseWriter, req *http.Request, args
 	// Make the RPC
 	var out bool
 	if err := s.agent.RPC(req.Context(), "KVS.Apply", &applyReq, &out); err != nil {
+		if structs.IsErrKVSchemaViolation(err) {
+			return nil, HTTPError{StatusCode: http.StatusBadRequest, Reason: err.Error()}
+		}
+		if structs.IsErrKVQuotaExceeded(err) {
+			return nil, HTTPError{StatusCode: http.StatusRequestEntityTooLarge, Reason: err.Error()}
+		}
 		return nil, err
 	}
 
@@ -5,11 +5,15 @@ package agent
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
 
 	"github.com/hashicorp/consul/testrpc"
 
@@ -575,3 +579,259 @@ func TestKVSEndpoint_DELETE_ConflictingFlags(t *testing.T) {
 		t.Fatalf("expected conflicting args error")
 	}
 }
+
+func TestKVSEndpoint_PUT_TTL(t *testing.T) {
+	if testing.Short() {
+		t.Skip("too slow for testing.Short")
+	}
+
+	t.Parallel()
+	a := NewTestAgent(t, "")
+	defer a.Shutdown()
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+	for _, ttl := range []string{"soon", "10ms", "48h"} {
+		req, _ := http.NewRequest("PUT", "/v1/kv/test?ttl="+ttl, bytes.NewBufferString("test"))
+		resp := httptest.NewRecorder()
+		_, err := a.srv.KVSEndpoint(resp, req)
+		require.Error(t, err)
+		require.Equal(t, http.StatusBadRequest, err.(HTTPError).StatusCode, ttl)
+	}
+
+	req, _ := http.NewRequest("PUT", "/v1/kv/test?ttl=1s", bytes.NewBufferString("test"))
+	resp := httptest.NewRecorder()
+	obj, err := a.srv.KVSEndpoint(resp, req)
+	require.NoError(t, err)
+	require.True(t, obj.(bool))
+
+	req, _ = http.NewRequest("GET", "/v1/kv/test", nil)
+	resp = httptest.NewRecorder()
+	obj, err = a.srv.KVSEndpoint(resp, req)
+	require.NoError(t, err)
+	require.Equal(t, "1s", obj.(structs.DirEntries)[0].TTL)
+
+	// A blocking query on the key returns when it expires.
+	index := obj.(structs.DirEntries)[0].ModifyIndex
+	req, _ = http.NewRequest("GET", fmt.Sprintf("/v1/kv/test?index=%d&wait=10s", index), nil)
+	resp = httptest.NewRecorder()
+	obj, err = a.srv.KVSEndpoint(resp, req)
+	require.NoError(t, err)
+	require.Nil(t, obj)
+	require.Equal(t, http.StatusNotFound, resp.Code)
+}
+
+func TestKVSEndpoint_GET_Revisions(t *testing.T) {
+	if testing.Short() {
+		t.Skip("too slow for testing.Short")
+	}
+
+	t.Parallel()
+	a := NewTestAgent(t, "")
+	defer a.Shutdown()
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+	entryArgs := structs.ConfigEntryRequest{
+		Datacenter: "dc1",
+		Entry: &structs.KVPrefixConfigEntry{
+			Name:    "config",
+			Prefix:  "config/",
+			History: &structs.KVHistoryConfig{MaxRevisions: 10},
+		},
+	}
+	var entryResp bool
+	require.NoError(t, a.RPC(context.Background(), "ConfigEntry.Apply", &entryArgs, &entryResp))
+
+	var indexes []uint64
+	for _, value := range []string{"v1", "v2"} {
+		req, _ := http.NewRequest("PUT", "/v1/kv/config/app", bytes.NewBufferString(value))
+		resp := httptest.NewRecorder()
+		_, err := a.srv.KVSEndpoint(resp, req)
+		require.NoError(t, err)
+
+		req, _ = http.NewRequest("GET", "/v1/kv/config/app", nil)
+		resp = httptest.NewRecorder()
+		obj, err := a.srv.KVSEndpoint(resp, req)
+		require.NoError(t, err)
+		indexes = append(indexes, obj.(structs.DirEntries)[0].ModifyIndex)
+	}
+
+	req, _ := http.NewRequest("GET", "/v1/kv/config/app?revisions", nil)
+	resp := httptest.NewRecorder()
+	obj, err := a.srv.KVSEndpoint(resp, req)
+	require.NoError(t, err)
+	assertIndex(t, resp)
+	revs := obj.(structs.DirEntryRevisions)
+	require.Len(t, revs, 1)
+	require.Equal(t, "v1", string(revs[0].Value))
+	require.Equal(t, indexes[1], revs[0].SupersededIndex)
+
+	// Keys without history have no revisions.
+	req, _ = http.NewRequest("GET", "/v1/kv/other?revisions", nil)
+	resp = httptest.NewRecorder()
+	obj, err = a.srv.KVSEndpoint(resp, req)
+	require.NoError(t, err)
+	require.Empty(t, obj.(structs.DirEntryRevisions))
+	require.NotNil(t, obj.(structs.DirEntryRevisions))
+
+	req, _ = http.NewRequest("GET", fmt.Sprintf("/v1/kv/config/app?at-index=%d", indexes[0]), nil)
+	resp = httptest.NewRecorder()
+	obj, err = a.srv.KVSEndpoint(resp, req)
+	require.NoError(t, err)
+	require.Equal(t, "v1", string(obj.(structs.DirEntries)[0].Value))
+
+	for _, query := range []string{"at-index=0", "at-index=nope", fmt.Sprintf("at-index=%d&recurse", indexes[0])} {
+		req, _ = http.NewRequest("GET", "/v1/kv/config/app?"+query, nil)
+		resp = httptest.NewRecorder()
+		_, err = a.srv.KVSEndpoint(resp, req)
+		require.Error(t, err)
+		require.Equal(t, http.StatusBadRequest, err.(HTTPError).StatusCode, query)
+	}
+}
+
+func TestKVSEndpoint_PUT_Schema(t *testing.T) {
+	if testing.Short() {
+		t.Skip("too slow for testing.Short")
+	}
+
+	t.Parallel()
+	a := NewTestAgent(t, "")
+	defer a.Shutdown()
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+	entryArgs := structs.ConfigEntryRequest{
+		Datacenter: "dc1",
+		Entry: &structs.KVPrefixConfigEntry{
+			Name:   "config",
+			Prefix: "config/",
+			Schema: `{"type": "object", "required": ["port"]}`,
+		},
+	}
+	var entryResp bool
+	require.NoError(t, a.RPC(context.Background(), "ConfigEntry.Apply", &entryArgs, &entryResp))
+
+	req, _ := http.NewRequest("PUT", "/v1/kv/config/app", bytes.NewBufferString(`{"port": 80}`))
+	resp := httptest.NewRecorder()
+	obj, err := a.srv.KVSEndpoint(resp, req)
+	require.NoError(t, err)
+	require.True(t, obj.(bool))
+
+	req, _ = http.NewRequest("PUT", "/v1/kv/config/app", bytes.NewBufferString(`{"prot": 80}`))
+	resp = httptest.NewRecorder()
+	_, err = a.srv.KVSEndpoint(resp, req)
+	require.Error(t, err)
+	httpErr, ok := err.(HTTPError)
+	require.True(t, ok, "unexpected error: %v", err)
+	require.Equal(t, http.StatusBadRequest, httpErr.StatusCode)
+	require.Contains(t, httpErr.Reason, "(root): port is required")
+}
+
+func TestKVSEndpoint_PUT_Quota(t *testing.T) {
+	if testing.Short() {
+		t.Skip("too slow for testing.Short")
+	}
+
+	t.Parallel()
+	a := NewTestAgent(t, "")
+	defer a.Shutdown()
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+	entryArgs := structs.ConfigEntryRequest{
+		Datacenter: "dc1",
+		Entry: &structs.KVPrefixConfigEntry{
+			Name:   "config",
+			Prefix: "config/",
+			Quota:  &structs.KVQuotaConfig{MaxBytes: 20},
+		},
+	}
+	var entryResp bool
+	require.NoError(t, a.RPC(context.Background(), "ConfigEntry.Apply", &entryArgs, &entryResp))
+
+	req, _ := http.NewRequest("PUT", "/v1/kv/config/app", bytes.NewBufferString("small"))
+	resp := httptest.NewRecorder()
+	obj, err := a.srv.KVSEndpoint(resp, req)
+	require.NoError(t, err)
+	require.True(t, obj.(bool))
+
+	req, _ = http.NewRequest("PUT", "/v1/kv/config/app", bytes.NewBufferString("a value that is too large"))
+	resp = httptest.NewRecorder()
+	_, err = a.srv.KVSEndpoint(resp, req)
+	require.Error(t, err)
+	httpErr, ok := err.(HTTPError)
+	require.True(t, ok, "unexpected error: %v", err)
+	require.Equal(t, http.StatusRequestEntityTooLarge, httpErr.StatusCode)
+	require.Contains(t, httpErr.Reason, "15 of 20 bytes used, the write needs 20 more")
+}
+
+func TestKVSEndpoint_Recurse_Blocking(t *testing.T) {
+	if testing.Short() {
+		t.Skip("too slow for testing.Short")
+	}
+
+	t.Parallel()
+
+	cases := []struct {
+		name         string
+		hcl          string
+		queryBackend string
+	}{
+		{
+			name:         "no streaming",
+			hcl:          `use_streaming_backend = false`,
+			queryBackend: "blocking-query",
+		},
+		{
+			name: "streaming",
+			hcl: `
+rpc { enable_streaming = true }
+use_streaming_backend = true
+`,
+			queryBackend: "streaming",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			a := NewTestAgent(t, tc.hcl)
+			defer a.Shutdown()
+			testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+			put := func(key string) error {
+				req, _ := http.NewRequest("PUT", "/v1/kv/"+key, bytes.NewBufferString("test"))
+				_, err := a.srv.KVSEndpoint(httptest.NewRecorder(), req)
+				return err
+			}
+			require.NoError(t, put("config/a"))
+
+			req, _ := http.NewRequest("GET", "/v1/kv/config/?recurse", nil)
+			resp := httptest.NewRecorder()
+			obj, err := a.srv.KVSEndpoint(resp, req)
+			require.NoError(t, err)
+			require.Len(t, obj.(structs.DirEntries), 1)
+			idx := getIndex(t, resp)
+
+			errCh := make(chan error, 1)
+			sleep := 200 * time.Millisecond
+			start := time.Now()
+			go func() {
+				time.Sleep(sleep)
+				errCh <- put("config/b")
+			}()
+
+			url := fmt.Sprintf("/v1/kv/config/?recurse&index=%d&wait=30s", idx)
+			req, _ = http.NewRequest("GET", url, nil)
+			resp = httptest.NewRecorder()
+			obj, err = a.srv.KVSEndpoint(resp, req)
+			require.NoError(t, err)
+			require.NoError(t, <-errCh)
+			require.True(t, time.Since(start) > sleep, "request should block until the key is written")
+
+			entries := obj.(structs.DirEntries)
+			require.Len(t, entries, 2)
+			require.Equal(t, "config/a", entries[0].Key)
+			require.Equal(t, "config/b", entries[1].Key)
+			require.Greater(t, getIndex(t, resp), idx)
+			require.Equal(t, tc.queryBackend, resp.Header().Get("X-Consul-Query-Backend"))
+		})
+	}
+}
@@ -114,6 +114,38 @@ func (s *HTTPHandlers) OperatorRaftPeer(resp http.ResponseWriter, req *http.Requ
 	return nil, nil
 }
 
+// OperatorRaftVerification returns the state of the Raft log verification of
+// all the servers on GET, and writes a verification checkpoint on PUT.
+func (s *HTTPHandlers) OperatorRaftVerification(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	switch req.Method {
+	case "GET":
+		var args structs.DCSpecificRequest
+		if done := s.parse(resp, req, &args.Datacenter, &args.QueryOptions); done {
+			return nil, nil
+		}
+
+		var reply structs.RaftLogVerificationResponse
+		if err := s.agent.RPC(req.Context(), "Operator.RaftLogVerification", &args, &reply); err != nil {
+			return nil, err
+		}
+		return reply, nil
+
+	case "PUT":
+		var args structs.RaftLogVerificationCheckpointRequest
+		s.parseDC(req, &args.Datacenter)
+		s.parseToken(req, &args.Token)
+
+		var reply structs.RaftLogVerificationCheckpointResponse
+		if err := s.agent.RPC(req.Context(), "Operator.RaftLogVerificationCheckpoint", &args, &reply); err != nil {
+			return nil, err
+		}
+		return reply, nil
+
+	default:
+		return nil, MethodNotAllowedError{req.Method, []string{"GET", "PUT"}}
+	}
+}
+
 type keyringArgs struct {
 	Key         string
 	Token       string
@@ -7,16 +7,93 @@
 package agent
 
 import (
-	"github.com/hashicorp/consul/api"
+	"sort"
+
+	"github.com/hashicorp/raft"
 	autopilot "github.com/hashicorp/raft-autopilot"
+	"github.com/mitchellh/mapstructure"
+
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/api"
 )
 
-func autopilotToAPIServerEnterprise(_ *autopilot.ServerState, _ *api.AutopilotServer) {
-	// noop in oss
+// decodeAutopilotExt decodes the promoter specific information of the
+// autopilot state. It's a generic map after a round trip through an RPC.
+func decodeAutopilotExt(ext interface{}, out interface{}) bool {
+	if ext == nil {
+		return false
+	}
+	return mapstructure.Decode(ext, out) == nil
+}
+
+func autopilotToAPIServerEnterprise(srv *autopilot.ServerState, apiSrv *api.AutopilotServer) {
+	var ext structs.AutopilotServerExt
+	if decodeAutopilotExt(srv.Server.Ext, &ext) {
+		apiSrv.RedundancyZone = ext.RedundancyZone
+		apiSrv.UpgradeVersion = ext.UpgradeVersion
+	}
+	apiSrv.ReadReplica = apiSrv.NodeType == api.AutopilotTypeReadReplica
 }
 
 func autopilotToAPIStateEnterprise(state *autopilot.State, apiState *api.AutopilotState) {
 	// without the enterprise features there is no different between these two and we don't want to
 	// alarm anyone by leaving this as the zero value.
 	apiState.OptimisticFailureTolerance = state.FailureTolerance
+
+	for id, srv := range apiState.Servers {
+		if srv.ReadReplica {
+			apiState.ReadReplicas = append(apiState.ReadReplicas, id)
+		}
+	}
+	sort.Strings(apiState.ReadReplicas)
+
+	var ext structs.AutopilotStateExt
+	if !decodeAutopilotExt(state.Ext, &ext) {
+		return
+	}
+	apiState.OptimisticFailureTolerance = ext.OptimisticFailureTolerance
+
+	if ext.RedundancyZones != nil {
+		apiState.RedundancyZones = make(map[string]api.AutopilotZone)
+		for name, zone := range ext.RedundancyZones {
+			apiState.RedundancyZones[name] = api.AutopilotZone{
+				Servers:          stringIDs(zone.Servers),
+				Voters:           stringIDs(zone.Voters),
+				FailureTolerance: zone.FailureTolerance,
+			}
+		}
+	}
+
+	if u := ext.Upgrade; u != nil {
+		apiState.Upgrade = &api.AutopilotUpgrade{
+			Status:                    api.AutopilotUpgradeStatus(u.Status),
+			TargetVersion:             u.TargetVersion,
+			TargetVersionVoters:       optionalIDs(u.TargetVersionVoters),
+			TargetVersionNonVoters:    optionalIDs(u.TargetVersionNonVoters),
+			TargetVersionReadReplicas: optionalIDs(u.TargetVersionReadReplicas),
+			OtherVersionVoters:        optionalIDs(u.OtherVersionVoters),
+			OtherVersionNonVoters:     optionalIDs(u.OtherVersionNonVoters),
+			OtherVersionReadReplicas:  optionalIDs(u.OtherVersionReadReplicas),
+		}
+		if u.RedundancyZones != nil {
+			apiState.Upgrade.RedundancyZones = make(map[string]api.AutopilotZoneUpgradeVersions)
+			for name, zone := range u.RedundancyZones {
+				apiState.Upgrade.RedundancyZones[name] = api.AutopilotZoneUpgradeVersions{
+					TargetVersionVoters:    optionalIDs(zone.TargetVersionVoters),
+					TargetVersionNonVoters: optionalIDs(zone.TargetVersionNonVoters),
+					OtherVersionVoters:     optionalIDs(zone.OtherVersionVoters),
+					OtherVersionNonVoters:  optionalIDs(zone.OtherVersionNonVoters),
+				}
+			}
+		}
+	}
+}
+
+// optionalIDs is like stringIDs, but keeps empty lists nil so they're
+// omitted from the output.
+func optionalIDs(ids []raft.ServerID) []string {
+	if len(ids) == 0 {
+		return nil
+	}
+	return stringIDs(ids)
 }
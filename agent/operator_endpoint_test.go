@@ -92,6 +92,46 @@ func TestOperator_RaftPeer(t *testing.T) {
 	})
 }
 
+func TestOperator_RaftVerification(t *testing.T) {
+	if testing.Short() {
+		t.Skip("too slow for testing.Short")
+	}
+
+	t.Parallel()
+	a := NewTestAgent(t, `
+		raft_logstore {
+			verification {
+				enabled = true
+				interval = "1h"
+			}
+		}
+	`)
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	req, _ := http.NewRequest("PUT", "/v1/operator/raft/verification", nil)
+	resp := httptest.NewRecorder()
+	obj, err := a.srv.OperatorRaftVerification(resp, req)
+	require.NoError(t, err)
+	checkpoint, ok := obj.(structs.RaftLogVerificationCheckpointResponse)
+	require.True(t, ok, "unexpected: %T", obj)
+	require.NotZero(t, checkpoint.Index)
+
+	retry.Run(t, func(r *retry.R) {
+		req, _ := http.NewRequest("GET", "/v1/operator/raft/verification", nil)
+		resp := httptest.NewRecorder()
+		obj, err := a.srv.OperatorRaftVerification(resp, req)
+		require.NoError(r, err)
+		out, ok := obj.(structs.RaftLogVerificationResponse)
+		require.True(r, ok, "unexpected: %T", obj)
+		require.Len(r, out.Servers, 1)
+		require.True(r, out.Servers[0].Enabled)
+		require.NotNil(r, out.Servers[0].LastReport)
+		require.Equal(r, checkpoint.Index, out.Servers[0].LastReport.Range.End)
+		require.Equal(r, structs.RaftLogVerificationOK, out.Servers[0].LastReport.Status)
+	})
+}
+
 func TestOperator_KeyringInstall(t *testing.T) {
 	if testing.Short() {
 		t.Skip("too slow for testing.Short")
@@ -649,6 +689,46 @@ func TestOperator_AutopilotState(t *testing.T) {
 	})
 }
 
+func TestOperator_AutopilotState_RedundancyZones(t *testing.T) {
+	if testing.Short() {
+		t.Skip("too slow for testing.Short")
+	}
+
+	a := NewTestAgent(t, `
+		node_meta {
+			zone = "zone-a"
+		}
+		autopilot {
+			redundancy_zone_tag = "zone"
+		}
+	`)
+	defer a.Shutdown()
+
+	req, err := http.NewRequest("GET", "/v1/operator/autopilot/state", nil)
+	require.NoError(t, err)
+	retry.Run(t, func(r *retry.R) {
+		resp := httptest.NewRecorder()
+		obj, err := a.srv.OperatorAutopilotState(resp, req)
+		require.NoError(r, err)
+		state, ok := obj.(*api.AutopilotState)
+		require.True(r, ok)
+
+		id := string(a.config.NodeID)
+		srv, ok := state.Servers[id]
+		require.True(r, ok)
+		require.Equal(r, "zone-a", srv.RedundancyZone)
+		require.Equal(r, api.AutopilotTypeZoneVoter, srv.NodeType)
+		require.NotEmpty(r, srv.UpgradeVersion)
+
+		require.Equal(r, map[string]api.AutopilotZone{
+			"zone-a": {Servers: []string{id}, Voters: []string{id}},
+		}, state.RedundancyZones)
+		require.NotNil(r, state.Upgrade)
+		require.Equal(r, api.AutopilotUpgradeIdle, state.Upgrade.Status)
+		require.Equal(r, []string{id}, state.Upgrade.TargetVersionVoters)
+	})
+}
+
 func TestAutopilotStateToAPIConversion(t *testing.T) {
 	var leaderID raft.ServerID = "79324811-9588-4311-b208-f272e38aaabf"
 	var follower1ID raft.ServerID = "ef8aee9a-f9d6-4ec4-b383-aac956bdb80f"
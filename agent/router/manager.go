@@ -157,6 +157,24 @@ func (l *serverList) cycleServer() (servers []*metadata.Server) {
 	return newServers
 }
 
+// moveServerToEnd returns true if it moved the server with the given name to
+// the end of the list. Like cycleServer, it assumes the caller is holding the
+// listLock, and it copies the list instead of modifying it in place.
+func (l *serverList) moveServerToEnd(name string) bool {
+	for i, s := range l.servers {
+		if s.Name != name || i == len(l.servers)-1 {
+			continue
+		}
+		newServers := make([]*metadata.Server, 0, len(l.servers))
+		newServers = append(newServers, l.servers[:i]...)
+		newServers = append(newServers, l.servers[i+1:]...)
+		newServers = append(newServers, s)
+		l.servers = newServers
+		return true
+	}
+	return false
+}
+
 // removeServerByKey performs an inline removal of the first matching server
 func (l *serverList) removeServerByKey(targetKey *metadata.Key) {
 	for i, s := range l.servers {
@@ -205,6 +223,21 @@ func (m *Manager) FindServer() *metadata.Server {
 	return l.servers[0]
 }
 
+// FindReadServer is like FindServer, but prefers read replicas, which can
+// serve stale reads without adding load to the servers that are part of the
+// quorum. The first read replica in the server list is returned, so failed
+// read replicas are cycled through the same way as other servers. If there
+// are no read replicas, this returns the same server as FindServer.
+func (m *Manager) FindReadServer() *metadata.Server {
+	l := m.getServerList()
+	for _, s := range l.servers {
+		if s.ReadReplica {
+			return s
+		}
+	}
+	return m.FindServer()
+}
+
 func (m *Manager) checkServers(fn func(srv *metadata.Server) bool) bool {
 	if m == nil {
 		return true
@@ -267,10 +300,12 @@ func (m *Manager) NotifyFailedServer(s *metadata.Server) {
 	// If the server being failed is not the first server on the list,
 	// this is a noop.  If, however, the server is failed and first on
 	// the list, acquire the lock, retest, and take the penalty of moving
-	// the server to the end of the list.
+	// the server to the end of the list. Read replicas are handed out by
+	// FindReadServer from anywhere in the list, so they're moved to the
+	// end wherever they are.
 
 	// Only rotate the server list when there is more than one server
-	if len(l.servers) > 1 && l.servers[0].Name == s.Name &&
+	if len(l.servers) > 1 && (l.servers[0].Name == s.Name || s.ReadReplica) &&
 		// Use atomic.CAS to emulate a TryLock().
 		atomic.CompareAndSwapInt32(&m.notifyFailedBarrier, 0, 1) {
 		defer atomic.StoreInt32(&m.notifyFailedBarrier, 0)
@@ -285,6 +320,9 @@ func (m *Manager) NotifyFailedServer(s *metadata.Server) {
 			l.servers = l.cycleServer()
 			m.saveServerList(l)
 			m.logger.Debug("cycled away from server", "server", s.String())
+		} else if s.ReadReplica && l.moveServerToEnd(s.Name) {
+			m.saveServerList(l)
+			m.logger.Debug("cycled away from read replica", "server", s.String())
 		}
 	}
 }
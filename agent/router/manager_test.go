@@ -259,6 +259,48 @@ func TestServers_NotifyFailedServer(t *testing.T) {
 	}
 }
 
+// func (m *Manager) FindReadServer() (server *metadata.Server) {
+func TestServers_FindReadServer(t *testing.T) {
+	m := testManager(t)
+
+	if m.FindReadServer() != nil {
+		t.Fatalf("Expected nil return")
+	}
+
+	s1 := &metadata.Server{Name: "s1"}
+	s2 := &metadata.Server{Name: "s2"}
+	m.AddServer(s1)
+	m.AddServer(s2)
+	if s := m.FindReadServer(); s == nil || s.Name != "s1" {
+		t.Fatalf("Expected the first server without read replicas, got %v", s)
+	}
+
+	r1 := &metadata.Server{Name: "r1", ReadReplica: true}
+	r2 := &metadata.Server{Name: "r2", ReadReplica: true}
+	m.AddServer(r1)
+	m.AddServer(r2)
+	if s := m.FindServer(); s == nil || s.Name != "s1" {
+		t.Fatalf("FindServer should ignore read replicas, got %v", s)
+	}
+	if s := m.FindReadServer(); s == nil || s.Name != "r1" {
+		t.Fatalf("Expected read replica r1, got %v", s)
+	}
+
+	// A failed read replica is moved to the end of the list even if it
+	// isn't the first server.
+	m.NotifyFailedServer(r1)
+	if s := m.FindReadServer(); s == nil || s.Name != "r2" {
+		t.Fatalf("Expected read replica r2, got %v", s)
+	}
+	if s := m.FindServer(); s == nil || s.Name != "s1" {
+		t.Fatalf("Expected the first server to be unchanged, got %v", s)
+	}
+	m.NotifyFailedServer(r2)
+	if s := m.FindReadServer(); s == nil || s.Name != "r1" {
+		t.Fatalf("Expected read replica r1, got %v", s)
+	}
+}
+
 // func (m *Manager) NumServers() (numServers int) {
 func TestServers_NumServers(t *testing.T) {
 	m := testManager(t)
@@ -390,6 +390,18 @@ func (r *Router) FindLANRoute() (*Manager, *metadata.Server) {
 	return mgr, mgr.FindServer()
 }
 
+// FindLANReadRoute is like FindLANRoute, but prefers read replicas. It's
+// used for reads that allow stale results.
+func (r *Router) FindLANReadRoute() (*Manager, *metadata.Server) {
+	mgr := r.GetLANManager()
+
+	if mgr == nil {
+		return nil, nil
+	}
+
+	return mgr, mgr.FindReadServer()
+}
+
 // FindLANServer will look for a server in the local datacenter.
 // This function may return a nil value if no server is available.
 func (r *Router) FindLANServer() *metadata.Server {
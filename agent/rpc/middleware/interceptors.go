@@ -8,6 +8,7 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/armon/go-metrics"
@@ -186,3 +187,101 @@ func GetNetRPCRateLimitingInterceptor(requestLimitsHandler rpcRate.RequestLimits
 		return requestLimitsHandler.Allow(op)
 	}
 }
+
+// tokenSecret is implemented by the requests that carry an ACL token, like
+// structs.RPCInfo.
+type tokenSecret interface {
+	TokenSecret() string
+}
+
+// NewNetRPCRateLimitingCodec wraps a net/rpc server codec to check the rate
+// limits that depend on the ACL token of a request, and the concurrency limits
+// that depend on whether it's a stale or blocking query. Those are only known
+// once the request body has been decoded, so they are checked after the limits
+// of GetNetRPCRateLimitingInterceptor, as the body is read. The operation stops
+// counting against the concurrency limits when its response is written.
+func NewNetRPCRateLimitingCodec(codec rpc.ServerCodec, requestLimitsHandler rpcRate.RequestLimitsHandler, panicHandler RecoveryHandlerFunc) rpc.ServerCodec {
+	return &rateLimitingCodec{
+		ServerCodec:          codec,
+		requestLimitsHandler: requestLimitsHandler,
+		panicHandler:         panicHandler,
+		releases:             make(map[uint64]func()),
+	}
+}
+
+type rateLimitingCodec struct {
+	rpc.ServerCodec
+	requestLimitsHandler rpcRate.RequestLimitsHandler
+	panicHandler         RecoveryHandlerFunc
+
+	// serviceMethod and seq of the request being read. Requests are read one
+	// at a time so there is no need for locking.
+	serviceMethod string
+	seq           uint64
+
+	// releases are the functions releasing the concurrency limits of the
+	// requests being handled, by sequence number. Responses are written
+	// concurrently with the requests being read.
+	releasesLock sync.Mutex
+	releases     map[uint64]func()
+}
+
+func (c *rateLimitingCodec) ReadRequestHeader(req *rpc.Request) error {
+	err := c.ServerCodec.ReadRequestHeader(req)
+	c.serviceMethod = req.ServiceMethod
+	c.seq = req.Seq
+	return err
+}
+
+func (c *rateLimitingCodec) ReadRequestBody(body interface{}) (retErr error) {
+	if err := c.ServerCodec.ReadRequestBody(body); err != nil || body == nil {
+		return err
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			retErr = c.panicHandler(r)
+		}
+	}()
+
+	op := rpcRate.Operation{
+		Name:       c.serviceMethod,
+		SourceAddr: c.SourceAddr(),
+		Type:       rpcRateLimitSpecs[c.serviceMethod].Type,
+		Category:   rpcRateLimitSpecs[c.serviceMethod].Category,
+	}
+	if info, ok := body.(tokenSecret); ok {
+		op.Token = info.TokenSecret()
+	}
+	if rq, ok := body.(readQuery); ok {
+		op.Blocking = rq.GetMinQueryIndex() > 0
+		op.AllowStale = rq.AllowStaleRead()
+	}
+
+	// Returning an error makes net/rpc send it back as the response without
+	// calling the endpoint.
+	if err := c.requestLimitsHandler.AllowToken(op); err != nil {
+		return err
+	}
+	release, err := c.requestLimitsHandler.AcquireConcurrency(op)
+	if err != nil {
+		return err
+	}
+
+	c.releasesLock.Lock()
+	c.releases[c.seq] = release
+	c.releasesLock.Unlock()
+	return nil
+}
+
+func (c *rateLimitingCodec) WriteResponse(resp *rpc.Response, body interface{}) error {
+	c.releasesLock.Lock()
+	release, ok := c.releases[resp.Seq]
+	delete(c.releases, resp.Seq)
+	c.releasesLock.Unlock()
+
+	if ok {
+		release()
+	}
+	return c.ServerCodec.WriteResponse(resp, body)
+}
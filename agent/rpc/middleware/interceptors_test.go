@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/armon/go-metrics"
+	"github.com/hashicorp/consul-net-rpc/net/rpc"
 	"github.com/hashicorp/consul/agent/consul/rate"
 	"github.com/hashicorp/go-hclog"
 	"github.com/stretchr/testify/mock"
@@ -313,3 +314,121 @@ func TestGetNetRPCRateLimitingInterceptor(t *testing.T) {
 		require.Equal(t, "rpc: panic serving request", err.Error())
 	})
 }
+
+func TestNetRPCRateLimitingCodec(t *testing.T) {
+	limiter := rate.NewMockRequestLimitsHandler(t)
+
+	logger := hclog.NewNullLogger()
+	addr := net.TCPAddrFromAddrPort(netip.MustParseAddrPort("1.2.3.4:5678"))
+	codec := NewNetRPCRateLimitingCodec(&fakeServerCodec{addr: addr}, limiter, NewPanicHandler(logger))
+
+	var req rpc.Request
+	require.NoError(t, codec.ReadRequestHeader(&req))
+	require.Equal(t, "KVS.Apply", req.ServiceMethod)
+
+	t.Run("allow operation", func(t *testing.T) {
+		op := rate.Operation{
+			Name:       "KVS.Apply",
+			SourceAddr: addr,
+			Type:       rate.OperationTypeWrite,
+			Category:   rate.OperationCategoryKV,
+			Token:      "secret",
+		}
+		limiter.On("AllowToken", op).
+			Return(nil).
+			Once()
+
+		var released bool
+		limiter.On("AcquireConcurrency", op).
+			Return(func() { released = true }, nil).
+			Once()
+
+		require.NoError(t, codec.ReadRequestBody(&fakeRequest{token: "secret"}))
+		require.False(t, released)
+
+		// The concurrency limit is released once the response is written.
+		require.NoError(t, codec.WriteResponse(&rpc.Response{Seq: req.Seq}, nil))
+		require.True(t, released)
+	})
+
+	t.Run("stale blocking query", func(t *testing.T) {
+		limiter.On("AllowToken", mock.Anything).
+			Return(nil).
+			Once()
+		limiter.On("AcquireConcurrency", mock.MatchedBy(func(op rate.Operation) bool {
+			return op.Blocking && op.AllowStale
+		})).
+			Return(func() {}, nil).
+			Once()
+
+		require.NoError(t, codec.ReadRequestBody(&fakeRequest{minQueryIndex: 5, allowStale: true}))
+		require.NoError(t, codec.WriteResponse(&rpc.Response{Seq: req.Seq}, nil))
+	})
+
+	t.Run("concurrency limit exceeded", func(t *testing.T) {
+		limiter.On("AllowToken", mock.Anything).
+			Return(nil).
+			Once()
+		limiter.On("AcquireConcurrency", mock.Anything).
+			Return(nil, rate.ErrRetryElsewhere).
+			Once()
+
+		err := codec.ReadRequestBody(&fakeRequest{})
+		require.ErrorIs(t, err, rate.ErrRetryElsewhere)
+
+		// The error response doesn't release anything.
+		require.NoError(t, codec.WriteResponse(&rpc.Response{Seq: req.Seq}, nil))
+	})
+
+	t.Run("allow returns error", func(t *testing.T) {
+		limiter.On("AllowToken", mock.Anything).
+			Return(rate.ErrRetryElsewhere).
+			Once()
+
+		err := codec.ReadRequestBody(&fakeRequest{})
+		require.ErrorIs(t, err, rate.ErrRetryElsewhere)
+	})
+
+	t.Run("allow panics", func(t *testing.T) {
+		limiter.On("AllowToken", mock.Anything).
+			Panic("uh oh").
+			Once()
+
+		err := codec.ReadRequestBody(&fakeRequest{})
+		require.Error(t, err)
+		require.Equal(t, "rpc: panic serving request", err.Error())
+	})
+
+	t.Run("discarded body", func(t *testing.T) {
+		require.NoError(t, codec.ReadRequestBody(nil))
+	})
+}
+
+type fakeServerCodec struct {
+	rpc.ServerCodec
+	addr net.Addr
+}
+
+func (c *fakeServerCodec) ReadRequestHeader(req *rpc.Request) error {
+	req.ServiceMethod = "KVS.Apply"
+	req.Seq = 7
+	return nil
+}
+
+func (c *fakeServerCodec) ReadRequestBody(interface{}) error { return nil }
+
+func (c *fakeServerCodec) WriteResponse(*rpc.Response, interface{}) error { return nil }
+
+func (c *fakeServerCodec) SourceAddr() net.Addr { return c.addr }
+
+type fakeRequest struct {
+	token         string
+	minQueryIndex uint64
+	allowStale    bool
+}
+
+func (r *fakeRequest) TokenSecret() string { return r.token }
+
+func (r *fakeRequest) GetMinQueryIndex() uint64 { return r.minQueryIndex }
+
+func (r *fakeRequest) AllowStaleRead() bool { return r.allowStale }
@@ -110,18 +110,21 @@ var rpcRateLimitSpecs = map[string]rate.OperationSpec{
 	"Internal.ServiceGateways":               {Type: rate.OperationTypeRead, Category: rate.OperationCategoryInternal},
 	"Internal.ServiceTopology":               {Type: rate.OperationTypeRead, Category: rate.OperationCategoryInternal},
 
-	"KVS.Apply":    {Type: rate.OperationTypeWrite, Category: rate.OperationCategoryKV},
-	"KVS.Get":      {Type: rate.OperationTypeRead, Category: rate.OperationCategoryKV},
-	"KVS.List":     {Type: rate.OperationTypeRead, Category: rate.OperationCategoryKV},
-	"KVS.ListKeys": {Type: rate.OperationTypeRead, Category: rate.OperationCategoryKV},
-
-	"Operator.AutopilotGetConfiguration": {Type: rate.OperationTypeExempt, Category: rate.OperationCategoryOperator},
-	"Operator.AutopilotSetConfiguration": {Type: rate.OperationTypeExempt, Category: rate.OperationCategoryOperator},
-	"Operator.AutopilotState":            {Type: rate.OperationTypeExempt, Category: rate.OperationCategoryOperator},
-	"Operator.RaftGetConfiguration":      {Type: rate.OperationTypeExempt, Category: rate.OperationCategoryOperator},
-	"Operator.RaftRemovePeerByAddress":   {Type: rate.OperationTypeExempt, Category: rate.OperationCategoryOperator},
-	"Operator.RaftRemovePeerByID":        {Type: rate.OperationTypeExempt, Category: rate.OperationCategoryOperator},
-	"Operator.ServerHealth":              {Type: rate.OperationTypeExempt, Category: rate.OperationCategoryOperator},
+	"KVS.Apply":     {Type: rate.OperationTypeWrite, Category: rate.OperationCategoryKV},
+	"KVS.Get":       {Type: rate.OperationTypeRead, Category: rate.OperationCategoryKV},
+	"KVS.List":      {Type: rate.OperationTypeRead, Category: rate.OperationCategoryKV},
+	"KVS.ListKeys":  {Type: rate.OperationTypeRead, Category: rate.OperationCategoryKV},
+	"KVS.Revisions": {Type: rate.OperationTypeRead, Category: rate.OperationCategoryKV},
+
+	"Operator.AutopilotGetConfiguration":     {Type: rate.OperationTypeExempt, Category: rate.OperationCategoryOperator},
+	"Operator.AutopilotSetConfiguration":     {Type: rate.OperationTypeExempt, Category: rate.OperationCategoryOperator},
+	"Operator.AutopilotState":                {Type: rate.OperationTypeExempt, Category: rate.OperationCategoryOperator},
+	"Operator.RaftGetConfiguration":          {Type: rate.OperationTypeExempt, Category: rate.OperationCategoryOperator},
+	"Operator.RaftLogVerification":           {Type: rate.OperationTypeExempt, Category: rate.OperationCategoryOperator},
+	"Operator.RaftLogVerificationCheckpoint": {Type: rate.OperationTypeExempt, Category: rate.OperationCategoryOperator},
+	"Operator.RaftRemovePeerByAddress":       {Type: rate.OperationTypeExempt, Category: rate.OperationCategoryOperator},
+	"Operator.RaftRemovePeerByID":            {Type: rate.OperationTypeExempt, Category: rate.OperationCategoryOperator},
+	"Operator.ServerHealth":                  {Type: rate.OperationTypeExempt, Category: rate.OperationCategoryOperator},
 
 	"PreparedQuery.Apply":         {Type: rate.OperationTypeWrite, Category: rate.OperationCategoryPreparedQuery},
 	"PreparedQuery.Execute":       {Type: rate.OperationTypeRead, Category: rate.OperationCategoryPreparedQuery},
@@ -137,10 +140,11 @@ var rpcRateLimitSpecs = map[string]rate.OperationSpec{
 	"Session.NodeSessions": {Type: rate.OperationTypeRead, Category: rate.OperationCategorySession},
 	"Session.Renew":        {Type: rate.OperationTypeWrite, Category: rate.OperationCategorySession},
 
-	"Status.Leader":    {Type: rate.OperationTypeExempt, Category: rate.OperationCategoryStatus},
-	"Status.Peers":     {Type: rate.OperationTypeExempt, Category: rate.OperationCategoryStatus},
-	"Status.Ping":      {Type: rate.OperationTypeExempt, Category: rate.OperationCategoryStatus},
-	"Status.RaftStats": {Type: rate.OperationTypeExempt, Category: rate.OperationCategoryStatus},
+	"Status.Leader":              {Type: rate.OperationTypeExempt, Category: rate.OperationCategoryStatus},
+	"Status.Peers":               {Type: rate.OperationTypeExempt, Category: rate.OperationCategoryStatus},
+	"Status.Ping":                {Type: rate.OperationTypeExempt, Category: rate.OperationCategoryStatus},
+	"Status.RaftStats":           {Type: rate.OperationTypeExempt, Category: rate.OperationCategoryStatus},
+	"Status.RaftLogVerification": {Type: rate.OperationTypeExempt, Category: rate.OperationCategoryStatus},
 
 	"Txn.Apply": {Type: rate.OperationTypeWrite, Category: rate.OperationCategoryTxn},
 	"Txn.Read":  {Type: rate.OperationTypeRead, Category: rate.OperationCategoryTxn},
@@ -50,6 +50,10 @@ const (
 	// With 1s we cover ~p96, then we initiate the 3-second retry loop.
 	meshGatewayWait      = 1 * time.Second
 	establishmentTimeout = 3 * time.Second
+
+	// defaultQueryTime is used for blocking queries when no default is
+	// configured, matching the server's default_query_time.
+	defaultQueryTime = 300 * time.Second
 )
 
 // errPeeringInvalidServerAddress is returned when an establish request contains
@@ -92,6 +96,11 @@ type Config struct {
 	ConnectEnabled bool
 	PeeringEnabled bool
 	Locality       *structs.Locality
+
+	// DefaultQueryTime and MaxQueryTime bound how long blocking queries
+	// wait for changes, as with the server's other blocking endpoints.
+	DefaultQueryTime time.Duration
+	MaxQueryTime     time.Duration
 }
 
 func NewServer(cfg Config) *Server {
@@ -188,6 +197,7 @@ type Store interface {
 	PeeringTrustBundleRead(ws memdb.WatchSet, q state.Query) (uint64, *pbpeering.PeeringTrustBundle, error)
 	PeeringTrustBundleList(ws memdb.WatchSet, entMeta acl.EnterpriseMeta) (uint64, []*pbpeering.PeeringTrustBundle, error)
 	TrustBundleListByService(ws memdb.WatchSet, service, dc string, entMeta acl.EnterpriseMeta) (uint64, []*pbpeering.PeeringTrustBundle, error)
+	AbandonCh() <-chan struct{}
 }
 
 var peeringNotEnabledErr = grpcstatus.Error(codes.FailedPrecondition, "peering must be enabled to use this endpoint")
@@ -688,7 +698,18 @@ func (s *Server) PeeringList(ctx context.Context, req *pbpeering.PeeringListRequ
 
 	defer metrics.MeasureSince([]string{"peering", "list"}, time.Now())
 
-	idx, peerings, err := s.Backend.Store().PeeringList(nil, *entMeta)
+	var (
+		idx      uint64
+		peerings []*pbpeering.Peering
+	)
+	err = s.blockingQuery(ctx, options, func(ws memdb.WatchSet, store Store) (uint64, error) {
+		idx, peerings, err = store.PeeringList(ws, *entMeta)
+		// Ensure we never return a zero index so that clients can block.
+		if idx == 0 {
+			idx = 1
+		}
+		return idx, err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -1066,3 +1087,54 @@ func copyPeering(p *pbpeering.Peering) *pbpeering.Peering {
 
 	return &copyP
 }
+
+// blockingQuery runs query against the state store and, when the request sets
+// a MinQueryIndex, re-runs it each time its watches fire until the returned
+// index exceeds MinQueryIndex or the query times out.
+func (s *Server) blockingQuery(ctx context.Context, options structs.QueryOptions, query func(memdb.WatchSet, Store) (uint64, error)) error {
+	if options.MinQueryIndex == 0 {
+		_, err := query(nil, s.Backend.Store())
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.queryTimeout(options.MaxQueryTime))
+	defer cancel()
+
+	for {
+		store := s.Backend.Store()
+
+		ws := memdb.NewWatchSet()
+		// This channel will be closed if a snapshot is restored and the
+		// whole state store is abandoned.
+		ws.Add(store.AbandonCh())
+
+		idx, err := query(ws, store)
+		if err != nil {
+			return err
+		}
+		if idx > options.MinQueryIndex {
+			return nil
+		}
+
+		// block until something changes, or the timeout
+		if err := ws.WatchCtx(ctx); err != nil {
+			return nil
+		}
+	}
+}
+
+// queryTimeout restricts the requested query time to the configured maximum
+// and applies a small amount of jitter.
+func (s *Server) queryTimeout(queryTimeout time.Duration) time.Duration {
+	if s.Config.MaxQueryTime > 0 && queryTimeout > s.Config.MaxQueryTime {
+		queryTimeout = s.Config.MaxQueryTime
+	} else if queryTimeout <= 0 {
+		queryTimeout = s.Config.DefaultQueryTime
+	}
+	if queryTimeout <= 0 {
+		queryTimeout = defaultQueryTime
+	}
+
+	queryTimeout += lib.RandomStagger(queryTimeout / structs.JitterFraction)
+	return queryTimeout
+}
@@ -1026,6 +1026,54 @@ func TestPeeringService_List(t *testing.T) {
 	prototest.AssertDeepEqual(t, expect, resp)
 }
 
+func TestPeeringService_List_Blocking(t *testing.T) {
+	// TODO(peering): see note on newTestServer, refactor to not use this
+	s := newTestServer(t, nil)
+
+	foo := &pbpeering.Peering{
+		ID:                  testUUID(t),
+		Name:                "foo",
+		State:               pbpeering.PeeringState_ESTABLISHING,
+		PeerServerName:      "fooservername",
+		PeerServerAddresses: []string{"addr1"},
+	}
+	require.NoError(t, s.Server.FSM().State().PeeringWrite(10, &pbpeering.PeeringWriteRequest{Peering: foo}))
+
+	client := pbpeering.NewPeeringServiceClient(s.ClientConn(t))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	ctx, err := external.ContextWithQueryOptions(ctx, structs.QueryOptions{
+		MinQueryIndex: 10,
+		MaxQueryTime:  5 * time.Second,
+	})
+	require.NoError(t, err)
+
+	bar := &pbpeering.Peering{
+		ID:                  testUUID(t),
+		Name:                "bar",
+		State:               pbpeering.PeeringState_ACTIVE,
+		PeerServerName:      "barservername",
+		PeerServerAddresses: []string{"addr1"},
+	}
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		require.NoError(t, s.Server.FSM().State().PeeringWrite(15, &pbpeering.PeeringWriteRequest{Peering: bar}))
+	}()
+
+	start := time.Now()
+	resp, err := client.PeeringList(ctx, &pbpeering.PeeringListRequest{})
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+
+	expect := &pbpeering.PeeringListResponse{
+		Peerings: []*pbpeering.Peering{bar, foo},
+		Index:    15,
+	}
+	prototest.AssertDeepEqual(t, expect, resp)
+}
+
 func TestPeeringService_List_ACLEnforcement(t *testing.T) {
 	// TODO(peering): see note on newTestServer, refactor to not use this
 	s := newTestServer(t, func(conf *consul.Config) {
@@ -0,0 +1,107 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+
+	"github.com/hashicorp/consul/acl"
+	"github.com/hashicorp/consul/agent/cache"
+	"github.com/hashicorp/consul/agent/rpcclient"
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/agent/submatview"
+	"github.com/hashicorp/consul/proto/private/pbsubscribe"
+)
+
+// Client provides access to KV data.
+type Client struct {
+	rpcclient.Client
+}
+
+// List returns the KV entries under the prefix of the request. Blocking
+// queries are served from a materialized view of the KV topic when the
+// streaming backend is enabled, other queries are sent to the servers.
+func (c *Client) List(
+	ctx context.Context,
+	req *structs.KeyRequest,
+) (structs.IndexedDirEntries, cache.ResultMeta, error) {
+	if c.useStreaming(req) {
+		c.QueryOptionDefaults(&req.QueryOptions)
+		result, err := c.ViewStore.Get(ctx, c.newKVRequest(req))
+		if err != nil {
+			return structs.IndexedDirEntries{}, cache.ResultMeta{}, err
+		}
+		meta := cache.ResultMeta{Index: result.Index, Hit: result.Cached}
+		return *result.Value.(*structs.IndexedDirEntries), meta, err
+	}
+
+	var out structs.IndexedDirEntries
+	err := c.NetRPC.RPC(ctx, "KVS.List", req, &out)
+	return out, cache.ResultMeta{}, err
+}
+
+func (c *Client) useStreaming(req *structs.KeyRequest) bool {
+	// Events are published with the exact namespace of the keys, so queries
+	// for all namespaces can't be served by a subscription.
+	return c.UseStreamingBackend &&
+		req.MinQueryIndex > 0 &&
+		req.AtIndex == 0 &&
+		req.NamespaceOrDefault() != acl.WildcardName
+}
+
+var _ submatview.Request = (*kvRequest)(nil)
+
+type kvRequest struct {
+	req  *structs.KeyRequest
+	deps rpcclient.MaterializerDeps
+}
+
+func (c *Client) newKVRequest(req *structs.KeyRequest) *kvRequest {
+	return &kvRequest{
+		req:  req,
+		deps: c.MaterializerDeps,
+	}
+}
+
+// CacheInfo returns information used for caching the KV request.
+func (r *kvRequest) CacheInfo() cache.RequestInfo {
+	return r.req.CacheInfo()
+}
+
+// Type returns a string which uniquely identifies the KV request.
+// The returned value is used as the prefix of the key used to index
+// entries in the Store.
+func (r *kvRequest) Type() string {
+	return "agent.rpcclient.kv.kvrequest"
+}
+
+// Request creates a new pbsubscribe.SubscribeRequest for the KV entries under
+// the prefix of the request.
+func (r *kvRequest) Request(index uint64) *pbsubscribe.SubscribeRequest {
+	return &pbsubscribe.SubscribeRequest{
+		Topic:      pbsubscribe.Topic_KV,
+		Index:      index,
+		Datacenter: r.req.Datacenter,
+		Token:      r.req.QueryOptions.Token,
+		Subject: &pbsubscribe.SubscribeRequest_NamedSubject{
+			NamedSubject: &pbsubscribe.NamedSubject{
+				Key:       r.req.Key,
+				Partition: r.req.PartitionOrDefault(),
+				Namespace: r.req.NamespaceOrDefault(),
+			},
+		},
+	}
+}
+
+// NewMaterializer will be called if there is no active materializer to fulfill
+// the request. It returns a Materializer appropriate for streaming
+// data to fulfil the KV request.
+func (r *kvRequest) NewMaterializer() (submatview.Materializer, error) {
+	deps := submatview.Deps{
+		View:    NewKVPrefixView(),
+		Logger:  r.deps.Logger,
+		Request: r.Request,
+	}
+	return submatview.NewRPCMaterializer(pbsubscribe.NewStateChangeSubscriptionClient(r.deps.Conn), deps), nil
+}
@@ -0,0 +1,151 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/consul/agent/cache"
+	"github.com/hashicorp/consul/agent/config"
+	"github.com/hashicorp/consul/agent/rpcclient"
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/agent/submatview"
+	"github.com/hashicorp/consul/proto/private/pbsubscribe"
+)
+
+func TestClient_List_BackendRouting(t *testing.T) {
+	type testCase struct {
+		name                string
+		req                 structs.KeyRequest
+		useStreamingBackend bool
+		expectStreaming     bool
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		rpc := &fakeNetRPC{}
+		store := &fakeViewStore{}
+		c := &Client{
+			Client: rpcclient.Client{
+				NetRPC:              rpc,
+				ViewStore:           store,
+				UseStreamingBackend: tc.useStreamingBackend,
+				QueryOptionDefaults: config.ApplyDefaultQueryOptions(&config.RuntimeConfig{}),
+			},
+		}
+		_, _, err := c.List(context.Background(), &tc.req)
+		require.NoError(t, err)
+
+		if tc.expectStreaming {
+			require.Len(t, rpc.calls, 0)
+			require.Len(t, store.calls, 1)
+		} else {
+			require.Equal(t, []string{"KVS.List"}, rpc.calls)
+			require.Len(t, store.calls, 0)
+		}
+	}
+
+	var testCases = []testCase{
+		{
+			name:                "rpc by default",
+			req:                 structs.KeyRequest{Key: "config/"},
+			useStreamingBackend: true,
+		},
+		{
+			name: "use streaming for MinQueryIndex",
+			req: structs.KeyRequest{
+				Key:          "config/",
+				QueryOptions: structs.QueryOptions{MinQueryIndex: 22},
+			},
+			useStreamingBackend: true,
+			expectStreaming:     true,
+		},
+		{
+			name: "rpc when streaming is disabled",
+			req: structs.KeyRequest{
+				Key:          "config/",
+				QueryOptions: structs.QueryOptions{MinQueryIndex: 22},
+			},
+			useStreamingBackend: false,
+		},
+		{
+			name: "rpc for point-in-time reads",
+			req: structs.KeyRequest{
+				Key:          "config/",
+				AtIndex:      10,
+				QueryOptions: structs.QueryOptions{MinQueryIndex: 22},
+			},
+			useStreamingBackend: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+func TestClient_List_SetsDefaults(t *testing.T) {
+	store := &fakeViewStore{}
+	c := &Client{
+		Client: rpcclient.Client{
+			ViewStore:           store,
+			UseStreamingBackend: true,
+			QueryOptionDefaults: config.ApplyDefaultQueryOptions(&config.RuntimeConfig{
+				MaxQueryTime:     200 * time.Second,
+				DefaultQueryTime: 100 * time.Second,
+			}),
+		},
+	}
+
+	req := structs.KeyRequest{
+		Datacenter:   "dc1",
+		Key:          "config/",
+		QueryOptions: structs.QueryOptions{MinQueryIndex: 22, Token: "token"},
+	}
+
+	_, _, err := c.List(context.Background(), &req)
+	require.NoError(t, err)
+
+	require.Len(t, store.calls, 1)
+	require.Equal(t, 100*time.Second, store.calls[0].CacheInfo().Timeout)
+
+	subReq := store.calls[0].(*kvRequest).Request(5)
+	require.Equal(t, pbsubscribe.Topic_KV, subReq.Topic)
+	require.Equal(t, uint64(5), subReq.Index)
+	require.Equal(t, "dc1", subReq.Datacenter)
+	require.Equal(t, "token", subReq.Token)
+	require.Equal(t, "config/", subReq.GetNamedSubject().Key)
+}
+
+var _ rpcclient.NetRPC = (*fakeNetRPC)(nil)
+
+type fakeNetRPC struct {
+	calls []string
+}
+
+func (f *fakeNetRPC) RPC(_ context.Context, method string, _ interface{}, _ interface{}) error {
+	f.calls = append(f.calls, method)
+	return nil
+}
+
+var _ rpcclient.MaterializedViewStore = (*fakeViewStore)(nil)
+
+type fakeViewStore struct {
+	calls []submatview.Request
+}
+
+func (f *fakeViewStore) Get(_ context.Context, req submatview.Request) (submatview.Result, error) {
+	f.calls = append(f.calls, req)
+	return submatview.Result{Value: &structs.IndexedDirEntries{}}, nil
+}
+
+func (f *fakeViewStore) NotifyCallback(_ context.Context, req submatview.Request, _ string, _ cache.Callback) error {
+	f.calls = append(f.calls, req)
+	return nil
+}
@@ -0,0 +1,73 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"sort"
+
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/agent/submatview"
+	"github.com/hashicorp/consul/proto/private/pbsubscribe"
+)
+
+var _ submatview.View = (*KVPrefixView)(nil)
+
+// KVPrefixView implements a submatview.View for the KV entries under a prefix.
+type KVPrefixView struct {
+	state map[string]*structs.DirEntry
+}
+
+// NewKVPrefixView constructs an empty KVPrefixView.
+func NewKVPrefixView() *KVPrefixView {
+	view := &KVPrefixView{}
+	view.Reset()
+	return view
+}
+
+// Reset resets the state of the view to an empty map of KV entries.
+func (v *KVPrefixView) Reset() {
+	v.state = make(map[string]*structs.DirEntry)
+}
+
+// Result returns the structs.IndexedDirEntries stored by this view, sorted by
+// key like the entries returned by the KVS.List RPC.
+func (v *KVPrefixView) Result(index uint64) any {
+	var entries structs.DirEntries
+	if len(v.state) > 0 {
+		entries = make(structs.DirEntries, 0, len(v.state))
+		for _, entry := range v.state {
+			entries = append(entries, entry)
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].Key < entries[j].Key
+		})
+	}
+
+	return &structs.IndexedDirEntries{
+		Entries: entries,
+		QueryMeta: structs.QueryMeta{
+			Index:   index,
+			Backend: structs.QueryBackendStreaming,
+		},
+	}
+}
+
+// Update updates the state of the view with the KV events.
+func (v *KVPrefixView) Update(events []*pbsubscribe.Event) error {
+	for _, event := range events {
+		update := event.GetKV()
+		if update == nil {
+			continue
+		}
+		entry := update.Entry.ToStructs()
+
+		switch update.Op {
+		case pbsubscribe.KVUpdate_Delete:
+			delete(v.state, entry.Key)
+		case pbsubscribe.KVUpdate_Upsert:
+			v.state[entry.Key] = entry
+		}
+	}
+	return nil
+}
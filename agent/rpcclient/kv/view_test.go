@@ -0,0 +1,79 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/proto/private/pbsubscribe"
+	"github.com/hashicorp/consul/sdk/testutil"
+)
+
+func TestKVPrefixView(t *testing.T) {
+	const index uint64 = 123
+
+	view := NewKVPrefixView()
+
+	kvEvent := func(op pbsubscribe.KVUpdate_UpdateOp, key, value string) *pbsubscribe.Event {
+		return &pbsubscribe.Event{
+			Index: index,
+			Payload: &pbsubscribe.Event_KV{
+				KV: &pbsubscribe.KVUpdate{
+					Op:    op,
+					Entry: &pbsubscribe.KVEntry{Key: key, Value: []byte(value)},
+				},
+			},
+		}
+	}
+
+	keys := func(t *testing.T) []string {
+		t.Helper()
+
+		result := view.Result(index)
+		resp, ok := result.(*structs.IndexedDirEntries)
+		require.Truef(t, ok, "expected IndexedDirEntries, got: %T", result)
+		require.Equal(t, index, resp.Index)
+		require.Equal(t, structs.QueryBackendStreaming, resp.Backend)
+
+		var keys []string
+		for _, entry := range resp.Entries {
+			keys = append(keys, entry.Key)
+		}
+		return keys
+	}
+
+	testutil.RunStep(t, "initial state", func(t *testing.T) {
+		require.Empty(t, keys(t))
+	})
+
+	testutil.RunStep(t, "upsert events", func(t *testing.T) {
+		err := view.Update([]*pbsubscribe.Event{
+			kvEvent(pbsubscribe.KVUpdate_Upsert, "config/c", "1"),
+			kvEvent(pbsubscribe.KVUpdate_Upsert, "config/a", "1"),
+			kvEvent(pbsubscribe.KVUpdate_Upsert, "config/b", "1"),
+			kvEvent(pbsubscribe.KVUpdate_Upsert, "config/a", "2"),
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"config/a", "config/b", "config/c"}, keys(t))
+
+		resp := view.Result(index).(*structs.IndexedDirEntries)
+		require.Equal(t, []byte("2"), resp.Entries[0].Value)
+	})
+
+	testutil.RunStep(t, "delete event", func(t *testing.T) {
+		err := view.Update([]*pbsubscribe.Event{
+			kvEvent(pbsubscribe.KVUpdate_Delete, "config/b", ""),
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"config/a", "config/c"}, keys(t))
+	})
+
+	testutil.RunStep(t, "reset", func(t *testing.T) {
+		view.Reset()
+		require.Empty(t, keys(t))
+	})
+}
@@ -274,6 +274,7 @@ func getPrometheusDefs(cfg *config.RuntimeConfig, isServer bool) ([]prometheus.G
 		cache.Gauges,
 		consul.RPCGauges,
 		consul.SessionGauges,
+		consul.KVSTTLGauges,
 		grpcWare.StatsGauges,
 		xds.StatsGauges,
 		usagemetrics.Gauges,
@@ -291,6 +292,7 @@ func getPrometheusDefs(cfg *config.RuntimeConfig, isServer bool) ([]prometheus.G
 			consul.LeaderCertExpirationGauges,
 			consul.LeaderPeeringMetrics,
 			xdscapacity.StatsGauges,
+			rate.Gauges,
 		)
 	}
 
@@ -354,6 +356,7 @@ func getPrometheusDefs(cfg *config.RuntimeConfig, isServer bool) ([]prometheus.G
 		consul.ACLCounters,
 		consul.CatalogCounters,
 		consul.ClientCounters,
+		consul.LeaderHealthCounters,
 		consul.RPCCounters,
 		grpcWare.StatsCounters,
 		local.StateCounters,
@@ -429,6 +432,7 @@ func getPrometheusDefs(cfg *config.RuntimeConfig, isServer bool) ([]prometheus.G
 		consul.FederationStateSummaries,
 		consul.IntentionSummaries,
 		consul.KVSummaries,
+		consul.KVSTTLSummaries,
 		consul.LeaderSummaries,
 		consul.PreparedQuerySummaries,
 		consul.RPCSummaries,
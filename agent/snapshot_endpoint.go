@@ -8,6 +8,7 @@ import (
 	"net/http"
 
 	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/snapshot"
 )
 
 // Snapshot handles requests to take and restore snapshots. This uses a special
@@ -21,9 +22,19 @@ func (s *HTTPHandlers) Snapshot(resp http.ResponseWriter, req *http.Request) (in
 		args.AllowStale = true
 	}
 
+	// The archive is encrypted or decrypted with the key or passphrase given
+	// in the headers, if any. They are never accepted as query parameters so
+	// they don't end up in logs.
+	args.EncryptionKey = req.Header.Get("X-Consul-Snapshot-Key")
+	args.EncryptionPassphrase = req.Header.Get("X-Consul-Snapshot-Passphrase")
+
 	switch req.Method {
 	case "GET":
 		args.Op = structs.SnapshotSave
+		args.Compression = req.URL.Query().Get("compression")
+		if _, err := snapshot.ParseCompression(args.Compression); err != nil {
+			return nil, HTTPError{StatusCode: http.StatusBadRequest, Reason: err.Error()}
+		}
 
 		// Headers need to go out before we stream the body.
 		replyFn := func(reply *structs.SnapshotResponse) error {
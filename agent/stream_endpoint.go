@@ -0,0 +1,245 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/consul/agent/cache"
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/api"
+)
+
+const (
+	// streamTopicHealth streams the health of the instances of a service, like
+	// /v1/health/service/:service.
+	streamTopicHealth = "health"
+
+	// streamTopicConnect streams the health of the Connect-capable instances
+	// of a service, like /v1/health/connect/:service.
+	streamTopicConnect = "connect"
+
+	streamFormatSSE    = "sse"
+	streamFormatNDJSON = "ndjson"
+
+	// streamKeepaliveInterval is how often a comment is sent on an idle
+	// Server-Sent Events stream, so proxies don't close it.
+	streamKeepaliveInterval = 30 * time.Second
+)
+
+// streamEvent is sent every time the results of a stream change.
+type streamEvent struct {
+	Topic string
+	Key   string
+	Index uint64
+	Nodes structs.CheckServiceNodes
+}
+
+// streamError is sent when a stream fails, before it ends.
+type streamError struct {
+	Error string
+}
+
+// Stream sends the results of a topic every time they change, as Server-Sent
+// Events or newline-delimited JSON, until the client disconnects. The results
+// come from the same materialized views as the streaming backend of the
+// health endpoints, or from blocking queries when it's disabled, so they are
+// filtered with the ACLs of the request's token by the servers.
+func (s *HTTPHandlers) Stream(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	params := req.URL.Query()
+
+	topic := params.Get("topic")
+	args := structs.ServiceSpecificRequest{
+		ServiceName: params.Get("key"),
+	}
+	switch topic {
+	case streamTopicHealth:
+	case streamTopicConnect:
+		args.Connect = true
+	case "":
+		return nil, HTTPError{StatusCode: http.StatusBadRequest, Reason: "Missing topic"}
+	default:
+		return nil, HTTPError{StatusCode: http.StatusBadRequest, Reason: fmt.Sprintf("Unsupported topic %q", topic)}
+	}
+	if args.ServiceName == "" {
+		return nil, HTTPError{StatusCode: http.StatusBadRequest, Reason: "Missing key"}
+	}
+
+	format, err := parseStreamFormat(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.parseEntMetaNoWildcard(req, &args.EnterpriseMeta); err != nil {
+		return nil, err
+	}
+	args.NodeMetaFilters = s.parseMetaFilter(req)
+	if done := s.parse(resp, req, &args.Datacenter, &args.QueryOptions); done {
+		return nil, nil
+	}
+	s.parsePeerName(req, &args)
+
+	if _, ok := params["tag"]; ok {
+		args.ServiceTags = params["tag"]
+		args.TagFilter = true
+	}
+
+	passing, err := getBoolQueryParam(params, api.HealthPassing)
+	if err != nil {
+		return nil, HTTPError{StatusCode: http.StatusBadRequest, Reason: "Invalid value for ?passing"}
+	}
+
+	// Resume from the index of the last event the client received.
+	if id := req.Header.Get("Last-Event-ID"); id != "" && args.MinQueryIndex == 0 {
+		index, err := strconv.ParseUint(id, 10, 64)
+		if err != nil {
+			return nil, HTTPError{StatusCode: http.StatusBadRequest, Reason: "Invalid Last-Event-ID"}
+		}
+		args.MinQueryIndex = index
+	}
+
+	// Reject unknown tokens before the stream starts, since the errors can't
+	// be reported with the status code afterwards.
+	if _, err := s.agent.delegate.ResolveTokenAndDefaultMeta(args.Token, &args.EnterpriseMeta, nil); err != nil {
+		return nil, err
+	}
+
+	flusher, ok := resp.(http.Flusher)
+	if !ok {
+		return nil, fmt.Errorf("Streaming not supported")
+	}
+
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+
+	updateCh := make(chan cache.UpdateEvent, 1)
+	err = s.agent.rpcClientHealth.Notify(ctx, args, topic, func(ctx context.Context, event cache.UpdateEvent) {
+		select {
+		case updateCh <- event:
+		case <-ctx.Done():
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if format == streamFormatSSE {
+		resp.Header().Set("Content-Type", "text/event-stream")
+	} else {
+		resp.Header().Set("Content-Type", "application/x-ndjson")
+	}
+	resp.Header().Set("Cache-Control", "no-cache")
+
+	// Send header so client can start streaming body
+	resp.WriteHeader(http.StatusOK)
+
+	// 0 byte write is needed before the Flush call so that if we are using
+	// a gzip stream it will go ahead and write out the HTTP response header
+	resp.Write([]byte(""))
+	flusher.Flush()
+
+	keepalive := time.NewTicker(streamKeepaliveInterval)
+	defer keepalive.Stop()
+
+	lastIndex := args.MinQueryIndex
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, nil
+
+		case <-keepalive.C:
+			if format == streamFormatSSE {
+				if _, err := fmt.Fprint(resp, ": keepalive\n\n"); err != nil {
+					return nil, nil
+				}
+				flusher.Flush()
+			}
+
+		case event := <-updateCh:
+			// The client can resume from the index of the last event it
+			// received, so the stream ends on errors rather than retrying.
+			if event.Err != nil {
+				writeStreamEvent(resp, format, "error", 0, streamError{Error: event.Err.Error()})
+				flusher.Flush()
+				return nil, nil
+			}
+
+			out, ok := event.Result.(*structs.IndexedCheckServiceNodes)
+			if !ok {
+				return nil, fmt.Errorf("unexpected result type %T", event.Result)
+			}
+
+			// Blocking queries return the results the client already has when
+			// they are resumed from its index, or time out.
+			if out.Index == lastIndex {
+				continue
+			}
+			lastIndex = out.Index
+
+			nodes := out.Nodes
+			if passing {
+				nodes = filterNonPassing(nodes)
+			}
+			s.agent.TranslateAddresses(args.Datacenter, nodes, TranslateAddressAcceptAny)
+
+			err := writeStreamEvent(resp, format, "update", out.Index, streamEvent{
+				Topic: topic,
+				Key:   args.ServiceName,
+				Index: out.Index,
+				Nodes: emptyListsForNil(nodes),
+			})
+			if err != nil {
+				return nil, nil
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// parseStreamFormat returns the format requested by the format query
+// parameter, or by the Accept header if it's not set.
+func parseStreamFormat(req *http.Request) (string, error) {
+	switch format := req.URL.Query().Get("format"); format {
+	case streamFormatSSE, streamFormatNDJSON:
+		return format, nil
+	case "":
+		if strings.Contains(req.Header.Get("Accept"), "application/x-ndjson") {
+			return streamFormatNDJSON, nil
+		}
+		return streamFormatSSE, nil
+	default:
+		return "", HTTPError{StatusCode: http.StatusBadRequest, Reason: fmt.Sprintf("Unsupported format %q", format)}
+	}
+}
+
+// writeStreamEvent writes an event of the given type in the given format. The
+// id of Server-Sent Events is the index the client can resume from, and is
+// omitted when zero.
+func writeStreamEvent(w io.Writer, format, eventType string, id uint64, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	if format == streamFormatNDJSON {
+		_, err = fmt.Fprintf(w, "%s\n", data)
+		return err
+	}
+
+	if id > 0 {
+		if _, err := fmt.Fprintf(w, "id: %d\n", id); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", eventType, data)
+	return err
+}
@@ -0,0 +1,200 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package agent
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/testrpc"
+)
+
+func TestStream_BadRequest(t *testing.T) {
+	if testing.Short() {
+		t.Skip("too slow for testing.Short")
+	}
+
+	t.Parallel()
+
+	a := NewTestAgent(t, "")
+	defer a.Shutdown()
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+	cases := map[string]struct {
+		url         string
+		lastEventID string
+		reason      string
+	}{
+		"missing topic":       {url: "/v1/stream?key=web", reason: "Missing topic"},
+		"unsupported topic":   {url: "/v1/stream?topic=kv&key=web", reason: `Unsupported topic "kv"`},
+		"missing key":         {url: "/v1/stream?topic=health", reason: "Missing key"},
+		"unsupported format":  {url: "/v1/stream?topic=health&key=web&format=xml", reason: `Unsupported format "xml"`},
+		"invalid passing":     {url: "/v1/stream?topic=health&key=web&passing=nope", reason: "Invalid value for ?passing"},
+		"invalid event id":    {url: "/v1/stream?topic=health&key=web", lastEventID: "nope", reason: "Invalid Last-Event-ID"},
+		"invalid query index": {url: "/v1/stream?topic=health&key=web&index=nope", reason: "Invalid index"},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", tc.url, nil)
+			if tc.lastEventID != "" {
+				req.Header.Set("Last-Event-ID", tc.lastEventID)
+			}
+			resp := httptest.NewRecorder()
+			a.srv.h.ServeHTTP(resp, req)
+			require.Equal(t, http.StatusBadRequest, resp.Code)
+			require.Contains(t, resp.Body.String(), tc.reason)
+		})
+	}
+}
+
+func TestStream_Health(t *testing.T) {
+	if testing.Short() {
+		t.Skip("too slow for testing.Short")
+	}
+
+	t.Parallel()
+
+	cases := map[string]string{
+		"no streaming": `use_streaming_backend = false`,
+		"streaming": `
+rpc { enable_streaming = true }
+use_streaming_backend = true
+`,
+	}
+	for name, hcl := range cases {
+		hcl := hcl
+		t.Run(name, func(t *testing.T) {
+			a := NewTestAgent(t, hcl)
+			defer a.Shutdown()
+			testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+			srv := httptest.NewServer(a.srv.h)
+			defer srv.Close()
+
+			register := func(t *testing.T, node string) {
+				args := &structs.RegisterRequest{
+					Datacenter: "dc1",
+					Node:       node,
+					Address:    "127.0.0.1",
+					Service: &structs.NodeService{
+						ID:      "web",
+						Service: "web",
+					},
+				}
+				var out struct{}
+				require.NoError(t, a.RPC(context.Background(), "Catalog.Register", args, &out))
+			}
+			register(t, "node1")
+
+			var lastIndex uint64
+			t.Run("server-sent events", func(t *testing.T) {
+				events := openStream(t, srv.URL+"/v1/stream?topic=health&key=web", nil)
+
+				event := <-events
+				require.Equal(t, "update", event.eventType)
+				require.Equal(t, "health", event.data.Topic)
+				require.Equal(t, "web", event.data.Key)
+				require.Equal(t, fmt.Sprint(event.data.Index), event.id)
+				require.Len(t, event.data.Nodes, 1)
+
+				register(t, "node2")
+
+				event = <-events
+				require.Greater(t, event.data.Index, lastIndex)
+				require.Len(t, event.data.Nodes, 2)
+				lastIndex = event.data.Index
+			})
+
+			t.Run("newline-delimited JSON resumed from an index", func(t *testing.T) {
+				header := http.Header{"Last-Event-ID": []string{fmt.Sprint(lastIndex)}}
+				events := openStream(t, srv.URL+"/v1/stream?topic=health&key=web&format=ndjson", header)
+
+				// Nothing is sent until the results change.
+				select {
+				case event := <-events:
+					t.Fatalf("unexpected event %#v", event)
+				case <-time.After(200 * time.Millisecond):
+				}
+
+				register(t, "node3")
+
+				event := <-events
+				require.Empty(t, event.id)
+				require.Greater(t, event.data.Index, lastIndex)
+				require.Len(t, event.data.Nodes, 3)
+			})
+		})
+	}
+}
+
+type testStreamEvent struct {
+	id        string
+	eventType string
+	data      streamEvent
+}
+
+// openStream reads the events of the stream of the given URL until the test
+// ends. It accepts both formats, the Server-Sent Events fields other than data
+// are left empty for newline-delimited JSON.
+func openStream(t *testing.T, url string, header http.Header) <-chan testStreamEvent {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	require.NoError(t, err)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	events := make(chan testStreamEvent, 10)
+	go func() {
+		defer close(events)
+
+		var event testStreamEvent
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(nil, 1<<20)
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "id: "):
+				event.id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "event: "):
+				event.eventType = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				line = strings.TrimPrefix(line, "data: ")
+				fallthrough
+			case strings.HasPrefix(line, "{"):
+				if err := json.Unmarshal([]byte(line), &event.data); err != nil {
+					return
+				}
+				if resp.Header.Get("Content-Type") == "text/event-stream" {
+					continue
+				}
+				fallthrough
+			case line == "":
+				if event.data.Topic != "" {
+					events <- event
+				}
+				event = testStreamEvent{}
+			}
+		}
+	}()
+	return events
+}
@@ -6,6 +6,7 @@ package structs
 import (
 	"time"
 
+	"github.com/hashicorp/raft"
 	autopilot "github.com/hashicorp/raft-autopilot"
 	"github.com/hashicorp/serf/serf"
 )
@@ -33,16 +34,16 @@ type AutopilotConfig struct {
 	// applicable with Raft protocol version 3 or higher.
 	ServerStabilizationTime time.Duration
 
-	// (Enterprise-only) RedundancyZoneTag is the node tag to use for separating
-	// servers into zones for redundancy. If left blank, this feature will be disabled.
+	// RedundancyZoneTag is the node tag to use for separating servers into
+	// zones for redundancy. If left blank, this feature will be disabled.
 	RedundancyZoneTag string
 
-	// (Enterprise-only) DisableUpgradeMigration will disable Autopilot's upgrade migration
+	// DisableUpgradeMigration will disable Autopilot's upgrade migration
 	// strategy of waiting until enough newer-versioned servers have been added to the
 	// cluster before promoting them to voters.
 	DisableUpgradeMigration bool
 
-	// (Enterprise-only) UpgradeVersionTag is the node tag to use for version info when
+	// UpgradeVersionTag is the node tag to use for version info when
 	// performing upgrade migrations. If left blank, the Consul version will be used.
 	UpgradeVersionTag string
 
@@ -65,6 +66,112 @@ func (c *AutopilotConfig) ToAutopilotLibraryConfig() *autopilot.Config {
 	}
 }
 
+// AutopilotServerExt is the information that Consul's autopilot promoter
+// tracks for each server, in addition to what the autopilot library tracks.
+type AutopilotServerExt struct {
+	// ReadReplica is true if the server is a read replica, which is never
+	// promoted to a voter.
+	ReadReplica bool
+
+	// RedundancyZone is the value of the redundancy zone tag in the node meta
+	// of the server.
+	RedundancyZone string
+
+	// UpgradeVersion is the value of the upgrade version tag in the node meta
+	// of the server, or its Consul version if there is no tag.
+	UpgradeVersion string
+}
+
+// AutopilotStateExt is the information that Consul's autopilot promoter adds
+// to the autopilot state.
+type AutopilotStateExt struct {
+	// OptimisticFailureTolerance is the number of healthy servers that could
+	// be lost without an outage occurring, assuming that autopilot promotes
+	// the standby servers of redundancy zones in time.
+	OptimisticFailureTolerance int
+
+	// RedundancyZones is the state of each redundancy zone. It's only set
+	// when the redundancy zone tag is configured.
+	RedundancyZones map[string]AutopilotZone
+
+	// Upgrade is the state of the upgrade migration.
+	Upgrade *AutopilotUpgrade
+}
+
+// AutopilotZone is the state of a redundancy zone.
+type AutopilotZone struct {
+	// Servers are all the servers in the zone, except read replicas.
+	Servers []raft.ServerID
+
+	// Voters are the servers in the zone which have voting rights.
+	Voters []raft.ServerID
+
+	// FailureTolerance is the number of servers in the zone that could fail
+	// while the zone still has a healthy server to be its voter.
+	FailureTolerance int
+}
+
+type AutopilotUpgradeStatus string
+
+const (
+	// AutopilotUpgradeIdle is the status when all the servers are running
+	// the same version.
+	AutopilotUpgradeIdle AutopilotUpgradeStatus = "idle"
+
+	// AutopilotUpgradeAwaitNewVoters is the status when there are not yet
+	// enough healthy servers of the target version to replace the voters.
+	AutopilotUpgradeAwaitNewVoters AutopilotUpgradeStatus = "await-new-voters"
+
+	// AutopilotUpgradePromoting is the status when servers of the target
+	// version are being promoted.
+	AutopilotUpgradePromoting AutopilotUpgradeStatus = "promoting"
+
+	// AutopilotUpgradeDemoting is the status when servers of other versions
+	// are being demoted.
+	AutopilotUpgradeDemoting AutopilotUpgradeStatus = "demoting"
+
+	// AutopilotUpgradeLeaderTransfer is the status when leadership is being
+	// transferred from a server of another version to a server of the target
+	// version.
+	AutopilotUpgradeLeaderTransfer AutopilotUpgradeStatus = "leader-transfer"
+
+	// AutopilotUpgradeAwaitNewServers is the status when the servers of other
+	// versions have been demoted, but there are fewer servers of the target
+	// version than of other versions.
+	AutopilotUpgradeAwaitNewServers AutopilotUpgradeStatus = "await-new-servers"
+
+	// AutopilotUpgradeAwaitServerRemoval is the status when the servers of
+	// other versions have been demoted and are waiting to be removed.
+	AutopilotUpgradeAwaitServerRemoval AutopilotUpgradeStatus = "await-server-removal"
+
+	// AutopilotUpgradeDisabled is the status when upgrade migrations are
+	// disabled in the autopilot configuration.
+	AutopilotUpgradeDisabled AutopilotUpgradeStatus = "disabled"
+)
+
+// AutopilotUpgrade is the state of an upgrade migration. The target version
+// is the highest version of the servers.
+type AutopilotUpgrade struct {
+	Status                    AutopilotUpgradeStatus
+	TargetVersion             string
+	TargetVersionVoters       []raft.ServerID
+	TargetVersionNonVoters    []raft.ServerID
+	TargetVersionReadReplicas []raft.ServerID
+	OtherVersionVoters        []raft.ServerID
+	OtherVersionNonVoters     []raft.ServerID
+	OtherVersionReadReplicas  []raft.ServerID
+	RedundancyZones           map[string]AutopilotZoneUpgradeVersions
+}
+
+// AutopilotZoneUpgradeVersions are the servers of a redundancy zone by
+// version during an upgrade migration.
+type AutopilotZoneUpgradeVersions struct {
+	TargetVersionVoters    []raft.ServerID
+	TargetVersionNonVoters []raft.ServerID
+	OtherVersionVoters     []raft.ServerID
+	OtherVersionNonVoters  []raft.ServerID
+}
+
 // AutopilotHealthReply is a representation of the overall health of the cluster
 type AutopilotHealthReply struct {
 	// Healthy is true if all the servers in the cluster are healthy.
@@ -128,6 +235,21 @@ type RaftStats struct {
 
 	// LastIndex is the last log index this server has a record of in its Raft log.
 	LastIndex uint64
+
+	// FSMApplyTime is the recent average time this server takes to apply a
+	// log to its state, or the time taken so far by an apply in progress when
+	// that is longer.
+	FSMApplyTime time.Duration
+
+	// StoreLogsTime is the recent average time this server takes to write
+	// logs to its log store and sync them to disk, or the time taken so far by
+	// a write in progress when that is longer.
+	StoreLogsTime time.Duration
+
+	// CommitTime is the recent average time between this server submitting a
+	// log as the leader and the log being committed and applied. It's only
+	// set on the leader.
+	CommitTime time.Duration
 }
 
 func (s *RaftStats) ToAutopilotServerStats() *autopilot.ServerStats {
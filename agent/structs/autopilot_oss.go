@@ -6,6 +6,8 @@
 
 package structs
 
+// autopilotConfigExt passes the redundancy zone and upgrade migration
+// settings on to the promoter.
 func (c *AutopilotConfig) autopilotConfigExt() interface{} {
-	return nil
+	return c
 }
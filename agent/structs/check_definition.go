@@ -45,6 +45,7 @@ type CheckDefinition struct {
 	OSService                      string
 	TLSServerName                  string
 	TLSSkipVerify                  bool
+	UseConnectTLS                  bool
 	AliasNode                      string
 	AliasService                   string
 	Timeout                        time.Duration
@@ -80,6 +81,7 @@ func (t *CheckDefinition) UnmarshalJSON(data []byte) (err error) {
 		ServiceIDSnake                      string      `json:"service_id"`
 		H2PingUseTLSSnake                   bool        `json:"h2ping_use_tls"`
 		DisableRedirectsSnake               bool        `json:"disable_redirects"`
+		UseConnectTLSSnake                  bool        `json:"use_connect_tls"`
 
 		*Alias
 	}{
@@ -128,6 +130,9 @@ func (t *CheckDefinition) UnmarshalJSON(data []byte) (err error) {
 	if aux.DisableRedirectsSnake {
 		t.DisableRedirects = aux.DisableRedirectsSnake
 	}
+	if aux.UseConnectTLSSnake {
+		t.UseConnectTLS = aux.UseConnectTLSSnake
+	}
 
 	if (aux.H2PING != "" && !aux.H2PingUseTLSSnake) || (aux.H2PING == "" && aux.H2PingUseTLSSnake) {
 		t.H2PingUseTLS = aux.H2PingUseTLSSnake
@@ -227,6 +232,7 @@ func (c *CheckDefinition) CheckType() *CheckType {
 		OSService:                      c.OSService,
 		TLSServerName:                  c.TLSServerName,
 		TLSSkipVerify:                  c.TLSSkipVerify,
+		UseConnectTLS:                  c.UseConnectTLS,
 		Timeout:                        c.Timeout,
 		TTL:                            c.TTL,
 		SuccessBeforePassing:           c.SuccessBeforePassing,
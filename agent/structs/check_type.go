@@ -53,6 +53,7 @@ type CheckType struct {
 	OSService              string
 	TLSServerName          string
 	TLSSkipVerify          bool
+	UseConnectTLS          bool
 	Timeout                time.Duration
 	TTL                    time.Duration
 	SuccessBeforePassing   int
@@ -89,6 +90,7 @@ func (t *CheckType) UnmarshalJSON(data []byte) (err error) {
 		TLSSkipVerifySnake                  bool        `json:"tls_skip_verify"`
 		GRPCUseTLSSnake                     bool        `json:"grpc_use_tls"`
 		H2PingUseTLSSnake                   bool        `json:"h2ping_use_tls"`
+		UseConnectTLSSnake                  bool        `json:"use_connect_tls"`
 
 		// These are going to be ignored but since we are disallowing unknown fields
 		// during parsing we have to be explicit about parsing but not using these.
@@ -134,6 +136,9 @@ func (t *CheckType) UnmarshalJSON(data []byte) (err error) {
 	if aux.GRPCUseTLSSnake {
 		t.GRPCUseTLS = aux.GRPCUseTLSSnake
 	}
+	if aux.UseConnectTLSSnake {
+		t.UseConnectTLS = aux.UseConnectTLSSnake
+	}
 	if aux.Interval != nil {
 		switch v := aux.Interval.(type) {
 		case string:
@@ -207,6 +212,9 @@ func (c *CheckType) Validate() error {
 	if c.FailuresBeforeWarning > c.FailuresBeforeCritical {
 		return fmt.Errorf("FailuresBeforeWarning can't be higher than FailuresBeforeCritical")
 	}
+	if c.UseConnectTLS && c.HTTP == "" && c.GRPC == "" {
+		return fmt.Errorf("UseConnectTLS is only supported for HTTP and GRPC checks")
+	}
 
 	return nil
 }
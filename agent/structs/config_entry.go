@@ -45,6 +45,8 @@ const (
 	TCPRoute           string = "tcp-route"
 	// TODO: decide if we want to highlight 'ip' keyword in the name of RateLimitIPConfig
 	RateLimitIPConfig string = "control-plane-request-limit"
+	KVPrefix          string = "kv-prefix"
+	RPCRateLimit      string = "rpc-rate-limit"
 
 	ProxyConfigGlobal string = "global"
 	MeshConfigMesh    string = "mesh"
@@ -72,6 +74,8 @@ var AllConfigEntryKinds = []string{
 	TCPRoute,
 	InlineCertificate,
 	RateLimitIPConfig,
+	KVPrefix,
+	RPCRateLimit,
 }
 
 // ConfigEntry is the interface for centralized configuration stored in Raft.
@@ -700,6 +704,10 @@ func MakeConfigEntry(kind, name string) (ConfigEntry, error) {
 		return &HTTPRouteConfigEntry{Name: name}, nil
 	case TCPRoute:
 		return &TCPRouteConfigEntry{Name: name}, nil
+	case KVPrefix:
+		return &KVPrefixConfigEntry{Name: name}, nil
+	case RPCRateLimit:
+		return &RPCRateLimitConfigEntry{Name: name}, nil
 	default:
 		return nil, fmt.Errorf("invalid config entry kind: %s", kind)
 	}
@@ -0,0 +1,317 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package structs
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/xeipuuv/gojsonschema"
+
+	"github.com/hashicorp/consul/acl"
+	"github.com/hashicorp/consul/lib"
+)
+
+// KVPrefixConfigEntry configures the behavior of the KV store for the keys
+// under a prefix. When the prefixes of several entries match a key, the entry
+// with the longest prefix applies.
+type KVPrefixConfigEntry struct {
+	// Kind of config entry. This will be set to structs.KVPrefix.
+	Kind string
+
+	// Name is used to identify the config entry.
+	Name string
+
+	// Prefix is the key prefix the config entry applies to. An empty prefix
+	// matches all the keys.
+	Prefix string
+
+	// History enables the revision history of the keys under the prefix.
+	History *KVHistoryConfig `json:",omitempty"`
+
+	// Schema is a JSON Schema document that the values written to the keys
+	// under the prefix must conform to.
+	Schema string `json:",omitempty"`
+
+	// Quota limits the number of keys and bytes under the prefix.
+	Quota *KVQuotaConfig `json:",omitempty"`
+
+	Meta               map[string]string `json:",omitempty"`
+	acl.EnterpriseMeta `hcl:",squash" mapstructure:",squash"`
+	RaftIndex
+
+	// compiled caches the compiled Schema, so it isn't compiled again for
+	// each write. It holds a *kvPrefixSchema, and is set lazily since the
+	// entries decoded from Raft and snapshots aren't normalized.
+	compiled atomic.Value
+}
+
+// kvPrefixSchema is a compiled Schema, along with the source it was compiled
+// from so that it isn't used if the Schema of the entry is changed.
+type kvPrefixSchema struct {
+	source string
+	schema *gojsonschema.Schema
+	err    error
+}
+
+// KVHistoryConfig bounds the revision history kept for each key. Revisions are
+// removed once either limit is exceeded.
+type KVHistoryConfig struct {
+	// MaxRevisions is the maximum number of previous revisions kept per key.
+	MaxRevisions int `json:",omitempty" alias:"max_revisions"`
+
+	// MaxAge is how long a revision is kept after it was superseded.
+	MaxAge time.Duration `json:",omitempty" alias:"max_age"`
+}
+
+// KVQuotaConfig limits the keys that a kv-prefix config entry applies to.
+// Writes that would exceed either limit are rejected.
+type KVQuotaConfig struct {
+	// MaxKeys is the maximum number of keys.
+	MaxKeys int `json:",omitempty" alias:"max_keys"`
+
+	// MaxBytes is the maximum total size of the keys and their values.
+	MaxBytes int `json:",omitempty" alias:"max_bytes"`
+}
+
+// KVPrefixUsage is the usage of the keys that a kv-prefix config entry
+// applies to.
+type KVPrefixUsage struct {
+	// Name is the name of the kv-prefix config entry.
+	Name   string
+	Prefix string
+
+	// Keys is the number of keys the config entry applies to, and Bytes their
+	// total size including the size of the keys.
+	Keys  int
+	Bytes int
+
+	// MaxKeys and MaxBytes are the limits of the quota of the config entry, if
+	// any.
+	MaxKeys  int `json:",omitempty"`
+	MaxBytes int `json:",omitempty"`
+
+	acl.EnterpriseMeta
+}
+
+func (e *KVPrefixConfigEntry) GetKind() string            { return KVPrefix }
+func (e *KVPrefixConfigEntry) GetName() string            { return e.Name }
+func (e *KVPrefixConfigEntry) GetMeta() map[string]string { return e.Meta }
+func (e *KVPrefixConfigEntry) GetEnterpriseMeta() *acl.EnterpriseMeta {
+	return &e.EnterpriseMeta
+}
+func (e *KVPrefixConfigEntry) GetRaftIndex() *RaftIndex { return &e.RaftIndex }
+
+func (e *KVPrefixConfigEntry) Normalize() error {
+	if e == nil {
+		return fmt.Errorf("config entry is nil")
+	}
+
+	e.Kind = KVPrefix
+	e.EnterpriseMeta.Normalize()
+	return nil
+}
+
+func (e *KVPrefixConfigEntry) Validate() error {
+	if e == nil {
+		return fmt.Errorf("config entry is nil")
+	}
+	if e.Name == "" {
+		return fmt.Errorf("Name is required")
+	}
+	if err := validateConfigEntryMeta(e.Meta); err != nil {
+		return err
+	}
+
+	if e.History != nil {
+		if e.History.MaxRevisions < 0 {
+			return fmt.Errorf("History.MaxRevisions must not be negative")
+		}
+		if e.History.MaxAge < 0 {
+			return fmt.Errorf("History.MaxAge must not be negative")
+		}
+		if e.History.MaxRevisions == 0 && e.History.MaxAge == 0 {
+			return fmt.Errorf("History requires MaxRevisions or MaxAge to be set")
+		}
+	}
+
+	if e.Quota != nil {
+		if e.Quota.MaxKeys < 0 {
+			return fmt.Errorf("Quota.MaxKeys must not be negative")
+		}
+		if e.Quota.MaxBytes < 0 {
+			return fmt.Errorf("Quota.MaxBytes must not be negative")
+		}
+		if e.Quota.MaxKeys == 0 && e.Quota.MaxBytes == 0 {
+			return fmt.Errorf("Quota requires MaxKeys or MaxBytes to be set")
+		}
+	}
+
+	if e.Schema != "" {
+		if _, err := compileKVPrefixSchema(e.Schema); err != nil {
+			return fmt.Errorf("Schema is invalid: %v", err)
+		}
+	}
+	return nil
+}
+
+// HistoryEnabled returns whether the revision history of the keys under the
+// prefix is kept.
+func (e *KVPrefixConfigEntry) HistoryEnabled() bool {
+	return e != nil && e.History != nil && (e.History.MaxRevisions > 0 || e.History.MaxAge > 0)
+}
+
+// ValidateValue returns an error if the value written to the key doesn't
+// conform to the schema of the config entry. Keys ending in "/" with no value,
+// which are used as folders, are not validated.
+//
+// It must only be called before writes are submitted to Raft, never when they
+// are applied, since the result could differ between servers running different
+// versions of the validator.
+func (e *KVPrefixConfigEntry) ValidateValue(key string, value []byte) error {
+	if e == nil || e.Schema == "" {
+		return nil
+	}
+	if len(value) == 0 && strings.HasSuffix(key, "/") {
+		return nil
+	}
+
+	schema, err := e.compiledSchema()
+	if err != nil {
+		return fmt.Errorf("Schema of kv-prefix config entry %q is invalid: %v", e.Name, err)
+	}
+
+	// The loader only decodes the first JSON value, so trailing data has to
+	// be rejected separately.
+	if !json.Valid(value) {
+		return fmt.Errorf("Value of key %q %s %q: value is not valid JSON", key, errKVSchemaViolation, e.Name)
+	}
+	result, err := schema.Validate(gojsonschema.NewBytesLoader(value))
+	if err != nil {
+		return fmt.Errorf("Value of key %q %s %q: %v", key, errKVSchemaViolation, e.Name, err)
+	}
+	if !result.Valid() {
+		return fmt.Errorf("Value of key %q %s %q: %s", key, errKVSchemaViolation, e.Name, result.Errors()[0])
+	}
+	return nil
+}
+
+// compiledSchema returns the compiled Schema of the config entry, compiling
+// it on first use.
+func (e *KVPrefixConfigEntry) compiledSchema() (*gojsonschema.Schema, error) {
+	if c, ok := e.compiled.Load().(*kvPrefixSchema); ok && c.source == e.Schema {
+		return c.schema, c.err
+	}
+	schema, err := compileKVPrefixSchema(e.Schema)
+	e.compiled.Store(&kvPrefixSchema{source: e.Schema, schema: schema, err: err})
+	return schema, err
+}
+
+// compileKVPrefixSchema compiles a JSON Schema. References to other
+// documents are rejected, since resolving them would make the servers fetch
+// them over the network.
+func compileKVPrefixSchema(source string) (*gojsonschema.Schema, error) {
+	var doc interface{}
+	if err := json.Unmarshal([]byte(source), &doc); err != nil {
+		return nil, err
+	}
+	if err := validateKVPrefixSchemaRefs(doc); err != nil {
+		return nil, err
+	}
+	return gojsonschema.NewSchema(gojsonschema.NewStringLoader(source))
+}
+
+func validateKVPrefixSchemaRefs(v interface{}) error {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			if ref, ok := child.(string); ok && k == "$ref" && !strings.HasPrefix(ref, "#") {
+				return fmt.Errorf("$ref %q is not supported, only references within the schema are", ref)
+			}
+			if err := validateKVPrefixSchemaRefs(child); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, child := range v {
+			if err := validateKVPrefixSchemaRefs(child); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// CheckQuota returns an error if a write to the key that changes the usage of
+// the config entry by the given number of keys and bytes would exceed its
+// quota. Writes that don't increase the usage are allowed even when the usage
+// already exceeds the quota, so that it can be brought back under the limit.
+func (e *KVPrefixConfigEntry) CheckQuota(key string, usage KVPrefixUsage, keysDelta, bytesDelta int) error {
+	if e == nil || e.Quota == nil {
+		return nil
+	}
+	if max := e.Quota.MaxKeys; max > 0 && keysDelta > 0 && usage.Keys+keysDelta > max {
+		return fmt.Errorf("Writing key %q %s %q: %d of %d keys used",
+			key, errKVQuotaExceeded, e.Name, usage.Keys, max)
+	}
+	if max := e.Quota.MaxBytes; max > 0 && bytesDelta > 0 && usage.Bytes+bytesDelta > max {
+		return fmt.Errorf("Writing key %q %s %q: %d of %d bytes used, the write needs %d more",
+			key, errKVQuotaExceeded, e.Name, usage.Bytes, max, bytesDelta)
+	}
+	return nil
+}
+
+// Matches returns whether the config entry applies to the key.
+func (e *KVPrefixConfigEntry) Matches(key string) bool {
+	return strings.HasPrefix(key, e.Prefix)
+}
+
+func (e *KVPrefixConfigEntry) CanRead(authz acl.Authorizer) error {
+	var authzContext acl.AuthorizerContext
+	e.FillAuthzContext(&authzContext)
+	return authz.ToAllowAuthorizer().OperatorReadAllowed(&authzContext)
+}
+
+func (e *KVPrefixConfigEntry) CanWrite(authz acl.Authorizer) error {
+	var authzContext acl.AuthorizerContext
+	e.FillAuthzContext(&authzContext)
+	return authz.ToAllowAuthorizer().OperatorWriteAllowed(&authzContext)
+}
+
+func (c *KVHistoryConfig) MarshalJSON() ([]byte, error) {
+	type Alias KVHistoryConfig
+	exported := &struct {
+		MaxAge string `json:",omitempty"`
+		*Alias
+	}{
+		Alias: (*Alias)(c),
+	}
+	if c.MaxAge != 0 {
+		exported.MaxAge = c.MaxAge.String()
+	}
+	return json.Marshal(exported)
+}
+
+func (c *KVHistoryConfig) UnmarshalJSON(data []byte) error {
+	type Alias KVHistoryConfig
+	aux := &struct {
+		MaxAge string
+		*Alias
+	}{
+		Alias: (*Alias)(c),
+	}
+	if err := lib.UnmarshalJSON(data, &aux); err != nil {
+		return err
+	}
+	if aux.MaxAge != "" {
+		var err error
+		if c.MaxAge, err = time.ParseDuration(aux.MaxAge); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package structs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKVPrefixConfigEntry(t *testing.T) {
+	cases := map[string]configEntryTestcase{
+		"name is required": {
+			entry:       &KVPrefixConfigEntry{Prefix: "config/"},
+			validateErr: "Name is required",
+		},
+		"no history": {
+			entry: &KVPrefixConfigEntry{Name: "config", Prefix: "config/"},
+			expected: &KVPrefixConfigEntry{
+				Kind:   KVPrefix,
+				Name:   "config",
+				Prefix: "config/",
+			},
+		},
+		"history": {
+			entry: &KVPrefixConfigEntry{
+				Name:    "config",
+				Prefix:  "config/",
+				History: &KVHistoryConfig{MaxRevisions: 5, MaxAge: time.Hour},
+			},
+			expected: &KVPrefixConfigEntry{
+				Kind:    KVPrefix,
+				Name:    "config",
+				Prefix:  "config/",
+				History: &KVHistoryConfig{MaxRevisions: 5, MaxAge: time.Hour},
+			},
+		},
+		"empty history": {
+			entry: &KVPrefixConfigEntry{
+				Name:    "config",
+				History: &KVHistoryConfig{},
+			},
+			validateErr: "History requires MaxRevisions or MaxAge to be set",
+		},
+		"negative max revisions": {
+			entry: &KVPrefixConfigEntry{
+				Name:    "config",
+				History: &KVHistoryConfig{MaxRevisions: -1},
+			},
+			validateErr: "History.MaxRevisions must not be negative",
+		},
+		"negative max age": {
+			entry: &KVPrefixConfigEntry{
+				Name:    "config",
+				History: &KVHistoryConfig{MaxRevisions: 1, MaxAge: -time.Second},
+			},
+			validateErr: "History.MaxAge must not be negative",
+		},
+		"schema": {
+			entry: &KVPrefixConfigEntry{
+				Name:   "config",
+				Schema: `{"type": "object"}`,
+			},
+			expected: &KVPrefixConfigEntry{
+				Kind:   KVPrefix,
+				Name:   "config",
+				Schema: `{"type": "object"}`,
+			},
+		},
+		"invalid schema": {
+			entry: &KVPrefixConfigEntry{
+				Name:   "config",
+				Schema: `{"type": "obj"}`,
+			},
+			validateErr: `Schema is invalid: has a primitive type that is NOT VALID -- given: /obj/`,
+		},
+		"remote schema reference": {
+			entry: &KVPrefixConfigEntry{
+				Name:   "config",
+				Schema: `{"properties": {"port": {"$ref": "https://example.com/port.json"}}}`,
+			},
+			validateErr: `Schema is invalid: $ref "https://example.com/port.json" is not supported, only references within the schema are`,
+		},
+		"quota": {
+			entry: &KVPrefixConfigEntry{
+				Name:  "config",
+				Quota: &KVQuotaConfig{MaxKeys: 100},
+			},
+			expected: &KVPrefixConfigEntry{
+				Kind:  KVPrefix,
+				Name:  "config",
+				Quota: &KVQuotaConfig{MaxKeys: 100},
+			},
+		},
+		"empty quota": {
+			entry: &KVPrefixConfigEntry{
+				Name:  "config",
+				Quota: &KVQuotaConfig{},
+			},
+			validateErr: "Quota requires MaxKeys or MaxBytes to be set",
+		},
+		"negative max keys": {
+			entry: &KVPrefixConfigEntry{
+				Name:  "config",
+				Quota: &KVQuotaConfig{MaxKeys: -1},
+			},
+			validateErr: "Quota.MaxKeys must not be negative",
+		},
+		"negative max bytes": {
+			entry: &KVPrefixConfigEntry{
+				Name:  "config",
+				Quota: &KVQuotaConfig{MaxKeys: 1, MaxBytes: -1},
+			},
+			validateErr: "Quota.MaxBytes must not be negative",
+		},
+	}
+
+	testConfigEntryNormalizeAndValidate(t, cases)
+}
+
+func TestKVPrefixConfigEntry_ValidateValue(t *testing.T) {
+	var nilEntry *KVPrefixConfigEntry
+	require.NoError(t, nilEntry.ValidateValue("config/app", []byte("x")))
+
+	entry := &KVPrefixConfigEntry{Name: "config", Schema: `{"type": "object"}`}
+	require.NoError(t, entry.ValidateValue("config/app", []byte(`{}`)))
+	require.NoError(t, entry.ValidateValue("config/app/", nil))
+
+	err := entry.ValidateValue("config/app", []byte(`[]`))
+	require.EqualError(t, err, `Value of key "config/app" does not conform to the schema of kv-prefix config entry "config": (root): Invalid type. Expected: object, given: array`)
+	require.True(t, IsErrKVSchemaViolation(err))
+
+	// The compiled schema is cached, but not used once the schema changes.
+	entry.Schema = `{"type": "array"}`
+	require.NoError(t, entry.ValidateValue("config/app", []byte(`[]`)))
+	require.Error(t, entry.ValidateValue("config/app", []byte(`{}`)))
+
+	// References within the schema are supported.
+	entry.Schema = `{"definitions": {"port": {"type": "integer"}}, "properties": {"port": {"$ref": "#/definitions/port"}}}`
+	require.NoError(t, entry.ValidateValue("config/app", []byte(`{"port": 80}`)))
+	require.ErrorContains(t, entry.ValidateValue("config/app", []byte(`{"port": "80"}`)), "port: Invalid type. Expected: integer, given: string")
+}
+
+func TestKVPrefixConfigEntry_CheckQuota(t *testing.T) {
+	var nilEntry *KVPrefixConfigEntry
+	require.NoError(t, nilEntry.CheckQuota("config/app", KVPrefixUsage{Keys: 10}, 1, 10))
+
+	entry := &KVPrefixConfigEntry{Name: "config", Quota: &KVQuotaConfig{MaxKeys: 2, MaxBytes: 100}}
+	require.NoError(t, entry.CheckQuota("config/app", KVPrefixUsage{Keys: 1, Bytes: 50}, 1, 50))
+
+	err := entry.CheckQuota("config/app", KVPrefixUsage{Keys: 2, Bytes: 50}, 1, 10)
+	require.EqualError(t, err, `Writing key "config/app" would exceed the quota of kv-prefix config entry "config": 2 of 2 keys used`)
+	require.True(t, IsErrKVQuotaExceeded(err))
+
+	err = entry.CheckQuota("config/app", KVPrefixUsage{Keys: 1, Bytes: 95}, 0, 10)
+	require.EqualError(t, err, `Writing key "config/app" would exceed the quota of kv-prefix config entry "config": 95 of 100 bytes used, the write needs 10 more`)
+	require.True(t, IsErrKVQuotaExceeded(err))
+
+	// Writes that don't increase the usage are allowed over the quota.
+	require.NoError(t, entry.CheckQuota("config/app", KVPrefixUsage{Keys: 3, Bytes: 150}, 0, -10))
+}
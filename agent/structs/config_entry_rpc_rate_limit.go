@@ -0,0 +1,173 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package structs
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/consul/acl"
+)
+
+const (
+	RPCRateLimitModePermissive = "permissive"
+	RPCRateLimitModeEnforcing  = "enforcing"
+	RPCRateLimitModeDisabled   = "disabled"
+
+	RPCRateLimitKeyToken    = "token"
+	RPCRateLimitKeyIdentity = "identity"
+	RPCRateLimitKeyEndpoint = "endpoint"
+)
+
+// RPCRateLimitConfigEntry defines a rate limit that every server applies to
+// the RPCs it receives, based on the RPC endpoint and the ACL token of the
+// request. Unlike the global request limits, it can give each token, or each
+// identity, a bucket of its own so a single client can't use up the capacity
+// of the servers.
+type RPCRateLimitConfigEntry struct {
+	// Kind of config entry. This will be set to structs.RPCRateLimit.
+	Kind string
+
+	// Name is used to identify the config entry.
+	Name string
+
+	// Mode is the action taken when the limit is exceeded: "permissive" only
+	// logs the request, "enforcing" rejects it, and "disabled" turns the limit
+	// off. It defaults to "enforcing".
+	Mode string
+
+	// Endpoints restricts the limit to these RPC endpoints, like
+	// "Catalog.Register" or
+	// "/hashicorp.consul.dataplane.DataplaneService/GetEnvoyBootstrapParams".
+	// A name ending with "*" matches all the endpoints starting with the rest
+	// of the name. The limit applies to all the endpoints when it's empty.
+	Endpoints []string `json:",omitempty"`
+
+	// Tokens restricts the limit to the requests made with the ACL tokens with
+	// these accessor IDs.
+	Tokens []string `json:",omitempty"`
+
+	// Identities restricts the limit to the requests made with the tokens of
+	// these identities, like "service:web" or "node:node1". The identity of a
+	// token without a service or node identity is "token:<accessor ID>". The
+	// limit applies to all the requests when Tokens and Identities are empty.
+	Identities []string `json:",omitempty"`
+
+	// KeyBy lists the properties of the requests that get a bucket of their
+	// own: "token", "identity" and "endpoint". Without keys, all the matching
+	// requests share a single bucket.
+	KeyBy []string `json:",omitempty" alias:"key_by"`
+
+	// MetricsIncludeTokens reports the accessor ID or identity of the tokens
+	// that exceed the limit in the metrics, when KeyBy contains "token" or
+	// "identity". It's off by default since the number of tokens is unbounded.
+	MetricsIncludeTokens bool `json:",omitempty" alias:"metrics_include_tokens"`
+
+	// ReadRate and WriteRate are the number of read and write requests per
+	// second allowed in each bucket, on each server. Zero means no limit.
+	ReadRate  float64 `json:",omitempty" alias:"read_rate"`
+	WriteRate float64 `json:",omitempty" alias:"write_rate"`
+
+	Meta               map[string]string `json:",omitempty"`
+	acl.EnterpriseMeta `hcl:",squash" mapstructure:",squash"`
+	RaftIndex
+}
+
+func (e *RPCRateLimitConfigEntry) GetKind() string            { return RPCRateLimit }
+func (e *RPCRateLimitConfigEntry) GetName() string            { return e.Name }
+func (e *RPCRateLimitConfigEntry) GetMeta() map[string]string { return e.Meta }
+func (e *RPCRateLimitConfigEntry) GetEnterpriseMeta() *acl.EnterpriseMeta {
+	return &e.EnterpriseMeta
+}
+func (e *RPCRateLimitConfigEntry) GetRaftIndex() *RaftIndex { return &e.RaftIndex }
+
+func (e *RPCRateLimitConfigEntry) Normalize() error {
+	if e == nil {
+		return fmt.Errorf("config entry is nil")
+	}
+
+	e.Kind = RPCRateLimit
+	if e.Mode == "" {
+		e.Mode = RPCRateLimitModeEnforcing
+	}
+	e.EnterpriseMeta.Normalize()
+	return nil
+}
+
+func (e *RPCRateLimitConfigEntry) Validate() error {
+	if e == nil {
+		return fmt.Errorf("config entry is nil")
+	}
+	if e.Name == "" {
+		return fmt.Errorf("Name is required")
+	}
+	if err := validateConfigEntryMeta(e.Meta); err != nil {
+		return err
+	}
+
+	switch e.Mode {
+	case "", RPCRateLimitModePermissive, RPCRateLimitModeEnforcing, RPCRateLimitModeDisabled:
+	default:
+		return fmt.Errorf("Mode must be one of %q, %q or %q", RPCRateLimitModePermissive, RPCRateLimitModeEnforcing, RPCRateLimitModeDisabled)
+	}
+
+	if e.ReadRate < 0 {
+		return fmt.Errorf("ReadRate must not be negative")
+	}
+	if e.WriteRate < 0 {
+		return fmt.Errorf("WriteRate must not be negative")
+	}
+	if e.ReadRate == 0 && e.WriteRate == 0 {
+		return fmt.Errorf("ReadRate or WriteRate must be set")
+	}
+
+	for _, endpoint := range e.Endpoints {
+		if endpoint == "" {
+			return fmt.Errorf("Endpoints must not contain an empty name")
+		}
+		if i := strings.Index(endpoint, "*"); i >= 0 && i != len(endpoint)-1 {
+			return fmt.Errorf("Endpoint %q is invalid: a wildcard is only allowed at the end", endpoint)
+		}
+	}
+	for _, token := range e.Tokens {
+		if token == "" {
+			return fmt.Errorf("Tokens must not contain an empty accessor ID")
+		}
+	}
+	for _, identity := range e.Identities {
+		kind, name, _ := strings.Cut(identity, ":")
+		if (kind != "service" && kind != "node" && kind != "token") || name == "" {
+			return fmt.Errorf("Identity %q is invalid: it must be \"service:<name>\", \"node:<name>\" or \"token:<accessor ID>\"", identity)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, key := range e.KeyBy {
+		switch key {
+		case RPCRateLimitKeyToken, RPCRateLimitKeyIdentity, RPCRateLimitKeyEndpoint:
+		default:
+			return fmt.Errorf("KeyBy must only contain %q, %q or %q", RPCRateLimitKeyToken, RPCRateLimitKeyIdentity, RPCRateLimitKeyEndpoint)
+		}
+		if seen[key] {
+			return fmt.Errorf("KeyBy contains %q more than once", key)
+		}
+		seen[key] = true
+	}
+	if seen[RPCRateLimitKeyToken] && seen[RPCRateLimitKeyIdentity] {
+		return fmt.Errorf("KeyBy can't contain both %q and %q", RPCRateLimitKeyToken, RPCRateLimitKeyIdentity)
+	}
+	return nil
+}
+
+func (e *RPCRateLimitConfigEntry) CanRead(authz acl.Authorizer) error {
+	var authzContext acl.AuthorizerContext
+	e.FillAuthzContext(&authzContext)
+	return authz.ToAllowAuthorizer().OperatorReadAllowed(&authzContext)
+}
+
+func (e *RPCRateLimitConfigEntry) CanWrite(authz acl.Authorizer) error {
+	var authzContext acl.AuthorizerContext
+	e.FillAuthzContext(&authzContext)
+	return authz.ToAllowAuthorizer().OperatorWriteAllowed(&authzContext)
+}
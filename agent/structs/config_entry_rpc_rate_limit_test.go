@@ -0,0 +1,93 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package structs
+
+import (
+	"testing"
+)
+
+func TestRPCRateLimitConfigEntry(t *testing.T) {
+	cases := map[string]configEntryTestcase{
+		"name is required": {
+			entry:       &RPCRateLimitConfigEntry{WriteRate: 10},
+			validateErr: "Name is required",
+		},
+		"defaults": {
+			entry: &RPCRateLimitConfigEntry{Name: "ci", WriteRate: 10},
+			expected: &RPCRateLimitConfigEntry{
+				Kind:      RPCRateLimit,
+				Name:      "ci",
+				Mode:      RPCRateLimitModeEnforcing,
+				WriteRate: 10,
+			},
+		},
+		"per token": {
+			entry: &RPCRateLimitConfigEntry{
+				Name:       "ci",
+				Mode:       RPCRateLimitModePermissive,
+				Endpoints:  []string{"KVS.Apply", "Catalog.*"},
+				Identities: []string{"service:ci", "node:runner", "token:5f4b3a2c"},
+				KeyBy:      []string{RPCRateLimitKeyToken, RPCRateLimitKeyEndpoint},
+				ReadRate:   100,
+				WriteRate:  10,
+			},
+			expected: &RPCRateLimitConfigEntry{
+				Kind:       RPCRateLimit,
+				Name:       "ci",
+				Mode:       RPCRateLimitModePermissive,
+				Endpoints:  []string{"KVS.Apply", "Catalog.*"},
+				Identities: []string{"service:ci", "node:runner", "token:5f4b3a2c"},
+				KeyBy:      []string{RPCRateLimitKeyToken, RPCRateLimitKeyEndpoint},
+				ReadRate:   100,
+				WriteRate:  10,
+			},
+		},
+		"invalid mode": {
+			entry:       &RPCRateLimitConfigEntry{Name: "ci", Mode: "strict", WriteRate: 10},
+			validateErr: `Mode must be one of "permissive", "enforcing" or "disabled"`,
+		},
+		"no rate": {
+			entry:       &RPCRateLimitConfigEntry{Name: "ci"},
+			validateErr: "ReadRate or WriteRate must be set",
+		},
+		"negative read rate": {
+			entry:       &RPCRateLimitConfigEntry{Name: "ci", ReadRate: -1, WriteRate: 10},
+			validateErr: "ReadRate must not be negative",
+		},
+		"negative write rate": {
+			entry:       &RPCRateLimitConfigEntry{Name: "ci", ReadRate: 10, WriteRate: -1},
+			validateErr: "WriteRate must not be negative",
+		},
+		"empty endpoint": {
+			entry:       &RPCRateLimitConfigEntry{Name: "ci", WriteRate: 10, Endpoints: []string{""}},
+			validateErr: "Endpoints must not contain an empty name",
+		},
+		"wildcard in the middle of an endpoint": {
+			entry:       &RPCRateLimitConfigEntry{Name: "ci", WriteRate: 10, Endpoints: []string{"KVS.*ly"}},
+			validateErr: `Endpoint "KVS.*ly" is invalid: a wildcard is only allowed at the end`,
+		},
+		"empty token": {
+			entry:       &RPCRateLimitConfigEntry{Name: "ci", WriteRate: 10, Tokens: []string{""}},
+			validateErr: "Tokens must not contain an empty accessor ID",
+		},
+		"invalid identity": {
+			entry:       &RPCRateLimitConfigEntry{Name: "ci", WriteRate: 10, Identities: []string{"web"}},
+			validateErr: `Identity "web" is invalid`,
+		},
+		"invalid key": {
+			entry:       &RPCRateLimitConfigEntry{Name: "ci", WriteRate: 10, KeyBy: []string{"ip"}},
+			validateErr: `KeyBy must only contain "token", "identity" or "endpoint"`,
+		},
+		"duplicate key": {
+			entry:       &RPCRateLimitConfigEntry{Name: "ci", WriteRate: 10, KeyBy: []string{"token", "token"}},
+			validateErr: `KeyBy contains "token" more than once`,
+		},
+		"token and identity keys": {
+			entry:       &RPCRateLimitConfigEntry{Name: "ci", WriteRate: 10, KeyBy: []string{"token", "identity"}},
+			validateErr: `KeyBy can't contain both "token" and "identity"`,
+		},
+	}
+
+	testConfigEntryNormalizeAndValidate(t, cases)
+}
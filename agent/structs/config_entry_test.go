@@ -2116,6 +2116,109 @@ func TestDecodeConfigEntry(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "kv-prefix",
+			snake: `
+				kind = "kv-prefix"
+				name = "config"
+				prefix = "config/"
+				schema = "{\"type\": \"object\"}"
+				meta {
+					"foo" = "bar"
+				}
+				history {
+					max_revisions = 10
+					max_age = "24h"
+				}
+				quota {
+					max_keys = 100
+					max_bytes = 1048576
+				}
+			`,
+			camel: `
+				Kind = "kv-prefix"
+				Name = "config"
+				Prefix = "config/"
+				Schema = "{\"type\": \"object\"}"
+				Meta {
+					"foo" = "bar"
+				}
+				History {
+					MaxRevisions = 10
+					MaxAge = "24h"
+				}
+				Quota {
+					MaxKeys = 100
+					MaxBytes = 1048576
+				}
+			`,
+			expect: &KVPrefixConfigEntry{
+				Kind:   "kv-prefix",
+				Name:   "config",
+				Prefix: "config/",
+				Schema: `{"type": "object"}`,
+				Meta: map[string]string{
+					"foo": "bar",
+				},
+				History: &KVHistoryConfig{
+					MaxRevisions: 10,
+					MaxAge:       24 * time.Hour,
+				},
+				Quota: &KVQuotaConfig{
+					MaxKeys:  100,
+					MaxBytes: 1048576,
+				},
+			},
+		},
+		{
+			name: "rpc-rate-limit",
+			snake: `
+				kind = "rpc-rate-limit"
+				name = "ci"
+				mode = "permissive"
+				endpoints = ["KVS.Apply", "Catalog.*"]
+				tokens = ["5f4b3a2c-0d8e-4b1a-9c6f-7e2d1a0b3c4d"]
+				identities = ["service:ci"]
+				key_by = ["token", "endpoint"]
+				metrics_include_tokens = true
+				read_rate = 100
+				write_rate = 10.5
+				meta {
+					"foo" = "bar"
+				}
+			`,
+			camel: `
+				Kind = "rpc-rate-limit"
+				Name = "ci"
+				Mode = "permissive"
+				Endpoints = ["KVS.Apply", "Catalog.*"]
+				Tokens = ["5f4b3a2c-0d8e-4b1a-9c6f-7e2d1a0b3c4d"]
+				Identities = ["service:ci"]
+				KeyBy = ["token", "endpoint"]
+				MetricsIncludeTokens = true
+				ReadRate = 100
+				WriteRate = 10.5
+				Meta {
+					"foo" = "bar"
+				}
+			`,
+			expect: &RPCRateLimitConfigEntry{
+				Kind:       "rpc-rate-limit",
+				Name:       "ci",
+				Mode:       "permissive",
+				Endpoints:  []string{"KVS.Apply", "Catalog.*"},
+				Tokens:     []string{"5f4b3a2c-0d8e-4b1a-9c6f-7e2d1a0b3c4d"},
+				Identities: []string{"service:ci"},
+				KeyBy:      []string{"token", "endpoint"},
+				ReadRate:   100,
+				WriteRate:  10.5,
+
+				MetricsIncludeTokens: true,
+				Meta: map[string]string{
+					"foo": "bar",
+				},
+			},
+		},
 	} {
 		tc := tc
 
@@ -19,6 +19,8 @@ const (
 	errServiceNotFound            = "Service not found: "
 	errQueryNotFound              = "Query not found"
 	errLeaderNotTracked           = "Raft leader not found in server lookup mapping"
+	errKVSchemaViolation          = "does not conform to the schema of kv-prefix config entry"
+	errKVQuotaExceeded            = "would exceed the quota of kv-prefix config entry"
 )
 
 var (
@@ -52,3 +54,11 @@ func IsErrRPCRateExceeded(err error) bool {
 func IsErrServiceNotFound(err error) bool {
 	return err != nil && strings.Contains(err.Error(), errServiceNotFound)
 }
+
+func IsErrKVSchemaViolation(err error) bool {
+	return err != nil && strings.Contains(err.Error(), errKVSchemaViolation)
+}
+
+func IsErrKVQuotaExceeded(err error) bool {
+	return err != nil && strings.Contains(err.Error(), errKVQuotaExceeded)
+}
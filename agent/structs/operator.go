@@ -5,6 +5,7 @@ package structs
 
 import (
 	"net"
+	"time"
 
 	"github.com/hashicorp/raft"
 )
@@ -31,9 +32,12 @@ type RaftServer struct {
 
 	// Voter is true if this server has a vote in the cluster. This might
 	// be false if the server is staging and still coming online, or if
-	// it's a non-voting server, which will be added in a future release of
-	// Consul.
+	// it's a read replica.
 	Voter bool
+
+	// ReadReplica is true if this server is a read replica, which never
+	// becomes a voter.
+	ReadReplica bool `json:",omitempty"`
 }
 
 // RaftConfigurationResponse is returned when querying for the current Raft
@@ -67,6 +71,126 @@ func (op *RaftRemovePeerRequest) RequestDatacenter() string {
 	return op.Datacenter
 }
 
+// The possible outcomes of the verification of a range of Raft logs.
+const (
+	// RaftLogVerificationOK means the checksum of the logs read back from the
+	// log store matched the checksum of the leader.
+	RaftLogVerificationOK = "ok"
+
+	// RaftLogVerificationFailed means a checksum didn't match the leader's,
+	// either because the logs were corrupted on their way to the server or
+	// in its log store.
+	RaftLogVerificationFailed = "failed"
+
+	// RaftLogVerificationRangeMismatch means the server didn't have all the
+	// logs of the range any more, so it couldn't verify them.
+	RaftLogVerificationRangeMismatch = "range-mismatch"
+
+	// RaftLogVerificationError means the verification couldn't complete, for
+	// example because the logs couldn't be read.
+	RaftLogVerificationError = "error"
+)
+
+// RaftLogRange is a range of Raft log indexes. Start is inclusive and End is
+// exclusive, End being the index of the checkpoint that closed the range.
+type RaftLogRange struct {
+	Start uint64
+	End   uint64
+}
+
+// RaftLogVerificationReport is the result of verifying a range of Raft logs on
+// a server.
+type RaftLogVerificationReport struct {
+	// Time is when the server finished verifying the range.
+	Time time.Time
+
+	// Range is the range of logs that was verified.
+	Range RaftLogRange
+
+	// SkippedRange is a range of logs before this one that wasn't verified
+	// because the server was still busy verifying a previous range.
+	SkippedRange *RaftLogRange `json:",omitempty"`
+
+	// Status is one of the RaftLogVerification constants.
+	Status string
+
+	// ExpectedChecksum is the checksum of the logs computed by the leader
+	// before writing them.
+	ExpectedChecksum string
+
+	// WrittenChecksum is the checksum of the logs computed by the server as it
+	// wrote them. It's empty on the leader, and on a server that didn't write
+	// the whole range since it started.
+	WrittenChecksum string `json:",omitempty"`
+
+	// ReadChecksum is the checksum of the logs read back from the log store.
+	ReadChecksum string `json:",omitempty"`
+
+	// Error describes why the verification didn't succeed.
+	Error string `json:",omitempty"`
+
+	// Elapsed is how long it took to verify the range.
+	Elapsed time.Duration
+}
+
+// RaftLogVerificationStatus has the state of the Raft log verification on a
+// single server.
+type RaftLogVerificationStatus struct {
+	// ID, Node and Address identify the server, like in RaftServer.
+	ID      raft.ServerID
+	Node    string
+	Address raft.ServerAddress
+
+	// Enabled is whether log verification is enabled on the server.
+	Enabled bool
+
+	// Error is set when the status couldn't be fetched from the server.
+	Error string `json:",omitempty"`
+
+	// RangesVerified and RangesFailed count the ranges the server verified,
+	// and the ones that failed verification, since it started.
+	RangesVerified uint64
+	RangesFailed   uint64
+
+	// LastReport is the most recent verification report of the server.
+	LastReport *RaftLogVerificationReport `json:",omitempty"`
+
+	// LastFailure is the most recent report of a range that failed
+	// verification, which is kept after later ranges succeed.
+	LastFailure *RaftLogVerificationReport `json:",omitempty"`
+}
+
+// RaftLogVerificationResponse is returned when querying for the state of the
+// Raft log verification.
+type RaftLogVerificationResponse struct {
+	// Servers has the verification state of each server in the Raft
+	// configuration.
+	Servers []*RaftLogVerificationStatus
+}
+
+// RaftLogVerificationCheckpointRequest is used to write a log verification
+// checkpoint to the Raft log on demand.
+type RaftLogVerificationCheckpointRequest struct {
+	// Datacenter is the target this request is intended for.
+	Datacenter string
+
+	// WriteRequest holds the ACL token to go along with this request.
+	WriteRequest
+}
+
+// RequestDatacenter returns the datacenter for a given request.
+func (op *RaftLogVerificationCheckpointRequest) RequestDatacenter() string {
+	return op.Datacenter
+}
+
+// RaftLogVerificationCheckpointResponse is returned after writing a log
+// verification checkpoint.
+type RaftLogVerificationCheckpointResponse struct {
+	// Index is the Raft index of the checkpoint. Servers report on the range
+	// ending with it once they've verified it.
+	Index uint64
+}
+
 // AutopilotSetConfigRequest is used by the Operator endpoint to update the
 // current Autopilot configuration of the cluster.
 type AutopilotSetConfigRequest struct {
@@ -32,6 +32,17 @@ type SnapshotRequest struct {
 
 	// Op is the operation code for the RPC.
 	Op SnapshotOp
+
+	// Compression is the compression of the archive, either "gzip" or
+	// "zstd". Only applies to SnapshotSave, restores detect the compression
+	// of the archive.
+	Compression string
+
+	// EncryptionKey is a base64-encoded 32-byte key, and EncryptionPassphrase
+	// a passphrase, used to encrypt the archive of a SnapshotSave or decrypt
+	// the archive of a SnapshotRestore. At most one of them may be set.
+	EncryptionKey        string
+	EncryptionPassphrase string
 }
 
 // SnapshotResponse is used header for a snapshot RPC response. This will
@@ -88,6 +88,8 @@ const (
 	PeeringSecretsWriteType                     = 40
 	RaftLogVerifierCheckpoint                   = 41 // Only used for log verifier, no-op on FSM.
 	ResourceOperationType                       = 42
+	KVRevisionPruneRequestType                  = 43
+	KVRevisionType                              = 44 // FSM snapshots only.
 )
 
 const (
@@ -156,6 +158,8 @@ var requestTypeStrings = map[MessageType]string{
 	PeeringSecretsWriteType:         "PeeringSecret",
 	RaftLogVerifierCheckpoint:       "RaftLogVerifierCheckpoint",
 	ResourceOperationType:           "Resource",
+	KVRevisionPruneRequestType:      "KVRevisionPrune",
+	KVRevisionType:                  "KVRevision", // FSM snapshots only.
 }
 
 const (
@@ -1848,6 +1852,7 @@ type HealthCheckDefinition struct {
 	HTTP                           string              `json:",omitempty"`
 	TLSServerName                  string              `json:",omitempty"`
 	TLSSkipVerify                  bool                `json:",omitempty"`
+	UseConnectTLS                  bool                `json:",omitempty"`
 	Header                         map[string][]string `json:",omitempty"`
 	Method                         string              `json:",omitempty"`
 	Body                           string              `json:",omitempty"`
@@ -2014,6 +2019,7 @@ func (c *HealthCheck) CheckType() *CheckType {
 		Shell:                          c.Definition.Shell,
 		TLSServerName:                  c.Definition.TLSServerName,
 		TLSSkipVerify:                  c.Definition.TLSSkipVerify,
+		UseConnectTLS:                  c.Definition.UseConnectTLS,
 		Timeout:                        c.Definition.Timeout,
 		TTL:                            c.Definition.TTL,
 		DeregisterCriticalServiceAfter: c.Definition.DeregisterCriticalServiceAfter,
@@ -2280,9 +2286,35 @@ type IndexedServices struct {
 type Usage struct {
 	Usage map[string]ServiceUsage
 
+	// KVPrefixes is a map of datacenter to the usage of the kv-prefix config
+	// entries of the requested partition.
+	KVPrefixes map[string][]KVPrefixUsage `json:",omitempty"`
+
+	// RPCConcurrency is a map of datacenter to the state of the adaptive
+	// concurrency limits of the server that handled the request. It's only set
+	// when the limits are enabled.
+	RPCConcurrency map[string][]RPCConcurrencyUsage `json:",omitempty"`
+
 	QueryMeta
 }
 
+// RPCConcurrencyUsage is the state of the concurrency limit of a class of
+// RPCs: "read", "write" or "blocking".
+type RPCConcurrencyUsage struct {
+	Server string
+	Class  string
+	Mode   string
+
+	// Limit is the current number of RPCs of the class the server handles
+	// concurrently, and InFlight the number of RPCs being handled.
+	Limit    int
+	InFlight int
+
+	// Shed is the number of RPCs that exceeded the limit since the server
+	// started.
+	Shed uint64
+}
+
 // ServiceUsage contains all of the usage data related to services
 type ServiceUsage struct {
 	Services                 int
@@ -2587,6 +2619,10 @@ type DirEntry struct {
 	Value     []byte
 	Session   string `json:",omitempty"`
 
+	// TTL is an optional duration after which the leader deletes the entry
+	// unless it was written again. Every write replaces the TTL.
+	TTL string `json:",omitempty"`
+
 	acl.EnterpriseMeta `bexpr:"-"`
 	RaftIndex
 }
@@ -2599,6 +2635,7 @@ func (d *DirEntry) Clone() *DirEntry {
 		Flags:     d.Flags,
 		Value:     d.Value,
 		Session:   d.Session,
+		TTL:       d.TTL,
 		RaftIndex: RaftIndex{
 			CreateIndex: d.CreateIndex,
 			ModifyIndex: d.ModifyIndex,
@@ -2612,7 +2649,8 @@ func (d *DirEntry) Equal(o *DirEntry) bool {
 		d.Key == o.Key &&
 		d.Flags == o.Flags &&
 		bytes.Equal(d.Value, o.Value) &&
-		d.Session == o.Session
+		d.Session == o.Session &&
+		d.TTL == o.TTL
 }
 
 // IDValue implements the state.singleValueID interface for indexing.
@@ -2622,6 +2660,40 @@ func (d *DirEntry) IDValue() string {
 
 type DirEntries []*DirEntry
 
+// DirEntryRevision is a previous version of a KV entry. Revisions are only
+// kept for the keys whose prefix has revision history enabled by a kv-prefix
+// config entry.
+type DirEntryRevision struct {
+	DirEntry
+
+	// SupersededIndex is the index at which the entry was overwritten or
+	// deleted. The revision was the current version of the entry from its
+	// ModifyIndex until, excluding, SupersededIndex.
+	SupersededIndex uint64
+
+	// Deleted is set if the entry was deleted at SupersededIndex.
+	Deleted bool `json:",omitempty"`
+
+	// SupersededAt is approximately when the entry was superseded. It is set
+	// by the leader shortly after, up to a minute later, and is used to apply
+	// the MaxAge of the history.
+	SupersededAt time.Time `json:",omitempty"`
+}
+
+type DirEntryRevisions []*DirEntryRevision
+
+// KVRevisionPruneRequest is used by the leader to stamp new KV revisions with
+// the current time and remove the revisions that exceed their history limits.
+type KVRevisionPruneRequest struct {
+	Datacenter string
+	Now        time.Time
+	WriteRequest
+}
+
+func (r *KVRevisionPruneRequest) RequestDatacenter() string {
+	return r.Datacenter
+}
+
 // KVSRequest is used to operate on the Key-Value store
 type KVSRequest struct {
 	Datacenter string
@@ -2638,6 +2710,11 @@ func (r *KVSRequest) RequestDatacenter() string {
 type KeyRequest struct {
 	Datacenter string
 	Key        string
+
+	// AtIndex requests the version of the key that was current at the given
+	// index, which requires revision history to be enabled for the key.
+	AtIndex uint64
+
 	acl.EnterpriseMeta
 	QueryOptions
 }
@@ -2646,6 +2723,31 @@ func (r *KeyRequest) RequestDatacenter() string {
 	return r.Datacenter
 }
 
+func (r *KeyRequest) CacheInfo() cache.RequestInfo {
+	info := cache.RequestInfo{
+		Token:          r.Token,
+		Datacenter:     r.Datacenter,
+		MinIndex:       r.MinQueryIndex,
+		Timeout:        r.MaxQueryTime,
+		MaxAge:         r.MaxAge,
+		MustRevalidate: r.MustRevalidate,
+	}
+
+	v, err := hashstructure.Hash([]interface{}{
+		r.Key,
+		r.AtIndex,
+		r.EnterpriseMeta,
+	}, nil)
+	if err == nil {
+		// If there is an error, we don't set the key. A blank key forces
+		// no cache for this request so the request is forwarded directly
+		// to the server.
+		info.Key = strconv.FormatUint(v, 10)
+	}
+
+	return info
+}
+
 // KeyListRequest is used to list keys
 type KeyListRequest struct {
 	Datacenter string
@@ -2664,6 +2766,11 @@ type IndexedDirEntries struct {
 	QueryMeta
 }
 
+type IndexedDirEntryRevisions struct {
+	Revisions DirEntryRevisions
+	QueryMeta
+}
+
 type IndexedKeyList struct {
 	Keys []string
 	QueryMeta
@@ -2681,6 +2788,12 @@ const (
 	SessionTTLMultiplier = 2
 )
 
+const (
+	// KVTTLMin and KVTTLMax bound the TTL of a KV entry.
+	KVTTLMin = time.Second
+	KVTTLMax = 24 * time.Hour
+)
+
 type Sessions []*Session
 
 // Session is used to represent an open session in the KV store.
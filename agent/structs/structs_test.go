@@ -1830,6 +1830,7 @@ func TestStructs_DirEntry_Clone(t *testing.T) {
 		Flags:     23,
 		Value:     []byte("this is a test"),
 		Session:   "session1",
+		TTL:       "30s",
 		RaftIndex: RaftIndex{
 			CreateIndex: 1,
 			ModifyIndex: 2,
@@ -155,6 +155,7 @@ func (s *HTTPHandlers) convertOps(resp http.ResponseWriter, req *http.Request) (
 						Value:   in.KV.Value,
 						Flags:   in.KV.Flags,
 						Session: in.KV.Session,
+						TTL:     in.KV.TTL,
 						EnterpriseMeta: acl.NewEnterpriseMetaWithPartition(
 							in.KV.Partition,
 							in.KV.Namespace,
@@ -309,6 +310,7 @@ func (s *HTTPHandlers) convertOps(resp http.ResponseWriter, req *http.Request) (
 							HTTP:                           check.Definition.HTTP,
 							TLSServerName:                  check.Definition.TLSServerName,
 							TLSSkipVerify:                  check.Definition.TLSSkipVerify,
+							UseConnectTLS:                  check.Definition.UseConnectTLS,
 							Header:                         check.Definition.Header,
 							Method:                         check.Definition.Method,
 							Body:                           check.Definition.Body,
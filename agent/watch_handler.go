@@ -5,20 +5,30 @@ package agent
 
 import (
 	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
 	"crypto/tls"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	osexec "os/exec"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"text/template"
+	"time"
 
 	"github.com/armon/circbuf"
 	"github.com/hashicorp/consul/agent/exec"
 	"github.com/hashicorp/consul/api/watch"
+	"github.com/hashicorp/consul/lib/file"
+	"github.com/hashicorp/consul/lib/retry"
 	"github.com/hashicorp/go-cleanhttp"
 	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-uuid"
 	"golang.org/x/net/context"
 )
 
@@ -104,88 +114,298 @@ func makeWatchHandler(logger hclog.Logger, handler interface{}) watch.HandlerFun
 	return fn
 }
 
-func makeHTTPWatchHandler(logger hclog.Logger, config *watch.HttpHandlerConfig) watch.HandlerFunc {
-	fn := func(idx uint64, data interface{}) {
-		trans := cleanhttp.DefaultTransport()
+// makeHTTPWatchHandler returns a handler for the given HTTP watch. Deliveries
+// are no longer retried once ctx is cancelled.
+func makeHTTPWatchHandler(ctx context.Context, logger hclog.Logger, config *watch.HttpHandlerConfig) watch.HandlerFunc {
+	body, err := parseWatchBodyTemplate(config.BodyTemplate)
+	if err != nil {
+		// makeWatchPlan already validated the template, so this can only
+		// happen for handlers that were built without it.
+		logger.Error("Failed to parse http watch body template",
+			"watch", config.Path,
+			"error", err,
+		)
+		return func(uint64, interface{}) {}
+	}
 
-		// Skip SSL certificate verification if TLSSkipVerify is true
-		if trans.TLSClientConfig == nil {
-			trans.TLSClientConfig = &tls.Config{
-				InsecureSkipVerify: config.TLSSkipVerify,
-			}
-		} else {
-			trans.TLSClientConfig.InsecureSkipVerify = config.TLSSkipVerify
+	trans := cleanhttp.DefaultTransport()
+
+	// Skip SSL certificate verification if TLSSkipVerify is true
+	if trans.TLSClientConfig == nil {
+		trans.TLSClientConfig = &tls.Config{
+			InsecureSkipVerify: config.TLSSkipVerify,
 		}
+	} else {
+		trans.TLSClientConfig.InsecureSkipVerify = config.TLSSkipVerify
+	}
 
-		ctx := context.Background()
-		ctx, cancel := context.WithTimeout(ctx, config.Timeout)
-		defer cancel()
+	h := &httpWatchHandler{
+		ctx:    ctx,
+		logger: logger,
+		config: config,
+		body:   body,
+		client: &http.Client{Transport: trans},
+	}
+	if config.MaxConcurrency > 0 {
+		h.sem = make(chan struct{}, config.MaxConcurrency)
+	}
+	return h.handle
+}
 
-		// Create the HTTP client.
-		httpClient := &http.Client{
-			Transport: trans,
-		}
+// httpWatchHandler delivers watch invocations to an HTTP endpoint. Failed
+// deliveries are retried with exponential backoff and, once the retries are
+// exhausted, written to the dead-letter directory if one is configured.
+type httpWatchHandler struct {
+	// ctx is cancelled when the watch is stopped, which interrupts the
+	// deliveries and their retries.
+	ctx    context.Context
+	logger hclog.Logger
+	config *watch.HttpHandlerConfig
+	body   *template.Template
+	client *http.Client
 
-		// Setup the input
-		var inp bytes.Buffer
+	// sem limits the number of in-flight deliveries. It is nil when
+	// deliveries are synchronous.
+	sem chan struct{}
+}
+
+// httpWatchDelivery is a single rendered watch invocation. It is also the
+// format of the files written to the dead-letter directory.
+type httpWatchDelivery struct {
+	ID       string
+	Index    uint64
+	Method   string
+	Path     string
+	Body     string
+	Attempts int    `json:",omitempty"`
+	Error    string `json:",omitempty"`
+	Time     time.Time
+}
+
+// httpWatchTemplateData is the data passed to a body_template.
+type httpWatchTemplateData struct {
+	Index uint64
+	Data  interface{}
+}
+
+func (h *httpWatchHandler) handle(idx uint64, data interface{}) {
+	var inp bytes.Buffer
+	if h.body != nil {
+		if err := h.body.Execute(&inp, httpWatchTemplateData{Index: idx, Data: data}); err != nil {
+			h.logger.Error("Failed to render body template for http watch",
+				"watch", h.config.Path,
+				"error", err,
+			)
+			return
+		}
+	} else {
 		enc := json.NewEncoder(&inp)
 		if err := enc.Encode(data); err != nil {
-			logger.Error("Failed to encode data for http watch",
-				"watch", config.Path,
+			h.logger.Error("Failed to encode data for http watch",
+				"watch", h.config.Path,
 				"error", err,
 			)
 			return
 		}
+	}
 
-		req, err := http.NewRequest(config.Method, config.Path, &inp)
-		if err != nil {
-			logger.Error("Failed to setup http watch", "error", err)
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		h.logger.Error("Failed to generate http watch delivery ID", "error", err)
+		return
+	}
+	d := &httpWatchDelivery{
+		ID:     id,
+		Index:  idx,
+		Method: h.config.Method,
+		Path:   h.config.Path,
+		Body:   inp.String(),
+		Time:   time.Now().UTC(),
+	}
+
+	if h.sem == nil {
+		h.deliver(d)
+		return
+	}
+
+	// Block the watch while all delivery slots are taken so a slow endpoint
+	// applies backpressure instead of piling up goroutines.
+	select {
+	case h.sem <- struct{}{}:
+	case <-h.ctx.Done():
+		d.Error = h.ctx.Err().Error()
+		h.deadLetter(d)
+		return
+	}
+	go func() {
+		defer func() { <-h.sem }()
+		h.deliver(d)
+	}()
+}
+
+// deliver sends the request, retrying retryable failures until MaxRetries
+// is exhausted or the watch is stopped.
+func (h *httpWatchHandler) deliver(d *httpWatchDelivery) {
+	waiter := &retry.Waiter{
+		MinWait: h.config.RetryBackoff,
+		MaxWait: h.config.RetryMaxBackoff,
+		Factor:  h.config.RetryBackoff,
+		Jitter:  retry.NewJitter(10),
+	}
+
+	for {
+		d.Attempts++
+		retryable, err := h.send(d)
+		if err == nil {
 			return
 		}
-		req = req.WithContext(ctx)
-		req.Header.Add("Content-Type", "application/json")
-		req.Header.Add("X-Consul-Index", strconv.FormatUint(idx, 10))
-		for key, values := range config.Header {
-			for _, val := range values {
-				req.Header.Add(key, val)
-			}
-		}
-		resp, err := httpClient.Do(req)
-		if err != nil {
-			logger.Error("Failed to invoke http watch handler",
-				"watch", config.Path,
+		d.Error = err.Error()
+
+		if !retryable || d.Attempts > h.config.MaxRetries {
+			h.logger.Error("Failed to deliver http watch",
+				"watch", h.config.Path,
+				"index", d.Index,
+				"attempts", d.Attempts,
 				"error", err,
 			)
+			h.deadLetter(d)
 			return
 		}
-		defer resp.Body.Close()
 
-		// Collect the output
-		output, _ := circbuf.NewBuffer(WatchBufSize)
-		io.Copy(output, resp.Body)
-
-		// Get the output, add a message about truncation
-		outputStr := string(output.Bytes())
-		if output.TotalWritten() > output.Size() {
-			outputStr = fmt.Sprintf("Captured %d of %d bytes\n...\n%s",
-				output.Size(), output.TotalWritten(), outputStr)
+		start := time.Now()
+		if err := waiter.Wait(h.ctx); err != nil {
+			h.logger.Error("Stopped retrying http watch",
+				"watch", h.config.Path,
+				"index", d.Index,
+				"attempts", d.Attempts,
+				"error", d.Error,
+			)
+			h.deadLetter(d)
+			return
 		}
+		h.logger.Warn("Retrying failed http watch delivery",
+			"watch", h.config.Path,
+			"index", d.Index,
+			"attempt", d.Attempts+1,
+			"waited", time.Since(start),
+			"error", d.Error,
+		)
+	}
+}
 
-		if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
-			// Log the output
-			logger.Trace("http watch handler output",
-				"watch", config.Path,
-				"output", outputStr,
-			)
-		} else {
-			logger.Error("http watch handler failed with output",
-				"watch", config.Path,
-				"status", resp.Status,
-				"output", outputStr,
-			)
+// send performs a single delivery attempt. It reports whether a failure is
+// worth retrying: connection errors, 429s and 5xx responses are retried,
+// other responses are not.
+func (h *httpWatchHandler) send(d *httpWatchDelivery) (bool, error) {
+	ctx, cancel := context.WithTimeout(h.ctx, h.config.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequest(d.Method, d.Path, strings.NewReader(d.Body))
+	if err != nil {
+		return false, fmt.Errorf("failed to setup http watch: %w", err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("X-Consul-Index", strconv.FormatUint(d.Index, 10))
+	req.Header.Add("X-Consul-Delivery-ID", d.ID)
+	req.Header.Add("X-Consul-Delivery-Attempt", strconv.Itoa(d.Attempts))
+	for key, values := range h.config.Header {
+		for _, val := range values {
+			req.Header.Add(key, val)
 		}
 	}
-	return fn
+	if h.config.HMACSecret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set("X-Consul-Signature-Timestamp", timestamp)
+		req.Header.Set("X-Consul-Signature", "sha256="+signWatchPayload(h.config.HMACSecret, timestamp, d.Body))
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	// Collect the output
+	output, _ := circbuf.NewBuffer(WatchBufSize)
+	io.Copy(output, resp.Body)
+
+	// Get the output, add a message about truncation
+	outputStr := string(output.Bytes())
+	if output.TotalWritten() > output.Size() {
+		outputStr = fmt.Sprintf("Captured %d of %d bytes\n...\n%s",
+			output.Size(), output.TotalWritten(), outputStr)
+	}
+
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		// Log the output
+		h.logger.Trace("http watch handler output",
+			"watch", h.config.Path,
+			"output", outputStr,
+		)
+		return false, nil
+	}
+
+	h.logger.Error("http watch handler failed with output",
+		"watch", h.config.Path,
+		"status", resp.Status,
+		"output", outputStr,
+	)
+	retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retryable, fmt.Errorf("unexpected response code: %s", resp.Status)
+}
+
+// deadLetter persists a failed delivery so that it can be inspected and
+// replayed later.
+func (h *httpWatchHandler) deadLetter(d *httpWatchDelivery) {
+	if h.config.DeadLetterDir == "" {
+		return
+	}
+
+	buf, err := json.Marshal(d)
+	if err != nil {
+		h.logger.Error("Failed to encode http watch dead letter", "error", err)
+		return
+	}
+	name := fmt.Sprintf("%d-%d-%s.json", d.Time.UnixNano(), d.Index, d.ID)
+	path := filepath.Join(h.config.DeadLetterDir, name)
+	if err := file.WriteAtomicWithPerms(path, buf, 0700, 0600); err != nil {
+		h.logger.Error("Failed to write http watch dead letter",
+			"watch", h.config.Path,
+			"path", path,
+			"error", err,
+		)
+		return
+	}
+	h.logger.Info("Wrote failed http watch delivery to dead letter directory",
+		"watch", h.config.Path,
+		"path", path,
+	)
+}
+
+// signWatchPayload returns the hex encoded HMAC-SHA256 of the timestamp and
+// body joined by a period. Including the timestamp lets receivers reject
+// replayed requests.
+func signWatchPayload(secret, timestamp, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write([]byte(body))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// parseWatchBodyTemplate parses the body_template of an HTTP watch handler.
+// It returns a nil template when none is configured.
+func parseWatchBodyTemplate(body string) (*template.Template, error) {
+	if body == "" {
+		return nil, nil
+	}
+	return template.New("body").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			buf, err := json.Marshal(v)
+			return string(buf), err
+		},
+	}).Parse(body)
 }
 
 // TODO: return a fully constructed watch.Plan with a Plan.Handler, so that Exempt
@@ -219,6 +439,12 @@ func makeWatchPlan(logger hclog.Logger, params map[string]interface{}) (*watch.P
 	if !hasHandler && !hasArgs && wp.HandlerType != "http" {
 		return nil, fmt.Errorf("Must define a watch handler")
 	}
+	if wp.HandlerType == "http" {
+		config := wp.Exempt["http_handler_config"].(*watch.HttpHandlerConfig)
+		if _, err := parseWatchBodyTemplate(config.BodyTemplate); err != nil {
+			return nil, fmt.Errorf("Failed to parse 'body_template': %v", err)
+		}
+	}
 	return wp, nil
 }
 
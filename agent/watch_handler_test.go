@@ -4,10 +4,19 @@
 package agent
 
 import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -65,10 +74,223 @@ func TestMakeHTTPWatchHandler(t *testing.T) {
 		Header:  map[string][]string{"X-Custom": {"abc", "def"}},
 		Timeout: time.Minute,
 	}
-	handler := makeHTTPWatchHandler(testutil.Logger(t), &config)
+	handler := makeHTTPWatchHandler(context.Background(), testutil.Logger(t), &config)
 	handler(100, []string{"foo", "bar", "baz"})
 }
 
+func TestMakeHTTPWatchHandler_Signature(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		timestamp := r.Header.Get("X-Consul-Signature-Timestamp")
+		require.NotEmpty(t, timestamp)
+		mac := hmac.New(sha256.New, []byte("s3cr3t"))
+		mac.Write([]byte(timestamp + "." + string(body)))
+		expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+		require.Equal(t, expected, r.Header.Get("X-Consul-Signature"))
+	}))
+	defer server.Close()
+	config := watch.HttpHandlerConfig{
+		Path:       server.URL,
+		Method:     "POST",
+		Timeout:    time.Minute,
+		HMACSecret: "s3cr3t",
+	}
+	handler := makeHTTPWatchHandler(context.Background(), testutil.Logger(t), &config)
+	handler(100, []string{"foo", "bar", "baz"})
+}
+
+func TestMakeHTTPWatchHandler_BodyTemplate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.Equal(t, `{"index":100,"keys":["foo","bar"]}`, string(body))
+	}))
+	defer server.Close()
+
+	tmpl := `{"index":{{ .Index }},"keys":{{ json .Data }}}`
+	config := watch.HttpHandlerConfig{
+		Path:         server.URL,
+		Method:       "POST",
+		Timeout:      time.Minute,
+		BodyTemplate: tmpl,
+	}
+	handler := makeHTTPWatchHandler(context.Background(), testutil.Logger(t), &config)
+	handler(100, []string{"foo", "bar"})
+}
+
+func TestMakeHTTPWatchHandler_Retry(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempt := atomic.AddInt32(&attempts, 1)
+		require.Equal(t, strconv.Itoa(int(attempt)), r.Header.Get("X-Consul-Delivery-Attempt"))
+		if attempt < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	dir := testutil.TempDir(t, "dead-letter")
+	config := watch.HttpHandlerConfig{
+		Path:            server.URL,
+		Method:          "POST",
+		Timeout:         time.Minute,
+		MaxRetries:      3,
+		RetryBackoff:    time.Millisecond,
+		RetryMaxBackoff: 10 * time.Millisecond,
+		DeadLetterDir:   dir,
+	}
+	handler := makeHTTPWatchHandler(context.Background(), testutil.Logger(t), &config)
+	handler(100, []string{"foo"})
+
+	require.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, files)
+}
+
+func TestMakeHTTPWatchHandler_DeadLetter(t *testing.T) {
+	run := func(t *testing.T, status int, expectAttempts int32) {
+		var attempts int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&attempts, 1)
+			w.WriteHeader(status)
+		}))
+		defer server.Close()
+
+		dir := testutil.TempDir(t, "dead-letter")
+		config := watch.HttpHandlerConfig{
+			Path:            server.URL,
+			Method:          "POST",
+			Timeout:         time.Minute,
+			MaxRetries:      2,
+			RetryBackoff:    time.Millisecond,
+			RetryMaxBackoff: 10 * time.Millisecond,
+			DeadLetterDir:   dir,
+		}
+		handler := makeHTTPWatchHandler(context.Background(), testutil.Logger(t), &config)
+		handler(100, []string{"foo"})
+
+		require.Equal(t, expectAttempts, atomic.LoadInt32(&attempts))
+
+		files, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		require.Len(t, files, 1)
+
+		buf, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+		require.NoError(t, err)
+		var d httpWatchDelivery
+		require.NoError(t, json.Unmarshal(buf, &d))
+		require.Equal(t, uint64(100), d.Index)
+		require.Equal(t, server.URL, d.Path)
+		require.Equal(t, "[\"foo\"]\n", d.Body)
+		require.Equal(t, int(expectAttempts), d.Attempts)
+		require.Contains(t, d.Error, strconv.Itoa(status))
+	}
+
+	t.Run("retries exhausted", func(t *testing.T) {
+		run(t, http.StatusInternalServerError, 3)
+	})
+	t.Run("not retryable", func(t *testing.T) {
+		run(t, http.StatusBadRequest, 1)
+	})
+}
+
+func TestMakeHTTPWatchHandler_Stopped(t *testing.T) {
+	var attempts int32
+	attempted := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		select {
+		case attempted <- struct{}{}:
+		default:
+		}
+	}))
+	defer server.Close()
+
+	dir := testutil.TempDir(t, "dead-letter")
+	config := watch.HttpHandlerConfig{
+		Path:            server.URL,
+		Method:          "POST",
+		Timeout:         time.Minute,
+		MaxRetries:      10,
+		RetryBackoff:    time.Hour,
+		RetryMaxBackoff: time.Hour,
+		DeadLetterDir:   dir,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	handler := makeHTTPWatchHandler(ctx, testutil.Logger(t), &config)
+
+	// Stopping the watch interrupts the backoff of the synchronous delivery.
+	done := make(chan struct{})
+	go func() {
+		handler(100, []string{"foo"})
+		close(done)
+	}()
+	<-attempted
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("handler was not stopped")
+	}
+
+	require.Equal(t, int32(1), atomic.LoadInt32(&attempts))
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+}
+
+func TestMakeHTTPWatchHandler_MaxConcurrency(t *testing.T) {
+	var inflight, maxInflight int32
+	var wg sync.WaitGroup
+	wg.Add(6)
+	arrived := make(chan struct{}, 6)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer wg.Done()
+		n := atomic.AddInt32(&inflight, 1)
+		defer atomic.AddInt32(&inflight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInflight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInflight, max, n) {
+				break
+			}
+		}
+		arrived <- struct{}{}
+		<-release
+	}))
+	defer server.Close()
+
+	config := watch.HttpHandlerConfig{
+		Path:           server.URL,
+		Method:         "POST",
+		Timeout:        time.Minute,
+		MaxConcurrency: 2,
+	}
+	handler := makeHTTPWatchHandler(context.Background(), testutil.Logger(t), &config)
+	go func() {
+		for i := 0; i < 6; i++ {
+			handler(uint64(i), []string{"foo"})
+		}
+	}()
+
+	// No other delivery starts while both slots are taken.
+	<-arrived
+	<-arrived
+	select {
+	case <-arrived:
+		t.Fatal("more than 2 deliveries in flight")
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(release)
+	wg.Wait()
+
+	require.Equal(t, int32(2), atomic.LoadInt32(&maxInflight))
+}
+
 type raw map[string]interface{}
 
 func TestMakeWatchPlan(t *testing.T) {
@@ -162,6 +384,19 @@ func TestMakeWatchPlan(t *testing.T) {
 			},
 			expectedErr: "Only one watch handler allowed",
 		},
+		{
+			name: "handler_type http, with invalid body template",
+			params: raw{
+				"type":         "key",
+				"key":          "foo",
+				"handler_type": "http",
+				"http_handler_config": raw{
+					"path":          "http://127.0.0.1:8000/watch",
+					"body_template": "{{ .Index",
+				},
+			},
+			expectedErr: "Failed to parse 'body_template'",
+		},
 		{
 			name: "no handler_type",
 			params: raw{
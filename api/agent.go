@@ -10,6 +10,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 // ServiceKind is the kind of service being registered.
@@ -348,6 +350,7 @@ type AgentServiceCheck struct {
 	Notes                  string              `json:",omitempty"`
 	TLSServerName          string              `json:",omitempty"`
 	TLSSkipVerify          bool                `json:",omitempty"`
+	UseConnectTLS          bool                `json:",omitempty"`
 	GRPC                   string              `json:",omitempty"`
 	GRPCUseTLS             bool                `json:",omitempty"`
 	H2PING                 string              `json:",omitempty"`
@@ -1217,6 +1220,70 @@ func (a *Agent) DisableNodeMaintenance() error {
 	return nil
 }
 
+// AgentDrainOpts configures a node drain.
+type AgentDrainOpts struct {
+	// Reason is recorded as the notes of the node maintenance check.
+	Reason string
+
+	// Timeout is how long to wait for the proxies' active connections to
+	// drop below Threshold. If zero, the agent's default is used.
+	Timeout time.Duration
+
+	// Threshold is the number of active connections per proxy below which
+	// the proxy is considered drained. If zero, the agent's default of 1 is
+	// used, i.e. the proxy must have no active connections.
+	Threshold int
+}
+
+// AgentDrainResult is the outcome of draining a node.
+type AgentDrainResult struct {
+	// Drained is true if the active connections of every proxy dropped
+	// below the threshold before the timeout.
+	Drained bool
+
+	// Proxies are the sidecar proxies that were drained and deregistered.
+	Proxies []AgentDrainProxy
+}
+
+// AgentDrainProxy is the drain status of a single sidecar proxy.
+type AgentDrainProxy struct {
+	ServiceID         string
+	ActiveConnections int
+	Drained           bool
+	Deregistered      bool
+	Error             string `json:",omitempty"`
+}
+
+// DrainNode places the node in maintenance mode, waits for the local Envoy
+// sidecar proxies to drain their active connections and then deregisters
+// them. It blocks until the drain has completed or timed out.
+func (a *Agent) DrainNode(opts AgentDrainOpts, q *WriteOptions) (*AgentDrainResult, error) {
+	r := a.c.newRequest("PUT", "/v1/agent/drain")
+	r.setWriteOptions(q)
+	if opts.Reason != "" {
+		r.params.Set("reason", opts.Reason)
+	}
+	if opts.Timeout != 0 {
+		r.params.Set("timeout", opts.Timeout.String())
+	}
+	if opts.Threshold != 0 {
+		r.params.Set("threshold", strconv.Itoa(opts.Threshold))
+	}
+	_, resp, err := a.c.doRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	defer closeResponseBody(resp)
+	if err := requireOK(resp); err != nil {
+		return nil, err
+	}
+	var out AgentDrainResult
+	if err := decodeBody(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
 // Monitor returns a channel which will receive streaming logs from the agent
 // Providing a non-nil stopCh can be used to close the connection and stop the
 // log stream. An empty string will be sent down the given channel when there's
@@ -35,6 +35,8 @@ const (
 	TCPRoute          string = "tcp-route"
 	InlineCertificate string = "inline-certificate"
 	HTTPRoute         string = "http-route"
+	KVPrefix          string = "kv-prefix"
+	RPCRateLimit      string = "rpc-rate-limit"
 )
 
 const (
@@ -375,6 +377,10 @@ func makeConfigEntry(kind, name string) (ConfigEntry, error) {
 		return &HTTPRouteConfigEntry{Kind: kind, Name: name}, nil
 	case RateLimitIPConfig:
 		return &RateLimitIPConfigEntry{Kind: kind, Name: name}, nil
+	case KVPrefix:
+		return &KVPrefixConfigEntry{Kind: kind, Name: name}, nil
+	case RPCRateLimit:
+		return &RPCRateLimitConfigEntry{Kind: kind, Name: name}, nil
 	default:
 		return nil, fmt.Errorf("invalid config entry kind: %s", kind)
 	}
@@ -0,0 +1,115 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package api
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// KVPrefixConfigEntry configures the behavior of the KV store for the keys
+// under a prefix. When the prefixes of several entries match a key, the entry
+// with the longest prefix applies.
+type KVPrefixConfigEntry struct {
+	// Kind of the config entry. This should be set to api.KVPrefix.
+	Kind string
+
+	// Name is used to identify the config entry.
+	Name string
+
+	// Prefix is the key prefix the config entry applies to. An empty prefix
+	// matches all the keys.
+	Prefix string
+
+	// History enables the revision history of the keys under the prefix.
+	History *KVHistoryConfig `json:",omitempty"`
+
+	// Schema is a JSON Schema document that the values written to the keys
+	// under the prefix must conform to.
+	Schema string `json:",omitempty"`
+
+	// Quota limits the number of keys and bytes under the prefix.
+	Quota *KVQuotaConfig `json:",omitempty"`
+
+	Meta map[string]string `json:",omitempty"`
+
+	// CreateIndex is the Raft index this entry was created at. This is a
+	// read-only field.
+	CreateIndex uint64
+
+	// ModifyIndex is used for the Check-And-Set operations and can also be fed
+	// back into the WaitIndex of the QueryOptions in order to perform blocking
+	// queries.
+	ModifyIndex uint64
+
+	// Partition is the partition the config entry is associated with.
+	// Partitioning is a Consul Enterprise feature.
+	Partition string `json:",omitempty"`
+
+	// Namespace is the namespace the config entry is associated with.
+	// Namespacing is a Consul Enterprise feature.
+	Namespace string `json:",omitempty"`
+}
+
+// KVHistoryConfig bounds the revision history kept for each key. Revisions are
+// removed once either limit is exceeded.
+type KVHistoryConfig struct {
+	// MaxRevisions is the maximum number of previous revisions kept per key.
+	MaxRevisions int `json:",omitempty" alias:"max_revisions"`
+
+	// MaxAge is how long a revision is kept after it was superseded.
+	MaxAge time.Duration `json:",omitempty" alias:"max_age"`
+}
+
+// KVQuotaConfig limits the keys that a kv-prefix config entry applies to.
+// Writes that would exceed either limit are rejected.
+type KVQuotaConfig struct {
+	// MaxKeys is the maximum number of keys.
+	MaxKeys int `json:",omitempty" alias:"max_keys"`
+
+	// MaxBytes is the maximum total size of the keys and their values.
+	MaxBytes int `json:",omitempty" alias:"max_bytes"`
+}
+
+func (e *KVPrefixConfigEntry) GetKind() string            { return KVPrefix }
+func (e *KVPrefixConfigEntry) GetName() string            { return e.Name }
+func (e *KVPrefixConfigEntry) GetPartition() string       { return e.Partition }
+func (e *KVPrefixConfigEntry) GetNamespace() string       { return e.Namespace }
+func (e *KVPrefixConfigEntry) GetMeta() map[string]string { return e.Meta }
+func (e *KVPrefixConfigEntry) GetCreateIndex() uint64     { return e.CreateIndex }
+func (e *KVPrefixConfigEntry) GetModifyIndex() uint64     { return e.ModifyIndex }
+
+func (c *KVHistoryConfig) MarshalJSON() ([]byte, error) {
+	type Alias KVHistoryConfig
+	exported := &struct {
+		MaxAge string `json:",omitempty"`
+		*Alias
+	}{
+		Alias: (*Alias)(c),
+	}
+	if c.MaxAge != 0 {
+		exported.MaxAge = c.MaxAge.String()
+	}
+	return json.Marshal(exported)
+}
+
+func (c *KVHistoryConfig) UnmarshalJSON(data []byte) error {
+	type Alias KVHistoryConfig
+	aux := &struct {
+		MaxAge string
+		*Alias
+	}{
+		Alias: (*Alias)(c),
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	if aux.MaxAge != "" {
+		var err error
+		if c.MaxAge, err = time.ParseDuration(aux.MaxAge); err != nil {
+			return err
+		}
+	}
+	return nil
+}
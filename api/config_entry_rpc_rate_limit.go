@@ -0,0 +1,72 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package api
+
+// RPCRateLimitConfigEntry defines a rate limit that every server applies to
+// the RPCs it receives, based on the RPC endpoint and the ACL token of the
+// request.
+type RPCRateLimitConfigEntry struct {
+	// Kind of the config entry. This should be set to api.RPCRateLimit.
+	Kind string
+
+	// Name is used to identify the config entry.
+	Name string
+
+	// Mode is the action taken when the limit is exceeded: "permissive",
+	// "enforcing" or "disabled". It defaults to "enforcing".
+	Mode string `json:",omitempty"`
+
+	// Endpoints restricts the limit to these RPC endpoints. A name ending with
+	// "*" matches all the endpoints starting with the rest of the name.
+	Endpoints []string `json:",omitempty"`
+
+	// Tokens restricts the limit to the requests made with the ACL tokens with
+	// these accessor IDs.
+	Tokens []string `json:",omitempty"`
+
+	// Identities restricts the limit to the requests made with the tokens of
+	// these identities, like "service:web", "node:node1" or
+	// "token:<accessor ID>".
+	Identities []string `json:",omitempty"`
+
+	// KeyBy lists the properties of the requests that get a bucket of their
+	// own: "token", "identity" and "endpoint".
+	KeyBy []string `json:",omitempty" alias:"key_by"`
+
+	// MetricsIncludeTokens reports the accessor ID or identity of the tokens
+	// that exceed the limit in the metrics.
+	MetricsIncludeTokens bool `json:",omitempty" alias:"metrics_include_tokens"`
+
+	// ReadRate and WriteRate are the number of read and write requests per
+	// second allowed in each bucket, on each server. Zero means no limit.
+	ReadRate  float64 `json:",omitempty" alias:"read_rate"`
+	WriteRate float64 `json:",omitempty" alias:"write_rate"`
+
+	Meta map[string]string `json:",omitempty"`
+
+	// CreateIndex is the Raft index this entry was created at. This is a
+	// read-only field.
+	CreateIndex uint64
+
+	// ModifyIndex is used for the Check-And-Set operations and can also be fed
+	// back into the WaitIndex of the QueryOptions in order to perform blocking
+	// queries.
+	ModifyIndex uint64
+
+	// Partition is the partition the config entry is associated with.
+	// Partitioning is a Consul Enterprise feature.
+	Partition string `json:",omitempty"`
+
+	// Namespace is the namespace the config entry is associated with.
+	// Namespacing is a Consul Enterprise feature.
+	Namespace string `json:",omitempty"`
+}
+
+func (e *RPCRateLimitConfigEntry) GetKind() string            { return RPCRateLimit }
+func (e *RPCRateLimitConfigEntry) GetName() string            { return e.Name }
+func (e *RPCRateLimitConfigEntry) GetPartition() string       { return e.Partition }
+func (e *RPCRateLimitConfigEntry) GetNamespace() string       { return e.Namespace }
+func (e *RPCRateLimitConfigEntry) GetMeta() map[string]string { return e.Meta }
+func (e *RPCRateLimitConfigEntry) GetCreateIndex() uint64     { return e.CreateIndex }
+func (e *RPCRateLimitConfigEntry) GetModifyIndex() uint64     { return e.ModifyIndex }
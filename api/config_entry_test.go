@@ -1387,6 +1387,40 @@ func TestDecodeConfigEntry(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "rpc-rate-limit",
+			body: `
+			{
+				"Kind": "rpc-rate-limit",
+				"Name": "ci",
+				"Mode": "permissive",
+				"Endpoints": ["KVS.Apply", "Catalog.*"],
+				"Identities": ["service:ci"],
+				"KeyBy": ["token", "endpoint"],
+				"MetricsIncludeTokens": true,
+				"ReadRate": 100,
+				"WriteRate": 10.5,
+				"Meta" : {
+					"foo": "bar"
+				}
+			}
+			`,
+			expect: &RPCRateLimitConfigEntry{
+				Kind:       RPCRateLimit,
+				Name:       "ci",
+				Mode:       "permissive",
+				Endpoints:  []string{"KVS.Apply", "Catalog.*"},
+				Identities: []string{"service:ci"},
+				KeyBy:      []string{"token", "endpoint"},
+				ReadRate:   100,
+				WriteRate:  10.5,
+
+				MetricsIncludeTokens: true,
+				Meta: map[string]string{
+					"foo": "bar",
+				},
+			},
+		},
 	} {
 		tc := tc
 
@@ -0,0 +1,295 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package api
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultElectionSessionName is the Session Name we assign if none is
+	// provided
+	DefaultElectionSessionName = "Consul API Election"
+)
+
+// Election is used to implement client-side leader election on top of a
+// Lock. The leader is the holder of the lock, and the value of the lock
+// entry is the value published by the leader so that other clients can
+// observe who the current leader is.
+type Election struct {
+	c    *Client
+	opts *ElectionOptions
+	lock *Lock
+
+	leaderCh <-chan struct{}
+	l        sync.Mutex
+}
+
+// ElectionOptions is used to parameterize the Election behavior.
+type ElectionOptions struct {
+	Key              string        // Must be set and have write permissions
+	Value            []byte        // Optional, value published while we are the leader
+	SessionName      string        // Optional, defaults to DefaultElectionSessionName
+	SessionTTL       string        // Optional, defaults to DefaultLockSessionTTL
+	MonitorRetries   int           // Optional, defaults to 0 which means no retries
+	MonitorRetryTime time.Duration // Optional, defaults to DefaultMonitorRetryTime
+	LockWaitTime     time.Duration // Optional, defaults to DefaultLockWaitTime
+	LockDelay        time.Duration // Optional, defaults to 15s
+	Namespace        string        `json:",omitempty"` // Optional, defaults to API client config, namespace of ACL token, or "default" namespace
+}
+
+// ElectionLeader is the current leader of an election.
+type ElectionLeader struct {
+	// Session is the ID of the session of the leader.
+	Session string
+
+	// Value is the value published by the leader.
+	Value []byte
+
+	// LockIndex is the number of times leadership was acquired, which can be
+	// used as a fencing token.
+	LockIndex uint64
+}
+
+// Election returns a handle to an election at the given key. The key used
+// must have write permissions.
+func (c *Client) Election(key string) (*Election, error) {
+	opts := &ElectionOptions{
+		Key: key,
+	}
+	return c.ElectionOpts(opts)
+}
+
+// ElectionOpts returns a handle to an election which can be used to campaign
+// for leadership, resign and observe the current leader. The key used must
+// have write permissions.
+func (c *Client) ElectionOpts(opts *ElectionOptions) (*Election, error) {
+	if opts.SessionName == "" {
+		opts.SessionName = DefaultElectionSessionName
+	}
+	lock, err := c.LockOpts(&LockOptions{
+		Key:              opts.Key,
+		Value:            opts.Value,
+		SessionName:      opts.SessionName,
+		SessionTTL:       opts.SessionTTL,
+		MonitorRetries:   opts.MonitorRetries,
+		MonitorRetryTime: opts.MonitorRetryTime,
+		LockWaitTime:     opts.LockWaitTime,
+		LockDelay:        opts.LockDelay,
+		Namespace:        opts.Namespace,
+	})
+	if err != nil {
+		return nil, err
+	}
+	e := &Election{
+		c:    c,
+		opts: opts,
+		lock: lock,
+	}
+	return e, nil
+}
+
+// Campaign blocks until we are elected leader. Providing a non-nil stopCh
+// can be used to abort the campaign, in which case a nil channel is returned.
+// Returns a channel that is closed if the leadership is lost, for example
+// because the session was invalidated. Once the leadership is lost, Campaign
+// can be called again to run for leadership with a new session.
+func (e *Election) Campaign(stopCh <-chan struct{}) (<-chan struct{}, error) {
+	e.l.Lock()
+	defer e.l.Unlock()
+
+	if e.leaderCh != nil {
+		select {
+		case <-e.leaderCh:
+			// The leadership was lost, clean up the lock before campaigning
+			// again so that a new session is created.
+			e.lock.Unlock()
+			e.leaderCh = nil
+		default:
+			return nil, ErrLockHeld
+		}
+	}
+
+	leaderCh, err := e.lock.Lock(stopCh)
+	if err != nil || leaderCh == nil {
+		return nil, err
+	}
+	e.leaderCh = leaderCh
+	return leaderCh, nil
+}
+
+// Resign gives up the leadership. It is an error to call this if we are not
+// the leader.
+func (e *Election) Resign() error {
+	e.l.Lock()
+	defer e.l.Unlock()
+
+	if e.leaderCh == nil {
+		return ErrLockNotHeld
+	}
+	e.leaderCh = nil
+	return e.lock.Unlock()
+}
+
+// Run campaigns for leadership until stopCh is closed. It returns a channel
+// that receives true when we are elected and false when the leadership is
+// lost. After the leadership is lost, Run campaigns again with a new session.
+// Errors are retried after DefaultLockRetryTime, except for ErrLockConflict.
+// When stopCh is closed, Run resigns if we are the leader and closes the
+// returned channel.
+func (e *Election) Run(stopCh <-chan struct{}) <-chan bool {
+	notifyCh := make(chan bool)
+	go e.run(stopCh, notifyCh)
+	return notifyCh
+}
+
+func (e *Election) run(stopCh <-chan struct{}, notifyCh chan<- bool) {
+	defer close(notifyCh)
+
+	notify := func(leader bool) bool {
+		select {
+		case notifyCh <- leader:
+			return true
+		case <-stopCh:
+			return false
+		}
+	}
+
+	for {
+		leaderCh, err := e.Campaign(stopCh)
+		if err == ErrLockConflict {
+			return
+		}
+		if err != nil {
+			select {
+			case <-time.After(DefaultLockRetryTime):
+				continue
+			case <-stopCh:
+				return
+			}
+		}
+		if leaderCh == nil {
+			return
+		}
+
+		if !notify(true) {
+			e.Resign()
+			return
+		}
+
+		select {
+		case <-leaderCh:
+			if !notify(false) {
+				return
+			}
+		case <-stopCh:
+			e.Resign()
+			return
+		}
+	}
+}
+
+// Leader returns the current leader, or nil if there is no leader.
+func (e *Election) Leader() (*ElectionLeader, error) {
+	leader, _, err := e.leader(&QueryOptions{Namespace: e.opts.Namespace})
+	return leader, err
+}
+
+// Observe returns a channel that receives the current leader, and the new
+// leader each time it changes. A nil leader is sent when there is no leader.
+// Errors reading the leader are retried after DefaultMonitorRetryTime. The
+// channel is closed when stopCh is closed.
+func (e *Election) Observe(stopCh <-chan struct{}) <-chan *ElectionLeader {
+	leaderCh := make(chan *ElectionLeader)
+	go e.observe(stopCh, leaderCh)
+	return leaderCh
+}
+
+func (e *Election) observe(stopCh <-chan struct{}, leaderCh chan<- *ElectionLeader) {
+	defer close(leaderCh)
+
+	// Cancel the blocking query in flight when stopCh is closed
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	opts := (&QueryOptions{
+		WaitTime:  e.lock.opts.LockWaitTime,
+		Namespace: e.opts.Namespace,
+	}).WithContext(ctx)
+
+	var last *ElectionLeader
+	first := true
+	for {
+		select {
+		case <-stopCh:
+			return
+		default:
+		}
+
+		leader, meta, err := e.leader(opts)
+		if err != nil {
+			select {
+			case <-time.After(e.lock.opts.MonitorRetryTime):
+				opts.WaitIndex = 0
+				continue
+			case <-stopCh:
+				return
+			}
+		}
+		opts.WaitIndex = meta.LastIndex
+
+		if !first && leader.equal(last) {
+			continue
+		}
+		first = false
+		last = leader
+
+		select {
+		case leaderCh <- leader:
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+// leader reads the lock entry and returns the leader it holds.
+func (e *Election) leader(q *QueryOptions) (*ElectionLeader, *QueryMeta, error) {
+	pair, meta, err := e.c.KV().Get(e.opts.Key, q)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read leader: %v", err)
+	}
+	if pair != nil && pair.Flags != LockFlagValue {
+		return nil, nil, ErrLockConflict
+	}
+	if pair == nil || pair.Session == "" {
+		return nil, meta, nil
+	}
+	leader := &ElectionLeader{
+		Session:   pair.Session,
+		Value:     pair.Value,
+		LockIndex: pair.LockIndex,
+	}
+	return leader, meta, nil
+}
+
+// equal returns whether the leaders are the same, with the same value.
+func (l *ElectionLeader) equal(other *ElectionLeader) bool {
+	if l == nil || other == nil {
+		return l == other
+	}
+	return l.Session == other.Session &&
+		l.LockIndex == other.LockIndex &&
+		bytes.Equal(l.Value, other.Value)
+}
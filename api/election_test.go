@@ -0,0 +1,225 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAPI_ElectionCampaignResign(t *testing.T) {
+	t.Parallel()
+	c, s := makeClientWithoutConnect(t)
+	defer s.Stop()
+
+	s.WaitForSerfCheck(t)
+
+	e, err := c.ElectionOpts(&ElectionOptions{
+		Key:   "test/election",
+		Value: []byte("candidate"),
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// There should be no leader yet
+	leader, err := e.Leader()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if leader != nil {
+		t.Fatalf("bad: %v", leader)
+	}
+
+	// Resign should fail before the campaign
+	if err := e.Resign(); err != ErrLockNotHeld {
+		t.Fatalf("err: %v", err)
+	}
+
+	leaderCh, err := e.Campaign(nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if leaderCh == nil {
+		t.Fatalf("not leader")
+	}
+
+	// A second campaign should fail while we are the leader
+	if _, err := e.Campaign(nil); err != ErrLockHeld {
+		t.Fatalf("err: %v", err)
+	}
+
+	leader, err = e.Leader()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if leader == nil || leader.Session == "" || string(leader.Value) != "candidate" || leader.LockIndex != 1 {
+		t.Fatalf("bad: %#v", leader)
+	}
+
+	if err := e.Resign(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	select {
+	case <-leaderCh:
+	case <-time.After(time.Second):
+		t.Fatalf("should not be leader")
+	}
+
+	leader, err = e.Leader()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if leader != nil {
+		t.Fatalf("bad: %v", leader)
+	}
+}
+
+func TestAPI_ElectionObserve(t *testing.T) {
+	t.Parallel()
+	c, s := makeClientWithoutConnect(t)
+	defer s.Stop()
+
+	s.WaitForSerfCheck(t)
+
+	newElection := func(value string) *Election {
+		e, err := c.ElectionOpts(&ElectionOptions{
+			Key:   "test/election",
+			Value: []byte(value),
+		})
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		return e
+	}
+	first := newElection("first")
+	second := newElection("second")
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	observeCh := first.Observe(stopCh)
+
+	next := func() *ElectionLeader {
+		t.Helper()
+		select {
+		case leader := <-observeCh:
+			return leader
+		case <-time.After(10 * time.Second):
+			t.Fatalf("timeout")
+		}
+		return nil
+	}
+
+	// The current leader is sent first
+	if leader := next(); leader != nil {
+		t.Fatalf("bad: %v", leader)
+	}
+
+	if _, err := first.Campaign(nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if leader := next(); leader == nil || string(leader.Value) != "first" {
+		t.Fatalf("bad: %v", leader)
+	}
+
+	// The second candidate is elected when the first one resigns
+	elected := make(chan error, 1)
+	go func() {
+		_, err := second.Campaign(nil)
+		elected <- err
+	}()
+	if err := first.Resign(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := <-elected; err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	leader := next()
+	if leader == nil {
+		// The observer may see the interval without a leader
+		leader = next()
+	}
+	if leader == nil || string(leader.Value) != "second" {
+		t.Fatalf("bad: %v", leader)
+	}
+	if err := second.Resign(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestAPI_ElectionRun_Recampaign(t *testing.T) {
+	t.Parallel()
+	c, s := makeClientWithoutConnect(t)
+	defer s.Stop()
+
+	s.WaitForSerfCheck(t)
+
+	e, err := c.ElectionOpts(&ElectionOptions{
+		Key:       "test/election",
+		LockDelay: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	stopCh := make(chan struct{})
+	notifyCh := e.Run(stopCh)
+
+	next := func() bool {
+		t.Helper()
+		select {
+		case leader := <-notifyCh:
+			return leader
+		case <-time.After(3 * DefaultLockRetryTime):
+			t.Fatalf("timeout")
+		}
+		return false
+	}
+
+	if !next() {
+		t.Fatalf("should be leader")
+	}
+	leader, err := e.Leader()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Invalidate the session, the leadership should be lost and won again
+	// with a new session.
+	if _, err := c.Session().Destroy(leader.Session, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if next() {
+		t.Fatalf("should not be leader")
+	}
+	if !next() {
+		t.Fatalf("should be leader")
+	}
+	newLeader, err := e.Leader()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if newLeader == nil || newLeader.Session == leader.Session {
+		t.Fatalf("bad: %v", newLeader)
+	}
+
+	// Stopping should resign and close the channel
+	close(stopCh)
+	select {
+	case _, ok := <-notifyCh:
+		if ok {
+			t.Fatalf("should be closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("timeout")
+	}
+	leader, err = e.Leader()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if leader != nil {
+		t.Fatalf("bad: %v", leader)
+	}
+}
@@ -66,6 +66,7 @@ type HealthCheckDefinition struct {
 	Body                                   string
 	TLSServerName                          string
 	TLSSkipVerify                          bool
+	UseConnectTLS                          bool
 	TCP                                    string
 	UDP                                    string
 	GRPC                                   string
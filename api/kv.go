@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // KVPair is used to represent a single K/V entry
@@ -44,6 +45,11 @@ type KVPair struct {
 	// session ID.
 	Session string
 
+	// TTL is an optional duration, such as "30s", after which the key is
+	// deleted. Every write replaces the TTL, so writing the key again
+	// extends its life and writing it without a TTL removes the expiry.
+	TTL string `json:",omitempty"`
+
 	// Namespace is the namespace the KVPair is associated with
 	// Namespacing is a Consul Enterprise feature.
 	Namespace string `json:",omitempty"`
@@ -56,6 +62,24 @@ type KVPair struct {
 // KVPairs is a list of KVPair objects
 type KVPairs []*KVPair
 
+// KVRevision is a previous version of a key. Revisions are only kept for the
+// keys whose prefix has revision history enabled by a kv-prefix config entry.
+type KVRevision struct {
+	KVPair
+
+	// SupersededIndex is the index at which the key was overwritten or
+	// deleted. The revision was the current version of the key from its
+	// ModifyIndex until, excluding, SupersededIndex.
+	SupersededIndex uint64
+
+	// Deleted is set if the key was deleted at SupersededIndex.
+	Deleted bool `json:",omitempty"`
+
+	// SupersededAt is approximately when the key was superseded. It is set
+	// shortly after the revision is created, up to a minute later.
+	SupersededAt time.Time `json:",omitempty"`
+}
+
 // KV is used to manipulate the K/V API
 type KV struct {
 	c *Client
@@ -88,6 +112,48 @@ func (k *KV) Get(key string, q *QueryOptions) (*KVPair, *QueryMeta, error) {
 	return nil, qm, nil
 }
 
+// GetAtIndex is used to lookup the version of a key that was current at the
+// given index. The returned pointer to the KVPair will be nil if the key did
+// not exist at that index, or if its revision is not kept.
+func (k *KV) GetAtIndex(key string, index uint64, q *QueryOptions) (*KVPair, *QueryMeta, error) {
+	resp, qm, err := k.getInternal(key, map[string]string{"at-index": strconv.FormatUint(index, 10)}, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp == nil {
+		return nil, qm, nil
+	}
+	defer closeResponseBody(resp)
+
+	var entries []*KVPair
+	if err := decodeBody(resp, &entries); err != nil {
+		return nil, nil, err
+	}
+	if len(entries) > 0 {
+		return entries[0], qm, nil
+	}
+	return nil, qm, nil
+}
+
+// Revisions is used to lookup the previous versions kept for a key, newest
+// first.
+func (k *KV) Revisions(key string, q *QueryOptions) ([]*KVRevision, *QueryMeta, error) {
+	resp, qm, err := k.getInternal(key, map[string]string{"revisions": ""}, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp == nil {
+		return nil, qm, nil
+	}
+	defer closeResponseBody(resp)
+
+	var entries []*KVRevision
+	if err := decodeBody(resp, &entries); err != nil {
+		return nil, nil, err
+	}
+	return entries, qm, nil
+}
+
 // List is used to lookup all keys under a prefix
 func (k *KV) List(prefix string, q *QueryOptions) (KVPairs, *QueryMeta, error) {
 	resp, qm, err := k.getInternal(prefix, map[string]string{"recurse": ""}, q)
@@ -158,12 +224,15 @@ func (k *KV) getInternal(key string, params map[string]string, q *QueryOptions)
 }
 
 // Put is used to write a new value. Only the
-// Key, Flags and Value is respected.
+// Key, Flags, TTL and Value is respected.
 func (k *KV) Put(p *KVPair, q *WriteOptions) (*WriteMeta, error) {
 	params := make(map[string]string, 1)
 	if p.Flags != 0 {
 		params["flags"] = strconv.FormatUint(p.Flags, 10)
 	}
+	if p.TTL != "" {
+		params["ttl"] = p.TTL
+	}
 	_, wm, err := k.put(p.Key, params, p.Value, q)
 	return wm, err
 }
@@ -176,6 +245,9 @@ func (k *KV) CAS(p *KVPair, q *WriteOptions) (bool, *WriteMeta, error) {
 	if p.Flags != 0 {
 		params["flags"] = strconv.FormatUint(p.Flags, 10)
 	}
+	if p.TTL != "" {
+		params["ttl"] = p.TTL
+	}
 	params["cas"] = strconv.FormatUint(p.ModifyIndex, 10)
 	return k.put(p.Key, params, p.Value, q)
 }
@@ -188,6 +260,9 @@ func (k *KV) Acquire(p *KVPair, q *WriteOptions) (bool, *WriteMeta, error) {
 	if p.Flags != 0 {
 		params["flags"] = strconv.FormatUint(p.Flags, 10)
 	}
+	if p.TTL != "" {
+		params["ttl"] = p.TTL
+	}
 	params["acquire"] = p.Session
 	return k.put(p.Key, params, p.Value, q)
 }
@@ -200,6 +275,9 @@ func (k *KV) Release(p *KVPair, q *WriteOptions) (bool, *WriteMeta, error) {
 	if p.Flags != 0 {
 		params["flags"] = strconv.FormatUint(p.Flags, 10)
 	}
+	if p.TTL != "" {
+		params["ttl"] = p.TTL
+	}
 	params["release"] = p.Session
 	return k.put(p.Key, params, p.Value, q)
 }
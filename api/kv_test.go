@@ -228,6 +228,39 @@ func TestAPI_ClientCAS(t *testing.T) {
 	}
 }
 
+func TestAPI_ClientTTL(t *testing.T) {
+	t.Parallel()
+	c, s := makeClient(t)
+	defer s.Stop()
+
+	s.WaitForSerfCheck(t)
+
+	kv := c.KV()
+
+	key := testKey()
+	p := &KVPair{Key: key, Value: []byte("test"), TTL: "1s"}
+	if _, err := kv.Put(p, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	pair, meta, err := kv.Get(key, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if pair == nil || pair.TTL != "1s" {
+		t.Fatalf("unexpected value: %#v", pair)
+	}
+
+	// A blocking query returns once the key expires.
+	pair, _, err = kv.Get(key, &QueryOptions{WaitIndex: meta.LastIndex, WaitTime: 10 * time.Second})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if pair != nil {
+		t.Fatalf("key should have expired: %#v", pair)
+	}
+}
+
 func TestAPI_ClientWatchGet(t *testing.T) {
 	t.Parallel()
 	c, s := makeClient(t)
@@ -581,3 +614,115 @@ func TestAPI_KVClientTxn(t *testing.T) {
 		t.Fatalf("unexpected value: %#v", meta)
 	}
 }
+
+func TestAPI_ClientRevisions(t *testing.T) {
+	t.Parallel()
+	c, s := makeClient(t)
+	defer s.Stop()
+
+	s.WaitForSerfCheck(t)
+
+	_, _, err := c.ConfigEntries().Set(&KVPrefixConfigEntry{
+		Kind:    KVPrefix,
+		Name:    "config",
+		Prefix:  "config/",
+		History: &KVHistoryConfig{MaxRevisions: 10, MaxAge: time.Hour},
+	}, nil)
+	require.NoError(t, err)
+
+	entry, _, err := c.ConfigEntries().Get(KVPrefix, "config", nil)
+	require.NoError(t, err)
+	require.Equal(t, time.Hour, entry.(*KVPrefixConfigEntry).History.MaxAge)
+
+	kv := c.KV()
+	var indexes []uint64
+	for _, value := range []string{"v1", "v2"} {
+		_, err := kv.Put(&KVPair{Key: "config/app", Value: []byte(value)}, nil)
+		require.NoError(t, err)
+		pair, _, err := kv.Get("config/app", nil)
+		require.NoError(t, err)
+		indexes = append(indexes, pair.ModifyIndex)
+	}
+	_, err = kv.Delete("config/app", nil)
+	require.NoError(t, err)
+
+	revs, meta, err := kv.Revisions("config/app", nil)
+	require.NoError(t, err)
+	require.NotZero(t, meta.LastIndex)
+	require.Len(t, revs, 2)
+	require.Equal(t, "v2", string(revs[0].Value))
+	require.True(t, revs[0].Deleted)
+	require.Equal(t, "v1", string(revs[1].Value))
+	require.Equal(t, indexes[1], revs[1].SupersededIndex)
+
+	pair, _, err := kv.GetAtIndex("config/app", indexes[0], nil)
+	require.NoError(t, err)
+	require.NotNil(t, pair)
+	require.Equal(t, "v1", string(pair.Value))
+
+	pair, _, err = kv.GetAtIndex("config/app", indexes[0]-1, nil)
+	require.NoError(t, err)
+	require.Nil(t, pair)
+}
+
+func TestAPI_ClientPut_Schema(t *testing.T) {
+	t.Parallel()
+	c, s := makeClient(t)
+	defer s.Stop()
+
+	s.WaitForSerfCheck(t)
+
+	_, _, err := c.ConfigEntries().Set(&KVPrefixConfigEntry{
+		Kind:   KVPrefix,
+		Name:   "config",
+		Prefix: "config/",
+		Schema: `{"type": "object", "properties": {"port": {"type": "integer"}}}`,
+	}, nil)
+	require.NoError(t, err)
+
+	kv := c.KV()
+	_, err = kv.Put(&KVPair{Key: "config/app", Value: []byte(`{"port": 80}`)}, nil)
+	require.NoError(t, err)
+
+	_, err = kv.Put(&KVPair{Key: "config/app", Value: []byte(`{"port": "eighty"}`)}, nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "400")
+	require.Contains(t, err.Error(), "/port: expected integer, got string")
+}
+
+func TestAPI_ClientPut_Quota(t *testing.T) {
+	t.Parallel()
+	c, s := makeClient(t)
+	defer s.Stop()
+
+	s.WaitForSerfCheck(t)
+
+	_, _, err := c.ConfigEntries().Set(&KVPrefixConfigEntry{
+		Kind:   KVPrefix,
+		Name:   "config",
+		Prefix: "config/",
+		Quota:  &KVQuotaConfig{MaxKeys: 1},
+	}, nil)
+	require.NoError(t, err)
+
+	kv := c.KV()
+	_, err = kv.Put(&KVPair{Key: "config/a", Value: []byte("1")}, nil)
+	require.NoError(t, err)
+
+	_, err = kv.Put(&KVPair{Key: "config/b", Value: []byte("1")}, nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "413")
+	require.Contains(t, err.Error(), "1 of 1 keys used")
+
+	usage, _, err := c.Operator().Usage(nil)
+	require.NoError(t, err)
+	require.Equal(t, []KVPrefixUsage{{
+		Name:      "config",
+		Prefix:    "config/",
+		Keys:      1,
+		Bytes:     9,
+		MaxKeys:   1,
+		Partition: defaultPartition,
+		Namespace: defaultNamespace,
+	}}, usage.KVPrefixes["dc1"])
+}
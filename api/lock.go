@@ -5,6 +5,7 @@ package api
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 )
@@ -39,6 +40,10 @@ const (
 	// is being used for a lock. It is used to detect a potential
 	// conflict with a semaphore.
 	LockFlagValue = 0x2ddccbc058a50c18
+
+	// LockQueueSuffix is appended to the key of a queued lock to form the
+	// prefix under which contenders register their queue entries.
+	LockQueueSuffix = "/.queue/"
 )
 
 var (
@@ -56,6 +61,11 @@ var (
 	// ErrLockConflict is returned if the flags on a key
 	// used for a lock do not match expectation
 	ErrLockConflict = fmt.Errorf("Existing key does not match lock use")
+
+	// ErrLockQueueEntryLost is returned if the queue entry of a queued lock
+	// is released or deleted while waiting for the lock, for example because
+	// the session was invalidated.
+	ErrLockQueueEntryLost = fmt.Errorf("Lock queue entry lost")
 )
 
 // Lock is used to implement client-side leader election. It is follows the
@@ -83,9 +93,22 @@ type LockOptions struct {
 	LockWaitTime     time.Duration // Optional, defaults to DefaultLockWaitTime
 	LockTryOnce      bool          // Optional, defaults to false which means try forever
 	LockDelay        time.Duration // Optional, defaults to 15s
+	Queued           bool          // Optional, defaults to false which means contenders race for the lock
 	Namespace        string        `json:",omitempty"` // Optional, defaults to API client config, namespace of ACL token, or "default" namespace
 }
 
+// LockInfo describes the holder of a lock and the contenders waiting for it.
+type LockInfo struct {
+	// Holder is the lock entry, with the session and value of the current
+	// holder, or nil if the lock is not held.
+	Holder *KVPair
+
+	// Queue contains the queue entries of the contenders of a queued lock,
+	// in the order they will be granted the lock. The session and value of
+	// each entry are the ones of the contender.
+	Queue KVPairs
+}
+
 // LockKey returns a handle to a lock struct which can be used
 // to acquire and release the mutex. The key used must have
 // write permissions.
@@ -180,6 +203,23 @@ func (l *Lock) Lock(stopCh <-chan struct{}) (<-chan struct{}, error) {
 	}
 
 	start := time.Now()
+
+	// In queued mode, register in the queue and wait for the contenders
+	// that registered before us to get the lock first. The queue entry is
+	// removed once we are done trying, so the next contender can proceed.
+	if l.opts.Queued {
+		queueEnt := l.queueEntry(l.lockSession)
+		if err := l.enqueue(queueEnt, &wOpts); err != nil {
+			return nil, err
+		}
+		defer kv.Delete(queueEnt.Key, &wOpts)
+
+		turn, err := l.waitTurn(stopCh, start)
+		if err != nil || !turn {
+			return nil, err
+		}
+	}
+
 	attempts := 0
 WAIT:
 	// Check if we should quit
@@ -337,6 +377,17 @@ func (l *Lock) Destroy() error {
 		return ErrLockInUse
 	}
 
+	// Queued locks are also in use while contenders are waiting
+	if l.opts.Queued {
+		queue, _, err := l.readQueue(&q)
+		if err != nil {
+			return err
+		}
+		if len(queue) > 0 {
+			return ErrLockInUse
+		}
+	}
+
 	// Attempt the delete
 	w := WriteOptions{Namespace: l.opts.Namespace}
 	didRemove, _, err := kv.DeleteCAS(pair, &w)
@@ -349,6 +400,39 @@ func (l *Lock) Destroy() error {
 	return nil
 }
 
+// Info returns the current holder of the lock and, for queued locks, the
+// contenders waiting in the queue.
+func (l *Lock) Info() (*LockInfo, error) {
+	kv := l.c.KV()
+	q := QueryOptions{Namespace: l.opts.Namespace}
+
+	pair, _, err := kv.Get(l.opts.Key, &q)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lock: %v", err)
+	}
+	if pair != nil && pair.Flags != LockFlagValue {
+		return nil, ErrLockConflict
+	}
+
+	info := &LockInfo{}
+	if pair != nil && pair.Session != "" {
+		info.Holder = pair
+	}
+
+	queue, _, err := l.readQueue(&q)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range queue {
+		// The holder removes its queue entry right after acquiring the lock.
+		if info.Holder != nil && entry.Session == info.Holder.Session {
+			continue
+		}
+		info.Queue = append(info.Queue, entry)
+	}
+	return info, nil
+}
+
 // createSession is used to create a new managed session
 func (l *Lock) createSession() (string, error) {
 	session := l.c.Session()
@@ -378,6 +462,108 @@ func (l *Lock) lockEntry(session string) *KVPair {
 	}
 }
 
+// queueEntry returns a formatted KVPair for the queue entry of the session
+func (l *Lock) queueEntry(session string) *KVPair {
+	return &KVPair{
+		Key:     l.opts.Key + LockQueueSuffix + session,
+		Value:   l.opts.Value,
+		Session: session,
+		Flags:   LockFlagValue,
+	}
+}
+
+// enqueue registers the queue entry. Any existing entry for the session is
+// deleted first, so the entry is ordered by the time of this registration.
+func (l *Lock) enqueue(entry *KVPair, wOpts *WriteOptions) error {
+	kv := l.c.KV()
+	if _, err := kv.Delete(entry.Key, wOpts); err != nil {
+		return fmt.Errorf("failed to register in lock queue: %v", err)
+	}
+	ok, _, err := kv.Acquire(entry, wOpts)
+	if err != nil {
+		return fmt.Errorf("failed to register in lock queue: %v", err)
+	}
+	if !ok {
+		return fmt.Errorf("failed to register in lock queue: entry %q could not be acquired", entry.Key)
+	}
+	return nil
+}
+
+// readQueue returns the live queue entries of the lock, ordered by the time
+// they were registered. Entries left behind by contenders whose session was
+// invalidated are removed.
+func (l *Lock) readQueue(q *QueryOptions) (KVPairs, *QueryMeta, error) {
+	kv := l.c.KV()
+	pairs, meta, err := kv.List(l.opts.Key+LockQueueSuffix, q)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read lock queue: %v", err)
+	}
+
+	var queue KVPairs
+	for _, pair := range pairs {
+		if pair.Flags != LockFlagValue {
+			return nil, nil, ErrLockConflict
+		}
+		if pair.Session == "" {
+			w := WriteOptions{Namespace: l.opts.Namespace}
+			kv.DeleteCAS(pair, &w)
+			continue
+		}
+		queue = append(queue, pair)
+	}
+	sort.Slice(queue, func(i, j int) bool {
+		return queue[i].CreateIndex < queue[j].CreateIndex
+	})
+	return queue, meta, nil
+}
+
+// waitTurn blocks until the queue entry of our session is the first one of
+// the queue. It returns false if stopCh is closed or the wait time of a
+// one-shot lock elapses first.
+func (l *Lock) waitTurn(stopCh <-chan struct{}, start time.Time) (bool, error) {
+	qOpts := QueryOptions{
+		WaitTime:  l.opts.LockWaitTime,
+		Namespace: l.opts.Namespace,
+	}
+	for {
+		// Check if we should quit
+		select {
+		case <-stopCh:
+			return false, nil
+		default:
+		}
+
+		// Handle the one-shot mode.
+		if l.opts.LockTryOnce {
+			elapsed := time.Since(start)
+			if elapsed > l.opts.LockWaitTime {
+				return false, nil
+			}
+			qOpts.WaitTime = l.opts.LockWaitTime - elapsed
+		}
+
+		queue, meta, err := l.readQueue(&qOpts)
+		if err != nil {
+			return false, err
+		}
+
+		position := -1
+		for i, entry := range queue {
+			if entry.Session == l.lockSession {
+				position = i
+				break
+			}
+		}
+		switch position {
+		case -1:
+			return false, ErrLockQueueEntryLost
+		case 0:
+			return true, nil
+		}
+		qOpts.WaitIndex = meta.LastIndex
+	}
+}
+
 // monitorLock is a long running routine to monitor a lock ownership
 // It closes the stopCh if we lose our leadership.
 func (l *Lock) monitorLock(session string, stopCh chan struct{}) {
@@ -587,3 +587,140 @@ func TestAPI_LockOneShot(t *testing.T) {
 		t.Fatalf("should be leader")
 	}
 }
+
+func TestAPI_LockQueued(t *testing.T) {
+	t.Parallel()
+	c, s := makeClientWithoutConnect(t)
+	defer s.Stop()
+
+	newLock := func(value string) *Lock {
+		lock, session := createTestLock(t, c, "test/lock")
+		t.Cleanup(func() { session.Destroy(lock.opts.Session, nil) })
+		lock.opts.Queued = true
+		lock.opts.Value = []byte(value)
+		return lock
+	}
+
+	holder := newLock("holder")
+	if _, err := holder.Lock(nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Register the contenders one after the other, so their order is known.
+	type result struct {
+		name string
+		err  error
+	}
+	acquired := make(chan result, 2)
+	contenders := map[string]*Lock{}
+	for i, name := range []string{"first", "second"} {
+		lock := newLock(name)
+		contenders[name] = lock
+		go func(name string) {
+			_, err := lock.Lock(nil)
+			acquired <- result{name, err}
+		}(name)
+
+		retry.Run(t, func(r *retry.R) {
+			info, err := holder.Info()
+			if err != nil {
+				r.Fatalf("err: %v", err)
+			}
+			if len(info.Queue) != i+1 {
+				r.Fatalf("bad: %v", info.Queue)
+			}
+		})
+	}
+
+	info, err := holder.Info()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if info.Holder == nil || string(info.Holder.Value) != "holder" {
+		t.Fatalf("bad: %v", info.Holder)
+	}
+	if string(info.Queue[0].Value) != "first" || string(info.Queue[1].Value) != "second" {
+		t.Fatalf("bad: %v", info.Queue)
+	}
+
+	if err := holder.Unlock(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The contenders should get the lock in the order they registered
+	for _, name := range []string{"first", "second"} {
+		select {
+		case res := <-acquired:
+			if res.err != nil {
+				t.Fatalf("err: %v", res.err)
+			}
+			if res.name != name {
+				t.Fatalf("expected %s to acquire the lock, got %s", name, res.name)
+			}
+		case <-time.After(3 * DefaultLockRetryTime):
+			t.Fatalf("timeout")
+		}
+
+		info, err := holder.Info()
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if info.Holder == nil || string(info.Holder.Value) != name {
+			t.Fatalf("bad: %v", info.Holder)
+		}
+		if err := holder.Destroy(); err != ErrLockInUse {
+			t.Fatalf("err: %v", err)
+		}
+		if err := contenders[name].Unlock(); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	info, err = holder.Info()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if info.Holder != nil || len(info.Queue) != 0 {
+		t.Fatalf("bad: %v", info)
+	}
+	if err := holder.Destroy(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestAPI_LockQueued_OneShot(t *testing.T) {
+	t.Parallel()
+	c, s := makeClientWithoutConnect(t)
+	defer s.Stop()
+
+	holder, session := createTestLock(t, c, "test/lock")
+	defer session.Destroy(holder.opts.Session, nil)
+	holder.opts.Queued = true
+	if _, err := holder.Lock(nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer holder.Unlock()
+
+	contender, session := createTestLock(t, c, "test/lock")
+	defer session.Destroy(contender.opts.Session, nil)
+	contender.opts.Queued = true
+	contender.opts.LockTryOnce = true
+	contender.opts.LockWaitTime = 250 * time.Millisecond
+
+	ch, err := contender.Lock(nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if ch != nil {
+		t.Fatalf("should not be leader")
+	}
+
+	// The contender should leave the queue when it gives up
+	info, err := holder.Info()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(info.Queue) != 0 {
+		t.Fatalf("bad: %v", info.Queue)
+	}
+}
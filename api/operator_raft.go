@@ -3,6 +3,8 @@
 
 package api
 
+import "time"
+
 // RaftServer has information about a server in the Raft configuration.
 type RaftServer struct {
 	// ID is the unique ID for the server. These are currently the same
@@ -25,9 +27,12 @@ type RaftServer struct {
 
 	// Voter is true if this server has a vote in the cluster. This might
 	// be false if the server is staging and still coming online, or if
-	// it's a non-voting server, which will be added in a future release of
-	// Consul.
+	// it's a read replica.
 	Voter bool
+
+	// ReadReplica is true if this server is a read replica, which never
+	// becomes a voter.
+	ReadReplica bool `json:",omitempty"`
 }
 
 // RaftConfiguration is returned when querying for the current Raft configuration.
@@ -44,6 +49,106 @@ type TransferLeaderResponse struct {
 	Success bool
 }
 
+const (
+	// RaftLogVerificationOK means the checksum of the logs read back from the
+	// log store matched the checksum of the leader.
+	RaftLogVerificationOK = "ok"
+
+	// RaftLogVerificationFailed means a checksum didn't match the leader's,
+	// either because the logs were corrupted on their way to the server or
+	// in its log store.
+	RaftLogVerificationFailed = "failed"
+
+	// RaftLogVerificationRangeMismatch means the server didn't have all the
+	// logs of the range any more, so it couldn't verify them.
+	RaftLogVerificationRangeMismatch = "range-mismatch"
+
+	// RaftLogVerificationError means the verification couldn't complete.
+	RaftLogVerificationError = "error"
+)
+
+// RaftLogRange is a range of Raft log indexes. Start is inclusive and End is
+// exclusive, End being the index of the checkpoint that closed the range.
+type RaftLogRange struct {
+	Start uint64
+	End   uint64
+}
+
+// RaftLogVerificationReport is the result of verifying a range of Raft logs on
+// a server.
+type RaftLogVerificationReport struct {
+	// Time is when the server finished verifying the range.
+	Time time.Time
+
+	// Range is the range of logs that was verified.
+	Range RaftLogRange
+
+	// SkippedRange is a range of logs before this one that wasn't verified
+	// because the server was still busy verifying a previous range.
+	SkippedRange *RaftLogRange `json:",omitempty"`
+
+	// Status is one of the RaftLogVerification constants.
+	Status string
+
+	// ExpectedChecksum is the checksum of the logs computed by the leader.
+	ExpectedChecksum string
+
+	// WrittenChecksum is the checksum of the logs computed by the server as it
+	// wrote them, if it wrote the whole range.
+	WrittenChecksum string `json:",omitempty"`
+
+	// ReadChecksum is the checksum of the logs read back from the log store.
+	ReadChecksum string `json:",omitempty"`
+
+	// Error describes why the verification didn't succeed.
+	Error string `json:",omitempty"`
+
+	// Elapsed is how long it took to verify the range.
+	Elapsed time.Duration
+}
+
+// RaftLogVerificationStatus has the state of the Raft log verification on a
+// single server.
+type RaftLogVerificationStatus struct {
+	// ID, Node and Address identify the server, like in RaftServer.
+	ID      string
+	Node    string
+	Address string
+
+	// Enabled is whether log verification is enabled on the server.
+	Enabled bool
+
+	// Error is set when the status couldn't be fetched from the server.
+	Error string `json:",omitempty"`
+
+	// RangesVerified and RangesFailed count the ranges the server verified,
+	// and the ones that failed verification, since it started.
+	RangesVerified uint64
+	RangesFailed   uint64
+
+	// LastReport is the most recent verification report of the server.
+	LastReport *RaftLogVerificationReport `json:",omitempty"`
+
+	// LastFailure is the most recent report of a range that failed
+	// verification.
+	LastFailure *RaftLogVerificationReport `json:",omitempty"`
+}
+
+// RaftLogVerification is returned when querying for the state of the Raft
+// log verification.
+type RaftLogVerification struct {
+	// Servers has the verification state of each server in the Raft
+	// configuration.
+	Servers []*RaftLogVerificationStatus
+}
+
+// RaftLogVerificationCheckpoint is returned after writing a log verification
+// checkpoint.
+type RaftLogVerificationCheckpoint struct {
+	// Index is the Raft index of the checkpoint.
+	Index uint64
+}
+
 // RaftGetConfiguration is used to query the current Raft peer set.
 func (op *Operator) RaftGetConfiguration(q *QueryOptions) (*RaftConfiguration, error) {
 	r := op.c.newRequest("GET", "/v1/operator/raft/configuration")
@@ -84,6 +189,49 @@ func (op *Operator) RaftLeaderTransfer(q *QueryOptions) (*TransferLeaderResponse
 	return &out, nil
 }
 
+// RaftLogVerification is used to query the state of the Raft log verification
+// of all the servers.
+func (op *Operator) RaftLogVerification(q *QueryOptions) (*RaftLogVerification, error) {
+	r := op.c.newRequest("GET", "/v1/operator/raft/verification")
+	r.setQueryOptions(q)
+	_, resp, err := op.c.doRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	defer closeResponseBody(resp)
+	if err := requireOK(resp); err != nil {
+		return nil, err
+	}
+
+	var out RaftLogVerification
+	if err := decodeBody(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RaftLogVerificationCheckpoint writes a log verification checkpoint, which
+// makes the servers verify the logs written since the previous one. Log
+// verification must be enabled on the leader.
+func (op *Operator) RaftLogVerificationCheckpoint(q *WriteOptions) (*RaftLogVerificationCheckpoint, error) {
+	r := op.c.newRequest("PUT", "/v1/operator/raft/verification")
+	r.setWriteOptions(q)
+	_, resp, err := op.c.doRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	defer closeResponseBody(resp)
+	if err := requireOK(resp); err != nil {
+		return nil, err
+	}
+
+	var out RaftLogVerificationCheckpoint
+	if err := decodeBody(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
 // RaftRemovePeerByAddress is used to kick a stale peer (one that it in the Raft
 // quorum but no longer known to Serf or the catalog) by address in the form of
 // "IP:port".
@@ -57,3 +57,30 @@ func TestAPI_OperatorRaftLeaderTransfer(t *testing.T) {
 		t.Fatalf("err:%v", transfer)
 	}
 }
+
+func TestAPI_OperatorRaftLogVerification(t *testing.T) {
+	t.Parallel()
+	c, s := makeClient(t)
+	defer s.Stop()
+
+	s.WaitForLeader(t)
+
+	// Verification is disabled by default, so the servers are listed but
+	// there is nothing to report.
+	operator := c.Operator()
+	out, err := operator.RaftLogVerification(nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(out.Servers) != 1 ||
+		out.Servers[0].Enabled ||
+		out.Servers[0].LastReport != nil {
+		t.Fatalf("bad: %v", out)
+	}
+
+	_, err = operator.RaftLogVerificationCheckpoint(nil)
+	if err == nil || !strings.Contains(err.Error(),
+		"Raft log verification is not enabled") {
+		t.Fatalf("err: %v", err)
+	}
+}
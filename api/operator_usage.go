@@ -6,6 +6,14 @@ package api
 type Usage struct {
 	// Usage is a map of datacenter -> usage information
 	Usage map[string]ServiceUsage
+
+	// KVPrefixes is a map of datacenter -> usage of the kv-prefix config
+	// entries of the requested partition
+	KVPrefixes map[string][]KVPrefixUsage `json:",omitempty"`
+
+	// RPCConcurrency is a map of datacenter -> state of the adaptive
+	// concurrency limits of the server that handled the request
+	RPCConcurrency map[string][]RPCConcurrencyUsage `json:",omitempty"`
 }
 
 // ServiceUsage contains information about the number of services and service instances for a datacenter.
@@ -31,6 +39,31 @@ type ServiceUsage struct {
 	PartitionNamespaceBillableServiceInstances map[string]map[string]int
 }
 
+// KVPrefixUsage contains the number of keys and bytes that a kv-prefix config
+// entry applies to, and the limits of its quota if any.
+type KVPrefixUsage struct {
+	Name     string
+	Prefix   string
+	Keys     int
+	Bytes    int
+	MaxKeys  int `json:",omitempty"`
+	MaxBytes int `json:",omitempty"`
+
+	Partition string `json:",omitempty"`
+	Namespace string `json:",omitempty"`
+}
+
+// RPCConcurrencyUsage contains the state of the adaptive concurrency limit of
+// a class of RPCs ("read", "write" or "blocking") on a server.
+type RPCConcurrencyUsage struct {
+	Server   string
+	Class    string
+	Mode     string
+	Limit    int
+	InFlight int
+	Shed     uint64
+}
+
 // Usage is used to query for usage information in the given datacenter.
 func (op *Operator) Usage(q *QueryOptions) (*Usage, *QueryMeta, error) {
 	r := op.c.newRequest("GET", "/v1/operator/usage")
@@ -13,6 +13,23 @@ type Snapshot struct {
 	c *Client
 }
 
+// SnapshotOptions are the options used to save and restore snapshots.
+type SnapshotOptions struct {
+	// Compression is the compression of the saved archive, either "gzip"
+	// (the default) or "zstd". Restores detect the compression of the
+	// archive.
+	Compression string
+
+	// EncryptionKey is a base64-encoded 32-byte key used to encrypt the
+	// saved archive, or to decrypt the restored archive.
+	EncryptionKey string
+
+	// EncryptionPassphrase is a passphrase used to encrypt the saved
+	// archive, or to decrypt the restored archive. It can't be used
+	// together with EncryptionKey.
+	EncryptionPassphrase string
+}
+
 // Snapshot returns a handle that exposes the snapshot endpoints.
 func (c *Client) Snapshot() *Snapshot {
 	return &Snapshot{c}
@@ -23,8 +40,18 @@ func (c *Client) Snapshot() *Snapshot {
 // of the caller to close it. Only a subset of the QueryOptions are supported:
 // Datacenter, AllowStale, and Token.
 func (s *Snapshot) Save(q *QueryOptions) (io.ReadCloser, *QueryMeta, error) {
+	return s.SaveOpts(SnapshotOptions{}, q)
+}
+
+// SaveOpts is like Save, but the archive is compressed and optionally
+// encrypted according to the given options.
+func (s *Snapshot) SaveOpts(opts SnapshotOptions, q *QueryOptions) (io.ReadCloser, *QueryMeta, error) {
 	r := s.c.newRequest("GET", "/v1/snapshot")
 	r.setQueryOptions(q)
+	if opts.Compression != "" {
+		r.params.Set("compression", opts.Compression)
+	}
+	opts.setHeaders(r)
 
 	rtt, resp, err := s.c.doRequest(r)
 	if err != nil {
@@ -42,10 +69,17 @@ func (s *Snapshot) Save(q *QueryOptions) (io.ReadCloser, *QueryMeta, error) {
 
 // Restore streams in an existing snapshot and attempts to restore it.
 func (s *Snapshot) Restore(q *WriteOptions, in io.Reader) error {
+	return s.RestoreOpts(SnapshotOptions{}, q, in)
+}
+
+// RestoreOpts is like Restore, but an encrypted snapshot is decrypted with the
+// key or passphrase of the given options.
+func (s *Snapshot) RestoreOpts(opts SnapshotOptions, q *WriteOptions, in io.Reader) error {
 	r := s.c.newRequest("PUT", "/v1/snapshot")
 	r.body = in
 	r.header.Set("Content-Type", "application/octet-stream")
 	r.setWriteOptions(q)
+	opts.setHeaders(r)
 	_, resp, err := s.c.doRequest(r)
 	if err != nil {
 		return err
@@ -55,3 +89,14 @@ func (s *Snapshot) Restore(q *WriteOptions, in io.Reader) error {
 	}
 	return nil
 }
+
+// setHeaders sets the encryption headers of the request. The key and
+// passphrase are sent as headers so they don't end up in logs.
+func (o SnapshotOptions) setHeaders(r *request) {
+	if o.EncryptionKey != "" {
+		r.header.Set("X-Consul-Snapshot-Key", o.EncryptionKey)
+	}
+	if o.EncryptionPassphrase != "" {
+		r.header.Set("X-Consul-Snapshot-Passphrase", o.EncryptionPassphrase)
+	}
+}
@@ -136,3 +136,61 @@ func TestAPI_Snapshot_Options(t *testing.T) {
 		t.Fatalf("err: %v", err)
 	}
 }
+
+func TestAPI_Snapshot_Encrypted(t *testing.T) {
+	t.Parallel()
+	c, s := makeClient(t)
+	defer s.Stop()
+
+	s.WaitForSerfCheck(t)
+	kv := c.KV()
+	key := &KVPair{Key: testKey(), Value: []byte("hello")}
+	if _, err := kv.Put(key, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Take an encrypted snapshot.
+	snapshot := c.Snapshot()
+	opts := SnapshotOptions{
+		Compression:          "zstd",
+		EncryptionPassphrase: "correct horse battery staple",
+	}
+	snap, _, err := snapshot.SaveOpts(opts, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(snap); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	snap.Close()
+
+	key.Value = []byte("goodbye")
+	if _, err := kv.Put(key, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The snapshot can't be restored without the passphrase.
+	err = snapshot.Restore(nil, bytes.NewReader(buf.Bytes()))
+	if err == nil || !strings.Contains(err.Error(), "snapshot is encrypted") {
+		t.Fatalf("err: %v", err)
+	}
+	err = snapshot.RestoreOpts(SnapshotOptions{EncryptionPassphrase: "wrong"}, nil, bytes.NewReader(buf.Bytes()))
+	if err == nil || !strings.Contains(err.Error(), "passphrase may be wrong") {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Restore the snapshot with the passphrase.
+	restoreOpts := SnapshotOptions{EncryptionPassphrase: opts.EncryptionPassphrase}
+	if err := snapshot.RestoreOpts(restoreOpts, nil, bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	pair, _, err := kv.Get(key.Key, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if pair == nil || !bytes.Equal(pair.Value, []byte("hello")) {
+		t.Fatalf("unexpected value: %#v", pair)
+	}
+}
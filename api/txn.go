@@ -85,6 +85,7 @@ type KVTxnOp struct {
 	Flags     uint64
 	Index     uint64
 	Session   string
+	TTL       string `json:",omitempty"`
 	Namespace string `json:",omitempty"`
 	Partition string `json:",omitempty"`
 }
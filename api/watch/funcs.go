@@ -5,7 +5,9 @@ package watch
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 
 	consulapi "github.com/hashicorp/consul/api"
 )
@@ -29,6 +31,12 @@ func init() {
 		"connect_roots": connectRootsWatch,
 		"connect_leaf":  connectLeafWatch,
 		"agent_service": agentServiceWatch,
+
+		"config_entry":      configEntryWatch,
+		"config_entries":    configEntriesWatch,
+		"intentions":        intentionsWatch,
+		"peerings":          peeringsWatch,
+		"exported_services": exportedServicesWatch,
 	}
 }
 
@@ -310,6 +318,158 @@ func agentServiceWatch(params map[string]interface{}) (WatcherFunc, error) {
 	return fn, nil
 }
 
+// configEntryWatch is used to watch a single config entry. The handler is
+// invoked with a nil value while the entry doesn't exist.
+func configEntryWatch(params map[string]interface{}) (WatcherFunc, error) {
+	stale := false
+	if err := assignValueBool(params, "stale", &stale); err != nil {
+		return nil, err
+	}
+
+	var kind, name string
+	if err := assignValue(params, "kind", &kind); err != nil {
+		return nil, err
+	}
+	if err := assignValue(params, "name", &name); err != nil {
+		return nil, err
+	}
+	if kind == "" || name == "" {
+		return nil, fmt.Errorf("Must specify a config entry kind and name to watch")
+	}
+
+	fn := func(p *Plan) (BlockingParamVal, interface{}, error) {
+		opts := makeQueryOptionsWithContext(p, stale)
+		defer p.cancelFunc()
+		return getConfigEntry(p.client.ConfigEntries(), kind, name, &opts)
+	}
+	return fn, nil
+}
+
+// configEntriesWatch is used to watch all config entries of a given kind
+func configEntriesWatch(params map[string]interface{}) (WatcherFunc, error) {
+	stale := false
+	if err := assignValueBool(params, "stale", &stale); err != nil {
+		return nil, err
+	}
+
+	var kind string
+	if err := assignValue(params, "kind", &kind); err != nil {
+		return nil, err
+	}
+	if kind == "" {
+		return nil, fmt.Errorf("Must specify a config entry kind to watch")
+	}
+
+	fn := func(p *Plan) (BlockingParamVal, interface{}, error) {
+		configEntries := p.client.ConfigEntries()
+		opts := makeQueryOptionsWithContext(p, stale)
+		defer p.cancelFunc()
+		entries, meta, err := configEntries.List(kind, &opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		return WaitIndexVal(meta.LastIndex), entries, err
+	}
+	return fn, nil
+}
+
+// intentionsWatch is used to watch the list of intentions
+func intentionsWatch(params map[string]interface{}) (WatcherFunc, error) {
+	stale := false
+	if err := assignValueBool(params, "stale", &stale); err != nil {
+		return nil, err
+	}
+
+	fn := func(p *Plan) (BlockingParamVal, interface{}, error) {
+		connect := p.client.Connect()
+		opts := makeQueryOptionsWithContext(p, stale)
+		defer p.cancelFunc()
+		intentions, meta, err := connect.Intentions(&opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		return WaitIndexVal(meta.LastIndex), intentions, err
+	}
+	return fn, nil
+}
+
+// peeringsWatch is used to watch the list of cluster peerings
+func peeringsWatch(params map[string]interface{}) (WatcherFunc, error) {
+	stale := false
+	if err := assignValueBool(params, "stale", &stale); err != nil {
+		return nil, err
+	}
+
+	fn := func(p *Plan) (BlockingParamVal, interface{}, error) {
+		peerings := p.client.Peerings()
+		opts := makeQueryOptionsWithContext(p, stale)
+		defer p.cancelFunc()
+		list, meta, err := peerings.List(opts.Context(), &opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		return WaitIndexVal(meta.LastIndex), list, err
+	}
+	return fn, nil
+}
+
+// exportedServicesWatch is used to watch the exported-services config entry
+// of an admin partition. The handler is invoked with a nil value while the
+// partition doesn't export any services.
+func exportedServicesWatch(params map[string]interface{}) (WatcherFunc, error) {
+	stale := false
+	if err := assignValueBool(params, "stale", &stale); err != nil {
+		return nil, err
+	}
+
+	var partition string
+	if err := assignValue(params, "partition", &partition); err != nil {
+		return nil, err
+	}
+
+	fn := func(p *Plan) (BlockingParamVal, interface{}, error) {
+		opts := makeQueryOptionsWithContext(p, stale)
+		defer p.cancelFunc()
+
+		// The exported-services config entry is named after the partition
+		// it belongs to.
+		name := partition
+		if name != "" {
+			opts.Partition = partition
+		} else {
+			name = "default"
+		}
+		return getConfigEntry(p.client.ConfigEntries(), consulapi.ExportedServices, name, &opts)
+	}
+	return fn, nil
+}
+
+// getConfigEntry performs a blocking read of a single config entry. Reading a
+// missing entry returns a 404 without the index needed to block on, so in
+// that case the entries of the same kind are listed instead and the index of
+// the list is returned along with a nil entry.
+func getConfigEntry(configEntries *consulapi.ConfigEntries, kind, name string, opts *consulapi.QueryOptions) (BlockingParamVal, interface{}, error) {
+	entry, meta, err := configEntries.Get(kind, name, opts)
+	if err == nil {
+		return WaitIndexVal(meta.LastIndex), entry, nil
+	}
+	var statusErr consulapi.StatusError
+	if !errors.As(err, &statusErr) || statusErr.Code != http.StatusNotFound {
+		return nil, nil, err
+	}
+
+	entries, meta, err := configEntries.List(kind, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, entry := range entries {
+		if entry.GetName() == name {
+			return WaitIndexVal(meta.LastIndex), entry, nil
+		}
+	}
+	return WaitIndexVal(meta.LastIndex), nil, nil
+}
+
 func makeQueryOptionsWithContext(p *Plan, stale bool) consulapi.QueryOptions {
 	ctx, cancel := context.WithCancel(context.Background())
 	p.setCancelFunc(cancel)
@@ -5,6 +5,7 @@ package watch_test
 
 import (
 	"bytes"
+	"context"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
@@ -1054,6 +1055,339 @@ func TestAgentServiceWatch(t *testing.T) {
 	}
 }
 
+func TestConfigEntryWatch(t *testing.T) {
+	t.Parallel()
+	c, s := makeClient(t)
+	defer s.Stop()
+
+	var (
+		wakeups  []api.ConfigEntry
+		notifyCh = make(chan struct{})
+	)
+
+	plan := mustParse(t, `{"type":"config_entry", "kind":"service-defaults", "name":"web"}`)
+	plan.Handler = func(idx uint64, raw interface{}) {
+		var v api.ConfigEntry
+		if raw != nil { // nil is a valid return value
+			var ok bool
+			if v, ok = raw.(api.ConfigEntry); !ok {
+				return // ignore
+			}
+		}
+		wakeups = append(wakeups, v)
+		notifyCh <- struct{}{}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := plan.Run(s.HTTPAddr); err != nil {
+			t.Errorf("err: %v", err)
+		}
+	}()
+	defer plan.Stop()
+
+	// Wait for first wakeup.
+	<-notifyCh
+	{
+		// Writing an unrelated entry of the same kind must not wake the
+		// handler.
+		_, _, err := c.ConfigEntries().Set(&api.ServiceConfigEntry{
+			Kind:     api.ServiceDefaults,
+			Name:     "api",
+			Protocol: "grpc",
+		}, nil)
+		require.NoError(t, err)
+
+		_, _, err = c.ConfigEntries().Set(&api.ServiceConfigEntry{
+			Kind:     api.ServiceDefaults,
+			Name:     "web",
+			Protocol: "http",
+		}, nil)
+		require.NoError(t, err)
+	}
+
+	// Wait for second wakeup.
+	<-notifyCh
+
+	plan.Stop()
+	wg.Wait()
+
+	require.Len(t, wakeups, 2)
+
+	{
+		v := wakeups[0]
+		require.Nil(t, v)
+	}
+	{
+		v, ok := wakeups[1].(*api.ServiceConfigEntry)
+		require.True(t, ok)
+		require.Equal(t, "web", v.Name)
+		require.Equal(t, "http", v.Protocol)
+	}
+}
+
+func TestConfigEntriesWatch(t *testing.T) {
+	t.Parallel()
+	c, s := makeClient(t)
+	defer s.Stop()
+
+	var (
+		wakeups  [][]api.ConfigEntry
+		notifyCh = make(chan struct{})
+	)
+
+	plan := mustParse(t, `{"type":"config_entries", "kind":"service-defaults"}`)
+	plan.Handler = func(idx uint64, raw interface{}) {
+		if raw == nil {
+			return // ignore
+		}
+		v, ok := raw.([]api.ConfigEntry)
+		if !ok {
+			return // ignore
+		}
+		wakeups = append(wakeups, v)
+		notifyCh <- struct{}{}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := plan.Run(s.HTTPAddr); err != nil {
+			t.Errorf("err: %v", err)
+		}
+	}()
+	defer plan.Stop()
+
+	// Wait for first wakeup.
+	<-notifyCh
+	{
+		_, _, err := c.ConfigEntries().Set(&api.ServiceConfigEntry{
+			Kind:     api.ServiceDefaults,
+			Name:     "web",
+			Protocol: "http",
+		}, nil)
+		require.NoError(t, err)
+	}
+
+	// Wait for second wakeup.
+	<-notifyCh
+
+	plan.Stop()
+	wg.Wait()
+
+	require.Len(t, wakeups, 2)
+
+	{
+		v := wakeups[0]
+		require.Len(t, v, 0)
+	}
+	{
+		v := wakeups[1]
+		require.Len(t, v, 1)
+		require.Equal(t, "web", v[0].GetName())
+	}
+}
+
+func TestIntentionsWatch(t *testing.T) {
+	t.Parallel()
+	c, s := makeClient(t)
+	defer s.Stop()
+
+	var (
+		wakeups  [][]*api.Intention
+		notifyCh = make(chan struct{})
+	)
+
+	plan := mustParse(t, `{"type":"intentions"}`)
+	plan.Handler = func(idx uint64, raw interface{}) {
+		if raw == nil {
+			return // ignore
+		}
+		v, ok := raw.([]*api.Intention)
+		if !ok {
+			return // ignore
+		}
+		wakeups = append(wakeups, v)
+		notifyCh <- struct{}{}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := plan.Run(s.HTTPAddr); err != nil {
+			t.Errorf("err: %v", err)
+		}
+	}()
+	defer plan.Stop()
+
+	// Wait for first wakeup.
+	<-notifyCh
+	{
+		_, err := c.Connect().IntentionUpsert(&api.Intention{
+			SourceName:      "web",
+			DestinationName: "db",
+			Action:          api.IntentionActionAllow,
+		}, nil)
+		require.NoError(t, err)
+	}
+
+	// Wait for second wakeup.
+	<-notifyCh
+
+	plan.Stop()
+	wg.Wait()
+
+	require.Len(t, wakeups, 2)
+
+	{
+		v := wakeups[0]
+		require.Len(t, v, 0)
+	}
+	{
+		v := wakeups[1]
+		require.Len(t, v, 1)
+		require.Equal(t, "web", v[0].SourceName)
+		require.Equal(t, "db", v[0].DestinationName)
+	}
+}
+
+func TestPeeringsWatch(t *testing.T) {
+	t.Parallel()
+	c, s := makeClient(t)
+	defer s.Stop()
+
+	var (
+		wakeups  [][]*api.Peering
+		notifyCh = make(chan struct{})
+	)
+
+	plan := mustParse(t, `{"type":"peerings"}`)
+	plan.Handler = func(idx uint64, raw interface{}) {
+		if raw == nil {
+			return // ignore
+		}
+		v, ok := raw.([]*api.Peering)
+		if !ok {
+			return // ignore
+		}
+		wakeups = append(wakeups, v)
+		notifyCh <- struct{}{}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := plan.Run(s.HTTPAddr); err != nil {
+			t.Errorf("err: %v", err)
+		}
+	}()
+	defer plan.Stop()
+
+	// Wait for first wakeup.
+	<-notifyCh
+	{
+		_, _, err := c.Peerings().GenerateToken(context.Background(), api.PeeringGenerateTokenRequest{
+			PeerName: "peer1",
+		}, nil)
+		require.NoError(t, err)
+	}
+
+	// Wait for second wakeup.
+	<-notifyCh
+
+	plan.Stop()
+	wg.Wait()
+
+	require.Len(t, wakeups, 2)
+
+	{
+		v := wakeups[0]
+		require.Len(t, v, 0)
+	}
+	{
+		v := wakeups[1]
+		require.Len(t, v, 1)
+		require.Equal(t, "peer1", v[0].Name)
+	}
+}
+
+func TestExportedServicesWatch(t *testing.T) {
+	t.Parallel()
+	c, s := makeClient(t)
+	defer s.Stop()
+
+	var (
+		wakeups  []*api.ExportedServicesConfigEntry
+		notifyCh = make(chan struct{})
+	)
+
+	plan := mustParse(t, `{"type":"exported_services"}`)
+	plan.Handler = func(idx uint64, raw interface{}) {
+		var v *api.ExportedServicesConfigEntry
+		if raw != nil { // nil is a valid return value
+			var ok bool
+			if v, ok = raw.(*api.ExportedServicesConfigEntry); !ok {
+				return // ignore
+			}
+		}
+		wakeups = append(wakeups, v)
+		notifyCh <- struct{}{}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := plan.Run(s.HTTPAddr); err != nil {
+			t.Errorf("err: %v", err)
+		}
+	}()
+	defer plan.Stop()
+
+	// Wait for first wakeup.
+	<-notifyCh
+	{
+		_, _, err := c.Peerings().GenerateToken(context.Background(), api.PeeringGenerateTokenRequest{
+			PeerName: "peer1",
+		}, nil)
+		require.NoError(t, err)
+
+		_, _, err = c.ConfigEntries().Set(&api.ExportedServicesConfigEntry{
+			Name: "default",
+			Services: []api.ExportedService{
+				{
+					Name:      "web",
+					Consumers: []api.ServiceConsumer{{Peer: "peer1"}},
+				},
+			},
+		}, nil)
+		require.NoError(t, err)
+	}
+
+	// Wait for second wakeup.
+	<-notifyCh
+
+	plan.Stop()
+	wg.Wait()
+
+	require.Len(t, wakeups, 2)
+
+	{
+		v := wakeups[0]
+		require.Nil(t, v)
+	}
+	{
+		v := wakeups[1]
+		require.Len(t, v.Services, 1)
+		require.Equal(t, "web", v.Services[0].Name)
+	}
+}
+
 func mustParse(t *testing.T, q string) *watch.Plan {
 	t.Helper()
 	var params map[string]interface{}
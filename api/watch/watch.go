@@ -17,6 +17,16 @@ import (
 
 const DefaultTimeout = 10 * time.Second
 
+const (
+	// DefaultRetryBackoff is the initial wait between delivery attempts of an
+	// HTTP handler that has retries enabled.
+	DefaultRetryBackoff = time.Second
+
+	// DefaultRetryMaxBackoff caps the exponential wait between delivery
+	// attempts of an HTTP handler.
+	DefaultRetryMaxBackoff = time.Minute
+)
+
 // Plan is the parsed version of a watch specification. A watch provides
 // the details of a query, which generates a view into the Consul data store.
 // This view is watched for changes and a handler is invoked to take any
@@ -56,6 +66,32 @@ type HttpHandlerConfig struct {
 	TimeoutRaw    string              `mapstructure:"timeout"`
 	Header        map[string][]string `mapstructure:"header"`
 	TLSSkipVerify bool                `mapstructure:"tls_skip_verify"`
+
+	// HMACSecret, if set, is used to sign every request with HMAC-SHA256 so
+	// the receiver can verify that it came from this agent.
+	HMACSecret string `mapstructure:"hmac_secret"`
+
+	// MaxRetries is the number of times a failed delivery is retried with
+	// exponential backoff, starting at RetryBackoff and capped at
+	// RetryMaxBackoff. Zero disables retries.
+	MaxRetries         int           `mapstructure:"max_retries"`
+	RetryBackoff       time.Duration `mapstructure:"-"`
+	RetryBackoffRaw    string        `mapstructure:"retry_backoff"`
+	RetryMaxBackoff    time.Duration `mapstructure:"-"`
+	RetryMaxBackoffRaw string        `mapstructure:"retry_max_backoff"`
+
+	// MaxConcurrency is the number of deliveries that may be in flight at
+	// once. Zero delivers synchronously, blocking the watch until the
+	// delivery and its retries are done.
+	MaxConcurrency int `mapstructure:"max_concurrency"`
+
+	// BodyTemplate is an optional text/template used to render the request
+	// body instead of the JSON encoded watch data.
+	BodyTemplate string `mapstructure:"body_template"`
+
+	// DeadLetterDir is an optional directory where deliveries that still
+	// failed after all retries are written, one JSON file per delivery.
+	DeadLetterDir string `mapstructure:"dead_letter_dir"`
 }
 
 // BlockingParamVal is an interface representing the common operations needed for
@@ -291,6 +327,29 @@ func parseHttpHandlerConfig(configParams interface{}) (*HttpHandlerConfig, error
 	} else {
 		config.Timeout = timeout
 	}
+	if config.MaxRetries < 0 {
+		return nil, fmt.Errorf("'max_retries' must not be negative")
+	}
+	if config.MaxConcurrency < 0 {
+		return nil, fmt.Errorf("'max_concurrency' must not be negative")
+	}
+	if config.RetryBackoffRaw == "" {
+		config.RetryBackoff = DefaultRetryBackoff
+	} else if backoff, err := time.ParseDuration(config.RetryBackoffRaw); err != nil {
+		return nil, fmt.Errorf("Failed to parse retry_backoff: %v", err)
+	} else {
+		config.RetryBackoff = backoff
+	}
+	if config.RetryMaxBackoffRaw == "" {
+		config.RetryMaxBackoff = DefaultRetryMaxBackoff
+	} else if backoff, err := time.ParseDuration(config.RetryMaxBackoffRaw); err != nil {
+		return nil, fmt.Errorf("Failed to parse retry_max_backoff: %v", err)
+	} else {
+		config.RetryMaxBackoff = backoff
+	}
+	if config.RetryMaxBackoff < config.RetryBackoff {
+		return nil, fmt.Errorf("'retry_max_backoff' must not be less than 'retry_backoff'")
+	}
 
 	return &config, nil
 }
@@ -6,7 +6,9 @@ package watch
 import (
 	"bytes"
 	"encoding/json"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestParseBasic(t *testing.T) {
@@ -43,6 +45,36 @@ func TestParse_exempt(t *testing.T) {
 	}
 }
 
+func TestParse_httpHandlerConfig(t *testing.T) {
+	t.Parallel()
+	params := makeParams(t, `{"type":"key", "key":"foo", "handler_type":"http",
+		"http_handler_config": {"path":"http://127.0.0.1:8000/watch", "hmac_secret":"s3cr3t",
+		"max_retries":3, "retry_backoff":"2s", "max_concurrency":4, "dead_letter_dir":"/tmp/dlq"}}`)
+	p, err := Parse(params)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	config := p.Exempt["http_handler_config"].(*HttpHandlerConfig)
+	if config.Method != "POST" || config.Timeout != DefaultTimeout {
+		t.Fatalf("bad: %#v", config)
+	}
+	if config.HMACSecret != "s3cr3t" || config.MaxRetries != 3 || config.MaxConcurrency != 4 {
+		t.Fatalf("bad: %#v", config)
+	}
+	if config.RetryBackoff != 2*time.Second || config.RetryMaxBackoff != DefaultRetryMaxBackoff {
+		t.Fatalf("bad: %#v", config)
+	}
+	if config.DeadLetterDir != "/tmp/dlq" {
+		t.Fatalf("bad: %#v", config)
+	}
+
+	params = makeParams(t, `{"type":"key", "key":"foo", "handler_type":"http",
+		"http_handler_config": {"path":"http://127.0.0.1:8000/watch", "retry_backoff":"2m"}}`)
+	if _, err := Parse(params); err == nil || !strings.Contains(err.Error(), "retry_max_backoff") {
+		t.Fatalf("expected error, got: %v", err)
+	}
+}
+
 func makeParams(t *testing.T, s string) map[string]interface{} {
 	var out map[string]interface{}
 	dec := json.NewDecoder(bytes.NewReader([]byte(s)))
@@ -0,0 +1,134 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package drain
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/mitchellh/cli"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/command/flags"
+)
+
+func New(ui cli.Ui) *cmd {
+	c := &cmd{UI: ui}
+	c.init()
+	return c
+}
+
+type cmd struct {
+	UI    cli.Ui
+	flags *flag.FlagSet
+	http  *flags.HTTPFlags
+	help  string
+
+	// flags
+	reason    string
+	timeout   time.Duration
+	threshold int
+}
+
+func (c *cmd) init() {
+	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
+	c.flags.StringVar(&c.reason, "reason", "",
+		"Text describing the reason for the drain.")
+	c.flags.DurationVar(&c.timeout, "timeout", 5*time.Minute,
+		"How long to wait for the proxies' active connections to drop below "+
+			"the threshold before deregistering them anyway.")
+	c.flags.IntVar(&c.threshold, "threshold", 1,
+		"Number of active connections per proxy below which the proxy is "+
+			"considered drained.")
+
+	c.http = &flags.HTTPFlags{}
+	flags.Merge(c.flags, c.http.ClientFlags())
+	c.help = flags.Usage(help, c.flags)
+}
+
+func (c *cmd) Run(args []string) int {
+	if err := c.flags.Parse(args); err != nil {
+		return 1
+	}
+	if len(c.flags.Args()) > 0 {
+		c.UI.Error(fmt.Sprintf("Too many arguments (expected 0, got %d)", len(c.flags.Args())))
+		return 1
+	}
+	if c.timeout <= 0 {
+		c.UI.Error("The -timeout flag must be strictly positive")
+		return 1
+	}
+	if c.threshold < 1 {
+		c.UI.Error("The -threshold flag must be strictly positive")
+		return 1
+	}
+
+	client, err := c.http.APIClient()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error connecting to Consul agent: %s", err))
+		return 1
+	}
+
+	c.UI.Info("Node maintenance is now enabled, draining sidecar proxies...")
+	result, err := client.Agent().DrainNode(api.AgentDrainOpts{
+		Reason:    c.reason,
+		Timeout:   c.timeout,
+		Threshold: c.threshold,
+	}, nil)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error draining node: %s", err))
+		return 1
+	}
+
+	for _, p := range result.Proxies {
+		status := "drained"
+		if !p.Drained {
+			status = fmt.Sprintf("%d active connections", p.ActiveConnections)
+		}
+		if p.Deregistered {
+			status += ", deregistered"
+		}
+		if p.Error != "" {
+			status += fmt.Sprintf(" (%s)", p.Error)
+		}
+		c.UI.Output(fmt.Sprintf("%s: %s", p.ServiceID, status))
+	}
+
+	if !result.Drained {
+		c.UI.Warn("Timed out waiting for the proxies to drain")
+		return 2
+	}
+	c.UI.Info("Node drained")
+	return 0
+}
+
+func (c *cmd) Synopsis() string {
+	return synopsis
+}
+
+func (c *cmd) Help() string {
+	return c.help
+}
+
+const synopsis = "Drains the node and deregisters its sidecar proxies"
+const help = `
+Usage: consul drain [options]
+
+  Places the node into maintenance mode, waits for the active connections
+  of the node's Envoy sidecar proxies to drop below the threshold and then
+  deregisters the proxies. The command blocks until the drain is complete
+  or the timeout is reached, in which case the proxies are deregistered
+  anyway and the command exits with code 2.
+
+  Active connections are read from the Envoy stats listener configured with
+  the "envoy_stats_bind_addr" proxy config, or from the default Envoy admin
+  listener at 127.0.0.1:19000 otherwise. Proxies whose stats can't be read
+  are waited on until the timeout.
+
+  The outcome of the drain is recorded in the output of the node maintenance
+  check. Use "consul maint -disable" to take the node out of maintenance.
+
+      $ consul drain -reason "Kernel upgrade" -timeout 2m
+`
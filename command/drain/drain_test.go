@@ -0,0 +1,107 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package drain
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/consul/agent"
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/testrpc"
+)
+
+func TestDrainCommand_noTabs(t *testing.T) {
+	t.Parallel()
+	if strings.ContainsRune(New(cli.NewMockUi()).Help(), '\t') {
+		t.Fatal("help has tabs")
+	}
+}
+
+func TestDrainCommand_Validation(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		args   []string
+		output string
+	}{
+		"extra args": {
+			args:   []string{"foo"},
+			output: "Too many arguments",
+		},
+		"zero timeout": {
+			args:   []string{"-timeout=0s"},
+			output: "-timeout flag must be strictly positive",
+		},
+		"zero threshold": {
+			args:   []string{"-threshold=0"},
+			output: "-threshold flag must be strictly positive",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			ui := cli.NewMockUi()
+			c := New(ui)
+			c.flags.SetOutput(ui.ErrorWriter)
+
+			require.Equal(t, 1, c.Run(tc.args))
+			require.Contains(t, ui.ErrorWriter.String(), tc.output)
+		})
+	}
+}
+
+func TestDrainCommand(t *testing.T) {
+	if testing.Short() {
+		t.Skip("too slow for testing.Short")
+	}
+
+	t.Parallel()
+	a := agent.NewTestAgent(t, ``)
+	defer a.Shutdown()
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+	// Serve the Envoy stats of an idle proxy.
+	stats := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "listener.0.0.0.0_21000.downstream_cx_active: 0")
+	}))
+	defer stats.Close()
+
+	require.NoError(t, a.AddService(agent.AddServiceRequest{
+		Service: &structs.NodeService{
+			Kind:    structs.ServiceKindConnectProxy,
+			ID:      "web-sidecar-proxy",
+			Service: "web-sidecar-proxy",
+			Port:    21000,
+			Proxy: structs.ConnectProxyConfig{
+				DestinationServiceName: "web",
+				DestinationServiceID:   "web",
+				Config: map[string]interface{}{
+					"envoy_stats_bind_addr": strings.TrimPrefix(stats.URL, "http://"),
+				},
+			},
+		},
+		Source: agent.ConfigSourceLocal,
+	}))
+
+	ui := cli.NewMockUi()
+	c := New(ui)
+	c.flags.SetOutput(ui.ErrorWriter)
+
+	code := c.Run([]string{"-http-addr=" + a.HTTPAddr(), "-reason=upgrade"})
+	require.Equal(t, 0, code, ui.ErrorWriter.String())
+	require.Contains(t, ui.OutputWriter.String(), "web-sidecar-proxy: drained, deregistered")
+	require.Contains(t, ui.OutputWriter.String(), "Node drained")
+
+	check := a.State.Check(structs.NodeMaintCheckID)
+	require.NotNil(t, check)
+	require.Equal(t, "upgrade", check.Notes)
+	require.Nil(t, a.State.Service(structs.NewServiceID("web-sidecar-proxy", nil)))
+}
@@ -0,0 +1,146 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package campaign
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/command/flags"
+	"github.com/mitchellh/cli"
+)
+
+const (
+	// defaultMonitorRetry is the number of 500 errors we will tolerate
+	// before declaring the leadership lost.
+	defaultMonitorRetry = 3
+)
+
+func New(ui cli.Ui, shutdownCh <-chan struct{}) *cmd {
+	c := &cmd{UI: ui, ShutdownCh: shutdownCh}
+	c.init()
+	return c
+}
+
+type cmd struct {
+	UI    cli.Ui
+	flags *flag.FlagSet
+	http  *flags.HTTPFlags
+	help  string
+
+	ShutdownCh <-chan struct{}
+
+	// flags
+	monitorRetry int
+	name         string
+}
+
+func (c *cmd) init() {
+	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
+	c.flags.IntVar(&c.monitorRetry, "monitor-retry", defaultMonitorRetry,
+		"Number of times to retry if Consul returns a 500 error while monitoring "+
+			"the leadership. The default value is 3. Set this value to 0 to "+
+			"disable retries.")
+	c.flags.StringVar(&c.name, "name", "",
+		"Optional name to associate with the election session. If not provided, "+
+			"one is generated based on the key.")
+
+	c.http = &flags.HTTPFlags{}
+	flags.Merge(c.flags, c.http.ClientFlags())
+	flags.Merge(c.flags, c.http.ServerFlags())
+	flags.Merge(c.flags, c.http.MultiTenancyFlags())
+	c.help = flags.Usage(help, c.flags)
+}
+
+func (c *cmd) Run(args []string) int {
+	if err := c.flags.Parse(args); err != nil {
+		return 1
+	}
+
+	var key, value string
+	args = c.flags.Args()
+	switch len(args) {
+	case 0:
+		c.UI.Error("Missing KEY argument")
+		return 1
+	case 1:
+		key = args[0]
+	case 2:
+		key, value = args[0], args[1]
+	default:
+		c.UI.Error(fmt.Sprintf("Too many arguments (expected 1 or 2, got %d)", len(args)))
+		return 1
+	}
+	key = strings.TrimPrefix(key, "/")
+
+	if c.monitorRetry < 0 {
+		c.UI.Error("Number for 'monitor-retry' must be >= 0")
+		return 1
+	}
+	if c.name == "" {
+		c.name = fmt.Sprintf("Consul election at '%s'", key)
+	}
+
+	client, err := c.http.APIClient()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error connecting to Consul agent: %s", err))
+		return 1
+	}
+
+	e, err := client.ElectionOpts(&api.ElectionOptions{
+		Key:            key,
+		Value:          []byte(value),
+		SessionName:    c.name,
+		MonitorRetries: c.monitorRetry,
+	})
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Election setup failed: %s", err))
+		return 1
+	}
+
+	c.UI.Info(fmt.Sprintf("Campaigning for the leadership of %q", key))
+	for leader := range e.Run(c.ShutdownCh) {
+		if leader {
+			c.UI.Info(fmt.Sprintf("Elected leader of %q", key))
+		} else {
+			c.UI.Warn(fmt.Sprintf("Lost the leadership of %q, campaigning again", key))
+		}
+	}
+
+	// The election only stops on its own if the key is used for something
+	// else than a lock.
+	select {
+	case <-c.ShutdownCh:
+	default:
+		c.UI.Error(fmt.Sprintf("Error campaigning: %s", api.ErrLockConflict))
+		return 1
+	}
+	return 0
+}
+
+func (c *cmd) Synopsis() string {
+	return synopsis
+}
+
+func (c *cmd) Help() string {
+	return c.help
+}
+
+const synopsis = "Campaign for the leadership of an election"
+const help = `
+Usage: consul election campaign [options] KEY [VALUE]
+
+  Campaigns for the leadership of the election at the given key until the
+  command is interrupted. While elected, the value is published in the key
+  so that other clients can observe the current leader. If the leadership is
+  lost, for example because the session is invalidated, the command
+  campaigns again with a new session. The leadership is resigned when the
+  command is interrupted.
+
+      $ consul election campaign service/web/leader 10.0.0.1:8080
+
+  The key must be writable.
+`
@@ -0,0 +1,112 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package campaign
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+
+	"github.com/hashicorp/consul/agent"
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+	"github.com/hashicorp/consul/testrpc"
+)
+
+func TestElectionCampaignCommand_noTabs(t *testing.T) {
+	t.Parallel()
+	if strings.ContainsRune(New(cli.NewMockUi(), nil).Help(), '\t') {
+		t.Fatal("help has tabs")
+	}
+}
+
+func TestElectionCampaignCommand_Validation(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		args   []string
+		output string
+	}{
+		"no key": {
+			[]string{},
+			"Missing KEY argument",
+		},
+		"extra args": {
+			[]string{"foo", "bar", "baz"},
+			"Too many arguments",
+		},
+		"bad monitor retry": {
+			[]string{"-monitor-retry=-1", "foo"},
+			"must be >= 0",
+		},
+	}
+
+	for name, tc := range cases {
+		ui := cli.NewMockUi()
+		c := New(ui, nil)
+
+		code := c.Run(tc.args)
+		if code == 0 {
+			t.Errorf("%s: expected non-zero exit", name)
+		}
+
+		output := ui.ErrorWriter.String()
+		if !strings.Contains(output, tc.output) {
+			t.Errorf("%s: expected %q to contain %q", name, output, tc.output)
+		}
+	}
+}
+
+func TestElectionCampaignCommand(t *testing.T) {
+	if testing.Short() {
+		t.Skip("too slow for testing.Short")
+	}
+
+	t.Parallel()
+	a := agent.NewTestAgent(t, ``)
+	defer a.Shutdown()
+	client := a.Client()
+
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+	ui := cli.NewMockUi()
+	shutdownCh := make(chan struct{})
+	c := New(ui, shutdownCh)
+
+	args := []string{"-http-addr=" + a.HTTPAddr(), "test/leader", "instance-1"}
+	codeCh := make(chan int, 1)
+	go func() {
+		codeCh <- c.Run(args)
+	}()
+
+	e, err := client.Election("test/leader")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	retry.Run(t, func(r *retry.R) {
+		leader, err := e.Leader()
+		if err != nil {
+			r.Fatalf("err: %v", err)
+		}
+		if leader == nil || string(leader.Value) != "instance-1" {
+			r.Fatalf("bad: %v", leader)
+		}
+		if output := ui.OutputWriter.String(); !strings.Contains(output, `Elected leader of "test/leader"`) {
+			r.Fatalf("bad: %#v", output)
+		}
+	})
+
+	// Interrupting the command should resign
+	close(shutdownCh)
+	if code := <-codeCh; code != 0 {
+		t.Fatalf("bad: %d. %#v", code, ui.ErrorWriter.String())
+	}
+	leader, err := e.Leader()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if leader != nil {
+		t.Fatalf("bad: %v", leader)
+	}
+}
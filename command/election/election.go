@@ -0,0 +1,51 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package election
+
+import (
+	"github.com/hashicorp/consul/command/flags"
+	"github.com/mitchellh/cli"
+)
+
+func New() *cmd {
+	return &cmd{}
+}
+
+type cmd struct{}
+
+func (c *cmd) Run(args []string) int {
+	return cli.RunResultHelp
+}
+
+func (c *cmd) Synopsis() string {
+	return synopsis
+}
+
+func (c *cmd) Help() string {
+	return flags.Usage(help, nil)
+}
+
+const synopsis = "Run and observe leader elections"
+const help = `
+Usage: consul election <subcommand> [options] [args]
+
+  This command has subcommands for running leader elections on a key of the
+  KV store, and for observing their leader. The leader holds a lock on the
+  key and publishes a value in it.
+
+  Campaign for the leadership of "service/web/leader", publishing the address
+  of this instance while it is the leader:
+
+      $ consul election campaign service/web/leader 10.0.0.1:8080
+
+  Read the current leader:
+
+      $ consul election leader service/web/leader
+
+  Watch the leader change:
+
+      $ consul election observe service/web/leader
+
+  For more examples, ask for subcommand help or view the documentation.
+`
@@ -0,0 +1,109 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package leader
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/hashicorp/consul/command/flags"
+	"github.com/mitchellh/cli"
+)
+
+func New(ui cli.Ui) *cmd {
+	c := &cmd{UI: ui}
+	c.init()
+	return c
+}
+
+type cmd struct {
+	UI    cli.Ui
+	flags *flag.FlagSet
+	http  *flags.HTTPFlags
+	help  string
+}
+
+func (c *cmd) init() {
+	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
+	c.http = &flags.HTTPFlags{}
+	flags.Merge(c.flags, c.http.ClientFlags())
+	flags.Merge(c.flags, c.http.ServerFlags())
+	flags.Merge(c.flags, c.http.MultiTenancyFlags())
+	c.help = flags.Usage(help, c.flags)
+}
+
+func (c *cmd) Run(args []string) int {
+	if err := c.flags.Parse(args); err != nil {
+		return 1
+	}
+
+	args = c.flags.Args()
+	switch len(args) {
+	case 0:
+		c.UI.Error("Missing KEY argument")
+		return 1
+	case 1:
+	default:
+		c.UI.Error(fmt.Sprintf("Too many arguments (expected 1, got %d)", len(args)))
+		return 1
+	}
+	key := strings.TrimPrefix(args[0], "/")
+
+	client, err := c.http.APIClient()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error connecting to Consul agent: %s", err))
+		return 1
+	}
+
+	e, err := client.Election(key)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Election setup failed: %s", err))
+		return 1
+	}
+	leader, err := e.Leader()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error querying Consul agent: %s", err))
+		return 1
+	}
+	if leader == nil {
+		c.UI.Error(fmt.Sprintf("Error! No leader for: %s", key))
+		return 1
+	}
+
+	var b bytes.Buffer
+	tw := tabwriter.NewWriter(&b, 0, 2, 6, ' ', 0)
+	fmt.Fprintf(tw, "Session\t%s\n", leader.Session)
+	fmt.Fprintf(tw, "LockIndex\t%d\n", leader.LockIndex)
+	fmt.Fprintf(tw, "Value\t%s", leader.Value)
+	if err := tw.Flush(); err != nil {
+		c.UI.Error(fmt.Sprintf("Error rendering leader: %s", err))
+		return 1
+	}
+	c.UI.Info(b.String())
+	return 0
+}
+
+func (c *cmd) Synopsis() string {
+	return synopsis
+}
+
+func (c *cmd) Help() string {
+	return c.help
+}
+
+const synopsis = "Read the current leader of an election"
+const help = `
+Usage: consul election leader [options] KEY
+
+  Reads the session, lock index and value of the current leader of the
+  election at the given key. The lock index is incremented each time the
+  leadership is acquired, so it can be used as a fencing token.
+
+      $ consul election leader service/web/leader
+
+  The command fails if there is no leader.
+`
@@ -0,0 +1,73 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package leader
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+
+	"github.com/hashicorp/consul/agent"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/testrpc"
+)
+
+func TestElectionLeaderCommand_noTabs(t *testing.T) {
+	t.Parallel()
+	if strings.ContainsRune(New(cli.NewMockUi()).Help(), '\t') {
+		t.Fatal("help has tabs")
+	}
+}
+
+func TestElectionLeaderCommand(t *testing.T) {
+	if testing.Short() {
+		t.Skip("too slow for testing.Short")
+	}
+
+	t.Parallel()
+	a := agent.NewTestAgent(t, ``)
+	defer a.Shutdown()
+	client := a.Client()
+
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+	args := []string{"-http-addr=" + a.HTTPAddr(), "test/leader"}
+
+	// There is no leader yet
+	ui := cli.NewMockUi()
+	if code := New(ui).Run(args); code != 1 {
+		t.Fatalf("bad: %d. %#v", code, ui.OutputWriter.String())
+	}
+	if output := ui.ErrorWriter.String(); !strings.Contains(output, "No leader for: test/leader") {
+		t.Fatalf("bad: %#v", output)
+	}
+
+	e, err := client.ElectionOpts(&api.ElectionOptions{
+		Key:   "test/leader",
+		Value: []byte("instance-1"),
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := e.Campaign(nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer e.Resign()
+	leader, err := e.Leader()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	ui = cli.NewMockUi()
+	if code := New(ui).Run(args); code != 0 {
+		t.Fatalf("bad: %d. %#v", code, ui.ErrorWriter.String())
+	}
+	output := ui.OutputWriter.String()
+	for _, expected := range []string{leader.Session, "instance-1", "LockIndex"} {
+		if !strings.Contains(output, expected) {
+			t.Fatalf("expected %q to contain %q", output, expected)
+		}
+	}
+}
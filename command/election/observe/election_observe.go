@@ -0,0 +1,95 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package observe
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/consul/command/flags"
+	"github.com/mitchellh/cli"
+)
+
+func New(ui cli.Ui, shutdownCh <-chan struct{}) *cmd {
+	c := &cmd{UI: ui, ShutdownCh: shutdownCh}
+	c.init()
+	return c
+}
+
+type cmd struct {
+	UI    cli.Ui
+	flags *flag.FlagSet
+	http  *flags.HTTPFlags
+	help  string
+
+	ShutdownCh <-chan struct{}
+}
+
+func (c *cmd) init() {
+	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
+	c.http = &flags.HTTPFlags{}
+	flags.Merge(c.flags, c.http.ClientFlags())
+	flags.Merge(c.flags, c.http.ServerFlags())
+	flags.Merge(c.flags, c.http.MultiTenancyFlags())
+	c.help = flags.Usage(help, c.flags)
+}
+
+func (c *cmd) Run(args []string) int {
+	if err := c.flags.Parse(args); err != nil {
+		return 1
+	}
+
+	args = c.flags.Args()
+	switch len(args) {
+	case 0:
+		c.UI.Error("Missing KEY argument")
+		return 1
+	case 1:
+	default:
+		c.UI.Error(fmt.Sprintf("Too many arguments (expected 1, got %d)", len(args)))
+		return 1
+	}
+	key := strings.TrimPrefix(args[0], "/")
+
+	client, err := c.http.APIClient()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error connecting to Consul agent: %s", err))
+		return 1
+	}
+
+	e, err := client.Election(key)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Election setup failed: %s", err))
+		return 1
+	}
+
+	for leader := range e.Observe(c.ShutdownCh) {
+		if leader == nil {
+			c.UI.Output("No leader")
+			continue
+		}
+		c.UI.Output(fmt.Sprintf("Leader session %s (lock index %d): %s",
+			leader.Session, leader.LockIndex, leader.Value))
+	}
+	return 0
+}
+
+func (c *cmd) Synopsis() string {
+	return synopsis
+}
+
+func (c *cmd) Help() string {
+	return c.help
+}
+
+const synopsis = "Watch the leader of an election"
+const help = `
+Usage: consul election observe [options] KEY
+
+  Prints the current leader of the election at the given key, and the new
+  leader each time it changes, until the command is interrupted.
+
+      $ consul election observe service/web/leader
+`
@@ -0,0 +1,75 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package observe
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+
+	"github.com/hashicorp/consul/agent"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+	"github.com/hashicorp/consul/testrpc"
+)
+
+func TestElectionObserveCommand_noTabs(t *testing.T) {
+	t.Parallel()
+	if strings.ContainsRune(New(cli.NewMockUi(), nil).Help(), '\t') {
+		t.Fatal("help has tabs")
+	}
+}
+
+func TestElectionObserveCommand(t *testing.T) {
+	if testing.Short() {
+		t.Skip("too slow for testing.Short")
+	}
+
+	t.Parallel()
+	a := agent.NewTestAgent(t, ``)
+	defer a.Shutdown()
+	client := a.Client()
+
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+	ui := cli.NewMockUi()
+	shutdownCh := make(chan struct{})
+	c := New(ui, shutdownCh)
+
+	args := []string{"-http-addr=" + a.HTTPAddr(), "test/leader"}
+	codeCh := make(chan int, 1)
+	go func() {
+		codeCh <- c.Run(args)
+	}()
+
+	retry.Run(t, func(r *retry.R) {
+		if output := ui.OutputWriter.String(); !strings.Contains(output, "No leader") {
+			r.Fatalf("bad: %#v", output)
+		}
+	})
+
+	e, err := client.ElectionOpts(&api.ElectionOptions{
+		Key:   "test/leader",
+		Value: []byte("instance-1"),
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := e.Campaign(nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer e.Resign()
+
+	retry.Run(t, func(r *retry.R) {
+		if output := ui.OutputWriter.String(); !strings.Contains(output, "(lock index 1): instance-1") {
+			r.Fatalf("bad: %#v", output)
+		}
+	})
+
+	close(shutdownCh)
+	if code := <-codeCh; code != 0 {
+		t.Fatalf("bad: %d. %#v", code, ui.ErrorWriter.String())
+	}
+}
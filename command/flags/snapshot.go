@@ -0,0 +1,56 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package flags
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// SnapshotEncryptionFlags are the flags used to encrypt and decrypt snapshot
+// archives.
+type SnapshotEncryptionFlags struct {
+	keyFile        string
+	passphraseFile string
+}
+
+// Flags returns the flag set with the encryption flags.
+func (f *SnapshotEncryptionFlags) Flags() *flag.FlagSet {
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	fs.StringVar(&f.keyFile, "key-file", "",
+		"Path to a file containing a base64-encoded 32-byte key used to encrypt "+
+			"or decrypt the snapshot archive, such as one generated by \"consul keygen\".")
+	fs.StringVar(&f.passphraseFile, "passphrase-file", "",
+		"Path to a file containing a passphrase used to encrypt or decrypt the "+
+			"snapshot archive. Can't be used together with -key-file.")
+	return fs
+}
+
+// Options reads the key or passphrase files and returns the snapshot options
+// with the encryption key or passphrase set.
+func (f *SnapshotEncryptionFlags) Options() (api.SnapshotOptions, error) {
+	var opts api.SnapshotOptions
+	if f.keyFile != "" && f.passphraseFile != "" {
+		return opts, fmt.Errorf("Only one of -key-file or -passphrase-file may be given")
+	}
+	if f.keyFile != "" {
+		key, err := os.ReadFile(f.keyFile)
+		if err != nil {
+			return opts, fmt.Errorf("Error reading key file: %s", err)
+		}
+		opts.EncryptionKey = strings.TrimSpace(string(key))
+	}
+	if f.passphraseFile != "" {
+		passphrase, err := os.ReadFile(f.passphraseFile)
+		if err != nil {
+			return opts, fmt.Errorf("Error reading passphrase file: %s", err)
+		}
+		opts.EncryptionPassphrase = strings.TrimRight(string(passphrase), "\r\n")
+	}
+	return opts, nil
+}
@@ -35,6 +35,7 @@ type cmd struct {
 	session       string
 	acquire       bool
 	release       bool
+	ttl           string
 
 	// testStdin is the input for testing.
 	testStdin io.Reader
@@ -67,6 +68,10 @@ func (c *cmd) init() {
 		"Forfeit the lock on the key at the given path. This requires the "+
 			"-session flag to be set. The key must be held by the session in order to "+
 			"be unlocked. The default value is false.")
+	c.flags.StringVar(&c.ttl, "ttl", "",
+		"Duration after which the key is deleted unless it is written again, "+
+			"such as \"30s\". Writing the key without a TTL removes the expiry. "+
+			"The default value is empty (no expiry).")
 
 	c.http = &flags.HTTPFlags{}
 	flags.Merge(c.flags, c.http.ClientFlags())
@@ -115,6 +120,7 @@ func (c *cmd) Run(args []string) int {
 		Flags:       c.kvflags,
 		Value:       dataBytes,
 		Session:     c.session,
+		TTL:         c.ttl,
 	}
 
 	switch {
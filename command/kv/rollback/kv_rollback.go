@@ -0,0 +1,162 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package rollback
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/mitchellh/cli"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/command/flags"
+)
+
+func New(ui cli.Ui) *cmd {
+	c := &cmd{UI: ui}
+	c.init()
+	return c
+}
+
+type cmd struct {
+	UI    cli.Ui
+	flags *flag.FlagSet
+	http  *flags.HTTPFlags
+	help  string
+
+	// flags
+	index uint64
+}
+
+func (c *cmd) init() {
+	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
+	c.flags.Uint64Var(&c.index, "index", 0,
+		"Roll the key back to the version that was current at this index. "+
+			"The default is to roll back to the most recent revision of the key.")
+
+	c.http = &flags.HTTPFlags{}
+	flags.Merge(c.flags, c.http.ClientFlags())
+	flags.Merge(c.flags, c.http.ServerFlags())
+	flags.Merge(c.flags, c.http.MultiTenancyFlags())
+	c.help = flags.Usage(help, c.flags)
+}
+
+func (c *cmd) Run(args []string) int {
+	if err := c.flags.Parse(args); err != nil {
+		return 1
+	}
+
+	key := ""
+	args = c.flags.Args()
+	switch len(args) {
+	case 0:
+		c.UI.Error("Error! Missing KEY argument")
+		return 1
+	case 1:
+		key = args[0]
+	default:
+		c.UI.Error(fmt.Sprintf("Error! Too many arguments (expected 1, got %d)", len(args)))
+		return 1
+	}
+
+	// Create and test the HTTP client
+	client, err := c.http.APIClient()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error connecting to Consul agent: %s", err))
+		return 1
+	}
+	kv := client.KV()
+
+	current, _, err := kv.Get(key, nil)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error! Failed to read key %s: %s", key, err))
+		return 1
+	}
+
+	var target *api.KVPair
+	if c.index == 0 {
+		revs, _, err := kv.Revisions(key, nil)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error! Failed to read the revisions of key %s: %s", key, err))
+			return 1
+		}
+		if len(revs) == 0 {
+			c.UI.Error(fmt.Sprintf("Error! No revision of key %s is kept", key))
+			return 1
+		}
+		target = &revs[0].KVPair
+	} else {
+		target, _, err = kv.GetAtIndex(key, c.index, nil)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error! Failed to read key %s at index %d: %s", key, c.index, err))
+			return 1
+		}
+		if target == nil {
+			c.UI.Error(fmt.Sprintf("Error! No revision of key %s is kept for index %d", key, c.index))
+			return 1
+		}
+	}
+
+	if current != nil && current.ModifyIndex == target.ModifyIndex {
+		c.UI.Info(fmt.Sprintf("Key %s is already at the revision from index %d", key, target.ModifyIndex))
+		return 0
+	}
+
+	// Write the previous version with a CAS on the current version so that a
+	// concurrent write is not overwritten.
+	pair := &api.KVPair{
+		Key:   key,
+		Flags: target.Flags,
+		Value: target.Value,
+		TTL:   target.TTL,
+	}
+	if current != nil {
+		pair.ModifyIndex = current.ModifyIndex
+	}
+	ok, _, err := kv.CAS(pair, nil)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error! Did not roll back %s: %s", key, err))
+		return 1
+	}
+	if !ok {
+		c.UI.Error(fmt.Sprintf("Error! Did not roll back %s: the key was modified concurrently", key))
+		return 1
+	}
+
+	c.UI.Info(fmt.Sprintf("Success! Rolled back %s to the revision from index %d", key, target.ModifyIndex))
+	return 0
+}
+
+func (c *cmd) Synopsis() string {
+	return synopsis
+}
+
+func (c *cmd) Help() string {
+	return c.help
+}
+
+const (
+	synopsis = "Restores a previous revision of a key"
+	help     = `
+Usage: consul kv rollback [options] KEY
+
+  Writes a previous revision of the key back to the key-value store. This
+  requires revision history to be enabled for the key with a kv-prefix config
+  entry.
+
+  To roll the key back to its most recent revision:
+
+      $ consul kv rollback config/redis/maxconns
+
+  To roll the key back to the version that was current at an index:
+
+      $ consul kv rollback -index=844 config/redis/maxconns
+
+  The write is a Check-And-Set operation on the current version of the key, so
+  it fails if the key is modified concurrently. The rollback itself is kept as
+  a new revision and can be rolled back as well.
+
+  Additional flags and more advanced use cases are detailed below.
+`
+)
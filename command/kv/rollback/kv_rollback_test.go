@@ -0,0 +1,127 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package rollback
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/consul/agent"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/testrpc"
+)
+
+func TestKVRollbackCommand_noTabs(t *testing.T) {
+	t.Parallel()
+	if strings.ContainsRune(New(nil).Help(), '\t') {
+		t.Fatal("help has tabs")
+	}
+}
+
+func TestKVRollbackCommand_Validation(t *testing.T) {
+	t.Parallel()
+	ui := cli.NewMockUi()
+	c := New(ui)
+
+	cases := map[string]struct {
+		args   []string
+		output string
+	}{
+		"no key": {
+			[]string{},
+			"Missing KEY argument",
+		},
+		"extra args": {
+			[]string{"foo", "bar"},
+			"Too many arguments",
+		},
+	}
+
+	for name, tc := range cases {
+		c.init()
+		// Ensure our buffer is always clear
+		if ui.ErrorWriter != nil {
+			ui.ErrorWriter.Reset()
+		}
+		if ui.OutputWriter != nil {
+			ui.OutputWriter.Reset()
+		}
+
+		code := c.Run(tc.args)
+		if code == 0 {
+			t.Errorf("%s: expected non-zero exit", name)
+		}
+
+		output := ui.ErrorWriter.String()
+		if !strings.Contains(output, tc.output) {
+			t.Errorf("%s: expected %q to contain %q", name, output, tc.output)
+		}
+	}
+}
+
+func TestKVRollbackCommand(t *testing.T) {
+	if testing.Short() {
+		t.Skip("too slow for testing.Short")
+	}
+
+	t.Parallel()
+	a := agent.NewTestAgent(t, ``)
+	defer a.Shutdown()
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+	client := a.Client()
+
+	_, _, err := client.ConfigEntries().Set(&api.KVPrefixConfigEntry{
+		Kind:    api.KVPrefix,
+		Name:    "config",
+		Prefix:  "config/",
+		History: &api.KVHistoryConfig{MaxRevisions: 10},
+	}, nil)
+	require.NoError(t, err)
+
+	kv := client.KV()
+	var indexes []uint64
+	for _, value := range []string{"v1", "v2", "v3"} {
+		_, err := kv.Put(&api.KVPair{Key: "config/app", Value: []byte(value)}, nil)
+		require.NoError(t, err)
+		pair, _, err := kv.Get("config/app", nil)
+		require.NoError(t, err)
+		indexes = append(indexes, pair.ModifyIndex)
+	}
+
+	run := func(t *testing.T, args ...string) (int, *cli.MockUi) {
+		ui := cli.NewMockUi()
+		c := New(ui)
+		return c.Run(append([]string{"-http-addr=" + a.HTTPAddr()}, args...)), ui
+	}
+	value := func(t *testing.T) string {
+		pair, _, err := kv.Get("config/app", nil)
+		require.NoError(t, err)
+		return string(pair.Value)
+	}
+
+	// Without -index the most recent revision is restored.
+	code, ui := run(t, "config/app")
+	require.Equal(t, 0, code, ui.ErrorWriter.String())
+	require.Contains(t, ui.OutputWriter.String(), fmt.Sprintf("to the revision from index %d", indexes[1]))
+	require.Equal(t, "v2", value(t))
+
+	code, ui = run(t, "-index="+fmt.Sprint(indexes[0]), "config/app")
+	require.Equal(t, 0, code, ui.ErrorWriter.String())
+	require.Equal(t, "v1", value(t))
+
+	code, ui = run(t, "-index="+fmt.Sprint(indexes[0]-1), "config/app")
+	require.Equal(t, 1, code)
+	require.Contains(t, ui.ErrorWriter.String(), "No revision of key config/app is kept")
+
+	// Keys without history can't be rolled back.
+	_, err = kv.Put(&api.KVPair{Key: "other", Value: []byte("a")}, nil)
+	require.NoError(t, err)
+	code, ui = run(t, "other")
+	require.Equal(t, 1, code)
+	require.Contains(t, ui.ErrorWriter.String(), "No revision of key other is kept")
+}
@@ -60,6 +60,7 @@ type cmd struct {
 	name               string
 	passStdin          bool
 	propagateChildCode bool
+	queue              bool
 	shell              bool
 	timeout            time.Duration
 }
@@ -91,6 +92,10 @@ func (c *cmd) init() {
 			"is generated based on the provided child command.")
 	c.flags.BoolVar(&c.passStdin, "pass-stdin", false,
 		"Pass stdin to the child process.")
+	c.flags.BoolVar(&c.queue, "queue", false,
+		"Wait for the lock in a queue, so that contenders using this flag are "+
+			"granted the lock in the order they started waiting for it. This "+
+			"can only be used when -n is 1. The default value is false.")
 	c.flags.BoolVar(&c.shell, "shell", true,
 		"Use a shell to run the command (can set a custom shell via the SHELL "+
 			"environment variable).")
@@ -126,6 +131,11 @@ func (c *cmd) run(args []string, lu **LockUnlock) int {
 		return 1
 	}
 
+	if c.queue && c.limit != 1 {
+		c.UI.Error("Queued locks can only be used when the lock holder limit is 1")
+		return 1
+	}
+
 	// Verify the prefix and child are provided
 	extra := c.flags.Args()
 	if len(extra) < 2 {
@@ -277,12 +287,16 @@ func (c *cmd) setupLock(client *api.Client, prefix, name string,
 	key := path.Join(prefix, api.DefaultSemaphoreKey)
 	if c.verbose {
 		c.UI.Info(fmt.Sprintf("Setting up lock at path: %s", key))
+		if c.queue {
+			c.UI.Info(fmt.Sprintf("Waiting in lock queue at prefix: %s", key+api.LockQueueSuffix))
+		}
 	}
 	opts := api.LockOptions{
 		Key:              key,
 		SessionName:      name,
 		MonitorRetries:   retry,
 		MonitorRetryTime: defaultMonitorRetryTime,
+		Queued:           c.queue,
 	}
 	if oneshot {
 		opts.LockTryOnce = true
@@ -484,5 +498,8 @@ Usage: consul lock [options] prefix child...
   exclusion. Setting a higher value switches to a semaphore allowing multiple
   holders to coordinate.
 
+  With -queue, contenders wait for the lock in a queue and are granted the
+  lock in the order they started waiting, instead of racing for it.
+
   The prefix provided must have write privileges.
 `
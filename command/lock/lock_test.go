@@ -41,6 +41,7 @@ func TestLockCommand_BadArgs(t *testing.T) {
 	argFail(t, []string{"-try=blah", "test/prefix", "date"}, "parse error")
 	argFail(t, []string{"-try=-10s", "test/prefix", "date"}, "Timeout must be positive")
 	argFail(t, []string{"-monitor-retry=-5", "test/prefix", "date"}, "must be >= 0")
+	argFail(t, []string{"-queue", "-n=2", "test/prefix", "date"}, "Queued locks can only be used when the lock holder limit is 1")
 }
 
 func TestLockCommand(t *testing.T) {
@@ -139,6 +140,53 @@ func TestLockCommand_TryLock(t *testing.T) {
 	}
 }
 
+func TestLockCommand_Queue(t *testing.T) {
+	if testing.Short() {
+		t.Skip("too slow for testing.Short")
+	}
+
+	t.Parallel()
+	a := agent.NewTestAgent(t, ``)
+	defer a.Shutdown()
+
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+	ui := cli.NewMockUi()
+	c := New(ui, nil)
+
+	filePath := filepath.Join(a.Config.DataDir, "test_touch")
+	args := []string{"-http-addr=" + a.HTTPAddr(), "-queue", "test/prefix", "touch", filePath}
+
+	// Run the command.
+	var lu *LockUnlock
+	code := c.run(args, &lu)
+	if code != 0 {
+		t.Fatalf("bad: %d. %#v", code, ui.ErrorWriter.String())
+	}
+	_, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Make sure the queue option was set correctly.
+	opts, ok := lu.rawOpts.(*api.LockOptions)
+	if !ok {
+		t.Fatalf("bad type")
+	}
+	if !opts.Queued {
+		t.Fatalf("bad: %#v", opts)
+	}
+
+	// The lock should have been cleaned up, including its queue.
+	pairs, _, err := a.Client().KV().List("test/prefix/", nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(pairs) != 0 {
+		t.Fatalf("bad: %v", pairs)
+	}
+}
+
 func TestLockCommand_TrySemaphore(t *testing.T) {
 	if testing.Short() {
 		t.Skip("too slow for testing.Short")
@@ -0,0 +1,310 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package inspectdata
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/raft"
+	raftboltdb "github.com/hashicorp/raft-boltdb/v2"
+	raftwal "github.com/hashicorp/raft-wal"
+	"github.com/hashicorp/raft-wal/fs"
+	"github.com/hashicorp/raft-wal/metadb"
+	"github.com/hashicorp/raft-wal/segment"
+	"github.com/hashicorp/raft-wal/types"
+	"go.etcd.io/bbolt"
+)
+
+const (
+	backendBoltDB = "boltdb"
+	backendWAL    = "wal"
+
+	// lockTimeout is how long we wait for the lock of a BoltDB file. The
+	// lock is held by a running server.
+	lockTimeout = time.Second
+)
+
+// logStore is read-only access to the Raft log and stable store of a data
+// directory.
+type logStore interface {
+	FirstIndex() (uint64, error)
+	LastIndex() (uint64, error)
+	GetLog(index uint64, log *raft.Log) error
+	Get(key []byte) ([]byte, error)
+	GetUint64(key []byte) (uint64, error)
+	Close() error
+}
+
+// openLogStore opens the Raft log in the raft directory of a data directory
+// without modifying it. It picks the backend the same way the server does:
+// raft.db is used if it exists, even if the WAL is configured.
+func openLogStore(raftDir string) (logStore, string, error) {
+	boltFile := filepath.Join(raftDir, "raft.db")
+	if _, err := os.Stat(boltFile); err == nil {
+		store, err := raftboltdb.New(raftboltdb.Options{
+			Path: boltFile,
+			BoltOptions: &bbolt.Options{
+				ReadOnly: true,
+				Timeout:  lockTimeout,
+			},
+		})
+		if err != nil {
+			return nil, "", lockError(boltFile, err)
+		}
+		return store, backendBoltDB, nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, "", err
+	}
+
+	walDir := filepath.Join(raftDir, "wal")
+	if _, err := os.Stat(filepath.Join(walDir, metadb.FileName)); err == nil {
+		store, err := openWALStore(walDir)
+		if err != nil {
+			return nil, "", err
+		}
+		return store, backendWAL, nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, "", err
+	}
+
+	return nil, "", fmt.Errorf("no Raft log found in %s, expected raft.db or wal/%s", raftDir, metadb.FileName)
+}
+
+func lockError(file string, err error) error {
+	if errors.Is(err, bbolt.ErrTimeout) {
+		return fmt.Errorf("%s is locked, the Consul server using this data directory must be stopped first", file)
+	}
+	return fmt.Errorf("failed to open %s: %v", file, err)
+}
+
+// walStore reads the segments of a WAL log store.
+//
+// The WAL itself can't be opened without writing to its files, so the
+// metadata is read with a read-only BoltDB handle, sealed segments are read
+// in place, and the unsealed tail segment is recovered from a temporary copy.
+type walStore struct {
+	meta     *bbolt.DB
+	segments []*walSegment
+	tmpDir   string
+	codec    raftwal.BinaryCodec
+}
+
+type walSegment struct {
+	info   types.SegmentInfo
+	reader types.SegmentReader
+
+	// last is the last index in the segment, which is only recorded in the
+	// metadata of sealed segments.
+	last uint64
+}
+
+func openWALStore(dir string) (_ *walStore, err error) {
+	metaFile := filepath.Join(dir, metadb.FileName)
+	db, err := bbolt.Open(metaFile, 0644, &bbolt.Options{
+		ReadOnly: true,
+		Timeout:  lockTimeout,
+	})
+	if err != nil {
+		return nil, lockError(metaFile, err)
+	}
+	s := &walStore{meta: db}
+	defer func() {
+		if err != nil {
+			s.Close()
+		}
+	}()
+
+	var state types.PersistentState
+	err = db.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket([]byte(metadb.MetaBucket))
+		if bucket == nil {
+			return nil
+		}
+		raw := bucket.Get([]byte(metadb.MetaKey))
+		if raw == nil {
+			return nil
+		}
+		return json.Unmarshal(raw, &state)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read WAL metadata: %v", err)
+	}
+	sort.Slice(state.Segments, func(i, j int) bool {
+		return state.Segments[i].BaseIndex < state.Segments[j].BaseIndex
+	})
+
+	filer := segment.NewFiler(dir, fs.New())
+	for _, info := range state.Segments {
+		if !info.SealTime.IsZero() {
+			r, err := filer.Open(info)
+			if err != nil {
+				return nil, fmt.Errorf("failed to open segment %s: %v", segment.FileName(info), err)
+			}
+			s.segments = append(s.segments, &walSegment{info: info, reader: r, last: info.MaxIndex})
+			continue
+		}
+
+		// Recovering the tail segment may truncate a partially written
+		// entry, so it's done on a copy.
+		if s.tmpDir == "" {
+			s.tmpDir, err = os.MkdirTemp("", "consul-raft-inspect")
+			if err != nil {
+				return nil, err
+			}
+		}
+		name := segment.FileName(info)
+		if err := copyFile(filepath.Join(dir, name), filepath.Join(s.tmpDir, name)); err != nil {
+			return nil, fmt.Errorf("failed to copy tail segment %s: %v", name, err)
+		}
+		w, err := segment.NewFiler(s.tmpDir, fs.New()).RecoverTail(info)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tail segment %s: %v", name, err)
+		}
+		s.segments = append(s.segments, &walSegment{info: info, reader: w, last: w.LastIndex()})
+	}
+	return s, nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func (s *walStore) FirstIndex() (uint64, error) {
+	for _, seg := range s.segments {
+		if seg.last > 0 {
+			return seg.info.MinIndex, nil
+		}
+	}
+	return 0, nil
+}
+
+func (s *walStore) LastIndex() (uint64, error) {
+	for i := len(s.segments) - 1; i >= 0; i-- {
+		if last := s.segments[i].last; last > 0 {
+			return last, nil
+		}
+	}
+	return 0, nil
+}
+
+func (s *walStore) GetLog(index uint64, log *raft.Log) error {
+	for _, seg := range s.segments {
+		if index < seg.info.MinIndex || index > seg.last {
+			continue
+		}
+		buf, err := seg.reader.GetLog(index)
+		if errors.Is(err, types.ErrNotFound) {
+			return raft.ErrLogNotFound
+		} else if err != nil {
+			return err
+		}
+		defer buf.Close()
+		return s.codec.Decode(buf.Bs, log)
+	}
+	return raft.ErrLogNotFound
+}
+
+func (s *walStore) Get(key []byte) ([]byte, error) {
+	var val []byte
+	err := s.meta.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket([]byte(metadb.StableBucket))
+		if bucket == nil {
+			return nil
+		}
+		val = append(val, bucket.Get(key)...)
+		return nil
+	})
+	return val, err
+}
+
+func (s *walStore) GetUint64(key []byte) (uint64, error) {
+	// The WAL encodes integers as little endian, unlike BoltDB.
+	raw, err := s.Get(key)
+	if err != nil || len(raw) == 0 {
+		return 0, err
+	}
+	if len(raw) != 8 {
+		return 0, fmt.Errorf("invalid value of %s: expected 8 bytes, got %d", key, len(raw))
+	}
+	return binary.LittleEndian.Uint64(raw), nil
+}
+
+func (s *walStore) Close() error {
+	for _, seg := range s.segments {
+		seg.reader.Close()
+	}
+	if s.tmpDir != "" {
+		os.RemoveAll(s.tmpDir)
+	}
+	return s.meta.Close()
+}
+
+// snapshotMeta is the metadata of a snapshot in the snapshot store.
+type snapshotMeta struct {
+	raft.SnapshotMeta
+	CRC []byte
+}
+
+// readSnapshots reads the metadata of the snapshots in the raft directory,
+// newest first, the same way the snapshot store orders them.
+func readSnapshots(raftDir string) ([]*snapshotMeta, error) {
+	dir := filepath.Join(raftDir, "snapshots")
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var snapshots []*snapshotMeta
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasSuffix(entry.Name(), ".tmp") {
+			continue
+		}
+		raw, err := os.ReadFile(filepath.Join(dir, entry.Name(), "meta.json"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read metadata of snapshot %s: %v", entry.Name(), err)
+		}
+		var meta snapshotMeta
+		if err := json.Unmarshal(raw, &meta); err != nil {
+			return nil, fmt.Errorf("failed to parse metadata of snapshot %s: %v", entry.Name(), err)
+		}
+		snapshots = append(snapshots, &meta)
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		a, b := snapshots[i], snapshots[j]
+		if a.Term != b.Term {
+			return a.Term > b.Term
+		}
+		if a.Index != b.Index {
+			return a.Index > b.Index
+		}
+		return a.ID > b.ID
+	})
+	return snapshots, nil
+}
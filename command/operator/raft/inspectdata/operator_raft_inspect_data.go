@@ -0,0 +1,342 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package inspectdata
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"github.com/mitchellh/cli"
+	"github.com/ryanuber/columnize"
+
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/command/flags"
+)
+
+func New(ui cli.Ui) *cmd {
+	c := &cmd{UI: ui}
+	c.init()
+	return c
+}
+
+type cmd struct {
+	UI    cli.Ui
+	flags *flag.FlagSet
+	help  string
+
+	// flags
+	index uint64
+}
+
+func (c *cmd) init() {
+	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
+	c.flags.Uint64Var(&c.index, "index", 0,
+		"Decode and output the log entry at this index instead of the summary of the log.")
+	c.help = flags.Usage(help, c.flags)
+}
+
+func (c *cmd) Run(args []string) int {
+	if err := c.flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		c.UI.Error(fmt.Sprintf("Failed to parse args: %v", err))
+		return 1
+	}
+
+	args = c.flags.Args()
+	switch len(args) {
+	case 0:
+		c.UI.Error("Missing DATA-DIR argument")
+		return 1
+	case 1:
+	default:
+		c.UI.Error(fmt.Sprintf("Too many arguments (expected 1, got %d)", len(args)))
+		return 1
+	}
+
+	raftDir := filepath.Join(args[0], "raft")
+	if _, err := os.Stat(raftDir); err != nil {
+		c.UI.Error(fmt.Sprintf("Error reading Raft directory: %s", err))
+		return 1
+	}
+
+	store, backend, err := openLogStore(raftDir)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error opening Raft log: %s", err))
+		return 1
+	}
+	defer store.Close()
+
+	if c.index > 0 {
+		out, err := formatEntry(store, c.index)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error reading log entry %d: %s", c.index, err))
+			return 1
+		}
+		c.UI.Output(out)
+		return 0
+	}
+
+	summary, err := summarize(store, backend)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error reading Raft log: %s", err))
+		return 1
+	}
+	snapshots, err := readSnapshots(raftDir)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error reading Raft snapshots: %s", err))
+		return 1
+	}
+
+	c.UI.Output(formatSummary(summary, snapshots))
+	return 0
+}
+
+// termRange is a range of consecutive log entries with the same term.
+type termRange struct {
+	Term  uint64
+	First uint64
+	Last  uint64
+}
+
+type typeStats struct {
+	Name  string
+	Count int
+	Size  int
+}
+
+type logSummary struct {
+	Backend      string
+	CurrentTerm  uint64
+	LastVoteTerm uint64
+	LastVoteCand string
+	FirstIndex   uint64
+	LastIndex    uint64
+	Missing      int
+	Terms        []termRange
+	Types        []typeStats
+}
+
+// summarize reads the whole log and groups its entries by term and type.
+func summarize(store logStore, backend string) (*logSummary, error) {
+	s := &logSummary{Backend: backend}
+
+	var err error
+	if s.CurrentTerm, err = store.GetUint64([]byte("CurrentTerm")); err != nil {
+		return nil, err
+	}
+	if s.LastVoteTerm, err = store.GetUint64([]byte("LastVoteTerm")); err != nil {
+		return nil, err
+	}
+	cand, err := store.Get([]byte("LastVoteCand"))
+	if err != nil {
+		return nil, err
+	}
+	s.LastVoteCand = string(cand)
+
+	if s.FirstIndex, err = store.FirstIndex(); err != nil {
+		return nil, err
+	}
+	if s.LastIndex, err = store.LastIndex(); err != nil {
+		return nil, err
+	}
+	if s.LastIndex == 0 {
+		return s, nil
+	}
+
+	types := make(map[string]*typeStats)
+	for index := s.FirstIndex; index <= s.LastIndex; index++ {
+		var log raft.Log
+		if err := store.GetLog(index, &log); err == raft.ErrLogNotFound {
+			s.Missing++
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to read log entry %d: %v", index, err)
+		}
+
+		if n := len(s.Terms); n > 0 && s.Terms[n-1].Term == log.Term {
+			s.Terms[n-1].Last = index
+		} else {
+			s.Terms = append(s.Terms, termRange{Term: log.Term, First: index, Last: index})
+		}
+
+		name := entryType(&log)
+		ts, ok := types[name]
+		if !ok {
+			ts = &typeStats{Name: name}
+			types[name] = ts
+		}
+		ts.Count++
+		ts.Size += len(log.Data)
+	}
+
+	for _, ts := range types {
+		s.Types = append(s.Types, *ts)
+	}
+	sort.Slice(s.Types, func(i, j int) bool {
+		if s.Types[i].Count == s.Types[j].Count {
+			return s.Types[i].Name < s.Types[j].Name
+		}
+		return s.Types[i].Count > s.Types[j].Count
+	})
+	return s, nil
+}
+
+// entryType names the type of a log entry, which is the FSM message type for
+// commands.
+func entryType(log *raft.Log) string {
+	if log.Type != raft.LogCommand {
+		return log.Type.String()
+	}
+	if len(log.Data) == 0 {
+		return "Command(empty)"
+	}
+	msgType := structs.MessageType(log.Data[0]) &^ structs.IgnoreUnknownTypeFlag
+	return msgType.String()
+}
+
+func formatSummary(s *logSummary, snapshots []*snapshotMeta) string {
+	var b strings.Builder
+
+	backend := "BoltDB (raft.db)"
+	if s.Backend == backendWAL {
+		backend = "WAL (wal/)"
+	}
+	info := []string{
+		fmt.Sprintf("Log Store\x1f%s", backend),
+		fmt.Sprintf("Current Term\x1f%d", s.CurrentTerm),
+	}
+	if s.LastVoteCand != "" {
+		info = append(info, fmt.Sprintf("Last Vote\x1f%s in term %d", s.LastVoteCand, s.LastVoteTerm))
+	}
+	if s.LastIndex == 0 {
+		info = append(info, "Log\x1fempty")
+	} else {
+		info = append(info,
+			fmt.Sprintf("First Index\x1f%d", s.FirstIndex),
+			fmt.Sprintf("Last Index\x1f%d", s.LastIndex),
+			fmt.Sprintf("Entries\x1f%d", s.LastIndex-s.FirstIndex+1-uint64(s.Missing)))
+		if s.Missing > 0 {
+			info = append(info, fmt.Sprintf("Missing Entries\x1f%d", s.Missing))
+		}
+	}
+	b.WriteString(columnize.Format(info, &columnize.Config{Delim: string([]byte{0x1f})}))
+
+	if len(s.Terms) > 0 {
+		terms := []string{"Term\x1fFirst Index\x1fLast Index\x1fEntries"}
+		for _, t := range s.Terms {
+			terms = append(terms, fmt.Sprintf("%d\x1f%d\x1f%d\x1f%d", t.Term, t.First, t.Last, t.Last-t.First+1))
+		}
+		b.WriteString("\n\n")
+		b.WriteString(columnize.Format(terms, &columnize.Config{Delim: string([]byte{0x1f})}))
+	}
+
+	if len(s.Types) > 0 {
+		types := []string{"Type\x1fCount\x1fSize"}
+		for _, t := range s.Types {
+			types = append(types, fmt.Sprintf("%s\x1f%d\x1f%d", t.Name, t.Count, t.Size))
+		}
+		b.WriteString("\n\n")
+		b.WriteString(columnize.Format(types, &columnize.Config{Delim: string([]byte{0x1f})}))
+	}
+
+	b.WriteString("\n\n")
+	if len(snapshots) == 0 {
+		b.WriteString("No snapshots")
+		return b.String()
+	}
+	latest := snapshots[0]
+	snap := []string{
+		fmt.Sprintf("Snapshots\x1f%d", len(snapshots)),
+		fmt.Sprintf("Latest Snapshot\x1f%s", latest.ID),
+		fmt.Sprintf("Index\x1f%d", latest.Index),
+		fmt.Sprintf("Term\x1f%d", latest.Term),
+		fmt.Sprintf("Size\x1f%d", latest.Size),
+		fmt.Sprintf("Version\x1f%d", latest.Version),
+	}
+	for _, server := range latest.Configuration.Servers {
+		snap = append(snap, fmt.Sprintf("Server\x1f%s %s (%s)", server.ID, server.Address, server.Suffrage))
+	}
+	b.WriteString(columnize.Format(snap, &columnize.Config{Delim: string([]byte{0x1f})}))
+	return b.String()
+}
+
+// formatEntry decodes a single log entry.
+func formatEntry(store logStore, index uint64) (string, error) {
+	var log raft.Log
+	if err := store.GetLog(index, &log); err != nil {
+		return "", err
+	}
+
+	info := []string{
+		fmt.Sprintf("Index\x1f%d", log.Index),
+		fmt.Sprintf("Term\x1f%d", log.Term),
+		fmt.Sprintf("Type\x1f%s", log.Type),
+	}
+	if log.Type == raft.LogCommand {
+		info = append(info, fmt.Sprintf("Message Type\x1f%s", entryType(&log)))
+	}
+	if !log.AppendedAt.IsZero() {
+		info = append(info, fmt.Sprintf("Appended At\x1f%s", log.AppendedAt.Format(time.RFC3339)))
+	}
+	info = append(info, fmt.Sprintf("Size\x1f%d", len(log.Data)))
+	out := columnize.Format(info, &columnize.Config{Delim: string([]byte{0x1f})})
+
+	var body interface{}
+	switch log.Type {
+	case raft.LogCommand:
+		if len(log.Data) == 0 {
+			return out, nil
+		}
+		if err := structs.Decode(log.Data[1:], &body); err != nil {
+			return "", fmt.Errorf("failed to decode %s message: %v", entryType(&log), err)
+		}
+	case raft.LogConfiguration:
+		body = raft.DecodeConfiguration(log.Data)
+	default:
+		return out, nil
+	}
+
+	raw, err := json.MarshalIndent(body, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return out + "\n\n" + string(raw), nil
+}
+
+func (c *cmd) Synopsis() string {
+	return synopsis
+}
+
+func (c *cmd) Help() string {
+	return c.help
+}
+
+const synopsis = "Inspect the Raft data of a stopped Consul server"
+const help = `
+Usage: consul operator raft inspect-data [options] DATA-DIR
+
+  Inspects the Raft log and snapshots in the data directory of a Consul
+  server. This is meant for debugging a server that won't start, and works
+  offline: the server must be stopped, and nothing in the data directory is
+  modified.
+
+  The summary includes the log store in use (BoltDB or WAL), the range of
+  indexes in the log, the terms of the entries and the number of entries of
+  each message type, and the metadata of the latest snapshot:
+
+    $ consul operator raft inspect-data /opt/consul
+
+  To decode the log entry at index 1234:
+
+    $ consul operator raft inspect-data -index=1234 /opt/consul
+`
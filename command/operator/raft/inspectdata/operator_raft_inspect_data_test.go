@@ -0,0 +1,247 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package inspectdata
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+	raftboltdb "github.com/hashicorp/raft-boltdb/v2"
+	raftwal "github.com/hashicorp/raft-wal"
+	"github.com/hashicorp/raft-wal/metadb"
+	"github.com/mitchellh/cli"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/sdk/testutil"
+)
+
+func TestOperatorRaftInspectDataCommand_noTabs(t *testing.T) {
+	t.Parallel()
+	if strings.ContainsRune(New(cli.NewMockUi()).Help(), '\t') {
+		t.Fatal("help has tabs")
+	}
+}
+
+func TestOperatorRaftInspectDataCommand_Validation(t *testing.T) {
+	t.Parallel()
+
+	empty := testutil.TempDir(t, "data")
+	require.NoError(t, os.Mkdir(filepath.Join(empty, "raft"), 0700))
+
+	cases := map[string]struct {
+		args   []string
+		output string
+	}{
+		"no data dir": {
+			[]string{},
+			"Missing DATA-DIR argument",
+		},
+		"extra args": {
+			[]string{"foo", "bar"},
+			"Too many arguments",
+		},
+		"no raft dir": {
+			[]string{testutil.TempDir(t, "data")},
+			"Error reading Raft directory",
+		},
+		"no log": {
+			[]string{empty},
+			"no Raft log found",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			ui := cli.NewMockUi()
+			c := New(ui)
+
+			code := c.Run(tc.args)
+			require.Equal(t, 1, code)
+			require.Contains(t, ui.ErrorWriter.String(), tc.output)
+		})
+	}
+}
+
+// testLogs returns log entries over two terms with a configuration change,
+// a no-op and commands of several message types.
+func testLogs(t *testing.T) []*raft.Log {
+	command := func(msgType structs.MessageType, req interface{}) []byte {
+		buf, err := structs.Encode(msgType, req)
+		require.NoError(t, err)
+		return buf
+	}
+
+	config := raft.Configuration{Servers: []raft.Server{
+		{Suffrage: raft.Voter, ID: "server-1", Address: "127.0.0.1:8300"},
+	}}
+	logs := []*raft.Log{
+		{Type: raft.LogConfiguration, Term: 1, Data: raft.EncodeConfiguration(config)},
+		{Type: raft.LogNoop, Term: 1},
+		{Type: raft.LogCommand, Term: 1, Data: command(structs.KVSRequestType, &structs.KVSRequest{
+			Op:     "set",
+			DirEnt: structs.DirEntry{Key: "foo", Value: []byte("bar")},
+		})},
+		{Type: raft.LogNoop, Term: 2},
+		{Type: raft.LogCommand, Term: 2, Data: command(structs.KVSRequestType, &structs.KVSRequest{
+			Op:     "delete",
+			DirEnt: structs.DirEntry{Key: "foo"},
+		})},
+		{Type: raft.LogCommand, Term: 2, Data: command(structs.RegisterRequestType, &structs.RegisterRequest{
+			Node:    "node1",
+			Address: "127.0.0.2",
+		})},
+	}
+	for i, log := range logs {
+		log.Index = uint64(i + 1)
+		log.AppendedAt = time.Unix(1600000000, 0).UTC()
+	}
+	return logs
+}
+
+// writeSnapshot adds a snapshot to the raft directory.
+func writeSnapshot(t *testing.T, raftDir string, index, term uint64) {
+	store, err := raft.NewFileSnapshotStore(raftDir, 2, io.Discard)
+	require.NoError(t, err)
+	config := raft.Configuration{Servers: []raft.Server{
+		{Suffrage: raft.Voter, ID: "server-1", Address: "127.0.0.1:8300"},
+	}}
+	_, trans := raft.NewInmemTransport("")
+	sink, err := store.Create(raft.SnapshotVersionMax, index, term, config, 1, trans)
+	require.NoError(t, err)
+	_, err = sink.Write([]byte("state"))
+	require.NoError(t, err)
+	require.NoError(t, sink.Close())
+}
+
+func TestOperatorRaftInspectDataCommand_BoltDB(t *testing.T) {
+	t.Parallel()
+
+	dataDir := testutil.TempDir(t, "data")
+	raftDir := filepath.Join(dataDir, "raft")
+	require.NoError(t, os.Mkdir(raftDir, 0700))
+
+	store, err := raftboltdb.NewBoltStore(filepath.Join(raftDir, "raft.db"))
+	require.NoError(t, err)
+	require.NoError(t, store.StoreLogs(testLogs(t)))
+	require.NoError(t, store.SetUint64([]byte("CurrentTerm"), 2))
+	require.NoError(t, store.SetUint64([]byte("LastVoteTerm"), 2))
+	require.NoError(t, store.Set([]byte("LastVoteCand"), []byte("127.0.0.1:8300")))
+
+	// The store is locked while it's open, as by a running server.
+	ui := cli.NewMockUi()
+	c := New(ui)
+	code := c.Run([]string{dataDir})
+	require.Equal(t, 1, code)
+	require.Contains(t, ui.ErrorWriter.String(), "must be stopped first")
+	require.NoError(t, store.Close())
+
+	writeSnapshot(t, raftDir, 2, 1)
+	writeSnapshot(t, raftDir, 5, 2)
+
+	ui = cli.NewMockUi()
+	c = New(ui)
+	code = c.Run([]string{dataDir})
+	require.Equal(t, 0, code, ui.ErrorWriter.String())
+	output := ui.OutputWriter.String()
+	require.Regexp(t, `Log Store\s+BoltDB`, output)
+	require.Regexp(t, `Current Term\s+2`, output)
+	require.Regexp(t, `Last Vote\s+127.0.0.1:8300 in term 2`, output)
+	require.Regexp(t, `First Index\s+1`, output)
+	require.Regexp(t, `Last Index\s+6`, output)
+	require.Regexp(t, `1\s+1\s+3\s+3`, output)
+	require.Regexp(t, `2\s+4\s+6\s+3`, output)
+	require.Regexp(t, `KVS\s+2`, output)
+	require.Regexp(t, `Register\s+1`, output)
+	require.Regexp(t, `LogNoop\s+2`, output)
+	require.Regexp(t, `LogConfiguration\s+1`, output)
+	require.Regexp(t, `Snapshots\s+2`, output)
+	require.Regexp(t, `Index\s+5\n`, output)
+	require.Regexp(t, `Server\s+server-1 127.0.0.1:8300 \(Voter\)`, output)
+
+	// The data directory isn't modified.
+	info, err := os.Stat(filepath.Join(raftDir, "raft.db"))
+	require.NoError(t, err)
+	modTime := info.ModTime()
+
+	ui = cli.NewMockUi()
+	c = New(ui)
+	code = c.Run([]string{"-index=3", dataDir})
+	require.Equal(t, 0, code, ui.ErrorWriter.String())
+	output = ui.OutputWriter.String()
+	require.Regexp(t, `Type\s+LogCommand`, output)
+	require.Regexp(t, `Message Type\s+KVS`, output)
+	require.Regexp(t, `Appended At\s+2020-09-13T12:26:40Z`, output)
+	require.Contains(t, output, `"Key": "foo"`)
+	require.Contains(t, output, `"Op": "set"`)
+
+	ui = cli.NewMockUi()
+	c = New(ui)
+	code = c.Run([]string{"-index=1", dataDir})
+	require.Equal(t, 0, code, ui.ErrorWriter.String())
+	require.Contains(t, ui.OutputWriter.String(), `"Address": "127.0.0.1:8300"`)
+
+	ui = cli.NewMockUi()
+	c = New(ui)
+	code = c.Run([]string{"-index=7", dataDir})
+	require.Equal(t, 1, code)
+	require.Contains(t, ui.ErrorWriter.String(), "log not found")
+
+	info, err = os.Stat(filepath.Join(raftDir, "raft.db"))
+	require.NoError(t, err)
+	require.Equal(t, modTime, info.ModTime())
+}
+
+func TestOperatorRaftInspectDataCommand_WAL(t *testing.T) {
+	t.Parallel()
+
+	dataDir := testutil.TempDir(t, "data")
+	walDir := filepath.Join(dataDir, "raft", "wal")
+	require.NoError(t, os.MkdirAll(walDir, 0700))
+
+	// Use tiny segments so the log spans sealed segments and a tail. Closing
+	// the WAL doesn't close its meta store, so it's closed separately.
+	meta := &metadb.BoltMetaDB{}
+	wal, err := raftwal.Open(walDir, raftwal.WithSegmentSize(512), raftwal.WithMetaStore(meta))
+	require.NoError(t, err)
+	for _, log := range testLogs(t) {
+		require.NoError(t, wal.StoreLogs([]*raft.Log{log}))
+	}
+	require.NoError(t, wal.SetUint64([]byte("CurrentTerm"), 2))
+	require.NoError(t, wal.Close())
+	require.NoError(t, meta.Close())
+
+	files, err := os.ReadDir(walDir)
+	require.NoError(t, err)
+	require.Greater(t, len(files), 2, "expected several segments")
+
+	ui := cli.NewMockUi()
+	c := New(ui)
+	code := c.Run([]string{dataDir})
+	require.Equal(t, 0, code, ui.ErrorWriter.String())
+	output := ui.OutputWriter.String()
+	require.Regexp(t, `Log Store\s+WAL`, output)
+	require.Regexp(t, `Current Term\s+2`, output)
+	require.Regexp(t, `First Index\s+1`, output)
+	require.Regexp(t, `Last Index\s+6`, output)
+	require.Regexp(t, `KVS\s+2`, output)
+	require.Contains(t, output, "No snapshots")
+
+	ui = cli.NewMockUi()
+	c = New(ui)
+	code = c.Run([]string{"-index=6", dataDir})
+	require.Equal(t, 0, code, ui.ErrorWriter.String())
+	require.Regexp(t, `Message Type\s+Register`, ui.OutputWriter.String())
+	require.Contains(t, ui.OutputWriter.String(), `"Node": "node1"`)
+
+	// The segments are left as they were.
+	after, err := os.ReadDir(walDir)
+	require.NoError(t, err)
+	require.Equal(t, len(files), len(after))
+}
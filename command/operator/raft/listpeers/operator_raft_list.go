@@ -81,6 +81,8 @@ func raftListPeers(client *api.Client, stale bool) (string, error) {
 		state := "follower"
 		if s.Leader {
 			state = "leader"
+		} else if s.ReadReplica {
+			state = "read-replica"
 		}
 		result = append(result, fmt.Sprintf("%s\x1f%s\x1f%s\x1f%s\x1f%v\x1f%s",
 			s.Node, s.ID, s.Address, state, s.Voter, raftProtocol))
@@ -0,0 +1,200 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package verify
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/cli"
+	"github.com/ryanuber/columnize"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/command/flags"
+)
+
+func New(ui cli.Ui) *cmd {
+	c := &cmd{UI: ui}
+	c.init()
+	return c
+}
+
+type cmd struct {
+	UI    cli.Ui
+	flags *flag.FlagSet
+	http  *flags.HTTPFlags
+	help  string
+
+	// flags
+	checkpoint bool
+	timeout    time.Duration
+}
+
+func (c *cmd) init() {
+	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
+	c.flags.BoolVar(&c.checkpoint, "checkpoint", false,
+		"Write a verification checkpoint first, and wait for the servers to verify the "+
+			"logs up to it before displaying the results.")
+	c.flags.DurationVar(&c.timeout, "timeout", 30*time.Second,
+		"How long to wait for the servers to verify the checkpoint with -checkpoint.")
+	c.http = &flags.HTTPFlags{}
+	flags.Merge(c.flags, c.http.ClientFlags())
+	flags.Merge(c.flags, c.http.ServerFlags())
+	c.help = flags.Usage(help, c.flags)
+}
+
+// pollInterval is how often the verification state is fetched while waiting
+// for a checkpoint to be verified.
+const pollInterval = 500 * time.Millisecond
+
+func (c *cmd) Run(args []string) int {
+	if err := c.flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		c.UI.Error(fmt.Sprintf("Failed to parse args: %v", err))
+		return 1
+	}
+
+	client, err := c.http.APIClient()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+	operator := client.Operator()
+
+	var index uint64
+	if c.checkpoint {
+		cp, err := operator.RaftLogVerificationCheckpoint(nil)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error writing verification checkpoint: %s", err))
+			return 1
+		}
+		index = cp.Index
+		c.UI.Info(fmt.Sprintf("Wrote verification checkpoint at index %d", index))
+	}
+
+	q := &api.QueryOptions{AllowStale: c.http.Stale()}
+	deadline := time.Now().Add(c.timeout)
+	var reply *api.RaftLogVerification
+	for {
+		reply, err = operator.RaftLogVerification(q)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error getting verification state: %s", err))
+			return 1
+		}
+		if index == 0 || checkpointVerified(reply, index) {
+			break
+		}
+		if time.Now().After(deadline) {
+			c.UI.Warn("Timed out waiting for all the servers to verify the checkpoint")
+			break
+		}
+		time.Sleep(pollInterval)
+	}
+
+	c.UI.Output(formatVerification(reply))
+
+	for _, s := range reply.Servers {
+		if s.LastReport != nil && s.LastReport.Status == api.RaftLogVerificationFailed {
+			return 2
+		}
+	}
+	return 0
+}
+
+// checkpointVerified returns whether every server with verification enabled
+// has reported on the range ending with the checkpoint at index, or a later
+// one.
+func checkpointVerified(reply *api.RaftLogVerification, index uint64) bool {
+	for _, s := range reply.Servers {
+		if s.Error != "" || !s.Enabled {
+			continue
+		}
+		if s.LastReport == nil || s.LastReport.Range.End < index {
+			return false
+		}
+	}
+	return true
+}
+
+func formatRange(r api.RaftLogRange) string {
+	return fmt.Sprintf("[%d, %d)", r.Start, r.End)
+}
+
+func formatVerification(reply *api.RaftLogVerification) string {
+	result := []string{"Node\x1fID\x1fEnabled\x1fLast Range\x1fStatus\x1fVerified\x1fFailed\x1fVerified At"}
+	var details []string
+	for _, s := range reply.Servers {
+		if s.Error != "" {
+			result = append(result, fmt.Sprintf("%s\x1f%s\x1f-\x1f-\x1funknown\x1f-\x1f-\x1f-", s.Node, s.ID))
+			details = append(details, fmt.Sprintf("%s: failed to fetch verification state: %s", s.Node, s.Error))
+			continue
+		}
+
+		lastRange, status, at := "-", "-", "-"
+		if r := s.LastReport; r != nil {
+			lastRange = formatRange(r.Range)
+			status = r.Status
+			at = r.Time.Format(time.RFC3339)
+		}
+		result = append(result, fmt.Sprintf("%s\x1f%s\x1f%v\x1f%s\x1f%s\x1f%d\x1f%d\x1f%s",
+			s.Node, s.ID, s.Enabled, lastRange, status, s.RangesVerified, s.RangesFailed, at))
+
+		if r := s.LastReport; r != nil && r.Status != api.RaftLogVerificationOK && r.Status != api.RaftLogVerificationFailed {
+			details = append(details, fmt.Sprintf("%s: range %s was not verified: %s", s.Node, formatRange(r.Range), r.Error))
+		}
+		if r := s.LastFailure; r != nil {
+			details = append(details, formatFailure(s.Node, r))
+		}
+	}
+
+	out := columnize.Format(result, &columnize.Config{Delim: string([]byte{0x1f})})
+	if len(details) > 0 {
+		out += "\n\n" + strings.Join(details, "\n")
+	}
+	return out
+}
+
+func formatFailure(node string, r *api.RaftLogVerificationReport) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: range %s FAILED verification at %s\n", node, formatRange(r.Range), r.Time.Format(time.RFC3339))
+	fmt.Fprintf(&b, "  Leader checksum:  %s\n", r.ExpectedChecksum)
+	if r.WrittenChecksum != "" {
+		fmt.Fprintf(&b, "  Written checksum: %s\n", r.WrittenChecksum)
+	}
+	if r.ReadChecksum != "" {
+		fmt.Fprintf(&b, "  Read checksum:    %s\n", r.ReadChecksum)
+	}
+	fmt.Fprintf(&b, "  Error:            %s", r.Error)
+	return b.String()
+}
+
+func (c *cmd) Synopsis() string {
+	return synopsis
+}
+
+func (c *cmd) Help() string {
+	return c.help
+}
+
+const synopsis = "Display the state of the Raft log verification"
+const help = `
+Usage: consul operator raft verify [options]
+
+  Displays the state of the Raft log verification of every server: the last
+  range of logs each server verified, its outcome, and the details of the last
+  range that failed verification, if any. Log verification must be enabled with
+  raft_logstore.verification on the servers.
+
+  To verify the logs written so far without waiting for the verification
+  interval:
+
+      $ consul operator raft verify -checkpoint
+
+  The command exits with code 2 when the last range verified by a server
+  failed.
+`
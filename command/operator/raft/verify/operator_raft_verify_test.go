@@ -0,0 +1,121 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package verify
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mitchellh/cli"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/consul/agent"
+	"github.com/hashicorp/consul/api"
+)
+
+func TestOperatorRaftVerifyCommand_noTabs(t *testing.T) {
+	t.Parallel()
+	if strings.ContainsRune(New(cli.NewMockUi()).Help(), '\t') {
+		t.Fatal("help has tabs")
+	}
+}
+
+func TestOperatorRaftVerifyCommand(t *testing.T) {
+	if testing.Short() {
+		t.Skip("too slow for testing.Short")
+	}
+
+	t.Parallel()
+	a := agent.NewTestAgent(t, `
+		raft_logstore {
+			verification {
+				enabled = true
+				interval = "1h"
+			}
+		}
+	`)
+	defer a.Shutdown()
+
+	ui := cli.NewMockUi()
+	c := New(ui)
+	code := c.Run([]string{"-http-addr=" + a.HTTPAddr(), "-checkpoint"})
+	require.Equal(t, 0, code, ui.ErrorWriter.String())
+	require.Empty(t, ui.ErrorWriter.String())
+
+	output := ui.OutputWriter.String()
+	require.Contains(t, output, "Wrote verification checkpoint at index")
+	require.Regexp(t, a.Config.NodeName+`\s+`+string(a.Config.NodeID)+`\s+true\s+\[\d+, \d+\)\s+ok\s+1\s+0`, output)
+}
+
+func TestOperatorRaftVerifyCommand_NotEnabled(t *testing.T) {
+	if testing.Short() {
+		t.Skip("too slow for testing.Short")
+	}
+
+	t.Parallel()
+	a := agent.NewTestAgent(t, ``)
+	defer a.Shutdown()
+
+	ui := cli.NewMockUi()
+	c := New(ui)
+	code := c.Run([]string{"-http-addr=" + a.HTTPAddr(), "-checkpoint"})
+	require.Equal(t, 1, code)
+	require.Contains(t, ui.ErrorWriter.String(), "Raft log verification is not enabled")
+}
+
+func TestFormatVerification(t *testing.T) {
+	at := time.Date(2023, 4, 5, 6, 7, 8, 0, time.UTC)
+	failure := &api.RaftLogVerificationReport{
+		Time:             at,
+		Range:            api.RaftLogRange{Start: 10, End: 20},
+		Status:           api.RaftLogVerificationFailed,
+		ExpectedChecksum: "0000abcd",
+		ReadChecksum:     "00001234",
+		Error:            "storage corruption",
+	}
+	reply := &api.RaftLogVerification{
+		Servers: []*api.RaftLogVerificationStatus{
+			{
+				ID:             "s1",
+				Node:           "node1",
+				Enabled:        true,
+				RangesVerified: 3,
+				RangesFailed:   1,
+				LastReport:     failure,
+				LastFailure:    failure,
+			},
+			{
+				ID:      "s2",
+				Node:    "node2",
+				Enabled: true,
+				LastReport: &api.RaftLogVerificationReport{
+					Time:   at,
+					Range:  api.RaftLogRange{Start: 10, End: 20},
+					Status: api.RaftLogVerificationRangeMismatch,
+					Error:  "range mismatch",
+				},
+				RangesVerified: 1,
+			},
+			{
+				ID:    "s3",
+				Node:  "node3",
+				Error: "rpc error",
+			},
+		},
+	}
+
+	expected := `Node   ID  Enabled  Last Range  Status          Verified  Failed  Verified At
+node1  s1  true     [10, 20)    failed          3         1       2023-04-05T06:07:08Z
+node2  s2  true     [10, 20)    range-mismatch  1         0       2023-04-05T06:07:08Z
+node3  s3  -        -           unknown         -         -       -
+
+node1: range [10, 20) FAILED verification at 2023-04-05T06:07:08Z
+  Leader checksum:  0000abcd
+  Read checksum:    00001234
+  Error:            storage corruption
+node2: range [10, 20) was not verified: range mismatch
+node3: failed to fetch verification state: rpc error`
+	require.Equal(t, expected, formatVerification(reply))
+}
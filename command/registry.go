@@ -63,6 +63,11 @@ import (
 	"github.com/hashicorp/consul/command/connect/proxy"
 	"github.com/hashicorp/consul/command/connect/redirecttraffic"
 	"github.com/hashicorp/consul/command/debug"
+	"github.com/hashicorp/consul/command/drain"
+	"github.com/hashicorp/consul/command/election"
+	electioncampaign "github.com/hashicorp/consul/command/election/campaign"
+	electionleader "github.com/hashicorp/consul/command/election/leader"
+	electionobserve "github.com/hashicorp/consul/command/election/observe"
 	"github.com/hashicorp/consul/command/event"
 	"github.com/hashicorp/consul/command/exec"
 	"github.com/hashicorp/consul/command/forceleave"
@@ -83,6 +88,7 @@ import (
 	kvget "github.com/hashicorp/consul/command/kv/get"
 	kvimp "github.com/hashicorp/consul/command/kv/imp"
 	kvput "github.com/hashicorp/consul/command/kv/put"
+	kvrollback "github.com/hashicorp/consul/command/kv/rollback"
 	"github.com/hashicorp/consul/command/leave"
 	"github.com/hashicorp/consul/command/lock"
 	"github.com/hashicorp/consul/command/login"
@@ -96,9 +102,11 @@ import (
 	operautoset "github.com/hashicorp/consul/command/operator/autopilot/set"
 	operautostate "github.com/hashicorp/consul/command/operator/autopilot/state"
 	operraft "github.com/hashicorp/consul/command/operator/raft"
+	operraftinspect "github.com/hashicorp/consul/command/operator/raft/inspectdata"
 	operraftlist "github.com/hashicorp/consul/command/operator/raft/listpeers"
 	operraftremove "github.com/hashicorp/consul/command/operator/raft/removepeer"
 	"github.com/hashicorp/consul/command/operator/raft/transferleader"
+	operraftverify "github.com/hashicorp/consul/command/operator/raft/verify"
 	"github.com/hashicorp/consul/command/operator/usage"
 	"github.com/hashicorp/consul/command/operator/usage/instances"
 	"github.com/hashicorp/consul/command/peering"
@@ -113,6 +121,8 @@ import (
 	svcsderegister "github.com/hashicorp/consul/command/services/deregister"
 	svcsregister "github.com/hashicorp/consul/command/services/register"
 	"github.com/hashicorp/consul/command/snapshot"
+	snapagent "github.com/hashicorp/consul/command/snapshot/agent"
+	snapdiff "github.com/hashicorp/consul/command/snapshot/diff"
 	snapinspect "github.com/hashicorp/consul/command/snapshot/inspect"
 	snaprestore "github.com/hashicorp/consul/command/snapshot/restore"
 	snapsave "github.com/hashicorp/consul/command/snapshot/save"
@@ -192,6 +202,11 @@ func RegisteredCommands(ui cli.Ui) map[string]mcli.CommandFactory {
 		entry{"connect expose", func(ui cli.Ui) (cli.Command, error) { return expose.New(ui), nil }},
 		entry{"connect redirect-traffic", func(ui cli.Ui) (cli.Command, error) { return redirecttraffic.New(ui), nil }},
 		entry{"debug", func(ui cli.Ui) (cli.Command, error) { return debug.New(ui), nil }},
+		entry{"drain", func(ui cli.Ui) (cli.Command, error) { return drain.New(ui), nil }},
+		entry{"election", func(cli.Ui) (cli.Command, error) { return election.New(), nil }},
+		entry{"election campaign", func(ui cli.Ui) (cli.Command, error) { return electioncampaign.New(ui, MakeShutdownCh()), nil }},
+		entry{"election leader", func(ui cli.Ui) (cli.Command, error) { return electionleader.New(ui), nil }},
+		entry{"election observe", func(ui cli.Ui) (cli.Command, error) { return electionobserve.New(ui, MakeShutdownCh()), nil }},
 		entry{"event", func(ui cli.Ui) (cli.Command, error) { return event.New(ui), nil }},
 		entry{"exec", func(ui cli.Ui) (cli.Command, error) { return exec.New(ui, MakeShutdownCh()), nil }},
 		entry{"force-leave", func(ui cli.Ui) (cli.Command, error) { return forceleave.New(ui), nil }},
@@ -212,6 +227,7 @@ func RegisteredCommands(ui cli.Ui) map[string]mcli.CommandFactory {
 		entry{"kv get", func(ui cli.Ui) (cli.Command, error) { return kvget.New(ui), nil }},
 		entry{"kv import", func(ui cli.Ui) (cli.Command, error) { return kvimp.New(ui), nil }},
 		entry{"kv put", func(ui cli.Ui) (cli.Command, error) { return kvput.New(ui), nil }},
+		entry{"kv rollback", func(ui cli.Ui) (cli.Command, error) { return kvrollback.New(ui), nil }},
 		entry{"leave", func(ui cli.Ui) (cli.Command, error) { return leave.New(ui), nil }},
 		entry{"lock", func(ui cli.Ui) (cli.Command, error) { return lock.New(ui, MakeShutdownCh()), nil }},
 		entry{"login", func(ui cli.Ui) (cli.Command, error) { return login.New(ui), nil }},
@@ -225,9 +241,11 @@ func RegisteredCommands(ui cli.Ui) map[string]mcli.CommandFactory {
 		entry{"operator autopilot set-config", func(ui cli.Ui) (cli.Command, error) { return operautoset.New(ui), nil }},
 		entry{"operator autopilot state", func(ui cli.Ui) (cli.Command, error) { return operautostate.New(ui), nil }},
 		entry{"operator raft", func(cli.Ui) (cli.Command, error) { return operraft.New(), nil }},
+		entry{"operator raft inspect-data", func(ui cli.Ui) (cli.Command, error) { return operraftinspect.New(ui), nil }},
 		entry{"operator raft list-peers", func(ui cli.Ui) (cli.Command, error) { return operraftlist.New(ui), nil }},
 		entry{"operator raft remove-peer", func(ui cli.Ui) (cli.Command, error) { return operraftremove.New(ui), nil }},
 		entry{"operator raft transfer-leader", func(ui cli.Ui) (cli.Command, error) { return transferleader.New(ui), nil }},
+		entry{"operator raft verify", func(ui cli.Ui) (cli.Command, error) { return operraftverify.New(ui), nil }},
 		entry{"operator usage", func(ui cli.Ui) (cli.Command, error) { return usage.New(), nil }},
 		entry{"operator usage instances", func(ui cli.Ui) (cli.Command, error) { return instances.New(ui), nil }},
 		entry{"peering", func(cli.Ui) (cli.Command, error) { return peering.New(), nil }},
@@ -242,6 +260,8 @@ func RegisteredCommands(ui cli.Ui) map[string]mcli.CommandFactory {
 		entry{"services register", func(ui cli.Ui) (cli.Command, error) { return svcsregister.New(ui), nil }},
 		entry{"services deregister", func(ui cli.Ui) (cli.Command, error) { return svcsderegister.New(ui), nil }},
 		entry{"snapshot", func(cli.Ui) (cli.Command, error) { return snapshot.New(), nil }},
+		entry{"snapshot agent", func(ui cli.Ui) (cli.Command, error) { return snapagent.New(ui, MakeShutdownCh()), nil }},
+		entry{"snapshot diff", func(ui cli.Ui) (cli.Command, error) { return snapdiff.New(ui), nil }},
 		entry{"snapshot inspect", func(ui cli.Ui) (cli.Command, error) { return snapinspect.New(ui), nil }},
 		entry{"snapshot restore", func(ui cli.Ui) (cli.Command, error) { return snaprestore.New(ui), nil }},
 		entry{"snapshot save", func(ui cli.Ui) (cli.Command, error) { return snapsave.New(ui), nil }},
@@ -0,0 +1,298 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/go-hclog"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/snapshot"
+)
+
+const (
+	// lockSessionName is the name of the session used to hold the lock.
+	lockSessionName = "Consul Snapshot Agent"
+
+	// lockRetryTime is how long we wait before trying to obtain the
+	// lock again after an error.
+	lockRetryTime = 5 * time.Second
+)
+
+// errTooManyFailures is returned by lead when the agent gives up its
+// leadership because too many snapshots failed in a row.
+var errTooManyFailures = errors.New("too many snapshot failures")
+
+// snapshotAgent takes snapshots at an interval, saves them to the storage
+// and removes the old ones.
+type snapshotAgent struct {
+	client  *api.Client
+	storage storage
+	logger  hclog.Logger
+
+	interval    time.Duration
+	retain      int
+	retainAge   time.Duration
+	stale       bool
+	lockKey     string
+	maxFailures int
+	scratchPath string
+	options     api.SnapshotOptions
+	keyProvider snapshot.KeyProvider
+
+	// now is used to get the current time, it's replaced in tests.
+	now func() time.Time
+}
+
+// Run takes a single snapshot when the interval is 0. Otherwise it takes
+// snapshots at the interval while holding the lock, until shutdownCh is
+// closed, so that only one of several agents takes snapshots.
+func (a *snapshotAgent) Run(shutdownCh <-chan struct{}) error {
+	if a.interval == 0 {
+		return a.snapshot()
+	}
+
+	lock, err := a.client.LockOpts(&api.LockOptions{
+		Key:         a.lockKey,
+		SessionName: lockSessionName,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create lock: %v", err)
+	}
+
+	for {
+		a.logger.Info("Waiting to obtain leadership...")
+		leaderCh, err := lock.Lock(shutdownCh)
+		if err != nil {
+			if err == api.ErrLockConflict {
+				return fmt.Errorf("failed to obtain leadership: %v", err)
+			}
+			a.logger.Error("Failed to obtain leadership", "error", err)
+			select {
+			case <-time.After(lockRetryTime):
+				continue
+			case <-shutdownCh:
+				return nil
+			}
+		}
+		if leaderCh == nil {
+			return nil
+		}
+
+		a.logger.Info("Obtained leadership")
+		metrics.SetGauge([]string{"snapshot", "agent", "is_leader"}, 1)
+		err = a.lead(leaderCh, shutdownCh)
+		metrics.SetGauge([]string{"snapshot", "agent", "is_leader"}, 0)
+		if err := lock.Unlock(); err != nil && err != api.ErrLockNotHeld {
+			a.logger.Error("Failed to release leadership", "error", err)
+		}
+
+		switch {
+		case err == nil:
+			return nil
+		case err == errTooManyFailures:
+			// Give another agent a chance to take over before we
+			// campaign again.
+			a.logger.Warn("Giving up leadership after too many snapshot failures", "failures", a.maxFailures)
+			select {
+			case <-time.After(a.interval):
+			case <-shutdownCh:
+				return nil
+			}
+		default:
+			a.logger.Warn("Lost leadership", "error", err)
+		}
+	}
+}
+
+// lead takes snapshots at the interval until the leadership is lost, too
+// many snapshots fail in a row, or shutdownCh is closed, in which case it
+// returns nil.
+func (a *snapshotAgent) lead(leaderCh, shutdownCh <-chan struct{}) error {
+	// Pick up the schedule of the previous leader from the stored
+	// snapshots, so a change of leadership doesn't take an extra snapshot.
+	next := a.now()
+	if last, err := a.lastSnapshot(); err != nil {
+		a.logger.Warn("Failed to list snapshots, taking a snapshot now", "error", err)
+	} else if !last.IsZero() {
+		next = last.Add(a.interval)
+	}
+
+	var failures int
+	for {
+		select {
+		case <-time.After(next.Sub(a.now())):
+		case <-leaderCh:
+			return errors.New("lock was lost")
+		case <-shutdownCh:
+			return nil
+		}
+
+		next = a.now().Add(a.interval)
+		if err := a.snapshot(); err != nil {
+			failures++
+			if a.maxFailures > 0 && failures >= a.maxFailures {
+				return errTooManyFailures
+			}
+			continue
+		}
+		failures = 0
+	}
+}
+
+// snapshot takes a snapshot, saves it to the storage and removes the old
+// snapshots. Failures are logged and counted in the metrics.
+func (a *snapshotAgent) snapshot() error {
+	start := a.now()
+	name, size, err := a.save(start)
+	if err != nil {
+		a.logger.Error("Failed to save snapshot", "error", err)
+		metrics.IncrCounter([]string{"snapshot", "agent", "save_failure"}, 1)
+		return err
+	}
+
+	a.logger.Info("Saved snapshot", "name", name, "size", size)
+	metrics.MeasureSince([]string{"snapshot", "agent", "save"}, start)
+	metrics.SetGauge([]string{"snapshot", "agent", "size"}, float32(size))
+	metrics.SetGauge([]string{"snapshot", "agent", "last_success"}, float32(start.Unix()))
+
+	if err := a.rotate(); err != nil {
+		a.logger.Error("Failed to remove old snapshots", "error", err)
+		metrics.IncrCounter([]string{"snapshot", "agent", "rotate_failure"}, 1)
+	}
+	return nil
+}
+
+// save takes a snapshot into a scratch file, verifies it and saves it to
+// the storage. It returns the name and size of the snapshot.
+func (a *snapshotAgent) save(now time.Time) (string, int64, error) {
+	snap, _, err := a.client.Snapshot().SaveOpts(a.options, &api.QueryOptions{
+		AllowStale: a.stale,
+	})
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to take snapshot: %v", err)
+	}
+	defer snap.Close()
+
+	scratch, err := os.CreateTemp(a.scratchPath, "consul-snapshot-")
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create scratch file: %v", err)
+	}
+	defer func() {
+		scratch.Close()
+		os.Remove(scratch.Name())
+	}()
+
+	size, err := scratch.ReadFrom(snap)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to write scratch file: %v", err)
+	}
+	if _, err := scratch.Seek(0, 0); err != nil {
+		return "", 0, fmt.Errorf("failed to rewind scratch file: %v", err)
+	}
+	if _, err := snapshot.Verify(scratch, a.keyProvider); err != nil {
+		return "", 0, fmt.Errorf("failed to verify snapshot: %v", err)
+	}
+	if _, err := scratch.Seek(0, 0); err != nil {
+		return "", 0, fmt.Errorf("failed to rewind scratch file: %v", err)
+	}
+
+	name := snapshotName(now)
+	ctx, cancel := context.WithTimeout(context.Background(), a.saveTimeout())
+	defer cancel()
+	if err := a.storage.Save(ctx, name, scratch); err != nil {
+		return "", 0, fmt.Errorf("failed to save snapshot to %s: %v", a.storage, err)
+	}
+	return name, size, nil
+}
+
+// saveTimeout bounds the time taken to save a snapshot to the storage, so a
+// hung upload doesn't stop the agent from taking snapshots.
+func (a *snapshotAgent) saveTimeout() time.Duration {
+	if a.interval > 0 {
+		return a.interval
+	}
+	return time.Hour
+}
+
+// lastSnapshot returns the time the most recent stored snapshot was taken,
+// or the zero time if there are no snapshots.
+func (a *snapshotAgent) lastSnapshot() (time.Time, error) {
+	snapshots, err := a.listSnapshots()
+	if err != nil || len(snapshots) == 0 {
+		return time.Time{}, err
+	}
+	return snapshots[0].time, nil
+}
+
+type storedSnapshot struct {
+	name string
+	time time.Time
+}
+
+// listSnapshots returns the stored snapshots, most recent first.
+func (a *snapshotAgent) listSnapshots() ([]storedSnapshot, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	names, err := a.storage.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := make([]storedSnapshot, 0, len(names))
+	for _, name := range names {
+		if t, ok := parseSnapshotName(name); ok {
+			snapshots = append(snapshots, storedSnapshot{name: name, time: t})
+		}
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].time.After(snapshots[j].time)
+	})
+	return snapshots, nil
+}
+
+// rotate removes the snapshots beyond the number of snapshots to retain, and
+// the snapshots older than the age to retain. The most recent snapshot is
+// always retained.
+func (a *snapshotAgent) rotate() error {
+	if a.retain == 0 && a.retainAge == 0 {
+		return nil
+	}
+
+	snapshots, err := a.listSnapshots()
+	if err != nil {
+		return err
+	}
+
+	var retained int
+	var lastErr error
+	now := a.now()
+	for i, snap := range snapshots {
+		expired := (a.retain > 0 && i >= a.retain) ||
+			(a.retainAge > 0 && i > 0 && now.Sub(snap.time) > a.retainAge)
+		if !expired {
+			retained++
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		err := a.storage.Delete(ctx, snap.name)
+		cancel()
+		if err != nil {
+			retained++
+			lastErr = fmt.Errorf("failed to remove snapshot %q: %v", snap.name, err)
+			continue
+		}
+		a.logger.Debug("Removed old snapshot", "name", snap.name)
+	}
+	metrics.SetGauge([]string{"snapshot", "agent", "retained"}, float32(retained))
+	return lastErr
+}
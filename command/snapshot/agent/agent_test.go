@@ -0,0 +1,163 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/require"
+)
+
+// memStorage keeps snapshots in memory.
+type memStorage struct {
+	mu        sync.Mutex
+	snapshots map[string][]byte
+	saveErr   error
+	deleteErr error
+}
+
+func newMemStorage(names ...string) *memStorage {
+	s := &memStorage{snapshots: make(map[string][]byte)}
+	for _, name := range names {
+		s.snapshots[name] = nil
+	}
+	return s
+}
+
+func (s *memStorage) String() string {
+	return "memory"
+}
+
+func (s *memStorage) Save(_ context.Context, name string, snap io.ReadSeeker) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.saveErr != nil {
+		return s.saveErr
+	}
+	data, err := io.ReadAll(snap)
+	if err != nil {
+		return err
+	}
+	s.snapshots[name] = data
+	return nil
+}
+
+func (s *memStorage) List(_ context.Context) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var names []string
+	for name := range s.snapshots {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s *memStorage) Delete(_ context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.deleteErr != nil {
+		return s.deleteErr
+	}
+	delete(s.snapshots, name)
+	return nil
+}
+
+func TestSnapshotAgent_Rotate(t *testing.T) {
+	now := time.Unix(1000000, 0)
+	hoursAgo := func(hours ...int) []string {
+		var names []string
+		for _, h := range hours {
+			names = append(names, snapshotName(now.Add(-time.Duration(h)*time.Hour)))
+		}
+		return names
+	}
+
+	cases := map[string]struct {
+		retain    int
+		retainAge time.Duration
+		stored    []string
+		expected  []string
+	}{
+		"retain all": {
+			stored:   hoursAgo(1, 2, 3),
+			expected: hoursAgo(1, 2, 3),
+		},
+		"retain count": {
+			retain:   2,
+			stored:   hoursAgo(3, 1, 2, 4),
+			expected: hoursAgo(1, 2),
+		},
+		"retain age": {
+			retainAge: 150 * time.Minute,
+			stored:    hoursAgo(1, 2, 3, 4),
+			expected:  hoursAgo(1, 2),
+		},
+		"retain count and age": {
+			retain:    3,
+			retainAge: 150 * time.Minute,
+			stored:    hoursAgo(1, 2, 3, 4),
+			expected:  hoursAgo(1, 2),
+		},
+		"most recent is always retained": {
+			retainAge: time.Hour,
+			stored:    hoursAgo(5, 6),
+			expected:  hoursAgo(5),
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			store := newMemStorage(tc.stored...)
+			a := &snapshotAgent{
+				storage:   store,
+				logger:    hclog.NewNullLogger(),
+				retain:    tc.retain,
+				retainAge: tc.retainAge,
+				now:       func() time.Time { return now },
+			}
+			require.NoError(t, a.rotate())
+
+			names, err := store.List(context.Background())
+			require.NoError(t, err)
+			expected := append([]string(nil), tc.expected...)
+			sort.Strings(expected)
+			require.Equal(t, expected, names)
+		})
+	}
+
+	t.Run("delete error", func(t *testing.T) {
+		store := newMemStorage(hoursAgo(1, 2)...)
+		store.deleteErr = errors.New("permission denied")
+		a := &snapshotAgent{
+			storage: store,
+			logger:  hclog.NewNullLogger(),
+			retain:  1,
+			now:     func() time.Time { return now },
+		}
+		err := a.rotate()
+		require.ErrorContains(t, err, "permission denied")
+	})
+}
+
+func TestSnapshotAgent_LastSnapshot(t *testing.T) {
+	a := &snapshotAgent{
+		storage: newMemStorage(),
+		logger:  hclog.NewNullLogger(),
+	}
+	last, err := a.lastSnapshot()
+	require.NoError(t, err)
+	require.True(t, last.IsZero())
+
+	a.storage = newMemStorage(snapshotName(time.Unix(20, 0)), snapshotName(time.Unix(30, 0)), snapshotName(time.Unix(10, 0)))
+	last, err = a.lastSnapshot()
+	require.NoError(t, err)
+	require.True(t, time.Unix(30, 0).Equal(last))
+}
@@ -0,0 +1,268 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package agent
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/mitchellh/cli"
+
+	"github.com/hashicorp/consul/command/flags"
+	"github.com/hashicorp/consul/lib"
+	"github.com/hashicorp/consul/logging"
+	"github.com/hashicorp/consul/snapshot"
+)
+
+func New(ui cli.Ui, shutdownCh <-chan struct{}) *cmd {
+	c := &cmd{UI: ui, shutdownCh: shutdownCh}
+	c.init()
+	return c
+}
+
+type cmd struct {
+	UI         cli.Ui
+	flags      *flag.FlagSet
+	http       *flags.HTTPFlags
+	encryption *flags.SnapshotEncryptionFlags
+	help       string
+
+	shutdownCh <-chan struct{}
+
+	// flags
+	interval         time.Duration
+	retain           int
+	retainAge        time.Duration
+	lockKey          string
+	maxFailures      int
+	scratchPath      string
+	compression      string
+	logLevel         string
+	logJSON          bool
+	statsdAddr       string
+	dogstatsdAddr    string
+	localPath        string
+	s3Bucket         string
+	s3KeyPrefix      string
+	s3Region         string
+	s3Endpoint       string
+	s3ForcePathStyle bool
+	s3SSE            bool
+	awsAccessKeyID   string
+	awsSecretKey     string
+	awsSessionToken  string
+}
+
+func (c *cmd) init() {
+	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
+	c.flags.DurationVar(&c.interval, "interval", time.Hour,
+		"Interval at which to take snapshots. If 0, the agent takes a single "+
+			"snapshot, removes the old snapshots and exits.")
+	c.flags.IntVar(&c.retain, "retain", 30,
+		"Number of snapshots to retain. Older snapshots are removed after each "+
+			"snapshot. If 0, snapshots are not removed based on their count.")
+	c.flags.DurationVar(&c.retainAge, "retain-age", 0,
+		"Age after which snapshots are removed. The most recent snapshot is "+
+			"always retained. If 0, snapshots are not removed based on their age.")
+	c.flags.StringVar(&c.lockKey, "lock-key", "consul-snapshot/lock",
+		"KV key used to coordinate several snapshot agents so that only one of "+
+			"them takes snapshots at a time.")
+	c.flags.IntVar(&c.maxFailures, "max-failures", 3,
+		"Number of snapshot failures in a row after which the agent gives up "+
+			"its leadership, so that another agent can take over.")
+	c.flags.StringVar(&c.scratchPath, "local-scratch-path", "",
+		"Directory to store snapshots in while they are verified and saved. "+
+			"Defaults to the system temporary directory.")
+	c.flags.StringVar(&c.compression, "compression", string(snapshot.CompressionGzip),
+		"Compression of the snapshot archives, either \"gzip\" or \"zstd\".")
+	c.flags.StringVar(&c.logLevel, "log-level", "INFO",
+		"Specifies the log level.")
+	c.flags.BoolVar(&c.logJSON, "log-json", false,
+		"Output logs in JSON format.")
+	c.flags.StringVar(&c.statsdAddr, "statsd-addr", "",
+		"Address of a statsd server to send the metrics to.")
+	c.flags.StringVar(&c.dogstatsdAddr, "dogstatsd-addr", "",
+		"Address of a DogStatsD server to send the metrics to.")
+	c.flags.StringVar(&c.localPath, "local-path", ".",
+		"Directory to save snapshots to. Ignored if -aws-s3-bucket is set.")
+	c.flags.StringVar(&c.s3Bucket, "aws-s3-bucket", "",
+		"S3 bucket to save snapshots to. Setting this disables local storage.")
+	c.flags.StringVar(&c.s3KeyPrefix, "aws-s3-key-prefix", "consul-snapshot",
+		"Prefix of the keys of the snapshots in the S3 bucket.")
+	c.flags.StringVar(&c.s3Region, "aws-s3-region", "",
+		"Region of the S3 bucket. Required for S3 storage.")
+	c.flags.StringVar(&c.s3Endpoint, "aws-s3-endpoint", os.Getenv("AWS_S3_ENDPOINT"),
+		"S3 endpoint to use, for S3-compatible storage such as MinIO. Can also "+
+			"be set with the AWS_S3_ENDPOINT environment variable.")
+	c.flags.BoolVar(&c.s3ForcePathStyle, "aws-s3-force-path-style", false,
+		"Use path-style addressing of the S3 bucket, which MinIO and other "+
+			"S3-compatible storage require.")
+	c.flags.BoolVar(&c.s3SSE, "aws-s3-server-side-encryption", false,
+		"Save snapshots to S3 with server side encryption.")
+	c.flags.StringVar(&c.awsAccessKeyID, "aws-access-key-id", "",
+		"Static access key ID for S3. The default AWS credential chain is used "+
+			"if this isn't set.")
+	c.flags.StringVar(&c.awsSecretKey, "aws-secret-access-key", "",
+		"Static secret access key for S3.")
+	c.flags.StringVar(&c.awsSessionToken, "aws-session-token", "",
+		"Static session token for S3.")
+
+	c.http = &flags.HTTPFlags{}
+	c.encryption = &flags.SnapshotEncryptionFlags{}
+	flags.Merge(c.flags, c.http.ClientFlags())
+	flags.Merge(c.flags, c.http.ServerFlags())
+	flags.Merge(c.flags, c.encryption.Flags())
+	c.help = flags.Usage(help, c.flags)
+}
+
+func (c *cmd) Run(args []string) int {
+	if err := c.flags.Parse(args); err != nil {
+		return 1
+	}
+	if len(c.flags.Args()) > 0 {
+		c.UI.Error(fmt.Sprintf("Too many arguments (expected 0, got %d)", len(c.flags.Args())))
+		return 1
+	}
+
+	if c.interval < 0 {
+		c.UI.Error("The -interval must not be negative")
+		return 1
+	}
+	if c.retain < 0 || c.retainAge < 0 {
+		c.UI.Error("The -retain and -retain-age must not be negative")
+		return 1
+	}
+	if c.lockKey == "" && c.interval > 0 {
+		c.UI.Error("The -lock-key must be set")
+		return 1
+	}
+	compression, err := snapshot.ParseCompression(c.compression)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Invalid -compression: %s", err))
+		return 1
+	}
+	opts, err := c.encryption.Options()
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+	opts.Compression = string(compression)
+	kp, err := snapshot.ParseKeyProvider(opts.EncryptionKey, opts.EncryptionPassphrase)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error loading encryption key: %s", err))
+		return 1
+	}
+
+	logger, err := logging.Setup(logging.Config{
+		LogLevel: c.logLevel,
+		Name:     logging.SnapshotAgent,
+		LogJSON:  c.logJSON,
+	}, &cli.UiWriter{Ui: c.UI})
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	metricsConfig, err := lib.InitTelemetry(lib.TelemetryConfig{
+		MetricsPrefix:   "consul",
+		FilterDefault:   true,
+		DisableHostname: true,
+		StatsdAddr:      c.statsdAddr,
+		DogstatsdAddr:   c.dogstatsdAddr,
+	}, logger)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error initializing telemetry: %s", err))
+		return 1
+	}
+	defer metricsConfig.Cancel()
+
+	var store storage
+	if c.s3Bucket != "" {
+		store, err = newS3Storage(s3Config{
+			Bucket:               c.s3Bucket,
+			KeyPrefix:            c.s3KeyPrefix,
+			Region:               c.s3Region,
+			Endpoint:             c.s3Endpoint,
+			ForcePathStyle:       c.s3ForcePathStyle,
+			ServerSideEncryption: c.s3SSE,
+			AccessKeyID:          c.awsAccessKeyID,
+			SecretAccessKey:      c.awsSecretKey,
+			SessionToken:         c.awsSessionToken,
+		})
+	} else {
+		store, err = newLocalStorage(c.localPath)
+	}
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error configuring snapshot storage: %s", err))
+		return 1
+	}
+
+	client, err := c.http.APIClient()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error connecting to Consul agent: %s", err))
+		return 1
+	}
+
+	agent := &snapshotAgent{
+		client:      client,
+		storage:     store,
+		logger:      logger,
+		interval:    c.interval,
+		retain:      c.retain,
+		retainAge:   c.retainAge,
+		stale:       c.http.Stale(),
+		lockKey:     c.lockKey,
+		maxFailures: c.maxFailures,
+		scratchPath: c.scratchPath,
+		options:     opts,
+		keyProvider: kp,
+		now:         time.Now,
+	}
+
+	logger.Info("Snapshot agent running", "storage", store.String(), "interval", c.interval)
+	if err := agent.Run(c.shutdownCh); err != nil {
+		c.UI.Error(fmt.Sprintf("Error running snapshot agent: %s", err))
+		return 1
+	}
+	return 0
+}
+
+func (c *cmd) Synopsis() string {
+	return synopsis
+}
+
+func (c *cmd) Help() string {
+	return c.help
+}
+
+const synopsis = "Periodically saves snapshots of Consul server state"
+const help = `
+Usage: consul snapshot agent [options]
+
+  Starts a process that takes snapshots of the state of the Consul servers at
+  an interval, saves them to a local directory or an S3-compatible bucket, and
+  removes the old snapshots.
+
+  Several agents can be run for high availability. They coordinate with a lock
+  in the KV store so that only one of them takes snapshots at a time.
+
+  If ACLs are enabled, a management token must be supplied in order to perform
+  snapshot operations.
+
+  To take a snapshot every hour and retain the last 30 snapshots in the
+  "/var/lib/consul-snapshots" directory:
+
+    $ consul snapshot agent -local-path=/var/lib/consul-snapshots
+
+  To take a single snapshot, for example from a batch job, and save it to a
+  MinIO bucket:
+
+    $ consul snapshot agent -interval=0 -aws-s3-bucket=backups \
+        -aws-s3-region=us-east-1 -aws-s3-endpoint=http://127.0.0.1:9000 \
+        -aws-s3-force-path-style
+
+  For a full list of options and examples, please see the Consul documentation.
+`
@@ -0,0 +1,229 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/mitchellh/cli"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/consul/agent"
+	"github.com/hashicorp/consul/sdk/testutil"
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+	"github.com/hashicorp/consul/snapshot"
+)
+
+func TestSnapshotAgentCommand_noTabs(t *testing.T) {
+	t.Parallel()
+	if strings.ContainsRune(New(cli.NewMockUi(), nil).Help(), '\t') {
+		t.Fatal("help has tabs")
+	}
+}
+
+func TestSnapshotAgentCommand_Validation(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		args   []string
+		output string
+	}{
+		"extra args": {
+			[]string{"foo"},
+			"Too many arguments",
+		},
+		"negative interval": {
+			[]string{"-interval=-1s"},
+			"The -interval must not be negative",
+		},
+		"negative retain": {
+			[]string{"-retain=-1"},
+			"The -retain and -retain-age must not be negative",
+		},
+		"no lock key": {
+			[]string{"-lock-key="},
+			"The -lock-key must be set",
+		},
+		"bad compression": {
+			[]string{"-compression=lz4"},
+			"Invalid -compression",
+		},
+		"incomplete S3 config": {
+			[]string{"-aws-s3-bucket=backups"},
+			"S3 region is required",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			ui := cli.NewMockUi()
+			c := New(ui, nil)
+
+			code := c.Run(tc.args)
+			require.Equal(t, 1, code)
+			require.Contains(t, ui.ErrorWriter.String(), tc.output)
+		})
+	}
+}
+
+func TestSnapshotAgentCommand_OneShot(t *testing.T) {
+	if testing.Short() {
+		t.Skip("too slow for testing.Short")
+	}
+
+	t.Parallel()
+	a := agent.NewTestAgent(t, ``)
+	defer a.Shutdown()
+
+	dir := testutil.TempDir(t, "snapshot")
+
+	// Seed the directory with old snapshots, only the most recent of them
+	// should be retained.
+	for _, age := range []time.Duration{time.Hour, 2 * time.Hour} {
+		name := snapshotName(time.Now().Add(-age))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0600))
+	}
+
+	ui := cli.NewMockUi()
+	c := New(ui, nil)
+	args := []string{
+		"-http-addr=" + a.HTTPAddr(),
+		"-interval=0",
+		"-retain=2",
+		"-compression=zstd",
+		"-local-path=" + dir,
+	}
+	code := c.Run(args)
+	require.Equal(t, 0, code, ui.ErrorWriter.String())
+	require.Contains(t, ui.OutputWriter.String(), "Saved snapshot")
+
+	s, err := newLocalStorage(dir)
+	require.NoError(t, err)
+	a2 := &snapshotAgent{storage: s, logger: hclog.NewNullLogger()}
+	snapshots, err := a2.listSnapshots()
+	require.NoError(t, err)
+	require.Len(t, snapshots, 2)
+	require.WithinDuration(t, time.Now(), snapshots[0].time, time.Minute)
+	require.WithinDuration(t, time.Now().Add(-time.Hour), snapshots[1].time, time.Minute)
+
+	f, err := os.Open(filepath.Join(dir, snapshots[0].name))
+	require.NoError(t, err)
+	defer f.Close()
+	_, err = snapshot.Verify(f, nil)
+	require.NoError(t, err)
+}
+
+func TestSnapshotAgentCommand_Daemon(t *testing.T) {
+	if testing.Short() {
+		t.Skip("too slow for testing.Short")
+	}
+
+	t.Parallel()
+	a := agent.NewTestAgent(t, ``)
+	defer a.Shutdown()
+	client := a.Client()
+
+	dir := testutil.TempDir(t, "snapshot")
+	shutdownCh := make(chan struct{})
+	ui := cli.NewMockUi()
+	c := New(ui, shutdownCh)
+	args := []string{
+		"-http-addr=" + a.HTTPAddr(),
+		"-interval=200ms",
+		"-retain=2",
+		"-lock-key=snapshot/lock",
+		"-local-path=" + dir,
+	}
+
+	doneCh := make(chan int, 1)
+	go func() {
+		doneCh <- c.Run(args)
+	}()
+
+	// Wait for a few snapshots to be taken and rotated.
+	s, err := newLocalStorage(dir)
+	require.NoError(t, err)
+	retry.Run(t, func(r *retry.R) {
+		require.Contains(r, ui.OutputWriter.String(), "Obtained leadership")
+		require.GreaterOrEqual(r, strings.Count(ui.OutputWriter.String(), "Saved snapshot"), 3)
+		names, err := s.List(context.Background())
+		require.NoError(r, err)
+		require.Len(r, names, 2)
+	})
+
+	// The lock is held while the agent runs.
+	pair, _, err := client.KV().Get("snapshot/lock", nil)
+	require.NoError(t, err)
+	require.NotNil(t, pair)
+	require.NotEmpty(t, pair.Session)
+
+	close(shutdownCh)
+	select {
+	case code := <-doneCh:
+		require.Equal(t, 0, code, ui.ErrorWriter.String())
+	case <-time.After(10 * time.Second):
+		t.Fatal("snapshot agent didn't shut down")
+	}
+
+	// The lock is released on shutdown.
+	pair, _, err = client.KV().Get("snapshot/lock", nil)
+	require.NoError(t, err)
+	if pair != nil {
+		require.Empty(t, pair.Session)
+	}
+}
+
+func TestSnapshotAgent_Failover(t *testing.T) {
+	if testing.Short() {
+		t.Skip("too slow for testing.Short")
+	}
+
+	t.Parallel()
+	a := agent.NewTestAgent(t, ``)
+	defer a.Shutdown()
+	client := a.Client()
+
+	newAgent := func(store storage) *snapshotAgent {
+		return &snapshotAgent{
+			client:      client,
+			storage:     store,
+			logger:      testutil.Logger(t),
+			interval:    100 * time.Millisecond,
+			lockKey:     "snapshot/lock",
+			maxFailures: 2,
+			now:         time.Now,
+		}
+	}
+
+	// The first agent fails to save snapshots, so it should give up its
+	// leadership to the second agent.
+	failing := newMemStorage()
+	failing.saveErr = errors.New("disk full")
+	working := newMemStorage()
+
+	shutdownCh := make(chan struct{})
+	defer close(shutdownCh)
+
+	go newAgent(failing).Run(shutdownCh)
+	retry.Run(t, func(r *retry.R) {
+		pair, _, err := client.KV().Get("snapshot/lock", nil)
+		require.NoError(r, err)
+		require.NotNil(r, pair)
+		require.NotEmpty(r, pair.Session)
+	})
+	go newAgent(working).Run(shutdownCh)
+
+	retry.Run(t, func(r *retry.R) {
+		names, err := working.List(context.Background())
+		require.NoError(r, err)
+		require.NotEmpty(r, names)
+	})
+}
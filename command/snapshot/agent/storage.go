@@ -0,0 +1,103 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package agent
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rboyer/safeio"
+)
+
+const (
+	snapshotPrefix = "consul-"
+	snapshotSuffix = ".snap"
+)
+
+// storage is a destination the snapshot agent saves snapshots to.
+type storage interface {
+	// String describes the storage in the logs.
+	String() string
+
+	// Save stores the snapshot under the given name.
+	Save(ctx context.Context, name string, snap io.ReadSeeker) error
+
+	// List returns the names of the stored snapshots. Files that don't look
+	// like snapshots saved by the agent are ignored.
+	List(ctx context.Context) ([]string, error)
+
+	// Delete removes the snapshot with the given name.
+	Delete(ctx context.Context, name string) error
+}
+
+// snapshotName returns the name of a snapshot taken at the given time. The
+// name includes a UNIX timestamp with nanosecond resolution, so names are
+// unlikely to collide and sort in the order the snapshots were taken.
+func snapshotName(t time.Time) string {
+	return fmt.Sprintf("%s%d%s", snapshotPrefix, t.UnixNano(), snapshotSuffix)
+}
+
+// parseSnapshotName returns the time the snapshot with the given name was
+// taken at, and false if the name isn't the name of a snapshot.
+func parseSnapshotName(name string) (time.Time, bool) {
+	if !strings.HasPrefix(name, snapshotPrefix) || !strings.HasSuffix(name, snapshotSuffix) {
+		return time.Time{}, false
+	}
+	id := strings.TrimSuffix(strings.TrimPrefix(name, snapshotPrefix), snapshotSuffix)
+	nanos, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(0, nanos), true
+}
+
+// localStorage saves snapshots to a local directory.
+type localStorage struct {
+	path string
+}
+
+func newLocalStorage(path string) (*localStorage, error) {
+	if err := os.MkdirAll(path, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create snapshot directory: %v", err)
+	}
+	return &localStorage{path: path}, nil
+}
+
+func (s *localStorage) String() string {
+	return fmt.Sprintf("local path %q", s.path)
+}
+
+func (s *localStorage) Save(_ context.Context, name string, snap io.ReadSeeker) error {
+	// safeio writes to a temporary file and renames it, so a partially
+	// written snapshot never shows up under its final name.
+	_, err := safeio.WriteToFile(snap, filepath.Join(s.path, name), 0600)
+	return err
+}
+
+func (s *localStorage) List(_ context.Context) ([]string, error) {
+	entries, err := os.ReadDir(s.path)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if _, ok := parseSnapshotName(entry.Name()); ok {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+func (s *localStorage) Delete(_ context.Context, name string) error {
+	return os.Remove(filepath.Join(s.path, name))
+}
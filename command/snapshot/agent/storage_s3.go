@@ -0,0 +1,126 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package agent
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// s3Config is the configuration of the S3 storage.
+type s3Config struct {
+	Bucket               string
+	KeyPrefix            string
+	Region               string
+	Endpoint             string
+	ForcePathStyle       bool
+	ServerSideEncryption bool
+
+	// The static credentials are optional, the default credential chain of
+	// the AWS SDK is used when they aren't set.
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// s3Storage saves snapshots to an S3 bucket, or any S3-compatible endpoint
+// like MinIO.
+type s3Storage struct {
+	config   s3Config
+	client   *s3.S3
+	uploader *s3manager.Uploader
+}
+
+func newS3Storage(config s3Config) (*s3Storage, error) {
+	if config.Bucket == "" {
+		return nil, fmt.Errorf("S3 bucket is required")
+	}
+	if config.Region == "" {
+		return nil, fmt.Errorf("S3 region is required")
+	}
+
+	awsConfig := aws.NewConfig().
+		WithRegion(config.Region).
+		WithS3ForcePathStyle(config.ForcePathStyle)
+	if config.Endpoint != "" {
+		awsConfig = awsConfig.WithEndpoint(config.Endpoint)
+	}
+	if config.AccessKeyID != "" || config.SecretAccessKey != "" {
+		awsConfig = awsConfig.WithCredentials(credentials.NewStaticCredentials(
+			config.AccessKeyID, config.SecretAccessKey, config.SessionToken))
+	}
+
+	sess, err := session.NewSession(awsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AWS session: %v", err)
+	}
+	client := s3.New(sess)
+	return &s3Storage{
+		config:   config,
+		client:   client,
+		uploader: s3manager.NewUploaderWithClient(client),
+	}, nil
+}
+
+func (s *s3Storage) String() string {
+	return fmt.Sprintf("S3 bucket %q", path.Join(s.config.Bucket, s.config.KeyPrefix))
+}
+
+// key returns the object key of the snapshot with the given name.
+func (s *s3Storage) key(name string) string {
+	return path.Join(s.config.KeyPrefix, name)
+}
+
+func (s *s3Storage) Save(ctx context.Context, name string, snap io.ReadSeeker) error {
+	input := &s3manager.UploadInput{
+		Bucket: aws.String(s.config.Bucket),
+		Key:    aws.String(s.key(name)),
+		Body:   snap,
+	}
+	if s.config.ServerSideEncryption {
+		input.ServerSideEncryption = aws.String(s3.ServerSideEncryptionAes256)
+	}
+	_, err := s.uploader.UploadWithContext(ctx, input)
+	return err
+}
+
+func (s *s3Storage) List(ctx context.Context) ([]string, error) {
+	prefix := s.key(snapshotPrefix)
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.config.Bucket),
+		Prefix: aws.String(prefix),
+	}
+
+	var names []string
+	err := s.client.ListObjectsV2PagesWithContext(ctx, input, func(page *s3.ListObjectsV2Output, _ bool) bool {
+		for _, obj := range page.Contents {
+			name := strings.TrimPrefix(aws.StringValue(obj.Key), strings.TrimSuffix(prefix, snapshotPrefix))
+			if _, ok := parseSnapshotName(name); ok {
+				names = append(names, name)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+func (s *s3Storage) Delete(ctx context.Context, name string) error {
+	_, err := s.client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.config.Bucket),
+		Key:    aws.String(s.key(name)),
+	})
+	return err
+}
@@ -0,0 +1,193 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/consul/sdk/testutil"
+)
+
+func TestSnapshotName(t *testing.T) {
+	now := time.Unix(1479360073, 448728784)
+	name := snapshotName(now)
+	require.Equal(t, "consul-1479360073448728784.snap", name)
+
+	parsed, ok := parseSnapshotName(name)
+	require.True(t, ok)
+	require.True(t, now.Equal(parsed))
+
+	for _, name := range []string{"backup.snap", "consul-abc.snap", "consul-123.snap.tmp", "consul-123"} {
+		_, ok := parseSnapshotName(name)
+		require.False(t, ok, name)
+	}
+}
+
+// testStorage runs the storage through saving, listing and deleting
+// snapshots.
+func testStorage(t *testing.T, s storage) {
+	ctx := context.Background()
+
+	names, err := s.List(ctx)
+	require.NoError(t, err)
+	require.Empty(t, names)
+
+	first := snapshotName(time.Unix(1, 0))
+	second := snapshotName(time.Unix(2, 0))
+	require.NoError(t, s.Save(ctx, first, bytes.NewReader([]byte("first"))))
+	require.NoError(t, s.Save(ctx, second, bytes.NewReader([]byte("second"))))
+
+	names, err = s.List(ctx)
+	require.NoError(t, err)
+	sort.Strings(names)
+	require.Equal(t, []string{first, second}, names)
+
+	require.NoError(t, s.Delete(ctx, first))
+	names, err = s.List(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{second}, names)
+}
+
+func TestLocalStorage(t *testing.T) {
+	dir := filepath.Join(testutil.TempDir(t, "snapshot"), "snapshots")
+	s, err := newLocalStorage(dir)
+	require.NoError(t, err)
+
+	// Files that aren't snapshots are ignored.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "other.snap"), []byte("other"), 0600))
+
+	testStorage(t, s)
+
+	data, err := os.ReadFile(filepath.Join(dir, snapshotName(time.Unix(2, 0))))
+	require.NoError(t, err)
+	require.Equal(t, "second", string(data))
+}
+
+func TestS3Storage(t *testing.T) {
+	fake := newFakeS3()
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	s, err := newS3Storage(s3Config{
+		Bucket:          "backups",
+		KeyPrefix:       "consul-snapshot",
+		Region:          "us-east-1",
+		Endpoint:        srv.URL,
+		ForcePathStyle:  true,
+		AccessKeyID:     "minio",
+		SecretAccessKey: "minio123",
+	})
+	require.NoError(t, err)
+
+	// Objects outside of the prefix and objects that aren't snapshots are
+	// ignored.
+	fake.put("backups", "other/consul-1.snap", []byte("other"))
+	fake.put("backups", "consul-snapshot/consul-latest.snap", []byte("other"))
+
+	testStorage(t, s)
+
+	require.Equal(t, []byte("second"), fake.get("backups", "consul-snapshot/"+snapshotName(time.Unix(2, 0))))
+}
+
+func TestS3Storage_Validation(t *testing.T) {
+	_, err := newS3Storage(s3Config{Region: "us-east-1"})
+	require.ErrorContains(t, err, "S3 bucket is required")
+
+	_, err = newS3Storage(s3Config{Bucket: "backups"})
+	require.ErrorContains(t, err, "S3 region is required")
+}
+
+// fakeS3 is a minimal S3-compatible server that supports path-style
+// requests to put, list and delete objects.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{objects: make(map[string][]byte)}
+}
+
+func (f *fakeS3) put(bucket, key string, data []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.objects[bucket+"/"+key] = data
+}
+
+func (f *fakeS3) get(bucket, key string) []byte {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.objects[bucket+"/"+key]
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	path := strings.TrimPrefix(req.URL.Path, "/")
+	bucket, key, _ := strings.Cut(path, "/")
+
+	switch {
+	case req.Method == http.MethodPut && key != "":
+		data, err := io.ReadAll(req.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		f.objects[path] = data
+		w.WriteHeader(http.StatusOK)
+
+	case req.Method == http.MethodDelete && key != "":
+		delete(f.objects, path)
+		w.WriteHeader(http.StatusNoContent)
+
+	case req.Method == http.MethodGet && key == "" && req.URL.Query().Get("list-type") == "2":
+		type content struct {
+			Key  string
+			Size int
+		}
+		result := struct {
+			XMLName     xml.Name `xml:"ListBucketResult"`
+			Name        string
+			Prefix      string
+			KeyCount    int
+			IsTruncated bool
+			Contents    []content
+		}{
+			Name:   bucket,
+			Prefix: req.URL.Query().Get("prefix"),
+		}
+		var keys []string
+		for name := range f.objects {
+			if strings.HasPrefix(name, bucket+"/"+result.Prefix) {
+				keys = append(keys, strings.TrimPrefix(name, bucket+"/"))
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			result.Contents = append(result.Contents, content{Key: k, Size: len(f.objects[bucket+"/"+k])})
+		}
+		result.KeyCount = len(result.Contents)
+
+		w.Header().Set("Content-Type", "application/xml")
+		xml.NewEncoder(w).Encode(result)
+
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
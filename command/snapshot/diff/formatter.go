@@ -0,0 +1,64 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package diff
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hashicorp/consul/command/snapshot/inspect"
+)
+
+type Formatter interface {
+	Format(*OutputFormat) (string, error)
+}
+
+func NewFormatter(format string) (Formatter, error) {
+	switch format {
+	case inspect.PrettyFormat:
+		return &prettyFormatter{}, nil
+	case inspect.JSONFormat:
+		return &jsonFormatter{}, nil
+	default:
+		return nil, fmt.Errorf("Unknown format: %s", format)
+	}
+}
+
+type prettyFormatter struct{}
+
+func (_ *prettyFormatter) Format(info *OutputFormat) (string, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s (index %d)\n", info.From.ID, info.From.Index)
+	fmt.Fprintf(&b, "To:   %s (index %d)\n", info.To.ID, info.To.Index)
+
+	for _, t := range info.Tables {
+		fmt.Fprintf(&b, "\n%s: ", t.Name)
+		if len(t.Added)+len(t.Removed)+len(t.Changed) == 0 {
+			fmt.Fprint(&b, "no changes\n")
+			continue
+		}
+		fmt.Fprintf(&b, "%d added, %d removed, %d changed\n", len(t.Added), len(t.Removed), len(t.Changed))
+		for _, key := range t.Added {
+			fmt.Fprintf(&b, "  + %s\n", key)
+		}
+		for _, key := range t.Removed {
+			fmt.Fprintf(&b, "  - %s\n", key)
+		}
+		for _, key := range t.Changed {
+			fmt.Fprintf(&b, "  ~ %s\n", key)
+		}
+	}
+	return b.String(), nil
+}
+
+type jsonFormatter struct{}
+
+func (_ *jsonFormatter) Format(info *OutputFormat) (string, error) {
+	b, err := json.MarshalIndent(info, "", "   ")
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
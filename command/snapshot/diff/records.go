@@ -0,0 +1,191 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/hashicorp/consul-net-rpc/go-msgpack/codec"
+
+	"github.com/hashicorp/consul/acl"
+	"github.com/hashicorp/consul/agent/consul/fsm"
+	"github.com/hashicorp/consul/agent/structs"
+)
+
+// The tables that are compared, in the order they are output.
+const (
+	tableKV            = "kv"
+	tableServices      = "services"
+	tableConfigEntries = "config-entries"
+	tableACLTokens     = "acl-tokens"
+	tableIntentions    = "intentions"
+)
+
+var tables = []string{tableKV, tableServices, tableConfigEntries, tableACLTokens, tableIntentions}
+
+// records holds a fingerprint of each record of a snapshot, by table and
+// then by the key of the record.
+type records map[string]map[string]string
+
+func (r records) add(table, key string, record interface{}) error {
+	fp, err := fingerprint(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s record %q: %v", table, key, err)
+	}
+	if r[table] == nil {
+		r[table] = make(map[string]string)
+	}
+	r[table][key] = fp
+	return nil
+}
+
+// fingerprint encodes a record for comparison, without the Raft indexes
+// which change when a snapshot is restored even if the record didn't.
+func fingerprint(record interface{}) (string, error) {
+	raw, err := json.Marshal(record)
+	if err != nil {
+		return "", err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return "", err
+	}
+	delete(m, "CreateIndex")
+	delete(m, "ModifyIndex")
+
+	// Maps are encoded with sorted keys, so equal records have equal
+	// fingerprints.
+	raw, err = json.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+	return string(raw), nil
+}
+
+// readRecords decodes the records of the compared tables from the raw state
+// of a snapshot, as returned by inspect.Open.
+func readRecords(in io.Reader) (records, error) {
+	recs := make(records)
+	handler := func(header *fsm.SnapshotHeader, msg structs.MessageType, dec *codec.Decoder) error {
+		switch msg {
+		case structs.KVSRequestType:
+			var entry structs.DirEntry
+			if err := dec.Decode(&entry); err != nil {
+				return err
+			}
+			return recs.add(tableKV, qualifiedName(&entry.EnterpriseMeta, entry.Key), &entry)
+
+		case structs.RegisterRequestType:
+			var req structs.RegisterRequest
+			if err := dec.Decode(&req); err != nil {
+				return err
+			}
+			// Nodes and checks are also persisted as register requests,
+			// only the ones with a service are compared.
+			if req.Service == nil {
+				return nil
+			}
+			key := req.Node + "/" + req.Service.ID
+			if req.PeerName != "" {
+				key = "peer:" + req.PeerName + "/" + key
+			}
+			return recs.add(tableServices, qualifiedName(&req.Service.EnterpriseMeta, key), req.Service)
+
+		case structs.ConfigEntryRequestType:
+			var req structs.ConfigEntryRequest
+			if err := dec.Decode(&req); err != nil {
+				return err
+			}
+			if req.Entry == nil {
+				return nil
+			}
+			// Intentions are stored as service-intentions config entries,
+			// they're reported in their own table.
+			if req.Entry.GetKind() == structs.ServiceIntentions {
+				return recs.add(tableIntentions, qualifiedName(req.Entry.GetEnterpriseMeta(), req.Entry.GetName()), req.Entry)
+			}
+			name := req.Entry.GetKind() + "/" + req.Entry.GetName()
+			return recs.add(tableConfigEntries, qualifiedName(req.Entry.GetEnterpriseMeta(), name), req.Entry)
+
+		case structs.IntentionRequestType:
+			// Legacy intentions from before they were migrated to config
+			// entries.
+			var ixn structs.Intention
+			if err := dec.Decode(&ixn); err != nil {
+				return err
+			}
+			return recs.add(tableIntentions, ixn.ID, &ixn)
+
+		case structs.ACLTokenSetRequestType:
+			var token structs.ACLToken
+			if err := dec.Decode(&token); err != nil {
+				return err
+			}
+			return recs.add(tableACLTokens, token.AccessorID, &token)
+
+		default:
+			var val interface{}
+			return dec.Decode(&val)
+		}
+	}
+	if err := fsm.ReadSnapshot(in, handler); err != nil {
+		return nil, err
+	}
+	return recs, nil
+}
+
+// qualifiedName prefixes the name with its partition and namespace, if any.
+func qualifiedName(entMeta *acl.EnterpriseMeta, name string) string {
+	if ns := entMeta.NamespaceOrEmpty(); ns != "" {
+		name = ns + "/" + name
+	}
+	if ap := entMeta.PartitionOrEmpty(); ap != "" {
+		name = ap + "/" + name
+	}
+	return name
+}
+
+// TableDiff is the difference of a table between two snapshots.
+type TableDiff struct {
+	Name    string
+	Added   []string
+	Removed []string
+	Changed []string
+}
+
+// diffRecords compares the records of two snapshots and returns the
+// difference of each table.
+func diffRecords(from, to records) []TableDiff {
+	diffs := make([]TableDiff, 0, len(tables))
+	for _, table := range tables {
+		d := TableDiff{
+			Name:    table,
+			Added:   []string{},
+			Removed: []string{},
+			Changed: []string{},
+		}
+		for key, fp := range to[table] {
+			old, ok := from[table][key]
+			switch {
+			case !ok:
+				d.Added = append(d.Added, key)
+			case old != fp:
+				d.Changed = append(d.Changed, key)
+			}
+		}
+		for key := range from[table] {
+			if _, ok := to[table][key]; !ok {
+				d.Removed = append(d.Removed, key)
+			}
+		}
+		sort.Strings(d.Added)
+		sort.Strings(d.Removed)
+		sort.Strings(d.Changed)
+		diffs = append(diffs, d)
+	}
+	return diffs
+}
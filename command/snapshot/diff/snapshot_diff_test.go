@@ -0,0 +1,200 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package diff
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/consul/agent"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/sdk/testutil"
+)
+
+// update allows golden files to be updated based on the current output.
+var update = flag.Bool("update", false, "update golden files")
+
+// golden reads and optionally writes the expected data to the golden file,
+// returning the contents as a string.
+func golden(t *testing.T, name, got string) string {
+	t.Helper()
+
+	golden := filepath.Join("testdata", name+".golden")
+	if *update && got != "" {
+		err := os.WriteFile(golden, []byte(got), 0644)
+		require.NoError(t, err)
+	}
+
+	expected, err := os.ReadFile(golden)
+	require.NoError(t, err)
+
+	return string(expected)
+}
+
+func TestSnapshotDiffCommand_noTabs(t *testing.T) {
+	t.Parallel()
+	if strings.ContainsRune(New(cli.NewMockUi()).Help(), '\t') {
+		t.Fatal("help has tabs")
+	}
+}
+
+func TestSnapshotDiffCommand_Validation(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		args   []string
+		output string
+	}{
+		"no files": {
+			[]string{},
+			"Expected two snapshot files to compare, got 0",
+		},
+		"one file": {
+			[]string{"a.snap"},
+			"Expected two snapshot files to compare, got 1",
+		},
+		"bad format": {
+			[]string{"-format=yaml", "a.snap", "b.snap"},
+			"Unknown format: yaml",
+		},
+		"missing file": {
+			[]string{"does-not-exist.snap", "b.snap"},
+			"Error opening snapshot file",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			ui := cli.NewMockUi()
+			c := New(ui)
+
+			code := c.Run(tc.args)
+			require.Equal(t, 1, code)
+			require.Contains(t, ui.ErrorWriter.String(), tc.output)
+		})
+	}
+}
+
+func TestSnapshotDiffCommand(t *testing.T) {
+	for _, format := range []string{"pretty", "json"} {
+		t.Run(format, func(t *testing.T) {
+			ui := cli.NewMockUi()
+			c := New(ui)
+			args := []string{
+				"-format=" + format,
+				"../inspect/testdata/backup.snap",
+				"../inspect/testdata/backupWithKV.snap",
+			}
+
+			code := c.Run(args)
+			require.Equal(t, 0, code, ui.ErrorWriter.String())
+
+			want := golden(t, format, ui.OutputWriter.String())
+			require.Equal(t, want, ui.OutputWriter.String())
+		})
+	}
+}
+
+func TestSnapshotDiffCommand_Changes(t *testing.T) {
+	if testing.Short() {
+		t.Skip("too slow for testing.Short")
+	}
+
+	t.Parallel()
+	a := agent.NewTestAgent(t, ``)
+	defer a.Shutdown()
+	client := a.Client()
+	dir := testutil.TempDir(t, "snapshot")
+
+	save := func(name string) string {
+		snap, _, err := client.Snapshot().Save(nil)
+		require.NoError(t, err)
+		defer snap.Close()
+		data, err := io.ReadAll(snap)
+		require.NoError(t, err)
+		file := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(file, data, 0600))
+		return file
+	}
+
+	for _, key := range []string{"a", "b", "c"} {
+		_, err := client.KV().Put(&api.KVPair{Key: key, Value: []byte("old")}, nil)
+		require.NoError(t, err)
+	}
+	_, _, err := client.ConfigEntries().Set(&api.ServiceConfigEntry{
+		Kind:     api.ServiceDefaults,
+		Name:     "web",
+		Protocol: "http",
+	}, nil)
+	require.NoError(t, err)
+	before := save("before.snap")
+
+	_, err = client.KV().Delete("a", nil)
+	require.NoError(t, err)
+	_, err = client.KV().Put(&api.KVPair{Key: "b", Value: []byte("new")}, nil)
+	require.NoError(t, err)
+	_, err = client.KV().Put(&api.KVPair{Key: "d", Value: []byte("new")}, nil)
+	require.NoError(t, err)
+	_, err = client.Catalog().Register(&api.CatalogRegistration{
+		Node:    "node1",
+		Address: "127.0.0.2",
+		Service: &api.AgentService{ID: "api-1", Service: "api"},
+	}, nil)
+	require.NoError(t, err)
+	_, _, err = client.ConfigEntries().Set(&api.ServiceConfigEntry{
+		Kind:     api.ServiceDefaults,
+		Name:     "web",
+		Protocol: "grpc",
+	}, nil)
+	require.NoError(t, err)
+	_, _, err = client.ConfigEntries().Set(&api.ServiceIntentionsConfigEntry{
+		Kind: api.ServiceIntentions,
+		Name: "web",
+		Sources: []*api.SourceIntention{
+			{Name: "api", Action: api.IntentionActionAllow},
+		},
+	}, nil)
+	require.NoError(t, err)
+	after := save("after.snap")
+
+	ui := cli.NewMockUi()
+	c := New(ui)
+	code := c.Run([]string{"-format=json", before, after})
+	require.Equal(t, 0, code, ui.ErrorWriter.String())
+
+	var out OutputFormat
+	require.NoError(t, json.Unmarshal(ui.OutputWriter.Bytes(), &out))
+	byName := make(map[string]TableDiff)
+	for _, d := range out.Tables {
+		byName[d.Name] = d
+	}
+
+	require.Equal(t, TableDiff{
+		Name:    "kv",
+		Added:   []string{"d"},
+		Removed: []string{"a"},
+		Changed: []string{"b"},
+	}, byName["kv"])
+	require.Equal(t, []string{"node1/api-1"}, byName["services"].Added)
+	require.Equal(t, []string{"service-defaults/web"}, byName["config-entries"].Changed)
+	require.Equal(t, []string{"web"}, byName["intentions"].Added)
+	require.Empty(t, byName["acl-tokens"].Added)
+	require.Empty(t, byName["acl-tokens"].Removed)
+	require.Empty(t, byName["acl-tokens"].Changed)
+
+	// Comparing a snapshot with itself finds no changes.
+	ui = cli.NewMockUi()
+	c = New(ui)
+	code = c.Run([]string{after, after})
+	require.Equal(t, 0, code, ui.ErrorWriter.String())
+	require.Equal(t, 5, strings.Count(ui.OutputWriter.String(), "no changes"))
+}
@@ -30,10 +30,11 @@ func New(ui cli.Ui) *cmd {
 }
 
 type cmd struct {
-	UI     cli.Ui
-	flags  *flag.FlagSet
-	help   string
-	format string
+	UI         cli.Ui
+	flags      *flag.FlagSet
+	encryption *flags.SnapshotEncryptionFlags
+	help       string
+	format     string
 
 	// flags
 	kvDetails bool
@@ -54,6 +55,8 @@ func (c *cmd) init() {
 		"format",
 		PrettyFormat,
 		fmt.Sprintf("Output format {%s}", strings.Join(GetSupportedFormats(), "|")))
+	c.encryption = &flags.SnapshotEncryptionFlags{}
+	flags.Merge(c.flags, c.encryption.Flags())
 
 	c.help = flags.Usage(help, c.flags)
 }
@@ -108,50 +111,27 @@ func (c *cmd) Run(args []string) int {
 		return 1
 	}
 
-	// Open the file.
-	f, err := os.Open(file)
+	opts, err := c.encryption.Options()
 	if err != nil {
-		c.UI.Error(fmt.Sprintf("Error opening snapshot file: %s", err))
+		c.UI.Error(err.Error())
+		return 1
+	}
+	kp, err := snapshot.ParseKeyProvider(opts.EncryptionKey, opts.EncryptionPassphrase)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error loading encryption key: %s", err))
 		return 1
 	}
-	defer f.Close()
-
-	var readFile *os.File
-	var meta *raft.SnapshotMeta
-
-	if strings.ToLower(path.Base(file)) == "state.bin" {
-		// This is an internal raw raft snapshot not a gzipped archive one
-		// downloaded from the API, we can read it directly
-		readFile = f
 
-		// Assume the meta is colocated and error if not.
-		metaRaw, err := os.ReadFile(path.Join(path.Dir(file), "meta.json"))
-		if err != nil {
-			c.UI.Error(fmt.Sprintf("Error reading meta.json from internal snapshot dir: %s", err))
-			return 1
-		}
-		var metaDecoded raft.SnapshotMeta
-		err = json.Unmarshal(metaRaw, &metaDecoded)
-		if err != nil {
-			c.UI.Error(fmt.Sprintf("Error parsing meta.json from internal snapshot dir: %s", err))
-			return 1
-		}
-		meta = &metaDecoded
-	} else {
-		readFile, meta, err = snapshot.Read(hclog.New(nil), f)
-		if err != nil {
-			c.UI.Error(fmt.Sprintf("Error reading snapshot: %s", err))
-			return 1
-		}
-		defer func() {
-			if err := readFile.Close(); err != nil {
-				c.UI.Error(fmt.Sprintf("Failed to close temp snapshot: %v", err))
-			}
-			if err := os.Remove(readFile.Name()); err != nil {
-				c.UI.Error(fmt.Sprintf("Failed to clean up temp snapshot: %v", err))
-			}
-		}()
+	readFile, meta, err := Open(file, kp)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
 	}
+	defer func() {
+		if err := readFile.Close(); err != nil {
+			c.UI.Error(fmt.Sprintf("Failed to clean up temp snapshot: %v", err))
+		}
+	}()
 
 	info, err := c.enhance(readFile)
 	if err != nil {
@@ -195,6 +175,55 @@ func (c *cmd) Run(args []string) int {
 	return 0
 }
 
+// Open opens a snapshot file for decoding with fsm.ReadSnapshot. The file is
+// either an archive saved from the API, which is decrypted with kp if it's
+// encrypted, or an internal raw Raft snapshot named "state.bin" with its
+// "meta.json" next to it. The returned reader must be closed to clean up.
+func Open(file string, kp snapshot.KeyProvider) (io.ReadCloser, *raft.SnapshotMeta, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error opening snapshot file: %s", err)
+	}
+
+	if strings.ToLower(path.Base(file)) == "state.bin" {
+		// This is an internal raw raft snapshot not a gzipped archive one
+		// downloaded from the API, we can read it directly
+
+		// Assume the meta is colocated and error if not.
+		metaRaw, err := os.ReadFile(path.Join(path.Dir(file), "meta.json"))
+		if err != nil {
+			f.Close()
+			return nil, nil, fmt.Errorf("Error reading meta.json from internal snapshot dir: %s", err)
+		}
+		var meta raft.SnapshotMeta
+		if err := json.Unmarshal(metaRaw, &meta); err != nil {
+			f.Close()
+			return nil, nil, fmt.Errorf("Error parsing meta.json from internal snapshot dir: %s", err)
+		}
+		return f, &meta, nil
+	}
+
+	defer f.Close()
+	readFile, meta, err := snapshot.Read(hclog.New(nil), f, kp)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error reading snapshot: %s", err)
+	}
+	return &tempSnapshot{readFile}, meta, nil
+}
+
+// tempSnapshot removes the temporary file snapshot.Read extracts the state
+// to when it's closed.
+type tempSnapshot struct {
+	*os.File
+}
+
+func (t *tempSnapshot) Close() error {
+	if err := t.File.Close(); err != nil {
+		return err
+	}
+	return os.Remove(t.Name())
+}
+
 type typeStats struct {
 	Name  string
 	Sum   int
@@ -367,6 +396,10 @@ Usage: consul snapshot inspect [options] FILE
   To inspect the file "backup.snap":
 
     $ consul snapshot inspect backup.snap
-  
+
+  To inspect a snapshot encrypted with the key in "snapshot.key":
+
+    $ consul snapshot inspect -key-file=snapshot.key backup.snap
+
   For a full list of options and examples, please see the Consul documentation.
 `
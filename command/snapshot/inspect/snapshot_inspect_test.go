@@ -171,6 +171,31 @@ func TestSnapshotInspectCommandRaw(t *testing.T) {
 	require.Equal(t, want, ui.OutputWriter.String())
 }
 
+// TestSnapshotInspectEncryptedCommand tests reading an encrypted snapshot,
+// which holds the same archive as backup.snap.
+func TestSnapshotInspectEncryptedCommand(t *testing.T) {
+	filepath := "./testdata/backup-encrypted.snap"
+
+	// Inspecting without the key fails.
+	ui := cli.NewMockUi()
+	c := New(ui)
+	code := c.Run([]string{filepath})
+	require.Equal(t, 1, code)
+	require.Contains(t, ui.ErrorWriter.String(), "snapshot is encrypted")
+
+	ui = cli.NewMockUi()
+	c = New(ui)
+	args := []string{"-key-file", "./testdata/backup-encrypted.key", filepath}
+
+	code = c.Run(args)
+	if code != 0 {
+		t.Fatalf("bad: %d. %#v", code, ui.ErrorWriter.String())
+	}
+
+	want := golden(t, t.Name(), ui.OutputWriter.String())
+	require.Equal(t, want, ui.OutputWriter.String())
+}
+
 func TestSnapshotInspectInvalidFile(t *testing.T) {
 	// Attempt to open a non-snapshot file.
 	filepath := "./testdata/TestSnapshotInspectCommand.golden"
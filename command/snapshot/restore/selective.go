@@ -0,0 +1,397 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package restore
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/consul-net-rpc/go-msgpack/codec"
+
+	"github.com/hashicorp/consul/agent/consul/fsm"
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/api"
+)
+
+const (
+	opCreate = "create"
+	opUpdate = "update"
+)
+
+// selection is the data to restore in a selective restore.
+type selection struct {
+	kvPrefixes       []string
+	configEntryKinds []string
+	aclPolicies      bool
+	intentions       bool
+}
+
+// empty returns true if nothing is selected, in which case the whole
+// snapshot is restored.
+func (s *selection) empty() bool {
+	return len(s.kvPrefixes) == 0 && len(s.configEntryKinds) == 0 && !s.aclPolicies && !s.intentions
+}
+
+func (s *selection) kv(key string) bool {
+	for _, prefix := range s.kvPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *selection) configEntry(kind string) bool {
+	if s.intentions && kind == structs.ServiceIntentions {
+		return true
+	}
+	for _, k := range s.configEntryKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// selectedData is the data decoded from a snapshot for a selection.
+type selectedData struct {
+	kv            []*structs.DirEntry
+	configEntries []structs.ConfigEntry
+	policies      []*structs.ACLPolicy
+}
+
+// readSelection decodes the selected data from the raw state of a snapshot,
+// as read with snapshot.Read.
+func readSelection(in io.Reader, sel *selection) (*selectedData, error) {
+	var data selectedData
+	handler := func(header *fsm.SnapshotHeader, msg structs.MessageType, dec *codec.Decoder) error {
+		switch {
+		case msg == structs.KVSRequestType && len(sel.kvPrefixes) > 0:
+			var entry structs.DirEntry
+			if err := dec.Decode(&entry); err != nil {
+				return err
+			}
+			if sel.kv(entry.Key) {
+				data.kv = append(data.kv, &entry)
+			}
+
+		case msg == structs.ConfigEntryRequestType && (len(sel.configEntryKinds) > 0 || sel.intentions):
+			var req structs.ConfigEntryRequest
+			if err := dec.Decode(&req); err != nil {
+				return err
+			}
+			if req.Entry != nil && sel.configEntry(req.Entry.GetKind()) {
+				data.configEntries = append(data.configEntries, req.Entry)
+			}
+
+		case msg == structs.ACLPolicySetRequestType && sel.aclPolicies:
+			var policy structs.ACLPolicy
+			if err := dec.Decode(&policy); err != nil {
+				return err
+			}
+			data.policies = append(data.policies, &policy)
+
+		default:
+			var val interface{}
+			if err := dec.Decode(&val); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := fsm.ReadSnapshot(in, handler); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+// change is a write that brings a piece of the current state back to its
+// state in the snapshot.
+type change struct {
+	Op   string
+	Type string
+	Name string
+
+	apply func() error
+}
+
+// plan compares the selected data with the current state and returns the
+// changes needed to restore it, along with the number of items that are
+// already up to date. Data that only exists in the current state is left
+// alone.
+func plan(client *api.Client, sel *selection, data *selectedData) ([]*change, int, error) {
+	var changes []*change
+	var unchanged int
+
+	kvChanges, n, err := planKV(client, sel.kvPrefixes, data.kv)
+	if err != nil {
+		return nil, 0, err
+	}
+	changes = append(changes, kvChanges...)
+	unchanged += n
+
+	entryChanges, n, err := planConfigEntries(client, data.configEntries)
+	if err != nil {
+		return nil, 0, err
+	}
+	changes = append(changes, entryChanges...)
+	unchanged += n
+
+	policyChanges, n, err := planACLPolicies(client, data.policies)
+	if err != nil {
+		return nil, 0, err
+	}
+	changes = append(changes, policyChanges...)
+	unchanged += n
+
+	return changes, unchanged, nil
+}
+
+func planKV(client *api.Client, prefixes []string, entries []*structs.DirEntry) ([]*change, int, error) {
+	if len(entries) == 0 {
+		return nil, 0, nil
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key < entries[j].Key
+	})
+
+	current := make(map[string]*api.KVPair)
+	for _, prefix := range prefixes {
+		pairs, _, err := client.KV().List(prefix, nil)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to list keys under %q: %v", prefix, err)
+		}
+		for _, pair := range pairs {
+			current[pair.Key] = pair
+		}
+	}
+
+	var changes []*change
+	var unchanged int
+	for _, entry := range entries {
+		op := opCreate
+		if existing, ok := current[entry.Key]; ok {
+			if existing.Flags == entry.Flags && bytes.Equal(existing.Value, entry.Value) {
+				unchanged++
+				continue
+			}
+			op = opUpdate
+		}
+
+		pair := &api.KVPair{
+			Key:   entry.Key,
+			Flags: entry.Flags,
+			Value: entry.Value,
+		}
+		changes = append(changes, &change{
+			Op:   op,
+			Type: "kv",
+			Name: entry.Key,
+			apply: func() error {
+				_, err := client.KV().Put(pair, nil)
+				return err
+			},
+		})
+	}
+	return changes, unchanged, nil
+}
+
+func planConfigEntries(client *api.Client, entries []structs.ConfigEntry) ([]*change, int, error) {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].GetKind() == entries[j].GetKind() {
+			return entries[i].GetName() < entries[j].GetName()
+		}
+		return entries[i].GetKind() < entries[j].GetKind()
+	})
+
+	// The current entries are listed once per kind, namespace and partition.
+	current := make(map[string]map[string]api.ConfigEntry)
+	var changes []*change
+	var unchanged int
+	for _, entry := range entries {
+		entMeta := entry.GetEnterpriseMeta()
+		opts := &api.QueryOptions{
+			Namespace: entMeta.NamespaceOrEmpty(),
+			Partition: entMeta.PartitionOrEmpty(),
+		}
+		listKey := fmt.Sprintf("%s/%s/%s", entry.GetKind(), opts.Partition, opts.Namespace)
+		existing, ok := current[listKey]
+		if !ok {
+			list, _, err := client.ConfigEntries().List(entry.GetKind(), opts)
+			if err != nil {
+				return nil, 0, fmt.Errorf("failed to list %s config entries: %v", entry.GetKind(), err)
+			}
+			existing = make(map[string]api.ConfigEntry, len(list))
+			for _, e := range list {
+				existing[e.GetName()] = e
+			}
+			current[listKey] = existing
+		}
+
+		restored, err := configEntryToAPI(entry)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to convert config entry %s/%s: %v", entry.GetKind(), entry.GetName(), err)
+		}
+
+		op := opCreate
+		if e, ok := existing[entry.GetName()]; ok {
+			equal, err := equalIgnoringIndexes(e, restored)
+			if err != nil {
+				return nil, 0, err
+			}
+			if equal {
+				unchanged++
+				continue
+			}
+			op = opUpdate
+		}
+
+		wopts := &api.WriteOptions{
+			Namespace: opts.Namespace,
+			Partition: opts.Partition,
+		}
+		changes = append(changes, &change{
+			Op:   op,
+			Type: "config-entry",
+			Name: entry.GetKind() + "/" + entry.GetName(),
+			apply: func() error {
+				_, _, err := client.ConfigEntries().Set(restored, wopts)
+				return err
+			},
+		})
+	}
+	return changes, unchanged, nil
+}
+
+// configEntryToAPI converts a config entry read from a snapshot to its API
+// representation, which shares its JSON encoding.
+func configEntryToAPI(entry structs.ConfigEntry) (api.ConfigEntry, error) {
+	raw, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+	return api.DecodeConfigEntryFromJSON(raw)
+}
+
+// equalIgnoringIndexes compares the JSON encoding of two values, ignoring
+// the Raft indexes which always differ between a snapshot and the current
+// state.
+func equalIgnoringIndexes(a, b interface{}) (bool, error) {
+	decode := func(v interface{}) (map[string]interface{}, error) {
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		var m map[string]interface{}
+		if err := json.Unmarshal(raw, &m); err != nil {
+			return nil, err
+		}
+		delete(m, "CreateIndex")
+		delete(m, "ModifyIndex")
+		return m, nil
+	}
+	ma, err := decode(a)
+	if err != nil {
+		return false, err
+	}
+	mb, err := decode(b)
+	if err != nil {
+		return false, err
+	}
+	return reflect.DeepEqual(ma, mb), nil
+}
+
+func planACLPolicies(client *api.Client, policies []*structs.ACLPolicy) ([]*change, int, error) {
+	if len(policies) == 0 {
+		return nil, 0, nil
+	}
+	sort.Slice(policies, func(i, j int) bool {
+		return policies[i].Name < policies[j].Name
+	})
+
+	list, _, err := client.ACL().PolicyList(nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list ACL policies: %v", err)
+	}
+	byID := make(map[string]*api.ACLPolicyListEntry, len(list))
+	byName := make(map[string]*api.ACLPolicyListEntry, len(list))
+	for _, p := range list {
+		byID[p.ID] = p
+		byName[p.Name] = p
+	}
+
+	var changes []*change
+	var unchanged int
+	for _, policy := range policies {
+		restored := &api.ACLPolicy{
+			Name:        policy.Name,
+			Description: policy.Description,
+			Rules:       policy.Rules,
+			Datacenters: policy.Datacenters,
+			Namespace:   policy.EnterpriseMeta.NamespaceOrEmpty(),
+			Partition:   policy.EnterpriseMeta.PartitionOrEmpty(),
+		}
+
+		// Policies are matched by ID first, so renamed policies are renamed
+		// back, and then by name. A deleted policy is created with a new ID,
+		// so tokens and roles that linked to it have to be linked again.
+		existing, ok := byID[policy.ID]
+		if !ok {
+			existing, ok = byName[policy.Name]
+		}
+		if !ok {
+			changes = append(changes, &change{
+				Op:   opCreate,
+				Type: "acl-policy",
+				Name: policy.Name,
+				apply: func() error {
+					_, _, err := client.ACL().PolicyCreate(restored, nil)
+					return err
+				},
+			})
+			continue
+		}
+
+		current, _, err := client.ACL().PolicyRead(existing.ID, nil)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to read ACL policy %q: %v", existing.Name, err)
+		}
+		if current.Name == restored.Name &&
+			current.Description == restored.Description &&
+			current.Rules == restored.Rules &&
+			equalStrings(current.Datacenters, restored.Datacenters) {
+			unchanged++
+			continue
+		}
+
+		restored.ID = existing.ID
+		changes = append(changes, &change{
+			Op:   opUpdate,
+			Type: "acl-policy",
+			Name: policy.Name,
+			apply: func() error {
+				_, _, err := client.ACL().PolicyUpdate(restored, nil)
+				return err
+			},
+		})
+	}
+	return changes, unchanged, nil
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -8,8 +8,13 @@ import (
 	"fmt"
 	"os"
 
-	"github.com/hashicorp/consul/command/flags"
+	"github.com/hashicorp/go-hclog"
 	"github.com/mitchellh/cli"
+	"github.com/ryanuber/columnize"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/command/flags"
+	"github.com/hashicorp/consul/snapshot"
 )
 
 func New(ui cli.Ui) *cmd {
@@ -19,17 +24,43 @@ func New(ui cli.Ui) *cmd {
 }
 
 type cmd struct {
-	UI    cli.Ui
-	flags *flag.FlagSet
-	http  *flags.HTTPFlags
-	help  string
+	UI         cli.Ui
+	flags      *flag.FlagSet
+	http       *flags.HTTPFlags
+	encryption *flags.SnapshotEncryptionFlags
+	help       string
+
+	// flags
+	kvPrefixes       []string
+	configEntryKinds []string
+	aclPolicies      bool
+	intentions       bool
+	dryRun           bool
 }
 
 func (c *cmd) init() {
 	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
+	c.flags.Var((*flags.AppendSliceValue)(&c.kvPrefixes), "kv-prefix",
+		"Restore only the keys under this prefix, through normal KV writes "+
+			"instead of replacing the whole state. May be specified multiple times.")
+	c.flags.Var((*flags.AppendSliceValue)(&c.configEntryKinds), "config-entry-kind",
+		"Restore only the config entries of this kind, through normal config "+
+			"entry writes instead of replacing the whole state. May be specified "+
+			"multiple times.")
+	c.flags.BoolVar(&c.aclPolicies, "acl-policies", false,
+		"Restore only the ACL policies, through normal ACL writes instead of "+
+			"replacing the whole state.")
+	c.flags.BoolVar(&c.intentions, "intentions", false,
+		"Restore only the intentions, through normal config entry writes "+
+			"instead of replacing the whole state.")
+	c.flags.BoolVar(&c.dryRun, "dry-run", false,
+		"Can only be used with a selective restore. Outputs the changes the "+
+			"restore would make, without making them.")
 	c.http = &flags.HTTPFlags{}
+	c.encryption = &flags.SnapshotEncryptionFlags{}
 	flags.Merge(c.flags, c.http.ClientFlags())
 	flags.Merge(c.flags, c.http.ServerFlags())
+	flags.Merge(c.flags, c.encryption.Flags())
 	c.help = flags.Usage(help, c.flags)
 }
 
@@ -52,6 +83,23 @@ func (c *cmd) Run(args []string) int {
 		return 1
 	}
 
+	opts, err := c.encryption.Options()
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	sel := &selection{
+		kvPrefixes:       c.kvPrefixes,
+		configEntryKinds: c.configEntryKinds,
+		aclPolicies:      c.aclPolicies,
+		intentions:       c.intentions,
+	}
+	if c.dryRun && sel.empty() {
+		c.UI.Error("The -dry-run flag can only be used with -kv-prefix, -config-entry-kind, -acl-policies or -intentions")
+		return 1
+	}
+
 	// Create and test the HTTP client
 	client, err := c.http.APIClient()
 	if err != nil {
@@ -67,8 +115,12 @@ func (c *cmd) Run(args []string) int {
 	}
 	defer f.Close()
 
+	if !sel.empty() {
+		return c.restoreSelection(client, f, sel, opts)
+	}
+
 	// Restore the snapshot.
-	err = client.Snapshot().Restore(nil, f)
+	err = client.Snapshot().RestoreOpts(opts, nil, f)
 	if err != nil {
 		c.UI.Error(fmt.Sprintf("Error restoring snapshot: %s", err))
 		return 1
@@ -78,6 +130,64 @@ func (c *cmd) Run(args []string) int {
 	return 0
 }
 
+// restoreSelection reads the snapshot locally and writes the selected data
+// back through the regular endpoints, leaving the rest of the state alone.
+func (c *cmd) restoreSelection(client *api.Client, f *os.File, sel *selection, opts api.SnapshotOptions) int {
+	kp, err := snapshot.ParseKeyProvider(opts.EncryptionKey, opts.EncryptionPassphrase)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error loading encryption key: %s", err))
+		return 1
+	}
+
+	state, _, err := snapshot.Read(hclog.New(nil), f, kp)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error reading snapshot: %s", err))
+		return 1
+	}
+	defer func() {
+		state.Close()
+		os.Remove(state.Name())
+	}()
+
+	data, err := readSelection(state, sel)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error decoding snapshot: %s", err))
+		return 1
+	}
+
+	changes, unchanged, err := plan(client, sel, data)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error comparing snapshot with the current state: %s", err))
+		return 1
+	}
+
+	if len(changes) > 0 {
+		c.UI.Output(formatChanges(changes))
+	}
+	if c.dryRun {
+		c.UI.Info(fmt.Sprintf("Dry run: %d changes would be made, %d items are unchanged", len(changes), unchanged))
+		return 0
+	}
+
+	for i, change := range changes {
+		if err := change.apply(); err != nil {
+			c.UI.Error(fmt.Sprintf("Error restoring %s %q (%d of %d changes made): %s",
+				change.Type, change.Name, i, len(changes), err))
+			return 1
+		}
+	}
+	c.UI.Info(fmt.Sprintf("Restored %d changes from snapshot, %d items are unchanged", len(changes), unchanged))
+	return 0
+}
+
+func formatChanges(changes []*change) string {
+	result := []string{"Operation\x1fType\x1fName"}
+	for _, change := range changes {
+		result = append(result, fmt.Sprintf("%s\x1f%s\x1f%s", change.Op, change.Type, change.Name))
+	}
+	return columnize.Format(result, &columnize.Config{Delim: string([]byte{0x1f})})
+}
+
 func (c *cmd) Synopsis() string {
 	return synopsis
 }
@@ -106,5 +216,19 @@ Usage: consul snapshot restore [options] FILE
 
     $ consul snapshot restore backup.snap
 
+  Encrypted snapshots are decrypted by the servers with the key or passphrase
+  they were saved with:
+
+    $ consul snapshot restore -key-file=snapshot.key backup.snap
+
+  Parts of the state can be restored selectively with -kv-prefix,
+  -config-entry-kind, -acl-policies and -intentions. The snapshot is read
+  locally and only the selected data is written back through the regular
+  endpoints, without replacing the rest of the state. Data missing from the
+  snapshot is left alone. To see what would change when restoring the keys
+  under "app/config/":
+
+    $ consul snapshot restore -kv-prefix=app/config/ -dry-run backup.snap
+
   For a full list of options and examples, please see the Consul documentation.
 `
@@ -15,6 +15,7 @@ import (
 	"github.com/hashicorp/consul/agent"
 	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/consul/sdk/testutil"
+	"github.com/hashicorp/consul/testrpc"
 	"github.com/mitchellh/cli"
 	"github.com/stretchr/testify/require"
 )
@@ -43,6 +44,10 @@ func TestSnapshotRestoreCommand_Validation(t *testing.T) {
 			[]string{"foo", "bar", "baz"},
 			"Too many arguments",
 		},
+		"dry run of full restore": {
+			[]string{"-dry-run", "foo"},
+			"The -dry-run flag can only be used with",
+		},
 	}
 
 	for name, tc := range cases {
@@ -110,6 +115,173 @@ func TestSnapshotRestoreCommand(t *testing.T) {
 	}
 }
 
+func TestSnapshotRestoreCommand_Encrypted(t *testing.T) {
+	if testing.Short() {
+		t.Skip("too slow for testing.Short")
+	}
+
+	t.Parallel()
+	a := agent.NewTestAgent(t, ``)
+	defer a.Shutdown()
+	client := a.Client()
+
+	dir := testutil.TempDir(t, "snapshot")
+	file := filepath.Join(dir, "backup.snap")
+	passphraseFile := filepath.Join(dir, "passphrase")
+	require.NoError(t, os.WriteFile(passphraseFile, []byte("hunter2\n"), 0600))
+
+	opts := api.SnapshotOptions{EncryptionPassphrase: "hunter2"}
+	snap, _, err := client.Snapshot().SaveOpts(opts, nil)
+	require.NoError(t, err)
+	defer snap.Close()
+	data, err := io.ReadAll(snap)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(file, data, 0600))
+
+	// Restoring without the passphrase fails.
+	ui := cli.NewMockUi()
+	c := New(ui)
+	code := c.Run([]string{"-http-addr=" + a.HTTPAddr(), file})
+	require.Equal(t, 1, code)
+	require.Contains(t, ui.ErrorWriter.String(), "snapshot is encrypted")
+
+	ui = cli.NewMockUi()
+	c = New(ui)
+	args := []string{
+		"-http-addr=" + a.HTTPAddr(),
+		"-passphrase-file=" + passphraseFile,
+		file,
+	}
+	code = c.Run(args)
+	require.Equal(t, 0, code, ui.ErrorWriter.String())
+	require.Contains(t, ui.OutputWriter.String(), "Restored snapshot")
+}
+
+func TestSnapshotRestoreCommand_Selective(t *testing.T) {
+	if testing.Short() {
+		t.Skip("too slow for testing.Short")
+	}
+
+	t.Parallel()
+	a := agent.NewTestAgent(t, `
+	primary_datacenter = "dc1"
+	acl {
+		enabled = true
+		tokens {
+			initial_management = "root"
+		}
+	}`)
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+	client := a.Client()
+	wopts := &api.WriteOptions{Token: "root"}
+
+	// Seed the state and take a snapshot of it.
+	for _, key := range []string{"app/a", "app/b", "other/c"} {
+		_, err := client.KV().Put(&api.KVPair{Key: key, Value: []byte("old")}, wopts)
+		require.NoError(t, err)
+	}
+	_, _, err := client.ConfigEntries().Set(&api.ServiceConfigEntry{
+		Kind:     api.ServiceDefaults,
+		Name:     "web",
+		Protocol: "http",
+	}, wopts)
+	require.NoError(t, err)
+	_, _, err = client.ConfigEntries().Set(&api.ServiceIntentionsConfigEntry{
+		Kind: api.ServiceIntentions,
+		Name: "web",
+		Sources: []*api.SourceIntention{
+			{Name: "api", Action: api.IntentionActionAllow},
+		},
+	}, wopts)
+	require.NoError(t, err)
+	policy, _, err := client.ACL().PolicyCreate(&api.ACLPolicy{
+		Name:  "web",
+		Rules: `service "web" { policy = "write" }`,
+	}, wopts)
+	require.NoError(t, err)
+
+	snap, _, err := client.Snapshot().Save(&api.QueryOptions{Token: "root"})
+	require.NoError(t, err)
+	data, err := io.ReadAll(snap)
+	snap.Close()
+	require.NoError(t, err)
+	file := filepath.Join(testutil.TempDir(t, "snapshot"), "backup.snap")
+	require.NoError(t, os.WriteFile(file, data, 0600))
+
+	// Change the state.
+	_, err = client.KV().Delete("app/a", wopts)
+	require.NoError(t, err)
+	for _, key := range []string{"app/b", "other/c"} {
+		_, err := client.KV().Put(&api.KVPair{Key: key, Value: []byte("new")}, wopts)
+		require.NoError(t, err)
+	}
+	_, err = client.ConfigEntries().Delete(api.ServiceDefaults, "web", wopts)
+	require.NoError(t, err)
+	_, err = client.ConfigEntries().Delete(api.ServiceIntentions, "web", wopts)
+	require.NoError(t, err)
+	policy.Rules = `service "web" { policy = "read" }`
+	_, _, err = client.ACL().PolicyUpdate(policy, wopts)
+	require.NoError(t, err)
+
+	args := []string{
+		"-http-addr=" + a.HTTPAddr(),
+		"-token=root",
+		"-kv-prefix=app/",
+		"-config-entry-kind=service-defaults",
+		"-intentions",
+		"-acl-policies",
+	}
+
+	// A dry run outputs the changes without making them.
+	ui := cli.NewMockUi()
+	c := New(ui)
+	code := c.Run(append(args, "-dry-run", file))
+	require.Equal(t, 0, code, ui.ErrorWriter.String())
+	output := ui.OutputWriter.String()
+	require.Regexp(t, `create\s+kv\s+app/a`, output)
+	require.Regexp(t, `update\s+kv\s+app/b`, output)
+	require.Regexp(t, `create\s+config-entry\s+service-defaults/web`, output)
+	require.Regexp(t, `create\s+config-entry\s+service-intentions/web`, output)
+	require.Regexp(t, `update\s+acl-policy\s+web`, output)
+	require.NotContains(t, output, "other/c")
+	require.Contains(t, output, "Dry run: 5 changes would be made")
+
+	pair, _, err := client.KV().Get("app/a", &api.QueryOptions{Token: "root"})
+	require.NoError(t, err)
+	require.Nil(t, pair)
+
+	// Restore the selected data.
+	ui = cli.NewMockUi()
+	c = New(ui)
+	code = c.Run(append(args, file))
+	require.Equal(t, 0, code, ui.ErrorWriter.String())
+	require.Contains(t, ui.OutputWriter.String(), "Restored 5 changes from snapshot")
+
+	qopts := &api.QueryOptions{Token: "root"}
+	for key, value := range map[string]string{"app/a": "old", "app/b": "old", "other/c": "new"} {
+		pair, _, err := client.KV().Get(key, qopts)
+		require.NoError(t, err)
+		require.NotNil(t, pair, key)
+		require.Equal(t, value, string(pair.Value), key)
+	}
+	entry, _, err := client.ConfigEntries().Get(api.ServiceDefaults, "web", qopts)
+	require.NoError(t, err)
+	require.Equal(t, "http", entry.(*api.ServiceConfigEntry).Protocol)
+	_, _, err = client.ConfigEntries().Get(api.ServiceIntentions, "web", qopts)
+	require.NoError(t, err)
+	restored, _, err := client.ACL().PolicyRead(policy.ID, qopts)
+	require.NoError(t, err)
+	require.Equal(t, `service "web" { policy = "write" }`, restored.Rules)
+
+	// Restoring again doesn't change anything.
+	ui = cli.NewMockUi()
+	c = New(ui)
+	code = c.Run(append(args, "-dry-run", file))
+	require.Equal(t, 0, code, ui.ErrorWriter.String())
+	require.Contains(t, ui.OutputWriter.String(), "Dry run: 0 changes would be made")
+}
+
 func TestSnapshotRestoreCommand_TruncatedSnapshot(t *testing.T) {
 	if testing.Short() {
 		t.Skip("too slow for testing.Short")
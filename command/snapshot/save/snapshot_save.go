@@ -23,17 +23,25 @@ func New(ui cli.Ui) *cmd {
 }
 
 type cmd struct {
-	UI    cli.Ui
-	flags *flag.FlagSet
-	http  *flags.HTTPFlags
-	help  string
+	UI         cli.Ui
+	flags      *flag.FlagSet
+	http       *flags.HTTPFlags
+	encryption *flags.SnapshotEncryptionFlags
+	help       string
+
+	// flags
+	compression string
 }
 
 func (c *cmd) init() {
 	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
+	c.flags.StringVar(&c.compression, "compression", string(snapshot.CompressionGzip),
+		"Compression of the snapshot archive, either \"gzip\" or \"zstd\".")
 	c.http = &flags.HTTPFlags{}
+	c.encryption = &flags.SnapshotEncryptionFlags{}
 	flags.Merge(c.flags, c.http.ClientFlags())
 	flags.Merge(c.flags, c.http.ServerFlags())
+	flags.Merge(c.flags, c.encryption.Flags())
 	c.help = flags.Usage(help, c.flags)
 }
 
@@ -56,6 +64,26 @@ func (c *cmd) Run(args []string) int {
 		return 1
 	}
 
+	compression, err := snapshot.ParseCompression(c.compression)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Invalid -compression: %s", err))
+		return 1
+	}
+	opts, err := c.encryption.Options()
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+	opts.Compression = string(compression)
+
+	// Build the key provider up front, we need it to verify the snapshot
+	// and this catches a bad key before taking the snapshot.
+	kp, err := snapshot.ParseKeyProvider(opts.EncryptionKey, opts.EncryptionPassphrase)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error loading encryption key: %s", err))
+		return 1
+	}
+
 	// Create and test the HTTP client
 	client, err := c.http.APIClient()
 	if err != nil {
@@ -64,7 +92,7 @@ func (c *cmd) Run(args []string) int {
 	}
 
 	// Take the snapshot.
-	snap, qm, err := client.Snapshot().Save(&api.QueryOptions{
+	snap, qm, err := client.Snapshot().SaveOpts(opts, &api.QueryOptions{
 		AllowStale: c.http.Stale(),
 	})
 	if err != nil {
@@ -87,7 +115,7 @@ func (c *cmd) Run(args []string) int {
 		c.UI.Error(fmt.Sprintf("Error opening snapshot file for verify: %s", err))
 		return 1
 	}
-	if _, err := snapshot.Verify(f); err != nil {
+	if _, err := snapshot.Verify(f, kp); err != nil {
 		f.Close()
 		c.UI.Error(fmt.Sprintf("Error verifying snapshot file: %s", err))
 		return 1
@@ -134,5 +162,10 @@ Usage: consul snapshot save [options] FILE
 
     $ consul snapshot save -stale backup.snap
 
+  To create a snapshot compressed with zstd and encrypted with the key in
+  "snapshot.key", generated with "consul keygen":
+
+    $ consul snapshot save -compression=zstd -key-file=snapshot.key backup.snap
+
   For a full list of options and examples, please see the Consul documentation.
 `
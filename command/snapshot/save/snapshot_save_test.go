@@ -4,7 +4,9 @@
 package save
 
 import (
+	"bytes"
 	"crypto/rand"
+	"encoding/base64"
 	"fmt"
 	"io"
 	"net/http"
@@ -46,6 +48,14 @@ func TestSnapshotSaveCommand_Validation(t *testing.T) {
 			[]string{"foo", "bar", "baz"},
 			"Too many arguments",
 		},
+		"bad compression": {
+			[]string{"-compression=lz4", "foo"},
+			"Invalid -compression",
+		},
+		"key and passphrase": {
+			[]string{"-key-file=key", "-passphrase-file=passphrase", "foo"},
+			"Only one of -key-file or -passphrase-file may be given",
+		},
 	}
 
 	for name, tc := range cases {
@@ -112,6 +122,50 @@ func TestSnapshotSaveCommand(t *testing.T) {
 	}
 }
 
+func TestSnapshotSaveCommand_Encrypted(t *testing.T) {
+	if testing.Short() {
+		t.Skip("too slow for testing.Short")
+	}
+
+	t.Parallel()
+	a := agent.NewTestAgent(t, ``)
+	defer a.Shutdown()
+	client := a.Client()
+
+	dir := testutil.TempDir(t, "snapshot")
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+	encodedKey := base64.StdEncoding.EncodeToString(key)
+	keyFile := filepath.Join(dir, "snapshot.key")
+	require.NoError(t, os.WriteFile(keyFile, []byte(encodedKey+"\n"), 0600))
+
+	ui := cli.NewMockUi()
+	c := New(ui)
+
+	file := filepath.Join(dir, "backup.snap")
+	args := []string{
+		"-http-addr=" + a.HTTPAddr(),
+		"-compression=zstd",
+		"-key-file=" + keyFile,
+		file,
+	}
+
+	code := c.Run(args)
+	require.Equal(t, 0, code, ui.ErrorWriter.String())
+
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+
+	// The snapshot can only be restored with the key.
+	err = client.Snapshot().Restore(nil, bytes.NewReader(data))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "snapshot is encrypted")
+
+	opts := api.SnapshotOptions{EncryptionKey: encodedKey}
+	require.NoError(t, client.Snapshot().RestoreOpts(opts, nil, bytes.NewReader(data)))
+}
+
 func TestSnapshotSaveCommand_TruncatedStream(t *testing.T) {
 	if testing.Short() {
 		t.Skip("too slow for testing.Short")
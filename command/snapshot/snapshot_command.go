@@ -50,8 +50,11 @@ Usage: consul snapshot <subcommand> [options] [args]
 
       $ consul snapshot inspect backup.snap
 
-  Run a daemon process that locally saves a snapshot every hour (available only in
-  Consul Enterprise) :
+  Compare two snapshots:
+
+      $ consul snapshot diff before.snap after.snap
+
+  Run a daemon process that locally saves a snapshot every hour:
 
       $ consul snapshot agent
 
@@ -44,6 +44,7 @@ type cmd struct {
 	passingOnly string
 	state       string
 	name        string
+	kind        string
 	shell       bool
 }
 
@@ -51,7 +52,8 @@ func (c *cmd) init() {
 	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
 	c.flags.StringVar(&c.watchType, "type", "",
 		"Specifies the watch type. One of key, keyprefix, services, nodes, "+
-			"service, checks, or event.")
+			"service, checks, event, config_entry, config_entries, intentions, "+
+			"peerings, or exported_services.")
 	c.flags.StringVar(&c.key, "key", "",
 		"Specifies the key to watch. Only for 'key' type.")
 	c.flags.StringVar(&c.prefix, "prefix", "",
@@ -70,7 +72,11 @@ func (c *cmd) init() {
 	c.flags.StringVar(&c.state, "state", "",
 		"Specifies the states to watch. Optional for 'checks' type.")
 	c.flags.StringVar(&c.name, "name", "",
-		"Specifies an event name to watch. Only for 'event' type.")
+		"Specifies an event name to watch for 'event' type, or the config entry "+
+			"name to watch for 'config_entry' type.")
+	c.flags.StringVar(&c.kind, "kind", "",
+		"Specifies the config entry kind to watch. Required for 'config_entry' "+
+			"and 'config_entries' types.")
 
 	c.http = &flags.HTTPFlags{}
 	flags.Merge(c.flags, c.http.ClientFlags())
@@ -140,6 +146,12 @@ func (c *cmd) Run(args []string) int {
 	if c.name != "" {
 		params["name"] = c.name
 	}
+	if c.kind != "" {
+		params["kind"] = c.kind
+	}
+	if c.watchType == "exported_services" && c.http.Partition() != "" {
+		params["partition"] = c.http.Partition()
+	}
 	if c.passingOnly != "" {
 		b, err := strconv.ParseBool(c.passingOnly)
 		if err != nil {
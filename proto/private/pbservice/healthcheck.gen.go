@@ -32,6 +32,7 @@ func CheckTypeToStructs(s *CheckType, t *structs.CheckType) {
 	t.OSService = s.OSService
 	t.TLSServerName = s.TLSServerName
 	t.TLSSkipVerify = s.TLSSkipVerify
+	t.UseConnectTLS = s.UseConnectTLS
 	t.Timeout = structs.DurationFromProto(s.Timeout)
 	t.TTL = structs.DurationFromProto(s.TTL)
 	t.SuccessBeforePassing = int(s.SuccessBeforePassing)
@@ -70,6 +71,7 @@ func CheckTypeFromStructs(t *structs.CheckType, s *CheckType) {
 	s.OSService = t.OSService
 	s.TLSServerName = t.TLSServerName
 	s.TLSSkipVerify = t.TLSSkipVerify
+	s.UseConnectTLS = t.UseConnectTLS
 	s.Timeout = structs.DurationToProto(t.Timeout)
 	s.TTL = structs.DurationToProto(t.TTL)
 	s.SuccessBeforePassing = int32(t.SuccessBeforePassing)
@@ -137,6 +139,7 @@ func HealthCheckDefinitionToStructs(s *HealthCheckDefinition, t *structs.HealthC
 	t.HTTP = s.HTTP
 	t.TLSServerName = s.TLSServerName
 	t.TLSSkipVerify = s.TLSSkipVerify
+	t.UseConnectTLS = s.UseConnectTLS
 	t.Header = MapHeadersToStructs(s.Header)
 	t.Method = s.Method
 	t.Body = s.Body
@@ -166,6 +169,7 @@ func HealthCheckDefinitionFromStructs(t *structs.HealthCheckDefinition, s *Healt
 	s.HTTP = t.HTTP
 	s.TLSServerName = t.TLSServerName
 	s.TLSSkipVerify = t.TLSSkipVerify
+	s.UseConnectTLS = t.UseConnectTLS
 	s.Header = NewMapHeadersFromStructs(t.Header)
 	s.Method = t.Method
 	s.Body = t.Body
@@ -0,0 +1,40 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package pbsubscribe
+
+import (
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/proto/private/pbcommon"
+)
+
+// NewKVEntryFromStructs converts a structs.DirEntry to a KVEntry.
+func NewKVEntryFromStructs(entry *structs.DirEntry) *KVEntry {
+	raftIndex := &pbcommon.RaftIndex{}
+	pbcommon.RaftIndexFromStructs(&entry.RaftIndex, raftIndex)
+	return &KVEntry{
+		Key:            entry.Key,
+		Value:          entry.Value,
+		Flags:          entry.Flags,
+		Session:        entry.Session,
+		LockIndex:      entry.LockIndex,
+		TTL:            entry.TTL,
+		RaftIndex:      raftIndex,
+		EnterpriseMeta: pbcommon.NewEnterpriseMetaFromStructs(entry.EnterpriseMeta),
+	}
+}
+
+// ToStructs converts the KVEntry to a structs.DirEntry.
+func (e *KVEntry) ToStructs() *structs.DirEntry {
+	entry := &structs.DirEntry{
+		Key:       e.Key,
+		Value:     e.Value,
+		Flags:     e.Flags,
+		Session:   e.Session,
+		LockIndex: e.LockIndex,
+		TTL:       e.TTL,
+	}
+	pbcommon.RaftIndexToStructs(e.RaftIndex, &entry.RaftIndex)
+	pbcommon.EnterpriseMetaToStructs(e.EnterpriseMeta, &entry.EnterpriseMeta)
+	return entry
+}
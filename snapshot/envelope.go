@@ -0,0 +1,498 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+// The envelope utilities manage the optional encryption of a snapshot archive.
+// An encrypted archive has the following format:
+//
+// magic         - "CSNAPENC" followed by a version byte
+// header length - 4-byte big endian length of the header
+// header        - JSON-encoded envelopeHeader, including the wrapped data key
+// chunks        - the compressed archive, sealed in chunks with AES-256-GCM
+//
+// Each chunk is a flag byte, marking the final chunk, followed by the 4-byte
+// big endian length of the sealed data and the sealed data itself. The nonce
+// of a chunk is made of a random prefix from the header and the chunk counter,
+// and the header and flag byte are authenticated as additional data, so chunks
+// can't be reordered, dropped or truncated without the archive failing to
+// decrypt.
+package snapshot
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// envelopeVersion is the version of the encrypted archive format.
+	envelopeVersion = 1
+
+	// envelopeCipher is the cipher used to seal the chunks of the archive.
+	envelopeCipher = "aes-256-gcm"
+
+	// envelopeChunkSize is the size of the plaintext sealed in each chunk.
+	envelopeChunkSize = 64 * 1024
+
+	// envelopeMaxHeaderSize bounds the header we are willing to read.
+	envelopeMaxHeaderSize = 64 * 1024
+
+	// dataKeySize is the size of the AES-256 keys used for the data key and
+	// the key encryption keys.
+	dataKeySize = 32
+
+	// The scrypt parameters used to derive a key encryption key from a
+	// passphrase.
+	scryptN = 32768
+	scryptR = 8
+	scryptP = 1
+
+	chunkFlagFinal byte = 1
+)
+
+var envelopeMagic = []byte("CSNAPENC")
+
+// ErrEncrypted is returned when reading an encrypted snapshot without a key
+// provider.
+var ErrEncrypted = errors.New("snapshot is encrypted, a key or passphrase is required to read it")
+
+// KeyProvider wraps and unwraps the data key an encrypted snapshot archive is
+// sealed with.
+type KeyProvider interface {
+	// WrapKey encrypts the data key so that it can be stored in the archive.
+	WrapKey(dataKey []byte) (*WrappedKey, error)
+
+	// UnwrapKey decrypts the data key stored in the archive.
+	UnwrapKey(wrapped *WrappedKey) ([]byte, error)
+}
+
+// WrappedKey is a data key encrypted by a KeyProvider, as stored in the header
+// of an encrypted archive.
+type WrappedKey struct {
+	// Provider is the kind of provider that wrapped the key, either
+	// "passphrase" or "key".
+	Provider string
+
+	// KeyID identifies the key used to wrap the data key, it's only set by
+	// the "key" provider.
+	KeyID string `json:",omitempty"`
+
+	// Salt and the scrypt parameters are used to derive the key encryption
+	// key from a passphrase.
+	Salt []byte `json:",omitempty"`
+	N    int    `json:",omitempty"`
+	R    int    `json:",omitempty"`
+	P    int    `json:",omitempty"`
+
+	Nonce      []byte
+	Ciphertext []byte
+}
+
+// passphraseKeyProvider wraps data keys with a key derived from a passphrase.
+type passphraseKeyProvider struct {
+	passphrase []byte
+}
+
+// NewPassphraseKeyProvider returns a KeyProvider that wraps data keys with a
+// key derived from the given passphrase using scrypt.
+func NewPassphraseKeyProvider(passphrase string) (KeyProvider, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("passphrase must not be empty")
+	}
+	return &passphraseKeyProvider{passphrase: []byte(passphrase)}, nil
+}
+
+// See KeyProvider.
+func (p *passphraseKeyProvider) WrapKey(dataKey []byte) (*WrappedKey, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %v", err)
+	}
+	kek, err := scrypt.Key(p.passphrase, salt, scryptN, scryptR, scryptP, dataKeySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key from passphrase: %v", err)
+	}
+	wrapped, err := wrapKey(kek, dataKey)
+	if err != nil {
+		return nil, err
+	}
+	wrapped.Provider = "passphrase"
+	wrapped.Salt = salt
+	wrapped.N, wrapped.R, wrapped.P = scryptN, scryptR, scryptP
+	return wrapped, nil
+}
+
+// See KeyProvider.
+func (p *passphraseKeyProvider) UnwrapKey(wrapped *WrappedKey) ([]byte, error) {
+	if wrapped.Provider != "passphrase" {
+		return nil, fmt.Errorf("snapshot was encrypted with a %s, not a passphrase", wrapped.Provider)
+	}
+	// Bound the work a crafted header can make us do.
+	if wrapped.N > 1<<20 || wrapped.R*wrapped.P > 64 {
+		return nil, fmt.Errorf("unsupported scrypt parameters N=%d r=%d p=%d", wrapped.N, wrapped.R, wrapped.P)
+	}
+	kek, err := scrypt.Key(p.passphrase, wrapped.Salt, wrapped.N, wrapped.R, wrapped.P, dataKeySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key from passphrase: %v", err)
+	}
+	dataKey, err := unwrapKey(kek, wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt snapshot key, the passphrase may be wrong: %v", err)
+	}
+	return dataKey, nil
+}
+
+// staticKeyProvider wraps data keys with a fixed key.
+type staticKeyProvider struct {
+	key []byte
+	id  string
+}
+
+// NewKeyProvider returns a KeyProvider that wraps data keys with the given
+// 32-byte key.
+func NewKeyProvider(key []byte) (KeyProvider, error) {
+	if len(key) != dataKeySize {
+		return nil, fmt.Errorf("key must be %d bytes, got %d", dataKeySize, len(key))
+	}
+	sum := sha256.Sum256(key)
+	return &staticKeyProvider{key: key, id: hex.EncodeToString(sum[:8])}, nil
+}
+
+// See KeyProvider.
+func (p *staticKeyProvider) WrapKey(dataKey []byte) (*WrappedKey, error) {
+	wrapped, err := wrapKey(p.key, dataKey)
+	if err != nil {
+		return nil, err
+	}
+	wrapped.Provider = "key"
+	wrapped.KeyID = p.id
+	return wrapped, nil
+}
+
+// See KeyProvider.
+func (p *staticKeyProvider) UnwrapKey(wrapped *WrappedKey) ([]byte, error) {
+	if wrapped.Provider != "key" {
+		return nil, fmt.Errorf("snapshot was encrypted with a %s, not a key", wrapped.Provider)
+	}
+	if wrapped.KeyID != p.id {
+		return nil, fmt.Errorf("snapshot was encrypted with key %q, not key %q", wrapped.KeyID, p.id)
+	}
+	dataKey, err := unwrapKey(p.key, wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt snapshot key: %v", err)
+	}
+	return dataKey, nil
+}
+
+// ParseKeyProvider returns the key provider for a base64-encoded 32-byte key
+// or a passphrase, or nil if neither is given.
+func ParseKeyProvider(key, passphrase string) (KeyProvider, error) {
+	switch {
+	case key != "" && passphrase != "":
+		return nil, fmt.Errorf("only one of an encryption key or passphrase may be given")
+	case key != "":
+		decoded, err := base64.StdEncoding.DecodeString(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode encryption key: %v", err)
+		}
+		return NewKeyProvider(decoded)
+	case passphrase != "":
+		return NewPassphraseKeyProvider(passphrase)
+	default:
+		return nil, nil
+	}
+}
+
+// wrapKey seals the data key with the key encryption key.
+func wrapKey(kek, dataKey []byte) (*WrappedKey, error) {
+	aead, err := newAEAD(kek)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %v", err)
+	}
+	return &WrappedKey{
+		Nonce:      nonce,
+		Ciphertext: aead.Seal(nil, nonce, dataKey, nil),
+	}, nil
+}
+
+// unwrapKey opens the data key with the key encryption key.
+func unwrapKey(kek []byte, wrapped *WrappedKey) ([]byte, error) {
+	aead, err := newAEAD(kek)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrapped.Nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce size %d", len(wrapped.Nonce))
+	}
+	return aead.Open(nil, wrapped.Nonce, wrapped.Ciphertext, nil)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %v", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// envelopeHeader is the header of an encrypted archive.
+type envelopeHeader struct {
+	Cipher      string
+	ChunkSize   int
+	NoncePrefix []byte
+	Key         *WrappedKey
+}
+
+// isEncrypted returns whether the buffered archive starts with the magic of
+// an encrypted archive.
+func isEncrypted(in *bufio.Reader) bool {
+	magic, _ := in.Peek(len(envelopeMagic))
+	return bytes.Equal(magic, envelopeMagic)
+}
+
+// encryptWriter seals everything written to it in chunks.
+type encryptWriter struct {
+	out     io.Writer
+	aead    cipher.AEAD
+	header  []byte
+	prefix  []byte
+	counter uint64
+	buf     []byte
+}
+
+// newEncryptWriter writes the header of an encrypted archive to out, using a
+// new data key wrapped by the key provider, and returns a writer that seals
+// the archive. You must call Close() to write the final chunk.
+func newEncryptWriter(out io.Writer, kp KeyProvider) (*encryptWriter, error) {
+	dataKey := make([]byte, dataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %v", err)
+	}
+	wrapped, err := kp.WrapKey(dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap data key: %v", err)
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := make([]byte, aead.NonceSize()-8)
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %v", err)
+	}
+	header, err := json.Marshal(&envelopeHeader{
+		Cipher:      envelopeCipher,
+		ChunkSize:   envelopeChunkSize,
+		NoncePrefix: prefix,
+		Key:         wrapped,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode header: %v", err)
+	}
+
+	var preamble bytes.Buffer
+	preamble.Write(envelopeMagic)
+	preamble.WriteByte(envelopeVersion)
+	binary.Write(&preamble, binary.BigEndian, uint32(len(header)))
+	preamble.Write(header)
+	if _, err := out.Write(preamble.Bytes()); err != nil {
+		return nil, fmt.Errorf("failed to write header: %v", err)
+	}
+
+	return &encryptWriter{
+		out:    out,
+		aead:   aead,
+		header: header,
+		prefix: prefix,
+		buf:    make([]byte, 0, envelopeChunkSize),
+	}, nil
+}
+
+// Write buffers the data and seals every full chunk.
+func (w *encryptWriter) Write(p []byte) (int, error) {
+	var n int
+	for len(p) > 0 {
+		free := envelopeChunkSize - len(w.buf)
+		if free > len(p) {
+			free = len(p)
+		}
+		w.buf = append(w.buf, p[:free]...)
+		p = p[free:]
+		n += free
+
+		// Only seal a full chunk once there's more data, so the final
+		// chunk is never empty unless the archive is.
+		if len(w.buf) == envelopeChunkSize && len(p) > 0 {
+			if err := w.seal(0); err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// Close seals the final chunk.
+func (w *encryptWriter) Close() error {
+	return w.seal(chunkFlagFinal)
+}
+
+func (w *encryptWriter) seal(flag byte) error {
+	nonce := chunkNonce(w.prefix, w.counter)
+	sealed := w.aead.Seal(nil, nonce, w.buf, chunkAdditionalData(w.header, flag))
+	w.counter++
+	w.buf = w.buf[:0]
+
+	var hdr [5]byte
+	hdr[0] = flag
+	binary.BigEndian.PutUint32(hdr[1:], uint32(len(sealed)))
+	if _, err := w.out.Write(hdr[:]); err != nil {
+		return err
+	}
+	_, err := w.out.Write(sealed)
+	return err
+}
+
+// decryptReader opens the chunks of an encrypted archive. It only returns
+// io.EOF once the final chunk has been read and authenticated.
+type decryptReader struct {
+	in      io.Reader
+	aead    cipher.AEAD
+	header  []byte
+	prefix  []byte
+	counter uint64
+	buf     []byte
+	final   bool
+}
+
+// newDecryptReader reads the header of an encrypted archive from in, unwraps
+// the data key with the key provider, and returns a reader for the archive.
+func newDecryptReader(in io.Reader, kp KeyProvider) (*decryptReader, error) {
+	preamble := make([]byte, len(envelopeMagic)+1+4)
+	if _, err := io.ReadFull(in, preamble); err != nil {
+		return nil, fmt.Errorf("failed to read encrypted snapshot header: %v", err)
+	}
+	if !bytes.Equal(preamble[:len(envelopeMagic)], envelopeMagic) {
+		return nil, fmt.Errorf("snapshot is not encrypted")
+	}
+	if v := preamble[len(envelopeMagic)]; v != envelopeVersion {
+		return nil, fmt.Errorf("unsupported encrypted snapshot version %d", v)
+	}
+	size := binary.BigEndian.Uint32(preamble[len(envelopeMagic)+1:])
+	if size > envelopeMaxHeaderSize {
+		return nil, fmt.Errorf("encrypted snapshot header is too large (%d bytes)", size)
+	}
+	header := make([]byte, size)
+	if _, err := io.ReadFull(in, header); err != nil {
+		return nil, fmt.Errorf("failed to read encrypted snapshot header: %v", err)
+	}
+
+	var hdr envelopeHeader
+	if err := json.Unmarshal(header, &hdr); err != nil {
+		return nil, fmt.Errorf("failed to decode encrypted snapshot header: %v", err)
+	}
+	if hdr.Cipher != envelopeCipher {
+		return nil, fmt.Errorf("unsupported snapshot cipher %q", hdr.Cipher)
+	}
+	if hdr.Key == nil {
+		return nil, fmt.Errorf("encrypted snapshot header is missing the data key")
+	}
+
+	dataKey, err := kp.UnwrapKey(hdr.Key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(hdr.NoncePrefix) != aead.NonceSize()-8 {
+		return nil, fmt.Errorf("invalid nonce prefix size %d", len(hdr.NoncePrefix))
+	}
+
+	return &decryptReader{
+		in:     in,
+		aead:   aead,
+		header: header,
+		prefix: hdr.NoncePrefix,
+	}, nil
+}
+
+// Read returns the data of the opened chunks.
+func (r *decryptReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.final {
+			return 0, io.EOF
+		}
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *decryptReader) open() error {
+	var hdr [5]byte
+	if _, err := io.ReadFull(r.in, hdr[:]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return fmt.Errorf("failed to read encrypted snapshot: %v", err)
+	}
+	flag := hdr[0]
+	if flag != 0 && flag != chunkFlagFinal {
+		return fmt.Errorf("invalid encrypted snapshot chunk")
+	}
+	size := binary.BigEndian.Uint32(hdr[1:])
+	if size > envelopeChunkSize+uint32(r.aead.Overhead()) {
+		return fmt.Errorf("encrypted snapshot chunk is too large (%d bytes)", size)
+	}
+	sealed := make([]byte, size)
+	if _, err := io.ReadFull(r.in, sealed); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return fmt.Errorf("failed to read encrypted snapshot: %v", err)
+	}
+
+	nonce := chunkNonce(r.prefix, r.counter)
+	plain, err := r.aead.Open(sealed[:0], nonce, sealed, chunkAdditionalData(r.header, flag))
+	if err != nil {
+		return fmt.Errorf("failed to decrypt snapshot: %v", err)
+	}
+	r.counter++
+	r.buf = plain
+	r.final = flag == chunkFlagFinal
+	return nil
+}
+
+func chunkNonce(prefix []byte, counter uint64) []byte {
+	nonce := make([]byte, len(prefix)+8)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint64(nonce[len(prefix):], counter)
+	return nonce
+}
+
+func chunkAdditionalData(header []byte, flag byte) []byte {
+	ad := make([]byte, len(header)+1)
+	copy(ad, header)
+	ad[len(header)] = flag
+	return ad
+}
@@ -0,0 +1,153 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package snapshot
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// encrypt seals the plaintext with the key provider.
+func encrypt(t *testing.T, kp KeyProvider, plaintext []byte) []byte {
+	var buf bytes.Buffer
+	w, err := newEncryptWriter(&buf, kp)
+	require.NoError(t, err)
+	_, err = w.Write(plaintext)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+// decrypt opens the ciphertext with the key provider.
+func decrypt(kp KeyProvider, ciphertext []byte) ([]byte, error) {
+	r, err := newDecryptReader(bytes.NewReader(ciphertext), kp)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func testKeyProvider(t *testing.T) KeyProvider {
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+	kp, err := NewKeyProvider(key)
+	require.NoError(t, err)
+	return kp
+}
+
+func TestEnvelope_RoundTrip(t *testing.T) {
+	kp := testKeyProvider(t)
+
+	for _, size := range []int{0, 1, envelopeChunkSize - 1, envelopeChunkSize, envelopeChunkSize + 1, 3*envelopeChunkSize + 7} {
+		plaintext := make([]byte, size)
+		_, err := rand.Read(plaintext)
+		require.NoError(t, err)
+
+		ciphertext := encrypt(t, kp, plaintext)
+		require.True(t, bytes.HasPrefix(ciphertext, envelopeMagic))
+		if size > 0 {
+			require.False(t, bytes.Contains(ciphertext, plaintext))
+		}
+
+		actual, err := decrypt(kp, ciphertext)
+		require.NoError(t, err)
+		require.Equal(t, plaintext, actual)
+	}
+}
+
+func TestEnvelope_Tampered(t *testing.T) {
+	kp := testKeyProvider(t)
+
+	plaintext := make([]byte, 2*envelopeChunkSize+100)
+	_, err := rand.Read(plaintext)
+	require.NoError(t, err)
+	ciphertext := encrypt(t, kp, plaintext)
+
+	t.Run("truncated", func(t *testing.T) {
+		for _, removeBytes := range []int{1, 100, envelopeChunkSize / 2} {
+			_, err := decrypt(kp, ciphertext[:len(ciphertext)-removeBytes])
+			require.Error(t, err)
+		}
+	})
+
+	t.Run("final chunk dropped", func(t *testing.T) {
+		// The final chunk holds the last 100 bytes.
+		final := 5 + 100 + 16
+		_, err := decrypt(kp, ciphertext[:len(ciphertext)-final])
+		require.ErrorContains(t, err, "unexpected EOF")
+	})
+
+	t.Run("flipped bit", func(t *testing.T) {
+		tampered := bytes.Clone(ciphertext)
+		tampered[len(tampered)-envelopeChunkSize] ^= 1
+		_, err := decrypt(kp, tampered)
+		require.ErrorContains(t, err, "failed to decrypt snapshot")
+	})
+}
+
+func TestEnvelope_KeyProviders(t *testing.T) {
+	plaintext := []byte("hello world")
+
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+	encodedKey := base64.StdEncoding.EncodeToString(key)
+
+	kp, err := ParseKeyProvider(encodedKey, "")
+	require.NoError(t, err)
+	pp, err := ParseKeyProvider("", "hunter2")
+	require.NoError(t, err)
+
+	t.Run("key", func(t *testing.T) {
+		ciphertext := encrypt(t, kp, plaintext)
+
+		other := testKeyProvider(t)
+		_, err := decrypt(other, ciphertext)
+		require.ErrorContains(t, err, "snapshot was encrypted with key")
+
+		_, err = decrypt(pp, ciphertext)
+		require.ErrorContains(t, err, "snapshot was encrypted with a key, not a passphrase")
+
+		actual, err := decrypt(kp, ciphertext)
+		require.NoError(t, err)
+		require.Equal(t, plaintext, actual)
+	})
+
+	t.Run("passphrase", func(t *testing.T) {
+		ciphertext := encrypt(t, pp, plaintext)
+
+		wrong, err := NewPassphraseKeyProvider("hunter3")
+		require.NoError(t, err)
+		_, err = decrypt(wrong, ciphertext)
+		require.ErrorContains(t, err, "the passphrase may be wrong")
+
+		_, err = decrypt(kp, ciphertext)
+		require.ErrorContains(t, err, "snapshot was encrypted with a passphrase, not a key")
+
+		actual, err := decrypt(pp, ciphertext)
+		require.NoError(t, err)
+		require.Equal(t, plaintext, actual)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := ParseKeyProvider(encodedKey, "hunter2")
+		require.ErrorContains(t, err, "only one of an encryption key or passphrase")
+
+		_, err = ParseKeyProvider("not base64!", "")
+		require.ErrorContains(t, err, "failed to decode encryption key")
+
+		_, err = ParseKeyProvider(base64.StdEncoding.EncodeToString([]byte("short")), "")
+		require.ErrorContains(t, err, "key must be 32 bytes, got 5")
+
+		kp, err := ParseKeyProvider("", "")
+		require.NoError(t, err)
+		require.Nil(t, kp)
+	})
+}
@@ -3,10 +3,13 @@
 
 // snapshot manages the interactions between Consul and Raft in order to take
 // and restore snapshots for disaster recovery. The internal format of a
-// snapshot is simply a tar file, as described in archive.go.
+// snapshot is simply a tar file, as described in archive.go, compressed with
+// gzip or zstd and optionally encrypted, as described in envelope.go.
 package snapshot
 
 import (
+	"bufio"
+	"bytes"
 	"compress/gzip"
 	"fmt"
 	"io"
@@ -14,8 +17,44 @@ import (
 
 	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/raft"
+	"github.com/klauspost/compress/zstd"
 )
 
+// Compression is the compression algorithm used for a snapshot archive.
+type Compression string
+
+const (
+	CompressionGzip Compression = "gzip"
+	CompressionZstd Compression = "zstd"
+)
+
+// zstdMagic is the magic number at the start of a zstd frame.
+var zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+// ParseCompression parses the name of a compression algorithm. An empty name
+// defaults to gzip.
+func ParseCompression(name string) (Compression, error) {
+	switch Compression(name) {
+	case "", CompressionGzip:
+		return CompressionGzip, nil
+	case CompressionZstd:
+		return CompressionZstd, nil
+	default:
+		return "", fmt.Errorf("unsupported snapshot compression %q, must be %q or %q", name, CompressionGzip, CompressionZstd)
+	}
+}
+
+// Options are the options used to write a snapshot archive.
+type Options struct {
+	// Compression is the compression algorithm of the archive, it defaults
+	// to gzip.
+	Compression Compression
+
+	// KeyProvider is used to wrap the data key the archive is encrypted
+	// with. The archive is only encrypted if this is set.
+	KeyProvider KeyProvider
+}
+
 // Snapshot is a structure that holds state about a temporary file that is used
 // to hold a snapshot. By using an intermediate file we avoid holding everything
 // in memory.
@@ -29,6 +68,17 @@ type Snapshot struct {
 // arrange to call Close() on the returned object or else you will leak a
 // temporary file.
 func New(logger hclog.Logger, r *raft.Raft) (*Snapshot, error) {
+	return NewWithOptions(logger, r, Options{})
+}
+
+// NewWithOptions is like New, but writes the archive with the given
+// compression and encryption options.
+func NewWithOptions(logger hclog.Logger, r *raft.Raft, opts Options) (*Snapshot, error) {
+	compression, err := ParseCompression(string(opts.Compression))
+	if err != nil {
+		return nil, err
+	}
+
 	// Take the snapshot.
 	future := r.Snapshot()
 	if err := future.Error(); err != nil {
@@ -67,8 +117,28 @@ func New(logger hclog.Logger, r *raft.Raft) (*Snapshot, error) {
 		}
 	}()
 
-	// Wrap the file writer in a gzip compressor.
-	compressor := gzip.NewWriter(archive)
+	// Wrap the file writer in an encryptor if we have a key provider.
+	var out io.Writer = archive
+	var encryptor *encryptWriter
+	if opts.KeyProvider != nil {
+		encryptor, err = newEncryptWriter(archive, opts.KeyProvider)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt snapshot file: %v", err)
+		}
+		out = encryptor
+	}
+
+	// Wrap the writer in a compressor.
+	var compressor io.WriteCloser
+	switch compression {
+	case CompressionZstd:
+		compressor, err = zstd.NewWriter(out)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compress snapshot file: %v", err)
+		}
+	default:
+		compressor = gzip.NewWriter(out)
+	}
 
 	// Write the archive.
 	if err := write(compressor, metadata, snap); err != nil {
@@ -80,6 +150,13 @@ func New(logger hclog.Logger, r *raft.Raft) (*Snapshot, error) {
 		return nil, fmt.Errorf("failed to compress snapshot file: %v", err)
 	}
 
+	// Seal the final chunk of the encrypted stream.
+	if encryptor != nil {
+		if err := encryptor.Close(); err != nil {
+			return nil, fmt.Errorf("failed to encrypt snapshot file: %v", err)
+		}
+	}
+
 	// Sync the compressed file and rewind it so it's ready to be streamed
 	// out by the caller.
 	if err := archive.Sync(); err != nil {
@@ -125,12 +202,13 @@ func (s *Snapshot) Close() error {
 	return os.Remove(s.file.Name())
 }
 
-// Verify takes the snapshot from the reader and verifies its contents.
-func Verify(in io.Reader) (*raft.SnapshotMeta, error) {
-	// Wrap the reader in a gzip decompressor.
-	decomp, err := gzip.NewReader(in)
+// Verify takes the snapshot from the reader and verifies its contents. The key
+// provider is only needed if the snapshot is encrypted.
+func Verify(in io.Reader, kp KeyProvider) (*raft.SnapshotMeta, error) {
+	// Open the archive, decrypting and decompressing it.
+	decomp, err := openArchive(in, kp)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decompress snapshot: %v", err)
+		return nil, err
 	}
 	defer decomp.Close()
 
@@ -140,34 +218,98 @@ func Verify(in io.Reader) (*raft.SnapshotMeta, error) {
 		return nil, fmt.Errorf("failed to read snapshot file: %v", err)
 	}
 
-	if err := concludeGzipRead(decomp); err != nil {
+	if err := decomp.conclude(); err != nil {
 		return nil, err
 	}
 
 	return &metadata, nil
 }
 
-// concludeGzipRead should be invoked after you think you've consumed all of
-// the data from the gzip stream. It will error if the stream was corrupt.
+// archiveReader reads the tar stream of a snapshot archive, decompressing and
+// decrypting it as needed.
+type archiveReader struct {
+	decomp  io.ReadCloser
+	decrypt *decryptReader
+}
+
+// openArchive detects the encryption and compression of the archive from its
+// magic bytes and returns a reader for the tar stream inside. The key
+// provider is only needed if the archive is encrypted.
+func openArchive(in io.Reader, kp KeyProvider) (*archiveReader, error) {
+	ar := &archiveReader{}
+
+	buffered := bufio.NewReader(in)
+	if isEncrypted(buffered) {
+		if kp == nil {
+			return nil, ErrEncrypted
+		}
+		decrypt, err := newDecryptReader(buffered, kp)
+		if err != nil {
+			return nil, err
+		}
+		ar.decrypt = decrypt
+		buffered = bufio.NewReader(decrypt)
+	}
+
+	if magic, _ := buffered.Peek(len(zstdMagic)); bytes.Equal(magic, zstdMagic) {
+		decomp, err := zstd.NewReader(buffered)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress snapshot: %v", err)
+		}
+		ar.decomp = decomp.IOReadCloser()
+		return ar, nil
+	}
+
+	decomp, err := gzip.NewReader(buffered)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress snapshot: %v", err)
+	}
+	ar.decomp = decomp
+	return ar, nil
+}
+
+// Read passes through to the decompressor.
+func (ar *archiveReader) Read(p []byte) (int, error) {
+	return ar.decomp.Read(p)
+}
+
+// Close closes the decompressor.
+func (ar *archiveReader) Close() error {
+	return ar.decomp.Close()
+}
+
+// conclude should be invoked after you think you've consumed all of the data
+// from the archive. It will error if the stream was corrupt or truncated.
 //
 // The docs for gzip.Reader say: "Clients should treat data returned by Read as
-// tentative until they receive the io.EOF marking the end of the data."
-func concludeGzipRead(decomp *gzip.Reader) error {
-	extra, err := io.ReadAll(decomp) // ReadAll consumes the EOF
+// tentative until they receive the io.EOF marking the end of the data." The
+// same goes for the final chunk of an encrypted archive.
+func (ar *archiveReader) conclude() error {
+	extra, err := io.ReadAll(ar.decomp) // ReadAll consumes the EOF
 	if err != nil {
 		return err
 	} else if len(extra) != 0 {
 		return fmt.Errorf("%d unread uncompressed bytes remain", len(extra))
 	}
+
+	if ar.decrypt != nil {
+		extra, err := io.ReadAll(ar.decrypt)
+		if err != nil {
+			return err
+		} else if len(extra) != 0 {
+			return fmt.Errorf("%d unread compressed bytes remain", len(extra))
+		}
+	}
 	return nil
 }
 
-// Read a snapshot into a temporary file. The caller is responsible for removing the file.
-func Read(logger hclog.Logger, in io.Reader) (*os.File, *raft.SnapshotMeta, error) {
-	// Wrap the reader in a gzip decompressor.
-	decomp, err := gzip.NewReader(in)
+// Read a snapshot into a temporary file. The caller is responsible for removing
+// the file. The key provider is only needed if the snapshot is encrypted.
+func Read(logger hclog.Logger, in io.Reader, kp KeyProvider) (*os.File, *raft.SnapshotMeta, error) {
+	// Open the archive, decrypting and decompressing it.
+	decomp, err := openArchive(in, kp)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to decompress snapshot: %v", err)
+		return nil, nil, err
 	}
 	defer func() {
 		if err := decomp.Close(); err != nil {
@@ -188,7 +330,7 @@ func Read(logger hclog.Logger, in io.Reader) (*os.File, *raft.SnapshotMeta, erro
 		return nil, nil, fmt.Errorf("failed to read snapshot file: %v", err)
 	}
 
-	if err := concludeGzipRead(decomp); err != nil {
+	if err := decomp.conclude(); err != nil {
 		return nil, nil, err
 	}
 
@@ -203,9 +345,10 @@ func Read(logger hclog.Logger, in io.Reader) (*os.File, *raft.SnapshotMeta, erro
 }
 
 // Restore takes the snapshot from the reader and attempts to apply it to the
-// given Raft instance.
-func Restore(logger hclog.Logger, in io.Reader, r *raft.Raft) error {
-	snap, metadata, err := Read(logger, in)
+// given Raft instance. The key provider is only needed if the snapshot is
+// encrypted.
+func Restore(logger hclog.Logger, in io.Reader, r *raft.Raft, kp KeyProvider) error {
+	snap, metadata, err := Read(logger, in, kp)
 	defer func() {
 		if snap == nil {
 			return
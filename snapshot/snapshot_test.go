@@ -8,6 +8,7 @@ import (
 	"crypto/rand"
 	"fmt"
 	"io"
+	"os"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -162,7 +163,7 @@ func TestSnapshot(t *testing.T) {
 	defer snap.Close()
 
 	// Verify the snapshot. We have to rewind it after for the restore.
-	metadata, err := Verify(snap)
+	metadata, err := Verify(snap, nil)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
@@ -196,7 +197,7 @@ func TestSnapshot(t *testing.T) {
 	}
 
 	// Restore the snapshot.
-	if err := Restore(logger, snap, after); err != nil {
+	if err := Restore(logger, snap, after, nil); err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
@@ -232,7 +233,7 @@ func TestSnapshot_Nil(t *testing.T) {
 
 func TestSnapshot_BadVerify(t *testing.T) {
 	buf := bytes.NewBuffer([]byte("nope"))
-	_, err := Verify(buf)
+	_, err := Verify(buf, nil)
 	if err == nil || !strings.Contains(err.Error(), "unexpected EOF") {
 		t.Fatalf("err: %v", err)
 	}
@@ -281,7 +282,7 @@ func TestSnapshot_TruncatedVerify(t *testing.T) {
 			// Lop off part of the end.
 			buf := bytes.NewReader(data[0 : len(data)-removeBytes])
 
-			_, err = Verify(buf)
+			_, err = Verify(buf, nil)
 			require.Error(t, err)
 		})
 	}
@@ -338,7 +339,7 @@ func TestSnapshot_BadRestore(t *testing.T) {
 
 	// Attempt to restore a truncated version of the snapshot. This is
 	// expected to fail.
-	err = Restore(logger, io.LimitReader(snap, 512), after)
+	err = Restore(logger, io.LimitReader(snap, 512), after, nil)
 	if err == nil || !strings.Contains(err.Error(), "unexpected EOF") {
 		t.Fatalf("err: %v", err)
 	}
@@ -356,3 +357,85 @@ func TestSnapshot_BadRestore(t *testing.T) {
 		}
 	}
 }
+
+func TestSnapshot_Options(t *testing.T) {
+	if testing.Short() {
+		t.Skip("too slow for testing.Short")
+	}
+
+	dir := testutil.TempDir(t, "snapshot")
+
+	// Make a Raft and populate it with some data.
+	before, _ := makeRaft(t, filepath.Join(dir, "before"))
+	defer before.Shutdown()
+	for i := 0; i < 4*1024; i++ {
+		var log bytes.Buffer
+		_, err := io.CopyN(&log, rand.Reader, 256)
+		require.NoError(t, err)
+
+		future := before.Apply(log.Bytes(), time.Second)
+		require.NoError(t, future.Error())
+	}
+
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+	keyProvider, err := NewKeyProvider(key)
+	require.NoError(t, err)
+	passphraseProvider, err := NewPassphraseKeyProvider("hunter2")
+	require.NoError(t, err)
+
+	cases := map[string]Options{
+		"gzip":                    {Compression: CompressionGzip},
+		"zstd":                    {Compression: CompressionZstd},
+		"gzip with key":           {Compression: CompressionGzip, KeyProvider: keyProvider},
+		"zstd with passphrase":    {Compression: CompressionZstd, KeyProvider: passphraseProvider},
+		"default with passphrase": {KeyProvider: passphraseProvider},
+	}
+	for name, opts := range cases {
+		t.Run(name, func(t *testing.T) {
+			logger := testutil.Logger(t)
+			snap, err := NewWithOptions(logger, before, opts)
+			require.NoError(t, err)
+			defer snap.Close()
+
+			var buf bytes.Buffer
+			_, err = io.Copy(&buf, snap)
+			require.NoError(t, err)
+			data := buf.Bytes()
+
+			if opts.KeyProvider != nil {
+				_, err := Verify(bytes.NewReader(data), nil)
+				require.ErrorIs(t, err, ErrEncrypted)
+			}
+
+			metadata, err := Verify(bytes.NewReader(data), opts.KeyProvider)
+			require.NoError(t, err)
+			require.Equal(t, snap.Index(), metadata.Index)
+
+			f, metadata, err := Read(logger, bytes.NewReader(data), opts.KeyProvider)
+			require.NoError(t, err)
+			require.NoError(t, f.Close())
+			require.NoError(t, os.Remove(f.Name()))
+			require.Equal(t, snap.Index(), metadata.Index)
+
+			for _, removeBytes := range []int{200, 16, 1} {
+				_, err = Verify(bytes.NewReader(data[0:len(data)-removeBytes]), opts.KeyProvider)
+				require.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestParseCompression(t *testing.T) {
+	c, err := ParseCompression("")
+	require.NoError(t, err)
+	require.Equal(t, CompressionGzip, c)
+
+	c, err = ParseCompression("zstd")
+	require.NoError(t, err)
+	require.Equal(t, CompressionZstd, c)
+
+	_, err = ParseCompression("lz4")
+	require.ErrorContains(t, err, `unsupported snapshot compression "lz4"`)
+}